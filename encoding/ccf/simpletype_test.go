@@ -24,7 +24,6 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/onflow/cadence"
-	"github.com/onflow/cadence/runtime"
 	"github.com/onflow/cadence/runtime/interpreter"
 	"github.com/onflow/cadence/runtime/sema"
 )
@@ -41,7 +40,9 @@ func TestTypeConversion(t *testing.T) {
 
 			t.Parallel()
 
-			cadenceType := runtime.ExportType(semaType, map[sema.TypeID]cadence.Type{})
+			// The runtime package cannot be imported to export the sema type,
+			// as it depends on this package. Primitive types are exported as primitive Cadence types
+			cadenceType := cadence.PrimitiveType(ty)
 
 			simpleTypeID, ok := simpleTypeIDByType(cadenceType)
 			require.True(t, ok)
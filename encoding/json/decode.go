@@ -160,8 +160,11 @@ func (d *Decoder) DecodeJSON(v any) cadence.Value {
 		panic(errors.NewDefaultUserError("expected JSON object with keys `%s` and `%s`", typeKey, valueKey))
 	}
 
-	valueJSON := obj.Get(valueKey)
+	return d.decodeValue(typeStr, obj.Get(valueKey))
+}
 
+// decodeValue decodes the value of a JSON-Cadence value object with the given type
+func (d *Decoder) decodeValue(typeStr string, valueJSON any) cadence.Value {
 	switch typeStr {
 	case optionalTypeStr:
 		return d.decodeOptional(valueJSON)
@@ -706,16 +709,7 @@ func (d *Decoder) decodeComposite(valueJSON any) composite {
 	obj := toObject(valueJSON)
 
 	typeID := obj.GetString(idKey)
-	location, qualifiedIdentifier, err := common.DecodeTypeID(d.gauge, typeID)
-
-	if err != nil {
-		panic(errors.NewDefaultUserError("invalid type ID `%s`: %w", typeID, err))
-	} else if location == nil && sema.NativeCompositeTypes[typeID] == nil {
-
-		// If the location is nil, and there is no native composite type with this ID, then it's an invalid type.
-		// Note: This is moved out from the common.DecodeTypeID() to avoid the circular dependency.
-		panic(errors.NewDefaultUserError("invalid type ID for built-in: `%s`", typeID))
-	}
+	location, qualifiedIdentifier := d.decodeCompositeTypeID(typeID)
 
 	fields := obj.GetSlice(fieldsKey)
 
@@ -742,6 +736,21 @@ func (d *Decoder) decodeComposite(valueJSON any) composite {
 	}
 }
 
+func (d *Decoder) decodeCompositeTypeID(typeID string) (common.Location, string) {
+	location, qualifiedIdentifier, err := common.DecodeTypeID(d.gauge, typeID)
+
+	if err != nil {
+		panic(errors.NewDefaultUserError("invalid type ID `%s`: %w", typeID, err))
+	} else if location == nil && sema.NativeCompositeTypes[typeID] == nil {
+
+		// If the location is nil, and there is no native composite type with this ID, then it's an invalid type.
+		// Note: This is moved out from the common.DecodeTypeID() to avoid the circular dependency.
+		panic(errors.NewDefaultUserError("invalid type ID for built-in: `%s`", typeID))
+	}
+
+	return location, qualifiedIdentifier
+}
+
 func (d *Decoder) decodeCompositeField(valueJSON any) (cadence.Value, cadence.Field) {
 	obj := toObject(valueJSON)
 
@@ -756,7 +765,10 @@ func (d *Decoder) decodeCompositeField(valueJSON any) (cadence.Value, cadence.Fi
 }
 
 func (d *Decoder) decodeStruct(valueJSON any) cadence.Struct {
-	comp := d.decodeComposite(valueJSON)
+	return d.newStruct(d.decodeComposite(valueJSON))
+}
+
+func (d *Decoder) newStruct(comp composite) cadence.Struct {
 
 	structure, err := cadence.NewMeteredStruct(
 		d.gauge,
@@ -780,7 +792,10 @@ func (d *Decoder) decodeStruct(valueJSON any) cadence.Struct {
 }
 
 func (d *Decoder) decodeResource(valueJSON any) cadence.Resource {
-	comp := d.decodeComposite(valueJSON)
+	return d.newResource(d.decodeComposite(valueJSON))
+}
+
+func (d *Decoder) newResource(comp composite) cadence.Resource {
 
 	resource, err := cadence.NewMeteredResource(
 		d.gauge,
@@ -803,7 +818,10 @@ func (d *Decoder) decodeResource(valueJSON any) cadence.Resource {
 }
 
 func (d *Decoder) decodeEvent(valueJSON any) cadence.Event {
-	comp := d.decodeComposite(valueJSON)
+	return d.newEvent(d.decodeComposite(valueJSON))
+}
+
+func (d *Decoder) newEvent(comp composite) cadence.Event {
 
 	event, err := cadence.NewMeteredEvent(
 		d.gauge,
@@ -827,7 +845,10 @@ func (d *Decoder) decodeEvent(valueJSON any) cadence.Event {
 }
 
 func (d *Decoder) decodeContract(valueJSON any) cadence.Contract {
-	comp := d.decodeComposite(valueJSON)
+	return d.newContract(d.decodeComposite(valueJSON))
+}
+
+func (d *Decoder) newContract(comp composite) cadence.Contract {
 
 	contract, err := cadence.NewMeteredContract(
 		d.gauge,
@@ -851,7 +872,10 @@ func (d *Decoder) decodeContract(valueJSON any) cadence.Contract {
 }
 
 func (d *Decoder) decodeEnum(valueJSON any) cadence.Enum {
-	comp := d.decodeComposite(valueJSON)
+	return d.newEnum(d.decodeComposite(valueJSON))
+}
+
+func (d *Decoder) newEnum(comp composite) cadence.Enum {
 
 	enum, err := cadence.NewMeteredEnum(
 		d.gauge,
@@ -0,0 +1,768 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package json
+
+import (
+	"encoding/json"
+	goErrors "errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/errors"
+)
+
+// Limits are the limits of a StreamDecoder.
+// A zero limit means no limit.
+type Limits struct {
+	// MaxDepth is the maximum nesting depth of the JSON objects and arrays
+	// which contain values, e.g. arrays, dictionaries, and composites.
+	// While a value which precedes its type is buffered, all its JSON objects and arrays count
+	MaxDepth int
+	// MaxElements is the maximum total number of array elements,
+	// dictionary entries, and composite fields
+	MaxElements int
+	// MaxBytes is the maximum number of bytes read from the input
+	MaxBytes int64
+}
+
+var (
+	// ErrMaxDepthExceeded is returned by StreamDecoder if the input exceeds the maximum nesting depth
+	ErrMaxDepthExceeded = goErrors.New("maximum depth exceeded")
+	// ErrMaxElementsExceeded is returned by StreamDecoder if the input exceeds the maximum number of elements
+	ErrMaxElementsExceeded = goErrors.New("maximum number of elements exceeded")
+	// ErrMaxBytesExceeded is returned by StreamDecoder if the input exceeds the maximum number of bytes
+	ErrMaxBytesExceeded = goErrors.New("maximum number of bytes exceeded")
+)
+
+// StreamDecodingError is an error which occurred when decoding a JSON-Cadence value with a StreamDecoder.
+// The path is the JSON path of the JSON value at which the error occurred, e.g. `$.value[1].value`.
+type StreamDecodingError struct {
+	Path string
+	Err  error
+}
+
+var _ errors.UserError = StreamDecodingError{}
+
+func (StreamDecodingError) IsUserError() {}
+
+func (e StreamDecodingError) Error() string {
+	return fmt.Sprintf("failed to decode JSON-Cadence value at %s: %s", e.Path, e.Err.Error())
+}
+
+func (e StreamDecodingError) Unwrap() error {
+	return e.Err
+}
+
+// A StreamDecoder decodes JSON-encoded representations of Cadence values,
+// like Decoder, but reads the input token by token,
+// instead of first unmarshalling the whole input.
+//
+// Arrays, dictionaries, and composites are decoded as they are read.
+// If the value of a JSON-Cadence value object precedes its type, like in the output of Encoder,
+// the JSON tokens of the value are buffered until the type is read.
+// The depth and size limits also apply while buffering.
+// Values nested in a buffered value are not buffered again.
+// The output of StreamEncoder has the type first, so it is decoded without buffering.
+type StreamDecoder struct {
+	decoder *Decoder
+	input   *json.Decoder
+	// dec is the decoder of the input, or a buffered value
+	dec      tokenDecoder
+	limits   Limits
+	path     []any
+	depth    int
+	elements int
+}
+
+// NewStreamDecoder initializes a StreamDecoder that will decode JSON-encoded bytes from the
+// given io.Reader, within the given limits.
+func NewStreamDecoder(
+	gauge common.MemoryGauge,
+	r io.Reader,
+	limits Limits,
+	options ...Option,
+) *StreamDecoder {
+	decoder := &Decoder{
+		gauge: gauge,
+	}
+	for _, option := range options {
+		option(decoder)
+	}
+
+	if limits.MaxBytes > 0 {
+		r = &limitedReader{
+			r:         r,
+			remaining: limits.MaxBytes,
+		}
+	}
+
+	dec := json.NewDecoder(r)
+
+	return &StreamDecoder{
+		decoder: decoder,
+		input:   dec,
+		dec:     dec,
+		limits:  limits,
+	}
+}
+
+// tokenDecoder reads JSON tokens, see json.Decoder
+type tokenDecoder interface {
+	Token() (json.Token, error)
+	More() bool
+}
+
+var _ tokenDecoder = &json.Decoder{}
+
+// tokenBuffer is a tokenDecoder which reads buffered tokens
+type tokenBuffer struct {
+	tokens []json.Token
+	offset int
+}
+
+var _ tokenDecoder = &tokenBuffer{}
+
+func (b *tokenBuffer) Token() (json.Token, error) {
+	if b.offset >= len(b.tokens) {
+		return nil, io.EOF
+	}
+	token := b.tokens[b.offset]
+	b.offset++
+	return token, nil
+}
+
+func (b *tokenBuffer) More() bool {
+	if b.offset >= len(b.tokens) {
+		return false
+	}
+	token := b.tokens[b.offset]
+	return token != json.Delim(']') && token != json.Delim('}')
+}
+
+// limitedReader is like io.LimitedReader,
+// but returns ErrMaxBytesExceeded instead of io.EOF when the limit is reached
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, ErrMaxBytesExceeded
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// Decode reads the next JSON-encoded value from the io.Reader and decodes it to a
+// Cadence value.
+//
+// This function returns a StreamDecodingError if the bytes represent JSON that is malformed,
+// does not conform to the JSON Cadence specification, or exceeds the limits.
+// The values are identical to the values decoded by Decoder.
+func (s *StreamDecoder) Decode() (value cadence.Value, err error) {
+	s.dec = s.input
+	s.path = s.path[:0]
+	s.depth = 0
+	s.elements = 0
+
+	// capture panics that occur during decoding
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr, isError := r.(error)
+			if !isError {
+				panic(r)
+			}
+
+			err = StreamDecodingError{
+				Path: s.formatPath(),
+				Err:  panicErr,
+			}
+		}
+	}()
+
+	return s.decodeValueObject(), nil
+}
+
+func (s *StreamDecoder) formatPath() string {
+	var builder strings.Builder
+	builder.WriteByte('$')
+	for _, element := range s.path {
+		switch element := element.(type) {
+		case string:
+			builder.WriteByte('.')
+			builder.WriteString(element)
+		case int:
+			builder.WriteByte('[')
+			builder.WriteString(strconv.Itoa(element))
+			builder.WriteByte(']')
+		}
+	}
+	return builder.String()
+}
+
+// pushKey and pushIndex extend the path.
+// The path is only shortened again by pop when decoding succeeds,
+// so the path of the first error is reported.
+
+func (s *StreamDecoder) pushKey(key string) {
+	s.path = append(s.path, key)
+}
+
+func (s *StreamDecoder) pushIndex(index int) {
+	s.path = append(s.path, index)
+}
+
+func (s *StreamDecoder) pop() {
+	s.path = s.path[:len(s.path)-1]
+}
+
+func (s *StreamDecoder) enter() {
+	s.depth++
+	if s.limits.MaxDepth > 0 && s.depth > s.limits.MaxDepth {
+		panic(fmt.Errorf("%w: %d", ErrMaxDepthExceeded, s.limits.MaxDepth))
+	}
+}
+
+func (s *StreamDecoder) leave() {
+	s.depth--
+}
+
+func (s *StreamDecoder) countElement() {
+	s.elements++
+	if s.limits.MaxElements > 0 && s.elements > s.limits.MaxElements {
+		panic(fmt.Errorf("%w: %d", ErrMaxElementsExceeded, s.limits.MaxElements))
+	}
+}
+
+func (s *StreamDecoder) readError(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return errors.NewDefaultUserError("failed to decode JSON: %w", err)
+}
+
+func (s *StreamDecoder) token() json.Token {
+	token, err := s.dec.Token()
+	if err != nil {
+		panic(s.readError(err))
+	}
+	return token
+}
+
+func (s *StreamDecoder) more() bool {
+	return s.dec.More()
+}
+
+func (s *StreamDecoder) expectDelim(delim json.Delim) {
+	token := s.token()
+	if token != delim {
+		panic(unexpectedTokenError(delim, token))
+	}
+}
+
+func unexpectedTokenError(delim json.Delim, token json.Token) error {
+	var expected string
+	switch delim {
+	case '{':
+		expected = "object"
+	case '[':
+		expected = "array"
+	default:
+		expected = delim.String()
+	}
+	return errors.NewDefaultUserError("expected JSON %s, got %v", expected, token)
+}
+
+func (s *StreamDecoder) readString() string {
+	token := s.token()
+	str, ok := token.(string)
+	if !ok {
+		panic(errors.NewDefaultUserError("expected JSON string, got %v", token))
+	}
+	return str
+}
+
+// readAny reads the next JSON value, like Decoder does
+func (s *StreamDecoder) readAny() any {
+	if _, ok := s.dec.(*tokenBuffer); ok {
+		return s.readBufferedAny()
+	}
+
+	var v any
+	err := s.input.Decode(&v)
+	if err != nil {
+		panic(s.readError(err))
+	}
+	return v
+}
+
+// readBufferedAny reads the next JSON value from the buffered tokens,
+// like json.Decoder.Decode does
+func (s *StreamDecoder) readBufferedAny() any {
+	token := s.token()
+	switch token {
+	case json.Delim('{'):
+		object := map[string]any{}
+		for s.more() {
+			key := s.readString()
+			object[key] = s.readBufferedAny()
+		}
+		s.expectDelim('}')
+		return object
+
+	case json.Delim('['):
+		array := []any{}
+		for s.more() {
+			array = append(array, s.readBufferedAny())
+		}
+		s.expectDelim(']')
+		return array
+
+	default:
+		return token
+	}
+}
+
+// skip reads and ignores the next JSON value
+func (s *StreamDecoder) skip() {
+	if _, ok := s.dec.(*tokenBuffer); ok {
+		s.readBufferedAny()
+		return
+	}
+
+	var raw json.RawMessage
+	err := s.input.Decode(&raw)
+	if err != nil {
+		panic(s.readError(err))
+	}
+}
+
+// bufferValue reads the next JSON value, so it can be decoded once its type is known.
+// The depth limit applies to all JSON objects and arrays of the value
+func (s *StreamDecoder) bufferValue() *tokenBuffer {
+	// A value nested in a buffered value is not buffered again,
+	// the buffered tokens of the value are reused
+	if buffer, ok := s.dec.(*tokenBuffer); ok {
+		start := buffer.offset
+		s.readJSONValue(nil)
+		return &tokenBuffer{
+			tokens: buffer.tokens[start:buffer.offset],
+		}
+	}
+
+	var tokens []json.Token
+	s.readJSONValue(&tokens)
+	return &tokenBuffer{
+		tokens: tokens,
+	}
+}
+
+// readJSONValue reads the next JSON value, and appends its tokens to the given tokens, if any
+func (s *StreamDecoder) readJSONValue(tokens *[]json.Token) {
+	read := func() json.Token {
+		token := s.token()
+		if tokens != nil {
+			*tokens = append(*tokens, token)
+		}
+		return token
+	}
+
+	switch read() {
+	case json.Delim('{'):
+		s.enter()
+		for s.more() {
+			key, ok := read().(string)
+			if !ok {
+				panic(errors.NewDefaultUserError("expected JSON string"))
+			}
+			s.pushKey(key)
+			s.readJSONValue(tokens)
+			s.pop()
+		}
+		read()
+		s.leave()
+
+	case json.Delim('['):
+		s.enter()
+		for index := 0; s.more(); index++ {
+			s.pushIndex(index)
+			s.readJSONValue(tokens)
+			s.pop()
+		}
+		read()
+		s.leave()
+	}
+}
+
+// decodeValueObject decodes a JSON-Cadence value object, i.e. a JSON object with a type and a value
+func (s *StreamDecoder) decodeValueObject() cadence.Value {
+	s.expectDelim('{')
+	return s.decodeValueObjectMembers()
+}
+
+// decodeValueObjectMembers decodes a JSON-Cadence value object,
+// after the opening brace has been read
+func (s *StreamDecoder) decodeValueObjectMembers() cadence.Value {
+	s.enter()
+
+	var typeStr string
+	var hasType, hasValue bool
+	var value cadence.Value
+	var bufferedValue *tokenBuffer
+
+	for s.more() {
+		key := s.readString()
+		switch key {
+		case typeKey:
+			if hasType {
+				panic(errors.NewDefaultUserError("duplicate property: %s", typeKey))
+			}
+			hasType = true
+			typeStr = s.readString()
+
+		case valueKey:
+			if hasValue {
+				panic(errors.NewDefaultUserError("duplicate property: %s", valueKey))
+			}
+			hasValue = true
+
+			if hasType {
+				s.pushKey(valueKey)
+				value = s.decodeValue(typeStr)
+				s.pop()
+			} else {
+				// The type is not known yet, buffer the value
+				s.pushKey(valueKey)
+				bufferedValue = s.bufferValue()
+				s.pop()
+			}
+
+		default:
+			panic(errors.NewDefaultUserError("expected JSON object with keys `%s` and `%s`", typeKey, valueKey))
+		}
+	}
+	s.expectDelim('}')
+
+	if !hasType {
+		panic(errors.NewDefaultUserError("missing property: %s", typeKey))
+	}
+
+	// void is a special case, does not have "value" field
+	if typeStr == voidTypeStr {
+		if hasValue {
+			panic(errors.NewDefaultUserError("invalid additional fields in void value"))
+		}
+		s.leave()
+		return cadence.NewMeteredVoid(s.decoder.gauge)
+	}
+
+	if !hasValue {
+		panic(errors.NewDefaultUserError("missing property: %s", valueKey))
+	}
+
+	if bufferedValue != nil {
+		s.pushKey(valueKey)
+		value = s.decodeBufferedValue(typeStr, bufferedValue)
+		s.pop()
+	}
+
+	s.leave()
+
+	return value
+}
+
+// decodeBufferedValue decodes the given buffered value of a JSON-Cadence value object with the given type
+func (s *StreamDecoder) decodeBufferedValue(typeStr string, bufferedValue *tokenBuffer) cadence.Value {
+	dec := s.dec
+	s.dec = bufferedValue
+
+	value := s.decodeValue(typeStr)
+
+	s.dec = dec
+
+	return value
+}
+
+// decodeValue decodes the value of a JSON-Cadence value object with the given type.
+// Values which may contain other values are decoded as they are read,
+// all other values are decoded by the Decoder
+func (s *StreamDecoder) decodeValue(typeStr string) cadence.Value {
+	switch typeStr {
+	case optionalTypeStr:
+		return s.decodeOptional()
+	case arrayTypeStr:
+		return s.decodeArray()
+	case dictionaryTypeStr:
+		return s.decodeDictionary()
+	case structTypeStr:
+		return s.decoder.newStruct(s.decodeComposite())
+	case resourceTypeStr:
+		return s.decoder.newResource(s.decodeComposite())
+	case eventTypeStr:
+		return s.decoder.newEvent(s.decodeComposite())
+	case contractTypeStr:
+		return s.decoder.newContract(s.decodeComposite())
+	case enumTypeStr:
+		return s.decoder.newEnum(s.decodeComposite())
+	default:
+		return s.decoder.decodeValue(typeStr, s.readAny())
+	}
+}
+
+func (s *StreamDecoder) decodeOptional() cadence.Optional {
+	gauge := s.decoder.gauge
+
+	token := s.token()
+	switch token {
+	case nil:
+		return cadence.NewMeteredOptional(gauge, nil)
+	case json.Delim('{'):
+		return cadence.NewMeteredOptional(gauge, s.decodeValueObjectMembers())
+	default:
+		panic(unexpectedTokenError('{', token))
+	}
+}
+
+func (s *StreamDecoder) decodeArray() cadence.Array {
+	s.expectDelim('[')
+	s.enter()
+
+	values := []cadence.Value{}
+
+	for index := 0; s.more(); index++ {
+		s.pushIndex(index)
+		s.countElement()
+		values = append(values, s.decodeValueObject())
+		s.pop()
+	}
+
+	s.expectDelim(']')
+	s.leave()
+
+	value, err := cadence.NewMeteredArray(
+		s.decoder.gauge,
+		len(values),
+		func() ([]cadence.Value, error) {
+			return values, nil
+		},
+	)
+	if err != nil {
+		panic(errors.NewDefaultUserError("invalid array: %w", err))
+	}
+	return value
+}
+
+func (s *StreamDecoder) decodeDictionary() cadence.Dictionary {
+	s.expectDelim('[')
+	s.enter()
+
+	pairs := []cadence.KeyValuePair{}
+
+	for index := 0; s.more(); index++ {
+		s.pushIndex(index)
+		s.countElement()
+		pairs = append(pairs, s.decodeKeyValuePair())
+		s.pop()
+	}
+
+	s.expectDelim(']')
+	s.leave()
+
+	value, err := cadence.NewMeteredDictionary(
+		s.decoder.gauge,
+		len(pairs),
+		func() ([]cadence.KeyValuePair, error) {
+			return pairs, nil
+		},
+	)
+	if err != nil {
+		panic(errors.NewDefaultUserError("invalid dictionary: %w", err))
+	}
+	return value
+}
+
+func (s *StreamDecoder) decodeKeyValuePair() cadence.KeyValuePair {
+	s.expectDelim('{')
+	s.enter()
+
+	var key, value cadence.Value
+
+	for s.more() {
+		name := s.readString()
+		switch name {
+		case keyKey:
+			s.pushKey(keyKey)
+			key = s.decodeValueObject()
+			s.pop()
+
+		case valueKey:
+			s.pushKey(valueKey)
+			value = s.decodeValueObject()
+			s.pop()
+
+		default:
+			s.skip()
+		}
+	}
+
+	s.expectDelim('}')
+
+	if key == nil {
+		panic(errors.NewDefaultUserError("missing property: %s", keyKey))
+	}
+	if value == nil {
+		panic(errors.NewDefaultUserError("missing property: %s", valueKey))
+	}
+
+	s.leave()
+
+	return cadence.NewMeteredKeyValuePair(
+		s.decoder.gauge,
+		key,
+		value,
+	)
+}
+
+func (s *StreamDecoder) decodeComposite() composite {
+	s.expectDelim('{')
+	s.enter()
+
+	var typeID string
+	var hasTypeID, hasFields bool
+	var fieldValues []cadence.Value
+	var fieldTypes []cadence.Field
+
+	for s.more() {
+		name := s.readString()
+		switch name {
+		case idKey:
+			typeID = s.readString()
+			hasTypeID = true
+
+		case fieldsKey:
+			s.pushKey(fieldsKey)
+			fieldValues, fieldTypes = s.decodeCompositeFields()
+			s.pop()
+			hasFields = true
+
+		default:
+			s.skip()
+		}
+	}
+
+	s.expectDelim('}')
+
+	if !hasTypeID {
+		panic(errors.NewDefaultUserError("missing property: %s", idKey))
+	}
+	if !hasFields {
+		panic(errors.NewDefaultUserError("missing property: %s", fieldsKey))
+	}
+
+	location, qualifiedIdentifier := s.decoder.decodeCompositeTypeID(typeID)
+
+	common.UseMemory(s.decoder.gauge, common.MemoryUsage{
+		Kind:   common.MemoryKindCadenceField,
+		Amount: uint64(len(fieldValues)),
+	})
+
+	s.leave()
+
+	return composite{
+		location:            location,
+		qualifiedIdentifier: qualifiedIdentifier,
+		fieldValues:         fieldValues,
+		fieldTypes:          fieldTypes,
+	}
+}
+
+func (s *StreamDecoder) decodeCompositeFields() ([]cadence.Value, []cadence.Field) {
+	s.expectDelim('[')
+	s.enter()
+
+	fieldValues := []cadence.Value{}
+	fieldTypes := []cadence.Field{}
+
+	for index := 0; s.more(); index++ {
+		s.pushIndex(index)
+		s.countElement()
+		value, fieldType := s.decodeCompositeField()
+		s.pop()
+
+		fieldValues = append(fieldValues, value)
+		fieldTypes = append(fieldTypes, fieldType)
+	}
+
+	s.expectDelim(']')
+	s.leave()
+
+	return fieldValues, fieldTypes
+}
+
+func (s *StreamDecoder) decodeCompositeField() (cadence.Value, cadence.Field) {
+	s.expectDelim('{')
+	s.enter()
+
+	var name string
+	var hasName bool
+	var value cadence.Value
+
+	for s.more() {
+		key := s.readString()
+		switch key {
+		case nameKey:
+			name = s.readString()
+			hasName = true
+
+		case valueKey:
+			s.pushKey(valueKey)
+			value = s.decodeValueObject()
+			s.pop()
+
+		default:
+			s.skip()
+		}
+	}
+
+	s.expectDelim('}')
+
+	if !hasName {
+		panic(errors.NewDefaultUserError("missing property: %s", nameKey))
+	}
+	if value == nil {
+		panic(errors.NewDefaultUserError("missing property: %s", valueKey))
+	}
+
+	s.leave()
+
+	// Unmetered because decodeCompositeField is metered in decodeComposite and called nowhere else
+	// Type is still metered.
+	field := cadence.NewField(name, value.MeteredType(s.decoder.gauge))
+
+	return value, field
+}
@@ -0,0 +1,264 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package json
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	goRuntime "runtime"
+
+	"github.com/onflow/cadence"
+)
+
+// A StreamEncoder converts Cadence values into JSON-encoded bytes, like Encoder,
+// but writes arrays, dictionaries, and composites element by element,
+// instead of first preparing the whole value.
+//
+// The type of each JSON-Cadence value object is written before the value,
+// so the output can be decoded by StreamDecoder without buffering.
+// Otherwise, the output is equivalent to the output of Encoder.
+type StreamEncoder struct {
+	output io.Writer
+	w      *bufio.Writer
+}
+
+// NewStreamEncoder initializes a StreamEncoder that will write JSON-encoded bytes to the
+// given io.Writer.
+func NewStreamEncoder(w io.Writer) *StreamEncoder {
+	return &StreamEncoder{
+		output: w,
+		w:      bufio.NewWriter(w),
+	}
+}
+
+// Encode writes the JSON-encoded representation of the given value to this
+// encoder's io.Writer, followed by a newline character.
+//
+// This function returns an error if the given value's type is not supported
+// by this encoder. As the output is written while encoding,
+// a part of the representation might have been written already.
+func (e *StreamEncoder) Encode(value cadence.Value) (err error) {
+	// capture panics that occur during encoding
+	defer func() {
+		if r := recover(); r != nil {
+			// discard the buffered part of the representation
+			e.w.Reset(e.output)
+
+			// don't recover Go errors
+			goErr, ok := r.(goRuntime.Error)
+			if ok {
+				panic(goErr)
+			}
+
+			panicErr, isError := r.(error)
+			if !isError {
+				panic(r)
+			}
+
+			err = fmt.Errorf("failed to encode value: %w", panicErr)
+		}
+	}()
+
+	e.encodeValue(value)
+	e.writeByte('\n')
+
+	return e.w.Flush()
+}
+
+func (e *StreamEncoder) writeString(s string) {
+	// Write errors are returned by Flush
+	_, _ = e.w.WriteString(s)
+}
+
+func (e *StreamEncoder) writeByte(b byte) {
+	// Write errors are returned by Flush
+	_ = e.w.WriteByte(b)
+}
+
+// writeJSON writes the given Go value encoded as JSON, like Encoder does
+func (e *StreamEncoder) writeJSON(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	// Write errors are returned by Flush
+	_, _ = e.w.Write(data)
+}
+
+// writeKey writes the given key of an object, preceded by a comma if it is not the first key
+func (e *StreamEncoder) writeKey(key string, first bool) {
+	if !first {
+		e.writeByte(',')
+	}
+	e.writeJSON(key)
+	e.writeByte(':')
+}
+
+// beginValueObject writes the beginning of a JSON-Cadence value object with the given type,
+// up to the value
+func (e *StreamEncoder) beginValueObject(typeStr string) {
+	e.writeByte('{')
+	e.writeKey(typeKey, true)
+	e.writeJSON(typeStr)
+	e.writeKey(valueKey, false)
+}
+
+func (e *StreamEncoder) endValueObject() {
+	e.writeByte('}')
+}
+
+func (e *StreamEncoder) encodeValue(value cadence.Value) {
+	switch value := value.(type) {
+	case cadence.Optional:
+		e.encodeOptional(value)
+	case cadence.Array:
+		e.encodeArray(value)
+	case cadence.Dictionary:
+		e.encodeDictionary(value)
+	case *cadence.InclusiveRange:
+		e.encodeInclusiveRange(value)
+	case cadence.Struct:
+		e.encodeComposite(structTypeStr, value.StructType.ID(), value.StructType.Fields, value.Fields)
+	case cadence.Resource:
+		e.encodeComposite(resourceTypeStr, value.ResourceType.ID(), value.ResourceType.Fields, value.Fields)
+	case cadence.Event:
+		e.encodeComposite(eventTypeStr, value.EventType.ID(), value.EventType.Fields, value.Fields)
+	case cadence.Contract:
+		e.encodeComposite(contractTypeStr, value.ContractType.ID(), value.ContractType.Fields, value.Fields)
+	case cadence.Enum:
+		e.encodeComposite(enumTypeStr, value.EnumType.ID(), value.EnumType.Fields, value.Fields)
+	case cadence.Attachment:
+		e.encodeComposite(attachmentTypeStr, value.AttachmentType.ID(), value.AttachmentType.Fields, value.Fields)
+	default:
+		// All other values do not contain values,
+		// so they are prepared like Encoder does
+		e.encodePrepared(Prepare(value))
+	}
+}
+
+func (e *StreamEncoder) encodePrepared(prepared jsonValue) {
+	valueObject, ok := prepared.(jsonValueObject)
+	if !ok {
+		e.writeJSON(prepared)
+		return
+	}
+
+	e.beginValueObject(valueObject.Type)
+	e.writeJSON(valueObject.Value)
+	e.endValueObject()
+}
+
+func (e *StreamEncoder) encodeOptional(value cadence.Optional) {
+	e.beginValueObject(optionalTypeStr)
+	if value.Value == nil {
+		e.writeString("null")
+	} else {
+		e.encodeValue(value.Value)
+	}
+	e.endValueObject()
+}
+
+func (e *StreamEncoder) encodeArray(value cadence.Array) {
+	e.beginValueObject(arrayTypeStr)
+	e.writeByte('[')
+	for i, element := range value.Values {
+		if i > 0 {
+			e.writeByte(',')
+		}
+		e.encodeValue(element)
+	}
+	e.writeByte(']')
+	e.endValueObject()
+}
+
+func (e *StreamEncoder) encodeDictionary(value cadence.Dictionary) {
+	e.beginValueObject(dictionaryTypeStr)
+	e.writeByte('[')
+	for i, pair := range value.Pairs {
+		if i > 0 {
+			e.writeByte(',')
+		}
+		e.writeByte('{')
+		e.writeKey(keyKey, true)
+		e.encodeValue(pair.Key)
+		e.writeKey(valueKey, false)
+		e.encodeValue(pair.Value)
+		e.writeByte('}')
+	}
+	e.writeByte(']')
+	e.endValueObject()
+}
+
+func (e *StreamEncoder) encodeInclusiveRange(value *cadence.InclusiveRange) {
+	e.beginValueObject(inclusiveRangeTypeStr)
+	e.writeByte('{')
+	e.writeKey(startKey, true)
+	e.encodeValue(value.Start)
+	e.writeKey(endKey, false)
+	e.encodeValue(value.End)
+	e.writeKey(stepKey, false)
+	e.encodeValue(value.Step)
+	e.writeByte('}')
+	e.endValueObject()
+}
+
+func (e *StreamEncoder) encodeComposite(kind, id string, fieldTypes []cadence.Field, fields []cadence.Value) {
+	// Ensure there are _at least _ as many field values as field types.
+	// There might be more field values in the case of attachments.
+	if len(fields) < len(fieldTypes) {
+		panic(fmt.Errorf(
+			"%s field count (%d) does not match declared type (%d)",
+			kind,
+			len(fields),
+			len(fieldTypes),
+		))
+	}
+
+	e.beginValueObject(kind)
+	e.writeByte('{')
+	e.writeKey(idKey, true)
+	e.writeJSON(id)
+	e.writeKey(fieldsKey, false)
+	e.writeByte('[')
+	for i, value := range fields {
+		if i > 0 {
+			e.writeByte(',')
+		}
+
+		var name string
+		// Provide the field name, if the field type is available.
+		// In the case of attachments, they are provided as field values,
+		// but there is no corresponding field type.
+		if i < len(fieldTypes) {
+			name = fieldTypes[i].Identifier
+		}
+
+		e.writeByte('{')
+		e.writeKey(nameKey, true)
+		e.writeJSON(name)
+		e.writeKey(valueKey, false)
+		e.encodeValue(value)
+		e.writeByte('}')
+	}
+	e.writeByte(']')
+	e.writeByte('}')
+	e.endValueObject()
+}
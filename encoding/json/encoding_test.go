@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"strings"
 	"testing"
 	"unicode/utf8"
 
@@ -3328,6 +3329,11 @@ func TestDecodeInvalidType(t *testing.T) {
 func testEncodeAndDecode(t *testing.T, val cadence.Value, expectedJSON string) {
 	actualJSON := testEncode(t, val, expectedJSON)
 	testDecode(t, actualJSON, val)
+
+	// The output of the stream encoder has the types first,
+	// so it is decoded by the stream decoder without buffering
+	streamJSON := testStreamEncode(t, val, expectedJSON)
+	testDecode(t, streamJSON, val)
 }
 
 func testEncode(t *testing.T, val cadence.Value, expectedJSON string) (actualJSON string) {
@@ -3341,6 +3347,18 @@ func testEncode(t *testing.T, val cadence.Value, expectedJSON string) (actualJSO
 	return actualJSON
 }
 
+func testStreamEncode(t *testing.T, val cadence.Value, expectedJSON string) (actualJSON string) {
+	var w strings.Builder
+	err := NewStreamEncoder(&w).Encode(val)
+	require.NoError(t, err)
+
+	actualJSON = w.String()
+
+	assert.JSONEq(t, expectedJSON, actualJSON, fmt.Sprintf("actual: %s", actualJSON))
+
+	return actualJSON
+}
+
 func testDecode(t *testing.T, actualJSON string, expectedVal cadence.Value, options ...Option) {
 	decodedVal, err := Decode(nil, []byte(actualJSON), options...)
 	require.NoError(t, err)
@@ -3350,6 +3368,15 @@ func testDecode(t *testing.T, actualJSON string, expectedVal cadence.Value, opti
 		expectedVal,
 		decodedVal,
 	)
+
+	streamDecodedVal, err := NewStreamDecoder(nil, strings.NewReader(actualJSON), Limits{}, options...).Decode()
+	require.NoError(t, err)
+
+	assert.Equal(
+		t,
+		expectedVal,
+		streamDecodedVal,
+	)
 }
 
 func newFooResourceType() *cadence.ResourceType {
@@ -3681,3 +3708,248 @@ func TestSimpleTypes(t *testing.T) {
 		test(cadenceType, semaType)
 	}
 }
+
+func TestStreamEncoder(t *testing.T) {
+
+	t.Parallel()
+
+	value := cadence.NewArray([]cadence.Value{
+		cadence.NewOptional(cadence.NewInt(1)),
+		cadence.NewOptional(nil),
+		cadence.NewDictionary([]cadence.KeyValuePair{
+			{Key: cadence.String("a"), Value: cadence.NewBool(true)},
+		}),
+	})
+
+	var w strings.Builder
+	err := NewStreamEncoder(&w).Encode(value)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		`{"type":"Array","value":[`+
+			`{"type":"Optional","value":{"type":"Int","value":"1"}},`+
+			`{"type":"Optional","value":null},`+
+			`{"type":"Dictionary","value":[{"key":{"type":"String","value":"a"},"value":{"type":"Bool","value":true}}]}`+
+			`]}`+"\n",
+		w.String(),
+	)
+}
+
+func TestStreamDecoder(t *testing.T) {
+
+	t.Parallel()
+
+	decode := func(json string, limits Limits) (cadence.Value, error) {
+		return NewStreamDecoder(nil, strings.NewReader(json), limits).Decode()
+	}
+
+	// language=json
+	const nestedJSON = `
+      {
+        "value": [
+          {"type": "Int", "value": "1"},
+          {
+            "value": [
+              {
+                "key": {"value": "a", "type": "String"},
+                "value": {"type": "Optional", "value": {"value": "2", "type": "Int"}}
+              }
+            ],
+            "type": "Dictionary"
+          }
+        ],
+        "type": "Array"
+      }
+    `
+
+	t.Run("value before type", func(t *testing.T) {
+		t.Parallel()
+
+		expected, err := Decode(nil, []byte(nestedJSON))
+		require.NoError(t, err)
+
+		actual, err := decode(nestedJSON, Limits{})
+		require.NoError(t, err)
+
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("multiple values", func(t *testing.T) {
+		t.Parallel()
+
+		decoder := NewStreamDecoder(
+			nil,
+			strings.NewReader(`{"type":"Int","value":"1"} {"type":"Bool","value":true}`),
+			Limits{},
+		)
+
+		first, err := decoder.Decode()
+		require.NoError(t, err)
+		assert.Equal(t, cadence.NewInt(1), first)
+
+		second, err := decoder.Decode()
+		require.NoError(t, err)
+		assert.Equal(t, cadence.NewBool(true), second)
+	})
+
+	t.Run("error path", func(t *testing.T) {
+		t.Parallel()
+
+		// language=json
+		const invalidJSON = `
+          {
+            "type": "Struct",
+            "value": {
+              "id": "S.test.Foo",
+              "fields": [
+                {"name": "a", "value": {"type": "Int", "value": "1"}},
+                {"name": "b", "value": {"type": "Array", "value": [{"type": "Int8", "value": "1000"}]}}
+              ]
+            }
+          }
+        `
+
+		_, err := decode(invalidJSON, Limits{})
+		require.EqualError(t,
+			err,
+			"failed to decode JSON-Cadence value at $.value.fields[1].value.value[0].value: invalid Int8: 1000",
+		)
+
+		var decodingErr StreamDecodingError
+		require.ErrorAs(t, err, &decodingErr)
+		assert.Equal(t, "$.value.fields[1].value.value[0].value", decodingErr.Path)
+	})
+
+	t.Run("error path, value before type", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := decode(`{"value": [{"type": "Int"}], "type": "Array"}`, Limits{})
+		require.EqualError(t,
+			err,
+			"failed to decode JSON-Cadence value at $.value[0]: missing property: value",
+		)
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		t.Parallel()
+
+		// The innermost value object, the integer of the optional, is nested at depth 7
+
+		_, err := decode(nestedJSON, Limits{MaxDepth: 7})
+		require.NoError(t, err)
+
+		_, err = decode(nestedJSON, Limits{MaxDepth: 6})
+		require.ErrorIs(t, err, ErrMaxDepthExceeded)
+		require.EqualError(t,
+			err,
+			"failed to decode JSON-Cadence value at $.value[1].value[0].value.value: maximum depth exceeded: 6",
+		)
+	})
+
+	t.Run("Encoder output", func(t *testing.T) {
+		t.Parallel()
+
+		value := cadence.NewArray([]cadence.Value{
+			cadence.NewOptional(cadence.NewInt(1)),
+			cadence.NewTypeValue(&cadence.OptionalType{Type: cadence.IntType}),
+			cadence.NewDictionary([]cadence.KeyValuePair{
+				{
+					Key: cadence.String("a"),
+					Value: cadence.NewArray([]cadence.Value{
+						cadence.NewBool(true),
+					}),
+				},
+			}),
+			cadence.NewStruct([]cadence.Value{
+				cadence.NewInt(2),
+			}).WithType(&cadence.StructType{
+				Location:            utils.TestLocation,
+				QualifiedIdentifier: "Foo",
+				Fields: []cadence.Field{
+					{
+						Identifier: "a",
+						Type:       cadence.IntType,
+					},
+				},
+			}),
+		})
+
+		encoded, err := Encode(value)
+		require.NoError(t, err)
+
+		// The Encoder writes the value of a value object before its type
+		require.True(t, strings.HasPrefix(string(encoded), `{"value":`))
+
+		expected, err := Decode(nil, encoded)
+		require.NoError(t, err)
+
+		// The innermost value object, the boolean in the array of the dictionary, is nested at depth 8
+
+		actual, err := decode(
+			string(encoded),
+			Limits{
+				MaxDepth:    8,
+				MaxElements: 7,
+				MaxBytes:    int64(len(encoded)),
+			},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		// The limits apply while the values are buffered
+
+		_, err = decode(string(encoded), Limits{MaxDepth: 7})
+		require.ErrorIs(t, err, ErrMaxDepthExceeded)
+		require.EqualError(t,
+			err,
+			"failed to decode JSON-Cadence value at $.value[2].value[0].value.value[0]: maximum depth exceeded: 7",
+		)
+
+		_, err = decode(string(encoded), Limits{MaxBytes: int64(len(encoded)) - 10})
+		require.ErrorIs(t, err, ErrMaxBytesExceeded)
+	})
+
+	t.Run("max elements", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := decode(nestedJSON, Limits{MaxElements: 3})
+		require.NoError(t, err)
+
+		_, err = decode(nestedJSON, Limits{MaxElements: 2})
+		require.ErrorIs(t, err, ErrMaxElementsExceeded)
+		require.EqualError(t,
+			err,
+			"failed to decode JSON-Cadence value at $.value[1].value[0]: maximum number of elements exceeded: 2",
+		)
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := decode(nestedJSON, Limits{MaxBytes: int64(len(nestedJSON))})
+		require.NoError(t, err)
+
+		_, err = decode(nestedJSON, Limits{MaxBytes: 100})
+		require.ErrorIs(t, err, ErrMaxBytesExceeded)
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := decode(`{"type": "Array", "value": [`, Limits{})
+		require.EqualError(t,
+			err,
+			"failed to decode JSON-Cadence value at $.value[0]: failed to decode JSON: unexpected end of JSON input",
+		)
+	})
+
+	t.Run("duplicate property", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := decode(`{"type": "Int", "type": "Int", "value": "1"}`, Limits{})
+		require.EqualError(t,
+			err,
+			"failed to decode JSON-Cadence value at $: duplicate property: type",
+		)
+	})
+}
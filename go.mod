@@ -34,7 +34,9 @@ require (
 	github.com/k0kubun/pp v3.0.1+incompatible
 	github.com/kodova/html-to-markdown v1.0.1
 	github.com/onflow/crypto v0.25.0
+	github.com/pmezard/go-difflib v1.0.0
 	golang.org/x/exp v0.0.0-20240103183307-be819d1f06fc
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -50,7 +52,6 @@ require (
 	github.com/mattn/go-tty v0.0.3 // indirect
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
 	github.com/pkg/term v1.2.0-beta.2 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rogpeppe/go-internal v1.9.0 // indirect
 	github.com/x448/float16 v0.8.4 // indirect
 	github.com/zeebo/assert v1.3.0 // indirect
@@ -59,5 +60,4 @@ require (
 	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/term v0.6.0 // indirect
 	gonum.org/v1/gonum v0.6.1 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,1048 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cadence
+
+import (
+	goerrors "errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/onflow/cadence/fixedpoint"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// Marshaler is implemented by Go types which convert themselves to Cadence values.
+type Marshaler interface {
+	MarshalCadence(typ Type) (Value, error)
+}
+
+// Unmarshaler is implemented by Go types which convert Cadence values to themselves.
+type Unmarshaler interface {
+	UnmarshalCadence(value Value) error
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	valueType       = reflect.TypeOf((*Value)(nil)).Elem()
+	bigIntType      = reflect.TypeOf(big.Int{})
+)
+
+// MarshalError is returned by Marshal if a Go value cannot be converted.
+type MarshalError struct {
+	// Path is the path of the Go value in the marshalled value, e.g. `.owner.balances["FLOW"]`.
+	// The path is empty if the marshalled value itself cannot be converted
+	Path string
+	Err  error
+}
+
+func (e *MarshalError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("cadence: cannot marshal value: %s", e.Err)
+	}
+	return fmt.Sprintf("cadence: cannot marshal value at %s: %s", e.Path, e.Err)
+}
+
+func (e *MarshalError) Unwrap() error {
+	return e.Err
+}
+
+// UnmarshalError is returned by Unmarshal if a Cadence value cannot be converted.
+type UnmarshalError struct {
+	// Path is the path of the Cadence value in the unmarshalled value, e.g. `.owner.balances["FLOW"]`.
+	// The path is empty if the unmarshalled value itself cannot be converted
+	Path string
+	Err  error
+}
+
+func (e *UnmarshalError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("cadence: cannot unmarshal value: %s", e.Err)
+	}
+	return fmt.Sprintf("cadence: cannot unmarshal value at %s: %s", e.Path, e.Err)
+}
+
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+// goField is a field of a Go struct which is converted to or from a Cadence composite field
+type goField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// goStructFields returns the fields of the given Go struct type which have a `cadence` tag.
+//
+// The tag specifies the name of the Cadence field, optionally followed by `,omitempty`.
+// Fields of embedded structs without a tag are promoted.
+func goStructFields(structType reflect.Type) []goField {
+	var fields []goField
+
+	for i := 0; i < structType.NumField(); i++ {
+		structField := structType.Field(i)
+
+		tag, ok := structField.Tag.Lookup("cadence")
+
+		if !ok {
+			if structField.Anonymous && structField.Type.Kind() == reflect.Struct {
+				for _, field := range goStructFields(structField.Type) {
+					field.index = append([]int{i}, field.index...)
+					fields = append(fields, field)
+				}
+			}
+			continue
+		}
+
+		if tag == "-" || !structField.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = structField.Name
+		}
+
+		fields = append(fields, goField{
+			name:      name,
+			index:     []int{i},
+			omitEmpty: options == "omitempty",
+		})
+	}
+
+	return fields
+}
+
+// Unmarshal converts the given Cadence value to the Go value pointed to by target.
+//
+// Values are converted recursively:
+//   - Optionals are converted to pointers, or to their inner value.
+//     Nil is converted to the zero value.
+//   - Integers are converted to Go integers, if they are in range, and to big.Int.
+//   - Fixed-point numbers are converted to Go floating-point numbers, and to their string representation.
+//   - Strings and characters are converted to Go strings.
+//   - Addresses are converted to byte arrays, and to their hex string representation.
+//   - Paths are converted to their string representation.
+//   - Arrays are converted to Go slices and arrays, dictionaries to Go maps.
+//   - Composites, e.g. structs, resources, and events, are converted to Go structs.
+//     Each field of the Go struct tagged with `cadence:"name"` is set to the field with the given name.
+//     The field must exist, unless the tag has the `omitempty` option, e.g. `cadence:"name,omitempty"`.
+//   - Enums are converted to Go integers, their raw value, or to Go structs.
+//
+// Cadence values are also stored as-is in Go values of Cadence value types and interfaces, e.g. `any`,
+// and Go types which implement Unmarshaler convert the Cadence value themselves.
+func Unmarshal(value Value, target any) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Pointer || targetValue.IsNil() {
+		return &UnmarshalError{
+			Err: fmt.Errorf("target must be a non-nil pointer, got %T", target),
+		}
+	}
+
+	return unmarshal(value, targetValue.Elem(), "")
+}
+
+func unmarshal(value Value, target reflect.Value, path string) error {
+
+	targetType := target.Type()
+
+	if value == nil {
+		return &UnmarshalError{
+			Path: path,
+			Err:  fmt.Errorf("missing value for Go value of type %s", targetType),
+		}
+	}
+
+	// Unmarshalers convert the value themselves
+
+	if target.CanAddr() && reflect.PointerTo(targetType).Implements(unmarshalerType) {
+		err := target.Addr().Interface().(Unmarshaler).UnmarshalCadence(value)
+		if err != nil {
+			return &UnmarshalError{
+				Path: path,
+				Err:  err,
+			}
+		}
+		return nil
+	}
+
+	// Cadence values are stored as-is, e.g. in `any` or `cadence.Value`
+
+	goValue := reflect.ValueOf(value)
+	if goValue.Type().AssignableTo(targetType) {
+		target.Set(goValue)
+		return nil
+	}
+
+	if optional, ok := value.(Optional); ok {
+		if optional.Value == nil {
+			target.Set(reflect.Zero(targetType))
+			return nil
+		}
+		return unmarshal(optional.Value, target, path)
+	}
+
+	if targetType.Kind() == reflect.Pointer {
+		element := reflect.New(targetType.Elem())
+		err := unmarshal(value, element.Elem(), path)
+		if err != nil {
+			return err
+		}
+		target.Set(element)
+		return nil
+	}
+
+	if targetType == bigIntType {
+		integer, ok := integerValueBig(value)
+		if !ok {
+			return unmarshalTypeError(value, targetType, path)
+		}
+		target.Set(reflect.ValueOf(*new(big.Int).Set(integer)))
+		return nil
+	}
+
+	switch targetType.Kind() {
+	case reflect.Bool:
+		boolValue, ok := value.(Bool)
+		if !ok {
+			return unmarshalTypeError(value, targetType, path)
+		}
+		target.SetBool(bool(boolValue))
+		return nil
+
+	case reflect.String:
+		switch value := value.(type) {
+		case String:
+			target.SetString(string(value))
+		case Character:
+			target.SetString(string(value))
+		case Address, Path, Fix64, UFix64:
+			target.SetString(value.String())
+		default:
+			return unmarshalTypeError(value, targetType, path)
+		}
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+
+		return unmarshalInteger(value, target, path)
+
+	case reflect.Float32, reflect.Float64:
+		var float float64
+		switch value := value.(type) {
+		case Fix64:
+			float = float64(value) / fixedpoint.Fix64Factor
+		case UFix64:
+			float = float64(value) / fixedpoint.Fix64Factor
+		default:
+			return unmarshalTypeError(value, targetType, path)
+		}
+		target.SetFloat(float)
+		return nil
+
+	case reflect.Slice:
+		array, ok := value.(Array)
+		if !ok {
+			return unmarshalTypeError(value, targetType, path)
+		}
+		slice := reflect.MakeSlice(targetType, len(array.Values), len(array.Values))
+		for i, element := range array.Values {
+			err := unmarshal(element, slice.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+
+	case reflect.Array:
+		switch value := value.(type) {
+		case Address:
+			if targetType.Elem().Kind() != reflect.Uint8 || targetType.Len() != AddressLength {
+				return unmarshalTypeError(value, targetType, path)
+			}
+			reflect.Copy(target, reflect.ValueOf(value[:]))
+			return nil
+
+		case Array:
+			if len(value.Values) != targetType.Len() {
+				return &UnmarshalError{
+					Path: path,
+					Err: fmt.Errorf(
+						"cannot unmarshal array with %d elements into Go value of type %s",
+						len(value.Values),
+						targetType,
+					),
+				}
+			}
+			for i, element := range value.Values {
+				err := unmarshal(element, target.Index(i), fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+
+		default:
+			return unmarshalTypeError(value, targetType, path)
+		}
+
+	case reflect.Map:
+		dictionary, ok := value.(Dictionary)
+		if !ok {
+			return unmarshalTypeError(value, targetType, path)
+		}
+		result := reflect.MakeMapWithSize(targetType, len(dictionary.Pairs))
+		for _, pair := range dictionary.Pairs {
+			keyPath := fmt.Sprintf("%s[%s]", path, pair.Key)
+
+			key := reflect.New(targetType.Key()).Elem()
+			err := unmarshal(pair.Key, key, keyPath)
+			if err != nil {
+				return err
+			}
+
+			element := reflect.New(targetType.Elem()).Elem()
+			err = unmarshal(pair.Value, element, keyPath)
+			if err != nil {
+				return err
+			}
+
+			result.SetMapIndex(key, element)
+		}
+		target.Set(result)
+		return nil
+
+	case reflect.Struct:
+		composite, ok := value.(HasFields)
+		if !ok {
+			return unmarshalTypeError(value, targetType, path)
+		}
+		return unmarshalComposite(composite, target, path)
+
+	default:
+		return unmarshalTypeError(value, targetType, path)
+	}
+}
+
+func unmarshalTypeError(value Value, targetType reflect.Type, path string) error {
+	return &UnmarshalError{
+		Path: path,
+		Err: fmt.Errorf(
+			"cannot unmarshal %s into Go value of type %s",
+			valueTypeDescription(value),
+			targetType,
+		),
+	}
+}
+
+func valueTypeDescription(value Value) string {
+	typ := value.Type()
+	if typ == nil {
+		return fmt.Sprintf("%T", value)
+	}
+	return typ.ID()
+}
+
+func unmarshalComposite(composite HasFields, target reflect.Value, path string) error {
+	fields := GetFieldsMappedByName(composite)
+
+	for _, field := range goStructFields(target.Type()) {
+		fieldPath := path + "." + field.name
+
+		fieldValue, ok := fields[field.name]
+		if !ok {
+			if field.omitEmpty {
+				continue
+			}
+			return &UnmarshalError{
+				Path: fieldPath,
+				Err:  fmt.Errorf("field not found"),
+			}
+		}
+
+		err := unmarshal(fieldValue, target.FieldByIndex(field.index), fieldPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unmarshalInteger(value Value, target reflect.Value, path string) error {
+	targetType := target.Type()
+
+	// Enums are converted to their raw value
+	if enum, ok := value.(Enum); ok {
+		rawValue := GetFieldByName(enum, sema.EnumRawValueFieldName)
+		if rawValue == nil {
+			return unmarshalTypeError(value, targetType, path)
+		}
+		value = rawValue
+	}
+
+	integer, ok := integerValueBig(value)
+	if !ok {
+		return unmarshalTypeError(value, targetType, path)
+	}
+
+	switch targetType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !integer.IsInt64() || target.OverflowInt(integer.Int64()) {
+			return unmarshalOverflowError(value, targetType, path)
+		}
+		target.SetInt(integer.Int64())
+
+	default:
+		if !integer.IsUint64() || target.OverflowUint(integer.Uint64()) {
+			return unmarshalOverflowError(value, targetType, path)
+		}
+		target.SetUint(integer.Uint64())
+	}
+
+	return nil
+}
+
+func unmarshalOverflowError(value Value, targetType reflect.Type, path string) error {
+	return &UnmarshalError{
+		Path: path,
+		Err:  fmt.Errorf("value %s overflows Go value of type %s", value, targetType),
+	}
+}
+
+// integerValueBig returns the given integer value as a big integer
+func integerValueBig(value Value) (*big.Int, bool) {
+	switch value := value.(type) {
+	case Int:
+		return value.Big(), true
+	case Int8:
+		return big.NewInt(int64(value)), true
+	case Int16:
+		return big.NewInt(int64(value)), true
+	case Int32:
+		return big.NewInt(int64(value)), true
+	case Int64:
+		return big.NewInt(int64(value)), true
+	case Int128:
+		return value.Big(), true
+	case Int256:
+		return value.Big(), true
+	case UInt:
+		return value.Big(), true
+	case UInt8:
+		return new(big.Int).SetUint64(uint64(value)), true
+	case UInt16:
+		return new(big.Int).SetUint64(uint64(value)), true
+	case UInt32:
+		return new(big.Int).SetUint64(uint64(value)), true
+	case UInt64:
+		return new(big.Int).SetUint64(uint64(value)), true
+	case UInt128:
+		return value.Big(), true
+	case UInt256:
+		return value.Big(), true
+	case Word8:
+		return new(big.Int).SetUint64(uint64(value)), true
+	case Word16:
+		return new(big.Int).SetUint64(uint64(value)), true
+	case Word32:
+		return new(big.Int).SetUint64(uint64(value)), true
+	case Word64:
+		return new(big.Int).SetUint64(uint64(value)), true
+	case Word128:
+		return value.Big(), true
+	case Word256:
+		return value.Big(), true
+	default:
+		return nil, false
+	}
+}
+
+// Marshal converts the given Go value to a Cadence value of the given type.
+//
+// Values are converted recursively, the inverse of Unmarshal:
+//   - Optional values are converted from nil pointers, maps, slices, and interfaces,
+//     and from zero values of struct fields with the `omitempty` option.
+//   - Integers are converted from Go integers and big.Int, if they are in range.
+//   - Fixed-point numbers are converted from Go floating-point numbers and strings.
+//   - Strings and characters are converted from Go strings.
+//   - Addresses are converted from byte arrays and hex strings.
+//   - Paths are converted from strings, e.g. `/storage/foo`.
+//   - Arrays are converted from Go slices and arrays, dictionaries from Go maps.
+//   - Composites, e.g. structs, resources, and events, are converted from Go structs.
+//     Each field of the composite type is converted from the Go struct field tagged with `cadence:"name"`.
+//     Fields of optional type may be missing.
+//   - Enums are converted from Go integers, their raw value, or from Go structs.
+//
+// Cadence values are returned as-is, and Go types which implement Marshaler convert themselves.
+func Marshal(value any, typ Type) (Value, error) {
+	if typ == nil {
+		return nil, &MarshalError{
+			Err: fmt.Errorf("missing type"),
+		}
+	}
+
+	return marshal(reflect.ValueOf(value), typ, "")
+}
+
+func marshal(value reflect.Value, typ Type, path string) (Value, error) {
+
+	// Interfaces are converted using their dynamic value
+
+	for value.IsValid() && value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+
+	if value.IsValid() {
+		if value.Type().Implements(valueType) {
+			return value.Interface().(Value), nil
+		}
+
+		if value.Type().Implements(marshalerType) &&
+			(value.Kind() != reflect.Pointer || !value.IsNil()) {
+
+			result, err := value.Interface().(Marshaler).MarshalCadence(typ)
+			if err != nil {
+				return nil, &MarshalError{
+					Path: path,
+					Err:  err,
+				}
+			}
+			return result, nil
+		}
+	}
+
+	if optionalType, ok := typ.(*OptionalType); ok {
+		if isNilGoValue(value) {
+			return NewOptional(nil), nil
+		}
+		if value.Kind() == reflect.Pointer {
+			value = value.Elem()
+		}
+		inner, err := marshal(value, optionalType.Type, path)
+		if err != nil {
+			return nil, err
+		}
+		return NewOptional(inner), nil
+	}
+
+	if isNilGoValue(value) {
+		return nil, &MarshalError{
+			Path: path,
+			Err:  fmt.Errorf("cannot marshal nil to non-optional type %s", typ.ID()),
+		}
+	}
+
+	if value.Kind() == reflect.Pointer {
+		return marshal(value.Elem(), typ, path)
+	}
+
+	switch typ := typ.(type) {
+	case PrimitiveType:
+		return marshalPrimitive(value, typ, path)
+
+	case *VariableSizedArrayType:
+		return marshalArray(value, typ, typ.ElementType, path)
+
+	case *ConstantSizedArrayType:
+		if (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) &&
+			value.Len() != int(typ.Size) {
+
+			return nil, &MarshalError{
+				Path: path,
+				Err: fmt.Errorf(
+					"cannot marshal %d elements to type %s",
+					value.Len(),
+					typ.ID(),
+				),
+			}
+		}
+		return marshalArray(value, typ, typ.ElementType, path)
+
+	case *DictionaryType:
+		return marshalDictionary(value, typ, path)
+
+	case *EnumType:
+		return marshalEnum(value, typ, path)
+
+	case CompositeType:
+		return marshalComposite(value, typ, path)
+
+	default:
+		return nil, marshalTypeError(value, typ, path)
+	}
+}
+
+func isNilGoValue(value reflect.Value) bool {
+	if !value.IsValid() {
+		return true
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return value.IsNil()
+	default:
+		return false
+	}
+}
+
+func marshalTypeError(value reflect.Value, typ Type, path string) error {
+	return &MarshalError{
+		Path: path,
+		Err: fmt.Errorf(
+			"cannot marshal Go value of type %s to type %s",
+			value.Type(),
+			typ.ID(),
+		),
+	}
+}
+
+func marshalPrimitive(value reflect.Value, typ PrimitiveType, path string) (Value, error) {
+
+	var result Value
+	var err error
+
+	switch typ {
+	case BoolType:
+		if value.Kind() != reflect.Bool {
+			return nil, marshalTypeError(value, typ, path)
+		}
+		result = NewBool(value.Bool())
+
+	case StringType:
+		if value.Kind() != reflect.String {
+			return nil, marshalTypeError(value, typ, path)
+		}
+		result, err = NewString(value.String())
+
+	case CharacterType:
+		if value.Kind() != reflect.String {
+			return nil, marshalTypeError(value, typ, path)
+		}
+		result, err = NewCharacter(value.String())
+
+	case AddressType:
+		result, err = marshalAddress(value, typ, path)
+
+	case PathType, StoragePathType, PublicPathType, PrivatePathType, CapabilityPathType:
+		if value.Kind() != reflect.String {
+			return nil, marshalTypeError(value, typ, path)
+		}
+		result, err = parsePath(value.String(), typ)
+
+	case Fix64Type, UFix64Type:
+		result, err = marshalFixedPoint(value, typ, path)
+
+	case IntType, Int8Type, Int16Type, Int32Type, Int64Type, Int128Type, Int256Type,
+		UIntType, UInt8Type, UInt16Type, UInt32Type, UInt64Type, UInt128Type, UInt256Type,
+		Word8Type, Word16Type, Word32Type, Word64Type, Word128Type, Word256Type:
+
+		integer, ok := goValueBig(value)
+		if !ok {
+			return nil, marshalTypeError(value, typ, path)
+		}
+		result, err = newIntegerValue(integer, typ)
+
+	default:
+		return nil, marshalTypeError(value, typ, path)
+	}
+
+	if err != nil {
+		var marshalError *MarshalError
+		if goerrors.As(err, &marshalError) {
+			return nil, err
+		}
+		return nil, &MarshalError{
+			Path: path,
+			Err:  err,
+		}
+	}
+
+	return result, nil
+}
+
+func marshalAddress(value reflect.Value, typ Type, path string) (Value, error) {
+	switch value.Kind() {
+	case reflect.String:
+		address, err := common.HexToAddress(value.String())
+		if err != nil {
+			return nil, err
+		}
+		return NewAddress(address), nil
+
+	case reflect.Array, reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.Uint8 {
+			return nil, marshalTypeError(value, typ, path)
+		}
+		bytes := make([]byte, value.Len())
+		reflect.Copy(reflect.ValueOf(bytes), value)
+		address, err := common.BytesToAddress(bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewAddress(address), nil
+
+	default:
+		return nil, marshalTypeError(value, typ, path)
+	}
+}
+
+// parsePath parses a path of the form `/domain/identifier`
+func parsePath(s string, typ PrimitiveType) (Value, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 || parts[0] != "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid path: %q", s)
+	}
+
+	domain := common.PathDomainFromIdentifier(parts[1])
+
+	path, err := NewPath(domain, parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	if typ != PathType {
+		pathType := path.Type()
+		if pathType != typ &&
+			!(typ == CapabilityPathType &&
+				(pathType == PublicPathType || pathType == PrivatePathType)) {
+
+			return nil, fmt.Errorf("path %s is not of type %s", s, typ.ID())
+		}
+	}
+
+	return path, nil
+}
+
+func marshalFixedPoint(value reflect.Value, typ PrimitiveType, path string) (Value, error) {
+	var literal string
+
+	switch value.Kind() {
+	case reflect.String:
+		literal = value.String()
+	case reflect.Float32, reflect.Float64:
+		float := value.Float()
+		if math.IsNaN(float) || math.IsInf(float, 0) {
+			return nil, fmt.Errorf("invalid fixed-point number: %v", float)
+		}
+		literal = strconv.FormatFloat(float, 'f', fixedpoint.Fix64Scale, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		literal = strconv.FormatInt(value.Int(), 10) + ".0"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		literal = strconv.FormatUint(value.Uint(), 10) + ".0"
+	default:
+		return nil, marshalTypeError(value, typ, path)
+	}
+
+	if typ == UFix64Type {
+		return NewUFix64(literal)
+	}
+	return NewFix64(literal)
+}
+
+// goValueBig returns the given Go integer as a big integer
+func goValueBig(value reflect.Value) (*big.Int, bool) {
+	if value.Type() == bigIntType {
+		integer := value.Interface().(big.Int)
+		return new(big.Int).Set(&integer), true
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(value.Uint()), true
+	default:
+		return nil, false
+	}
+}
+
+// newIntegerValue returns an integer value of the given type,
+// or an error if the given integer is out of range
+func newIntegerValue(integer *big.Int, typ PrimitiveType) (Value, error) {
+
+	outOfRange := func() error {
+		return fmt.Errorf("value %s is out of range for type %s", integer, typ.ID())
+	}
+
+	int64InRange := func(min, max int64) bool {
+		return integer.IsInt64() &&
+			integer.Int64() >= min &&
+			integer.Int64() <= max
+	}
+
+	uint64InRange := func(max uint64) bool {
+		return integer.IsUint64() && integer.Uint64() <= max
+	}
+
+	var result Value
+	var err error
+
+	switch typ {
+	case IntType:
+		result = NewIntFromBig(integer)
+	case Int8Type:
+		if !int64InRange(math.MinInt8, math.MaxInt8) {
+			return nil, outOfRange()
+		}
+		result = NewInt8(int8(integer.Int64()))
+	case Int16Type:
+		if !int64InRange(math.MinInt16, math.MaxInt16) {
+			return nil, outOfRange()
+		}
+		result = NewInt16(int16(integer.Int64()))
+	case Int32Type:
+		if !int64InRange(math.MinInt32, math.MaxInt32) {
+			return nil, outOfRange()
+		}
+		result = NewInt32(int32(integer.Int64()))
+	case Int64Type:
+		if !integer.IsInt64() {
+			return nil, outOfRange()
+		}
+		result = NewInt64(integer.Int64())
+	case Int128Type:
+		result, err = NewInt128FromBig(integer)
+	case Int256Type:
+		result, err = NewInt256FromBig(integer)
+	case UIntType:
+		result, err = NewUIntFromBig(integer)
+	case UInt8Type, Word8Type:
+		if !uint64InRange(math.MaxUint8) {
+			return nil, outOfRange()
+		}
+		if typ == Word8Type {
+			result = NewWord8(uint8(integer.Uint64()))
+		} else {
+			result = NewUInt8(uint8(integer.Uint64()))
+		}
+	case UInt16Type, Word16Type:
+		if !uint64InRange(math.MaxUint16) {
+			return nil, outOfRange()
+		}
+		if typ == Word16Type {
+			result = NewWord16(uint16(integer.Uint64()))
+		} else {
+			result = NewUInt16(uint16(integer.Uint64()))
+		}
+	case UInt32Type, Word32Type:
+		if !uint64InRange(math.MaxUint32) {
+			return nil, outOfRange()
+		}
+		if typ == Word32Type {
+			result = NewWord32(uint32(integer.Uint64()))
+		} else {
+			result = NewUInt32(uint32(integer.Uint64()))
+		}
+	case UInt64Type, Word64Type:
+		if !integer.IsUint64() {
+			return nil, outOfRange()
+		}
+		if typ == Word64Type {
+			result = NewWord64(integer.Uint64())
+		} else {
+			result = NewUInt64(integer.Uint64())
+		}
+	case UInt128Type:
+		result, err = NewUInt128FromBig(integer)
+	case UInt256Type:
+		result, err = NewUInt256FromBig(integer)
+	case Word128Type:
+		result, err = NewWord128FromBig(integer)
+	case Word256Type:
+		result, err = NewWord256FromBig(integer)
+	default:
+		return nil, fmt.Errorf("unsupported integer type: %s", typ.ID())
+	}
+
+	if err != nil {
+		return nil, outOfRange()
+	}
+
+	return result, nil
+}
+
+func marshalArray(value reflect.Value, typ ArrayType, elementType Type, path string) (Value, error) {
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return nil, marshalTypeError(value, typ, path)
+	}
+
+	count := value.Len()
+	values := make([]Value, count)
+	for i := 0; i < count; i++ {
+		element, err := marshal(value.Index(i), elementType, fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = element
+	}
+
+	return NewArray(values).WithType(typ), nil
+}
+
+func marshalDictionary(value reflect.Value, typ *DictionaryType, path string) (Value, error) {
+	if value.Kind() != reflect.Map {
+		return nil, marshalTypeError(value, typ, path)
+	}
+
+	pairs := make([]KeyValuePair, 0, value.Len())
+
+	iterator := value.MapRange()
+	for iterator.Next() {
+		keyPath := fmt.Sprintf("%s[%s]", path, goMapKeyDescription(iterator.Key()))
+
+		key, err := marshal(iterator.Key(), typ.KeyType, keyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		element, err := marshal(iterator.Value(), typ.ElementType, keyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, KeyValuePair{
+			Key:   key,
+			Value: element,
+		})
+	}
+
+	// Go maps are unordered, sort the pairs to get a deterministic result
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Key.String() < pairs[j].Key.String()
+	})
+
+	return NewDictionary(pairs).WithType(typ), nil
+}
+
+func goMapKeyDescription(key reflect.Value) string {
+	for key.Kind() == reflect.Interface && !key.IsNil() {
+		key = key.Elem()
+	}
+	if key.Kind() == reflect.String {
+		return strconv.Quote(key.String())
+	}
+	return fmt.Sprint(key.Interface())
+}
+
+func marshalEnum(value reflect.Value, typ *EnumType, path string) (Value, error) {
+
+	var fields []Value
+
+	if value.Kind() == reflect.Struct {
+		var err error
+		fields, err = marshalCompositeFields(value, typ, path)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		rawValue, err := marshal(value, typ.RawType, path)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = make([]Value, len(typ.Fields))
+		for i, field := range typ.Fields {
+			if field.Identifier != sema.EnumRawValueFieldName {
+				return nil, marshalTypeError(value, typ, path)
+			}
+			fields[i] = rawValue
+		}
+	}
+
+	return NewEnum(fields).WithType(typ), nil
+}
+
+func marshalComposite(value reflect.Value, typ CompositeType, path string) (Value, error) {
+	if value.Kind() != reflect.Struct {
+		return nil, marshalTypeError(value, typ, path)
+	}
+
+	fields, err := marshalCompositeFields(value, typ, path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ := typ.(type) {
+	case *StructType:
+		return NewStruct(fields).WithType(typ), nil
+	case *ResourceType:
+		return NewResource(fields).WithType(typ), nil
+	case *EventType:
+		return NewEvent(fields).WithType(typ), nil
+	case *ContractType:
+		return NewContract(fields).WithType(typ), nil
+	case *AttachmentType:
+		return NewAttachment(fields).WithType(typ), nil
+	default:
+		return nil, marshalTypeError(value, typ, path)
+	}
+}
+
+func marshalCompositeFields(value reflect.Value, typ CompositeType, path string) ([]Value, error) {
+	goFields := map[string]goField{}
+	for _, field := range goStructFields(value.Type()) {
+		goFields[field.name] = field
+	}
+
+	compositeFields := typ.CompositeFields()
+	values := make([]Value, len(compositeFields))
+
+	for i, field := range compositeFields {
+		fieldPath := path + "." + field.Identifier
+
+		_, isOptional := field.Type.(*OptionalType)
+
+		goField, ok := goFields[field.Identifier]
+		if !ok {
+			if !isOptional {
+				return nil, &MarshalError{
+					Path: fieldPath,
+					Err:  fmt.Errorf("missing Go struct field for field of type %s", field.Type.ID()),
+				}
+			}
+			values[i] = NewOptional(nil)
+			continue
+		}
+
+		fieldValue := value.FieldByIndex(goField.index)
+
+		if isOptional && goField.omitEmpty && fieldValue.IsZero() {
+			values[i] = NewOptional(nil)
+			continue
+		}
+
+		var err error
+		values[i], err = marshal(fieldValue, field.Type, fieldPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,361 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cadence
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+type testMarshalBalance struct {
+	Token  string  `cadence:"token"`
+	Amount float64 `cadence:"amount"`
+}
+
+type testMarshalKind uint8
+
+type testMarshalMetadata struct {
+	Tags []string `cadence:"tags"`
+}
+
+type testMarshalAccount struct {
+	testMarshalMetadata
+	Owner    [8]byte                        `cadence:"owner"`
+	ID       uint64                         `cadence:"id"`
+	Supply   *big.Int                       `cadence:"supply"`
+	Name     *string                        `cadence:"name"`
+	Nickname string                         `cadence:"nickname,omitempty"`
+	Kind     testMarshalKind                `cadence:"kind"`
+	Balances map[string]*testMarshalBalance `cadence:"balances"`
+	Ignored  string
+}
+
+var testMarshalBalanceType = &StructType{
+	Location:            utils.TestLocation,
+	QualifiedIdentifier: "Balance",
+	Fields: []Field{
+		{Identifier: "token", Type: StringType},
+		{Identifier: "amount", Type: UFix64Type},
+	},
+}
+
+var testMarshalKindType = &EnumType{
+	Location:            utils.TestLocation,
+	QualifiedIdentifier: "Kind",
+	RawType:             UInt8Type,
+	Fields: []Field{
+		{Identifier: "rawValue", Type: UInt8Type},
+	},
+}
+
+var testMarshalAccountType = &StructType{
+	Location:            utils.TestLocation,
+	QualifiedIdentifier: "Account",
+	Fields: []Field{
+		{Identifier: "owner", Type: AddressType},
+		{Identifier: "id", Type: UInt64Type},
+		{Identifier: "supply", Type: UInt256Type},
+		{Identifier: "name", Type: NewOptionalType(StringType)},
+		{Identifier: "nickname", Type: NewOptionalType(StringType)},
+		{Identifier: "kind", Type: testMarshalKindType},
+		{Identifier: "tags", Type: NewVariableSizedArrayType(StringType)},
+		{
+			Identifier: "balances",
+			Type:       NewDictionaryType(StringType, testMarshalBalanceType),
+		},
+	},
+}
+
+func newTestMarshalAccountValue() Value {
+	return NewStruct([]Value{
+		NewAddress([8]byte{0, 0, 0, 0, 0, 0, 0, 1}),
+		NewUInt64(42),
+		UInt256{Value: big.NewInt(1_000_000)},
+		NewOptional(String("Alice")),
+		NewOptional(nil),
+		NewEnum([]Value{NewUInt8(2)}).WithType(testMarshalKindType),
+		NewArray([]Value{
+			String("a"),
+			String("b"),
+		}).WithType(NewVariableSizedArrayType(StringType)),
+		NewDictionary([]KeyValuePair{
+			{
+				Key: String("FLOW"),
+				Value: NewStruct([]Value{
+					String("FLOW"),
+					UFix64(150_000_000),
+				}).WithType(testMarshalBalanceType),
+			},
+		}).WithType(NewDictionaryType(StringType, testMarshalBalanceType)),
+	}).WithType(testMarshalAccountType)
+}
+
+func TestUnmarshal(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("composite", func(t *testing.T) {
+		t.Parallel()
+
+		var account testMarshalAccount
+		err := Unmarshal(newTestMarshalAccountValue(), &account)
+		require.NoError(t, err)
+
+		name := "Alice"
+
+		assert.Equal(t,
+			testMarshalAccount{
+				testMarshalMetadata: testMarshalMetadata{
+					Tags: []string{"a", "b"},
+				},
+				Owner:  [8]byte{0, 0, 0, 0, 0, 0, 0, 1},
+				ID:     42,
+				Supply: big.NewInt(1_000_000),
+				Name:   &name,
+				Kind:   2,
+				Balances: map[string]*testMarshalBalance{
+					"FLOW": {
+						Token:  "FLOW",
+						Amount: 1.5,
+					},
+				},
+			},
+			account,
+		)
+	})
+
+	t.Run("scalars", func(t *testing.T) {
+		t.Parallel()
+
+		var i8 int8
+		require.NoError(t, Unmarshal(NewInt(-128), &i8))
+		assert.Equal(t, int8(-128), i8)
+
+		var u uint
+		require.NoError(t, Unmarshal(NewWord64(7), &u))
+		assert.Equal(t, uint(7), u)
+
+		var b big.Int
+		require.NoError(t, Unmarshal(NewInt128(-3), &b))
+		assert.Equal(t, big.NewInt(-3), &b)
+
+		var s string
+		require.NoError(t, Unmarshal(NewAddress([8]byte{0, 0, 0, 0, 0, 0, 0, 1}), &s))
+		assert.Equal(t, "0x0000000000000001", s)
+
+		require.NoError(t, Unmarshal(MustNewPath(common.PathDomainStorage, "foo"), &s))
+		assert.Equal(t, "/storage/foo", s)
+
+		require.NoError(t, Unmarshal(Fix64(-150_000_000), &s))
+		assert.Equal(t, "-1.50000000", s)
+
+		var f float32
+		require.NoError(t, Unmarshal(UFix64(25_000_000), &f))
+		assert.Equal(t, float32(0.25), f)
+
+		var p *bool
+		require.NoError(t, Unmarshal(NewOptional(NewOptional(NewBool(true))), &p))
+		require.NotNil(t, p)
+		assert.True(t, *p)
+
+		require.NoError(t, Unmarshal(NewOptional(nil), &p))
+		assert.Nil(t, p)
+
+		var v Value
+		require.NoError(t, Unmarshal(NewInt(1), &v))
+		assert.Equal(t, NewInt(1), v)
+
+		var a [2]uint8
+		require.NoError(t, Unmarshal(NewArray([]Value{NewUInt8(1), NewUInt8(2)}), &a))
+		assert.Equal(t, [2]uint8{1, 2}, a)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		var account testMarshalAccount
+
+		value := newTestMarshalAccountValue().(Struct)
+		value.Fields[7] = NewDictionary([]KeyValuePair{
+			{
+				Key: String("FLOW"),
+				Value: NewStruct([]Value{
+					NewInt(1),
+					UFix64(1),
+				}).WithType(testMarshalBalanceType),
+			},
+		})
+
+		err := Unmarshal(value, &account)
+		var unmarshalError *UnmarshalError
+		require.ErrorAs(t, err, &unmarshalError)
+		assert.Equal(t, `.balances["FLOW"].token`, unmarshalError.Path)
+		assert.EqualError(t,
+			err,
+			`cadence: cannot unmarshal value at .balances["FLOW"].token: `+
+				`cannot unmarshal Int into Go value of type string`,
+		)
+
+		var i8 int8
+		err = Unmarshal(NewInt(128), &i8)
+		assert.EqualError(t, err, "cadence: cannot unmarshal value: value 128 overflows Go value of type int8")
+
+		var us []uint64
+		err = Unmarshal(NewArray([]Value{NewInt(-1)}), &us)
+		assert.EqualError(t, err, "cadence: cannot unmarshal value at [0]: value -1 overflows Go value of type uint64")
+
+		err = Unmarshal(NewInt(1), account)
+		assert.EqualError(t,
+			err,
+			"cadence: cannot unmarshal value: target must be a non-nil pointer, got cadence.testMarshalAccount",
+		)
+
+		err = Unmarshal(
+			NewStruct(nil).WithType(&StructType{QualifiedIdentifier: "Empty"}),
+			&account,
+		)
+		assert.EqualError(t, err, "cadence: cannot unmarshal value at .tags: field not found")
+	})
+}
+
+func TestMarshal(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("composite", func(t *testing.T) {
+		t.Parallel()
+
+		name := "Alice"
+
+		value, err := Marshal(
+			testMarshalAccount{
+				testMarshalMetadata: testMarshalMetadata{
+					Tags: []string{"a", "b"},
+				},
+				Owner:  [8]byte{0, 0, 0, 0, 0, 0, 0, 1},
+				ID:     42,
+				Supply: big.NewInt(1_000_000),
+				Name:   &name,
+				Kind:   2,
+				Balances: map[string]*testMarshalBalance{
+					"FLOW": {
+						Token:  "FLOW",
+						Amount: 1.5,
+					},
+				},
+			},
+			testMarshalAccountType,
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, newTestMarshalAccountValue(), value)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+
+		var account testMarshalAccount
+		err := Unmarshal(newTestMarshalAccountValue(), &account)
+		require.NoError(t, err)
+
+		value, err := Marshal(account, testMarshalAccountType)
+		require.NoError(t, err)
+
+		assert.Equal(t, newTestMarshalAccountValue(), value)
+	})
+
+	t.Run("scalars", func(t *testing.T) {
+		t.Parallel()
+
+		test := func(goValue any, typ Type, expected Value) {
+			value, err := Marshal(goValue, typ)
+			require.NoError(t, err)
+			assert.Equal(t, expected, value)
+		}
+
+		test(int64(-5), Int8Type, NewInt8(-5))
+		test(uint8(5), IntType, NewInt(5))
+		test(big.NewInt(5), Word128Type, Word128{Value: big.NewInt(5)})
+		test(-1.5, Fix64Type, Fix64(-150_000_000))
+		test("2.5", UFix64Type, UFix64(250_000_000))
+		test(3, UFix64Type, UFix64(300_000_000))
+		test("0x1", AddressType, NewAddress([8]byte{0, 0, 0, 0, 0, 0, 0, 1}))
+		test("/public/foo", CapabilityPathType, MustNewPath(common.PathDomainPublic, "foo"))
+		test("x", CharacterType, Character("x"))
+		test((*int)(nil), NewOptionalType(IntType), NewOptional(nil))
+		test(1, NewOptionalType(IntType), NewOptional(NewInt(1)))
+		test(NewInt(1), AnyStructType, NewInt(1))
+		test(
+			map[string]uint8{"b": 2, "a": 1},
+			NewDictionaryType(StringType, UInt8Type),
+			NewDictionary([]KeyValuePair{
+				{Key: String("a"), Value: NewUInt8(1)},
+				{Key: String("b"), Value: NewUInt8(2)},
+			}).WithType(NewDictionaryType(StringType, UInt8Type)),
+		)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Marshal(
+			testMarshalAccount{
+				Supply: big.NewInt(-1),
+			},
+			testMarshalAccountType,
+		)
+		var marshalError *MarshalError
+		require.ErrorAs(t, err, &marshalError)
+		assert.Equal(t, ".supply", marshalError.Path)
+		assert.EqualError(t,
+			err,
+			"cadence: cannot marshal value at .supply: value -1 is out of range for type UInt256",
+		)
+
+		_, err = Marshal(
+			map[string][]int{"a": {1, 300}},
+			NewDictionaryType(StringType, NewVariableSizedArrayType(UInt8Type)),
+		)
+		assert.EqualError(t,
+			err,
+			`cadence: cannot marshal value at ["a"][1]: value 300 is out of range for type UInt8`,
+		)
+
+		_, err = Marshal(nil, IntType)
+		assert.EqualError(t, err, "cadence: cannot marshal value: cannot marshal nil to non-optional type Int")
+
+		_, err = Marshal(true, StringType)
+		assert.EqualError(t, err, "cadence: cannot marshal value: cannot marshal Go value of type bool to type String")
+
+		_, err = Marshal([]int{1}, NewConstantSizedArrayType(2, IntType))
+		assert.EqualError(t, err, "cadence: cannot marshal value: cannot marshal 1 elements to type [Int;2]")
+
+		_, err = Marshal(struct{}{}, testMarshalBalanceType)
+		assert.EqualError(t,
+			err,
+			"cadence: cannot marshal value at .token: missing Go struct field for field of type String",
+		)
+	})
+}
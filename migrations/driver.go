@@ -0,0 +1,631 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+const defaultDriverBatchSize = 100
+
+// DriverConfig configures a Driver.
+type DriverConfig struct {
+	// Name is the name of the storage migration.
+	// It must be a valid identifier, see NewStorageMigration
+	Name string
+	// Ledger is the ledger which contains the accounts' storage.
+	// It must be safe for concurrent use, as each worker reads and writes it
+	Ledger atree.Ledger
+	// Workers is the number of accounts migrated concurrently.
+	// Defaults to 1
+	Workers int
+	// BatchSize is the number of accounts migrated and committed together.
+	// Defaults to 100
+	BatchSize int
+	// CheckpointPath is the path of the file which records the progress.
+	// The file is a log, to which a line is appended for each committed batch.
+	// If empty, no progress is recorded
+	CheckpointPath string
+	// NewValueMigrations returns the value migrations for a batch of accounts,
+	// given the interpreter and the storage of the worker migrating the batch
+	NewValueMigrations func(inter *interpreter.Interpreter, storage *runtime.Storage) []ValueMigration
+	// NewInterpreter optionally returns the interpreter for a batch of accounts.
+	// Defaults to an interpreter without a program
+	NewInterpreter func(storage *runtime.Storage) (*interpreter.Interpreter, error)
+	// Reporter optionally receives all reports, in addition to the driver's report.
+	// It is called concurrently by the workers
+	Reporter Reporter
+	// DryRun optionally records the changes of the migration instead of committing them.
+	// The checkpoint file is neither read nor written in a dry run
+	DryRun *DryRunRecorder
+}
+
+// Driver migrates the storage of many accounts.
+//
+// Accounts are migrated in batches, which are distributed across workers.
+// Each worker migrates a batch with its own storage and interpreter,
+// and commits the storage after all accounts of the batch got migrated.
+//
+// After a batch got committed, the progress is recorded in the checkpoint file, if any.
+// A run with the same checkpoint file resumes from the recorded progress,
+// i.e. it skips the accounts which were already migrated.
+//
+// The progress is recorded after the batch got committed,
+// so a batch might be migrated again if the process exits in between.
+// Value migrations must therefore be idempotent.
+type Driver struct {
+	config DriverConfig
+}
+
+// NewDriver returns a new driver with the given configuration.
+func NewDriver(config DriverConfig) (*Driver, error) {
+	if config.Ledger == nil {
+		return nil, errors.New("missing ledger")
+	}
+	if config.NewValueMigrations == nil {
+		return nil, errors.New("missing value migrations")
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultDriverBatchSize
+	}
+
+	// Validate the name early, instead of for each account
+	_, err := NewStorageMigration(nil, nil, config.Name, common.ZeroAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Driver{
+		config: config,
+	}, nil
+}
+
+// Run migrates the storage of the given accounts,
+// skipping the accounts recorded as migrated in the checkpoint file.
+//
+// The returned report aggregates the reports of all runs with the same checkpoint file.
+func (d *Driver) Run(ctx context.Context, addresses []common.Address) (*Report, error) {
+
+	checkpoint, err := d.openCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	defer checkpoint.close()
+
+	completed := checkpoint.completed
+
+	// Determine the accounts which still have to be migrated,
+	// in a deterministic order
+
+	pending := make([]common.Address, 0, len(addresses))
+	for _, address := range addresses {
+		if _, ok := completed[address]; ok {
+			continue
+		}
+		// Avoid migrating an account more than once
+		completed[address] = struct{}{}
+		pending = append(pending, address)
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Hex() < pending[j].Hex()
+	})
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan []common.Address)
+
+	go func() {
+		defer close(batches)
+
+		batchSize := d.config.BatchSize
+		for start := 0; start < len(pending); start += batchSize {
+			end := start + batchSize
+			if end > len(pending) {
+				end = len(pending)
+			}
+
+			select {
+			case batches <- pending[start:end]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if firstErr == nil {
+			firstErr = err
+		}
+		cancel()
+	}
+
+	for i := 0; i < d.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for batch := range batches {
+				if ctx.Err() != nil {
+					return
+				}
+
+				report, err := d.migrateBatch(batch)
+				if err != nil {
+					fail(err)
+					return
+				}
+
+				// Record the progress of the committed batch
+
+				err = func() error {
+					mu.Lock()
+					defer mu.Unlock()
+
+					return checkpoint.append(batch, report)
+				}()
+				if err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	// The context may have been cancelled by the caller
+	err = ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint.report.sort()
+
+	return checkpoint.report, nil
+}
+
+func (d *Driver) migrateBatch(addresses []common.Address) (*Report, error) {
+	ledger := d.config.Ledger
+	if d.config.DryRun != nil {
+		// Buffer all changes, so the dry run does not modify the ledger
+		ledger = runtime.NewBufferedLedger(ledger)
+	}
+
+	storage := runtime.NewStorage(ledger, nil)
+
+	var inter *interpreter.Interpreter
+	var err error
+	if d.config.NewInterpreter != nil {
+		inter, err = d.config.NewInterpreter(storage)
+	} else {
+		inter, err = interpreter.NewInterpreter(
+			nil,
+			nil,
+			&interpreter.Config{
+				Storage: storage,
+			},
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create interpreter: %w", err)
+	}
+
+	valueMigrations := d.config.NewValueMigrations(inter, storage)
+
+	report := newReport()
+
+	for _, address := range addresses {
+		migration, err := NewStorageMigration(inter, storage, d.config.Name, address)
+		if err != nil {
+			return nil, err
+		}
+
+		reporter := &accountReporter{
+			address: address,
+			report:  report,
+			next:    d.config.Reporter,
+		}
+
+		if d.config.DryRun != nil {
+			err = d.config.DryRun.Migrate(migration, reporter, valueMigrations...)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			migration.Migrate(
+				migration.NewValueMigrationsPathMigrator(
+					reporter,
+					valueMigrations...,
+				),
+			)
+		}
+
+		report.Accounts++
+	}
+
+	if d.config.DryRun != nil {
+		return report, nil
+	}
+
+	err = storage.Commit(inter, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	return report, nil
+}
+
+// Checkpoint
+
+// driverCheckpointHeader is the first line of a checkpoint file
+type driverCheckpointHeader struct {
+	Name string `json:"name"`
+}
+
+// driverCheckpointEntry is a line of a checkpoint file, which records a committed batch
+type driverCheckpointEntry struct {
+	Completed []string `json:"completed"`
+	Report    *Report  `json:"report"`
+}
+
+// driverCheckpoint is the progress of a driver.
+//
+// The checkpoint file is only appended to,
+// so recording a batch does not rewrite the batches recorded before it
+type driverCheckpoint struct {
+	// file is the checkpoint file, or nil if no progress is recorded
+	file      *os.File
+	completed map[common.Address]struct{}
+	report    *Report
+}
+
+// openCheckpoint reads the checkpoint file, if any,
+// and opens it for appending the batches of this run.
+//
+// The last entry may be incomplete if the process exited while it was written.
+// It is discarded, as the batch was committed but not recorded.
+func (d *Driver) openCheckpoint() (_ *driverCheckpoint, err error) {
+	checkpoint := &driverCheckpoint{
+		completed: map[common.Address]struct{}{},
+		report:    newReport(),
+	}
+
+	path := d.config.CheckpointPath
+	if path == "" || d.config.DryRun != nil {
+		return checkpoint, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+		}
+	}()
+
+	decoder := json.NewDecoder(file)
+
+	var header driverCheckpointHeader
+	err = decoder.Decode(&header)
+	switch {
+	case err == io.EOF:
+		// New checkpoint
+		err = writeCheckpointLine(file, driverCheckpointHeader{
+			Name: d.config.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		checkpoint.file = file
+		return checkpoint, nil
+
+	case err != nil:
+		return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+
+	if header.Name != d.config.Name {
+		return nil, fmt.Errorf(
+			"checkpoint is for migration %s, not %s",
+			header.Name,
+			d.config.Name,
+		)
+	}
+
+	// The offset of the end of the last complete entry
+	offset := decoder.InputOffset()
+
+	for {
+		var entry driverCheckpointEntry
+		err = decoder.Decode(&entry)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
+		}
+
+		for _, hexAddress := range entry.Completed {
+			address, err := common.HexToAddress(hexAddress)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address in checkpoint: %w", err)
+			}
+			checkpoint.completed[address] = struct{}{}
+		}
+
+		if entry.Report != nil {
+			if entry.Report.Migrations == nil {
+				entry.Report.Migrations = map[string]*MigrationCounts{}
+			}
+			checkpoint.report.merge(entry.Report)
+		}
+
+		offset = decoder.InputOffset()
+	}
+
+	// Discard an incomplete last entry, and append after the last complete entry
+
+	err = file.Truncate(offset)
+	if err == nil {
+		_, err = file.Seek(offset, io.SeekStart)
+	}
+	if err == nil {
+		_, err = file.Write([]byte{'\n'})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+
+	checkpoint.file = file
+	return checkpoint, nil
+}
+
+// append records the given committed batch and its report.
+// It must not be called concurrently
+func (c *driverCheckpoint) append(addresses []common.Address, report *Report) error {
+	c.report.merge(report)
+
+	if c.file == nil {
+		return nil
+	}
+
+	completed := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		completed = append(completed, address.HexWithPrefix())
+	}
+
+	report.sort()
+
+	return writeCheckpointLine(c.file, driverCheckpointEntry{
+		Completed: completed,
+		Report:    report,
+	})
+}
+
+func (c *driverCheckpoint) close() {
+	if c.file == nil {
+		return
+	}
+	_ = c.file.Close()
+}
+
+// writeCheckpointLine appends the given value to the checkpoint file as a line of JSON,
+// and flushes the file, so the line is recorded even if the process exits
+func writeCheckpointLine(file *os.File, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	data = append(data, '\n')
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// Report
+
+// Report is the aggregated report of a Driver.
+type Report struct {
+	// Accounts is the number of migrated accounts
+	Accounts int `json:"accounts"`
+	// Migrations maps the names of value migrations to their counts
+	Migrations map[string]*MigrationCounts `json:"migrations"`
+	// Errors are the errors which occurred while migrating values
+	Errors []ReportedError `json:"errors,omitempty"`
+	// DictionaryKeyConflicts are the paths to which the values of conflicting dictionary keys were moved
+	DictionaryKeyConflicts []ReportedDictionaryKeyConflict `json:"dictionaryKeyConflicts,omitempty"`
+}
+
+// MigrationCounts are the counts of a value migration.
+type MigrationCounts struct {
+	// Migrated is the number of stored values which got migrated
+	Migrated int `json:"migrated"`
+	// Errors is the number of errors which occurred
+	Errors int `json:"errors"`
+}
+
+// ReportedError is an error which occurred while migrating a value.
+type ReportedError struct {
+	Address       string `json:"address"`
+	Migration     string `json:"migration,omitempty"`
+	StorageKey    string `json:"storageKey,omitempty"`
+	StorageMapKey string `json:"storageMapKey,omitempty"`
+	Message       string `json:"message"`
+}
+
+// ReportedDictionaryKeyConflict is the path to which the value of a conflicting dictionary key was moved.
+type ReportedDictionaryKeyConflict struct {
+	Address string `json:"address"`
+	Path    string `json:"path"`
+}
+
+func newReport() *Report {
+	return &Report{
+		Migrations: map[string]*MigrationCounts{},
+	}
+}
+
+func (r *Report) counts(migration string) *MigrationCounts {
+	counts, ok := r.Migrations[migration]
+	if !ok {
+		counts = &MigrationCounts{}
+		r.Migrations[migration] = counts
+	}
+	return counts
+}
+
+func (r *Report) merge(other *Report) {
+	r.Accounts += other.Accounts
+
+	// Safe to iterate, as the order does not matter
+	for migration, otherCounts := range other.Migrations { //nolint:maprange
+		counts := r.counts(migration)
+		counts.Migrated += otherCounts.Migrated
+		counts.Errors += otherCounts.Errors
+	}
+
+	r.Errors = append(r.Errors, other.Errors...)
+	r.DictionaryKeyConflicts = append(r.DictionaryKeyConflicts, other.DictionaryKeyConflicts...)
+}
+
+// sort sorts the errors and dictionary key conflicts,
+// so the report is independent of the order in which batches were migrated
+func (r *Report) sort() {
+	sort.SliceStable(r.Errors, func(i, j int) bool {
+		a, b := r.Errors[i], r.Errors[j]
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		if a.StorageKey != b.StorageKey {
+			return a.StorageKey < b.StorageKey
+		}
+		return a.StorageMapKey < b.StorageMapKey
+	})
+
+	sort.SliceStable(r.DictionaryKeyConflicts, func(i, j int) bool {
+		a, b := r.DictionaryKeyConflicts[i], r.DictionaryKeyConflicts[j]
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		return a.Path < b.Path
+	})
+}
+
+// accountReporter is a Reporter which records the reports for an account in a Report,
+// and forwards them to another reporter, if any
+type accountReporter struct {
+	address common.Address
+	report  *Report
+	next    Reporter
+}
+
+var _ Reporter = &accountReporter{}
+
+func (r *accountReporter) Migrated(
+	storageKey interpreter.StorageKey,
+	storageMapKey interpreter.StorageMapKey,
+	migration string,
+) {
+	r.report.counts(migration).Migrated++
+
+	if r.next != nil {
+		r.next.Migrated(storageKey, storageMapKey, migration)
+	}
+}
+
+func (r *accountReporter) DictionaryKeyConflict(addressPath interpreter.AddressPath) {
+	r.report.DictionaryKeyConflicts = append(
+		r.report.DictionaryKeyConflicts,
+		ReportedDictionaryKeyConflict{
+			Address: addressPath.Address.HexWithPrefix(),
+			Path:    addressPath.Path.String(),
+		},
+	)
+
+	if r.next != nil {
+		r.next.DictionaryKeyConflict(addressPath)
+	}
+}
+
+func (r *accountReporter) Error(err error) {
+	reportedError := ReportedError{
+		Address: r.address.HexWithPrefix(),
+	}
+
+	var migrationErr StorageMigrationError
+	if errors.As(err, &migrationErr) {
+		reportedError.Address = migrationErr.StorageKey.Address.HexWithPrefix()
+		reportedError.Migration = migrationErr.Migration
+		reportedError.StorageKey = migrationErr.StorageKey.Key
+		reportedError.StorageMapKey = fmt.Sprint(migrationErr.StorageMapKey)
+		// Exclude the stack trace, which is not useful in the aggregated report
+		reportedError.Message = migrationErr.Err.Error()
+
+		r.report.counts(migrationErr.Migration).Errors++
+	} else {
+		reportedError.Message = err.Error()
+	}
+
+	r.report.Errors = append(r.report.Errors, reportedError)
+
+	if r.next != nil {
+		r.next.Error(err)
+	}
+}
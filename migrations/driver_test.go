@@ -0,0 +1,350 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	. "github.com/onflow/cadence/runtime/tests/runtime_utils"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+// concurrentTestLedger is a ledger which is safe for concurrent use
+type concurrentTestLedger struct {
+	mu     sync.Mutex
+	ledger atree.Ledger
+}
+
+var _ atree.Ledger = &concurrentTestLedger{}
+
+func (l *concurrentTestLedger) GetValue(owner, key []byte) ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ledger.GetValue(owner, key)
+}
+
+func (l *concurrentTestLedger) SetValue(owner, key, value []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ledger.SetValue(owner, key, value)
+}
+
+func (l *concurrentTestLedger) ValueExists(owner, key []byte) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ledger.ValueExists(owner, key)
+}
+
+func (l *concurrentTestLedger) AllocateStorageIndex(owner []byte) (atree.StorageIndex, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ledger.AllocateStorageIndex(owner)
+}
+
+func newDriverTestInterpreter(t *testing.T, storage *runtime.Storage) *interpreter.Interpreter {
+	inter, err := interpreter.NewInterpreter(
+		nil,
+		utils.TestLocation,
+		&interpreter.Config{
+			Storage:                       storage,
+			AtreeValueValidationEnabled:   true,
+			AtreeStorageValidationEnabled: true,
+		},
+	)
+	require.NoError(t, err)
+	return inter
+}
+
+func TestDriver(t *testing.T) {
+
+	t.Parallel()
+
+	const storageDomain = "storage"
+
+	newAddress := func(i int) common.Address {
+		return common.MustBytesToAddress([]byte{byte(i)})
+	}
+
+	const accountCount = 10
+
+	var addresses []common.Address
+	for i := 1; i <= accountCount; i++ {
+		addresses = append(addresses, newAddress(i))
+	}
+
+	// Store a string and an Int8 in each account
+
+	newLedger := func(t *testing.T, addresses []common.Address) atree.Ledger {
+		ledger := &concurrentTestLedger{
+			ledger: NewTestLedger(nil, nil),
+		}
+
+		storage := runtime.NewStorage(ledger, nil)
+		inter := newDriverTestInterpreter(t, storage)
+
+		for _, address := range addresses {
+			inter.WriteStored(
+				address,
+				storageDomain,
+				interpreter.StringStorageMapKey("string"),
+				interpreter.NewUnmeteredStringValue("hello"),
+			)
+			inter.WriteStored(
+				address,
+				storageDomain,
+				interpreter.StringStorageMapKey("int8"),
+				interpreter.NewUnmeteredInt8Value(5),
+			)
+		}
+
+		err := storage.Commit(inter, true)
+		require.NoError(t, err)
+
+		return ledger
+	}
+
+	readString := func(t *testing.T, ledger atree.Ledger, address common.Address) string {
+		storage := runtime.NewStorage(ledger, nil)
+
+		value := storage.GetStorageMap(address, storageDomain, false).
+			ReadValue(nil, interpreter.StringStorageMapKey("string"))
+		require.IsType(t, &interpreter.StringValue{}, value)
+
+		return value.(*interpreter.StringValue).Str
+	}
+
+	newValueMigrations := func(_ *interpreter.Interpreter, _ *runtime.Storage) []ValueMigration {
+		return []ValueMigration{
+			testStringMigration{},
+			testInt8Migration{mustError: true},
+		}
+	}
+
+	t.Run("migrate", func(t *testing.T) {
+		t.Parallel()
+
+		ledger := newLedger(t, addresses)
+
+		driver, err := NewDriver(DriverConfig{
+			Name:               "test",
+			Ledger:             ledger,
+			Workers:            4,
+			BatchSize:          3,
+			NewValueMigrations: newValueMigrations,
+		})
+		require.NoError(t, err)
+
+		report, err := driver.Run(context.Background(), addresses)
+		require.NoError(t, err)
+
+		assert.Equal(t, accountCount, report.Accounts)
+		assert.Equal(t,
+			map[string]*MigrationCounts{
+				"testStringMigration": {
+					Migrated: accountCount,
+				},
+				"testInt8Migration": {
+					Errors: accountCount,
+				},
+			},
+			report.Migrations,
+		)
+
+		require.Len(t, report.Errors, accountCount)
+		for i, reportedError := range report.Errors {
+			assert.Equal(t,
+				ReportedError{
+					Address:       addresses[i].HexWithPrefix(),
+					Migration:     "testInt8Migration",
+					StorageKey:    storageDomain,
+					StorageMapKey: "int8",
+					Message:       "error occurred while migrating int8",
+				},
+				reportedError,
+			)
+		}
+
+		for _, address := range addresses {
+			assert.Equal(t, "updated_hello", readString(t, ledger, address))
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		t.Parallel()
+
+		ledger := newLedger(t, addresses)
+
+		checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+
+		newDriver := func(name string) *Driver {
+			driver, err := NewDriver(DriverConfig{
+				Name:               name,
+				Ledger:             ledger,
+				Workers:            2,
+				BatchSize:          2,
+				CheckpointPath:     checkpointPath,
+				NewValueMigrations: newValueMigrations,
+			})
+			require.NoError(t, err)
+			return driver
+		}
+
+		// Migrate some of the accounts, simulating a crash
+
+		const migratedCount = 4
+
+		_, err := newDriver("test").Run(context.Background(), addresses[:migratedCount])
+		require.NoError(t, err)
+
+		// The checkpoint is a header, followed by an entry for each batch
+
+		data, err := os.ReadFile(checkpointPath)
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		require.Len(t, lines, 1+migratedCount/2)
+
+		var header driverCheckpointHeader
+		err = json.Unmarshal([]byte(lines[0]), &header)
+		require.NoError(t, err)
+		assert.Equal(t, "test", header.Name)
+
+		var completedCount int
+		for _, line := range lines[1:] {
+			var entry driverCheckpointEntry
+			err = json.Unmarshal([]byte(line), &entry)
+			require.NoError(t, err)
+			assert.Len(t, entry.Completed, 2)
+			completedCount += len(entry.Completed)
+		}
+		assert.Equal(t, migratedCount, completedCount)
+
+		// A checkpoint of another migration is rejected
+
+		_, err = newDriver("other").Run(context.Background(), addresses)
+		require.ErrorContains(t, err, "checkpoint is for migration test")
+
+		// Resume. The string migration is not idempotent,
+		// so migrating an account again would be detected
+
+		report, err := newDriver("test").Run(context.Background(), addresses)
+		require.NoError(t, err)
+
+		assert.Equal(t, accountCount, report.Accounts)
+		assert.Equal(t, accountCount, report.Migrations["testStringMigration"].Migrated)
+		assert.Len(t, report.Errors, accountCount)
+
+		for _, address := range addresses {
+			assert.Equal(t, "updated_hello", readString(t, ledger, address))
+		}
+	})
+
+	t.Run("resume after incomplete checkpoint entry", func(t *testing.T) {
+		t.Parallel()
+
+		ledger := newLedger(t, addresses)
+
+		checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+
+		newDriver := func() *Driver {
+			driver, err := NewDriver(DriverConfig{
+				Name:               "test",
+				Ledger:             ledger,
+				BatchSize:          2,
+				CheckpointPath:     checkpointPath,
+				NewValueMigrations: newValueMigrations,
+			})
+			require.NoError(t, err)
+			return driver
+		}
+
+		_, err := newDriver().Run(context.Background(), addresses[:2])
+		require.NoError(t, err)
+
+		// Simulate a crash while an entry was written
+
+		file, err := os.OpenFile(checkpointPath, os.O_APPEND|os.O_WRONLY, 0)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"completed":["0x`)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		// The incomplete entry is discarded
+
+		report, err := newDriver().Run(context.Background(), addresses)
+		require.NoError(t, err)
+
+		assert.Equal(t, accountCount, report.Accounts)
+		assert.Equal(t, accountCount, report.Migrations["testStringMigration"].Migrated)
+
+		for _, address := range addresses {
+			assert.Equal(t, "updated_hello", readString(t, ledger, address))
+		}
+
+		// Resuming again skips all accounts
+
+		report, err = newDriver().Run(context.Background(), addresses)
+		require.NoError(t, err)
+		assert.Equal(t, accountCount, report.Accounts)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ledger := newLedger(t, addresses)
+
+		driver, err := NewDriver(DriverConfig{
+			Name:               "test",
+			Ledger:             ledger,
+			NewValueMigrations: newValueMigrations,
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = driver.Run(ctx, addresses)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewDriver(DriverConfig{
+			Name:               "invalid name",
+			Ledger:             NewTestLedger(nil, nil),
+			NewValueMigrations: newValueMigrations,
+		})
+		require.ErrorContains(t, err, "invalid migration name")
+	})
+}
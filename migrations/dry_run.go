@@ -0,0 +1,499 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrations
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/onflow/cadence"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+// DryRunSummaryMaxEntries is the maximum number of entries listed in the HTML summary.
+// All entries are written to the JSONL report
+const DryRunSummaryMaxEntries = 1000
+
+// DryRunEntry describes how a migration changes the value stored at a path.
+type DryRunEntry struct {
+	Address string `json:"address"`
+	Domain  string `json:"domain"`
+	Key     string `json:"key"`
+	// Migrations are the names of the value migrations which reported the value or nested values as migrated
+	Migrations []string `json:"migrations,omitempty"`
+	// Errors are the errors which occurred while migrating the value or nested values
+	Errors []string `json:"errors,omitempty"`
+	// OldType and NewType are the IDs of the static types of the value before and after the migration
+	OldType string `json:"oldType"`
+	NewType string `json:"newType"`
+	// OldValue and NewValue are the JSON-CDC encodings of the value before and after the migration,
+	// or empty if the value could not be exported
+	OldValue json.RawMessage `json:"oldValue,omitempty"`
+	NewValue json.RawMessage `json:"newValue,omitempty"`
+	// OldValueError and NewValueError are the errors which occurred when exporting the value, if any
+	OldValueError string `json:"oldValueError,omitempty"`
+	NewValueError string `json:"newValueError,omitempty"`
+}
+
+// DryRunRecorder records how migrations change stored values, without committing the changes.
+//
+// Each path which is changed by a migration is written as a DryRunEntry to a JSONL report.
+// WriteHTML writes a summary of all recorded entries.
+//
+// The recorder is safe for concurrent use.
+type DryRunRecorder struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	err     error
+	paths   map[dryRunPath]*dryRunPathReport
+	summary dryRunSummary
+}
+
+type dryRunPath struct {
+	interpreter.StorageKey
+	interpreter.StorageMapKey
+}
+
+type dryRunPathReport struct {
+	migrations []string
+	errors     []string
+}
+
+type dryRunSummary struct {
+	Entries     int
+	Errors      int
+	Migrations  map[string]int
+	TypeChanges map[[2]string]int
+	Samples     []dryRunSample
+}
+
+type dryRunSample struct {
+	DryRunEntry
+	OldValueString string
+	NewValueString string
+}
+
+// NewDryRunRecorder returns a new recorder which writes the JSONL report to the given writer.
+func NewDryRunRecorder(w io.Writer) *DryRunRecorder {
+	return &DryRunRecorder{
+		encoder: json.NewEncoder(w),
+		paths:   map[dryRunPath]*dryRunPathReport{},
+		summary: dryRunSummary{
+			Migrations:  map[string]int{},
+			TypeChanges: map[[2]string]int{},
+		},
+	}
+}
+
+// Migrate performs the given value migrations for the account of the given storage migration,
+// and records the changes. The storage must not be committed afterwards.
+//
+// Migrations may allocate storage indices and write to the ledger even if the storage is not committed.
+// The storage of the migration should therefore use a ledger which buffers changes, see runtime.NewBufferedLedger.
+//
+// All reports are forwarded to the given reporter, if any.
+//
+// An error is returned if the report could not be written.
+func (r *DryRunRecorder) Migrate(
+	migration *StorageMigration,
+	reporter Reporter,
+	valueMigrations ...ValueMigration,
+) error {
+	migrator := migration.NewValueMigrationsPathMigrator(
+		dryRunReporter{
+			recorder: r,
+			next:     reporter,
+		},
+		valueMigrations...,
+	)
+
+	migration.Migrate(
+		dryRunPathMigrator{
+			recorder: r,
+			migrator: migrator,
+		},
+	)
+
+	return r.Err()
+}
+
+// Err returns the first error which occurred when writing the report, if any.
+func (r *DryRunRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+func (r *DryRunRecorder) beginPath(path dryRunPath) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.paths[path] = &dryRunPathReport{}
+}
+
+func (r *DryRunRecorder) migrated(path dryRunPath, migration string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report, ok := r.paths[path]
+	if !ok {
+		return
+	}
+
+	// Nested values are reported individually, only record each migration once
+	for _, existing := range report.migrations {
+		if existing == migration {
+			return
+		}
+	}
+	report.migrations = append(report.migrations, migration)
+}
+
+func (r *DryRunRecorder) error(path dryRunPath, migration string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report, ok := r.paths[path]
+	if !ok {
+		return
+	}
+
+	report.errors = append(report.errors, fmt.Sprintf("%s: %s", migration, err))
+}
+
+func (r *DryRunRecorder) endPath(path dryRunPath, before, after dryRunSnapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := r.paths[path]
+	delete(r.paths, path)
+
+	if len(report.migrations) == 0 &&
+		len(report.errors) == 0 &&
+		before.typeID == after.typeID &&
+		before.exportErr == after.exportErr &&
+		bytes.Equal(before.encoded, after.encoded) {
+
+		return
+	}
+
+	entry := DryRunEntry{
+		Address:       path.Address.HexWithPrefix(),
+		Domain:        path.Key,
+		Key:           fmt.Sprint(path.StorageMapKey),
+		Migrations:    report.migrations,
+		Errors:        report.errors,
+		OldType:       before.typeID,
+		NewType:       after.typeID,
+		OldValue:      before.encoded,
+		NewValue:      after.encoded,
+		OldValueError: before.exportErr,
+		NewValueError: after.exportErr,
+	}
+
+	if r.err == nil {
+		err := r.encoder.Encode(entry)
+		if err != nil {
+			r.err = fmt.Errorf("failed to write dry-run report: %w", err)
+		}
+	}
+
+	summary := &r.summary
+	summary.Entries++
+	if len(entry.Errors) > 0 {
+		summary.Errors++
+	}
+	for _, migration := range entry.Migrations {
+		summary.Migrations[migration]++
+	}
+	if entry.OldType != entry.NewType {
+		summary.TypeChanges[[2]string{entry.OldType, entry.NewType}]++
+	}
+
+	if len(summary.Samples) < DryRunSummaryMaxEntries {
+		summary.Samples = append(
+			summary.Samples,
+			dryRunSample{
+				DryRunEntry:    entry,
+				OldValueString: before.String(),
+				NewValueString: after.String(),
+			},
+		)
+	}
+}
+
+// WriteHTML writes an HTML summary of the recorded entries to the given writer.
+func (r *DryRunRecorder) WriteHTML(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	summary := r.summary
+
+	type count struct {
+		Name  string
+		Count int
+	}
+
+	type typeChange struct {
+		OldType string
+		NewType string
+		Count   int
+	}
+
+	migrations := make([]count, 0, len(summary.Migrations))
+	// Safe to iterate, as the result is sorted
+	for name, n := range summary.Migrations { //nolint:maprange
+		migrations = append(migrations, count{Name: name, Count: n})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Name < migrations[j].Name
+	})
+
+	typeChanges := make([]typeChange, 0, len(summary.TypeChanges))
+	// Safe to iterate, as the result is sorted
+	for types, n := range summary.TypeChanges { //nolint:maprange
+		typeChanges = append(typeChanges, typeChange{OldType: types[0], NewType: types[1], Count: n})
+	}
+	sort.Slice(typeChanges, func(i, j int) bool {
+		a, b := typeChanges[i], typeChanges[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.OldType != b.OldType {
+			return a.OldType < b.OldType
+		}
+		return a.NewType < b.NewType
+	})
+
+	return dryRunHTMLTemplate.Execute(
+		w,
+		struct {
+			Entries     int
+			Errors      int
+			Migrations  []count
+			TypeChanges []typeChange
+			Samples     []dryRunSample
+		}{
+			Entries:     summary.Entries,
+			Errors:      summary.Errors,
+			Migrations:  migrations,
+			TypeChanges: typeChanges,
+			Samples:     summary.Samples,
+		},
+	)
+}
+
+var dryRunHTMLTemplate = template.Must(template.New("dryRun").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Migration dry-run report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; vertical-align: top; }
+td.value { font-family: monospace; white-space: pre-wrap; word-break: break-all; max-width: 40em; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Migration dry-run report</h1>
+<p>{{.Entries}} changed paths, {{.Errors}} paths with errors.</p>
+
+<h2>Migrations</h2>
+<table>
+<tr><th>Migration</th><th>Paths</th></tr>
+{{range .Migrations}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+
+<h2>Type changes</h2>
+<table>
+<tr><th>Old type</th><th>New type</th><th>Paths</th></tr>
+{{range .TypeChanges}}<tr><td>{{.OldType}}</td><td>{{.NewType}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+
+<h2>Changed paths</h2>
+{{if lt (len .Samples) .Entries}}<p>Showing the first {{len .Samples}} of {{.Entries}} changed paths.</p>
+{{end}}<table>
+<tr><th>Address</th><th>Domain</th><th>Key</th><th>Migrations</th><th>Old type</th><th>New type</th><th>Old value</th><th>New value</th></tr>
+{{range .Samples}}<tr>
+<td>{{.Address}}</td>
+<td>{{.Domain}}</td>
+<td>{{.Key}}</td>
+<td>{{range .Migrations}}{{.}}<br>{{end}}{{range .Errors}}<span class="error">{{.}}</span><br>{{end}}</td>
+<td>{{.OldType}}</td>
+<td>{{.NewType}}</td>
+<td class="value">{{.OldValueString}}</td>
+<td class="value">{{.NewValueString}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// dryRunSnapshot is the state of a stored value before or after a migration
+type dryRunSnapshot struct {
+	typeID    string
+	value     cadence.Value
+	encoded   json.RawMessage
+	exportErr string
+}
+
+func (s dryRunSnapshot) String() string {
+	if s.value == nil {
+		return s.exportErr
+	}
+	return s.value.String()
+}
+
+func newDryRunSnapshot(inter *interpreter.Interpreter, value interpreter.Value) (snapshot dryRunSnapshot) {
+	if value == nil {
+		return
+	}
+
+	snapshot.typeID = string(value.StaticType(inter).ID())
+
+	exported, err := exportDryRunValue(inter, value)
+	if err == nil {
+		var encoded []byte
+		encoded, err = jsoncdc.Encode(exported)
+		if err == nil {
+			snapshot.value = exported
+			snapshot.encoded = bytes.TrimSpace(encoded)
+		}
+	}
+	if err != nil {
+		snapshot.exportErr = err.Error()
+	}
+
+	return
+}
+
+func exportDryRunValue(inter *interpreter.Interpreter, value interpreter.Value) (exported cadence.Value, err error) {
+	// Not all stored values can be exported,
+	// e.g. composite values of types which cannot be loaded
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			err, ok = r.(error)
+			if !ok {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	return runtime.ExportValue(value, inter, interpreter.EmptyLocationRange)
+}
+
+// dryRunPathMigrator is a StorageMapKeyMigrator which records the changes of another migrator
+type dryRunPathMigrator struct {
+	recorder *DryRunRecorder
+	migrator StorageMapKeyMigrator
+}
+
+var _ StorageMapKeyMigrator = dryRunPathMigrator{}
+
+func (m dryRunPathMigrator) Migrate(
+	inter *interpreter.Interpreter,
+	storageKey interpreter.StorageKey,
+	storageMap *interpreter.StorageMap,
+	storageMapKey interpreter.StorageMapKey,
+) {
+	path := dryRunPath{
+		StorageKey:    storageKey,
+		StorageMapKey: storageMapKey,
+	}
+
+	// Take the snapshot before migrating,
+	// as migrations may modify the value in place
+	before := newDryRunSnapshot(inter, storageMap.ReadValue(nil, storageMapKey))
+
+	m.recorder.beginPath(path)
+
+	m.migrator.Migrate(inter, storageKey, storageMap, storageMapKey)
+
+	after := newDryRunSnapshot(inter, storageMap.ReadValue(nil, storageMapKey))
+
+	m.recorder.endPath(path, before, after)
+}
+
+func (m dryRunPathMigrator) Domains() map[string]struct{} {
+	return m.migrator.Domains()
+}
+
+// dryRunReporter is a Reporter which records reports in a DryRunRecorder,
+// and forwards them to another reporter, if any
+type dryRunReporter struct {
+	recorder *DryRunRecorder
+	next     Reporter
+}
+
+var _ Reporter = dryRunReporter{}
+
+func (r dryRunReporter) Migrated(
+	storageKey interpreter.StorageKey,
+	storageMapKey interpreter.StorageMapKey,
+	migration string,
+) {
+	r.recorder.migrated(
+		dryRunPath{
+			StorageKey:    storageKey,
+			StorageMapKey: storageMapKey,
+		},
+		migration,
+	)
+
+	if r.next != nil {
+		r.next.Migrated(storageKey, storageMapKey, migration)
+	}
+}
+
+func (r dryRunReporter) DictionaryKeyConflict(addressPath interpreter.AddressPath) {
+	if r.next != nil {
+		r.next.DictionaryKeyConflict(addressPath)
+	}
+}
+
+func (r dryRunReporter) Error(err error) {
+	var migrationErr StorageMigrationError
+	if errors.As(err, &migrationErr) {
+		r.recorder.error(
+			dryRunPath{
+				StorageKey:    migrationErr.StorageKey,
+				StorageMapKey: migrationErr.StorageMapKey,
+			},
+			migrationErr.Migration,
+			// Exclude the stack trace
+			migrationErr.Err,
+		)
+	}
+
+	if r.next != nil {
+		r.next.Error(err)
+	}
+}
@@ -0,0 +1,347 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrations
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	. "github.com/onflow/cadence/runtime/tests/runtime_utils"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+// testInt8ToInt16Migration
+
+type testInt8ToInt16Migration struct{}
+
+var _ ValueMigration = testInt8ToInt16Migration{}
+
+func (testInt8ToInt16Migration) Name() string {
+	return "testInt8ToInt16Migration"
+}
+
+func (testInt8ToInt16Migration) Migrate(
+	_ interpreter.StorageKey,
+	_ interpreter.StorageMapKey,
+	value interpreter.Value,
+	_ *interpreter.Interpreter,
+) (interpreter.Value, error) {
+	int8Value, ok := value.(interpreter.Int8Value)
+	if !ok {
+		return nil, nil
+	}
+
+	return interpreter.NewUnmeteredInt16Value(int16(int8Value)), nil
+}
+
+func (testInt8ToInt16Migration) CanSkip(_ interpreter.StaticType) bool {
+	return false
+}
+
+func (testInt8ToInt16Migration) Domains() map[string]struct{} {
+	return nil
+}
+
+func TestDryRunRecorder(t *testing.T) {
+
+	t.Parallel()
+
+	const storageDomain = "storage"
+
+	address := common.MustBytesToAddress([]byte{0x1})
+
+	newLedger := func(t *testing.T) atree.Ledger {
+		ledger := &concurrentTestLedger{
+			ledger: NewTestLedger(nil, nil),
+		}
+
+		storage := runtime.NewStorage(ledger, nil)
+		inter := newDriverTestInterpreter(t, storage)
+
+		for key, value := range map[string]interpreter.Value{
+			"string": interpreter.NewUnmeteredStringValue("hello"),
+			"int8":   interpreter.NewUnmeteredInt8Value(5),
+			"bool":   interpreter.TrueValue,
+		} {
+			inter.WriteStored(
+				address,
+				storageDomain,
+				interpreter.StringStorageMapKey(key),
+				value,
+			)
+		}
+
+		err := storage.Commit(inter, true)
+		require.NoError(t, err)
+
+		return ledger
+	}
+
+	assertUnchanged := func(t *testing.T, ledger atree.Ledger) {
+		storage := runtime.NewStorage(ledger, nil)
+		storageMap := storage.GetStorageMap(address, storageDomain, false)
+
+		assert.Equal(t,
+			interpreter.NewUnmeteredStringValue("hello"),
+			storageMap.ReadValue(nil, interpreter.StringStorageMapKey("string")),
+		)
+		assert.Equal(t,
+			interpreter.NewUnmeteredInt8Value(5),
+			storageMap.ReadValue(nil, interpreter.StringStorageMapKey("int8")),
+		)
+	}
+
+	readEntries := func(t *testing.T, data []byte) []DryRunEntry {
+		var entries []DryRunEntry
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			var entry DryRunEntry
+			err := json.Unmarshal(scanner.Bytes(), &entry)
+			require.NoError(t, err)
+			entries = append(entries, entry)
+		}
+		require.NoError(t, scanner.Err())
+
+		return entries
+	}
+
+	valueMigrations := []ValueMigration{
+		testStringMigration{},
+		testInt8ToInt16Migration{},
+	}
+
+	t.Run("recorder", func(t *testing.T) {
+		t.Parallel()
+
+		ledger := newLedger(t)
+		storage := runtime.NewStorage(runtime.NewBufferedLedger(ledger), nil)
+		inter := newDriverTestInterpreter(t, storage)
+
+		var report bytes.Buffer
+		recorder := NewDryRunRecorder(&report)
+
+		migration, err := NewStorageMigration(inter, storage, "test", address)
+		require.NoError(t, err)
+
+		reporter := newTestReporter()
+
+		err = recorder.Migrate(migration, reporter, valueMigrations...)
+		require.NoError(t, err)
+
+		// Reports are forwarded
+		assert.Len(t, reporter.migrated, 2)
+
+		entries := readEntries(t, report.Bytes())
+		require.Len(t, entries, 2)
+
+		// Storage map keys are iterated in an unspecified order
+		byKey := map[string]DryRunEntry{}
+		for _, entry := range entries {
+			byKey[entry.Key] = entry
+		}
+
+		assert.Equal(t,
+			DryRunEntry{
+				Address:    address.HexWithPrefix(),
+				Domain:     storageDomain,
+				Key:        "string",
+				Migrations: []string{"testStringMigration"},
+				OldType:    "String",
+				NewType:    "String",
+				OldValue:   json.RawMessage(`{"value":"hello","type":"String"}`),
+				NewValue:   json.RawMessage(`{"value":"updated_hello","type":"String"}`),
+			},
+			byKey["string"],
+		)
+
+		assert.Equal(t,
+			DryRunEntry{
+				Address:    address.HexWithPrefix(),
+				Domain:     storageDomain,
+				Key:        "int8",
+				Migrations: []string{"testInt8ToInt16Migration"},
+				OldType:    "Int8",
+				NewType:    "Int16",
+				OldValue:   json.RawMessage(`{"value":"5","type":"Int8"}`),
+				NewValue:   json.RawMessage(`{"value":"5","type":"Int16"}`),
+			},
+			byKey["int8"],
+		)
+
+		var html strings.Builder
+		err = recorder.WriteHTML(&html)
+		require.NoError(t, err)
+
+		assert.Contains(t, html.String(), "2 changed paths")
+		assert.Contains(t, html.String(), "<td>Int8</td><td>Int16</td><td>1</td>")
+		assert.Contains(t, html.String(), "<td>testStringMigration</td><td>1</td>")
+		assert.Contains(t, html.String(), `&#34;updated_hello&#34;`)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		ledger := newLedger(t)
+		storage := runtime.NewStorage(runtime.NewBufferedLedger(ledger), nil)
+		inter := newDriverTestInterpreter(t, storage)
+
+		var report bytes.Buffer
+		recorder := NewDryRunRecorder(&report)
+
+		migration, err := NewStorageMigration(inter, storage, "test", address)
+		require.NoError(t, err)
+
+		err = recorder.Migrate(migration, nil, testInt8Migration{mustError: true})
+		require.NoError(t, err)
+
+		entries := readEntries(t, report.Bytes())
+		require.Len(t, entries, 1)
+
+		assert.Equal(t,
+			[]string{"testInt8Migration: error occurred while migrating int8"},
+			entries[0].Errors,
+		)
+		assert.Equal(t, entries[0].OldValue, entries[0].NewValue)
+	})
+
+	t.Run("driver", func(t *testing.T) {
+		t.Parallel()
+
+		ledger := newLedger(t)
+
+		var report bytes.Buffer
+		recorder := NewDryRunRecorder(&report)
+
+		checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+
+		driver, err := NewDriver(DriverConfig{
+			Name:           "test",
+			Ledger:         ledger,
+			CheckpointPath: checkpointPath,
+			NewValueMigrations: func(_ *interpreter.Interpreter, _ *runtime.Storage) []ValueMigration {
+				return valueMigrations
+			},
+			DryRun: recorder,
+		})
+		require.NoError(t, err)
+
+		result, err := driver.Run(context.Background(), []common.Address{address})
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, result.Accounts)
+		assert.Len(t, readEntries(t, report.Bytes()), 2)
+
+		// Nothing got committed, and no progress got recorded
+
+		assertUnchanged(t, ledger)
+
+		_, err = os.Stat(checkpointPath)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("ledger unchanged", func(t *testing.T) {
+		t.Parallel()
+
+		testLedger := NewTestLedger(nil, nil)
+
+		// Record the storage index allocations of the dry run
+
+		var allocations int
+		allocateStorageIndex := testLedger.OnAllocateStorageIndex
+		testLedger.OnAllocateStorageIndex = func(owner []byte) (atree.StorageIndex, error) {
+			allocations++
+			return allocateStorageIndex(owner)
+		}
+
+		ledger := &concurrentTestLedger{
+			ledger: testLedger,
+		}
+
+		// Store a composite value, which the container migration replaces
+		// with a new composite value, which allocates a new slab
+
+		storage := runtime.NewStorage(ledger, nil)
+		inter := newDriverTestInterpreter(t, storage)
+
+		inter.WriteStored(
+			address,
+			storageDomain,
+			interpreter.StringStorageMapKey("inner"),
+			interpreter.NewCompositeValue(
+				inter,
+				interpreter.EmptyLocationRange,
+				utils.TestLocation,
+				"Inner",
+				common.CompositeKindStructure,
+				nil,
+				address,
+			),
+		)
+
+		err := storage.Commit(inter, true)
+		require.NoError(t, err)
+
+		storedValues := make(map[string][]byte, len(testLedger.StoredValues))
+		for key, value := range testLedger.StoredValues {
+			storedValues[key] = bytes.Clone(value)
+		}
+		allocations = 0
+
+		var report bytes.Buffer
+		recorder := NewDryRunRecorder(&report)
+
+		driver, err := NewDriver(DriverConfig{
+			Name:   "test",
+			Ledger: ledger,
+			NewValueMigrations: func(_ *interpreter.Interpreter, _ *runtime.Storage) []ValueMigration {
+				return []ValueMigration{
+					testContainerMigration{},
+				}
+			},
+			DryRun: recorder,
+		})
+		require.NoError(t, err)
+
+		_, err = driver.Run(context.Background(), []common.Address{address})
+		require.NoError(t, err)
+
+		entries := readEntries(t, report.Bytes())
+		require.Len(t, entries, 1)
+		assert.Equal(t, "S.test.Inner", entries[0].OldType)
+		assert.Equal(t, "S.test.Inner2", entries[0].NewType)
+
+		assert.Equal(t, storedValues, testLedger.StoredValues)
+		assert.Zero(t, allocations)
+	})
+}
@@ -32,6 +32,9 @@ import (
 
 type ValueMigration interface {
 	Name() string
+	// Migrate returns the migrated value, or nil if the value was not migrated.
+	// Migrations which modify a container value (composite, array, or dictionary) in place
+	// return the given value, so the migration is reported, but the value is not replaced
 	Migrate(
 		storageKey interpreter.StorageKey,
 		storageMapKey interpreter.StorageMapKey,
@@ -387,6 +390,13 @@ func (m *StorageMigration) MigrateNestedValue(
 				))
 			}
 
+			if newKey != nil {
+				// Key was migrated, remove the old key
+				interpreter.StoredValue(inter, existingKeyStorable, m.storage).
+					DeepRemove(inter)
+				inter.RemoveReferencedSlab(existingKeyStorable)
+			}
+
 			if newValue == nil {
 				valueToSet = existingValue
 			} else {
@@ -525,6 +535,17 @@ func (m *StorageMigration) MigrateNestedValue(
 
 		if convertedValue != nil {
 
+			if migratedInPlace(value, convertedValue) {
+				if reporter != nil {
+					reporter.Migrated(
+						storageKey,
+						storageMapKey,
+						migration.Name(),
+					)
+				}
+				continue
+			}
+
 			// Sanity check: ensure that the owner of the new value
 			// is the same as the owner of the old value
 			if ownedValue, ok := value.(interpreter.OwnedValue); ok {
@@ -559,6 +580,26 @@ func (m *StorageMigration) MigrateNestedValue(
 
 }
 
+// migratedInPlace returns true if the migrated value is the given container value itself,
+// i.e. the migration modified the value in place
+func migratedInPlace(value, convertedValue interpreter.Value) bool {
+	switch value := value.(type) {
+	case *interpreter.CompositeValue:
+		convertedComposite, ok := convertedValue.(*interpreter.CompositeValue)
+		return ok && convertedComposite == value
+
+	case *interpreter.ArrayValue:
+		convertedArray, ok := convertedValue.(*interpreter.ArrayValue)
+		return ok && convertedArray == value
+
+	case *interpreter.DictionaryValue:
+		convertedDictionary, ok := convertedValue.(*interpreter.DictionaryValue)
+		return ok && convertedDictionary == value
+	}
+
+	return false
+}
+
 func (m *StorageMigration) nextDictionaryKeyConflictStorageMapKey() interpreter.StringStorageMapKey {
 	m.dictionaryKeyConflicts++
 	return m.DictionaryKeyConflictStorageMapKey(m.dictionaryKeyConflicts)
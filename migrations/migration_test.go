@@ -22,6 +22,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/fxamacker/cbor/v2"
@@ -1623,6 +1624,115 @@ func TestMigratingNestedContainers(t *testing.T) {
 	})
 }
 
+func TestMigratingDictionaryKeyStoredInSeparateSlab(t *testing.T) {
+
+	t.Parallel()
+
+	var testAddress = common.Address{0x42}
+
+	locationRange := interpreter.EmptyLocationRange
+
+	ledger := NewTestLedger(nil, nil)
+	storage := runtime.NewStorage(ledger, nil)
+
+	inter, err := interpreter.NewInterpreter(
+		nil,
+		utils.TestLocation,
+		&interpreter.Config{
+			Storage:                     storage,
+			AtreeValueValidationEnabled: true,
+			// NOTE: disabled, as storage is not expected to be always valid _during_ migration
+			AtreeStorageValidationEnabled: false,
+		},
+	)
+	require.NoError(t, err)
+
+	// The key is too large to be stored inline,
+	// so it is stored in a separate slab
+
+	largeKey := strings.Repeat("a", 1024)
+
+	// {"aaa…": 1234}: {String: Int}
+
+	storedValue := interpreter.NewDictionaryValue(
+		inter,
+		locationRange,
+		interpreter.NewDictionaryStaticType(
+			nil,
+			interpreter.PrimitiveStaticTypeString,
+			interpreter.PrimitiveStaticTypeInt,
+		),
+		interpreter.NewUnmeteredStringValue(largeKey),
+		interpreter.NewUnmeteredIntValueFromInt64(1234),
+	).Transfer(
+		inter,
+		locationRange,
+		atree.Address(testAddress),
+		false,
+		nil,
+		nil,
+	)
+
+	storageMapKey := interpreter.StringStorageMapKey("test_value")
+	storageDomain := common.PathDomainStorage.Identifier()
+
+	inter.WriteStored(
+		testAddress,
+		storageDomain,
+		storageMapKey,
+		storedValue,
+	)
+
+	err = storage.Commit(inter, true)
+	require.NoError(t, err)
+
+	// Migrate
+
+	migration, err := NewStorageMigration(inter, storage, "test", testAddress)
+	require.NoError(t, err)
+
+	reporter := newTestReporter()
+
+	migration.Migrate(
+		migration.NewValueMigrationsPathMigrator(
+			reporter,
+			testStringMigration{},
+		),
+	)
+
+	err = migration.Commit()
+	require.NoError(t, err)
+
+	// Assert
+
+	require.Empty(t, reporter.errors)
+
+	// The slab of the old key must have been removed
+	err = storage.CheckHealth()
+	require.NoError(t, err)
+
+	storageMap := storage.GetStorageMap(testAddress, storageDomain, false)
+	require.NotNil(t, storageMap)
+
+	expected := interpreter.NewDictionaryValue(
+		inter,
+		locationRange,
+		interpreter.NewDictionaryStaticType(
+			nil,
+			interpreter.PrimitiveStaticTypeString,
+			interpreter.PrimitiveStaticTypeInt,
+		),
+		interpreter.NewUnmeteredStringValue("updated_"+largeKey),
+		interpreter.NewUnmeteredIntValueFromInt64(1234),
+	)
+
+	utils.AssertValuesEqual(t,
+		inter,
+		expected,
+		storageMap.ReadValue(nil, storageMapKey),
+	)
+}
+
 // testPanicMigration
 
 type testPanicMigration struct{}
@@ -0,0 +1,184 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package typerewrite
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// Mapping declares how types and the fields of composite values are rewritten.
+//
+// For example, the following mapping renames the composite type Foo.Bar to Foo.Baz,
+// renames the field balance of Foo.Bar values to amount, and drops the field legacy:
+//
+//	types:
+//	  A.0000000000000001.Foo.Bar: A.0000000000000001.Foo.Baz
+//	fields:
+//	  A.0000000000000001.Foo.Bar:
+//	    rename:
+//	      balance: amount
+//	    drop:
+//	      - legacy
+//
+// Mappings can be written in YAML or JSON.
+type Mapping struct {
+	// Types maps old type IDs to new type IDs.
+	// Composite types are rewritten to composite types, interface types to interface types,
+	// and entitlements to entitlements
+	Types map[common.TypeID]common.TypeID `json:"types,omitempty" yaml:"types,omitempty"`
+	// Fields maps the old type IDs of composite types to the rewrites of their fields
+	Fields map[common.TypeID]FieldsMapping `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// FieldsMapping declares how the fields of composite values are rewritten.
+type FieldsMapping struct {
+	// Rename maps old field names to new field names
+	Rename map[string]string `json:"rename,omitempty" yaml:"rename,omitempty"`
+	// Drop lists the names of fields which are removed.
+	// Fields which contain resources cannot be dropped
+	Drop []string `json:"drop,omitempty" yaml:"drop,omitempty"`
+}
+
+// ReadMapping reads a mapping in YAML or JSON format from the given reader,
+// and validates it.
+func ReadMapping(r io.Reader) (Mapping, error) {
+	var mapping Mapping
+
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(&mapping)
+	if err != nil && err != io.EOF {
+		return Mapping{}, fmt.Errorf("failed to decode type rewrite mapping: %w", err)
+	}
+
+	err = mapping.Validate()
+	if err != nil {
+		return Mapping{}, err
+	}
+
+	return mapping, nil
+}
+
+// ReadMappingFile reads a mapping in YAML or JSON format from the file at the given path,
+// and validates it.
+func ReadMappingFile(path string) (Mapping, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Mapping{}, err
+	}
+	defer file.Close()
+
+	return ReadMapping(file)
+}
+
+// Validate checks that all type IDs are valid, qualified type IDs,
+// and that all field names are valid identifiers
+// and each field is either renamed or dropped, but not both.
+func (m Mapping) Validate() error {
+	for _, oldTypeID := range sortedTypeIDs(m.Types) {
+		newTypeID := m.Types[oldTypeID]
+
+		for _, typeID := range []common.TypeID{oldTypeID, newTypeID} {
+			err := validateTypeID(typeID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, typeID := range sortedTypeIDs(m.Fields) {
+		err := validateTypeID(typeID)
+		if err != nil {
+			return err
+		}
+
+		fieldsMapping := m.Fields[typeID]
+
+		newNames := map[string]struct{}{}
+
+		for _, oldName := range sortedKeys(fieldsMapping.Rename) {
+			newName := fieldsMapping.Rename[oldName]
+
+			for _, name := range []string{oldName, newName} {
+				if !lexer.IsValidIdentifier(name) {
+					return fmt.Errorf("invalid field name for type %s: %q", typeID, name)
+				}
+			}
+
+			if _, ok := newNames[newName]; ok {
+				return fmt.Errorf("field of type %s renamed more than once to %s", typeID, newName)
+			}
+			newNames[newName] = struct{}{}
+		}
+
+		for _, name := range fieldsMapping.Drop {
+			if !lexer.IsValidIdentifier(name) {
+				return fmt.Errorf("invalid field name for type %s: %q", typeID, name)
+			}
+
+			if _, ok := fieldsMapping.Rename[name]; ok {
+				return fmt.Errorf("field %s of type %s is both renamed and dropped", name, typeID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateTypeID(typeID common.TypeID) error {
+	location, qualifiedIdentifier, err := common.DecodeTypeID(nil, string(typeID))
+	if err != nil {
+		return fmt.Errorf("invalid type ID %q: %w", typeID, err)
+	}
+	if location == nil || qualifiedIdentifier == "" {
+		return fmt.Errorf("invalid type ID %q: missing location or qualified identifier", typeID)
+	}
+	return nil
+}
+
+func sortedTypeIDs[T any](m map[common.TypeID]T) []common.TypeID {
+	typeIDs := make([]common.TypeID, 0, len(m))
+	// Safe to iterate, as the result is sorted
+	for typeID := range m { //nolint:maprange
+		typeIDs = append(typeIDs, typeID)
+	}
+	sort.Slice(typeIDs, func(i, j int) bool {
+		return typeIDs[i] < typeIDs[j]
+	})
+	return typeIDs
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	// Safe to iterate, as the result is sorted
+	for key := range m { //nolint:maprange
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
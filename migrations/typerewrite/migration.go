@@ -0,0 +1,555 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package typerewrite provides a value migration which rewrites types and the fields of composite values
+// as declared by a Mapping, so routine contract refactors do not need bespoke migration code.
+package typerewrite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/migrations"
+	"github.com/onflow/cadence/migrations/statictypes"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+// attachmentFieldNamePrefix is the prefix of the names of the fields
+// in which attachments are stored, followed by the type ID of the attachment
+const attachmentFieldNamePrefix = "$"
+
+type rewrittenType struct {
+	location            common.Location
+	qualifiedIdentifier string
+	typeID              common.TypeID
+}
+
+type TypeRewriteMigration struct {
+	types  map[common.TypeID]rewrittenType
+	fields map[common.TypeID]FieldsMapping
+}
+
+var _ migrations.ValueMigration = &TypeRewriteMigration{}
+
+// NewTypeRewriteMigration returns a new migration which rewrites types and fields
+// as declared by the given mapping.
+func NewTypeRewriteMigration(mapping Mapping) (*TypeRewriteMigration, error) {
+	err := mapping.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[common.TypeID]rewrittenType, len(mapping.Types))
+
+	// Safe to iterate, as the order does not matter
+	for oldTypeID, newTypeID := range mapping.Types { //nolint:maprange
+		location, qualifiedIdentifier, err := common.DecodeTypeID(nil, string(newTypeID))
+		if err != nil {
+			return nil, err
+		}
+		types[oldTypeID] = rewrittenType{
+			location:            location,
+			qualifiedIdentifier: qualifiedIdentifier,
+			typeID:              newTypeID,
+		}
+	}
+
+	return &TypeRewriteMigration{
+		types:  types,
+		fields: mapping.Fields,
+	}, nil
+}
+
+func (*TypeRewriteMigration) Name() string {
+	return "TypeRewriteMigration"
+}
+
+func (*TypeRewriteMigration) Domains() map[string]struct{} {
+	return nil
+}
+
+func (m *TypeRewriteMigration) CanSkip(valueType interpreter.StaticType) bool {
+	return statictypes.CanSkipStaticTypeMigration(valueType)
+}
+
+// Migrate rewrites the type and fields of composite values,
+// and the static types in type values, capabilities, capability controllers, path links,
+// arrays, and dictionaries.
+func (m *TypeRewriteMigration) Migrate(
+	_ interpreter.StorageKey,
+	_ interpreter.StorageMapKey,
+	value interpreter.Value,
+	inter *interpreter.Interpreter,
+) (newValue interpreter.Value, err error) {
+	switch value := value.(type) {
+	case *interpreter.CompositeValue:
+		return m.migrateComposite(value, inter)
+
+	case interpreter.TypeValue:
+		// Type is optional. nil represents "unknown"/"invalid" type
+		ty := value.Type
+		if ty == nil {
+			return
+		}
+		convertedType := m.rewriteStaticType(ty)
+		if convertedType == nil {
+			return
+		}
+		return interpreter.NewTypeValue(nil, convertedType), nil
+
+	case *interpreter.IDCapabilityValue:
+		convertedBorrowType := m.rewriteStaticType(value.BorrowType)
+		if convertedBorrowType == nil {
+			return
+		}
+		return interpreter.NewUnmeteredCapabilityValue(value.ID, value.Address, convertedBorrowType), nil
+
+	case *interpreter.PathCapabilityValue: //nolint:staticcheck
+		// Type is optional
+		borrowType := value.BorrowType
+		if borrowType == nil {
+			return
+		}
+		convertedBorrowType := m.rewriteStaticType(borrowType)
+		if convertedBorrowType == nil {
+			return
+		}
+		return &interpreter.PathCapabilityValue{ //nolint:staticcheck
+			BorrowType: convertedBorrowType,
+			Path:       value.Path,
+			Address:    value.Address,
+		}, nil
+
+	case interpreter.PathLinkValue: //nolint:staticcheck
+		convertedBorrowType := m.rewriteStaticType(value.Type)
+		if convertedBorrowType == nil {
+			return
+		}
+		return interpreter.PathLinkValue{ //nolint:staticcheck
+			Type:       convertedBorrowType,
+			TargetPath: value.TargetPath,
+		}, nil
+
+	case *interpreter.AccountCapabilityControllerValue:
+		convertedBorrowType := m.rewriteStaticType(value.BorrowType)
+		if convertedBorrowType == nil {
+			return
+		}
+		borrowType := convertedBorrowType.(*interpreter.ReferenceStaticType)
+		return interpreter.NewUnmeteredAccountCapabilityControllerValue(borrowType, value.CapabilityID), nil
+
+	case *interpreter.StorageCapabilityControllerValue:
+		convertedBorrowType := m.rewriteStaticType(value.BorrowType)
+		if convertedBorrowType == nil {
+			return
+		}
+		borrowType := convertedBorrowType.(*interpreter.ReferenceStaticType)
+		return interpreter.NewUnmeteredStorageCapabilityControllerValue(
+			borrowType,
+			value.CapabilityID,
+			value.TargetPath,
+		), nil
+
+	case *interpreter.ArrayValue:
+		convertedElementType := m.rewriteStaticType(value.Type)
+		if convertedElementType == nil {
+			return
+		}
+
+		value.SetType(
+			convertedElementType.(interpreter.ArrayStaticType),
+		)
+
+		// The type was rewritten in place
+		return value, nil
+
+	case *interpreter.DictionaryValue:
+		convertedElementType := m.rewriteStaticType(value.Type)
+		if convertedElementType == nil {
+			return
+		}
+
+		value.SetType(
+			convertedElementType.(*interpreter.DictionaryStaticType),
+		)
+
+		// The type was rewritten in place
+		return value, nil
+	}
+
+	return
+}
+
+// migrateComposite rewrites the fields and the type of the given composite value.
+//
+// Like the types of arrays and dictionaries, composite values are rewritten in place,
+// as they may be resources, which cannot be replaced.
+// The given value is returned, so the migration is reported.
+func (m *TypeRewriteMigration) migrateComposite(
+	value *interpreter.CompositeValue,
+	inter *interpreter.Interpreter,
+) (
+	interpreter.Value,
+	error,
+) {
+	oldTypeID := value.TypeID()
+
+	location := value.Location
+	qualifiedIdentifier := value.QualifiedIdentifier
+
+	newType, typeRewritten := m.types[oldTypeID]
+	if typeRewritten {
+		location = newType.location
+		qualifiedIdentifier = newType.qualifiedIdentifier
+	}
+
+	fieldsMapping := m.fields[oldTypeID]
+
+	// Read the field names first, so the iteration is not affected by the removal of the fields
+
+	var fieldNames []string
+	value.ForEachFieldName(func(fieldName string) (resume bool) {
+		fieldNames = append(fieldNames, fieldName)
+		return true
+	})
+
+	drop := make(map[string]struct{}, len(fieldsMapping.Drop))
+	for _, name := range fieldsMapping.Drop {
+		drop[name] = struct{}{}
+	}
+
+	newFieldNames := make(map[string]string, len(fieldNames))
+	fieldsRewritten := false
+
+	for _, fieldName := range fieldNames {
+		if _, ok := drop[fieldName]; ok {
+			fieldsRewritten = true
+			continue
+		}
+
+		newFieldName := m.rewriteFieldName(fieldsMapping, fieldName)
+		if newFieldName != fieldName {
+			fieldsRewritten = true
+		}
+		newFieldNames[fieldName] = newFieldName
+	}
+
+	if !typeRewritten && !fieldsRewritten {
+		return nil, nil
+	}
+
+	// Validate before modifying the value,
+	// so a failed migration leaves the value unchanged
+
+	seenFieldNames := make(map[string]string, len(newFieldNames))
+
+	for _, fieldName := range fieldNames {
+		if _, ok := drop[fieldName]; ok {
+			fieldValue := value.GetField(inter, emptyLocationRange, fieldName)
+			if fieldValue.IsResourceKinded(inter) {
+				return nil, fmt.Errorf(
+					"cannot drop field %s of %s: field contains a resource",
+					fieldName,
+					oldTypeID,
+				)
+			}
+			continue
+		}
+
+		newFieldName := newFieldNames[fieldName]
+		if otherFieldName, ok := seenFieldNames[newFieldName]; ok {
+			return nil, fmt.Errorf(
+				"cannot rewrite fields of %s: fields %s and %s are both rewritten to %s",
+				oldTypeID,
+				otherFieldName,
+				fieldName,
+				newFieldName,
+			)
+		}
+		seenFieldNames[newFieldName] = fieldName
+	}
+
+	owner := value.GetOwner()
+
+	// Enum values may be dictionary keys, and the hash of a dictionary key depends on its type.
+	// Return a new value, so the migration framework re-inserts the key,
+	// instead of changing the key in place
+
+	if value.Kind == common.CompositeKindEnum {
+		fields := make([]interpreter.CompositeField, 0, len(newFieldNames))
+
+		for _, fieldName := range fieldNames {
+			if _, ok := drop[fieldName]; ok {
+				continue
+			}
+
+			fieldValue := value.GetField(inter, emptyLocationRange, fieldName).
+				Transfer(inter, emptyLocationRange, atree.Address(owner), false, nil, nil)
+
+			fields = append(
+				fields,
+				interpreter.NewUnmeteredCompositeField(newFieldNames[fieldName], fieldValue),
+			)
+		}
+
+		return interpreter.NewCompositeValue(
+			inter,
+			emptyLocationRange,
+			location,
+			qualifiedIdentifier,
+			value.Kind,
+			fields,
+			owner,
+		), nil
+	}
+
+	// Drop and rename the fields in place.
+	// Remove all renamed fields before setting them, so renamed fields may swap names
+
+	renamedFields := make([]interpreter.CompositeField, 0, len(newFieldNames))
+
+	for _, fieldName := range fieldNames {
+		if _, ok := drop[fieldName]; ok {
+			value.RemoveField(inter, emptyLocationRange, fieldName)
+			continue
+		}
+
+		newFieldName := newFieldNames[fieldName]
+		if newFieldName == fieldName {
+			continue
+		}
+
+		fieldValue := value.RemoveMember(inter, emptyLocationRange, fieldName)
+
+		renamedFields = append(
+			renamedFields,
+			interpreter.NewUnmeteredCompositeField(newFieldName, fieldValue),
+		)
+	}
+
+	for _, field := range renamedFields {
+		value.SetMember(inter, emptyLocationRange, field.Name, field.Value)
+	}
+
+	if typeRewritten {
+		value.SetType(location, qualifiedIdentifier)
+	}
+
+	return value, nil
+}
+
+// rewriteFieldName returns the new name of the given field.
+// Attachments are stored in fields named after the type of the attachment,
+// so their fields are renamed when the attachment type is rewritten
+func (m *TypeRewriteMigration) rewriteFieldName(fieldsMapping FieldsMapping, fieldName string) string {
+	if newFieldName, ok := fieldsMapping.Rename[fieldName]; ok {
+		return newFieldName
+	}
+
+	if strings.HasPrefix(fieldName, attachmentFieldNamePrefix) {
+		attachmentTypeID := common.TypeID(strings.TrimPrefix(fieldName, attachmentFieldNamePrefix))
+		if newType, ok := m.types[attachmentTypeID]; ok {
+			return attachmentFieldNamePrefix + string(newType.typeID)
+		}
+	}
+
+	return fieldName
+}
+
+var emptyLocationRange = interpreter.EmptyLocationRange
+
+// rewriteStaticType returns the rewritten static type,
+// or nil if the static type does not need to be rewritten.
+func (m *TypeRewriteMigration) rewriteStaticType(staticType interpreter.StaticType) interpreter.StaticType {
+	switch staticType := staticType.(type) {
+	case *interpreter.ConstantSizedStaticType:
+		convertedType := m.rewriteStaticType(staticType.Type)
+		if convertedType != nil {
+			return interpreter.NewConstantSizedStaticType(nil, convertedType, staticType.Size)
+		}
+
+	case *interpreter.VariableSizedStaticType:
+		convertedType := m.rewriteStaticType(staticType.Type)
+		if convertedType != nil {
+			return interpreter.NewVariableSizedStaticType(nil, convertedType)
+		}
+
+	case *interpreter.DictionaryStaticType:
+		convertedKeyType := m.rewriteStaticType(staticType.KeyType)
+		convertedValueType := m.rewriteStaticType(staticType.ValueType)
+		if convertedKeyType == nil && convertedValueType == nil {
+			return nil
+		}
+		if convertedKeyType == nil {
+			convertedKeyType = staticType.KeyType
+		}
+		if convertedValueType == nil {
+			convertedValueType = staticType.ValueType
+		}
+		return interpreter.NewDictionaryStaticType(nil, convertedKeyType, convertedValueType)
+
+	case *interpreter.OptionalStaticType:
+		convertedInnerType := m.rewriteStaticType(staticType.Type)
+		if convertedInnerType != nil {
+			return interpreter.NewOptionalStaticType(nil, convertedInnerType)
+		}
+
+	case *interpreter.CapabilityStaticType:
+		borrowType := staticType.BorrowType
+		if borrowType != nil {
+			convertedBorrowType := m.rewriteStaticType(borrowType)
+			if convertedBorrowType != nil {
+				return interpreter.NewCapabilityStaticType(nil, convertedBorrowType)
+			}
+		}
+
+	case *interpreter.ReferenceStaticType:
+		convertedAuthorization := m.rewriteAuthorization(staticType.Authorization)
+		convertedReferencedType := m.rewriteStaticType(staticType.ReferencedType)
+		if convertedAuthorization == nil && convertedReferencedType == nil {
+			return nil
+		}
+		if convertedAuthorization == nil {
+			convertedAuthorization = staticType.Authorization
+		}
+		if convertedReferencedType == nil {
+			convertedReferencedType = staticType.ReferencedType
+		}
+		return interpreter.NewReferenceStaticType(
+			nil,
+			convertedAuthorization,
+			convertedReferencedType,
+		)
+
+	case *interpreter.IntersectionStaticType:
+		var convertedInterfaceTypes []*interpreter.InterfaceStaticType
+		converted := false
+
+		for index, interfaceType := range staticType.Types {
+			convertedType := m.rewriteStaticType(interfaceType)
+			if convertedType == nil {
+				if convertedInterfaceTypes != nil {
+					convertedInterfaceTypes = append(convertedInterfaceTypes, interfaceType)
+				}
+				continue
+			}
+
+			// lazily allocate the slice
+			if convertedInterfaceTypes == nil {
+				convertedInterfaceTypes = make([]*interpreter.InterfaceStaticType, 0, len(staticType.Types))
+				convertedInterfaceTypes = append(convertedInterfaceTypes, staticType.Types[:index]...)
+			}
+			convertedInterfaceTypes = append(
+				convertedInterfaceTypes,
+				convertedType.(*interpreter.InterfaceStaticType),
+			)
+			converted = true
+		}
+
+		legacyType := staticType.LegacyType
+		if legacyType != nil {
+			convertedLegacyType := m.rewriteStaticType(legacyType)
+			if convertedLegacyType != nil {
+				legacyType = convertedLegacyType
+				converted = true
+			}
+		}
+
+		if !converted {
+			return nil
+		}
+
+		if convertedInterfaceTypes == nil {
+			convertedInterfaceTypes = staticType.Types
+		}
+
+		result := interpreter.NewIntersectionStaticType(nil, convertedInterfaceTypes)
+		result.LegacyType = legacyType
+		return result
+
+	case *interpreter.CompositeStaticType:
+		newType, ok := m.types[staticType.TypeID]
+		if ok {
+			return interpreter.NewCompositeStaticType(
+				nil,
+				newType.location,
+				newType.qualifiedIdentifier,
+				newType.typeID,
+			)
+		}
+
+	case *interpreter.InterfaceStaticType:
+		newType, ok := m.types[staticType.TypeID]
+		if ok {
+			return interpreter.NewInterfaceStaticType(
+				nil,
+				newType.location,
+				newType.qualifiedIdentifier,
+				newType.typeID,
+			)
+		}
+
+	case interpreter.FunctionStaticType,
+		interpreter.InclusiveRangeStaticType,
+		interpreter.PrimitiveStaticType:
+		// Do not contain any user-defined types
+	}
+
+	return nil
+}
+
+// rewriteAuthorization returns the rewritten authorization,
+// or nil if the authorization does not need to be rewritten.
+func (m *TypeRewriteMigration) rewriteAuthorization(authorization interpreter.Authorization) interpreter.Authorization {
+	switch authorization := authorization.(type) {
+	case interpreter.EntitlementSetAuthorization:
+		var entitlements []common.TypeID
+		converted := false
+
+		authorization.Entitlements.Foreach(func(entitlement common.TypeID, _ struct{}) {
+			if newType, ok := m.types[entitlement]; ok {
+				entitlement = newType.typeID
+				converted = true
+			}
+			entitlements = append(entitlements, entitlement)
+		})
+
+		if !converted {
+			return nil
+		}
+
+		return interpreter.NewEntitlementSetAuthorization(
+			nil,
+			func() []common.TypeID {
+				return entitlements
+			},
+			len(entitlements),
+			authorization.SetKind,
+		)
+
+	case interpreter.EntitlementMapAuthorization:
+		if newType, ok := m.types[authorization.TypeID]; ok {
+			return interpreter.NewEntitlementMapAuthorization(nil, newType.typeID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,620 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package typerewrite
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/migrations"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	. "github.com/onflow/cadence/runtime/tests/runtime_utils"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+var testAddress = common.MustBytesToAddress([]byte{0x1})
+
+var fooLocation = common.NewAddressLocation(nil, testAddress, "Foo")
+
+func fooTypeID(qualifiedIdentifier string) common.TypeID {
+	return fooLocation.TypeID(nil, "Foo."+qualifiedIdentifier)
+}
+
+func fooCompositeType(qualifiedIdentifier string) *interpreter.CompositeStaticType {
+	return interpreter.NewCompositeStaticType(
+		nil,
+		fooLocation,
+		"Foo."+qualifiedIdentifier,
+		fooTypeID(qualifiedIdentifier),
+	)
+}
+
+func fooInterfaceType(qualifiedIdentifier string) *interpreter.InterfaceStaticType {
+	return interpreter.NewInterfaceStaticType(
+		nil,
+		fooLocation,
+		"Foo."+qualifiedIdentifier,
+		fooTypeID(qualifiedIdentifier),
+	)
+}
+
+type testReporter struct {
+	migrated []interpreter.StorageMapKey
+	errors   []error
+}
+
+var _ migrations.Reporter = &testReporter{}
+
+func (t *testReporter) Migrated(
+	_ interpreter.StorageKey,
+	storageMapKey interpreter.StorageMapKey,
+	_ string,
+) {
+	t.migrated = append(t.migrated, storageMapKey)
+}
+
+func (t *testReporter) DictionaryKeyConflict(_ interpreter.AddressPath) {
+	// NO-OP
+}
+
+func (t *testReporter) Error(err error) {
+	t.errors = append(t.errors, err)
+}
+
+func TestReadMapping(t *testing.T) {
+
+	t.Parallel()
+
+	expected := Mapping{
+		Types: map[common.TypeID]common.TypeID{
+			"A.0000000000000001.Foo.Bar": "A.0000000000000001.Foo.Baz",
+		},
+		Fields: map[common.TypeID]FieldsMapping{
+			"A.0000000000000001.Foo.Bar": {
+				Rename: map[string]string{
+					"balance": "amount",
+				},
+				Drop: []string{"legacy"},
+			},
+		},
+	}
+
+	t.Run("YAML", func(t *testing.T) {
+		t.Parallel()
+
+		mapping, err := ReadMapping(strings.NewReader(`
+types:
+  A.0000000000000001.Foo.Bar: A.0000000000000001.Foo.Baz
+fields:
+  A.0000000000000001.Foo.Bar:
+    rename:
+      balance: amount
+    drop:
+      - legacy
+`))
+		require.NoError(t, err)
+		assert.Equal(t, expected, mapping)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		mapping, err := ReadMapping(strings.NewReader(`
+          {
+            "types": {"A.0000000000000001.Foo.Bar": "A.0000000000000001.Foo.Baz"},
+            "fields": {
+              "A.0000000000000001.Foo.Bar": {
+                "rename": {"balance": "amount"},
+                "drop": ["legacy"]
+              }
+            }
+          }
+        `))
+		require.NoError(t, err)
+		assert.Equal(t, expected, mapping)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ReadMapping(strings.NewReader(`renames: {}`))
+		require.Error(t, err)
+	})
+
+	t.Run("invalid type ID", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ReadMapping(strings.NewReader(`
+types:
+  Bar: A.0000000000000001.Foo.Baz
+`))
+		require.ErrorContains(t, err, "invalid type ID")
+	})
+
+	t.Run("renamed and dropped", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ReadMapping(strings.NewReader(`
+fields:
+  A.0000000000000001.Foo.Bar:
+    rename:
+      balance: amount
+    drop:
+      - balance
+`))
+		require.ErrorContains(t, err, "both renamed and dropped")
+	})
+}
+
+func TestTypeRewriteMigration(t *testing.T) {
+
+	t.Parallel()
+
+	mapping := Mapping{
+		Types: map[common.TypeID]common.TypeID{
+			fooTypeID("Bar"): fooTypeID("Baz"),
+			fooTypeID("I"):   fooTypeID("J"),
+			fooTypeID("R"):   fooTypeID("S"),
+			fooTypeID("E"):   fooTypeID("F"),
+		},
+		Fields: map[common.TypeID]FieldsMapping{
+			fooTypeID("Bar"): {
+				Rename: map[string]string{
+					"balance": "amount",
+				},
+				Drop: []string{"legacy"},
+			},
+			fooTypeID("Holder"): {
+				Rename: map[string]string{
+					"a": "b",
+					"b": "a",
+				},
+			},
+		},
+	}
+
+	type testCase struct {
+		newValue func(inter *interpreter.Interpreter) interpreter.Value
+		check    func(t *testing.T, inter *interpreter.Interpreter, value interpreter.Value)
+	}
+
+	newBar := func(inter *interpreter.Interpreter, address common.Address) *interpreter.CompositeValue {
+		return interpreter.NewCompositeValue(
+			inter,
+			interpreter.EmptyLocationRange,
+			fooLocation,
+			"Foo.Bar",
+			common.CompositeKindStructure,
+			[]interpreter.CompositeField{
+				interpreter.NewUnmeteredCompositeField("balance", interpreter.NewUnmeteredIntValueFromInt64(42)),
+				interpreter.NewUnmeteredCompositeField("legacy", interpreter.NewUnmeteredStringValue("old")),
+				interpreter.NewUnmeteredCompositeField("name", interpreter.NewUnmeteredStringValue("bar")),
+			},
+			address,
+		)
+	}
+
+	checkBaz := func(t *testing.T, inter *interpreter.Interpreter, value interpreter.Value) {
+		require.IsType(t, &interpreter.CompositeValue{}, value)
+		composite := value.(*interpreter.CompositeValue)
+
+		assert.Equal(t, fooTypeID("Baz"), composite.TypeID())
+		assert.Equal(t, fooCompositeType("Baz"), composite.StaticType(inter))
+
+		var fieldNames []string
+		composite.ForEachFieldName(func(fieldName string) (resume bool) {
+			fieldNames = append(fieldNames, fieldName)
+			return true
+		})
+		assert.ElementsMatch(t, []string{"amount", "name"}, fieldNames)
+
+		assert.Equal(t,
+			interpreter.NewUnmeteredIntValueFromInt64(42),
+			composite.GetField(inter, interpreter.EmptyLocationRange, "amount"),
+		)
+	}
+
+	newEnum := func(inter *interpreter.Interpreter, qualifiedIdentifier string, rawValue uint8) *interpreter.CompositeValue {
+		return interpreter.NewCompositeValue(
+			inter,
+			interpreter.EmptyLocationRange,
+			fooLocation,
+			"Foo."+qualifiedIdentifier,
+			common.CompositeKindEnum,
+			[]interpreter.CompositeField{
+				interpreter.NewUnmeteredCompositeField(
+					"rawValue",
+					interpreter.NewUnmeteredUInt8Value(rawValue),
+				),
+			},
+			common.ZeroAddress,
+		)
+	}
+
+	testCases := map[string]testCase{
+		"composite": {
+			newValue: func(inter *interpreter.Interpreter) interpreter.Value {
+				return newBar(inter, testAddress)
+			},
+			check: checkBaz,
+		},
+		"array of composites": {
+			newValue: func(inter *interpreter.Interpreter) interpreter.Value {
+				return interpreter.NewArrayValue(
+					inter,
+					interpreter.EmptyLocationRange,
+					interpreter.NewVariableSizedStaticType(nil, fooCompositeType("Bar")),
+					testAddress,
+					newBar(inter, common.ZeroAddress),
+				)
+			},
+			check: func(t *testing.T, inter *interpreter.Interpreter, value interpreter.Value) {
+				require.IsType(t, &interpreter.ArrayValue{}, value)
+				array := value.(*interpreter.ArrayValue)
+
+				assert.Equal(t,
+					interpreter.NewVariableSizedStaticType(nil, fooCompositeType("Baz")),
+					array.Type,
+				)
+				require.Equal(t, 1, array.Count())
+				checkBaz(t, inter, array.Get(inter, interpreter.EmptyLocationRange, 0))
+			},
+		},
+		"swapped field names": {
+			newValue: func(inter *interpreter.Interpreter) interpreter.Value {
+				return interpreter.NewCompositeValue(
+					inter,
+					interpreter.EmptyLocationRange,
+					fooLocation,
+					"Foo.Holder",
+					common.CompositeKindStructure,
+					[]interpreter.CompositeField{
+						interpreter.NewUnmeteredCompositeField("a", interpreter.NewUnmeteredIntValueFromInt64(1)),
+						interpreter.NewUnmeteredCompositeField("b", interpreter.NewUnmeteredIntValueFromInt64(2)),
+					},
+					testAddress,
+				)
+			},
+			check: func(t *testing.T, inter *interpreter.Interpreter, value interpreter.Value) {
+				composite := value.(*interpreter.CompositeValue)
+				assert.Equal(t, fooTypeID("Holder"), composite.TypeID())
+				assert.Equal(t,
+					interpreter.NewUnmeteredIntValueFromInt64(2),
+					composite.GetField(inter, interpreter.EmptyLocationRange, "a"),
+				)
+				assert.Equal(t,
+					interpreter.NewUnmeteredIntValueFromInt64(1),
+					composite.GetField(inter, interpreter.EmptyLocationRange, "b"),
+				)
+			},
+		},
+		"nested resource": {
+			newValue: func(inter *interpreter.Interpreter) interpreter.Value {
+				inner := interpreter.NewCompositeValue(
+					inter,
+					interpreter.EmptyLocationRange,
+					fooLocation,
+					"Foo.R",
+					common.CompositeKindResource,
+					[]interpreter.CompositeField{
+						interpreter.NewUnmeteredCompositeField("uuid", interpreter.NewUnmeteredUInt64Value(1)),
+					},
+					common.ZeroAddress,
+				)
+				return interpreter.NewCompositeValue(
+					inter,
+					interpreter.EmptyLocationRange,
+					fooLocation,
+					"Foo.Outer",
+					common.CompositeKindResource,
+					[]interpreter.CompositeField{
+						interpreter.NewUnmeteredCompositeField("uuid", interpreter.NewUnmeteredUInt64Value(2)),
+						interpreter.NewUnmeteredCompositeField("inner", inner),
+					},
+					testAddress,
+				)
+			},
+			check: func(t *testing.T, inter *interpreter.Interpreter, value interpreter.Value) {
+				outer := value.(*interpreter.CompositeValue)
+				assert.Equal(t, fooTypeID("Outer"), outer.TypeID())
+
+				inner := outer.GetField(inter, interpreter.EmptyLocationRange, "inner")
+				require.IsType(t, &interpreter.CompositeValue{}, inner)
+				assert.Equal(t, fooTypeID("S"), inner.(*interpreter.CompositeValue).TypeID())
+			},
+		},
+		"dictionary with enum keys": {
+			newValue: func(inter *interpreter.Interpreter) interpreter.Value {
+				return interpreter.NewDictionaryValueWithAddress(
+					inter,
+					interpreter.EmptyLocationRange,
+					interpreter.NewDictionaryStaticType(
+						nil,
+						fooCompositeType("E"),
+						interpreter.PrimitiveStaticTypeInt,
+					),
+					testAddress,
+					newEnum(inter, "E", 1),
+					interpreter.NewUnmeteredIntValueFromInt64(1),
+					newEnum(inter, "E", 2),
+					interpreter.NewUnmeteredIntValueFromInt64(2),
+				)
+			},
+			check: func(t *testing.T, inter *interpreter.Interpreter, value interpreter.Value) {
+				dictionary := value.(*interpreter.DictionaryValue)
+
+				assert.Equal(t,
+					interpreter.NewDictionaryStaticType(
+						nil,
+						fooCompositeType("F"),
+						interpreter.PrimitiveStaticTypeInt,
+					),
+					dictionary.Type,
+				)
+				require.Equal(t, 2, dictionary.Count())
+
+				for _, rawValue := range []uint8{1, 2} {
+					result, ok := dictionary.Get(
+						inter,
+						interpreter.EmptyLocationRange,
+						newEnum(inter, "F", rawValue),
+					)
+					require.True(t, ok)
+					assert.Equal(t, interpreter.NewUnmeteredIntValueFromInt64(int64(rawValue)), result)
+				}
+			},
+		},
+		"type value": {
+			newValue: func(_ *interpreter.Interpreter) interpreter.Value {
+				return interpreter.NewUnmeteredTypeValue(
+					interpreter.NewOptionalStaticType(nil, fooCompositeType("Bar")),
+				)
+			},
+			check: func(t *testing.T, _ *interpreter.Interpreter, value interpreter.Value) {
+				assert.Equal(t,
+					interpreter.NewUnmeteredTypeValue(
+						interpreter.NewOptionalStaticType(nil, fooCompositeType("Baz")),
+					),
+					value,
+				)
+			},
+		},
+		"capability": {
+			newValue: func(_ *interpreter.Interpreter) interpreter.Value {
+				return interpreter.NewUnmeteredCapabilityValue(
+					1,
+					interpreter.AddressValue(testAddress),
+					interpreter.NewReferenceStaticType(
+						nil,
+						interpreter.UnauthorizedAccess,
+						interpreter.NewIntersectionStaticType(
+							nil,
+							[]*interpreter.InterfaceStaticType{
+								fooInterfaceType("I"),
+							},
+						),
+					),
+				)
+			},
+			check: func(t *testing.T, _ *interpreter.Interpreter, value interpreter.Value) {
+				assert.Equal(t,
+					interpreter.NewUnmeteredCapabilityValue(
+						1,
+						interpreter.AddressValue(testAddress),
+						interpreter.NewReferenceStaticType(
+							nil,
+							interpreter.UnauthorizedAccess,
+							interpreter.NewIntersectionStaticType(
+								nil,
+								[]*interpreter.InterfaceStaticType{
+									fooInterfaceType("J"),
+								},
+							),
+						),
+					),
+					value,
+				)
+			},
+		},
+		"path link": {
+			newValue: func(_ *interpreter.Interpreter) interpreter.Value {
+				return interpreter.PathLinkValue{ //nolint:staticcheck
+					Type: interpreter.NewReferenceStaticType(
+						nil,
+						interpreter.UnauthorizedAccess,
+						fooCompositeType("Bar"),
+					),
+					TargetPath: interpreter.NewUnmeteredPathValue(common.PathDomainStorage, "bar"),
+				}
+			},
+			check: func(t *testing.T, _ *interpreter.Interpreter, value interpreter.Value) {
+				assert.Equal(t,
+					interpreter.PathLinkValue{ //nolint:staticcheck
+						Type: interpreter.NewReferenceStaticType(
+							nil,
+							interpreter.UnauthorizedAccess,
+							fooCompositeType("Baz"),
+						),
+						TargetPath: interpreter.NewUnmeteredPathValue(common.PathDomainStorage, "bar"),
+					},
+					value,
+				)
+			},
+		},
+	}
+
+	test := func(name string, testCase testCase) {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ledger := NewTestLedger(nil, nil)
+			storage := runtime.NewStorage(ledger, nil)
+
+			inter, err := interpreter.NewInterpreter(
+				nil,
+				utils.TestLocation,
+				&interpreter.Config{
+					Storage:                       storage,
+					AtreeValueValidationEnabled:   false,
+					AtreeStorageValidationEnabled: false,
+				},
+			)
+			require.NoError(t, err)
+
+			storageMapKey := interpreter.StringStorageMapKey("test")
+			storageDomain := common.PathDomainStorage.Identifier()
+
+			inter.WriteStored(
+				testAddress,
+				storageDomain,
+				storageMapKey,
+				testCase.newValue(inter),
+			)
+
+			err = storage.Commit(inter, true)
+			require.NoError(t, err)
+
+			// Migrate
+
+			migration, err := migrations.NewStorageMigration(inter, storage, "test", testAddress)
+			require.NoError(t, err)
+
+			typeRewriteMigration, err := NewTypeRewriteMigration(mapping)
+			require.NoError(t, err)
+
+			reporter := &testReporter{}
+
+			migration.Migrate(
+				migration.NewValueMigrationsPathMigrator(
+					reporter,
+					typeRewriteMigration,
+				),
+			)
+
+			err = migration.Commit()
+			require.NoError(t, err)
+
+			// Assert
+
+			require.Empty(t, reporter.errors)
+			require.Contains(t, reporter.migrated, storageMapKey)
+
+			err = storage.CheckHealth()
+			require.NoError(t, err)
+
+			storageMap := storage.GetStorageMap(testAddress, storageDomain, false)
+			require.NotNil(t, storageMap)
+
+			testCase.check(t, inter, storageMap.ReadValue(nil, storageMapKey))
+		})
+	}
+
+	for name, testCase := range testCases {
+		test(name, testCase)
+	}
+}
+
+func TestTypeRewriteMigrationDropResourceField(t *testing.T) {
+
+	t.Parallel()
+
+	ledger := NewTestLedger(nil, nil)
+	storage := runtime.NewStorage(ledger, nil)
+
+	inter, err := interpreter.NewInterpreter(
+		nil,
+		utils.TestLocation,
+		&interpreter.Config{
+			Storage:                       storage,
+			AtreeValueValidationEnabled:   false,
+			AtreeStorageValidationEnabled: false,
+		},
+	)
+	require.NoError(t, err)
+
+	inner := interpreter.NewCompositeValue(
+		inter,
+		interpreter.EmptyLocationRange,
+		fooLocation,
+		"Foo.R",
+		common.CompositeKindResource,
+		[]interpreter.CompositeField{
+			interpreter.NewUnmeteredCompositeField("uuid", interpreter.NewUnmeteredUInt64Value(1)),
+		},
+		common.ZeroAddress,
+	)
+	outer := interpreter.NewCompositeValue(
+		inter,
+		interpreter.EmptyLocationRange,
+		fooLocation,
+		"Foo.Outer",
+		common.CompositeKindResource,
+		[]interpreter.CompositeField{
+			interpreter.NewUnmeteredCompositeField("uuid", interpreter.NewUnmeteredUInt64Value(2)),
+			interpreter.NewUnmeteredCompositeField("inner", inner),
+		},
+		testAddress,
+	)
+
+	storageMapKey := interpreter.StringStorageMapKey("test")
+	storageDomain := common.PathDomainStorage.Identifier()
+
+	inter.WriteStored(testAddress, storageDomain, storageMapKey, outer)
+
+	err = storage.Commit(inter, true)
+	require.NoError(t, err)
+
+	migration, err := migrations.NewStorageMigration(inter, storage, "test", testAddress)
+	require.NoError(t, err)
+
+	typeRewriteMigration, err := NewTypeRewriteMigration(Mapping{
+		Fields: map[common.TypeID]FieldsMapping{
+			fooTypeID("Outer"): {
+				Drop: []string{"inner"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	reporter := &testReporter{}
+
+	migration.Migrate(
+		migration.NewValueMigrationsPathMigrator(
+			reporter,
+			typeRewriteMigration,
+		),
+	)
+
+	err = migration.Commit()
+	require.NoError(t, err)
+
+	require.Len(t, reporter.errors, 1)
+	require.ErrorContains(t, reporter.errors[0], "field contains a resource")
+
+	// The value is unchanged
+
+	storageMap := storage.GetStorageMap(testAddress, storageDomain, false)
+	value := storageMap.ReadValue(nil, storageMapKey).(*interpreter.CompositeValue)
+	assert.NotNil(t, value.GetField(inter, interpreter.EmptyLocationRange, "inner"))
+}
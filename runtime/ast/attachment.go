@@ -140,9 +140,7 @@ func (d *AttachmentDeclaration) Doc() prettier.Doc {
 		prettier.Space,
 		d.BaseType.Doc(),
 	)
-	var membersDoc prettier.Concat
-
-	membersDoc = append(membersDoc, prettier.Line{}, d.Members.Doc())
+	membersDoc := d.Members.Doc()
 
 	if len(d.Conformances) > 0 {
 		conformancesDoc := prettier.Concat{
@@ -170,10 +170,7 @@ func TestAttachmentDeclaration_Doc(t *testing.T) {
 						prettier.Dedent{
 							Doc: prettier.Concat{
 								prettier.Line{},
-								prettier.Concat{
-									prettier.Line{},
-									prettier.Text("{}"),
-								},
+								prettier.Text("{}"),
 							},
 						},
 					},
@@ -185,7 +182,7 @@ func TestAttachmentDeclaration_Doc(t *testing.T) {
 
 	require.Equal(t,
 		`access(all)
-attachment Foo for Bar: Baz  {}`,
+attachment Foo for Bar: Baz {}`,
 		decl.String(),
 	)
 }
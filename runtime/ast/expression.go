@@ -862,11 +862,23 @@ func (e *MemberExpression) Doc() prettier.Doc {
 		separatorDoc = memberExpressionSeparatorDoc
 	}
 
-	return prettier.Concat{
-		parenthesizedExpressionDoc(
+	var expressionDoc prettier.Doc
+	if _, ok := e.Expression.(*IntegerExpression); ok {
+		// The member access of an integer literal must be parenthesized,
+		// as the separator would otherwise be lexed as part of a fixed-point literal
+		expressionDoc = prettier.WrapParentheses(
+			e.Expression.Doc(),
+			prettier.SoftLine{},
+		)
+	} else {
+		expressionDoc = parenthesizedExpressionDoc(
 			e.Expression,
 			e.precedence(),
-		),
+		)
+	}
+
+	return prettier.Concat{
+		expressionDoc,
 		prettier.Group{
 			Doc: prettier.Indent{
 				Doc: prettier.Concat{
@@ -1158,6 +1170,10 @@ func parenthesizedExpressionDoc(e Expression, parentPrecedence precedence) prett
 	if parentPrecedence <= subPrecedence {
 		return doc
 	}
+	// Postfix operations chain with accesses, e.g. `a!.b` is parsed as `(a!).b`
+	if parentPrecedence == precedenceAccess && subPrecedence == precedenceUnaryPostfix {
+		return doc
+	}
 	return prettier.WrapParentheses(
 		doc,
 		prettier.SoftLine{},
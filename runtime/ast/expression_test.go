@@ -1073,6 +1073,50 @@ func TestMemberExpression_Doc(t *testing.T) {
 		)
 	})
 
+	t.Run("integer literal", func(t *testing.T) {
+
+		t.Parallel()
+
+		expr := &MemberExpression{
+			Expression: &IntegerExpression{
+				PositiveLiteral: []byte("1"),
+				Value:           big.NewInt(1),
+				Base:            10,
+			},
+			Identifier: Identifier{
+				Identifier: "foo",
+			},
+		}
+
+		assert.Equal(t,
+			"(1).foo",
+			Prettier(expr),
+		)
+	})
+
+	t.Run("force-unwrapped", func(t *testing.T) {
+
+		t.Parallel()
+
+		expr := &MemberExpression{
+			Expression: &ForceExpression{
+				Expression: &IdentifierExpression{
+					Identifier: Identifier{
+						Identifier: "foo",
+					},
+				},
+			},
+			Identifier: Identifier{
+				Identifier: "bar",
+			},
+		}
+
+		assert.Equal(t,
+			"foo!.bar",
+			Prettier(expr),
+		)
+	})
+
 	t.Run("nested, same precedence", func(t *testing.T) {
 
 		t.Parallel()
@@ -277,15 +277,11 @@ const arrayTypeStartDoc = prettier.Text("[")
 const arrayTypeEndDoc = prettier.Text("]")
 
 func (t *VariableSizedType) Doc() prettier.Doc {
+	// Types are not broken into multiple lines,
+	// so e.g. the parameter list of a function declaration is broken instead of its return type
 	return prettier.Concat{
 		arrayTypeStartDoc,
-		prettier.Indent{
-			Doc: prettier.Concat{
-				prettier.SoftLine{},
-				t.Type.Doc(),
-			},
-		},
-		prettier.SoftLine{},
+		t.Type.Doc(),
 		arrayTypeEndDoc,
 	}
 }
@@ -340,15 +336,9 @@ const constantSizedTypeSeparatorSpaceDoc = prettier.Text("; ")
 func (t *ConstantSizedType) Doc() prettier.Doc {
 	return prettier.Concat{
 		arrayTypeStartDoc,
-		prettier.Indent{
-			Doc: prettier.Concat{
-				prettier.SoftLine{},
-				t.Type.Doc(),
-				constantSizedTypeSeparatorSpaceDoc,
-				t.Size.Doc(),
-			},
-		},
-		prettier.SoftLine{},
+		t.Type.Doc(),
+		constantSizedTypeSeparatorSpaceDoc,
+		t.Size.Doc(),
 		arrayTypeEndDoc,
 	}
 }
@@ -404,15 +394,9 @@ const dictionaryTypeEndDoc = prettier.Text("}")
 func (t *DictionaryType) Doc() prettier.Doc {
 	return prettier.Concat{
 		dictionaryTypeStartDoc,
-		prettier.Indent{
-			Doc: prettier.Concat{
-				prettier.SoftLine{},
-				t.KeyType.Doc(),
-				typeSeparatorSpaceDoc,
-				t.ValueType.Doc(),
-			},
-		},
-		prettier.SoftLine{},
+		t.KeyType.Doc(),
+		typeSeparatorSpaceDoc,
+		t.ValueType.Doc(),
 		dictionaryTypeEndDoc,
 	}
 }
@@ -513,10 +497,18 @@ func (t *FunctionType) Doc() prettier.Doc {
 				closeParenthesisDoc,
 			},
 		},
-		typeSeparatorSpaceDoc,
-		t.ReturnTypeAnnotation.Doc(),
 	)
 
+	if t.ReturnTypeAnnotation != nil &&
+		!IsEmptyType(t.ReturnTypeAnnotation.Type) {
+
+		result = append(
+			result,
+			typeSeparatorSpaceDoc,
+			t.ReturnTypeAnnotation.Doc(),
+		)
+	}
+
 	return result
 }
 
@@ -608,11 +600,15 @@ func (t *ReferenceType) Doc() prettier.Doc {
 		)
 	}
 
-	return append(
-		doc,
-		referenceTypeSymbolDoc,
-		t.Type.Doc(),
-	)
+	doc = append(doc, referenceTypeSymbolDoc)
+
+	// A reference to a reference must be separated,
+	// as the symbols would otherwise be lexed as a logical and operator
+	if _, ok := t.Type.(*ReferenceType); ok {
+		doc = append(doc, prettier.Space)
+	}
+
+	return append(doc, t.Type.Doc())
 }
 
 func (t *ReferenceType) MarshalJSON() ([]byte, error) {
@@ -351,13 +351,7 @@ func TestVariableSizedType_Doc(t *testing.T) {
 	assert.Equal(t,
 		prettier.Concat{
 			prettier.Text("["),
-			prettier.Indent{
-				Doc: prettier.Concat{
-					prettier.SoftLine{},
-					prettier.Text("T"),
-				},
-			},
-			prettier.SoftLine{},
+			prettier.Text("T"),
 			prettier.Text("]"),
 		},
 		ty.Doc(),
@@ -445,15 +439,9 @@ func TestConstantSizedType_Doc(t *testing.T) {
 	assert.Equal(t,
 		prettier.Concat{
 			prettier.Text("["),
-			prettier.Indent{
-				Doc: prettier.Concat{
-					prettier.SoftLine{},
-					prettier.Text("T"),
-					prettier.Text("; "),
-					prettier.Text("42"),
-				},
-			},
-			prettier.SoftLine{},
+			prettier.Text("T"),
+			prettier.Text("; "),
+			prettier.Text("42"),
 			prettier.Text("]"),
 		},
 		ty.Doc(),
@@ -563,15 +551,9 @@ func TestDictionaryType_Doc(t *testing.T) {
 	assert.Equal(t,
 		prettier.Concat{
 			prettier.Text("{"),
-			prettier.Indent{
-				Doc: prettier.Concat{
-					prettier.SoftLine{},
-					prettier.Text("AB"),
-					prettier.Text(": "),
-					prettier.Text("CD"),
-				},
-			},
-			prettier.SoftLine{},
+			prettier.Text("AB"),
+			prettier.Text(": "),
+			prettier.Text("CD"),
 			prettier.Text("}"),
 		},
 		ty.Doc(),
@@ -728,6 +710,31 @@ func TestFunctionType_Doc(t *testing.T) {
 	)
 }
 
+func TestFunctionType_Doc_WithoutReturnType(t *testing.T) {
+
+	t.Parallel()
+
+	ty := &FunctionType{
+		ParameterTypeAnnotations: []*TypeAnnotation{
+			{
+				Type: &NominalType{
+					Identifier: Identifier{
+						Identifier: "AB",
+					},
+				},
+			},
+		},
+		ReturnTypeAnnotation: &TypeAnnotation{
+			Type: &NominalType{},
+		},
+	}
+
+	assert.Equal(t,
+		"fun (AB)",
+		Prettier(ty),
+	)
+}
+
 func TestFunctionType_String(t *testing.T) {
 
 	t.Parallel()
@@ -1007,6 +1014,26 @@ func TestReferenceType_Doc(t *testing.T) {
 		)
 	})
 
+	t.Run("reference to reference", func(t *testing.T) {
+
+		t.Parallel()
+
+		ty := &ReferenceType{
+			Type: &ReferenceType{
+				Type: &NominalType{
+					Identifier: Identifier{
+						Identifier: "T",
+					},
+				},
+			},
+		}
+
+		assert.Equal(t,
+			"& &T",
+			Prettier(ty),
+		)
+	})
+
 	t.Run("un-auth", func(t *testing.T) {
 
 		t.Parallel()
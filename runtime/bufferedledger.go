@@ -0,0 +1,140 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime/errors"
+)
+
+type bufferedRegisterKey struct {
+	owner string
+	key   string
+}
+
+// BufferedLedger is a ledger which reads through to another ledger,
+// but buffers all writes and storage index allocations,
+// so the other ledger is not modified.
+//
+// It is used to execute transactions or migrations without committing their effects,
+// e.g. when simulating a transaction or performing a migration dry-run.
+//
+// It is not safe for concurrent use.
+type BufferedLedger struct {
+	ledger  atree.Ledger
+	values  map[bufferedRegisterKey][]byte
+	indices map[string]uint64
+}
+
+var _ atree.Ledger = &BufferedLedger{}
+
+// NewBufferedLedger returns a new ledger which buffers the changes to the given ledger.
+func NewBufferedLedger(ledger atree.Ledger) *BufferedLedger {
+	return &BufferedLedger{
+		ledger:  ledger,
+		values:  map[bufferedRegisterKey][]byte{},
+		indices: map[string]uint64{},
+	}
+}
+
+func (l *BufferedLedger) GetValue(owner, key []byte) ([]byte, error) {
+	value, ok := l.values[bufferedRegisterKey{owner: string(owner), key: string(key)}]
+	if ok {
+		return value, nil
+	}
+	return l.ledger.GetValue(owner, key)
+}
+
+func (l *BufferedLedger) SetValue(owner, key, value []byte) error {
+	l.values[bufferedRegisterKey{owner: string(owner), key: string(key)}] = bytes.Clone(value)
+	return nil
+}
+
+func (l *BufferedLedger) ValueExists(owner, key []byte) (bool, error) {
+	value, ok := l.values[bufferedRegisterKey{owner: string(owner), key: string(key)}]
+	if ok {
+		return len(value) > 0, nil
+	}
+	return l.ledger.ValueExists(owner, key)
+}
+
+// AllocateStorageIndex allocates a storage index without allocating it in the other ledger.
+//
+// The other ledger does not provide its next storage index,
+// so indices are allocated downwards from the largest index,
+// skipping the indices of existing slabs
+func (l *BufferedLedger) AllocateStorageIndex(owner []byte) (atree.StorageIndex, error) {
+	next, ok := l.indices[string(owner)]
+	if !ok {
+		next = math.MaxUint64
+	}
+
+	for {
+		if next == 0 {
+			return atree.StorageIndex{}, errors.NewUnexpectedError("storage indices exhausted")
+		}
+
+		var index atree.StorageIndex
+		binary.BigEndian.PutUint64(index[:], next)
+		next--
+
+		exists, err := l.ValueExists(owner, atree.SlabIndexToLedgerKey(index))
+		if err != nil {
+			return atree.StorageIndex{}, err
+		}
+		if !exists {
+			l.indices[string(owner)] = next
+			return index, nil
+		}
+	}
+}
+
+// ForEachBufferedRegister calls the given function for each register which was written,
+// ordered by owner and key, and stops at the first error.
+// The value of a removed register is empty
+func (l *BufferedLedger) ForEachBufferedRegister(f func(owner, key, value []byte) error) error {
+	keys := make([]bufferedRegisterKey, 0, len(l.values))
+	for key := range l.values { //nolint:maprange
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a := keys[i]
+		b := keys[j]
+		if a.owner != b.owner {
+			return a.owner < b.owner
+		}
+		return a.key < b.key
+	})
+
+	for _, key := range keys {
+		err := f([]byte(key.owner), []byte(key.key), l.values[key])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime_test
+
+import (
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/inmemory"
+)
+
+func TestBufferedLedger(t *testing.T) {
+
+	t.Parallel()
+
+	owner := []byte{0x1}
+
+	t.Run("read through, buffer writes", func(t *testing.T) {
+
+		t.Parallel()
+
+		inner := inmemory.NewLedger()
+		require.NoError(t, inner.SetValue(owner, []byte("a"), []byte{1}))
+		require.NoError(t, inner.SetValue(owner, []byte("b"), []byte{2}))
+
+		ledger := NewBufferedLedger(inner)
+
+		value, err := ledger.GetValue(owner, []byte("a"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1}, value)
+
+		require.NoError(t, ledger.SetValue(owner, []byte("a"), []byte{3}))
+		require.NoError(t, ledger.SetValue(owner, []byte("b"), nil))
+
+		value, err = ledger.GetValue(owner, []byte("a"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte{3}, value)
+
+		exists, err := ledger.ValueExists(owner, []byte("b"))
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		// The inner ledger is not modified
+
+		value, err = inner.GetValue(owner, []byte("a"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1}, value)
+
+		exists, err = inner.ValueExists(owner, []byte("b"))
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		type register struct {
+			key   string
+			value []byte
+		}
+
+		var registers []register
+		err = ledger.ForEachBufferedRegister(func(_, key, value []byte) error {
+			registers = append(registers, register{
+				key:   string(key),
+				value: value,
+			})
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t,
+			[]register{
+				{key: "a", value: []byte{3}},
+				{key: "b"},
+			},
+			registers,
+		)
+	})
+
+	t.Run("allocate storage index", func(t *testing.T) {
+
+		t.Parallel()
+
+		inner := inmemory.NewLedger()
+
+		// Store a slab at the largest index, which must be skipped
+		largestIndex := atree.StorageIndex{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		require.NoError(t, inner.SetValue(owner, atree.SlabIndexToLedgerKey(largestIndex), []byte{1}))
+
+		ledger := NewBufferedLedger(inner)
+
+		index, err := ledger.AllocateStorageIndex(owner)
+		require.NoError(t, err)
+		assert.Equal(t,
+			atree.StorageIndex{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe},
+			index,
+		)
+
+		index, err = ledger.AllocateStorageIndex(owner)
+		require.NoError(t, err)
+		assert.Equal(t,
+			atree.StorageIndex{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfd},
+			index,
+		)
+
+		// The allocation state of the inner ledger is not changed
+
+		index, err = inner.AllocateStorageIndex(owner)
+		require.NoError(t, err)
+		assert.Equal(t,
+			atree.StorageIndex{0, 0, 0, 0, 0, 0, 0, 1},
+			index,
+		)
+	})
+}
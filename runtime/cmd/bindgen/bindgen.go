@@ -0,0 +1,783 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package bindgen generates typed Go and TypeScript bindings for Cadence programs.
+//
+// For contracts, a Go type is generated for each struct, resource, event, and enum declared in the contract.
+// For scripts and transactions, the source code, a function which builds the arguments,
+// and for scripts, a function which decodes the result are generated.
+// Composite types used by the parameters, results, and fields, e.g. imported from other contracts,
+// are generated as well.
+//
+// The generated Go code converts between Go values and Cadence values
+// using cadence.Marshal and cadence.Unmarshal.
+// The generated TypeScript code decodes values encoded in the JSON-Cadence Data Interchange Format,
+// see GenerateTypeScript.
+package bindgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+// Config configures the generation of Go bindings.
+type Config struct {
+	// PackageName is the name of the package of the generated code
+	PackageName string
+}
+
+const (
+	cadenceImportPath = "github.com/onflow/cadence"
+	commonImportPath  = "github.com/onflow/cadence/runtime/common"
+	bigImportPath     = "math/big"
+	fmtImportPath     = "fmt"
+)
+
+type programKind int
+
+const (
+	programKindScript programKind = iota
+	programKindTransaction
+)
+
+func (k programKind) String() string {
+	switch k {
+	case programKindScript:
+		return "script"
+	case programKindTransaction:
+		return "transaction"
+	default:
+		panic(fmt.Errorf("unknown program kind %d", k))
+	}
+}
+
+// scriptOrTransaction is a script or transaction
+// for which bindings are generated
+type scriptOrTransaction struct {
+	kind       programKind
+	location   common.Location
+	name       string
+	code       []byte
+	parameters []sema.Parameter
+	// resultType is the result type of a script, if any
+	resultType cadence.Type
+}
+
+// generator gathers the types of the given programs,
+// and generates the bindings for them
+type generator struct {
+	config        Config
+	exportedTypes map[sema.TypeID]cadence.Type
+	// composites are the composite types for which Go types are generated,
+	// in the order they were discovered
+	composites []cadence.CompositeType
+	// compositeNames are the names of the generated Go types, by composite type ID
+	compositeNames map[string]string
+	// enumCases are the names of the cases of all loaded enums, by type ID
+	enumCases map[string][]string
+	// declaredNames are the declared Go identifiers, used to detect conflicts,
+	// mapped to what declared them
+	declaredNames map[string]string
+	programs      []scriptOrTransaction
+	imports       map[string]struct{}
+}
+
+// Generate generates the Go bindings for the programs at the given locations,
+// and returns the formatted Go source code.
+//
+// The programs must have been loaded with at least the analysis.NeedTypes mode.
+// Each program must be a contract, a contract interface, a transaction, or a script.
+func Generate(config Config, programs analysis.Programs, locations ...common.Location) ([]byte, error) {
+	if config.PackageName == "" {
+		return nil, fmt.Errorf("missing package name")
+	}
+
+	g, err := newGenerator(config, programs, locations)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.generate()
+}
+
+// newGenerator returns a generator for the programs at the given locations,
+// which has gathered the composite types and the scripts and transactions to generate
+func newGenerator(config Config, programs analysis.Programs, locations []common.Location) (*generator, error) {
+	g := &generator{
+		config:         config,
+		exportedTypes:  map[sema.TypeID]cadence.Type{},
+		compositeNames: map[string]string{},
+		enumCases:      map[string][]string{},
+		declaredNames:  map[string]string{},
+		imports:        map[string]struct{}{},
+	}
+
+	// Enum cases are only available in the declarations,
+	// so gather them from all loaded programs, including imported programs
+
+	for _, program := range programs { //nolint:maprange
+		if program == nil || program.Program == nil || program.Checker == nil {
+			continue
+		}
+		g.gatherEnumCases(program.Checker.Elaboration, program.Program.CompositeDeclarations())
+		for _, interfaceDeclaration := range program.Program.InterfaceDeclarations() {
+			g.gatherEnumCases(program.Checker.Elaboration, interfaceDeclaration.Members.Composites())
+		}
+	}
+
+	for _, location := range locations {
+		err := g.addProgram(programs[location], location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+func (g *generator) gatherEnumCases(elaboration *sema.Elaboration, declarations []*ast.CompositeDeclaration) {
+	for _, declaration := range declarations {
+		compositeType := elaboration.CompositeDeclarationType(declaration)
+		if compositeType == nil {
+			continue
+		}
+
+		if declaration.Kind() == common.CompositeKindEnum {
+			enumCases := declaration.Members.EnumCases()
+			names := make([]string, 0, len(enumCases))
+			for _, enumCase := range enumCases {
+				names = append(names, enumCase.Identifier.Identifier)
+			}
+			g.enumCases[string(compositeType.ID())] = names
+		}
+
+		g.gatherEnumCases(elaboration, declaration.Members.Composites())
+	}
+}
+
+func (g *generator) addProgram(program *analysis.Program, location common.Location) error {
+	if program == nil || program.Program == nil || program.Checker == nil {
+		return fmt.Errorf("program %s was not loaded", location)
+	}
+
+	if program.LoadError != nil {
+		return program.LoadError
+	}
+
+	astProgram := program.Program
+	elaboration := program.Checker.Elaboration
+
+	if declaration := astProgram.SoleContractDeclaration(); declaration != nil {
+		g.addDeclaredComposites(elaboration, []*ast.CompositeDeclaration{declaration})
+		return nil
+	}
+
+	if declaration := astProgram.SoleContractInterfaceDeclaration(); declaration != nil {
+		g.addDeclaredComposites(elaboration, declaration.Members.Composites())
+		return nil
+	}
+
+	if declaration := astProgram.SoleTransactionDeclaration(); declaration != nil {
+		transactionType := elaboration.TransactionDeclarationType(declaration)
+		g.addScriptOrTransaction(scriptOrTransaction{
+			kind:       programKindTransaction,
+			location:   location,
+			code:       program.Code,
+			parameters: transactionType.Parameters,
+		})
+		return nil
+	}
+
+	for _, declaration := range astProgram.FunctionDeclarations() {
+		if declaration.Identifier.Identifier != "main" {
+			continue
+		}
+
+		functionType := elaboration.FunctionDeclarationFunctionType(declaration)
+
+		var resultType cadence.Type
+		returnType := functionType.ReturnTypeAnnotation.Type
+		if returnType != nil && returnType != sema.VoidType {
+			resultType = g.exportType(returnType)
+		}
+
+		g.addScriptOrTransaction(scriptOrTransaction{
+			kind:       programKindScript,
+			location:   location,
+			code:       program.Code,
+			parameters: functionType.Parameters,
+			resultType: resultType,
+		})
+		return nil
+	}
+
+	return fmt.Errorf(
+		"program %s is neither a contract, a contract interface, a transaction, nor a script",
+		location,
+	)
+}
+
+// addDeclaredComposites adds the given composite declarations,
+// and all nested composite declarations
+func (g *generator) addDeclaredComposites(elaboration *sema.Elaboration, declarations []*ast.CompositeDeclaration) {
+	for _, declaration := range declarations {
+		compositeType := elaboration.CompositeDeclarationType(declaration)
+		if compositeType == nil {
+			continue
+		}
+
+		g.addReferencedTypes(g.exportType(compositeType))
+
+		g.addDeclaredComposites(elaboration, declaration.Members.Composites())
+	}
+}
+
+func (g *generator) addScriptOrTransaction(program scriptOrTransaction) {
+	program.name = programName(program.location)
+
+	for _, parameter := range program.parameters {
+		g.addReferencedTypes(g.exportType(parameter.TypeAnnotation.Type))
+	}
+
+	if program.resultType != nil {
+		g.addReferencedTypes(program.resultType)
+	}
+
+	g.programs = append(g.programs, program)
+}
+
+func (g *generator) exportType(t sema.Type) cadence.Type {
+	return runtime.ExportType(t, g.exportedTypes)
+}
+
+// addReferencedTypes adds the composite types referenced by the given type
+func (g *generator) addReferencedTypes(t cadence.Type) {
+	switch t := t.(type) {
+	case *cadence.OptionalType:
+		g.addReferencedTypes(t.Type)
+
+	case *cadence.VariableSizedArrayType:
+		g.addReferencedTypes(t.ElementType)
+
+	case *cadence.ConstantSizedArrayType:
+		g.addReferencedTypes(t.ElementType)
+
+	case *cadence.DictionaryType:
+		g.addReferencedTypes(t.KeyType)
+		g.addReferencedTypes(t.ElementType)
+
+	case *cadence.ReferenceType:
+		g.addReferencedTypes(t.Type)
+
+	case *cadence.CapabilityType:
+		g.addReferencedTypes(t.BorrowType)
+
+	case *cadence.InclusiveRangeType:
+		g.addReferencedTypes(t.ElementType)
+
+	case *cadence.IntersectionType:
+		for _, intersectedType := range t.Types {
+			g.addReferencedTypes(intersectedType)
+		}
+
+	case cadence.CompositeType:
+		if !isGeneratedCompositeType(t) {
+			return
+		}
+
+		typeID := t.ID()
+		if _, ok := g.compositeNames[typeID]; ok {
+			return
+		}
+
+		g.compositeNames[typeID] = exportedName(t.CompositeTypeQualifiedIdentifier())
+		g.composites = append(g.composites, t)
+
+		for _, field := range t.CompositeFields() {
+			g.addReferencedTypes(field.Type)
+		}
+	}
+}
+
+// isGeneratedCompositeType returns true if a Go type is generated for the given composite type.
+// Contracts and attachments cannot be passed as arguments or returned, so they are skipped
+func isGeneratedCompositeType(t cadence.CompositeType) bool {
+	switch t.(type) {
+	case *cadence.StructType, *cadence.ResourceType, *cadence.EventType, *cadence.EnumType:
+		return true
+	default:
+		return false
+	}
+}
+
+func compositeKindDescription(t cadence.CompositeType) string {
+	switch t.(type) {
+	case *cadence.StructType:
+		return "struct"
+	case *cadence.ResourceType:
+		return "resource"
+	case *cadence.EventType:
+		return "event"
+	case *cadence.EnumType:
+		return "enum"
+	default:
+		panic(fmt.Errorf("unsupported composite type %s", t.ID()))
+	}
+}
+
+// declare records the declaration of the given Go identifier,
+// and reports an error if it was already declared
+func (g *generator) declare(name string, description string) error {
+	if existing, ok := g.declaredNames[name]; ok {
+		return fmt.Errorf(
+			"cannot declare %s for %s: already declared for %s",
+			name,
+			description,
+			existing,
+		)
+	}
+	g.declaredNames[name] = description
+	return nil
+}
+
+func (g *generator) use(importPath string) {
+	g.imports[importPath] = struct{}{}
+}
+
+func (g *generator) generate() ([]byte, error) {
+	var body bytes.Buffer
+
+	for _, composite := range g.composites {
+		err := g.generateComposite(&body, composite)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(g.composites) > 0 {
+		err := g.generateCompositeFields(&body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, program := range g.programs {
+		err := g.generateScriptOrTransaction(&body, program)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var source bytes.Buffer
+
+	source.WriteString("// Code generated by cadence-bindgen. DO NOT EDIT.\n\n")
+	_, _ = fmt.Fprintf(&source, "package %s\n\n", g.config.PackageName)
+
+	if len(g.imports) > 0 {
+		importPaths := make([]string, 0, len(g.imports))
+		for importPath := range g.imports { //nolint:maprange
+			importPaths = append(importPaths, importPath)
+		}
+		sort.Slice(importPaths, func(i, j int) bool {
+			a, b := importPaths[i], importPaths[j]
+			if isStandardLibraryImport(a) != isStandardLibraryImport(b) {
+				return isStandardLibraryImport(a)
+			}
+			return a < b
+		})
+
+		source.WriteString("import (\n")
+		for i, importPath := range importPaths {
+			// Separate the standard library imports from the other imports
+			if i > 0 && isStandardLibraryImport(importPaths[i-1]) && !isStandardLibraryImport(importPath) {
+				source.WriteString("\n")
+			}
+			_, _ = fmt.Fprintf(&source, "\t%q\n", importPath)
+		}
+		source.WriteString(")\n\n")
+	}
+
+	source.Write(body.Bytes())
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+
+	return formatted, nil
+}
+
+func isStandardLibraryImport(importPath string) bool {
+	firstElement, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(firstElement, ".")
+}
+
+func (g *generator) generateComposite(w *bytes.Buffer, t cadence.CompositeType) error {
+	name := g.compositeNames[t.ID()]
+	qualifiedIdentifier := t.CompositeTypeQualifiedIdentifier()
+	kind := compositeKindDescription(t)
+	description := fmt.Sprintf("%s %s", kind, t.ID())
+
+	typeName := name + "Type"
+	typeIDName := name + "TypeID"
+	decodeName := "Decode" + name
+
+	for _, declaredName := range []string{name, typeName, typeIDName, decodeName} {
+		err := g.declare(declaredName, description)
+		if err != nil {
+			return err
+		}
+	}
+
+	location, err := g.locationExpression(t.CompositeTypeLocation())
+	if err != nil {
+		return fmt.Errorf("cannot generate %s: %w", description, err)
+	}
+
+	g.use(cadenceImportPath)
+
+	var composite string
+	var rawType string
+
+	switch t := t.(type) {
+	case *cadence.StructType:
+		composite = "cadence.StructType"
+	case *cadence.ResourceType:
+		composite = "cadence.ResourceType"
+	case *cadence.EventType:
+		composite = "cadence.EventType"
+	case *cadence.EnumType:
+		composite = "cadence.EnumType"
+		rawType, err = g.typeExpression(t.RawType)
+		if err != nil {
+			return fmt.Errorf("cannot generate %s: %w", description, err)
+		}
+	}
+
+	// Go type
+
+	if enumType, ok := t.(*cadence.EnumType); ok {
+		err := g.generateEnum(w, enumType, name, description)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := g.generateStruct(w, t, name, description)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Type ID and type
+
+	_, _ = fmt.Fprintf(w, "// %s is the type ID of the %s %s.\n", typeIDName, kind, qualifiedIdentifier)
+	_, _ = fmt.Fprintf(w, "const %s = %q\n\n", typeIDName, t.ID())
+
+	_, _ = fmt.Fprintf(w, "// %s is the Cadence type of the %s %s.\n", typeName, kind, qualifiedIdentifier)
+	_, _ = fmt.Fprintf(w, "var %s = &%s{\n", typeName, composite)
+	_, _ = fmt.Fprintf(w, "Location: %s,\n", location)
+	_, _ = fmt.Fprintf(w, "QualifiedIdentifier: %q,\n", qualifiedIdentifier)
+	if rawType != "" {
+		_, _ = fmt.Fprintf(w, "RawType: %s,\n", rawType)
+	}
+	w.WriteString("}\n\n")
+
+	// Conversion functions
+
+	_, _ = fmt.Fprintf(w, "// ToCadence converts the value to a Cadence value of type %s.\n", typeName)
+	_, _ = fmt.Fprintf(w, "func (v %s) ToCadence() (cadence.Value, error) {\n", name)
+	_, _ = fmt.Fprintf(w, "return cadence.Marshal(v, %s)\n", typeName)
+	w.WriteString("}\n\n")
+
+	_, _ = fmt.Fprintf(w, "// %s decodes a Cadence value of type %s.\n", decodeName, typeName)
+	_, _ = fmt.Fprintf(w, "func %s(value cadence.Value) (result %s, err error) {\n", decodeName, name)
+	w.WriteString("err = cadence.Unmarshal(value, &result)\n")
+	w.WriteString("return\n")
+	w.WriteString("}\n\n")
+
+	return nil
+}
+
+func (g *generator) generateStruct(w *bytes.Buffer, t cadence.CompositeType, name string, description string) error {
+	kind := compositeKindDescription(t)
+
+	_, _ = fmt.Fprintf(
+		w,
+		"// %s is the Go representation of the %s %s.\n",
+		name,
+		kind,
+		t.CompositeTypeQualifiedIdentifier(),
+	)
+	_, _ = fmt.Fprintf(w, "type %s struct {\n", name)
+
+	fieldNames := map[string]struct{}{}
+
+	for _, field := range t.CompositeFields() {
+		fieldName := exportedName(field.Identifier)
+		if _, ok := fieldNames[fieldName]; ok {
+			return fmt.Errorf(
+				"cannot generate %s: field %s conflicts with another field named %s",
+				description,
+				field.Identifier,
+				fieldName,
+			)
+		}
+		fieldNames[fieldName] = struct{}{}
+
+		_, _ = fmt.Fprintf(
+			w,
+			"%s %s `cadence:%q`\n",
+			fieldName,
+			g.goType(field.Type),
+			field.Identifier,
+		)
+	}
+
+	w.WriteString("}\n\n")
+
+	return nil
+}
+
+func (g *generator) generateEnum(w *bytes.Buffer, t *cadence.EnumType, name string, description string) error {
+	rawType := g.goType(t.RawType)
+
+	if !isGoFixedSizeIntegerType(rawType) {
+		return fmt.Errorf("cannot generate %s: unsupported raw type %s", description, t.RawType.ID())
+	}
+
+	_, _ = fmt.Fprintf(
+		w,
+		"// %s is the Go representation of the enum %s.\n",
+		name,
+		t.QualifiedIdentifier,
+	)
+	_, _ = fmt.Fprintf(w, "type %s %s\n\n", name, rawType)
+
+	cases := g.enumCases[t.ID()]
+	if len(cases) == 0 {
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(w, "// The cases of the enum %s.\n", t.QualifiedIdentifier)
+	w.WriteString("const (\n")
+
+	for rawValue, enumCase := range cases {
+		caseName := name + exportedName(enumCase)
+
+		err := g.declare(caseName, fmt.Sprintf("case %s of %s", enumCase, description))
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(w, "%s %s = %d\n", caseName, name, rawValue)
+	}
+
+	w.WriteString(")\n\n")
+
+	return nil
+}
+
+// generateCompositeFields generates an init function which sets the fields of all composite types.
+// The fields are set after all types are declared, as composite types may be recursive
+func (g *generator) generateCompositeFields(w *bytes.Buffer) error {
+	w.WriteString("func init() {\n")
+
+	for _, composite := range g.composites {
+		fields := composite.CompositeFields()
+		if len(fields) == 0 {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(w, "%sType.Fields = []cadence.Field{\n", g.compositeNames[composite.ID()])
+
+		for _, field := range fields {
+			fieldType, err := g.typeExpression(field.Type)
+			if err != nil {
+				return fmt.Errorf(
+					"cannot generate field %s of %s: %w",
+					field.Identifier,
+					composite.ID(),
+					err,
+				)
+			}
+
+			_, _ = fmt.Fprintf(w, "{Identifier: %q, Type: %s},\n", field.Identifier, fieldType)
+		}
+
+		w.WriteString("}\n")
+	}
+
+	w.WriteString("}\n\n")
+
+	return nil
+}
+
+func (g *generator) generateScriptOrTransaction(w *bytes.Buffer, program scriptOrTransaction) error {
+	kind := program.kind.String()
+	description := fmt.Sprintf("%s %s", kind, programDescription(program.location))
+
+	sourceName := program.name + exportedName(kind)
+	argumentsName := "New" + program.name + "Arguments"
+
+	for _, declaredName := range []string{sourceName, argumentsName} {
+		err := g.declare(declaredName, description)
+		if err != nil {
+			return err
+		}
+	}
+
+	g.use(cadenceImportPath)
+
+	// Source
+
+	_, _ = fmt.Fprintf(w, "// %s is the source code of the %s.\n", sourceName, description)
+	_, _ = fmt.Fprintf(w, "const %s = %s\n\n", sourceName, stringLiteral(string(program.code)))
+
+	// Arguments
+
+	parameterNames := make([]string, len(program.parameters))
+	parameterDeclarations := make([]string, len(program.parameters))
+	usedParameterNames := map[string]struct{}{}
+
+	for i, parameter := range program.parameters {
+		parameterName := parameterGoName(parameter.Identifier, i)
+		for {
+			if _, ok := usedParameterNames[parameterName]; !ok {
+				break
+			}
+			parameterName += "_"
+		}
+		usedParameterNames[parameterName] = struct{}{}
+
+		parameterNames[i] = parameterName
+
+		parameterType := g.exportType(parameter.TypeAnnotation.Type)
+		parameterDeclarations[i] = fmt.Sprintf("%s %s", parameterName, g.goType(parameterType))
+	}
+
+	_, _ = fmt.Fprintf(w, "// %s returns the arguments for the %s.\n", argumentsName, description)
+	_, _ = fmt.Fprintf(
+		w,
+		"func %s(%s) ([]cadence.Value, error) {\n",
+		argumentsName,
+		strings.Join(parameterDeclarations, ", "),
+	)
+
+	if len(program.parameters) == 0 {
+		w.WriteString("return nil, nil\n")
+	} else {
+		g.use(fmtImportPath)
+
+		_, _ = fmt.Fprintf(w, "arguments := make([]cadence.Value, %d)\n", len(program.parameters))
+		w.WriteString("var err error\n\n")
+
+		for i, parameter := range program.parameters {
+			parameterType, err := g.typeExpression(g.exportType(parameter.TypeAnnotation.Type))
+			if err != nil {
+				return fmt.Errorf(
+					"cannot generate parameter %s of %s: %w",
+					parameter.Identifier,
+					description,
+					err,
+				)
+			}
+
+			_, _ = fmt.Fprintf(w, "arguments[%d], err = cadence.Marshal(%s, %s)\n", i, parameterNames[i], parameterType)
+			w.WriteString("if err != nil {\n")
+			_, _ = fmt.Fprintf(w, "return nil, fmt.Errorf(\"invalid argument %s: %%w\", err)\n", parameter.Identifier)
+			w.WriteString("}\n\n")
+		}
+
+		w.WriteString("return arguments, nil\n")
+	}
+
+	w.WriteString("}\n\n")
+
+	// Result
+
+	if program.resultType != nil {
+		decodeName := "Decode" + program.name + "Result"
+		err := g.declare(decodeName, description)
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(w, "// %s decodes the result of the %s.\n", decodeName, description)
+		_, _ = fmt.Fprintf(
+			w,
+			"func %s(value cadence.Value) (result %s, err error) {\n",
+			decodeName,
+			g.goType(program.resultType),
+		)
+		w.WriteString("err = cadence.Unmarshal(value, &result)\n")
+		w.WriteString("return\n")
+		w.WriteString("}\n\n")
+	}
+
+	return nil
+}
+
+// programName returns the name of the script or transaction at the given location,
+// used as the prefix of the generated declarations.
+// For example, the name of the file `scripts/get_balance.cdc` is `GetBalance`
+func programName(location common.Location) string {
+	var name string
+	switch location := location.(type) {
+	case common.StringLocation:
+		name = filepath.Base(string(location))
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	case common.AddressLocation:
+		name = location.Name
+	default:
+		name = location.ID()
+	}
+
+	return exportedName(name)
+}
+
+// programDescription returns the description of the given location in comments
+func programDescription(location common.Location) string {
+	switch location := location.(type) {
+	case common.StringLocation:
+		// Avoid including the full path, which depends on the environment
+		return filepath.Base(string(location))
+	default:
+		return location.String()
+	}
+}
+
+// stringLiteral returns a Go string literal for the given string.
+// A raw string literal is used if possible, to keep the source code readable
+func stringLiteral(s string) string {
+	if strings.ContainsAny(s, "`\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
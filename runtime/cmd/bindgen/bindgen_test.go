@@ -0,0 +1,355 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bindgen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+var testContractLocation = common.AddressLocation{
+	Address: common.MustBytesToAddress([]byte{0x1}),
+	Name:    "Example",
+}
+
+const testContract = `
+  access(all) contract Example {
+
+      access(all) enum Color: UInt8 {
+          access(all) case red
+          access(all) case green
+      }
+
+      access(all) struct Item {
+          access(all) let id: UInt64
+          access(all) let color: Color
+          access(all) let next: Item?
+
+          init(id: UInt64, color: Color, next: Item?) {
+              self.id = id
+              self.color = color
+              self.next = next
+          }
+      }
+
+      access(all) event Minted(id: UInt64, to: Address?)
+  }
+`
+
+// load loads the programs at the given locations,
+// resolving the test contract and the given codes
+func load(t *testing.T, codes map[common.Location]string, locations ...common.Location) analysis.Programs {
+	codeBytes := map[common.Location][]byte{
+		testContractLocation: []byte(testContract),
+	}
+	for location, code := range codes { //nolint:maprange
+		codeBytes[location] = []byte(code)
+	}
+
+	config := analysis.NewSimpleConfig(
+		analysis.NeedTypes,
+		codeBytes,
+		map[common.Address][]string{
+			testContractLocation.Address: {testContractLocation.Name},
+		},
+		nil,
+	)
+
+	programs, err := analysis.Load(config, locations...)
+	require.NoError(t, err)
+
+	return programs
+}
+
+func generate(t *testing.T, codes map[common.Location]string, locations ...common.Location) ([]byte, error) {
+	programs := load(t, codes, locations...)
+
+	return Generate(
+		Config{
+			PackageName: "bindings",
+		},
+		programs,
+		locations...,
+	)
+}
+
+func TestGenerate(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("contract", func(t *testing.T) {
+		t.Parallel()
+
+		code, err := generate(t, nil, testContractLocation)
+		require.NoError(t, err)
+
+		assert.Equal(t,
+			`// Code generated by cadence-bindgen. DO NOT EDIT.
+
+package bindings
+
+import (
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// ExampleColor is the Go representation of the enum Example.Color.
+type ExampleColor uint8
+
+// The cases of the enum Example.Color.
+const (
+	ExampleColorRed   ExampleColor = 0
+	ExampleColorGreen ExampleColor = 1
+)
+
+// ExampleColorTypeID is the type ID of the enum Example.Color.
+const ExampleColorTypeID = "A.0000000000000001.Example.Color"
+
+// ExampleColorType is the Cadence type of the enum Example.Color.
+var ExampleColorType = &cadence.EnumType{
+	Location:            common.AddressLocation{Address: common.Address{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}, Name: "Example"},
+	QualifiedIdentifier: "Example.Color",
+	RawType:             cadence.UInt8Type,
+}
+
+// ToCadence converts the value to a Cadence value of type ExampleColorType.
+func (v ExampleColor) ToCadence() (cadence.Value, error) {
+	return cadence.Marshal(v, ExampleColorType)
+}
+
+// DecodeExampleColor decodes a Cadence value of type ExampleColorType.
+func DecodeExampleColor(value cadence.Value) (result ExampleColor, err error) {
+	err = cadence.Unmarshal(value, &result)
+	return
+}
+
+// ExampleItem is the Go representation of the struct Example.Item.
+type ExampleItem struct {
+	ID    uint64       `+"`"+`cadence:"id"`+"`"+`
+	Color ExampleColor `+"`"+`cadence:"color"`+"`"+`
+	Next  *ExampleItem `+"`"+`cadence:"next"`+"`"+`
+}
+
+// ExampleItemTypeID is the type ID of the struct Example.Item.
+const ExampleItemTypeID = "A.0000000000000001.Example.Item"
+
+// ExampleItemType is the Cadence type of the struct Example.Item.
+var ExampleItemType = &cadence.StructType{
+	Location:            common.AddressLocation{Address: common.Address{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}, Name: "Example"},
+	QualifiedIdentifier: "Example.Item",
+}
+
+// ToCadence converts the value to a Cadence value of type ExampleItemType.
+func (v ExampleItem) ToCadence() (cadence.Value, error) {
+	return cadence.Marshal(v, ExampleItemType)
+}
+
+// DecodeExampleItem decodes a Cadence value of type ExampleItemType.
+func DecodeExampleItem(value cadence.Value) (result ExampleItem, err error) {
+	err = cadence.Unmarshal(value, &result)
+	return
+}
+
+// ExampleMinted is the Go representation of the event Example.Minted.
+type ExampleMinted struct {
+	ID uint64           `+"`"+`cadence:"id"`+"`"+`
+	To *cadence.Address `+"`"+`cadence:"to"`+"`"+`
+}
+
+// ExampleMintedTypeID is the type ID of the event Example.Minted.
+const ExampleMintedTypeID = "A.0000000000000001.Example.Minted"
+
+// ExampleMintedType is the Cadence type of the event Example.Minted.
+var ExampleMintedType = &cadence.EventType{
+	Location:            common.AddressLocation{Address: common.Address{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}, Name: "Example"},
+	QualifiedIdentifier: "Example.Minted",
+}
+
+// ToCadence converts the value to a Cadence value of type ExampleMintedType.
+func (v ExampleMinted) ToCadence() (cadence.Value, error) {
+	return cadence.Marshal(v, ExampleMintedType)
+}
+
+// DecodeExampleMinted decodes a Cadence value of type ExampleMintedType.
+func DecodeExampleMinted(value cadence.Value) (result ExampleMinted, err error) {
+	err = cadence.Unmarshal(value, &result)
+	return
+}
+
+func init() {
+	ExampleColorType.Fields = []cadence.Field{
+		{Identifier: "rawValue", Type: cadence.UInt8Type},
+	}
+	ExampleItemType.Fields = []cadence.Field{
+		{Identifier: "id", Type: cadence.UInt64Type},
+		{Identifier: "color", Type: ExampleColorType},
+		{Identifier: "next", Type: cadence.NewOptionalType(ExampleItemType)},
+	}
+	ExampleMintedType.Fields = []cadence.Field{
+		{Identifier: "id", Type: cadence.UInt64Type},
+		{Identifier: "to", Type: cadence.NewOptionalType(cadence.AddressType)},
+	}
+}
+`,
+			string(code),
+		)
+	})
+
+	t.Run("script", func(t *testing.T) {
+		t.Parallel()
+
+		location := common.StringLocation("scripts/get_items.cdc")
+
+		code, err := generate(
+			t,
+			map[common.Location]string{
+				location: `
+                  import Example from 0x1
+
+                  access(all) fun main(ids: [UInt64], supply: {Int: String}): [Example.Item] {
+                      return []
+                  }
+                `,
+			},
+			location,
+		)
+		require.NoError(t, err)
+
+		for _, expected := range []string{
+			// Imported composite types used by the script are generated
+			"type ExampleItem struct {",
+			"type ExampleColor uint8",
+			"const GetItemsScript = `\n",
+			// Dictionaries with keys which are not comparable in Go are kept as Cadence values
+			"func NewGetItemsArguments(ids []uint64, supply cadence.Value) ([]cadence.Value, error) {",
+			"arguments[0], err = cadence.Marshal(ids, cadence.NewVariableSizedArrayType(cadence.UInt64Type))",
+			"arguments[1], err = cadence.Marshal(supply, cadence.NewDictionaryType(cadence.IntType, cadence.StringType))",
+			`return nil, fmt.Errorf("invalid argument supply: %w", err)`,
+			"func DecodeGetItemsResult(value cadence.Value) (result []ExampleItem, err error) {",
+		} {
+			assert.Contains(t, string(code), expected)
+		}
+
+		// Types of the contract which are not used are not generated
+		assert.NotContains(t, string(code), "ExampleMinted")
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		t.Parallel()
+
+		location := common.StringLocation("mint.cdc")
+
+		code, err := generate(
+			t,
+			map[common.Location]string{
+				location: "transaction(type: String, amount: UInt256?, err: StoragePath) {}",
+			},
+			location,
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t,
+			"// Code generated by cadence-bindgen. DO NOT EDIT.\n"+
+				`
+package bindings
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/onflow/cadence"
+)
+
+// MintTransaction is the source code of the transaction mint.cdc.
+const MintTransaction = `+"`"+`transaction(type: String, amount: UInt256?, err: StoragePath) {}`+"`"+`
+
+// NewMintArguments returns the arguments for the transaction mint.cdc.
+func NewMintArguments(type_ string, amount *big.Int, err_ cadence.Path) ([]cadence.Value, error) {
+	arguments := make([]cadence.Value, 3)
+	var err error
+
+	arguments[0], err = cadence.Marshal(type_, cadence.StringType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid argument type: %w", err)
+	}
+
+	arguments[1], err = cadence.Marshal(amount, cadence.NewOptionalType(cadence.UInt256Type))
+	if err != nil {
+		return nil, fmt.Errorf("invalid argument amount: %w", err)
+	}
+
+	arguments[2], err = cadence.Marshal(err_, cadence.StoragePathType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid argument err: %w", err)
+	}
+
+	return arguments, nil
+}
+`,
+			string(code),
+		)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		t.Parallel()
+
+		location := common.StringLocation("test.cdc")
+
+		_, err := generate(
+			t,
+			map[common.Location]string{
+				location: `
+                  access(all) struct A_B {}
+
+                  access(all) struct AB {}
+
+                  access(all) fun main(a: A_B, b: AB) {}
+                `,
+			},
+			location,
+		)
+		require.EqualError(t,
+			err,
+			"cannot declare AB for struct S.test.cdc.AB: already declared for struct S.test.cdc.A_B",
+		)
+	})
+
+	t.Run("not a script or transaction", func(t *testing.T) {
+		t.Parallel()
+
+		location := common.StringLocation("test.cdc")
+
+		_, err := generate(
+			t,
+			map[common.Location]string{
+				location: "access(all) fun test() {}",
+			},
+			location,
+		)
+		require.EqualError(t,
+			err,
+			"program test.cdc is neither a contract, a contract interface, a transaction, nor a script",
+		)
+	})
+}
@@ -0,0 +1,116 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bindgen
+
+import "github.com/onflow/cadence"
+
+// primitiveTypeNames are the names of the variables
+var primitiveTypeNames = map[cadence.PrimitiveType]string{
+	// of the primitive types declared in the cadence package
+	cadence.VoidType:                             "VoidType",
+	cadence.AnyType:                              "AnyType",
+	cadence.NeverType:                            "NeverType",
+	cadence.AnyStructType:                        "AnyStructType",
+	cadence.AnyResourceType:                      "AnyResourceType",
+	cadence.AnyStructAttachmentType:              "AnyStructAttachmentType",
+	cadence.AnyResourceAttachmentType:            "AnyResourceAttachmentType",
+	cadence.HashableStructType:                   "HashableStructType",
+	cadence.BoolType:                             "BoolType",
+	cadence.AddressType:                          "AddressType",
+	cadence.StringType:                           "StringType",
+	cadence.CharacterType:                        "CharacterType",
+	cadence.MetaType:                             "MetaType",
+	cadence.BlockType:                            "BlockType",
+	cadence.NumberType:                           "NumberType",
+	cadence.SignedNumberType:                     "SignedNumberType",
+	cadence.IntegerType:                          "IntegerType",
+	cadence.SignedIntegerType:                    "SignedIntegerType",
+	cadence.FixedSizeUnsignedIntegerType:         "FixedSizeUnsignedIntegerType",
+	cadence.FixedPointType:                       "FixedPointType",
+	cadence.SignedFixedPointType:                 "SignedFixedPointType",
+	cadence.IntType:                              "IntType",
+	cadence.Int8Type:                             "Int8Type",
+	cadence.Int16Type:                            "Int16Type",
+	cadence.Int32Type:                            "Int32Type",
+	cadence.Int64Type:                            "Int64Type",
+	cadence.Int128Type:                           "Int128Type",
+	cadence.Int256Type:                           "Int256Type",
+	cadence.UIntType:                             "UIntType",
+	cadence.UInt8Type:                            "UInt8Type",
+	cadence.UInt16Type:                           "UInt16Type",
+	cadence.UInt32Type:                           "UInt32Type",
+	cadence.UInt64Type:                           "UInt64Type",
+	cadence.UInt128Type:                          "UInt128Type",
+	cadence.UInt256Type:                          "UInt256Type",
+	cadence.Word8Type:                            "Word8Type",
+	cadence.Word16Type:                           "Word16Type",
+	cadence.Word32Type:                           "Word32Type",
+	cadence.Word64Type:                           "Word64Type",
+	cadence.Word128Type:                          "Word128Type",
+	cadence.Word256Type:                          "Word256Type",
+	cadence.Fix64Type:                            "Fix64Type",
+	cadence.UFix64Type:                           "UFix64Type",
+	cadence.PathType:                             "PathType",
+	cadence.CapabilityPathType:                   "CapabilityPathType",
+	cadence.StoragePathType:                      "StoragePathType",
+	cadence.PublicPathType:                       "PublicPathType",
+	cadence.PrivatePathType:                      "PrivatePathType",
+	cadence.DeployedContractType:                 "DeployedContractType",
+	cadence.StorageCapabilityControllerType:      "StorageCapabilityControllerType",
+	cadence.AccountCapabilityControllerType:      "AccountCapabilityControllerType",
+	cadence.AccountType:                          "AccountType",
+	cadence.Account_ContractsType:                "Account_ContractsType",
+	cadence.Account_KeysType:                     "Account_KeysType",
+	cadence.Account_StorageType:                  "Account_StorageType",
+	cadence.Account_InboxType:                    "Account_InboxType",
+	cadence.Account_CapabilitiesType:             "Account_CapabilitiesType",
+	cadence.Account_StorageCapabilitiesType:      "Account_StorageCapabilitiesType",
+	cadence.Account_AccountCapabilitiesType:      "Account_AccountCapabilitiesType",
+	cadence.MutateType:                           "MutateType",
+	cadence.InsertType:                           "InsertType",
+	cadence.RemoveType:                           "RemoveType",
+	cadence.IdentityType:                         "IdentityType",
+	cadence.StorageType:                          "StorageType",
+	cadence.SaveValueType:                        "SaveValueType",
+	cadence.LoadValueType:                        "LoadValueType",
+	cadence.CopyValueType:                        "CopyValueType",
+	cadence.BorrowValueType:                      "BorrowValueType",
+	cadence.ContractsType:                        "ContractsType",
+	cadence.AddContractType:                      "AddContractType",
+	cadence.UpdateContractType:                   "UpdateContractType",
+	cadence.RemoveContractType:                   "RemoveContractType",
+	cadence.KeysType:                             "KeysType",
+	cadence.AddKeyType:                           "AddKeyType",
+	cadence.RevokeKeyType:                        "RevokeKeyType",
+	cadence.InboxType:                            "InboxType",
+	cadence.PublishInboxCapabilityType:           "PublishInboxCapabilityType",
+	cadence.UnpublishInboxCapabilityType:         "UnpublishInboxCapabilityType",
+	cadence.ClaimInboxCapabilityType:             "ClaimInboxCapabilityType",
+	cadence.CapabilitiesType:                     "CapabilitiesType",
+	cadence.StorageCapabilitiesType:              "StorageCapabilitiesType",
+	cadence.AccountCapabilitiesType:              "AccountCapabilitiesType",
+	cadence.PublishCapabilityType:                "PublishCapabilityType",
+	cadence.UnpublishCapabilityType:              "UnpublishCapabilityType",
+	cadence.GetStorageCapabilityControllerType:   "GetStorageCapabilityControllerType",
+	cadence.IssueStorageCapabilityControllerType: "IssueStorageCapabilityControllerType",
+	cadence.GetAccountCapabilityControllerType:   "GetAccountCapabilityControllerType",
+	cadence.IssueAccountCapabilityControllerType: "IssueAccountCapabilityControllerType",
+	cadence.CapabilitiesMappingType:              "CapabilitiesMappingType",
+	cadence.AccountMappingType:                   "AccountMappingType",
+}
@@ -0,0 +1,363 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bindgen
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// goType returns the Go type used to represent values of the given Cadence type.
+// Values of types without a more specific representation are represented as cadence.Value
+func (g *generator) goType(t cadence.Type) string {
+	switch t := t.(type) {
+	case cadence.PrimitiveType:
+		return g.primitiveGoType(t)
+
+	case *cadence.OptionalType:
+		innerType := g.goType(t.Type)
+		if isNilableGoType(innerType) {
+			return innerType
+		}
+		return "*" + innerType
+
+	case *cadence.VariableSizedArrayType:
+		return "[]" + g.goType(t.ElementType)
+
+	case *cadence.ConstantSizedArrayType:
+		return fmt.Sprintf("[%d]%s", t.Size, g.goType(t.ElementType))
+
+	case *cadence.DictionaryType:
+		keyType := g.goType(t.KeyType)
+		// Big integers and Cadence values are not usable as map keys,
+		// so the dictionary is kept as a Cadence value
+		if isNilableGoType(keyType) {
+			return "cadence.Value"
+		}
+		return fmt.Sprintf("map[%s]%s", keyType, g.goType(t.ElementType))
+
+	case cadence.CompositeType:
+		if name, ok := g.compositeNames[t.ID()]; ok {
+			return name
+		}
+	}
+
+	return "cadence.Value"
+}
+
+func (g *generator) primitiveGoType(t cadence.PrimitiveType) string {
+	switch t {
+	case cadence.BoolType:
+		return "bool"
+
+	case cadence.StringType, cadence.CharacterType:
+		return "string"
+
+	case cadence.AddressType:
+		return "cadence.Address"
+
+	case cadence.Int8Type:
+		return "int8"
+	case cadence.Int16Type:
+		return "int16"
+	case cadence.Int32Type:
+		return "int32"
+	case cadence.Int64Type:
+		return "int64"
+
+	case cadence.UInt8Type, cadence.Word8Type:
+		return "uint8"
+	case cadence.UInt16Type, cadence.Word16Type:
+		return "uint16"
+	case cadence.UInt32Type, cadence.Word32Type:
+		return "uint32"
+	case cadence.UInt64Type, cadence.Word64Type:
+		return "uint64"
+
+	case cadence.IntType, cadence.Int128Type, cadence.Int256Type,
+		cadence.UIntType, cadence.UInt128Type, cadence.UInt256Type,
+		cadence.Word128Type, cadence.Word256Type:
+
+		g.use(bigImportPath)
+		return "*big.Int"
+
+	case cadence.Fix64Type:
+		return "cadence.Fix64"
+	case cadence.UFix64Type:
+		return "cadence.UFix64"
+
+	case cadence.PathType,
+		cadence.CapabilityPathType,
+		cadence.StoragePathType,
+		cadence.PublicPathType,
+		cadence.PrivatePathType:
+
+		return "cadence.Path"
+
+	default:
+		return "cadence.Value"
+	}
+}
+
+// isNilableGoType returns true if the given Go type has nil as a value,
+// so it can represent optionals without an additional pointer
+func isNilableGoType(goType string) bool {
+	return goType == "cadence.Value" ||
+		strings.HasPrefix(goType, "*") ||
+		strings.HasPrefix(goType, "[]") ||
+		strings.HasPrefix(goType, "map[")
+}
+
+func isGoFixedSizeIntegerType(goType string) bool {
+	switch goType {
+	case "int8", "int16", "int32", "int64",
+		"uint8", "uint16", "uint32", "uint64":
+		return true
+	default:
+		return false
+	}
+}
+
+// typeExpression returns a Go expression which evaluates to the given Cadence type
+func (g *generator) typeExpression(t cadence.Type) (string, error) {
+	switch t := t.(type) {
+	case cadence.PrimitiveType:
+		if name, ok := primitiveTypeNames[t]; ok {
+			return "cadence." + name, nil
+		}
+
+	case *cadence.OptionalType:
+		innerType, err := g.typeExpression(t.Type)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("cadence.NewOptionalType(%s)", innerType), nil
+
+	case *cadence.VariableSizedArrayType:
+		elementType, err := g.typeExpression(t.ElementType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("cadence.NewVariableSizedArrayType(%s)", elementType), nil
+
+	case *cadence.ConstantSizedArrayType:
+		elementType, err := g.typeExpression(t.ElementType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("cadence.NewConstantSizedArrayType(%d, %s)", t.Size, elementType), nil
+
+	case *cadence.DictionaryType:
+		keyType, err := g.typeExpression(t.KeyType)
+		if err != nil {
+			return "", err
+		}
+		elementType, err := g.typeExpression(t.ElementType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("cadence.NewDictionaryType(%s, %s)", keyType, elementType), nil
+
+	case *cadence.ReferenceType:
+		authorization, err := g.authorizationExpression(t.Authorization)
+		if err != nil {
+			return "", err
+		}
+		referencedType, err := g.typeExpression(t.Type)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("cadence.NewReferenceType(%s, %s)", authorization, referencedType), nil
+
+	case *cadence.IntersectionType:
+		types := make([]string, 0, len(t.Types))
+		for _, intersectedType := range t.Types {
+			typ, err := g.typeExpression(intersectedType)
+			if err != nil {
+				return "", err
+			}
+			types = append(types, typ)
+		}
+		return fmt.Sprintf(
+			"cadence.NewIntersectionType([]cadence.Type{%s})",
+			strings.Join(types, ", "),
+		), nil
+
+	case *cadence.CapabilityType:
+		if t.BorrowType == nil {
+			return "cadence.NewCapabilityType(nil)", nil
+		}
+		borrowType, err := g.typeExpression(t.BorrowType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("cadence.NewCapabilityType(%s)", borrowType), nil
+
+	case *cadence.InclusiveRangeType:
+		elementType, err := g.typeExpression(t.ElementType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("cadence.NewInclusiveRangeType(%s)", elementType), nil
+
+	case cadence.CompositeType:
+		if name, ok := g.compositeNames[t.ID()]; ok {
+			return name + "Type", nil
+		}
+	}
+
+	// Types which are not generated, e.g. interface types, are only referred to by their ID
+	return fmt.Sprintf("cadence.TypeID(%q)", t.ID()), nil
+}
+
+func (g *generator) authorizationExpression(authorization cadence.Authorization) (string, error) {
+	switch authorization := authorization.(type) {
+	case cadence.Unauthorized:
+		return "cadence.UnauthorizedAccess", nil
+
+	case *cadence.EntitlementSetAuthorization:
+		g.use(commonImportPath)
+
+		entitlements := make([]string, 0, len(authorization.Entitlements))
+		for _, entitlement := range authorization.Entitlements {
+			entitlements = append(entitlements, fmt.Sprintf("%q", entitlement))
+		}
+
+		var kind string
+		switch authorization.Kind {
+		case cadence.Conjunction:
+			kind = "cadence.Conjunction"
+		case cadence.Disjunction:
+			kind = "cadence.Disjunction"
+		default:
+			return "", fmt.Errorf("unsupported entitlement set kind %d", authorization.Kind)
+		}
+
+		return fmt.Sprintf(
+			"cadence.NewEntitlementSetAuthorization(nil, []common.TypeID{%s}, %s)",
+			strings.Join(entitlements, ", "),
+			kind,
+		), nil
+
+	case cadence.EntitlementMapAuthorization:
+		return fmt.Sprintf("cadence.NewEntitlementMapAuthorization(nil, %q)", authorization.TypeID), nil
+
+	default:
+		return "", fmt.Errorf("unsupported authorization %T", authorization)
+	}
+}
+
+// locationExpression returns a Go expression which evaluates to the given location
+func (g *generator) locationExpression(location common.Location) (string, error) {
+	g.use(commonImportPath)
+
+	switch location := location.(type) {
+	case common.AddressLocation:
+		addressBytes := make([]string, 0, len(location.Address))
+		for _, b := range location.Address {
+			addressBytes = append(addressBytes, fmt.Sprintf("0x%02x", b))
+		}
+
+		return fmt.Sprintf(
+			"common.AddressLocation{Address: common.Address{%s}, Name: %q}",
+			strings.Join(addressBytes, ", "),
+			location.Name,
+		), nil
+
+	case common.StringLocation:
+		return fmt.Sprintf("common.StringLocation(%q)", string(location)), nil
+
+	case common.IdentifierLocation:
+		return fmt.Sprintf("common.IdentifierLocation(%q)", string(location)), nil
+
+	default:
+		return "", fmt.Errorf("unsupported location %s", location)
+	}
+}
+
+// goInitialisms are the words which are capitalized entirely in exported Go names,
+// following the Go naming conventions
+var goInitialisms = map[string]string{
+	"id":   "ID",
+	"ids":  "IDs",
+	"uuid": "UUID",
+	"url":  "URL",
+	"uri":  "URI",
+	"nft":  "NFT",
+	"json": "JSON",
+	"http": "HTTP",
+}
+
+// exportedName returns an exported Go name for the given Cadence identifier,
+// e.g. `Foo.Bar` becomes `FooBar`, and `token_id` becomes `TokenID`.
+// Any characters which are not letters or digits are treated as word separators
+func exportedName(identifier string) string {
+	words := strings.FieldsFunc(identifier, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var builder strings.Builder
+	for _, word := range words {
+		if initialism, ok := goInitialisms[word]; ok {
+			builder.WriteString(initialism)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		builder.WriteString(string(runes))
+	}
+
+	name := builder.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// reservedParameterNames are the identifiers used in the generated argument functions,
+// which parameters must not shadow
+var reservedParameterNames = map[string]struct{}{
+	"arguments": {},
+	"err":       {},
+	"make":      {},
+	"nil":       {},
+	"cadence":   {},
+	"common":    {},
+	"big":       {},
+	"fmt":       {},
+}
+
+// parameterGoName returns the Go name for the given Cadence parameter identifier
+func parameterGoName(identifier string, index int) string {
+	if identifier == "" || identifier == "_" {
+		return fmt.Sprintf("argument%d", index)
+	}
+
+	_, reserved := reservedParameterNames[identifier]
+	if reserved || token.IsKeyword(identifier) {
+		return identifier + "_"
+	}
+
+	return identifier
+}
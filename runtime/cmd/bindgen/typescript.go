@@ -0,0 +1,740 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bindgen
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+// TypeScript declarations which are generated on demand.
+// They are written in this order, before the generated types
+const (
+	tsJsonCadenceValue = "JsonCadenceValue"
+	tsCadencePath      = "CadencePath"
+	tsDictionaryEntry  = "DictionaryEntry"
+
+	tsExpectType              = "expectType"
+	tsDecodeBool              = "decodeBool"
+	tsDecodeNumber            = "decodeNumber"
+	tsDecodeBigInt            = "decodeBigInt"
+	tsDecodeString            = "decodeString"
+	tsDecodePath              = "decodePath"
+	tsDecodeAny               = "decodeAny"
+	tsDecodeOptional          = "decodeOptional"
+	tsDecodeArray             = "decodeArray"
+	tsDecodeDictionary        = "decodeDictionary"
+	tsDecodeDictionaryEntries = "decodeDictionaryEntries"
+	tsDecodeComposite         = "decodeComposite"
+)
+
+type tsDeclaration struct {
+	name     string
+	code     string
+	requires []string
+}
+
+var tsDeclarations = []tsDeclaration{
+	{
+		name: tsJsonCadenceValue,
+		code: `// JsonCadenceValue is a value encoded in the JSON-Cadence Data Interchange Format.
+export interface JsonCadenceValue {
+  type: string;
+  value?: any;
+}
+`,
+	},
+	{
+		name: tsCadencePath,
+		code: `// CadencePath is the TypeScript representation of a Cadence path.
+export interface CadencePath {
+  domain: string;
+  identifier: string;
+}
+`,
+	},
+	{
+		name: tsDictionaryEntry,
+		code: `// DictionaryEntry is an entry of a Cadence dictionary,
+// used to represent dictionaries with keys that are not comparable in maps.
+export interface DictionaryEntry<K, V> {
+  key: K;
+  value: V;
+}
+`,
+	},
+	{
+		name: tsExpectType,
+		code: `function expectType(value: JsonCadenceValue, type: string): any {
+  if (value.type !== type) {
+    throw new Error(` + "`expected JSON-Cadence value of type ${type}, got ${value.type}`" + `);
+  }
+  return value.value;
+}
+`,
+	},
+	{
+		name: tsDecodeBool,
+		code: `function decodeBool(value: JsonCadenceValue): boolean {
+  return expectType(value, "Bool");
+}
+`,
+		requires: []string{tsExpectType},
+	},
+	{
+		name: tsDecodeNumber,
+		code: `function decodeNumber(type: string): (value: JsonCadenceValue) => number {
+  return (value) => Number(expectType(value, type));
+}
+`,
+		requires: []string{tsExpectType},
+	},
+	{
+		name: tsDecodeBigInt,
+		code: `function decodeBigInt(type: string): (value: JsonCadenceValue) => bigint {
+  return (value) => BigInt(expectType(value, type));
+}
+`,
+		requires: []string{tsExpectType},
+	},
+	{
+		name: tsDecodeString,
+		code: `function decodeString(type: string): (value: JsonCadenceValue) => string {
+  return (value) => expectType(value, type);
+}
+`,
+		requires: []string{tsExpectType},
+	},
+	{
+		name: tsDecodePath,
+		code: `function decodePath(value: JsonCadenceValue): CadencePath {
+  return expectType(value, "Path");
+}
+`,
+		requires: []string{tsExpectType, tsCadencePath},
+	},
+	{
+		name: tsDecodeAny,
+		code: `function decodeAny(value: JsonCadenceValue): JsonCadenceValue {
+  return value;
+}
+`,
+	},
+	{
+		name: tsDecodeOptional,
+		code: `function decodeOptional<T>(
+  decode: (value: JsonCadenceValue) => T,
+): (value: JsonCadenceValue) => T | null {
+  return (value) => {
+    const inner = expectType(value, "Optional");
+    return inner === null ? null : decode(inner);
+  };
+}
+`,
+		requires: []string{tsExpectType},
+	},
+	{
+		name: tsDecodeArray,
+		code: `function decodeArray<T>(
+  decode: (value: JsonCadenceValue) => T,
+): (value: JsonCadenceValue) => T[] {
+  return (value) =>
+    expectType(value, "Array").map((element: JsonCadenceValue) => decode(element));
+}
+`,
+		requires: []string{tsExpectType},
+	},
+	{
+		name: tsDecodeDictionary,
+		code: `function decodeDictionary<K, V>(
+  decodeKey: (value: JsonCadenceValue) => K,
+  decodeValue: (value: JsonCadenceValue) => V,
+): (value: JsonCadenceValue) => Map<K, V> {
+  return (value) =>
+    new Map(
+      expectType(value, "Dictionary").map(
+        (entry: { key: JsonCadenceValue; value: JsonCadenceValue }): [K, V] => [
+          decodeKey(entry.key),
+          decodeValue(entry.value),
+        ],
+      ),
+    );
+}
+`,
+		requires: []string{tsExpectType},
+	},
+	{
+		name: tsDecodeDictionaryEntries,
+		code: `function decodeDictionaryEntries<K, V>(
+  decodeKey: (value: JsonCadenceValue) => K,
+  decodeValue: (value: JsonCadenceValue) => V,
+): (value: JsonCadenceValue) => DictionaryEntry<K, V>[] {
+  return (value) =>
+    expectType(value, "Dictionary").map(
+      (entry: { key: JsonCadenceValue; value: JsonCadenceValue }): DictionaryEntry<K, V> => ({
+        key: decodeKey(entry.key),
+        value: decodeValue(entry.value),
+      }),
+    );
+}
+`,
+		requires: []string{tsExpectType, tsDictionaryEntry},
+	},
+	{
+		name: tsDecodeComposite,
+		code: `function decodeComposite(
+  value: JsonCadenceValue,
+  type: string,
+  typeID: string,
+): (name: string) => JsonCadenceValue {
+  const composite: {
+    id: string;
+    fields: { name: string; value: JsonCadenceValue }[];
+  } = expectType(value, type);
+  if (composite.id !== typeID) {
+    throw new Error(` + "`expected JSON-Cadence value of type ${typeID}, got ${composite.id}`" + `);
+  }
+  return (name) => {
+    const field = composite.fields.find((field) => field.name === name);
+    if (field === undefined) {
+      throw new Error(` + "`missing field ${name} of ${typeID}`" + `);
+    }
+    return field.value;
+  };
+}
+`,
+		requires: []string{tsExpectType},
+	},
+}
+
+// tsGenerator generates TypeScript bindings
+type tsGenerator struct {
+	*generator
+	// usedDeclarations are the names of the used declarations of tsDeclarations
+	usedDeclarations map[string]struct{}
+}
+
+// GenerateTypeScript generates TypeScript bindings for the programs at the given locations,
+// and returns the TypeScript source code.
+//
+// The bindings decode values encoded in the JSON-Cadence Data Interchange Format,
+// e.g. event payloads and script results.
+// For each struct, resource, event, and enum, an interface or enum,
+// its type ID, and a decode function are generated.
+// For scripts and transactions, the source code,
+// and for scripts, a function which decodes the result are generated.
+//
+// Integers which may not fit into a JavaScript number, i.e. with 64 or more bits, are represented as bigint,
+// and fixed-point numbers are represented as strings, to avoid a loss of precision.
+//
+// The programs must have been loaded with at least the analysis.NeedTypes mode.
+// Each program must be a contract, a contract interface, a transaction, or a script.
+func GenerateTypeScript(programs analysis.Programs, locations ...common.Location) ([]byte, error) {
+	g, err := newGenerator(Config{}, programs, locations)
+	if err != nil {
+		return nil, err
+	}
+
+	tsg := &tsGenerator{
+		generator:        g,
+		usedDeclarations: map[string]struct{}{},
+	}
+
+	return tsg.generate()
+}
+
+func (g *tsGenerator) useDeclaration(name string) {
+	if _, ok := g.usedDeclarations[name]; ok {
+		return
+	}
+	g.usedDeclarations[name] = struct{}{}
+
+	for _, declaration := range tsDeclarations {
+		if declaration.name != name {
+			continue
+		}
+		for _, required := range declaration.requires {
+			g.useDeclaration(required)
+		}
+	}
+}
+
+func (g *tsGenerator) generate() ([]byte, error) {
+
+	// The names of the declarations must not be used by generated declarations
+
+	for _, declaration := range tsDeclarations {
+		err := g.declare(declaration.name, "the generated helpers")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var body bytes.Buffer
+
+	for _, composite := range g.composites {
+		err := g.generateComposite(&body, composite)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, program := range g.programs {
+		err := g.generateScriptOrTransaction(&body, program)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var source bytes.Buffer
+
+	source.WriteString("// Code generated by cadence-bindgen. DO NOT EDIT.\n")
+
+	g.useDeclaration(tsJsonCadenceValue)
+
+	for _, declaration := range tsDeclarations {
+		if _, ok := g.usedDeclarations[declaration.name]; !ok {
+			continue
+		}
+		source.WriteString("\n")
+		source.WriteString(declaration.code)
+	}
+
+	source.Write(body.Bytes())
+
+	return source.Bytes(), nil
+}
+
+func compositeJSONKind(t cadence.CompositeType) string {
+	switch t.(type) {
+	case *cadence.StructType:
+		return "Struct"
+	case *cadence.ResourceType:
+		return "Resource"
+	case *cadence.EventType:
+		return "Event"
+	case *cadence.EnumType:
+		return "Enum"
+	default:
+		panic(fmt.Errorf("unsupported composite type %s", t.ID()))
+	}
+}
+
+func (g *tsGenerator) generateComposite(w *bytes.Buffer, t cadence.CompositeType) error {
+	name := g.compositeNames[t.ID()]
+	qualifiedIdentifier := t.CompositeTypeQualifiedIdentifier()
+	kind := compositeKindDescription(t)
+	description := fmt.Sprintf("%s %s", kind, t.ID())
+
+	typeIDName := name + "TypeID"
+	decodeName := "decode" + name
+
+	for _, declaredName := range []string{name, typeIDName, decodeName} {
+		err := g.declare(declaredName, description)
+		if err != nil {
+			return err
+		}
+	}
+
+	g.useDeclaration(tsDecodeComposite)
+
+	w.WriteString("\n")
+
+	// Type
+
+	if enumType, ok := t.(*cadence.EnumType); ok {
+		g.generateEnum(w, enumType, name)
+	} else {
+		_, _ = fmt.Fprintf(
+			w,
+			"// %s is the TypeScript representation of the %s %s.\n",
+			name,
+			kind,
+			qualifiedIdentifier,
+		)
+		_, _ = fmt.Fprintf(w, "export interface %s {\n", name)
+		for _, field := range t.CompositeFields() {
+			_, _ = fmt.Fprintf(w, "  %s: %s;\n", tsPropertyName(field.Identifier), g.tsType(field.Type))
+		}
+		w.WriteString("}\n")
+	}
+
+	// Type ID
+
+	w.WriteString("\n")
+	_, _ = fmt.Fprintf(w, "// %s is the type ID of the %s %s.\n", typeIDName, kind, qualifiedIdentifier)
+	_, _ = fmt.Fprintf(w, "export const %s = %s;\n", typeIDName, tsStringLiteral(t.ID()))
+
+	// Decode function
+
+	w.WriteString("\n")
+	_, _ = fmt.Fprintf(
+		w,
+		"// %s decodes a JSON-Cadence value of the %s %s.\n",
+		decodeName,
+		kind,
+		qualifiedIdentifier,
+	)
+	_, _ = fmt.Fprintf(w, "export function %s(value: JsonCadenceValue): %s {\n", decodeName, name)
+
+	compositeDecoding := fmt.Sprintf(
+		"decodeComposite(value, %s, %s)",
+		tsStringLiteral(compositeJSONKind(t)),
+		typeIDName,
+	)
+
+	fields := t.CompositeFields()
+
+	switch t := t.(type) {
+	case *cadence.EnumType:
+		g.useDeclaration(tsExpectType)
+		_, _ = fmt.Fprintf(w, "  const field = %s;\n", compositeDecoding)
+		_, _ = fmt.Fprintf(
+			w,
+			"  return Number(expectType(field(%s), %s));\n",
+			tsStringLiteral(sema.EnumRawValueFieldName),
+			tsStringLiteral(t.RawType.ID()),
+		)
+
+	default:
+		if len(fields) == 0 {
+			_, _ = fmt.Fprintf(w, "  %s;\n", compositeDecoding)
+			w.WriteString("  return {};\n")
+			break
+		}
+
+		_, _ = fmt.Fprintf(w, "  const field = %s;\n", compositeDecoding)
+		w.WriteString("  return {\n")
+		for _, field := range fields {
+			_, _ = fmt.Fprintf(
+				w,
+				"    %s: %s(field(%s)),\n",
+				tsPropertyName(field.Identifier),
+				g.tsDecoder(field.Type),
+				tsStringLiteral(field.Identifier),
+			)
+		}
+		w.WriteString("  };\n")
+	}
+
+	w.WriteString("}\n")
+
+	return nil
+}
+
+func (g *tsGenerator) generateEnum(w *bytes.Buffer, t *cadence.EnumType, name string) {
+	cases := g.enumCases[t.ID()]
+
+	_, _ = fmt.Fprintf(
+		w,
+		"// %s is the TypeScript representation of the enum %s.\n",
+		name,
+		t.QualifiedIdentifier,
+	)
+
+	// The cases of enums of imported programs which were not loaded are unknown
+	if len(cases) == 0 {
+		_, _ = fmt.Fprintf(w, "export type %s = number;\n", name)
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "export enum %s {\n", name)
+	for rawValue, enumCase := range cases {
+		_, _ = fmt.Fprintf(w, "  %s = %d,\n", exportedName(enumCase), rawValue)
+	}
+	w.WriteString("}\n")
+}
+
+func (g *tsGenerator) generateScriptOrTransaction(w *bytes.Buffer, program scriptOrTransaction) error {
+	kind := program.kind.String()
+	description := fmt.Sprintf("%s %s", kind, programDescription(program.location))
+
+	sourceName := program.name + exportedName(kind)
+
+	err := g.declare(sourceName, description)
+	if err != nil {
+		return err
+	}
+
+	// Source
+
+	w.WriteString("\n")
+	_, _ = fmt.Fprintf(w, "// %s is the source code of the %s.\n", sourceName, description)
+	_, _ = fmt.Fprintf(w, "export const %s = %s;\n", sourceName, tsTemplateLiteral(string(program.code)))
+
+	// Result
+
+	if program.resultType != nil {
+		decodeName := "decode" + program.name + "Result"
+		err := g.declare(decodeName, description)
+		if err != nil {
+			return err
+		}
+
+		w.WriteString("\n")
+		_, _ = fmt.Fprintf(w, "// %s decodes the result of the %s.\n", decodeName, description)
+		_, _ = fmt.Fprintf(
+			w,
+			"export function %s(value: JsonCadenceValue): %s {\n",
+			decodeName,
+			g.tsType(program.resultType),
+		)
+		_, _ = fmt.Fprintf(w, "  return %s(value);\n", g.tsDecoder(program.resultType))
+		w.WriteString("}\n")
+	}
+
+	return nil
+}
+
+// tsNumberTypes are the integer types which are represented as number,
+// as all their values can be represented exactly
+var tsNumberTypes = map[cadence.PrimitiveType]struct{}{
+	cadence.Int8Type:   {},
+	cadence.Int16Type:  {},
+	cadence.Int32Type:  {},
+	cadence.UInt8Type:  {},
+	cadence.UInt16Type: {},
+	cadence.UInt32Type: {},
+	cadence.Word8Type:  {},
+	cadence.Word16Type: {},
+	cadence.Word32Type: {},
+}
+
+// tsBigIntTypes are the integer types which are represented as bigint
+var tsBigIntTypes = map[cadence.PrimitiveType]struct{}{
+	cadence.Int64Type:   {},
+	cadence.Int128Type:  {},
+	cadence.Int256Type:  {},
+	cadence.IntType:     {},
+	cadence.UInt64Type:  {},
+	cadence.UInt128Type: {},
+	cadence.UInt256Type: {},
+	cadence.UIntType:    {},
+	cadence.Word64Type:  {},
+	cadence.Word128Type: {},
+	cadence.Word256Type: {},
+}
+
+// tsStringTypes are the types which are represented as strings.
+// Fixed-point numbers are represented as strings to avoid a loss of precision
+var tsStringTypes = map[cadence.PrimitiveType]struct{}{
+	cadence.StringType:    {},
+	cadence.CharacterType: {},
+	cadence.AddressType:   {},
+	cadence.Fix64Type:     {},
+	cadence.UFix64Type:    {},
+}
+
+func isTSPathType(t cadence.PrimitiveType) bool {
+	switch t {
+	case cadence.PathType,
+		cadence.CapabilityPathType,
+		cadence.StoragePathType,
+		cadence.PublicPathType,
+		cadence.PrivatePathType:
+
+		return true
+	default:
+		return false
+	}
+}
+
+// tsType returns the TypeScript type used to represent values of the given Cadence type.
+// Values of types without a more specific representation are represented as JSON-Cadence values
+func (g *tsGenerator) tsType(t cadence.Type) string {
+	switch t := t.(type) {
+	case cadence.PrimitiveType:
+		switch {
+		case t == cadence.BoolType:
+			return "boolean"
+		case isTSPathType(t):
+			g.useDeclaration(tsCadencePath)
+			return tsCadencePath
+		}
+		if _, ok := tsNumberTypes[t]; ok {
+			return "number"
+		}
+		if _, ok := tsBigIntTypes[t]; ok {
+			return "bigint"
+		}
+		if _, ok := tsStringTypes[t]; ok {
+			return "string"
+		}
+
+	case *cadence.OptionalType:
+		innerType := g.tsType(t.Type)
+		if strings.HasSuffix(innerType, " | null") {
+			return innerType
+		}
+		return innerType + " | null"
+
+	case *cadence.VariableSizedArrayType:
+		return tsArrayType(g.tsType(t.ElementType))
+
+	case *cadence.ConstantSizedArrayType:
+		return tsArrayType(g.tsType(t.ElementType))
+
+	case *cadence.DictionaryType:
+		keyType := g.tsType(t.KeyType)
+		elementType := g.tsType(t.ElementType)
+		if isTSMapKeyType(keyType, t.KeyType) {
+			return fmt.Sprintf("Map<%s, %s>", keyType, elementType)
+		}
+		g.useDeclaration(tsDictionaryEntry)
+		return tsArrayType(fmt.Sprintf("DictionaryEntry<%s, %s>", keyType, elementType))
+
+	case cadence.CompositeType:
+		if name, ok := g.compositeNames[t.ID()]; ok {
+			return name
+		}
+	}
+
+	return tsJsonCadenceValue
+}
+
+// tsDecoder returns a TypeScript expression which evaluates to a function
+// that decodes a JSON-Cadence value of the given type
+func (g *tsGenerator) tsDecoder(t cadence.Type) string {
+	switch t := t.(type) {
+	case cadence.PrimitiveType:
+		switch {
+		case t == cadence.BoolType:
+			g.useDeclaration(tsDecodeBool)
+			return tsDecodeBool
+		case isTSPathType(t):
+			g.useDeclaration(tsDecodePath)
+			return tsDecodePath
+		}
+		if _, ok := tsNumberTypes[t]; ok {
+			g.useDeclaration(tsDecodeNumber)
+			return fmt.Sprintf("decodeNumber(%s)", tsStringLiteral(t.ID()))
+		}
+		if _, ok := tsBigIntTypes[t]; ok {
+			g.useDeclaration(tsDecodeBigInt)
+			return fmt.Sprintf("decodeBigInt(%s)", tsStringLiteral(t.ID()))
+		}
+		if _, ok := tsStringTypes[t]; ok {
+			g.useDeclaration(tsDecodeString)
+			return fmt.Sprintf("decodeString(%s)", tsStringLiteral(t.ID()))
+		}
+
+	case *cadence.OptionalType:
+		g.useDeclaration(tsDecodeOptional)
+		return fmt.Sprintf("decodeOptional(%s)", g.tsDecoder(t.Type))
+
+	case *cadence.VariableSizedArrayType:
+		g.useDeclaration(tsDecodeArray)
+		return fmt.Sprintf("decodeArray(%s)", g.tsDecoder(t.ElementType))
+
+	case *cadence.ConstantSizedArrayType:
+		g.useDeclaration(tsDecodeArray)
+		return fmt.Sprintf("decodeArray(%s)", g.tsDecoder(t.ElementType))
+
+	case *cadence.DictionaryType:
+		decoder := tsDecodeDictionaryEntries
+		if isTSMapKeyType(g.tsType(t.KeyType), t.KeyType) {
+			decoder = tsDecodeDictionary
+		}
+		g.useDeclaration(decoder)
+		return fmt.Sprintf(
+			"%s(%s, %s)",
+			decoder,
+			g.tsDecoder(t.KeyType),
+			g.tsDecoder(t.ElementType),
+		)
+
+	case cadence.CompositeType:
+		if name, ok := g.compositeNames[t.ID()]; ok {
+			return "decode" + name
+		}
+	}
+
+	g.useDeclaration(tsDecodeAny)
+	return tsDecodeAny
+}
+
+// isTSMapKeyType returns true if values of the given TypeScript type can be used as keys of a map,
+// i.e. they are compared by value
+func isTSMapKeyType(tsType string, t cadence.Type) bool {
+	switch tsType {
+	case "boolean", "number", "bigint", "string":
+		return true
+	}
+	_, isEnum := t.(*cadence.EnumType)
+	return isEnum
+}
+
+var tsSimpleTypePattern = regexp.MustCompile(`^[A-Za-z0-9_]+(\[])*$`)
+
+func tsArrayType(elementType string) string {
+	if tsSimpleTypePattern.MatchString(elementType) {
+		return elementType + "[]"
+	}
+	return fmt.Sprintf("Array<%s>", elementType)
+}
+
+var tsIdentifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsPropertyName returns the TypeScript property name for the given field identifier
+func tsPropertyName(identifier string) string {
+	if tsIdentifierPattern.MatchString(identifier) {
+		return identifier
+	}
+	return tsStringLiteral(identifier)
+}
+
+// tsStringLiteral returns a TypeScript string literal for the given string
+func tsStringLiteral(s string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			builder.WriteByte('\\')
+			builder.WriteRune(r)
+		case r == '\n':
+			builder.WriteString(`\n`)
+		case r < 0x20 || r == 0x2028 || r == 0x2029:
+			_, _ = fmt.Fprintf(&builder, `\u%04x`, r)
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+// tsTemplateLiteral returns a TypeScript template literal for the given string,
+// which keeps multi-line strings like source code readable
+func tsTemplateLiteral(s string) string {
+	replacer := strings.NewReplacer(
+		"\\", "\\\\",
+		"`", "\\`",
+		"${", "\\${",
+		"\r", "\\r",
+	)
+	return "`" + replacer.Replace(s) + "`"
+}
@@ -0,0 +1,121 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bindgen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+)
+
+func TestGenerateTypeScript(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("contract", func(t *testing.T) {
+		t.Parallel()
+
+		programs := load(t, nil, testContractLocation)
+
+		code, err := GenerateTypeScript(programs, testContractLocation)
+		require.NoError(t, err)
+
+		for _, expected := range []string{
+			"export interface JsonCadenceValue {",
+			`export enum ExampleColor {
+  Red = 0,
+  Green = 1,
+}
+`,
+			`export const ExampleColorTypeID = "A.0000000000000001.Example.Color";`,
+			`export function decodeExampleColor(value: JsonCadenceValue): ExampleColor {
+  const field = decodeComposite(value, "Enum", ExampleColorTypeID);
+  return Number(expectType(field("rawValue"), "UInt8"));
+}
+`,
+			`export interface ExampleItem {
+  id: bigint;
+  color: ExampleColor;
+  next: ExampleItem | null;
+}
+`,
+			`export function decodeExampleItem(value: JsonCadenceValue): ExampleItem {
+  const field = decodeComposite(value, "Struct", ExampleItemTypeID);
+  return {
+    id: decodeBigInt("UInt64")(field("id")),
+    color: decodeExampleColor(field("color")),
+    next: decodeOptional(decodeExampleItem)(field("next")),
+  };
+}
+`,
+			`export interface ExampleMinted {
+  id: bigint;
+  to: string | null;
+}
+`,
+			`const field = decodeComposite(value, "Event", ExampleMintedTypeID);`,
+			`to: decodeOptional(decodeString("Address"))(field("to")),`,
+		} {
+			assert.Contains(t, string(code), expected)
+		}
+
+		// Only the used helpers are generated
+		for _, unexpected := range []string{
+			"function decodeBool(",
+			"function decodePath(",
+			"function decodeDictionary<",
+			"interface DictionaryEntry<",
+		} {
+			assert.NotContains(t, string(code), unexpected)
+		}
+	})
+
+	t.Run("script", func(t *testing.T) {
+		t.Parallel()
+
+		location := common.StringLocation("get_paths.cdc")
+
+		programs := load(
+			t,
+			map[common.Location]string{
+				location: "access(all) fun main(): {StoragePath: [Int8]}? { return nil }",
+			},
+			location,
+		)
+
+		code, err := GenerateTypeScript(programs, location)
+		require.NoError(t, err)
+
+		for _, expected := range []string{
+			"export const GetPathsScript = `access(all) fun main(): {StoragePath: [Int8]}? { return nil }`;",
+			// Paths are objects, which are not usable as map keys
+			`export function decodeGetPathsResult(value: JsonCadenceValue): Array<DictionaryEntry<CadencePath, number[]>> | null {
+  return decodeOptional(decodeDictionaryEntries(decodePath, decodeArray(decodeNumber("Int8"))))(value);
+}
+`,
+			"function decodeDictionaryEntries<K, V>(",
+			"function decodePath(",
+		} {
+			assert.Contains(t, string(code), expected)
+		}
+	})
+}
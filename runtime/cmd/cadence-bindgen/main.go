@@ -0,0 +1,236 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// cadence-bindgen generates typed Go or TypeScript bindings for Cadence contracts, scripts, and transactions,
+// see the runtime/cmd/bindgen package.
+//
+// The language of the bindings is given by the -lang flag: go (default) or typescript.
+// The TypeScript bindings decode values encoded in the JSON-Cadence Data Interchange Format.
+//
+// Each argument is a location: either the path of a file,
+// or an address location of the form A.<address>.<contract name>.
+// Contracts should be given as address locations, so the generated types have the on-chain type IDs.
+//
+// Imported address locations are resolved from the directory given by the -contracts flag,
+// which contains a directory per address (e.g. 0x0000000000000001),
+// each containing a file per contract (e.g. Foo.cdc).
+//
+// The generated code is written to the file given by the -o flag, or to standard output.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/cmd/bindgen"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/pretty"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+const contractFileExtension = ".cdc"
+
+const (
+	languageGo         = "go"
+	languageTypeScript = "typescript"
+)
+
+var languageFlag = flag.String("lang", languageGo, "language of the generated code: go or typescript")
+var packageFlag = flag.String("package", "bindings", "name of the package of the generated Go code")
+var outputFlag = flag.String("o", "", "file to write the generated code to (default standard output)")
+var contractsFlag = flag.String("contracts", "", "directory to resolve imported address locations from")
+var colorFlag = flag.Bool("color", true, "colorize the errors")
+
+func main() {
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <location>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch *languageFlag {
+	case languageGo, languageTypeScript:
+		break
+	default:
+		exitWithError(fmt.Errorf("unsupported language: %s", *languageFlag))
+	}
+
+	locations := make([]common.Location, 0, flag.NArg())
+	for _, arg := range flag.Args() {
+		location, err := parseLocation(arg)
+		if err != nil {
+			exitWithError(err)
+		}
+		locations = append(locations, location)
+	}
+
+	var loadErrors []analysis.ParsingCheckingError
+
+	config := &analysis.Config{
+		Mode:                        analysis.NeedTypes,
+		ResolveAddressContractNames: resolveAddressContractNames,
+		ResolveCode:                 resolveCode,
+		HandleParserError: func(err analysis.ParsingCheckingError, _ *ast.Program) error {
+			loadErrors = append(loadErrors, err)
+			return nil
+		},
+		HandleCheckerError: func(err analysis.ParsingCheckingError, _ *sema.Checker) error {
+			loadErrors = append(loadErrors, err)
+			return nil
+		},
+	}
+
+	programs, err := analysis.Load(config, locations...)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Bindings cannot be generated for invalid programs
+
+	if len(loadErrors) > 0 {
+		codes := map[common.Location][]byte{}
+		for location, program := range programs { //nolint:maprange
+			codes[location] = program.Code
+		}
+
+		printer := pretty.NewErrorPrettyPrinter(os.Stderr, *colorFlag)
+		for _, loadError := range loadErrors {
+			printErr := printer.PrettyPrintError(loadError, loadError.ImportLocation(), codes)
+			if printErr != nil {
+				panic(printErr)
+			}
+		}
+		os.Exit(1)
+	}
+
+	var code []byte
+	switch *languageFlag {
+	case languageGo:
+		code, err = bindgen.Generate(
+			bindgen.Config{
+				PackageName: *packageFlag,
+			},
+			programs,
+			locations...,
+		)
+	case languageTypeScript:
+		code, err = bindgen.GenerateTypeScript(programs, locations...)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+
+	if *outputFlag == "" {
+		_, err = os.Stdout.Write(code)
+	} else {
+		err = os.WriteFile(*outputFlag, code, 0644)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func exitWithError(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func parseLocation(arg string) (common.Location, error) {
+	if strings.HasPrefix(arg, common.AddressLocationPrefix+".") {
+		location, _, err := common.DecodeTypeID(nil, arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address location %s: %w", arg, err)
+		}
+		return location, nil
+	}
+
+	return common.StringLocation(arg), nil
+}
+
+func addressContractsDirectory(address common.Address) (string, error) {
+	if *contractsFlag == "" {
+		return "", fmt.Errorf(
+			"cannot resolve contracts of address %s: no contracts directory given",
+			address.HexWithPrefix(),
+		)
+	}
+
+	return filepath.Join(*contractsFlag, address.HexWithPrefix()), nil
+}
+
+func resolveAddressContractNames(address common.Address) ([]string, error) {
+	directory, err := addressContractsDirectory(address)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != contractFileExtension {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(name, contractFileExtension))
+	}
+
+	return names, nil
+}
+
+func resolveCode(
+	location common.Location,
+	importingLocation common.Location,
+	_ ast.Range,
+) ([]byte, error) {
+	switch location := location.(type) {
+	case common.StringLocation:
+		path := string(location)
+
+		// Resolve string imports relative to the importing file
+		if importingPath, ok := importingLocation.(common.StringLocation); ok && !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(string(importingPath)), path)
+		}
+
+		return os.ReadFile(path)
+
+	case common.AddressLocation:
+		directory, err := addressContractsDirectory(location.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		return os.ReadFile(filepath.Join(directory, location.Name+contractFileExtension))
+
+	default:
+		return nil, fmt.Errorf("cannot resolve location %s", location)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// cadence-codec converts Cadence values between the JSON-Cadence Data Interchange Format (JSON-CDC)
+// and the Cadence Compact Format (CCF), see the runtime/cmd/codec package.
+//
+// The input is read from the file given as the argument after the command, or from standard input.
+// The commands are:
+//
+//   - json-to-ccf: converts a JSON-CDC value to CCF
+//   - ccf-to-json: converts a CCF value to JSON-CDC
+//   - diag: prints a CCF value in CBOR diagnostic notation
+//   - type: prints the type of a JSON-CDC or CCF value, as a JSON-CDC type value
+//   - validate: checks that a CCF value is valid and deterministically encoded
+//
+// CCF input and output is hex-encoded by default, see the -ccf-format flag.
+//
+// The sort orders of composite fields, intersection types, and entitlement types
+// are used when encoding CCF, and enforced when decoding CCF.
+// The default sort order is none, the sort order of event payloads.
+// Deterministic encodings require the bytewise-lexical sort order.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/cmd/codec"
+)
+
+const (
+	commandJSONToCCF = "json-to-ccf"
+	commandCCFToJSON = "ccf-to-json"
+	commandDiag      = "diag"
+	commandType      = "type"
+	commandValidate  = "validate"
+)
+
+var ccfFormatFlag = flag.String("ccf-format", "hex", "format of CCF input and output: binary, hex, or base64")
+var sortCompositeFieldsFlag = flag.String("sort-composite-fields", "none", "sort order of composite fields: none or bytewise-lexical")
+var sortIntersectionTypesFlag = flag.String("sort-intersection-types", "none", "sort order of intersection types: none or bytewise-lexical")
+var sortEntitlementTypesFlag = flag.String("sort-entitlement-types", "none", "sort order of entitlement types: none or bytewise-lexical")
+var prettyFlag = flag.Bool("pretty", false, "indent JSON-CDC output")
+
+func main() {
+	flag.Usage = func() {
+		output := flag.CommandLine.Output()
+		_, _ = fmt.Fprintf(
+			output,
+			"usage: %s [flags] <command> [file]\n\ncommands: %s\n\nflags:\n",
+			os.Args[0],
+			strings.Join(
+				[]string{
+					commandJSONToCCF,
+					commandCCFToJSON,
+					commandDiag,
+					commandType,
+					commandValidate,
+				},
+				", ",
+			),
+		)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	options, err := parseOptions()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	input, err := readInput(flag.Arg(1))
+	if err != nil {
+		exitWithError(err)
+	}
+
+	var output []byte
+
+	switch flag.Arg(0) {
+	case commandJSONToCCF:
+		output, err = options.JSONToCCF(input)
+
+	case commandCCFToJSON:
+		output, err = options.CCFToJSON(input)
+
+	case commandDiag:
+		var data []byte
+		data, err = options.DecodeCCFInput(input)
+		if err == nil {
+			err = codec.WriteDiagnostic(os.Stdout, data)
+		}
+
+	case commandType:
+		output, err = options.Type(input)
+
+	case commandValidate:
+		err = options.Validate(input)
+		if err == nil {
+			output = []byte("valid\n")
+		}
+
+	default:
+		exitWithError(fmt.Errorf("unknown command: %s", flag.Arg(0)))
+	}
+
+	if err != nil {
+		exitWithError(err)
+	}
+
+	_, err = os.Stdout.Write(output)
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func parseOptions() (options codec.Options, err error) {
+	options.IndentJSON = *prettyFlag
+
+	options.CCFFormat, err = codec.ParseCCFFormat(*ccfFormatFlag)
+	if err != nil {
+		return
+	}
+
+	options.SortCompositeFields, err = codec.ParseSortMode(*sortCompositeFieldsFlag)
+	if err != nil {
+		return
+	}
+
+	options.SortIntersectionTypes, err = codec.ParseSortMode(*sortIntersectionTypesFlag)
+	if err != nil {
+		return
+	}
+
+	options.SortEntitlementTypes, err = codec.ParseSortMode(*sortEntitlementTypesFlag)
+	return
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func exitWithError(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
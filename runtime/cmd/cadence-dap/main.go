@@ -0,0 +1,72 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// cadence-dap is a Debug Adapter Protocol server for Cadence programs.
+//
+// By default, the server communicates with the client over stdin/stdout.
+// If the -listen flag is given, the server accepts client connections over TCP,
+// one debug session per connection.
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+
+	"github.com/onflow/cadence/runtime/cmd/dap"
+)
+
+var listenFlag = flag.String("listen", "", "serve debug sessions over TCP on the given address, e.g. :4711")
+
+func main() {
+	flag.Parse()
+
+	if *listenFlag == "" {
+		err := dap.NewSession(os.Stdin, os.Stdout).Run()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	listener, err := net.Listen("tcp", *listenFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("listening on %s", listener.Addr())
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		go func() {
+			defer func() {
+				_ = conn.Close()
+			}()
+
+			err := dap.NewSession(conn, conn).Run()
+			if err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,343 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// cadence-lint runs the analyzers of the tools/analysis/passes package
+// over a set of Cadence programs, and reports the diagnostics and errors.
+//
+// Each argument is a location: either the path of a file,
+// or an address location of the form A.<address>.<contract name>.
+//
+// Imported address locations are resolved from the directory given by the -contracts flag,
+// which contains a directory per address (e.g. 0x0000000000000001),
+// each containing a file per contract (e.g. Foo.cdc).
+//
+// With the -sarif flag, the diagnostics and errors are printed in the SARIF format.
+//
+// The exit code is 1 if any diagnostic or error was reported.
+package main
+
+import (
+	goerrors "errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/errors"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/pretty"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/tools/analysis"
+	"github.com/onflow/cadence/tools/analysis/passes"
+	"github.com/onflow/cadence/tools/analysis/sarif"
+)
+
+const contractFileExtension = ".cdc"
+
+var analyzersFlag = flag.String(
+	"analyzers",
+	"",
+	fmt.Sprintf(
+		"comma-separated names of the analyzers to run (default all): %s",
+		strings.Join(passes.AnalyzerNames(), ", "),
+	),
+)
+var contractsFlag = flag.String("contracts", "", "directory to resolve imported address locations from")
+var colorFlag = flag.Bool("color", true, "colorize the output")
+var sarifFlag = flag.Bool("sarif", false, "print the diagnostics and errors formatted as SARIF")
+
+func main() {
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <location>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	analyzers, err := selectAnalyzers(*analyzersFlag)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	locations := make([]common.Location, 0, flag.NArg())
+	for _, arg := range flag.Args() {
+		location, err := parseLocation(arg)
+		if err != nil {
+			exitWithError(err)
+		}
+		locations = append(locations, location)
+	}
+
+	// Parser and checker errors are collected and reported,
+	// but do not prevent the analysis
+
+	var loadErrors []error
+
+	config := &analysis.Config{
+		Mode:                        passes.LoadMode | analysis.NeedSuggestions,
+		ResolveAddressContractNames: resolveAddressContractNames,
+		ResolveCode:                 resolveCode,
+		HandleParserError: func(err analysis.ParsingCheckingError, _ *ast.Program) error {
+			loadErrors = append(loadErrors, err)
+			return nil
+		},
+		HandleCheckerError: func(err analysis.ParsingCheckingError, _ *sema.Checker) error {
+			loadErrors = append(loadErrors, err)
+			return nil
+		},
+	}
+
+	programs, err := analysis.Load(config, locations...)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	codes := map[common.Location][]byte{}
+	for location, program := range programs { //nolint:maprange
+		codes[location] = program.Code
+	}
+
+	printer := pretty.NewErrorPrettyPrinter(os.Stdout, *colorFlag)
+	reporter := sarif.NewReporter("cadence-lint", "")
+
+	reportedDeprecatedAccessModifiers := false
+	for _, analyzer := range analyzers {
+		if analyzer == passes.DeprecatedAccessModifierAnalyzer {
+			reportedDeprecatedAccessModifiers = true
+		}
+	}
+
+	reported := false
+
+	reportError := func(err error, location common.Location) {
+		if *sarifFlag {
+			reporter.ReportError(err, location, codes)
+			reported = true
+			return
+		}
+
+		if reported {
+			fmt.Println()
+		}
+		reported = true
+
+		printErr := printer.PrettyPrintError(err, location, codes)
+		if printErr != nil {
+			panic(printErr)
+		}
+	}
+
+	reportDiagnostic := func(diagnostic analysis.Diagnostic) {
+		if *sarifFlag {
+			reporter.ReportDiagnostic(diagnostic)
+			reported = true
+			return
+		}
+
+		reportError(diagnosticError{diagnostic}, diagnostic.Location)
+	}
+
+	for _, loadError := range loadErrors {
+		var parsingCheckingError analysis.ParsingCheckingError
+		if !goerrors.As(loadError, &parsingCheckingError) {
+			continue
+		}
+		location := parsingCheckingError.ImportLocation()
+
+		// Parser errors for removed access modifiers are reported as diagnostics
+
+		var parserError parser.Error
+		if reportedDeprecatedAccessModifiers && goerrors.As(loadError, &parserError) {
+			for _, err := range parserError.Errors {
+				if passes.IsDeprecatedAccessModifierError(err) {
+					continue
+				}
+				reportError(err, location)
+			}
+			continue
+		}
+
+		reportError(loadError, location)
+	}
+
+	for _, location := range locations {
+		program := programs[location]
+
+		var lock sync.Mutex
+		var diagnostics []analysis.Diagnostic
+
+		program.Run(
+			analyzers,
+			func(diagnostic analysis.Diagnostic) {
+				lock.Lock()
+				defer lock.Unlock()
+				diagnostics = append(diagnostics, diagnostic)
+			},
+		)
+
+		sort.SliceStable(diagnostics, func(i, j int) bool {
+			return diagnostics[i].StartPos.Compare(diagnostics[j].StartPos) < 0
+		})
+
+		for _, diagnostic := range diagnostics {
+			reportDiagnostic(diagnostic)
+		}
+	}
+
+	if *sarifFlag {
+		err := reporter.Write(os.Stdout)
+		if err != nil {
+			exitWithError(err)
+		}
+	}
+
+	if reported {
+		os.Exit(1)
+	}
+}
+
+func exitWithError(err error) {
+	_, _ = fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func selectAnalyzers(names string) ([]*analysis.Analyzer, error) {
+	if names == "" {
+		analyzers := make([]*analysis.Analyzer, 0, len(passes.Analyzers))
+		for _, name := range passes.AnalyzerNames() {
+			analyzers = append(analyzers, passes.Analyzers[name])
+		}
+		return analyzers, nil
+	}
+
+	var analyzers []*analysis.Analyzer
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		analyzer, ok := passes.Analyzers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown analyzer: %s", name)
+		}
+		analyzers = append(analyzers, analyzer)
+	}
+	return analyzers, nil
+}
+
+func parseLocation(arg string) (common.Location, error) {
+	if strings.HasPrefix(arg, common.AddressLocationPrefix+".") {
+		location, _, err := common.DecodeTypeID(nil, arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address location %s: %w", arg, err)
+		}
+		return location, nil
+	}
+
+	return common.StringLocation(arg), nil
+}
+
+func addressContractsDirectory(address common.Address) (string, error) {
+	if *contractsFlag == "" {
+		return "", fmt.Errorf(
+			"cannot resolve contracts of address %s: no contracts directory given",
+			address.HexWithPrefix(),
+		)
+	}
+
+	return filepath.Join(*contractsFlag, address.HexWithPrefix()), nil
+}
+
+func resolveAddressContractNames(address common.Address) ([]string, error) {
+	directory, err := addressContractsDirectory(address)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != contractFileExtension {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(name, contractFileExtension))
+	}
+
+	return names, nil
+}
+
+func resolveCode(
+	location common.Location,
+	importingLocation common.Location,
+	_ ast.Range,
+) ([]byte, error) {
+	switch location := location.(type) {
+	case common.StringLocation:
+		path := string(location)
+
+		// Resolve string imports relative to the importing file
+		if importingPath, ok := importingLocation.(common.StringLocation); ok && !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(string(importingPath)), path)
+		}
+
+		return os.ReadFile(path)
+
+	case common.AddressLocation:
+		directory, err := addressContractsDirectory(location.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		return os.ReadFile(filepath.Join(directory, location.Name+contractFileExtension))
+
+	default:
+		return nil, fmt.Errorf("cannot resolve location %s", location)
+	}
+}
+
+// diagnosticError allows an analysis.Diagnostic to be pretty-printed like an error
+type diagnosticError struct {
+	analysis.Diagnostic
+}
+
+var _ error = diagnosticError{}
+var _ errors.HasPrefix = diagnosticError{}
+var _ errors.SecondaryError = diagnosticError{}
+
+func (e diagnosticError) Error() string {
+	return e.Message
+}
+
+func (e diagnosticError) Prefix() string {
+	return fmt.Sprintf("%s (%s)", e.Category, e.Code)
+}
+
+func (e diagnosticError) SecondaryError() string {
+	return e.SecondaryMessage
+}
@@ -0,0 +1,36 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// cadence-lsp is a Language Server Protocol server for Cadence programs.
+//
+// The server communicates with the client over stdin/stdout.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/onflow/cadence/runtime/cmd/lsp"
+)
+
+func main() {
+	err := lsp.NewServer(os.Stdin, os.Stdout).Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/onflow/cadence/runtime/pretty"
 	"github.com/onflow/cadence/runtime/sema"
 	"github.com/onflow/cadence/runtime/stdlib"
+	"github.com/onflow/cadence/tools/analysis/sarif"
 )
 
 type memberAccountAccessFlags []string
@@ -52,6 +53,7 @@ func (f *memberAccountAccessFlags) Set(value string) error {
 
 var benchFlag = flag.Bool("bench", false, "benchmark the checker")
 var jsonFlag = flag.Bool("json", false, "print the result formatted as JSON")
+var sarifFlag = flag.Bool("sarif", false, "print the checker errors formatted as SARIF, including suggested fixes")
 
 var memberAccountAccessFlag memberAccountAccessFlags
 
@@ -88,7 +90,7 @@ func main() {
 	}
 
 	args := flag.Args()
-	run(args, *benchFlag, *jsonFlag, memberAccountAccess)
+	run(args, *benchFlag, *jsonFlag, *sarifFlag, memberAccountAccess)
 }
 
 type benchResult struct {
@@ -103,6 +105,10 @@ type result struct {
 	Bench    *benchResult `json:"bench,omitempty"`
 	BenchStr string       `json:"-"`
 	Error    string       `json:"error,omitempty"`
+	// checkerError is the error returned by the checker, if any
+	checkerError error
+	location     common.Location
+	codes        map[common.Location][]byte
 }
 
 type output interface {
@@ -133,6 +139,32 @@ func (j *jsonOutput) End() {
 	}
 }
 
+type sarifOutput struct {
+	reporter *sarif.Reporter
+}
+
+func newSARIFOutput() sarifOutput {
+	return sarifOutput{
+		reporter: sarif.NewReporter("check", ""),
+	}
+}
+
+func (s sarifOutput) Append(r result) {
+	if r.checkerError != nil {
+		s.reporter.ReportError(r.checkerError, r.location, r.codes)
+	} else if len(r.Error) > 0 {
+		// Internal errors are not reported as results
+		_, _ = fmt.Fprintf(os.Stderr, "%s\nerror:\t%s\n", r.Path, r.Error)
+	}
+}
+
+func (s sarifOutput) End() {
+	err := s.reporter.Write(os.Stdout)
+	if err != nil {
+		panic(err)
+	}
+}
+
 type stdoutOutput struct {
 	writer *tabwriter.Writer
 }
@@ -181,6 +213,7 @@ func run(
 	paths []string,
 	bench bool,
 	json bool,
+	sarif bool,
 	memberAccountAccess map[common.Location]map[common.Location]struct{},
 ) {
 	if len(paths) == 0 {
@@ -190,16 +223,19 @@ func run(
 	allSucceeded := true
 
 	var out output
-	if json {
+	switch {
+	case sarif:
+		out = newSARIFOutput()
+	case json:
 		out = newJSONOutput(len(paths))
-	} else {
+	default:
 		out = newStdoutOutput()
 	}
 
-	useColor := !json
+	useColor := !json && !sarif
 
 	for _, path := range paths {
-		res, runSucceeded := runPath(path, bench, useColor, memberAccountAccess)
+		res, runSucceeded := runPath(path, bench, useColor, sarif, memberAccountAccess)
 		if !runSucceeded {
 			allSucceeded = false
 		}
@@ -218,6 +254,7 @@ func runPath(
 	path string,
 	bench bool,
 	useColor bool,
+	suggestionsEnabled bool,
 	memberAccountAccess map[common.Location]map[common.Location]struct{},
 ) (res result, succeeded bool) {
 	res = result{
@@ -258,8 +295,14 @@ func runPath(
 			must,
 		)
 
+		checker.Config.SuggestionsEnabled = suggestionsEnabled
+
 		err = checker.Check()
 		if err != nil {
+			res.checkerError = err
+			res.location = location
+			res.codes = codes
+
 			var builder strings.Builder
 			printErr := pretty.NewErrorPrettyPrinter(&builder, useColor).
 				PrettyPrintError(err, location, codes)
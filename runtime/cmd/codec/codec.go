@@ -0,0 +1,316 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package codec converts Cadence values between the JSON-Cadence Data Interchange Format (JSON-CDC)
+// and the Cadence Compact Format (CCF), and inspects CCF-encoded values.
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/encoding/ccf"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+)
+
+// CCFFormat is the textual or binary format of CCF-encoded data.
+type CCFFormat int
+
+const (
+	// CCFFormatBinary is the raw CCF-encoded data.
+	CCFFormatBinary CCFFormat = iota
+	// CCFFormatHex is the hex-encoded CCF-encoded data.
+	CCFFormatHex
+	// CCFFormatBase64 is the base64-encoded CCF-encoded data.
+	CCFFormatBase64
+)
+
+var ccfFormatNames = map[string]CCFFormat{
+	"binary": CCFFormatBinary,
+	"hex":    CCFFormatHex,
+	"base64": CCFFormatBase64,
+}
+
+// ParseCCFFormat returns the CCF format with the given name: binary, hex, or base64.
+func ParseCCFFormat(name string) (CCFFormat, error) {
+	format, ok := ccfFormatNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unsupported CCF format: %s", name)
+	}
+	return format, nil
+}
+
+var sortModeNames = map[string]ccf.SortMode{
+	"none":             ccf.SortNone,
+	"bytewise-lexical": ccf.SortBytewiseLexical,
+}
+
+// ParseSortMode returns the CCF sort mode with the given name: none or bytewise-lexical.
+func ParseSortMode(name string) (ccf.SortMode, error) {
+	sortMode, ok := sortModeNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unsupported sort mode: %s", name)
+	}
+	return sortMode, nil
+}
+
+// Options are the options of the conversions.
+type Options struct {
+	// CCFFormat is the format of the CCF-encoded input and output
+	CCFFormat CCFFormat
+	// SortCompositeFields is the sort order of composite fields.
+	// When encoding CCF, the fields are sorted in this order.
+	// When decoding CCF, the fields must be sorted in this order
+	SortCompositeFields ccf.SortMode
+	// SortIntersectionTypes is the sort order of intersection types,
+	// see SortCompositeFields
+	SortIntersectionTypes ccf.SortMode
+	// SortEntitlementTypes is the sort order of entitlement types,
+	// see SortCompositeFields
+	SortEntitlementTypes ccf.SortMode
+	// IndentJSON indents the JSON-CDC output
+	IndentJSON bool
+}
+
+func (o Options) encMode() (ccf.EncMode, error) {
+	return ccf.EncOptions{
+		SortCompositeFields:   o.SortCompositeFields,
+		SortIntersectionTypes: o.SortIntersectionTypes,
+		SortEntitlementTypes:  o.SortEntitlementTypes,
+	}.EncMode()
+}
+
+func enforceSortMode(sortMode ccf.SortMode) ccf.EnforceSortMode {
+	switch sortMode {
+	case ccf.SortBytewiseLexical:
+		return ccf.EnforceSortBytewiseLexical
+	default:
+		return ccf.EnforceSortNone
+	}
+}
+
+func (o Options) decMode() (ccf.DecMode, error) {
+	return ccf.DecOptions{
+		EnforceSortCompositeFields:   enforceSortMode(o.SortCompositeFields),
+		EnforceSortIntersectionTypes: enforceSortMode(o.SortIntersectionTypes),
+		EnforceSortEntitlementTypes:  enforceSortMode(o.SortEntitlementTypes),
+	}.DecMode()
+}
+
+// DecodeCCFInput returns the CCF-encoded data of the given input in the configured format.
+func (o Options) DecodeCCFInput(input []byte) ([]byte, error) {
+	switch o.CCFFormat {
+	case CCFFormatBinary:
+		return input, nil
+
+	case CCFFormatHex:
+		text := strings.Join(strings.Fields(string(input)), "")
+		text = strings.TrimPrefix(text, "0x")
+		data, err := hex.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex-encoded CCF: %w", err)
+		}
+		return data, nil
+
+	case CCFFormatBase64:
+		text := strings.Join(strings.Fields(string(input)), "")
+		data, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64-encoded CCF: %w", err)
+		}
+		return data, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported CCF format: %d", o.CCFFormat)
+	}
+}
+
+// EncodeCCFOutput returns the given CCF-encoded data in the configured format.
+func (o Options) EncodeCCFOutput(data []byte) []byte {
+	switch o.CCFFormat {
+	case CCFFormatHex:
+		return []byte(hex.EncodeToString(data) + "\n")
+	case CCFFormatBase64:
+		return []byte(base64.StdEncoding.EncodeToString(data) + "\n")
+	default:
+		return data
+	}
+}
+
+// DecodeCCF decodes the given CCF input.
+func (o Options) DecodeCCF(input []byte) (cadence.Value, error) {
+	data, err := o.DecodeCCFInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	decMode, err := o.decMode()
+	if err != nil {
+		return nil, err
+	}
+
+	return decMode.Decode(nil, data)
+}
+
+// EncodeCCF encodes the given value as CCF output.
+func (o Options) EncodeCCF(value cadence.Value) ([]byte, error) {
+	encMode, err := o.encMode()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := encMode.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.EncodeCCFOutput(data), nil
+}
+
+// EncodeJSON encodes the given value as JSON-CDC.
+func (o Options) EncodeJSON(value cadence.Value) ([]byte, error) {
+	data, err := jsoncdc.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if !o.IndentJSON {
+		return data, nil
+	}
+
+	var indented bytes.Buffer
+	err = json.Indent(&indented, data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+// DecodeJSON decodes the given JSON-CDC input.
+// The types of arrays and dictionaries, which are not encoded in JSON-CDC, are inferred.
+func (o Options) DecodeJSON(input []byte) (cadence.Value, error) {
+	value, err := jsoncdc.Decode(nil, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return inferTypes(value), nil
+}
+
+// JSONToCCF converts the given JSON-CDC input to CCF output.
+func (o Options) JSONToCCF(input []byte) ([]byte, error) {
+	value, err := o.DecodeJSON(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.EncodeCCF(value)
+}
+
+// CCFToJSON converts the given CCF input to JSON-CDC output.
+func (o Options) CCFToJSON(input []byte) ([]byte, error) {
+	value, err := o.DecodeCCF(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.EncodeJSON(value)
+}
+
+// Type returns the type of the given JSON-CDC or CCF input, encoded as JSON-CDC type value.
+// Input which starts with `{` is decoded as JSON-CDC, other input is decoded as CCF.
+func (o Options) Type(input []byte) ([]byte, error) {
+	var value cadence.Value
+	var err error
+
+	if bytes.HasPrefix(bytes.TrimSpace(input), []byte("{")) {
+		value, err = o.DecodeJSON(input)
+	} else {
+		value, err = o.DecodeCCF(input)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return o.EncodeJSON(cadence.NewTypeValue(value.Type()))
+}
+
+// NonDeterministicEncodingError is returned by Validate
+// if the CCF-encoded data is valid, but not deterministically encoded.
+type NonDeterministicEncodingError struct {
+	// Offset is the offset of the first byte which differs from the deterministic encoding
+	Offset int
+}
+
+func (e NonDeterministicEncodingError) Error() string {
+	return fmt.Sprintf(
+		"CCF is not deterministically encoded: differs from the deterministic encoding at byte offset %d",
+		e.Offset,
+	)
+}
+
+// Validate checks that the given CCF input is valid, and that it is deterministically encoded:
+// The decoded value is encoded again, and the encoding must be identical to the input.
+//
+// The sort orders of the options are enforced when decoding, and used when encoding again,
+// so e.g. bytewise-lexical sort orders are required to validate deterministic encodings
+// as defined by the CCF specification.
+func (o Options) Validate(input []byte) error {
+	data, err := o.DecodeCCFInput(input)
+	if err != nil {
+		return err
+	}
+
+	decMode, err := o.decMode()
+	if err != nil {
+		return err
+	}
+
+	value, err := decMode.Decode(nil, data)
+	if err != nil {
+		return err
+	}
+
+	encMode, err := o.encMode()
+	if err != nil {
+		return err
+	}
+
+	encoded, err := encMode.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(data, encoded) {
+		return nil
+	}
+
+	offset := 0
+	for offset < len(data) && offset < len(encoded) && data[offset] == encoded[offset] {
+		offset++
+	}
+
+	return NonDeterministicEncodingError{
+		Offset: offset,
+	}
+}
@@ -0,0 +1,274 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/encoding/ccf"
+)
+
+const testEventJSON = `{"value":{"id":"A.0000000000000001.Foo.Bar","fields":[{"value":{"value":"-1","type":"Int"},"name":"z"},{"value":{"value":{"value":"hi","type":"String"},"type":"Optional"},"name":"a"}]},"type":"Event"}` + "\n"
+
+func TestConversion(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+
+		for _, format := range []CCFFormat{CCFFormatBinary, CCFFormatHex, CCFFormatBase64} {
+
+			options := Options{
+				CCFFormat: format,
+			}
+
+			encoded, err := options.JSONToCCF([]byte(testEventJSON))
+			require.NoError(t, err)
+
+			decoded, err := options.CCFToJSON(encoded)
+			require.NoError(t, err)
+
+			assert.Equal(t, testEventJSON, string(decoded))
+		}
+	})
+
+	t.Run("hex input", func(t *testing.T) {
+		t.Parallel()
+
+		options := Options{
+			CCFFormat: CCFFormatHex,
+		}
+
+		decoded, err := options.CCFToJSON([]byte("0x d8 82 82 \n d8 89 04 c2 42 01 00\n"))
+		require.NoError(t, err)
+
+		assert.Equal(t, `{"value":"256","type":"Int"}`+"\n", string(decoded))
+	})
+
+	t.Run("inferred types", func(t *testing.T) {
+		t.Parallel()
+
+		options := Options{
+			CCFFormat: CCFFormatBinary,
+		}
+
+		// JSON-CDC does not encode the types of arrays and dictionaries
+
+		const arrayJSON = `{"value":[{"value":"1","type":"Int"},{"value":"2","type":"Int"}],"type":"Array"}` + "\n"
+
+		typeJSON, err := options.Type([]byte(arrayJSON))
+		require.NoError(t, err)
+		assert.Equal(t,
+			`{"value":{"staticType":{"type":{"kind":"Int"},"kind":"VariableSizedArray"}},"type":"Type"}`+"\n",
+			string(typeJSON),
+		)
+
+		const dictionaryJSON = `{"value":[{"key":{"value":"a","type":"String"},"value":{"value":"1","type":"Int"}},{"key":{"value":"b","type":"String"},"value":{"value":true,"type":"Bool"}}],"type":"Dictionary"}` + "\n"
+
+		typeJSON, err = options.Type([]byte(dictionaryJSON))
+		require.NoError(t, err)
+		assert.Equal(t,
+			`{"value":{"staticType":{"key":{"kind":"String"},"value":{"kind":"AnyStruct"},"kind":"Dictionary"}},"type":"Type"}`+"\n",
+			string(typeJSON),
+		)
+
+		for _, input := range []string{arrayJSON, dictionaryJSON} {
+			encoded, err := options.JSONToCCF([]byte(input))
+			require.NoError(t, err)
+
+			decoded, err := options.CCFToJSON(encoded)
+			require.NoError(t, err)
+
+			assert.Equal(t, input, string(decoded))
+		}
+	})
+
+	t.Run("sort order", func(t *testing.T) {
+		t.Parallel()
+
+		unsorted := Options{
+			CCFFormat: CCFFormatBinary,
+		}
+		sorted := Options{
+			CCFFormat:           CCFFormatBinary,
+			SortCompositeFields: ccf.SortBytewiseLexical,
+		}
+
+		unsortedEncoded, err := unsorted.JSONToCCF([]byte(testEventJSON))
+		require.NoError(t, err)
+
+		sortedEncoded, err := sorted.JSONToCCF([]byte(testEventJSON))
+		require.NoError(t, err)
+
+		// Unsorted fields are rejected when the sort order is enforced
+
+		_, err = sorted.CCFToJSON(unsortedEncoded)
+		require.ErrorContains(t, err, "field names are not sorted")
+
+		_, err = unsorted.CCFToJSON(sortedEncoded)
+		require.NoError(t, err)
+
+		require.NoError(t, sorted.Validate(sortedEncoded))
+		require.NoError(t, unsorted.Validate(unsortedEncoded))
+	})
+
+	t.Run("type", func(t *testing.T) {
+		t.Parallel()
+
+		options := Options{
+			CCFFormat: CCFFormatHex,
+		}
+
+		expected := `{"value":{"staticType":{"kind":"Int"}},"type":"Type"}` + "\n"
+
+		fromJSON, err := options.Type([]byte(`{"type":"Int","value":"1"}`))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(fromJSON))
+
+		fromCCF, err := options.Type([]byte("d88282d88904 c24101"))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(fromCCF))
+	})
+
+	t.Run("validate non-deterministic", func(t *testing.T) {
+		t.Parallel()
+
+		options := Options{
+			CCFFormat: CCFFormatHex,
+		}
+
+		// The tag number 130 is encoded with two bytes instead of one
+		err := options.Validate([]byte("d9008282d88904c2420100"))
+		require.Equal(t,
+			NonDeterministicEncodingError{Offset: 0},
+			err,
+		)
+	})
+
+	t.Run("invalid sort mode", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseSortMode("random")
+		require.EqualError(t, err, "unsupported sort mode: random")
+	})
+}
+
+func TestWriteDiagnostic(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("CCF", func(t *testing.T) {
+		t.Parallel()
+
+		options := Options{
+			CCFFormat: CCFFormatBinary,
+		}
+
+		encoded, err := options.JSONToCCF([]byte(testEventJSON))
+		require.NoError(t, err)
+
+		var diagnostic bytes.Buffer
+		err = WriteDiagnostic(&diagnostic, encoded)
+		require.NoError(t, err)
+
+		assert.Equal(t,
+			`129([
+  [
+    162([
+      h'',
+      "A.0000000000000001.Foo.Bar",
+      [
+        [
+          "z",
+          137(4)
+        ],
+        [
+          "a",
+          138(137(1))
+        ]
+      ]
+    ])
+  ],
+  [
+    136(h''),
+    [
+      3(h''),
+      "hi"
+    ]
+  ]
+])
+`,
+			diagnostic.String(),
+		)
+	})
+
+	t.Run("CBOR", func(t *testing.T) {
+		t.Parallel()
+
+		for encoded, expected := range map[string]string{
+			"00":                 "0",
+			"1903e8":             "1000",
+			"20":                 "-1",
+			"3bffffffffffffffff": "-18446744073709551616",
+			"f4":                 "false",
+			"f5":                 "true",
+			"f6":                 "null",
+			"f7":                 "undefined",
+			"f0":                 "simple(16)",
+			"f93c00":             "1.0_1",
+			"f97c00":             "Infinity_1",
+			"fa47c35000":         "100000.0_2",
+			"fb3ff199999999999a": "1.1_3",
+			"80":                 "[]",
+			"a161614162":         "{\n  \"a\": h'62'\n}",
+		} {
+			data, err := hex.DecodeString(encoded)
+			require.NoError(t, err)
+
+			var diagnostic bytes.Buffer
+			err = WriteDiagnostic(&diagnostic, data)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected+"\n", diagnostic.String(), encoded)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		for encoded, expected := range map[string]string{
+			"9f":   "invalid CBOR at byte offset 1: unsupported indefinite-length item",
+			"1c":   "invalid CBOR at byte offset 1: reserved additional information 28",
+			"8201": "invalid CBOR at byte offset 2: unexpected end of data",
+			"0000": "unexpected trailing data at byte offset 1",
+		} {
+			data, err := hex.DecodeString(encoded)
+			require.NoError(t, err)
+
+			err = WriteDiagnostic(&bytes.Buffer{}, data)
+			require.EqualError(t, err, expected, encoded)
+		}
+	})
+}
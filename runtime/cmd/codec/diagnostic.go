@@ -0,0 +1,284 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package codec
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// CBOR major types, see RFC 8949, section 3.1
+const (
+	cborMajorTypeUnsignedInteger = 0
+	cborMajorTypeNegativeInteger = 1
+	cborMajorTypeByteString      = 2
+	cborMajorTypeTextString      = 3
+	cborMajorTypeArray           = 4
+	cborMajorTypeMap             = 5
+	cborMajorTypeTag             = 6
+	cborMajorTypeSimpleOrFloat   = 7
+)
+
+// WriteDiagnostic writes the given CBOR-encoded data, e.g. a CCF-encoded value,
+// in CBOR diagnostic notation, see RFC 8949, section 8.
+//
+// Arrays and maps are written with one element per line.
+// CCF only uses definite-length items, so indefinite-length items are rejected.
+func WriteDiagnostic(w io.Writer, data []byte) error {
+	d := &diagnosticWriter{
+		data: data,
+	}
+
+	err := d.writeItem(0)
+	if err != nil {
+		return err
+	}
+
+	if d.offset < len(data) {
+		return fmt.Errorf("unexpected trailing data at byte offset %d", d.offset)
+	}
+
+	d.builder.WriteByte('\n')
+
+	_, err = io.WriteString(w, d.builder.String())
+	return err
+}
+
+type diagnosticWriter struct {
+	data    []byte
+	offset  int
+	builder strings.Builder
+}
+
+func (d *diagnosticWriter) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid CBOR at byte offset %d: %s", d.offset, fmt.Sprintf(format, args...))
+}
+
+func (d *diagnosticWriter) read(length uint64) ([]byte, error) {
+	if length > uint64(len(d.data)-d.offset) {
+		return nil, d.errorf("unexpected end of data")
+	}
+	start := d.offset
+	d.offset += int(length)
+	return d.data[start:d.offset], nil
+}
+
+// readHead reads the head of a data item,
+// and returns its major type, its additional information, and its argument.
+func (d *diagnosticWriter) readHead() (majorType byte, additionalInfo byte, argument uint64, err error) {
+	head, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	majorType = head[0] >> 5
+	additionalInfo = head[0] & 0x1f
+
+	switch {
+	case additionalInfo < 24:
+		argument = uint64(additionalInfo)
+
+	case additionalInfo <= 27:
+		var bytes []byte
+		bytes, err = d.read(1 << (additionalInfo - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		for _, b := range bytes {
+			argument = argument<<8 | uint64(b)
+		}
+
+	case additionalInfo == 31:
+		return 0, 0, 0, d.errorf("unsupported indefinite-length item")
+
+	default:
+		return 0, 0, 0, d.errorf("reserved additional information %d", additionalInfo)
+	}
+
+	return majorType, additionalInfo, argument, nil
+}
+
+func (d *diagnosticWriter) newline(depth int) {
+	d.builder.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		d.builder.WriteString("  ")
+	}
+}
+
+func (d *diagnosticWriter) writeItem(depth int) error {
+	majorType, additionalInfo, argument, err := d.readHead()
+	if err != nil {
+		return err
+	}
+
+	switch majorType {
+	case cborMajorTypeUnsignedInteger:
+		d.builder.WriteString(strconv.FormatUint(argument, 10))
+
+	case cborMajorTypeNegativeInteger:
+		// The value is -1 - argument, which might not fit into an int64
+		value := new(big.Int).SetUint64(argument)
+		value.Neg(value)
+		value.Sub(value, big.NewInt(1))
+		d.builder.WriteString(value.String())
+
+	case cborMajorTypeByteString:
+		bytes, err := d.read(argument)
+		if err != nil {
+			return err
+		}
+		d.builder.WriteString("h'")
+		d.builder.WriteString(hex.EncodeToString(bytes))
+		d.builder.WriteByte('\'')
+
+	case cborMajorTypeTextString:
+		bytes, err := d.read(argument)
+		if err != nil {
+			return err
+		}
+		d.builder.WriteString(strconv.Quote(string(bytes)))
+
+	case cborMajorTypeArray:
+		return d.writeElements(depth, argument, '[', ']', false)
+
+	case cborMajorTypeMap:
+		return d.writeElements(depth, argument, '{', '}', true)
+
+	case cborMajorTypeTag:
+		d.builder.WriteString(strconv.FormatUint(argument, 10))
+		d.builder.WriteByte('(')
+		err := d.writeItem(depth)
+		if err != nil {
+			return err
+		}
+		d.builder.WriteByte(')')
+
+	case cborMajorTypeSimpleOrFloat:
+		d.writeSimpleOrFloat(additionalInfo, argument)
+	}
+
+	return nil
+}
+
+func (d *diagnosticWriter) writeElements(depth int, count uint64, open, closing byte, isMap bool) error {
+	d.builder.WriteByte(open)
+
+	if count == 0 {
+		d.builder.WriteByte(closing)
+		return nil
+	}
+
+	for i := uint64(0); i < count; i++ {
+		if i > 0 {
+			d.builder.WriteByte(',')
+		}
+		d.newline(depth + 1)
+
+		err := d.writeItem(depth + 1)
+		if err != nil {
+			return err
+		}
+
+		if isMap {
+			d.builder.WriteString(": ")
+			err = d.writeItem(depth + 1)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	d.newline(depth)
+	d.builder.WriteByte(closing)
+
+	return nil
+}
+
+func (d *diagnosticWriter) writeSimpleOrFloat(additionalInfo byte, argument uint64) {
+	switch additionalInfo {
+	case 20:
+		d.builder.WriteString("false")
+	case 21:
+		d.builder.WriteString("true")
+	case 22:
+		d.builder.WriteString("null")
+	case 23:
+		d.builder.WriteString("undefined")
+	case 25:
+		d.writeFloat(float64(float16ToFloat32(uint16(argument))), "_1")
+	case 26:
+		d.writeFloat(float64(math.Float32frombits(uint32(argument))), "_2")
+	case 27:
+		d.writeFloat(math.Float64frombits(argument), "_3")
+	default:
+		d.builder.WriteString("simple(")
+		d.builder.WriteString(strconv.FormatUint(argument, 10))
+		d.builder.WriteByte(')')
+	}
+}
+
+// writeFloat writes the given floating-point number,
+// with the given encoding indicator, see RFC 8949, section 8.1
+func (d *diagnosticWriter) writeFloat(value float64, encodingIndicator string) {
+	switch {
+	case math.IsNaN(value):
+		d.builder.WriteString("NaN")
+	case math.IsInf(value, 1):
+		d.builder.WriteString("Infinity")
+	case math.IsInf(value, -1):
+		d.builder.WriteString("-Infinity")
+	default:
+		formatted := strconv.FormatFloat(value, 'g', -1, 64)
+		if !strings.ContainsAny(formatted, ".eEn") {
+			formatted += ".0"
+		}
+		d.builder.WriteString(formatted)
+	}
+	d.builder.WriteString(encodingIndicator)
+}
+
+// float16ToFloat32 converts the given IEEE 754 half-precision floating-point number
+// to a single-precision floating-point number
+func float16ToFloat32(bits uint16) float32 {
+	sign := uint32(bits>>15) << 31
+	exponent := uint32(bits>>10) & 0x1f
+	mantissa := uint32(bits) & 0x3ff
+
+	switch exponent {
+	case 0:
+		// Zero or subnormal number
+		value := float32(mantissa) / (1 << 24)
+		if sign != 0 {
+			value = -value
+		}
+		return value
+
+	case 0x1f:
+		// Infinity or NaN
+		return math.Float32frombits(sign | 0xff<<23 | mantissa<<13)
+
+	default:
+		return math.Float32frombits(sign | (exponent+127-15)<<23 | mantissa<<13)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package codec
+
+import (
+	"github.com/onflow/cadence"
+)
+
+// inferTypes returns the given value with the types of arrays and dictionaries inferred,
+// and the missing field types of composites filled in.
+//
+// JSON-CDC does not encode the types of arrays and dictionaries, but CCF requires them.
+// The element type is the type of all elements, if they have the same type,
+// or AnyStruct (AnyResource for resources) otherwise.
+func inferTypes(value cadence.Value) cadence.Value {
+	switch value := value.(type) {
+	case cadence.Optional:
+		if value.Value == nil {
+			return value
+		}
+		return cadence.NewOptional(inferTypes(value.Value))
+
+	case cadence.Array:
+		if value.ArrayType != nil {
+			return value
+		}
+		values := make([]cadence.Value, len(value.Values))
+		for i, element := range value.Values {
+			values[i] = inferTypes(element)
+		}
+		value.Values = values
+		return value.WithType(cadence.NewVariableSizedArrayType(commonType(values)))
+
+	case cadence.Dictionary:
+		if value.DictionaryType != nil {
+			return value
+		}
+		pairs := make([]cadence.KeyValuePair, len(value.Pairs))
+		keys := make([]cadence.Value, len(value.Pairs))
+		values := make([]cadence.Value, len(value.Pairs))
+		for i, pair := range value.Pairs {
+			key := inferTypes(pair.Key)
+			element := inferTypes(pair.Value)
+			pairs[i] = cadence.KeyValuePair{Key: key, Value: element}
+			keys[i] = key
+			values[i] = element
+		}
+		value.Pairs = pairs
+		return value.WithType(cadence.NewDictionaryType(commonType(keys), commonType(values)))
+
+	case cadence.Struct:
+		value.Fields = inferFieldTypes(value.StructType.Fields, value.Fields)
+		return value
+
+	case cadence.Resource:
+		value.Fields = inferFieldTypes(value.ResourceType.Fields, value.Fields)
+		return value
+
+	case cadence.Event:
+		value.Fields = inferFieldTypes(value.EventType.Fields, value.Fields)
+		return value
+
+	case cadence.Contract:
+		value.Fields = inferFieldTypes(value.ContractType.Fields, value.Fields)
+		return value
+
+	default:
+		return value
+	}
+}
+
+// inferFieldTypes infers the types of the given field values,
+// and fills in the types of the given fields which are missing
+func inferFieldTypes(fields []cadence.Field, values []cadence.Value) []cadence.Value {
+	result := make([]cadence.Value, len(values))
+	for i, value := range values {
+		value = inferTypes(value)
+		result[i] = value
+
+		if i < len(fields) && isIncompleteType(fields[i].Type) {
+			fields[i].Type = value.Type()
+		}
+	}
+	return result
+}
+
+// isIncompleteType returns true if the given type is missing,
+// or is an optional type of a missing type
+func isIncompleteType(ty cadence.Type) bool {
+	switch ty := ty.(type) {
+	case nil:
+		return true
+	case *cadence.OptionalType:
+		return isIncompleteType(ty.Type)
+	default:
+		return false
+	}
+}
+
+// commonType returns the type of the given values if they all have the same type,
+// or else AnyResource if any value is a resource, or AnyStruct otherwise
+func commonType(values []cadence.Value) cadence.Type {
+	if len(values) == 0 {
+		return cadence.AnyStructType
+	}
+
+	first := values[0].Type()
+	same := true
+	for _, value := range values[1:] {
+		if !first.Equal(value.Type()) {
+			same = false
+			break
+		}
+	}
+	if same {
+		return first
+	}
+
+	for _, value := range values {
+		if _, ok := value.(cadence.Resource); ok {
+			return cadence.AnyResourceType
+		}
+	}
+	return cadence.AnyStructType
+}
@@ -0,0 +1,327 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dap
+
+import (
+	"encoding/binary"
+	goerrors "errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/onflow/atree"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/onflow/cadence"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+// host is a minimal runtime.Interface which executes programs
+// with an empty, in-memory ledger.
+//
+// Imports of string locations are resolved to files,
+// relative to the directory of the launched program.
+type host struct {
+	directory      string
+	signers        []runtime.Address
+	log            func(message string)
+	emit           func(event cadence.Event)
+	programs       map[runtime.Location]*interpreter.Program
+	storedValues   map[string][]byte
+	storageIndices map[string]uint64
+	sharedState    *interpreter.SharedState
+	uuid           uint64
+	accountIDs     map[common.Address]uint64
+}
+
+var _ runtime.Interface = &host{}
+
+func newHost(
+	directory string,
+	signers []runtime.Address,
+	log func(message string),
+	emit func(event cadence.Event),
+) *host {
+	return &host{
+		directory:      directory,
+		signers:        signers,
+		log:            log,
+		emit:           emit,
+		programs:       map[runtime.Location]*interpreter.Program{},
+		storedValues:   map[string][]byte{},
+		storageIndices: map[string]uint64{},
+		accountIDs:     map[common.Address]uint64{},
+	}
+}
+
+var errNotSupported = goerrors.New("not supported in the debug adapter")
+
+func (h *host) fileLocation(path string) common.StringLocation {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(h.directory, path)
+	}
+	return common.StringLocation(filepath.Clean(path))
+}
+
+func (h *host) ResolveLocation(
+	identifiers []runtime.Identifier,
+	location runtime.Location,
+) ([]runtime.ResolvedLocation, error) {
+	if stringLocation, ok := location.(common.StringLocation); ok {
+		location = h.fileLocation(string(stringLocation))
+	}
+
+	return []runtime.ResolvedLocation{
+		{
+			Location:    location,
+			Identifiers: identifiers,
+		},
+	}, nil
+}
+
+func (h *host) GetCode(location runtime.Location) ([]byte, error) {
+	stringLocation, ok := location.(common.StringLocation)
+	if !ok {
+		return nil, errNotSupported
+	}
+	return os.ReadFile(string(h.fileLocation(string(stringLocation))))
+}
+
+func (h *host) GetOrLoadProgram(
+	location runtime.Location,
+	load func() (*interpreter.Program, error),
+) (
+	program *interpreter.Program,
+	err error,
+) {
+	program, ok := h.programs[location]
+	if ok {
+		return program, nil
+	}
+
+	program, err = load()
+
+	// NOTE: important: still set empty program,
+	// even if error occurred
+
+	h.programs[location] = program
+
+	return program, err
+}
+
+func (h *host) SetInterpreterSharedState(state *interpreter.SharedState) {
+	h.sharedState = state
+}
+
+func (h *host) GetInterpreterSharedState() *interpreter.SharedState {
+	return h.sharedState
+}
+
+func storageKey(owner, key []byte) string {
+	return string(owner) + "|" + string(key)
+}
+
+func (h *host) GetValue(owner, key []byte) ([]byte, error) {
+	return h.storedValues[storageKey(owner, key)], nil
+}
+
+func (h *host) SetValue(owner, key, value []byte) error {
+	h.storedValues[storageKey(owner, key)] = value
+	return nil
+}
+
+func (h *host) ValueExists(owner, key []byte) (bool, error) {
+	return len(h.storedValues[storageKey(owner, key)]) > 0, nil
+}
+
+func (h *host) AllocateStorageIndex(owner []byte) (result atree.StorageIndex, err error) {
+	index := h.storageIndices[string(owner)] + 1
+	h.storageIndices[string(owner)] = index
+	binary.BigEndian.PutUint64(result[:], index)
+	return
+}
+
+func (h *host) CreateAccount(_ runtime.Address) (runtime.Address, error) {
+	return runtime.Address{}, errNotSupported
+}
+
+func (h *host) AddAccountKey(
+	_ runtime.Address,
+	_ *runtime.PublicKey,
+	_ runtime.HashAlgorithm,
+	_ int,
+) (*runtime.AccountKey, error) {
+	return nil, errNotSupported
+}
+
+func (h *host) GetAccountKey(_ runtime.Address, _ int) (*runtime.AccountKey, error) {
+	return nil, nil
+}
+
+func (h *host) AccountKeysCount(_ runtime.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (h *host) RevokeAccountKey(_ runtime.Address, _ int) (*runtime.AccountKey, error) {
+	return nil, errNotSupported
+}
+
+func (h *host) UpdateAccountContractCode(_ common.AddressLocation, _ []byte) error {
+	return errNotSupported
+}
+
+func (h *host) GetAccountContractCode(_ common.AddressLocation) ([]byte, error) {
+	return nil, nil
+}
+
+func (h *host) RemoveAccountContractCode(_ common.AddressLocation) error {
+	return errNotSupported
+}
+
+func (h *host) GetSigningAccounts() ([]runtime.Address, error) {
+	return h.signers, nil
+}
+
+func (h *host) ProgramLog(message string) error {
+	h.log(message)
+	return nil
+}
+
+func (h *host) EmitEvent(event cadence.Event) error {
+	h.emit(event)
+	return nil
+}
+
+func (h *host) GenerateUUID() (uint64, error) {
+	h.uuid++
+	return h.uuid, nil
+}
+
+func (h *host) DecodeArgument(argument []byte, _ cadence.Type) (cadence.Value, error) {
+	return jsoncdc.Decode(nil, argument)
+}
+
+func (h *host) GetCurrentBlockHeight() (uint64, error) {
+	return 0, nil
+}
+
+func (h *host) GetBlockAtHeight(_ uint64) (runtime.Block, bool, error) {
+	return runtime.Block{}, false, nil
+}
+
+func (h *host) ReadRandom(_ []byte) error {
+	// Leave the buffer zeroed, so executions are reproducible
+	return nil
+}
+
+func (h *host) VerifySignature(
+	_ []byte,
+	_ string,
+	_ []byte,
+	_ []byte,
+	_ runtime.SignatureAlgorithm,
+	_ runtime.HashAlgorithm,
+) (bool, error) {
+	return false, errNotSupported
+}
+
+func (h *host) Hash(_ []byte, _ string, _ runtime.HashAlgorithm) ([]byte, error) {
+	return nil, errNotSupported
+}
+
+func (h *host) GetAccountBalance(_ common.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (h *host) GetAccountAvailableBalance(_ common.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (h *host) GetStorageUsed(_ runtime.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (h *host) GetStorageCapacity(_ runtime.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (h *host) ImplementationDebugLog(_ string) error {
+	return nil
+}
+
+func (h *host) ValidatePublicKey(_ *runtime.PublicKey) error {
+	return errNotSupported
+}
+
+func (h *host) GetAccountContractNames(_ runtime.Address) ([]string, error) {
+	return nil, nil
+}
+
+func (h *host) RecordTrace(_ string, _ runtime.Location, _ time.Duration, _ []attribute.KeyValue) {
+	// NO-OP
+}
+
+func (h *host) BLSVerifyPOP(_ *runtime.PublicKey, _ []byte) (bool, error) {
+	return false, errNotSupported
+}
+
+func (h *host) BLSAggregateSignatures(_ [][]byte) ([]byte, error) {
+	return nil, errNotSupported
+}
+
+func (h *host) BLSAggregatePublicKeys(_ []*runtime.PublicKey) (*runtime.PublicKey, error) {
+	return nil, errNotSupported
+}
+
+func (h *host) ResourceOwnerChanged(
+	_ *interpreter.Interpreter,
+	_ *interpreter.CompositeValue,
+	_ common.Address,
+	_ common.Address,
+) {
+	// NO-OP
+}
+
+func (h *host) GenerateAccountID(address common.Address) (uint64, error) {
+	h.accountIDs[address]++
+	return h.accountIDs[address], nil
+}
+
+func (h *host) MeterMemory(_ common.MemoryUsage) error {
+	return nil
+}
+
+func (h *host) MeterComputation(_ common.ComputationKind, _ uint) error {
+	return nil
+}
+
+func (h *host) ComputationUsed() (uint64, error) {
+	return 0, nil
+}
+
+func (h *host) MemoryUsed() (uint64, error) {
+	return 0, nil
+}
+
+func (h *host) InteractionUsed() (uint64, error) {
+	return 0, nil
+}
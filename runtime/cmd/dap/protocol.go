@@ -0,0 +1,294 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// This file implements the subset of the Debug Adapter Protocol
+// (https://microsoft.github.io/debug-adapter-protocol/specification)
+// that is needed to drive an interpreter.Debugger.
+
+const (
+	messageTypeRequest  = "request"
+	messageTypeResponse = "response"
+	messageTypeEvent    = "event"
+)
+
+const contentLengthHeader = "Content-Length"
+
+// Request is a client-to-adapter request.
+type Request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// Response is an adapter-to-client response to a request.
+type Response struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+// Event is an adapter-to-client notification.
+type Event struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+// Capabilities are the features supported by the adapter,
+// returned in the response to the initialize request.
+type Capabilities struct {
+	SupportsConfigurationDoneRequest  bool `json:"supportsConfigurationDoneRequest"`
+	SupportsEvaluateForHovers         bool `json:"supportsEvaluateForHovers"`
+	SupportsTerminateRequest          bool `json:"supportsTerminateRequest"`
+	SupportsConditionalBreakpoints    bool `json:"supportsConditionalBreakpoints"`
+	SupportsHitConditionalBreakpoints bool `json:"supportsHitConditionalBreakpoints"`
+	SupportsLogPoints                 bool `json:"supportsLogPoints"`
+}
+
+type Source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type SourceBreakpoint struct {
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+	// Condition is a Cadence expression of type Bool
+	Condition string `json:"condition,omitempty"`
+	// HitCondition is the number of hits after which to stop, e.g. "3" or ">= 3"
+	HitCondition string `json:"hitCondition,omitempty"`
+	// LogMessage is logged instead of stopping.
+	// Expressions in braces are interpolated, e.g. "amount: {amount}"
+	LogMessage string `json:"logMessage,omitempty"`
+}
+
+type Breakpoint struct {
+	Verified bool    `json:"verified"`
+	Message  string  `json:"message,omitempty"`
+	Source   *Source `json:"source,omitempty"`
+	Line     int     `json:"line,omitempty"`
+}
+
+type Thread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type StackFrame struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Source *Source `json:"source,omitempty"`
+	Line   int     `json:"line"`
+	Column int     `json:"column"`
+}
+
+type Scope struct {
+	Name               string `json:"name"`
+	PresentationHint   string `json:"presentationHint,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type Variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// Request arguments
+
+type LaunchArguments struct {
+	// Program is the path of the transaction or script file to execute
+	Program string `json:"program"`
+	// Arguments are the JSON-Cadence encoded arguments
+	Arguments []json.RawMessage `json:"args,omitempty"`
+	// Signers are the hex-encoded addresses of the signing accounts of a transaction
+	Signers     []string `json:"signers,omitempty"`
+	StopOnEntry bool     `json:"stopOnEntry,omitempty"`
+	NoDebug     bool     `json:"noDebug,omitempty"`
+}
+
+type SetBreakpointsArguments struct {
+	Source      Source             `json:"source"`
+	Breakpoints []SourceBreakpoint `json:"breakpoints"`
+}
+
+type StackTraceArguments struct {
+	ThreadID   int `json:"threadId"`
+	StartFrame int `json:"startFrame,omitempty"`
+	Levels     int `json:"levels,omitempty"`
+}
+
+type ScopesArguments struct {
+	FrameID int `json:"frameId"`
+}
+
+type VariablesArguments struct {
+	VariablesReference int `json:"variablesReference"`
+}
+
+type EvaluateArguments struct {
+	Expression string `json:"expression"`
+	FrameID    int    `json:"frameId,omitempty"`
+	Context    string `json:"context,omitempty"`
+}
+
+// Event bodies
+
+type StoppedEventBody struct {
+	Reason            string `json:"reason"`
+	Description       string `json:"description,omitempty"`
+	ThreadID          int    `json:"threadId"`
+	AllThreadsStopped bool   `json:"allThreadsStopped"`
+}
+
+type OutputEventBody struct {
+	Category string `json:"category"`
+	Output   string `json:"output"`
+}
+
+type ExitedEventBody struct {
+	ExitCode int `json:"exitCode"`
+}
+
+// Conn reads requests from and writes responses and events to a client.
+// Writes are safe for concurrent use.
+type Conn struct {
+	reader  *bufio.Reader
+	writer  io.Writer
+	writeMu sync.Mutex
+	seq     int
+}
+
+func NewConn(reader io.Reader, writer io.Writer) *Conn {
+	return &Conn{
+		reader: bufio.NewReader(reader),
+		writer: writer,
+	}
+}
+
+// ReadRequest reads the next request from the client.
+func (c *Conn) ReadRequest() (*Request, error) {
+	headers, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	contentLength, err := strconv.Atoi(strings.TrimSpace(headers.Get(contentLengthHeader)))
+	if err != nil || contentLength <= 0 {
+		return nil, fmt.Errorf("invalid %s header", contentLengthHeader)
+	}
+
+	content := make([]byte, contentLength)
+	_, err = io.ReadFull(c.reader, content)
+	if err != nil {
+		return nil, err
+	}
+
+	var request Request
+	err = json.Unmarshal(content, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Type != messageTypeRequest {
+		return nil, fmt.Errorf("unsupported message type: %s", request.Type)
+	}
+
+	return &request, nil
+}
+
+func (c *Conn) write(message interface{ setSeq(int) }) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.seq++
+	message.setSeq(c.seq)
+
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.writer, "%s: %d\r\n\r\n", contentLengthHeader, len(content))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.writer.Write(content)
+	return err
+}
+
+func (r *Response) setSeq(seq int) {
+	r.Seq = seq
+}
+
+func (e *Event) setSeq(seq int) {
+	e.Seq = seq
+}
+
+// SendResponse sends a successful response for the given request.
+func (c *Conn) SendResponse(request *Request, body any) error {
+	return c.write(&Response{
+		Type:       messageTypeResponse,
+		RequestSeq: request.Seq,
+		Success:    true,
+		Command:    request.Command,
+		Body:       body,
+	})
+}
+
+// SendErrorResponse sends a failed response for the given request.
+func (c *Conn) SendErrorResponse(request *Request, err error) error {
+	return c.write(&Response{
+		Type:       messageTypeResponse,
+		RequestSeq: request.Seq,
+		Success:    false,
+		Command:    request.Command,
+		Message:    err.Error(),
+	})
+}
+
+// SendEvent sends an event.
+func (c *Conn) SendEvent(event string, body any) error {
+	return c.write(&Event{
+		Type:  messageTypeEvent,
+		Event: event,
+		Body:  body,
+	})
+}
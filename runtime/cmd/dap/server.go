@@ -0,0 +1,915 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dap
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/pretty"
+)
+
+// The interpreter is single-threaded
+const mainThreadID = 1
+
+const (
+	stopReasonEntry      = "entry"
+	stopReasonStep       = "step"
+	stopReasonBreakpoint = "breakpoint"
+	stopReasonPause      = "pause"
+)
+
+const (
+	scopeLocals = "Locals"
+)
+
+var errNotStopped = goerrors.New("program is not stopped")
+
+// Session is a debug session for a single client connection.
+//
+// The session launches the program on a separate goroutine,
+// using an interpreter.Debugger to stop at breakpoints and to step.
+type Session struct {
+	conn     *Conn
+	debugger *interpreter.Debugger
+
+	// mu protects the fields below,
+	// which are accessed by both the request loop and the program
+	mu           sync.Mutex
+	launch       *launch
+	breakpoints  map[string][]SourceBreakpoint
+	configured   bool
+	started      bool
+	disconnected bool
+	stop         *interpreter.Stop
+	stopReason   string
+	variables    variableHandles
+
+	// done is closed when the program has finished executing
+	done chan struct{}
+}
+
+func NewSession(reader io.Reader, writer io.Writer) *Session {
+	session := &Session{
+		conn:        NewConn(reader, writer),
+		debugger:    interpreter.NewDebugger(),
+		breakpoints: map[string][]SourceBreakpoint{},
+		done:        make(chan struct{}),
+	}
+
+	session.debugger.SetLogpointHandler(func(message string, _ interpreter.LocationRange) {
+		session.output("console", message+"\n")
+	})
+
+	return session
+}
+
+// launch is a program which is launched in a session
+type launch struct {
+	LaunchArguments
+	code     []byte
+	program  *ast.Program
+	location common.Location
+}
+
+// Run handles requests until the client disconnects
+// or the connection is closed.
+func (s *Session) Run() error {
+	for {
+		request, err := s.conn.ReadRequest()
+		if err != nil {
+			if goerrors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		disconnect, err := s.handleRequest(request)
+		if err != nil {
+			err = s.conn.SendErrorResponse(request, err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if disconnect {
+			return nil
+		}
+	}
+}
+
+func (s *Session) handleRequest(request *Request) (disconnect bool, err error) {
+	switch request.Command {
+	case "initialize":
+		return false, s.onInitialize(request)
+	case "launch":
+		return false, s.onLaunch(request)
+	case "setBreakpoints":
+		return false, s.onSetBreakpoints(request)
+	case "setExceptionBreakpoints":
+		return false, s.conn.SendResponse(request, nil)
+	case "configurationDone":
+		return false, s.onConfigurationDone(request)
+	case "threads":
+		return false, s.onThreads(request)
+	case "stackTrace":
+		return false, s.onStackTrace(request)
+	case "scopes":
+		return false, s.onScopes(request)
+	case "variables":
+		return false, s.onVariables(request)
+	case "evaluate":
+		return false, s.onEvaluate(request)
+	case "continue":
+		return false, s.onContinue(request)
+	case "next", "stepIn", "stepOut":
+		return false, s.onStep(request)
+	case "pause":
+		return false, s.onPause(request)
+	case "disconnect", "terminate":
+		return true, s.onDisconnect(request)
+	default:
+		return false, fmt.Errorf("unsupported request: %s", request.Command)
+	}
+}
+
+func unmarshalArguments(request *Request, arguments any) error {
+	if len(request.Arguments) == 0 {
+		return nil
+	}
+	return json.Unmarshal(request.Arguments, arguments)
+}
+
+func (s *Session) onInitialize(request *Request) error {
+	err := s.conn.SendResponse(
+		request,
+		Capabilities{
+			SupportsConfigurationDoneRequest:  true,
+			SupportsEvaluateForHovers:         true,
+			SupportsTerminateRequest:          true,
+			SupportsConditionalBreakpoints:    true,
+			SupportsHitConditionalBreakpoints: true,
+			SupportsLogPoints:                 true,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.conn.SendEvent("initialized", nil)
+}
+
+func (s *Session) onLaunch(request *Request) error {
+	var arguments LaunchArguments
+	err := unmarshalArguments(request, &arguments)
+	if err != nil {
+		return err
+	}
+
+	launch, err := newLaunch(arguments)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.launch = launch
+	// Breakpoints for the program may have been set before its location was known
+	_ = s.applyBreakpoints(launch.Program)
+	s.mu.Unlock()
+
+	err = s.conn.SendResponse(request, nil)
+	if err != nil {
+		return err
+	}
+
+	return s.startIfReady()
+}
+
+func newLaunch(arguments LaunchArguments) (*launch, error) {
+	if arguments.Program == "" {
+		return nil, goerrors.New("missing program")
+	}
+
+	path, err := filepath.Abs(arguments.Program)
+	if err != nil {
+		return nil, err
+	}
+	arguments.Program = path
+
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := parser.ParseProgram(nil, code, parser.Config{})
+	if err != nil {
+		var sb strings.Builder
+		printErr := pretty.NewErrorPrettyPrinter(&sb, false).
+			PrettyPrintError(
+				err,
+				common.StringLocation(path),
+				map[common.Location][]byte{
+					common.StringLocation(path): code,
+				},
+			)
+		if printErr != nil {
+			return nil, err
+		}
+		return nil, goerrors.New(sb.String())
+	}
+
+	// The runtime requires the launched program to have a transaction or script location.
+	// Like on-chain, identify the program by the hash of its code
+
+	hash := sha256.Sum256(code)
+
+	var location common.Location
+	if isTransaction(program) {
+		location = common.TransactionLocation(hash)
+	} else {
+		location = common.ScriptLocation(hash)
+	}
+
+	return &launch{
+		LaunchArguments: arguments,
+		code:            code,
+		program:         program,
+		location:        location,
+	}, nil
+}
+
+func (s *Session) onConfigurationDone(request *Request) error {
+	s.mu.Lock()
+	s.configured = true
+	s.mu.Unlock()
+
+	err := s.conn.SendResponse(request, nil)
+	if err != nil {
+		return err
+	}
+
+	return s.startIfReady()
+}
+
+// sourceLocation returns the location of the program at the given absolute source path.
+// Imported programs are executed with string locations of their absolute file paths,
+// so breakpoints and stack frames can be mapped to sources.
+func (s *Session) sourceLocation(path string) common.Location {
+	if s.launch != nil && path == s.launch.Program {
+		return s.launch.location
+	}
+
+	return common.StringLocation(path)
+}
+
+func (s *Session) locationSource(location common.Location) *Source {
+	var path string
+
+	switch location := location.(type) {
+	case common.StringLocation:
+		path = string(location)
+
+	default:
+		if s.launch == nil || location != s.launch.location {
+			return &Source{
+				Name: location.String(),
+			}
+		}
+		path = s.launch.Program
+	}
+
+	return &Source{
+		Name: filepath.Base(path),
+		Path: path,
+	}
+}
+
+// applyBreakpoints sets the debugger's breakpoints for the given source path.
+// It returns the error for each breakpoint which could not be added.
+func (s *Session) applyBreakpoints(path string) []error {
+	s.debugger.ClearBreakpointsForLocation(common.StringLocation(path))
+
+	location := s.sourceLocation(path)
+	s.debugger.ClearBreakpointsForLocation(location)
+
+	sourceBreakpoints := s.breakpoints[path]
+	errs := make([]error, len(sourceBreakpoints))
+
+	for i, sourceBreakpoint := range sourceBreakpoints {
+		line := uint(sourceBreakpoint.Line)
+
+		if sourceBreakpoint.Condition == "" &&
+			sourceBreakpoint.HitCondition == "" &&
+			sourceBreakpoint.LogMessage == "" {
+
+			s.debugger.AddBreakpoint(location, line)
+			continue
+		}
+
+		hitCount, err := parseHitCondition(sourceBreakpoint.HitCondition)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		breakpoint, err := runtime.ParseBreakpoint(
+			sourceBreakpoint.Condition,
+			hitCount,
+			sourceBreakpoint.LogMessage,
+		)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		s.debugger.AddConditionalBreakpoint(location, line, breakpoint)
+	}
+
+	return errs
+}
+
+// parseHitCondition parses a hit condition of the form "N" or ">= N"
+func parseHitCondition(hitCondition string) (uint, error) {
+	hitCondition = strings.TrimSpace(hitCondition)
+	if hitCondition == "" {
+		return 0, nil
+	}
+
+	hitCondition = strings.TrimSpace(strings.TrimPrefix(hitCondition, ">="))
+
+	hitCount, err := strconv.ParseUint(hitCondition, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hit condition: expected a number of hits")
+	}
+
+	return uint(hitCount), nil
+}
+
+func (s *Session) onSetBreakpoints(request *Request) error {
+	var arguments SetBreakpointsArguments
+	err := unmarshalArguments(request, &arguments)
+	if err != nil {
+		return err
+	}
+
+	if arguments.Source.Path == "" {
+		return goerrors.New("missing source path")
+	}
+
+	path, err := filepath.Abs(arguments.Source.Path)
+	if err != nil {
+		return err
+	}
+
+	sourceBreakpoints := make([]SourceBreakpoint, 0, len(arguments.Breakpoints))
+	breakpoints := make([]*Breakpoint, 0, len(arguments.Breakpoints))
+	validBreakpoints := make([]*Breakpoint, 0, len(arguments.Breakpoints))
+
+	for _, sourceBreakpoint := range arguments.Breakpoints {
+		if sourceBreakpoint.Line <= 0 {
+			breakpoints = append(breakpoints, &Breakpoint{
+				Verified: false,
+				Message:  "invalid line",
+			})
+			continue
+		}
+
+		sourceBreakpoints = append(sourceBreakpoints, sourceBreakpoint)
+
+		breakpoint := &Breakpoint{
+			Verified: true,
+			Source:   &arguments.Source,
+			Line:     sourceBreakpoint.Line,
+		}
+		breakpoints = append(breakpoints, breakpoint)
+		validBreakpoints = append(validBreakpoints, breakpoint)
+	}
+
+	s.mu.Lock()
+	s.breakpoints[path] = sourceBreakpoints
+	errs := s.applyBreakpoints(path)
+	s.mu.Unlock()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		validBreakpoints[i].Verified = false
+		validBreakpoints[i].Message = err.Error()
+	}
+
+	return s.conn.SendResponse(
+		request,
+		map[string]any{
+			"breakpoints": breakpoints,
+		},
+	)
+}
+
+func (s *Session) onThreads(request *Request) error {
+	return s.conn.SendResponse(
+		request,
+		map[string]any{
+			"threads": []Thread{
+				{
+					ID:   mainThreadID,
+					Name: "main",
+				},
+			},
+		},
+	)
+}
+
+func (s *Session) onStackTrace(request *Request) error {
+	var arguments StackTraceArguments
+	err := unmarshalArguments(request, &arguments)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop == nil {
+		return errNotStopped
+	}
+
+	callStack := s.stop.CallStack()
+	totalFrames := len(callStack)
+
+	startFrame := arguments.StartFrame
+	if startFrame > totalFrames {
+		startFrame = totalFrames
+	}
+
+	endFrame := totalFrames
+	if arguments.Levels > 0 && startFrame+arguments.Levels < endFrame {
+		endFrame = startFrame + arguments.Levels
+	}
+
+	frames := make([]StackFrame, 0, endFrame-startFrame)
+
+	for id := startFrame; id < endFrame; id++ {
+		frame := s.frame(id)
+
+		locationRange := frame.LocationRange
+		position := locationRange.StartPosition()
+		source := s.locationSource(locationRange.Location)
+
+		frames = append(frames, StackFrame{
+			ID:     id,
+			Name:   frameName(frame, source),
+			Source: source,
+			Line:   position.Line,
+			// DAP columns are 1-based
+			Column: position.Column + 1,
+		})
+	}
+
+	return s.conn.SendResponse(
+		request,
+		map[string]any{
+			"stackFrames": frames,
+			"totalFrames": totalFrames,
+		},
+	)
+}
+
+// frame returns the stack frame with the given ID.
+// Frame IDs are indices into the call stack, starting with the current, innermost frame,
+// which is the reverse order of interpreter.Stop.CallStack.
+//
+// NOTE: s.mu must be held and the program must be stopped
+func (s *Session) frame(id int) *interpreter.StackFrame {
+	callStack := s.stop.CallStack()
+	index := len(callStack) - 1 - id
+	if index < 0 || index >= len(callStack) {
+		return nil
+	}
+	return &callStack[index]
+}
+
+// frameName returns the name of the function of the given frame.
+// Functions do not have names, so the name is the invoked expression, e.g. `vault.withdraw`.
+func frameName(frame *interpreter.StackFrame, source *Source) string {
+	if invocationExpression, ok := frame.Invocation.HasPosition.(*ast.InvocationExpression); ok {
+		return invocationExpression.InvokedExpression.String()
+	}
+
+	return source.Name
+}
+
+func (s *Session) onScopes(request *Request) error {
+	var arguments ScopesArguments
+	err := unmarshalArguments(request, &arguments)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop == nil {
+		return errNotStopped
+	}
+
+	frame := s.frame(arguments.FrameID)
+	if frame == nil {
+		return fmt.Errorf("unknown frame: %d", arguments.FrameID)
+	}
+
+	localsReference := s.variables.add(func() []Variable {
+		return s.variables.activationVariables(
+			frame.Interpreter,
+			frame.LocationRange,
+			frame.Activation,
+		)
+	})
+
+	return s.conn.SendResponse(
+		request,
+		map[string]any{
+			"scopes": []Scope{
+				{
+					Name:               scopeLocals,
+					PresentationHint:   "locals",
+					VariablesReference: localsReference,
+				},
+			},
+		},
+	)
+}
+
+func (s *Session) onVariables(request *Request) error {
+	var arguments VariablesArguments
+	err := unmarshalArguments(request, &arguments)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop == nil {
+		return errNotStopped
+	}
+
+	children := s.variables.get(arguments.VariablesReference)
+	if children == nil {
+		return fmt.Errorf("unknown variables reference: %d", arguments.VariablesReference)
+	}
+
+	variables := children()
+	if variables == nil {
+		variables = []Variable{}
+	}
+
+	return s.conn.SendResponse(
+		request,
+		map[string]any{
+			"variables": variables,
+		},
+	)
+}
+
+// onEvaluate evaluates the given expression in the activation of the given frame
+func (s *Session) onEvaluate(request *Request) error {
+	var arguments EvaluateArguments
+	err := unmarshalArguments(request, &arguments)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop == nil {
+		return errNotStopped
+	}
+
+	frame := s.frame(arguments.FrameID)
+	if frame == nil {
+		return fmt.Errorf("unknown frame: %d", arguments.FrameID)
+	}
+
+	inter := frame.Interpreter
+
+	expression, err := runtime.ParseDebuggerExpression(arguments.Expression)
+	if err != nil {
+		return err
+	}
+
+	value, err := s.debugger.Evaluate(inter, frame.Activation, expression)
+	if err != nil {
+		return err
+	}
+
+	result := s.variables.variable(
+		inter,
+		frame.LocationRange,
+		strings.TrimSpace(arguments.Expression),
+		value,
+	)
+
+	return s.conn.SendResponse(
+		request,
+		map[string]any{
+			"result":             result.Value,
+			"type":               result.Type,
+			"variablesReference": result.VariablesReference,
+		},
+	)
+}
+
+// resume continues the stopped program, stepping in the given mode, if any.
+// The given reason is reported when the program stops next.
+func (s *Session) resume(reason string, stepMode interpreter.StepMode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop == nil {
+		return errNotStopped
+	}
+
+	if stepMode != interpreter.StepModeNone {
+		s.debugger.RequestStep(stepMode)
+	}
+
+	s.stop = nil
+	s.stopReason = reason
+	s.variables.reset()
+
+	s.debugger.Continue()
+
+	return nil
+}
+
+func (s *Session) onContinue(request *Request) error {
+	err := s.resume("", interpreter.StepModeNone)
+	if err != nil {
+		return err
+	}
+
+	return s.conn.SendResponse(
+		request,
+		map[string]any{
+			"allThreadsContinued": true,
+		},
+	)
+}
+
+var stepModes = map[string]interpreter.StepMode{
+	"stepIn":  interpreter.StepModeInto,
+	"next":    interpreter.StepModeOver,
+	"stepOut": interpreter.StepModeOut,
+}
+
+func (s *Session) onStep(request *Request) error {
+	err := s.resume(stopReasonStep, stepModes[request.Command])
+	if err != nil {
+		return err
+	}
+
+	return s.conn.SendResponse(request, nil)
+}
+
+func (s *Session) onPause(request *Request) error {
+	s.mu.Lock()
+	if s.stop == nil {
+		s.stopReason = stopReasonPause
+	}
+	s.mu.Unlock()
+
+	s.debugger.RequestPause()
+
+	return s.conn.SendResponse(request, nil)
+}
+
+func (s *Session) onDisconnect(request *Request) error {
+	// Let the program run to completion
+
+	s.mu.Lock()
+	s.disconnected = true
+	s.mu.Unlock()
+
+	s.debugger.ClearBreakpoints()
+
+	_ = s.resume("", interpreter.StepModeNone)
+
+	return s.conn.SendResponse(request, nil)
+}
+
+func (s *Session) startIfReady() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started || !s.configured || s.launch == nil {
+		return nil
+	}
+
+	s.started = true
+
+	launch := s.launch
+
+	if launch.NoDebug {
+		s.debugger.ClearBreakpoints()
+	} else if launch.StopOnEntry {
+		s.stopReason = stopReasonEntry
+		s.debugger.RequestPause()
+	}
+
+	go s.watchStops()
+	go s.execute(launch)
+
+	return nil
+}
+
+func (s *Session) watchStops() {
+	for {
+		select {
+		case stop := <-s.debugger.Stops():
+			s.onStop(stop)
+
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Session) onStop(stop interpreter.Stop) {
+	s.mu.Lock()
+
+	if s.disconnected {
+		s.mu.Unlock()
+		s.debugger.Continue()
+		return
+	}
+
+	s.stop = &stop
+
+	reason := s.stopReason
+	if reason == "" {
+		reason = stopReasonBreakpoint
+	}
+	s.stopReason = ""
+
+	s.mu.Unlock()
+
+	var description string
+	if stop.BreakpointError != nil {
+		s.output("stderr", stop.BreakpointError.Error()+"\n")
+
+		description = "failed to evaluate breakpoint condition"
+	}
+
+	_ = s.conn.SendEvent(
+		"stopped",
+		StoppedEventBody{
+			Reason:            reason,
+			Description:       description,
+			ThreadID:          mainThreadID,
+			AllThreadsStopped: true,
+		},
+	)
+}
+
+func (s *Session) output(category string, output string) {
+	_ = s.conn.SendEvent(
+		"output",
+		OutputEventBody{
+			Category: category,
+			Output:   output,
+		},
+	)
+}
+
+func (s *Session) execute(launch *launch) {
+	defer close(s.done)
+
+	exitCode := 0
+
+	err := s.run(launch)
+	if err != nil {
+		exitCode = 1
+
+		var sb strings.Builder
+		printError(&sb, err)
+		s.output("stderr", sb.String())
+	}
+
+	_ = s.conn.SendEvent(
+		"exited",
+		ExitedEventBody{
+			ExitCode: exitCode,
+		},
+	)
+	_ = s.conn.SendEvent("terminated", nil)
+}
+
+func printError(writer pretty.Writer, err error) {
+	var runtimeErr runtime.Error
+	if goerrors.As(err, &runtimeErr) {
+		printErr := pretty.NewErrorPrettyPrinter(writer, false).
+			PrettyPrintError(runtimeErr.Err, runtimeErr.Location, runtimeErr.Codes)
+		if printErr == nil {
+			return
+		}
+	}
+
+	_, _ = fmt.Fprintln(writer, err.Error())
+}
+
+// run executes the launched program,
+// as a transaction if it declares one, and as a script otherwise
+func (s *Session) run(launch *launch) error {
+	signers := make([]runtime.Address, 0, len(launch.Signers))
+	for _, signer := range launch.Signers {
+		address, err := common.HexToAddress(signer)
+		if err != nil {
+			return fmt.Errorf("invalid signer address %s: %w", signer, err)
+		}
+		signers = append(signers, address)
+	}
+
+	arguments := make([][]byte, 0, len(launch.Arguments))
+	for _, argument := range launch.Arguments {
+		arguments = append(arguments, argument)
+	}
+
+	runtimeInterface := newHost(
+		filepath.Dir(launch.Program),
+		signers,
+		func(message string) {
+			s.output("stdout", message+"\n")
+		},
+		func(event cadence.Event) {
+			s.output("console", fmt.Sprintf("event: %s\n", event))
+		},
+	)
+
+	config := runtime.Config{
+		AttachmentsEnabled: true,
+	}
+	if !launch.NoDebug {
+		config.Debugger = s.debugger
+	}
+
+	rt := runtime.NewInterpreterRuntime(config)
+
+	script := runtime.Script{
+		Source:    launch.code,
+		Arguments: arguments,
+	}
+
+	context := runtime.Context{
+		Interface: runtimeInterface,
+		Location:  launch.location,
+	}
+
+	if _, ok := launch.location.(common.TransactionLocation); ok {
+		return rt.ExecuteTransaction(script, context)
+	}
+
+	result, err := rt.ExecuteScript(script, context)
+	if err != nil {
+		return err
+	}
+
+	s.output("console", fmt.Sprintf("result: %s\n", result))
+
+	return nil
+}
+
+func isTransaction(program *ast.Program) bool {
+	return len(program.TransactionDeclarations()) > 0
+}
@@ -0,0 +1,446 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testClient struct {
+	t      *testing.T
+	writer io.Writer
+	reader *bufio.Reader
+	seq    int
+	// outputs are the bodies of the received output events
+	outputs []OutputEventBody
+}
+
+type testMessage struct {
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+func newTestClient(t *testing.T) *testClient {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	session := NewSession(serverReader, serverWriter)
+
+	go func() {
+		_ = session.Run()
+		_ = serverWriter.Close()
+	}()
+
+	return &testClient{
+		t:      t,
+		writer: clientWriter,
+		reader: bufio.NewReader(clientReader),
+	}
+}
+
+func (c *testClient) send(command string, arguments any) int {
+	c.seq++
+
+	content, err := json.Marshal(map[string]any{
+		"seq":       c.seq,
+		"type":      "request",
+		"command":   command,
+		"arguments": arguments,
+	})
+	require.NoError(c.t, err)
+
+	_, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	require.NoError(c.t, err)
+
+	return c.seq
+}
+
+func (c *testClient) read() testMessage {
+	headers, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	require.NoError(c.t, err)
+
+	contentLength, err := strconv.Atoi(headers.Get("Content-Length"))
+	require.NoError(c.t, err)
+
+	content := make([]byte, contentLength)
+	_, err = io.ReadFull(c.reader, content)
+	require.NoError(c.t, err)
+
+	var message testMessage
+	err = json.Unmarshal(content, &message)
+	require.NoError(c.t, err)
+
+	return message
+}
+
+// readOutput records the given message if it is an output event
+func (c *testClient) readOutput(message testMessage) bool {
+	if message.Type != "event" || message.Event != "output" {
+		return false
+	}
+
+	c.t.Log(string(message.Body))
+
+	var output OutputEventBody
+	err := json.Unmarshal(message.Body, &output)
+	require.NoError(c.t, err)
+	c.outputs = append(c.outputs, output)
+
+	return true
+}
+
+// expectEvent reads messages until the given event is received,
+// skipping output events
+func (c *testClient) expectEvent(event string) testMessage {
+	for {
+		message := c.read()
+		if c.readOutput(message) {
+			continue
+		}
+		require.Equal(c.t, "event", message.Type, "expected event %s", event)
+		require.Equal(c.t, event, message.Event)
+		return message
+	}
+}
+
+// request sends the given request and returns the body of the successful response
+func (c *testClient) request(command string, arguments any, body any) {
+	seq := c.send(command, arguments)
+
+	for {
+		message := c.read()
+		if c.readOutput(message) {
+			continue
+		}
+
+		require.Equal(c.t, "response", message.Type)
+		require.Equal(c.t, seq, message.RequestSeq)
+		require.True(c.t, message.Success, message.Message)
+
+		if body != nil {
+			err := json.Unmarshal(message.Body, body)
+			require.NoError(c.t, err)
+		}
+		return
+	}
+}
+
+func writeTestProgram(t *testing.T, code string) string {
+	path := filepath.Join(t.TempDir(), "test.cdc")
+	err := os.WriteFile(path, []byte(code), 0600)
+	require.NoError(t, err)
+	return path
+}
+
+func TestSessionBreakpoint(t *testing.T) {
+
+	t.Parallel()
+
+	program := writeTestProgram(t, `
+      transaction {
+          prepare(signer: &Account) {
+              let answer = 42
+              let numbers = [1, 2]
+              log(answer)
+          }
+      }
+    `)
+
+	client := newTestClient(t)
+
+	client.request("initialize", map[string]any{"adapterID": "cadence"}, nil)
+	client.expectEvent("initialized")
+
+	var breakpoints struct {
+		Breakpoints []Breakpoint `json:"breakpoints"`
+	}
+	client.request(
+		"setBreakpoints",
+		SetBreakpointsArguments{
+			Source: Source{Path: program},
+			Breakpoints: []SourceBreakpoint{
+				{Line: 6},
+			},
+		},
+		&breakpoints,
+	)
+	require.Len(t, breakpoints.Breakpoints, 1)
+	require.True(t, breakpoints.Breakpoints[0].Verified)
+
+	client.request(
+		"launch",
+		LaunchArguments{
+			Program: program,
+			Signers: []string{"0x1"},
+		},
+		nil,
+	)
+	client.request("configurationDone", nil, nil)
+
+	stopped := client.expectEvent("stopped")
+
+	var stoppedBody StoppedEventBody
+	err := json.Unmarshal(stopped.Body, &stoppedBody)
+	require.NoError(t, err)
+	require.Equal(t, stopReasonBreakpoint, stoppedBody.Reason)
+
+	var stackTrace struct {
+		StackFrames []StackFrame `json:"stackFrames"`
+	}
+	client.request("stackTrace", StackTraceArguments{ThreadID: mainThreadID}, &stackTrace)
+	require.NotEmpty(t, stackTrace.StackFrames)
+	require.Equal(t, 6, stackTrace.StackFrames[0].Line)
+	require.Equal(t, program, stackTrace.StackFrames[0].Source.Path)
+
+	var scopes struct {
+		Scopes []Scope `json:"scopes"`
+	}
+	client.request("scopes", ScopesArguments{FrameID: stackTrace.StackFrames[0].ID}, &scopes)
+	require.NotEmpty(t, scopes.Scopes)
+
+	var variables struct {
+		Variables []Variable `json:"variables"`
+	}
+	client.request(
+		"variables",
+		VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference},
+		&variables,
+	)
+
+	variablesByName := map[string]Variable{}
+	for _, variable := range variables.Variables {
+		variablesByName[variable.Name] = variable
+	}
+
+	require.Equal(t, "42", variablesByName["answer"].Value)
+	require.Equal(t, "Int", variablesByName["answer"].Type)
+
+	numbers := variablesByName["numbers"]
+	require.NotZero(t, numbers.VariablesReference)
+
+	client.request(
+		"variables",
+		VariablesArguments{VariablesReference: numbers.VariablesReference},
+		&variables,
+	)
+	require.Equal(t,
+		[]Variable{
+			{Name: "0", Value: "1", Type: "Int"},
+			{Name: "1", Value: "2", Type: "Int"},
+		},
+		variables.Variables,
+	)
+
+	client.request("continue", map[string]any{"threadId": mainThreadID}, nil)
+
+	exited := client.expectEvent("exited")
+
+	var exitedBody ExitedEventBody
+	err = json.Unmarshal(exited.Body, &exitedBody)
+	require.NoError(t, err)
+	require.Equal(t, 0, exitedBody.ExitCode)
+
+	client.expectEvent("terminated")
+
+	client.request("disconnect", nil, nil)
+}
+
+func TestSessionStopOnEntryAndStep(t *testing.T) {
+
+	t.Parallel()
+
+	program := writeTestProgram(t, `
+      access(all) fun double(_ n: Int): Int {
+          return n * 2
+      }
+
+      access(all) fun main(): Int {
+          let a = double(1)
+          let b = double(a)
+          return a + b
+      }
+    `)
+
+	client := newTestClient(t)
+
+	client.request("initialize", nil, nil)
+	client.expectEvent("initialized")
+
+	client.request(
+		"launch",
+		LaunchArguments{
+			Program:     program,
+			StopOnEntry: true,
+		},
+		nil,
+	)
+	client.request("configurationDone", nil, nil)
+
+	expectStop := func(reason string, lines ...int) []StackFrame {
+		stopped := client.expectEvent("stopped")
+
+		var stoppedBody StoppedEventBody
+		err := json.Unmarshal(stopped.Body, &stoppedBody)
+		require.NoError(t, err)
+		require.Equal(t, reason, stoppedBody.Reason)
+
+		var stackTrace struct {
+			StackFrames []StackFrame `json:"stackFrames"`
+		}
+		client.request("stackTrace", StackTraceArguments{ThreadID: mainThreadID}, &stackTrace)
+
+		frameLines := make([]int, 0, len(stackTrace.StackFrames))
+		for _, frame := range stackTrace.StackFrames {
+			frameLines = append(frameLines, frame.Line)
+		}
+		require.Equal(t, lines, frameLines)
+
+		return stackTrace.StackFrames
+	}
+
+	expectStop(stopReasonEntry, 7)
+
+	client.request("stepIn", map[string]any{"threadId": mainThreadID}, nil)
+	frames := expectStop(stopReasonStep, 3, 7)
+	require.Equal(t, "double", frames[0].Name)
+
+	var evaluate struct {
+		Result string `json:"result"`
+	}
+	client.request("evaluate", EvaluateArguments{Expression: "n", FrameID: frames[0].ID}, &evaluate)
+	require.Equal(t, "1", evaluate.Result)
+
+	client.request("stepOut", map[string]any{"threadId": mainThreadID}, nil)
+	expectStop(stopReasonStep, 8)
+
+	client.request("next", map[string]any{"threadId": mainThreadID}, nil)
+	frames = expectStop(stopReasonStep, 9)
+
+	client.request("evaluate", EvaluateArguments{Expression: "b", FrameID: frames[0].ID}, &evaluate)
+	require.Equal(t, "4", evaluate.Result)
+
+	client.request("continue", map[string]any{"threadId": mainThreadID}, nil)
+
+	client.expectEvent("exited")
+	client.expectEvent("terminated")
+}
+
+func TestSessionConditionalBreakpointAndLogpoint(t *testing.T) {
+
+	t.Parallel()
+
+	program := writeTestProgram(t, `
+      access(all) fun main() {
+          var i = 0
+          while i < 3 {
+              i = i + 1
+              let doubled = i * 2
+          }
+      }
+    `)
+
+	client := newTestClient(t)
+
+	var capabilities Capabilities
+	client.request("initialize", nil, &capabilities)
+	require.True(t, capabilities.SupportsConditionalBreakpoints)
+	require.True(t, capabilities.SupportsHitConditionalBreakpoints)
+	require.True(t, capabilities.SupportsLogPoints)
+
+	client.expectEvent("initialized")
+
+	var breakpoints struct {
+		Breakpoints []Breakpoint `json:"breakpoints"`
+	}
+	client.request(
+		"setBreakpoints",
+		SetBreakpointsArguments{
+			Source: Source{Path: program},
+			Breakpoints: []SourceBreakpoint{
+				{Line: 4, HitCondition: "many"},
+				{Line: 5, Condition: "i >= 1", HitCondition: ">= 2"},
+				{Line: 6, LogMessage: "doubled {i} is {i * 2}"},
+			},
+		},
+		&breakpoints,
+	)
+	require.Len(t, breakpoints.Breakpoints, 3)
+	require.False(t, breakpoints.Breakpoints[0].Verified)
+	require.NotEmpty(t, breakpoints.Breakpoints[0].Message)
+	require.True(t, breakpoints.Breakpoints[1].Verified)
+	require.True(t, breakpoints.Breakpoints[2].Verified)
+
+	client.request("launch", LaunchArguments{Program: program}, nil)
+	client.request("configurationDone", nil, nil)
+
+	client.expectEvent("stopped")
+
+	var stackTrace struct {
+		StackFrames []StackFrame `json:"stackFrames"`
+	}
+	client.request("stackTrace", StackTraceArguments{ThreadID: mainThreadID}, &stackTrace)
+	require.Equal(t, 5, stackTrace.StackFrames[0].Line)
+
+	var evaluate struct {
+		Result string `json:"result"`
+	}
+	client.request(
+		"evaluate",
+		EvaluateArguments{Expression: "i + 10", FrameID: stackTrace.StackFrames[0].ID},
+		&evaluate,
+	)
+	require.Equal(t, "12", evaluate.Result)
+
+	client.request("continue", map[string]any{"threadId": mainThreadID}, nil)
+
+	client.expectEvent("exited")
+	client.expectEvent("terminated")
+
+	var logMessages []string
+	for _, output := range client.outputs {
+		if output.Category == "console" && strings.HasPrefix(output.Output, "doubled") {
+			logMessages = append(logMessages, output.Output)
+		}
+	}
+	require.Equal(
+		t,
+		[]string{
+			"doubled 1 is 2\n",
+			"doubled 2 is 4\n",
+			"doubled 3 is 6\n",
+		},
+		logMessages,
+	)
+}
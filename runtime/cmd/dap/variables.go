@@ -0,0 +1,186 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dap
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+// variableHandles assigns DAP variable references to containers
+// (activations and values) which have child variables.
+//
+// Handles are only valid while the program is stopped,
+// and must be reset when it continues.
+type variableHandles struct {
+	children []func() []Variable
+}
+
+func (h *variableHandles) reset() {
+	h.children = nil
+}
+
+func (h *variableHandles) add(children func() []Variable) int {
+	h.children = append(h.children, children)
+	// References must be > 0, 0 indicates the variable has no children
+	return len(h.children)
+}
+
+func (h *variableHandles) get(reference int) func() []Variable {
+	index := reference - 1
+	if index < 0 || index >= len(h.children) {
+		return nil
+	}
+	return h.children[index]
+}
+
+func (h *variableHandles) activationVariables(
+	inter *interpreter.Interpreter,
+	locationRange interpreter.LocationRange,
+	activation *interpreter.VariableActivation,
+) []Variable {
+	if activation == nil {
+		return nil
+	}
+
+	functionValues := activation.FunctionValues()
+
+	names := make([]string, 0, len(functionValues))
+	for name := range functionValues { //nolint:maprange
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	variables := make([]Variable, 0, len(names))
+	for _, name := range names {
+		value := functionValues[name].GetValue(inter)
+		variables = append(
+			variables,
+			h.variable(inter, locationRange, name, value),
+		)
+	}
+
+	return variables
+}
+
+func (h *variableHandles) variable(
+	inter *interpreter.Interpreter,
+	locationRange interpreter.LocationRange,
+	name string,
+	value interpreter.Value,
+) Variable {
+	variable := Variable{
+		Name: name,
+	}
+
+	if value == nil {
+		variable.Value = "<uninitialized>"
+		return variable
+	}
+
+	variable.Type = value.StaticType(inter).String()
+
+	children := h.valueChildren(inter, locationRange, value)
+	if children != nil {
+		variable.Value = variable.Type
+		variable.VariablesReference = h.add(children)
+	} else {
+		variable.Value = value.String()
+	}
+
+	return variable
+}
+
+// valueChildren returns a function which produces the child variables of the given value,
+// or nil if the value has no children
+func (h *variableHandles) valueChildren(
+	inter *interpreter.Interpreter,
+	locationRange interpreter.LocationRange,
+	value interpreter.Value,
+) func() []Variable {
+
+	switch value := value.(type) {
+	case *interpreter.SomeValue:
+		return h.valueChildren(
+			inter,
+			locationRange,
+			value.InnerValue(inter, locationRange),
+		)
+
+	case *interpreter.EphemeralReferenceValue:
+		return h.valueChildren(inter, locationRange, value.Value)
+
+	case *interpreter.CompositeValue:
+		return func() []Variable {
+			var variables []Variable
+			value.ForEachField(
+				inter,
+				func(fieldName string, fieldValue interpreter.Value) (resume bool) {
+					variables = append(
+						variables,
+						h.variable(inter, locationRange, fieldName, fieldValue),
+					)
+					return true
+				},
+				locationRange,
+			)
+			return variables
+		}
+
+	case *interpreter.ArrayValue:
+		return func() []Variable {
+			variables := make([]Variable, 0, value.Count())
+			value.Iterate(
+				inter,
+				func(element interpreter.Value) (resume bool) {
+					name := strconv.Itoa(len(variables))
+					variables = append(
+						variables,
+						h.variable(inter, locationRange, name, element),
+					)
+					return true
+				},
+				false,
+				locationRange,
+			)
+			return variables
+		}
+
+	case *interpreter.DictionaryValue:
+		return func() []Variable {
+			variables := make([]Variable, 0, value.Count())
+			value.Iterate(
+				inter,
+				func(key, element interpreter.Value) (resume bool) {
+					variables = append(
+						variables,
+						h.variable(inter, locationRange, key.String(), element),
+					)
+					return true
+				},
+				locationRange,
+			)
+			return variables
+		}
+	}
+
+	return nil
+}
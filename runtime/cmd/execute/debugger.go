@@ -26,6 +26,7 @@ import (
 
 	"github.com/c-bata/go-prompt"
 
+	"github.com/onflow/cadence/runtime/ast"
 	"github.com/onflow/cadence/runtime/interpreter"
 )
 
@@ -35,6 +36,8 @@ const commandShortContinue = "c"
 const commandLongContinue = "continue"
 const commandShortNext = "n"
 const commandLongNext = "next"
+const commandLongStep = "step"
+const commandLongOut = "out"
 const commandLongExit = "exit"
 const commandShortShow = "s"
 const commandLongShow = "show"
@@ -43,8 +46,10 @@ const commandLongWhere = "where"
 
 var debuggerCommandSuggestions = []prompt.Suggest{
 	{Text: commandLongContinue, Description: "Continue"},
-	{Text: commandLongNext, Description: "Next / step"},
-	{Text: commandLongWhere, Description: "Location info"},
+	{Text: commandLongNext, Description: "Next / step over"},
+	{Text: commandLongStep, Description: "Step into"},
+	{Text: commandLongOut, Description: "Step out"},
+	{Text: commandLongWhere, Description: "Call stack"},
 	{Text: commandLongShow, Description: "Show variable(s)"},
 	{Text: commandLongExit, Description: "Exit"},
 	{Text: commandLongHelp, Description: "Help"},
@@ -67,7 +72,15 @@ func (d *InteractiveDebugger) Continue() {
 }
 
 func (d *InteractiveDebugger) Next() {
-	d.stop = d.debugger.Next()
+	d.stop = d.debugger.StepOver()
+}
+
+func (d *InteractiveDebugger) Step() {
+	d.stop = d.debugger.StepInto()
+}
+
+func (d *InteractiveDebugger) Out() {
+	d.stop = d.debugger.StepOut()
 }
 
 // Show shows the values for the variables with the given names.
@@ -123,6 +136,10 @@ func (d *InteractiveDebugger) Run() {
 			d.Continue()
 		case commandShortNext, commandLongNext:
 			d.Next()
+		case commandLongStep:
+			d.Step()
+		case commandLongOut:
+			d.Out()
 		case commandShortShow, commandLongShow:
 			d.Show(arguments)
 		case commandShortWhere, commandLongWhere:
@@ -176,10 +193,22 @@ func (d *InteractiveDebugger) Help() {
 	_ = w.Flush()
 }
 
+// Where prints the call stack, starting with the current function
 func (d *InteractiveDebugger) Where() {
-	fmt.Printf(
-		"%s @ %d\n",
-		d.stop.Interpreter.Location,
-		d.stop.Statement.StartPosition().Line,
-	)
+	callStack := d.stop.CallStack()
+	for i := len(callStack) - 1; i >= 0; i-- {
+		frame := callStack[i]
+
+		name := "<entry>"
+		if invocationExpression, ok := frame.Invocation.HasPosition.(*ast.InvocationExpression); ok {
+			name = invocationExpression.InvokedExpression.String()
+		}
+
+		fmt.Printf(
+			"%s @ %s:%d\n",
+			name,
+			frame.LocationRange.Location,
+			frame.LocationRange.StartPosition().Line,
+		)
+	}
 }
@@ -0,0 +1,252 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/pretty"
+)
+
+const fileExtension = ".cdc"
+
+const standardInputName = "<standard input>"
+
+type command struct {
+	stdout  io.Writer
+	stderr  io.Writer
+	write   bool
+	diff    bool
+	check   bool
+	failed  bool
+	changed bool
+}
+
+// Run runs the `fmt` command with the given arguments, and returns the exit code.
+//
+// Each argument is the path of a file or a directory.
+// Directories are walked recursively, and all Cadence files (.cdc) in them are formatted.
+// Without arguments, the program read from the standard input is formatted.
+//
+// By default, the formatted programs are printed.
+// With the -w flag, the files are overwritten with the formatted programs instead.
+// With the -d flag, the differences to the formatted programs are printed instead.
+// With the -check flag, the paths of the files which are not formatted are printed instead,
+// and the exit code is 1 if any file is not formatted.
+func Run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	write := flags.Bool("w", false, "write the formatted program to the file instead of printing it")
+	diff := flags.Bool("d", false, "print the differences to the formatted program instead of printing it")
+	check := flags.Bool("check", false, "print the paths of the files which are not formatted, and fail if there are any")
+
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "usage: cadence fmt [flags] [path ...]")
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	c := &command{
+		stdout: stdout,
+		stderr: stderr,
+		write:  *write,
+		diff:   *diff,
+		check:  *check,
+	}
+
+	if flags.NArg() == 0 {
+		if c.write {
+			_, _ = fmt.Fprintln(stderr, "error: cannot use -w with standard input")
+			return 2
+		}
+
+		code, err := io.ReadAll(stdin)
+		if err != nil {
+			c.reportError(err)
+		} else {
+			c.formatCode(standardInputName, code, nil)
+		}
+	}
+
+	for _, path := range flags.Args() {
+		c.formatPath(path)
+	}
+
+	if c.failed || (c.check && c.changed) {
+		return 1
+	}
+	return 0
+}
+
+func (c *command) formatPath(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		c.reportError(err)
+		return
+	}
+
+	if !info.IsDir() {
+		c.formatFile(path)
+		return
+	}
+
+	err = filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			c.reportError(err)
+			return nil
+		}
+
+		if entry.IsDir() || filepath.Ext(path) != fileExtension {
+			return nil
+		}
+
+		c.formatFile(path)
+		return nil
+	})
+	if err != nil {
+		c.reportError(err)
+	}
+}
+
+func (c *command) formatFile(path string) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		c.reportError(err)
+		return
+	}
+
+	c.formatCode(path, code, func(formatted []byte) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, formatted, info.Mode().Perm())
+	})
+}
+
+func (c *command) formatCode(path string, code []byte, write func([]byte) error) {
+	formatted, err := Format(code)
+	if err != nil {
+		location := common.StringLocation(path)
+
+		var builder strings.Builder
+		printErr := pretty.NewErrorPrettyPrinter(&builder, false).
+			PrettyPrintError(
+				err,
+				location,
+				map[common.Location][]byte{
+					location: code,
+				},
+			)
+		if printErr != nil {
+			c.reportError(err)
+			return
+		}
+
+		_, _ = io.WriteString(c.stderr, builder.String())
+		c.failed = true
+		return
+	}
+
+	changed := !bytes.Equal(code, formatted)
+	if changed {
+		c.changed = true
+	}
+
+	if c.check {
+		if changed {
+			_, _ = fmt.Fprintln(c.stdout, path)
+		}
+		return
+	}
+
+	if c.write && changed {
+		err = write(formatted)
+		if err != nil {
+			c.reportError(err)
+			return
+		}
+	}
+
+	if c.diff {
+		if changed {
+			err = writeDiff(c.stdout, path, code, formatted)
+			if err != nil {
+				c.reportError(err)
+			}
+		}
+		return
+	}
+
+	if !c.write {
+		_, err = c.stdout.Write(formatted)
+		if err != nil {
+			c.reportError(err)
+		}
+	}
+}
+
+func (c *command) reportError(err error) {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		_, _ = fmt.Fprintf(c.stderr, "error: %s: %s\n", pathErr.Path, pathErr.Err)
+	} else {
+		_, _ = fmt.Fprintf(c.stderr, "error: %s\n", err)
+	}
+	c.failed = true
+}
+
+// writeDiff writes the unified diff between the given program and the formatted program
+func writeDiff(writer io.Writer, path string, code []byte, formatted []byte) error {
+	return difflib.WriteUnifiedDiff(
+		writer,
+		difflib.UnifiedDiff{
+			A:        splitLines(code),
+			B:        splitLines(formatted),
+			FromFile: path + ".orig",
+			ToFile:   path,
+			Context:  3,
+		},
+	)
+}
+
+// splitLines splits the given text into lines, including the line breaks
+func splitLines(text []byte) []string {
+	lines := strings.SplitAfter(string(text), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,154 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const unformattedCode = "fun test() {  return  }\n"
+const formattedCode = "fun test() {\n    return\n}\n"
+
+func writeTestFiles(t *testing.T) (dir string, unformattedPath string, formattedPath string) {
+	dir = t.TempDir()
+
+	unformattedPath = filepath.Join(dir, "unformatted.cdc")
+	err := os.WriteFile(unformattedPath, []byte(unformattedCode), 0644)
+	require.NoError(t, err)
+
+	formattedPath = filepath.Join(dir, "nested", "formatted.cdc")
+	err = os.MkdirAll(filepath.Dir(formattedPath), 0755)
+	require.NoError(t, err)
+	err = os.WriteFile(formattedPath, []byte(formattedCode), 0644)
+	require.NoError(t, err)
+
+	// Files with other extensions are ignored when walking directories
+	err = os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Test"), 0644)
+	require.NoError(t, err)
+
+	return
+}
+
+func runCommand(args ...string) (exitCode int, stdout string, stderr string) {
+	var stdoutBuilder, stderrBuilder strings.Builder
+	exitCode = Run(args, strings.NewReader(unformattedCode), &stdoutBuilder, &stderrBuilder)
+	return exitCode, stdoutBuilder.String(), stderrBuilder.String()
+}
+
+func TestRun(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("standard input", func(t *testing.T) {
+
+		t.Parallel()
+
+		exitCode, stdout, stderr := runCommand()
+		require.Equal(t, 0, exitCode)
+		require.Equal(t, formattedCode, stdout)
+		require.Empty(t, stderr)
+	})
+
+	t.Run("check", func(t *testing.T) {
+
+		t.Parallel()
+
+		dir, unformattedPath, formattedPath := writeTestFiles(t)
+
+		exitCode, stdout, stderr := runCommand("-check", dir)
+		require.Equal(t, 1, exitCode)
+		require.Equal(t, unformattedPath+"\n", stdout)
+		require.Empty(t, stderr)
+
+		exitCode, stdout, stderr = runCommand("-check", formattedPath)
+		require.Equal(t, 0, exitCode)
+		require.Empty(t, stdout)
+		require.Empty(t, stderr)
+	})
+
+	t.Run("diff", func(t *testing.T) {
+
+		t.Parallel()
+
+		dir, unformattedPath, _ := writeTestFiles(t)
+
+		exitCode, stdout, stderr := runCommand("-d", dir)
+		require.Equal(t, 0, exitCode)
+		require.Equal(t,
+			"--- "+unformattedPath+".orig\n"+
+				"+++ "+unformattedPath+"\n"+
+				"@@ -1 +1,3 @@\n"+
+				"-fun test() {  return  }\n"+
+				"+fun test() {\n"+
+				"+    return\n"+
+				"+}\n",
+			stdout,
+		)
+		require.Empty(t, stderr)
+	})
+
+	t.Run("write", func(t *testing.T) {
+
+		t.Parallel()
+
+		dir, unformattedPath, formattedPath := writeTestFiles(t)
+
+		exitCode, stdout, stderr := runCommand("-w", dir)
+		require.Equal(t, 0, exitCode)
+		require.Empty(t, stdout)
+		require.Empty(t, stderr)
+
+		for _, path := range []string{unformattedPath, formattedPath} {
+			code, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, formattedCode, string(code))
+		}
+	})
+
+	t.Run("write standard input", func(t *testing.T) {
+
+		t.Parallel()
+
+		exitCode, stdout, stderr := runCommand("-w")
+		require.Equal(t, 2, exitCode)
+		require.Empty(t, stdout)
+		require.Equal(t, "error: cannot use -w with standard input\n", stderr)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+
+		t.Parallel()
+
+		dir := t.TempDir()
+		path := filepath.Join(dir, "invalid.cdc")
+		err := os.WriteFile(path, []byte("fun test() {"), 0644)
+		require.NoError(t, err)
+
+		exitCode, stdout, stderr := runCommand("-check", path)
+		require.Equal(t, 1, exitCode)
+		require.Empty(t, stdout)
+		require.Contains(t, stderr, "error: ")
+		require.Contains(t, stderr, path)
+	})
+}
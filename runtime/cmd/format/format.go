@@ -0,0 +1,580 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package format formats Cadence programs in the canonical style.
+//
+// The program is pretty-printed from its AST (see ast.Program.Doc).
+// The AST does not contain comments and blank lines,
+// so they are restored from the source code:
+// The tokens of the source code and the pretty-printed code are aligned,
+// and comments and blank lines are re-inserted before the aligned tokens.
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/turbolent/prettier"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+const (
+	MaxLineWidth = 80
+	Indentation  = "    "
+)
+
+// Format formats the given program.
+//
+// Comments are preserved, and so are single blank lines between declarations and statements.
+// Blank lines at the start and end of blocks are removed.
+func Format(code []byte) ([]byte, error) {
+	program, err := parser.ParseProgram(nil, code, parser.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	pretty := prettyPrint(program)
+
+	source := scan(code)
+	target := scan(pretty)
+
+	f := &formatter{
+		source:  source,
+		target:  target,
+		pretty:  pretty,
+		matches: align(source.tokens, target.tokens),
+	}
+	result := f.format()
+
+	// Ensure the result is still a valid program, and that it is equivalent to the given program,
+	// i.e. it is pretty-printed the same.
+	// This should never fail, but if it does, the source code must not be replaced
+
+	resultProgram, err := parser.ParseProgram(nil, result, parser.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to format program: result is invalid: %w", err)
+	}
+
+	if !bytes.Equal(prettyPrint(resultProgram), pretty) {
+		return nil, errors.New("failed to format program: result is not equivalent")
+	}
+
+	return result, nil
+}
+
+func prettyPrint(program *ast.Program) []byte {
+	var builder strings.Builder
+	prettier.Prettier(&builder, program.Doc(), MaxLineWidth, Indentation)
+	return []byte(builder.String())
+}
+
+type token struct {
+	// text is only set for identifiers (and keywords),
+	// the text of other tokens, like literals, is not significant for the alignment
+	text        string
+	startOffset int
+	endOffset   int
+	// newlines is the number of line breaks between the previous token or comment and this token
+	newlines int
+	lexer.TokenType
+}
+
+func (t token) equal(other token) bool {
+	return t.TokenType == other.TokenType &&
+		t.text == other.text
+}
+
+func (t token) Is(ty lexer.TokenType) bool {
+	return t.TokenType == ty
+}
+
+func (t token) isBinaryOperator() bool {
+	switch t.TokenType {
+	case lexer.TokenAmpersand,
+		lexer.TokenMinus:
+
+		return true
+	}
+	return false
+}
+
+func (t token) isOpening() bool {
+	switch t.TokenType {
+	case lexer.TokenParenOpen,
+		lexer.TokenBracketOpen:
+
+		return true
+	}
+	return false
+}
+
+func (t token) isClosing() bool {
+	switch t.TokenType {
+	case lexer.TokenBraceClose,
+		lexer.TokenParenClose,
+		lexer.TokenBracketClose:
+
+		return true
+	}
+	return false
+}
+
+type comment struct {
+	text string
+	// indentation is the indentation of the line the comment starts on,
+	// if the comment is the first element of the line
+	indentation string
+	// newlines is the number of line breaks between the previous token or comment and this comment
+	newlines int
+	// newlineAfter is true if the comment is followed by a line break
+	newlineAfter bool
+	isLine       bool
+}
+
+type scanResult struct {
+	// tokens are the tokens of the code, excluding spaces and comments,
+	// and including the final EOF token
+	tokens []token
+	// comments are the comments preceding each token
+	comments [][]comment
+}
+
+func scan(code []byte) scanResult {
+	tokenStream := lexer.Lex(code, nil)
+	defer tokenStream.Reclaim()
+
+	var result scanResult
+	var pendingComments []comment
+
+	previousEndOffset := 0
+	blockCommentNesting := 0
+	blockCommentStartOffset := 0
+
+	addComment := func(startOffset, endOffset int, isLine bool) {
+		text := string(bytes.TrimRight(code[startOffset:endOffset], " \t\r"))
+		newlines := bytes.Count(code[previousEndOffset:startOffset], []byte{'\n'})
+
+		var indentation string
+		if newlines > 0 || previousEndOffset == 0 {
+			lineStartOffset := bytes.LastIndexByte(code[:startOffset], '\n') + 1
+			indentation = string(code[lineStartOffset:startOffset])
+		}
+
+		if len(pendingComments) > 0 {
+			pendingComments[len(pendingComments)-1].newlineAfter = newlines > 0
+		}
+
+		pendingComments = append(
+			pendingComments,
+			comment{
+				text:        text,
+				indentation: indentation,
+				newlines:    newlines,
+				isLine:      isLine,
+			},
+		)
+		previousEndOffset = endOffset
+	}
+
+	for {
+		t := tokenStream.Next()
+
+		switch t.Type {
+		case lexer.TokenSpace, lexer.TokenBlockCommentContent:
+			continue
+
+		case lexer.TokenBlockCommentStart:
+			if blockCommentNesting == 0 {
+				blockCommentStartOffset = t.StartPos.Offset
+			}
+			blockCommentNesting++
+			continue
+
+		case lexer.TokenBlockCommentEnd:
+			blockCommentNesting--
+			if blockCommentNesting == 0 {
+				addComment(blockCommentStartOffset, t.EndPos.Offset+1, false)
+			}
+			continue
+
+		case lexer.TokenLineComment:
+			addComment(t.StartPos.Offset, t.EndPos.Offset+1, true)
+			continue
+		}
+
+		startOffset := t.StartPos.Offset
+		endOffset := t.EndPos.Offset + 1
+		if t.Type == lexer.TokenEOF {
+			startOffset = len(code)
+			endOffset = len(code)
+		}
+
+		newlines := bytes.Count(code[previousEndOffset:startOffset], []byte{'\n'})
+
+		if len(pendingComments) > 0 {
+			pendingComments[len(pendingComments)-1].newlineAfter = newlines > 0
+		}
+
+		var text string
+		if t.Type == lexer.TokenIdentifier {
+			text = string(code[startOffset:endOffset])
+		}
+
+		result.tokens = append(
+			result.tokens,
+			token{
+				TokenType:   t.Type,
+				text:        text,
+				startOffset: startOffset,
+				endOffset:   endOffset,
+				newlines:    newlines,
+			},
+		)
+		result.comments = append(result.comments, pendingComments)
+		pendingComments = nil
+
+		previousEndOffset = endOffset
+
+		if t.Type == lexer.TokenEOF {
+			return result
+		}
+	}
+}
+
+// maxEditDistance is the maximum number of inserted and deleted tokens
+// for which the source and target tokens are aligned
+const maxEditDistance = 2000
+
+// align aligns the source tokens and the target tokens.
+// The pretty-printed code may differ slightly from the source code,
+// for example, semicolons are dropped and parentheses are removed or added.
+//
+// The tokens are aligned using Myers' difference algorithm,
+// i.e. the longest common subsequence of the tokens is aligned.
+//
+// The result contains for each target token the index of the aligned source token,
+// or -1 if the target token is not aligned to any source token.
+func align(source, target []token) []int {
+	sourceCount := len(source)
+	targetCount := len(target)
+
+	matches := make([]int, targetCount)
+	for i := range matches {
+		matches[i] = -1
+	}
+
+	// v contains the furthest reaching source index for each diagonal k,
+	// offset by maxDistance, as k may be negative.
+	// trace contains the snapshots of v for each edit distance d,
+	// for the diagonals -d to d
+
+	maxDistance := sourceCount + targetCount
+	if maxDistance > maxEditDistance {
+		maxDistance = maxEditDistance
+	}
+
+	v := make([]int, 2*maxDistance+2)
+	var trace [][]int
+
+	distance := -1
+
+outer:
+	for d := 0; d <= maxDistance; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[maxDistance+k-1] < v[maxDistance+k+1]) {
+				x = v[maxDistance+k+1]
+			} else {
+				x = v[maxDistance+k-1] + 1
+			}
+			y := x - k
+
+			for x < sourceCount && y < targetCount && source[x].equal(target[y]) {
+				x++
+				y++
+			}
+
+			v[maxDistance+k] = x
+
+			if x >= sourceCount && y >= targetCount {
+				trace = append(trace, append([]int(nil), v[maxDistance-d:maxDistance+d+1]...))
+				distance = d
+				break outer
+			}
+		}
+
+		trace = append(trace, append([]int(nil), v[maxDistance-d:maxDistance+d+1]...))
+	}
+
+	if distance < 0 {
+		// The source and target differ too much, only align the EOF tokens
+		matches[targetCount-1] = sourceCount - 1
+		return matches
+	}
+
+	// Backtrack through the trace and align the tokens on the diagonals
+
+	x := sourceCount
+	y := targetCount
+
+	for d := distance; d > 0; d-- {
+		previous := trace[d-1]
+		previousAt := func(k int) int {
+			return previous[k+d-1]
+		}
+
+		k := x - y
+
+		var previousK int
+		if k == -d || (k != d && previousAt(k-1) < previousAt(k+1)) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+
+		previousX := previousAt(previousK)
+		previousY := previousX - previousK
+
+		startX := previousX
+		if previousK == k-1 {
+			startX++
+		}
+
+		for x > startX {
+			x--
+			y--
+			matches[y] = x
+		}
+
+		x = previousX
+		y = previousY
+	}
+
+	for x > 0 && y > 0 {
+		x--
+		y--
+		matches[y] = x
+	}
+
+	return matches
+}
+
+type formatter struct {
+	source  scanResult
+	target  scanResult
+	pretty  []byte
+	matches []int
+	output  bytes.Buffer
+}
+
+func (f *formatter) format() []byte {
+	previousSourceIndex := -1
+	previousEndOffset := 0
+
+	for targetIndex, targetToken := range f.target.tokens {
+		gap := f.pretty[previousEndOffset:targetToken.startOffset]
+
+		sourceIndex := f.matches[targetIndex]
+		if sourceIndex < 0 && targetToken.isOpening() {
+			// Parentheses and brackets may have been added by the pretty-printer,
+			// e.g. around a force-unwrapped expression.
+			// Restore the comments of the following aligned token before them
+			sourceIndex = f.nextMatch(targetIndex)
+		}
+
+		if sourceIndex < 0 {
+			f.output.Write(gap)
+		} else {
+			// Also restore the comments of source tokens which were not aligned, e.g. dropped semicolons
+			var comments []comment
+			droppedSemicolon := false
+			for index := previousSourceIndex + 1; index <= sourceIndex; index++ {
+				comments = append(comments, f.source.comments[index]...)
+				if index < sourceIndex && f.source.tokens[index].Is(lexer.TokenSemicolon) {
+					droppedSemicolon = true
+				}
+			}
+			previousSourceIndex = sourceIndex
+
+			// The pretty-printer drops semicolons between statements,
+			// but they are required if the next statement starts with a binary operator,
+			// e.g. a reference expression
+			if droppedSemicolon && targetToken.isBinaryOperator() {
+				f.output.WriteByte(';')
+			}
+
+			f.writeGap(gap, comments, f.source.tokens[sourceIndex], targetToken)
+		}
+
+		f.output.Write(f.pretty[targetToken.startOffset:targetToken.endOffset])
+		previousEndOffset = targetToken.endOffset
+	}
+
+	return trimTrailingWhitespace(f.output.Bytes())
+}
+
+// nextMatch returns the index of the source token aligned to the next aligned target token
+func (f *formatter) nextMatch(targetIndex int) int {
+	for _, sourceIndex := range f.matches[targetIndex+1:] {
+		if sourceIndex >= 0 {
+			return sourceIndex
+		}
+	}
+	return -1
+}
+
+// writeGap writes the space between the previous token and the given token,
+// and the comments preceding the token.
+func (f *formatter) writeGap(gap []byte, comments []comment, sourceToken token, targetToken token) {
+	isEOF := targetToken.Is(lexer.TokenEOF)
+
+	lineBreakIndex := bytes.LastIndexByte(gap, '\n')
+	hasLineBreak := lineBreakIndex >= 0 || f.output.Len() == 0
+
+	// The indentation of new lines:
+	// If the pretty-printed token starts a line, use its indentation.
+	// Otherwise, a line break is inserted because of a comment,
+	// so indent the continuation line
+
+	var indentation string
+	switch {
+	case isEOF:
+		indentation = ""
+	case hasLineBreak:
+		indentation = string(gap[lineBreakIndex+1:])
+	default:
+		indentation = f.currentLineIndentation() + Indentation
+	}
+
+	if len(comments) == 0 {
+		switch {
+		case isEOF:
+			// The final line break is added when trimming
+		case hasLineBreak:
+			f.writeLineBreaks(sourceToken.newlines, sourceToken)
+			f.output.WriteString(indentation)
+		default:
+			f.output.Write(gap)
+		}
+		return
+	}
+
+	for _, comment := range comments {
+		if comment.newlines > 0 || f.output.Len() == 0 {
+			f.writeLineBreaks(comment.newlines, token{})
+			f.output.WriteString(indentation)
+			f.output.WriteString(reindent(comment.text, comment.indentation, indentation))
+		} else {
+			if !f.followsOpening() {
+				f.output.WriteByte(' ')
+			}
+			f.output.WriteString(comment.text)
+		}
+	}
+
+	lastComment := comments[len(comments)-1]
+
+	switch {
+	case isEOF:
+		// The final line break is added when trimming
+	case hasLineBreak || lastComment.isLine || lastComment.newlineAfter:
+		f.writeLineBreaks(sourceToken.newlines, sourceToken)
+		f.output.WriteString(indentation)
+	default:
+		f.output.WriteByte(' ')
+	}
+}
+
+// writeLineBreaks writes a line break, or two, i.e. a blank line,
+// if there was a blank line in the source code.
+//
+// Blank lines are neither written at the start of the output,
+// nor at the start and end of blocks, lists, etc.
+func (f *formatter) writeLineBreaks(newlines int, next token) {
+	if f.output.Len() == 0 {
+		return
+	}
+
+	f.output.WriteByte('\n')
+
+	if newlines > 1 &&
+		!next.isClosing() &&
+		!f.followsOpening() {
+
+		f.output.WriteByte('\n')
+	}
+}
+
+// followsOpening returns true if the output ends with an opening brace, parenthesis, or bracket
+func (f *formatter) followsOpening() bool {
+	output := bytes.TrimRight(f.output.Bytes(), " \t\n")
+	if len(output) == 0 {
+		return false
+	}
+	switch output[len(output)-1] {
+	case '{', '(', '[':
+		return true
+	}
+	return false
+}
+
+func (f *formatter) currentLineIndentation() string {
+	output := f.output.Bytes()
+	lineStartOffset := bytes.LastIndexByte(output, '\n') + 1
+	line := output[lineStartOffset:]
+	return string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+}
+
+// reindent replaces the original indentation of the subsequent lines of a multi-line comment
+func reindent(text string, originalIndentation string, indentation string) string {
+	if originalIndentation == indentation ||
+		!strings.Contains(text, "\n") {
+
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(originalIndentation) == "" &&
+			strings.HasPrefix(line, originalIndentation) {
+
+			lines[i] = indentation + line[len(originalIndentation):]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trimTrailingWhitespace removes trailing whitespace from all lines,
+// and ensures the result ends with exactly one line break
+func trimTrailingWhitespace(output []byte) []byte {
+	lines := bytes.Split(output, []byte{'\n'})
+	for i, line := range lines {
+		lines[i] = bytes.TrimRight(line, " \t\r")
+	}
+	result := bytes.TrimRight(bytes.Join(lines, []byte{'\n'}), "\n")
+	if len(result) == 0 {
+		return result
+	}
+	return append(result, '\n')
+}
@@ -0,0 +1,196 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+func testFormat(t *testing.T, code string, expected string) {
+	formatted, err := Format([]byte(code))
+	require.NoError(t, err)
+	require.Equal(t, expected, string(formatted))
+
+	// Formatting is idempotent
+
+	formattedAgain, err := Format(formatted)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(formattedAgain))
+}
+
+func TestFormat(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("canonical", func(t *testing.T) {
+
+		t.Parallel()
+
+		testFormat(t,
+			`
+              access(all)   fun add(a:Int,b :Int): Int {return a+b}
+            `,
+			`access(all)
+fun add(a: Int, b: Int): Int {
+    return a + b
+}
+`,
+		)
+	})
+
+	t.Run("comments", func(t *testing.T) {
+
+		t.Parallel()
+
+		testFormat(t,
+			`// Header
+
+/// The contract
+access(all) contract Test {
+  // The counter
+  access(all) var count: Int   // trailing
+
+  /* block
+     comment */
+  init() {
+      self.count = 0;  /* inline */ self.count = 1
+  }
+}
+// Footer
+`,
+			`// Header
+
+/// The contract
+access(all)
+contract Test {
+    // The counter
+    access(all)
+    var count: Int // trailing
+
+    /* block
+       comment */
+    init() {
+        self.count = 0 /* inline */
+        self.count = 1
+    }
+}
+// Footer
+`,
+		)
+	})
+
+	t.Run("blank lines", func(t *testing.T) {
+
+		t.Parallel()
+
+		testFormat(t,
+			`
+fun test() {
+
+    let a = 1
+    let b = 2
+
+
+
+    let c = 3
+
+}
+`,
+			`fun test() {
+    let a = 1
+    let b = 2
+
+    let c = 3
+}
+`,
+		)
+	})
+
+	t.Run("added parentheses", func(t *testing.T) {
+
+		t.Parallel()
+
+		testFormat(t,
+			`
+fun test(a: Int, b: Int): Int {
+    return a * // first
+      (b + 1)
+}
+`,
+			`fun test(a: Int, b: Int): Int {
+    return a * // first
+        (b + 1)
+}
+`,
+		)
+	})
+
+	t.Run("required semicolon", func(t *testing.T) {
+
+		t.Parallel()
+
+		testFormat(t,
+			`
+fun test() {
+    let numbers = [1, 2, 3]
+    let x = 1;
+    &numbers as &[Int]
+}
+`,
+			`fun test() {
+    let numbers = [1, 2, 3]
+    let x = 1;
+    &numbers as &[Int]
+}
+`,
+		)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+
+		t.Parallel()
+
+		testFormat(t, "\n\n", "")
+	})
+
+	t.Run("only comments", func(t *testing.T) {
+
+		t.Parallel()
+
+		testFormat(t,
+			"  // one\n\n\n  /* two */\n",
+			"// one\n\n/* two */\n",
+		)
+	})
+}
+
+func TestFormatInvalid(t *testing.T) {
+
+	t.Parallel()
+
+	_, err := Format([]byte("fun test() {"))
+	require.Error(t, err)
+
+	var parserError parser.Error
+	require.ErrorAs(t, err, &parserError)
+}
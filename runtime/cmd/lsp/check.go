@@ -0,0 +1,289 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/errors"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/stdlib"
+)
+
+var baseValueActivation = func() *sema.VariableActivation {
+	activation := sema.NewVariableActivation(sema.BaseValueActivation)
+	for _, value := range stdlib.DefaultScriptStandardLibraryValues(nil) {
+		activation.DeclareValue(value)
+	}
+	return activation
+}()
+
+// checkResult is the result of parsing and checking a program
+type checkResult struct {
+	// checker is the checker of the program.
+	// It is nil if the program could not be parsed
+	checker *sema.Checker
+	// imports are the programs imported directly or indirectly by the program
+	imports map[common.Location]*importedProgram
+	// err is the parsing or checking error, if any
+	err error
+}
+
+type importedProgram struct {
+	checker *sema.Checker
+	err     error
+}
+
+// checkers returns the checkers of the program and of all successfully parsed imported programs
+func (r *checkResult) checkers() []*sema.Checker {
+	var checkers []*sema.Checker
+	if r.checker != nil {
+		checkers = append(checkers, r.checker)
+	}
+	for _, imported := range r.imports { //nolint:maprange
+		if imported.checker != nil {
+			checkers = append(checkers, imported.checker)
+		}
+	}
+	return checkers
+}
+
+// check parses and checks the given code, with position information enabled.
+//
+// Files imported by the program are resolved relative to the importing program,
+// and are read from the open documents, or from disk if they are not open.
+func (s *Server) check(location common.Location, code string) (result *checkResult) {
+	result = &checkResult{
+		imports: map[common.Location]*importedProgram{},
+	}
+
+	// The checker is not expected to panic,
+	// but the server must not crash if it does for some invalid program
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result.err = fmt.Errorf("internal error: %v", recovered)
+		}
+	}()
+
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		result.err = err
+		return
+	}
+
+	config := &sema.Config{
+		BaseValueActivationHandler: func(_ common.Location) *sema.VariableActivation {
+			return baseValueActivation
+		},
+		AccessCheckMode:     sema.AccessCheckModeStrict,
+		PositionInfoEnabled: true,
+		SuggestionsEnabled:  true,
+		AttachmentsEnabled:  true,
+		ImportHandler: func(
+			checker *sema.Checker,
+			importedLocation common.Location,
+			_ ast.Range,
+		) (sema.Import, error) {
+
+			if importedLocation == stdlib.CryptoCheckerLocation {
+				return sema.ElaborationImport{
+					Elaboration: stdlib.CryptoChecker().Elaboration,
+				}, nil
+			}
+
+			stringLocation, ok := importedLocation.(common.StringLocation)
+			if !ok {
+				return nil, fmt.Errorf(
+					"cannot import `%s`: only files are supported",
+					importedLocation,
+				)
+			}
+
+			resolvedLocation := resolveImport(checker.Location, stringLocation)
+
+			imported, ok := result.imports[resolvedLocation]
+			if !ok {
+				// Record the imported program before checking it,
+				// so cyclic imports are detected
+				imported = &importedProgram{}
+				result.imports[resolvedLocation] = imported
+				s.checkImport(checker, resolvedLocation, imported)
+			}
+
+			if imported.err != nil {
+				return nil, imported.err
+			}
+
+			return sema.ElaborationImport{
+				Elaboration: imported.checker.Elaboration,
+			}, nil
+		},
+	}
+
+	checker, err := sema.NewChecker(program, location, nil, config)
+	if err != nil {
+		result.err = err
+		return
+	}
+	result.checker = checker
+
+	// Importing the program itself, directly or indirectly, is a cyclic import
+
+	result.imports[location] = &importedProgram{
+		checker: checker,
+	}
+
+	result.err = checker.Check()
+
+	delete(result.imports, location)
+
+	return
+}
+
+func (s *Server) checkImport(
+	importingChecker *sema.Checker,
+	location common.StringLocation,
+	imported *importedProgram,
+) {
+	code, err := s.readCode(location)
+	if err != nil {
+		imported.err = err
+		return
+	}
+
+	program, err := parser.ParseProgram(nil, code, parser.Config{})
+	if err != nil {
+		imported.err = err
+		return
+	}
+
+	imported.checker, err = importingChecker.SubChecker(program, location)
+	if err != nil {
+		imported.err = err
+		return
+	}
+
+	imported.err = imported.checker.Check()
+}
+
+// readCode returns the code of the program with the given location.
+// Open documents take precedence over the files on disk
+func (s *Server) readCode(location common.StringLocation) ([]byte, error) {
+	if document, ok := s.documents[location]; ok {
+		return []byte(document.text), nil
+	}
+	return os.ReadFile(string(location))
+}
+
+// resolveImport resolves the location of an imported file
+// relative to the location of the importing program
+func resolveImport(importingLocation common.Location, location common.StringLocation) common.StringLocation {
+	path := filepath.FromSlash(string(location))
+	if filepath.IsAbs(path) {
+		return common.StringLocation(filepath.Clean(path))
+	}
+
+	if importingLocation, ok := importingLocation.(common.StringLocation); ok {
+		importingPath := string(importingLocation)
+		if filepath.IsAbs(importingPath) {
+			path = filepath.Join(filepath.Dir(importingPath), path)
+		}
+	}
+
+	return common.StringLocation(filepath.Clean(path))
+}
+
+// diagnostics returns the diagnostics for the parsing and checking errors of the document
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	if d.result == nil || d.result.err == nil {
+		return diagnostics
+	}
+
+	var errs []error
+	switch err := d.result.err.(type) {
+	case parser.Error:
+		errs = err.Errors
+	case *sema.CheckerError:
+		errs = err.Errors
+	default:
+		errs = []error{err}
+	}
+
+	for _, err := range errs {
+		diagnostics = append(diagnostics, d.diagnostic(err))
+	}
+
+	return diagnostics
+}
+
+func (d *document) diagnostic(err error) Diagnostic {
+	message := err.Error()
+	if secondaryError, ok := err.(errors.SecondaryError); ok {
+		secondaryMessage := secondaryError.SecondaryError()
+		if secondaryMessage != "" {
+			message += "\n" + secondaryMessage
+		}
+	}
+
+	diagnostic := Diagnostic{
+		Severity: DiagnosticSeverityError,
+		Source:   "cadence",
+		Message:  message,
+	}
+
+	if positioned, ok := err.(ast.HasPosition); ok {
+		diagnostic.Range = d.rangeFromAST(
+			positioned.StartPosition(),
+			positioned.EndPosition(nil),
+		)
+	}
+
+	if errorNotes, ok := err.(errors.ErrorNotes); ok {
+		for _, errorNote := range errorNotes.ErrorNotes() {
+			positioned, ok := errorNote.(ast.HasPosition)
+			if !ok {
+				continue
+			}
+
+			diagnostic.RelatedInformation = append(
+				diagnostic.RelatedInformation,
+				DiagnosticRelatedInformation{
+					Location: Location{
+						URI: d.uri,
+						Range: d.rangeFromAST(
+							positioned.StartPosition(),
+							positioned.EndPosition(nil),
+						),
+					},
+					Message: errorNote.Message(),
+				},
+			)
+		}
+	}
+
+	return diagnostic
+}
@@ -0,0 +1,211 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// document is a text document opened in the client
+type document struct {
+	uri      string
+	location common.Location
+	version  int
+	text     string
+	// lineStarts are the byte offsets of the starts of the lines in the text
+	lineStarts []int
+	// result is the result of checking the current text
+	result *checkResult
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{
+		uri:      uri,
+		location: uriLocation(uri),
+		version:  version,
+	}
+	d.setText(text)
+	return d
+}
+
+func (d *document) setText(text string) {
+	d.text = text
+
+	d.lineStarts = d.lineStarts[:0]
+	d.lineStarts = append(d.lineStarts, 0)
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+}
+
+// line returns the text of the given zero-based line, without the line break
+func (d *document) line(line int) string {
+	if line < 0 || line >= len(d.lineStarts) {
+		return ""
+	}
+
+	start := d.lineStarts[line]
+	end := len(d.text)
+	if line+1 < len(d.lineStarts) {
+		end = d.lineStarts[line+1] - 1
+	}
+
+	return strings.TrimSuffix(d.text[start:end], "\r")
+}
+
+// offset returns the byte offset in the text of the given position.
+func (d *document) offset(position Position) int {
+	if position.Line < 0 {
+		return 0
+	}
+	if position.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+
+	line := d.line(position.Line)
+	return d.lineStarts[position.Line] +
+		byteOffset(line, runeColumn(line, position.Character))
+}
+
+// semaPosition converts the given protocol position to a checker position.
+// Checker positions have one-based lines and zero-based columns, measured in runes
+func (d *document) semaPosition(position Position) sema.Position {
+	return sema.Position{
+		Line:   position.Line + 1,
+		Column: runeColumn(d.line(position.Line), position.Character),
+	}
+}
+
+// position converts the given checker line and column to a protocol position.
+func (d *document) position(line int, column int) Position {
+	text := d.line(line - 1)
+
+	return Position{
+		Line:      line - 1,
+		Character: utf16Length(text[:byteOffset(text, column)]),
+	}
+}
+
+// rangeFromPositions converts the given inclusive checker positions
+// to a protocol range, which has an exclusive end
+func (d *document) rangeFromPositions(start, end sema.Position) Range {
+	endPosition := d.position(end.Line, end.Column)
+
+	text := d.line(end.Line - 1)
+	offset := byteOffset(text, end.Column)
+	if offset < len(text) {
+		r, _ := utf8.DecodeRuneInString(text[offset:])
+		endPosition.Character += utf16.RuneLen(r)
+	}
+
+	return Range{
+		Start: d.position(start.Line, start.Column),
+		End:   endPosition,
+	}
+}
+
+func (d *document) rangeFromAST(start, end ast.Position) Range {
+	return d.rangeFromPositions(
+		sema.ASTToSemaPosition(start),
+		sema.ASTToSemaPosition(end),
+	)
+}
+
+// textBetween returns the text between the given inclusive checker positions on the same line
+func (d *document) textBetween(start, end sema.Position) string {
+	if start.Line != end.Line {
+		return ""
+	}
+
+	text := d.line(start.Line - 1)
+	startOffset := byteOffset(text, start.Column)
+	endOffset := byteOffset(text, end.Column+1)
+	if startOffset > endOffset {
+		return ""
+	}
+	return text[startOffset:endOffset]
+}
+
+// runeColumn returns the rune offset in the given line
+// of the given character offset, measured in UTF-16 code units
+func runeColumn(line string, character int) int {
+	units := 0
+	column := 0
+	for _, r := range line {
+		if units >= character {
+			break
+		}
+		units += utf16.RuneLen(r)
+		column++
+	}
+	return column
+}
+
+// byteOffset returns the byte offset in the given line of the given rune offset
+func byteOffset(line string, column int) int {
+	if column <= 0 {
+		return 0
+	}
+
+	runes := 0
+	for offset := range line {
+		if runes == column {
+			return offset
+		}
+		runes++
+	}
+	return len(line)
+}
+
+// utf16Length returns the number of UTF-16 code units needed to encode the given text
+func utf16Length(text string) int {
+	length := 0
+	for _, r := range text {
+		length += utf16.RuneLen(r)
+	}
+	return length
+}
+
+// uriLocation returns the location of the document with the given URI.
+// Files are identified by their path, so they can be imported by other programs
+func uriLocation(uri string) common.Location {
+	parsed, err := url.Parse(uri)
+	if err == nil && parsed.Scheme == "file" {
+		return common.StringLocation(filepath.FromSlash(parsed.Path))
+	}
+	return common.StringLocation(uri)
+}
+
+// pathURI returns the URI of the file with the given path
+func pathURI(path string) string {
+	return (&url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(path),
+	}).String()
+}
@@ -0,0 +1,714 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lsp
+
+import (
+	goerrors "errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// completionPlaceholder is inserted at the position of a completion or signature help request
+// if there is no identifier at the position, e.g. after a `.` or `(`,
+// so the program can be parsed and checked
+const completionPlaceholder = "__completion"
+
+// occurrenceAt returns the given document and the occurrence at the given position.
+// The occurrence is nil if the document could not be parsed or there is no occurrence at the position
+func (s *Server) occurrenceAt(uri string, position Position) (*document, *sema.Occurrence, error) {
+	document, err := s.document(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if document.result == nil || document.result.checker == nil {
+		return document, nil, nil
+	}
+	checker := document.result.checker
+
+	occurrence := checker.PositionInfo.Occurrences.Find(document.semaPosition(position))
+	return document, occurrence, nil
+}
+
+func (s *Server) hover(params *TextDocumentPositionParams) (*Hover, error) {
+	document, occurrence, err := s.occurrenceAt(params.TextDocument.URI, params.Position)
+	if err != nil || occurrence == nil {
+		return nil, err
+	}
+
+	name := document.textBetween(occurrence.StartPos, occurrence.EndPos)
+
+	var signature, docString string
+
+	if origin := occurrence.Origin; origin != nil && origin.Type != nil {
+		signature = declarationSignature(origin.DeclarationKind, name, origin.Type)
+		docString = origin.DocString
+	} else {
+		// Members of built-in types have no origin,
+		// but they can be resolved from the accessed type
+
+		member := document.accessedMember(occurrence, name)
+		if member == nil {
+			return nil, nil
+		}
+		signature = declarationSignature(member.DeclarationKind, name, member.TypeAnnotation.Type)
+		docString = member.DocString
+	}
+
+	value := "```cadence\n" + signature + "\n```"
+	if docString != "" {
+		value += "\n\n" + formatDocString(docString)
+	}
+
+	occurrenceRange := document.rangeFromPositions(occurrence.StartPos, occurrence.EndPos)
+
+	return &Hover{
+		Contents: MarkupContent{
+			Kind:  markupKindMarkdown,
+			Value: value,
+		},
+		Range: &occurrenceRange,
+	}, nil
+}
+
+// accessedMember returns the member with the given name
+// of the type accessed at the given member occurrence
+func (d *document) accessedMember(occurrence *sema.Occurrence, name string) *sema.Member {
+	access := d.result.checker.PositionInfo.MemberAccesses.Find(occurrence.StartPos)
+	if access == nil {
+		return nil
+	}
+
+	resolver, ok := access.AccessedType.GetMembers()[name]
+	if !ok {
+		return nil
+	}
+
+	return resolver.Resolve(nil, name, ast.EmptyRange, func(error) {})
+}
+
+// declarationSignature returns the Cadence signature of the declaration
+// with the given kind, name, and type, e.g. `let x: Int` or `fun f(x: Int): Int`
+func declarationSignature(kind common.DeclarationKind, name string, ty sema.Type) string {
+	keywords := kind.Keywords()
+
+	if kind.IsTypeDeclaration() {
+		return keywords + " " + name
+	}
+
+	if functionType, ok := ty.(*sema.FunctionType); ok {
+		switch kind {
+		case common.DeclarationKindFunction,
+			common.DeclarationKindInitializer:
+
+			return functionType.NamedQualifiedString(name)
+		}
+	}
+
+	signature := name + ": " + ty.QualifiedString()
+	if keywords != "" {
+		signature = keywords + " " + signature
+	}
+	return signature
+}
+
+// declaration is the position of a declaration in a program
+type declaration struct {
+	location common.Location
+	position sema.Position
+}
+
+// declaration returns the declaration of the given origin, which occurs with the given name.
+// It returns false if the origin is not declared in the program or in any imported program,
+// e.g. because it is a built-in declaration
+func (r *checkResult) declaration(origin *sema.Origin, name string) (declaration, bool) {
+	if origin == nil || origin.StartPos == nil {
+		return declaration{}, false
+	}
+
+	// Imported values and types are declared in the importing program without a position.
+	// Find their declaration in the imported programs instead
+
+	if origin.StartPos.Line == 0 {
+		return r.importedDeclaration(name)
+	}
+
+	position := sema.ASTToSemaPosition(*origin.StartPos)
+
+	// The origin is declared in the program which has an occurrence of the declaration itself.
+	// Several origins may share a declaration, e.g. a composite's type and its constructor
+
+	for _, checker := range r.checkers() {
+		occurrence := checker.PositionInfo.Occurrences.Find(position)
+		if occurrence == nil ||
+			occurrence.StartPos != position ||
+			occurrence.Origin == nil ||
+			occurrence.Origin.StartPos == nil ||
+			sema.ASTToSemaPosition(*occurrence.Origin.StartPos) != position {
+
+			continue
+		}
+
+		return declaration{
+			location: checker.Location,
+			position: position,
+		}, true
+	}
+
+	return declaration{}, false
+}
+
+// importedDeclaration returns the declaration of the global value or type
+// with the given name in the imported programs
+func (r *checkResult) importedDeclaration(name string) (declaration, bool) {
+	for _, checker := range r.checkers() {
+		if checker == r.checker {
+			continue
+		}
+
+		elaboration := checker.Elaboration
+
+		for _, get := range []func(string) (*sema.Variable, bool){
+			elaboration.GetGlobalValue,
+			elaboration.GetGlobalType,
+		} {
+			variable, ok := get(name)
+			if !ok || variable.Pos == nil || variable.Pos.Line == 0 {
+				continue
+			}
+
+			return declaration{
+				location: checker.Location,
+				position: sema.ASTToSemaPosition(*variable.Pos),
+			}, true
+		}
+	}
+
+	return declaration{}, false
+}
+
+// locationDocument returns the document with the given location.
+// If the document is not open, it is read from disk
+func (s *Server) locationDocument(location common.Location) *document {
+	if document, ok := s.documents[location]; ok {
+		return document
+	}
+
+	stringLocation, ok := location.(common.StringLocation)
+	if !ok {
+		return nil
+	}
+
+	code, err := s.readCode(stringLocation)
+	if err != nil {
+		return nil
+	}
+
+	return newDocument(pathURI(string(stringLocation)), 0, string(code))
+}
+
+func (s *Server) definition(params *TextDocumentPositionParams) (*Location, error) {
+	document, occurrence, err := s.occurrenceAt(params.TextDocument.URI, params.Position)
+	if err != nil || occurrence == nil {
+		return nil, err
+	}
+
+	name := document.textBetween(occurrence.StartPos, occurrence.EndPos)
+
+	declaration, ok := document.result.declaration(occurrence.Origin, name)
+	if !ok {
+		return nil, nil
+	}
+
+	declarationDocument := s.locationDocument(declaration.location)
+	if declarationDocument == nil {
+		return nil, nil
+	}
+
+	endPosition := declaration.position
+	endPosition.Column += len([]rune(name)) - 1
+
+	return &Location{
+		URI:   declarationDocument.uri,
+		Range: declarationDocument.rangeFromPositions(declaration.position, endPosition),
+	}, nil
+}
+
+// occurrence is an occurrence of a declaration in a program
+type occurrence struct {
+	location common.Location
+	start    sema.Position
+	end      sema.Position
+}
+
+// occurrences returns all occurrences of the given declaration
+// in the open documents and the programs they import
+func (s *Server) occurrences(target declaration) []occurrence {
+	var occurrences []occurrence
+	seen := map[occurrence]bool{}
+
+	for _, document := range s.documents { //nolint:maprange
+		result := document.result
+		if result == nil {
+			continue
+		}
+
+		for _, checker := range result.checkers() {
+			checkerDocument := s.locationDocument(checker.Location)
+			if checkerDocument == nil {
+				continue
+			}
+
+			for _, semaOccurrence := range checker.PositionInfo.Occurrences.All() {
+				origin := semaOccurrence.Origin
+				if origin == nil || origin.StartPos == nil {
+					continue
+				}
+
+				// Only imported values and types have origins without a position
+				if origin.StartPos.Line != 0 &&
+					sema.ASTToSemaPosition(*origin.StartPos) != target.position {
+
+					continue
+				}
+
+				name := checkerDocument.textBetween(semaOccurrence.StartPos, semaOccurrence.EndPos)
+
+				declaration, ok := result.declaration(origin, name)
+				if !ok || declaration != target {
+					continue
+				}
+
+				occurrence := occurrence{
+					location: checker.Location,
+					start:    semaOccurrence.StartPos,
+					end:      semaOccurrence.EndPos,
+				}
+				if seen[occurrence] {
+					continue
+				}
+				seen[occurrence] = true
+
+				occurrences = append(occurrences, occurrence)
+			}
+		}
+	}
+
+	sort.Slice(occurrences, func(i, j int) bool {
+		a := occurrences[i]
+		b := occurrences[j]
+		if a.location.ID() != b.location.ID() {
+			return a.location.ID() < b.location.ID()
+		}
+		return a.start.Compare(b.start) < 0
+	})
+
+	return occurrences
+}
+
+func (s *Server) references(params *ReferenceParams) ([]Location, error) {
+	document, occurrence, err := s.occurrenceAt(params.TextDocument.URI, params.Position)
+	if err != nil || occurrence == nil {
+		return nil, err
+	}
+
+	name := document.textBetween(occurrence.StartPos, occurrence.EndPos)
+
+	declaration, ok := document.result.declaration(occurrence.Origin, name)
+	if !ok {
+		return nil, nil
+	}
+
+	locations := []Location{}
+
+	for _, occurrence := range s.occurrences(declaration) {
+		if !params.Context.IncludeDeclaration &&
+			occurrence.location == declaration.location &&
+			occurrence.start == declaration.position {
+
+			continue
+		}
+
+		occurrenceDocument := s.locationDocument(occurrence.location)
+		if occurrenceDocument == nil {
+			continue
+		}
+
+		locations = append(locations, Location{
+			URI:   occurrenceDocument.uri,
+			Range: occurrenceDocument.rangeFromPositions(occurrence.start, occurrence.end),
+		})
+	}
+
+	return locations, nil
+}
+
+func (s *Server) rename(params *RenameParams) (*WorkspaceEdit, error) {
+	if !isIdentifier(params.NewName) {
+		return nil, fmt.Errorf("invalid name: %s", params.NewName)
+	}
+
+	document, occurrence, err := s.occurrenceAt(params.TextDocument.URI, params.Position)
+	if err != nil {
+		return nil, err
+	}
+	if occurrence == nil {
+		return nil, goerrors.New("no declaration to rename")
+	}
+
+	origin := occurrence.Origin
+	if origin != nil && origin.DeclarationKind == common.DeclarationKindSelf {
+		return nil, goerrors.New("cannot rename self")
+	}
+
+	name := document.textBetween(occurrence.StartPos, occurrence.EndPos)
+
+	declaration, ok := document.result.declaration(origin, name)
+	if !ok {
+		return nil, goerrors.New("cannot rename built-in declaration")
+	}
+
+	changes := map[string][]TextEdit{}
+
+	for _, occurrence := range s.occurrences(declaration) {
+		occurrenceDocument := s.locationDocument(occurrence.location)
+		if occurrenceDocument == nil {
+			continue
+		}
+
+		uri := occurrenceDocument.uri
+		changes[uri] = append(changes[uri], TextEdit{
+			Range:   occurrenceDocument.rangeFromPositions(occurrence.start, occurrence.end),
+			NewText: params.NewName,
+		})
+	}
+
+	return &WorkspaceEdit{
+		Changes: changes,
+	}, nil
+}
+
+// isIdentifier returns true if the given name can be used as the identifier of a declaration
+func isIdentifier(name string) bool {
+	program, err := parser.ParseProgram(nil, []byte("let "+name+" = 0"), parser.Config{})
+	if err != nil {
+		return false
+	}
+
+	declarations := program.VariableDeclarations()
+	return len(declarations) == 1 &&
+		declarations[0].Identifier.Identifier == name
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '_' ||
+		(b >= 'a' && b <= 'z') ||
+		(b >= 'A' && b <= 'Z') ||
+		(b >= '0' && b <= '9')
+}
+
+// checkAt checks the given document for a request at the given byte offset.
+// If insertPlaceholder is true, the completion placeholder is inserted at the offset
+func (s *Server) checkAt(document *document, offset int, insertPlaceholder bool) *checkResult {
+	if !insertPlaceholder {
+		return document.result
+	}
+
+	code := document.text[:offset] + completionPlaceholder + document.text[offset:]
+	return s.check(document.location, code)
+}
+
+func (s *Server) completion(params *TextDocumentPositionParams) (*CompletionList, error) {
+	document, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &CompletionList{
+		Items: []CompletionItem{},
+	}
+
+	offset := document.offset(params.Position)
+
+	prefixStart := offset
+	for prefixStart > 0 && isIdentifierByte(document.text[prefixStart-1]) {
+		prefixStart--
+	}
+
+	result := s.checkAt(document, offset, prefixStart == offset)
+	checker := result.checker
+	if checker == nil {
+		return list, nil
+	}
+
+	// Positions before the request position are not affected by the placeholder
+
+	prefixPosition := document.semaPosition(params.Position)
+	prefixPosition.Column -= offset - prefixStart
+
+	if prefixStart > 0 && document.text[prefixStart-1] == '.' {
+		accessPosition := prefixPosition
+		accessPosition.Column--
+
+		access := checker.PositionInfo.MemberAccesses.Find(accessPosition)
+		if access != nil {
+			list.Items = memberCompletionItems(access.AccessedType)
+		}
+
+		return list, nil
+	}
+
+	list.Items = valueCompletionItems(checker, prefixPosition)
+
+	return list, nil
+}
+
+func memberCompletionItems(ty sema.Type) []CompletionItem {
+	items := []CompletionItem{}
+
+	for name, resolver := range ty.GetMembers() { //nolint:maprange
+		member := resolver.Resolve(nil, name, ast.EmptyRange, func(error) {})
+		if member == nil {
+			continue
+		}
+
+		kind := CompletionItemKindField
+		if member.DeclarationKind == common.DeclarationKindFunction {
+			kind = CompletionItemKindMethod
+		}
+
+		items = append(items, CompletionItem{
+			Label:         name,
+			Kind:          kind,
+			Detail:        member.TypeAnnotation.QualifiedString(),
+			Documentation: documentation(member.DocString),
+		})
+	}
+
+	sortCompletionItems(items)
+
+	return items
+}
+
+// valueCompletionItems returns the completion items for the values and types
+// that are in scope at the given position
+func valueCompletionItems(checker *sema.Checker, position sema.Position) []CompletionItem {
+	items := []CompletionItem{}
+	seen := map[string]bool{}
+
+	add := func(name string, kind common.DeclarationKind, ty sema.Type, docString string) {
+		if seen[name] || name == completionPlaceholder {
+			return
+		}
+		seen[name] = true
+
+		item := CompletionItem{
+			Label:         name,
+			Kind:          completionItemKind(kind),
+			Documentation: documentation(docString),
+		}
+		if ty != nil && !kind.IsTypeDeclaration() {
+			item.Detail = ty.QualifiedString()
+		}
+
+		items = append(items, item)
+	}
+
+	for _, ra := range checker.PositionInfo.Ranges.FindAll(position) {
+		add(ra.Identifier, ra.DeclarationKind, ra.Type, ra.DocString)
+	}
+
+	_ = checker.Config.BaseValueActivationHandler(nil).ForEach(
+		func(name string, variable *sema.Variable) error {
+			add(name, variable.DeclarationKind, variable.Type, variable.DocString)
+			return nil
+		},
+	)
+
+	_ = sema.BaseTypeActivation.ForEach(
+		func(name string, variable *sema.Variable) error {
+			add(name, common.DeclarationKindType, variable.Type, variable.DocString)
+			return nil
+		},
+	)
+
+	sortCompletionItems(items)
+
+	return items
+}
+
+func completionItemKind(kind common.DeclarationKind) CompletionItemKind {
+	switch kind {
+	case common.DeclarationKindFunction:
+		return CompletionItemKindFunction
+	case common.DeclarationKindConstant:
+		return CompletionItemKindConstant
+	case common.DeclarationKindField:
+		return CompletionItemKindField
+	case common.DeclarationKindStructure,
+		common.DeclarationKindResource,
+		common.DeclarationKindAttachment:
+		return CompletionItemKindStruct
+	case common.DeclarationKindStructureInterface,
+		common.DeclarationKindResourceInterface,
+		common.DeclarationKindContractInterface:
+		return CompletionItemKindInterface
+	case common.DeclarationKindEnum:
+		return CompletionItemKindEnum
+	case common.DeclarationKindEnumCase:
+		return CompletionItemKindEnumCase
+	case common.DeclarationKindEvent:
+		return CompletionItemKindEvent
+	case common.DeclarationKindContract,
+		common.DeclarationKindType:
+		return CompletionItemKindClass
+	default:
+		return CompletionItemKindVariable
+	}
+}
+
+func sortCompletionItems(items []CompletionItem) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+}
+
+func documentation(docString string) *MarkupContent {
+	if docString == "" {
+		return nil
+	}
+	return &MarkupContent{
+		Kind:  markupKindMarkdown,
+		Value: formatDocString(docString),
+	}
+}
+
+// formatDocString removes the space after the doc comment markers, e.g. `/// `,
+// which is included in the doc string
+func formatDocString(docString string) string {
+	lines := strings.Split(docString, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func (s *Server) signatureHelp(params *TextDocumentPositionParams) (*SignatureHelp, error) {
+	document, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := document.offset(params.Position)
+
+	// Invocations without arguments are not recorded,
+	// and there is no argument yet after a trailing separator,
+	// so insert a placeholder argument
+
+	previous := strings.TrimRight(document.text[:offset], " \t\r\n")
+	insertPlaceholder := strings.HasSuffix(previous, "(") ||
+		strings.HasSuffix(previous, ",")
+
+	result := s.checkAt(document, offset, insertPlaceholder)
+	checker := result.checker
+	if checker == nil {
+		return nil, nil
+	}
+
+	position := document.semaPosition(params.Position)
+
+	// Find the innermost invocation, i.e. the one with the latest start
+
+	var invocation *sema.FunctionInvocation
+	for _, candidate := range checker.PositionInfo.FunctionInvocations.FindAll(position) {
+		candidate := candidate
+		if invocation == nil || candidate.StartPos.Compare(invocation.StartPos) > 0 {
+			invocation = &candidate
+		}
+	}
+	if invocation == nil {
+		return nil, nil
+	}
+
+	activeParameter := 0
+	for _, separatorPosition := range invocation.TrailingSeparatorPositions {
+		// Arguments without a trailing separator have an empty position
+		if separatorPosition.Line == 0 {
+			continue
+		}
+		if sema.ASTToSemaPosition(separatorPosition).Compare(position) < 0 {
+			activeParameter++
+		}
+	}
+
+	return &SignatureHelp{
+		Signatures: []SignatureInformation{
+			functionSignature(invocation.FunctionType),
+		},
+		ActiveParameter: activeParameter,
+	}, nil
+}
+
+// functionSignature returns the signature information for the given function type.
+// The parameter labels are the offsets of the parameters in the signature label
+func functionSignature(functionType *sema.FunctionType) SignatureInformation {
+	var label strings.Builder
+	parameters := make([]ParameterInformation, 0, len(functionType.Parameters))
+
+	purity := functionType.Purity.String()
+	if purity != "" {
+		label.WriteString(purity)
+		label.WriteByte(' ')
+	}
+
+	label.WriteString("fun(")
+
+	for i, parameter := range functionType.Parameters {
+		if i > 0 {
+			label.WriteString(", ")
+		}
+
+		start := utf16Length(label.String())
+		label.WriteString(parameter.QualifiedString())
+		end := utf16Length(label.String())
+
+		parameters = append(parameters, ParameterInformation{
+			Label: [2]int{start, end},
+		})
+	}
+
+	label.WriteByte(')')
+
+	returnType := functionType.ReturnTypeAnnotation.Type
+	if returnType != nil && returnType != sema.VoidType {
+		label.WriteString(": ")
+		label.WriteString(functionType.ReturnTypeAnnotation.QualifiedString())
+	}
+
+	return SignatureInformation{
+		Label:      label.String(),
+		Parameters: parameters,
+	}
+}
@@ -0,0 +1,400 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// This file implements the subset of the Language Server Protocol
+// (https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/)
+// that is needed to provide editor features based on the checker's position information.
+
+const jsonRPCVersion = "2.0"
+
+const contentLengthHeader = "Content-Length"
+
+// Error codes
+const (
+	CodeParseError           = -32700
+	CodeInvalidRequest       = -32600
+	CodeMethodNotFound       = -32601
+	CodeInvalidParams        = -32602
+	CodeInternalError        = -32603
+	CodeServerNotInitialized = -32002
+	CodeRequestFailed        = -32803
+)
+
+// Message is a JSON-RPC request or notification sent by the client.
+// Notifications have no ID.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification returns true if the message is a notification,
+// i.e. the client does not expect a response.
+func (m *Message) IsNotification() bool {
+	return len(m.ID) == 0
+}
+
+// IsResponse returns true if the message is a response to a server-to-client request.
+func (m *Message) IsResponse() bool {
+	return m.Method == ""
+}
+
+// Response is a successful response to a request.
+// The result is always sent, even if it is null.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+}
+
+// ErrorResponse is a failed response to a request.
+type ErrorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *ResponseError  `json:"error"`
+}
+
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+// Notification is a server-to-client notification.
+type Notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// Position is a zero-based line and character offset in a document.
+// The character offset is measured in UTF-16 code units.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type TextDocumentContentChangeEvent struct {
+	// Range is the range of the document that changed.
+	// If it is not set, the text is the full content of the document
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes"`
+}
+
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+const markupKindMarkdown = "markdown"
+
+// Server capabilities
+
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type TextDocumentSyncOptions struct {
+	OpenClose bool `json:"openClose"`
+	Change    int  `json:"change"`
+}
+
+const textDocumentSyncKindFull = 1
+
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+type SignatureHelpOptions struct {
+	TriggerCharacters   []string `json:"triggerCharacters,omitempty"`
+	RetriggerCharacters []string `json:"retriggerCharacters,omitempty"`
+}
+
+type ServerCapabilities struct {
+	TextDocumentSync      TextDocumentSyncOptions `json:"textDocumentSync"`
+	HoverProvider         bool                    `json:"hoverProvider"`
+	DefinitionProvider    bool                    `json:"definitionProvider"`
+	ReferencesProvider    bool                    `json:"referencesProvider"`
+	RenameProvider        bool                    `json:"renameProvider"`
+	CompletionProvider    *CompletionOptions      `json:"completionProvider,omitempty"`
+	SignatureHelpProvider *SignatureHelpOptions   `json:"signatureHelpProvider,omitempty"`
+}
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   ServerInfo         `json:"serverInfo"`
+}
+
+// Request parameters
+
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type ReferenceParams struct {
+	TextDocumentPositionParams
+	Context ReferenceContext `json:"context"`
+}
+
+type RenameParams struct {
+	TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+// Results and notification parameters
+
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+type DiagnosticSeverity int
+
+const (
+	DiagnosticSeverityError       DiagnosticSeverity = 1
+	DiagnosticSeverityWarning     DiagnosticSeverity = 2
+	DiagnosticSeverityInformation DiagnosticSeverity = 3
+	DiagnosticSeverityHint        DiagnosticSeverity = 4
+)
+
+type DiagnosticRelatedInformation struct {
+	Location Location `json:"location"`
+	Message  string   `json:"message"`
+}
+
+type Diagnostic struct {
+	Range              Range                          `json:"range"`
+	Severity           DiagnosticSeverity             `json:"severity"`
+	Source             string                         `json:"source"`
+	Message            string                         `json:"message"`
+	RelatedInformation []DiagnosticRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     *int         `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type CompletionItemKind int
+
+const (
+	CompletionItemKindMethod    CompletionItemKind = 2
+	CompletionItemKindFunction  CompletionItemKind = 3
+	CompletionItemKindField     CompletionItemKind = 5
+	CompletionItemKindVariable  CompletionItemKind = 6
+	CompletionItemKindClass     CompletionItemKind = 7
+	CompletionItemKindInterface CompletionItemKind = 8
+	CompletionItemKindEnum      CompletionItemKind = 13
+	CompletionItemKindEnumCase  CompletionItemKind = 20
+	CompletionItemKindConstant  CompletionItemKind = 21
+	CompletionItemKindStruct    CompletionItemKind = 22
+	CompletionItemKindEvent     CompletionItemKind = 23
+)
+
+type CompletionItem struct {
+	Label         string             `json:"label"`
+	Kind          CompletionItemKind `json:"kind,omitempty"`
+	Detail        string             `json:"detail,omitempty"`
+	Documentation *MarkupContent     `json:"documentation,omitempty"`
+}
+
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+type ParameterInformation struct {
+	// Label is the inclusive start and exclusive end offset
+	// of the parameter in the signature label
+	Label [2]int `json:"label"`
+}
+
+type SignatureInformation struct {
+	Label         string                 `json:"label"`
+	Documentation *MarkupContent         `json:"documentation,omitempty"`
+	Parameters    []ParameterInformation `json:"parameters"`
+}
+
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+// Conn reads messages from and writes responses and notifications to a client.
+// Writes are safe for concurrent use.
+type Conn struct {
+	reader  *bufio.Reader
+	writer  io.Writer
+	writeMu sync.Mutex
+}
+
+func NewConn(reader io.Reader, writer io.Writer) *Conn {
+	return &Conn{
+		reader: bufio.NewReader(reader),
+		writer: writer,
+	}
+}
+
+// ReadMessage reads the next message from the client.
+func (c *Conn) ReadMessage() (*Message, error) {
+	headers, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	contentLength, err := strconv.Atoi(strings.TrimSpace(headers.Get(contentLengthHeader)))
+	if err != nil || contentLength <= 0 {
+		return nil, fmt.Errorf("invalid %s header", contentLengthHeader)
+	}
+
+	content := make([]byte, contentLength)
+	_, err = io.ReadFull(c.reader, content)
+	if err != nil {
+		return nil, err
+	}
+
+	var message Message
+	err = json.Unmarshal(content, &message)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.JSONRPC != jsonRPCVersion {
+		return nil, fmt.Errorf("unsupported JSON-RPC version: %q", message.JSONRPC)
+	}
+
+	return &message, nil
+}
+
+func (c *Conn) write(message any) error {
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err = fmt.Fprintf(c.writer, "%s: %d\r\n\r\n", contentLengthHeader, len(content))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.writer.Write(content)
+	return err
+}
+
+// SendResponse sends a successful response for the given request.
+func (c *Conn) SendResponse(request *Message, result any) error {
+	return c.write(&Response{
+		JSONRPC: jsonRPCVersion,
+		ID:      request.ID,
+		Result:  result,
+	})
+}
+
+// SendErrorResponse sends a failed response for the given request.
+func (c *Conn) SendErrorResponse(request *Message, err *ResponseError) error {
+	return c.write(&ErrorResponse{
+		JSONRPC: jsonRPCVersion,
+		ID:      request.ID,
+		Error:   err,
+	})
+}
+
+// SendNotification sends a notification.
+func (c *Conn) SendNotification(method string, params any) error {
+	return c.write(&Notification{
+		JSONRPC: jsonRPCVersion,
+		Method:  method,
+		Params:  params,
+	})
+}
@@ -0,0 +1,355 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lsp
+
+import (
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"io"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime/common"
+)
+
+const serverName = "cadence-lsp"
+
+// ErrExitWithoutShutdown is returned by Server.Run
+// if the client sent the exit notification before the shutdown request.
+// In this case the server process should exit with a non-zero exit code
+var ErrExitWithoutShutdown = goerrors.New("exit notification received before shutdown request")
+
+// Server is a language server for a single client connection.
+//
+// All features are provided by the checker:
+// Documents are parsed and checked with position information enabled whenever they change,
+// and requests are answered from the resulting occurrences, member accesses, ranges,
+// and function invocations.
+//
+// Requests are handled sequentially, in the order they are received.
+type Server struct {
+	conn        *Conn
+	documents   map[common.Location]*document
+	initialized bool
+	shutdown    bool
+}
+
+func NewServer(reader io.Reader, writer io.Writer) *Server {
+	return &Server{
+		conn:      NewConn(reader, writer),
+		documents: map[common.Location]*document{},
+	}
+}
+
+// Run handles messages until the client sends the exit notification
+// or the connection is closed.
+func (s *Server) Run() error {
+	for {
+		message, err := s.conn.ReadMessage()
+		if err != nil {
+			if goerrors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		// The server does not send any requests to the client,
+		// so there are no responses to handle
+		if message.IsResponse() {
+			continue
+		}
+
+		if message.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+
+		if message.IsNotification() {
+			err = s.handleNotification(message)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, err := s.handleRequest(message)
+		if err != nil {
+			var responseError *ResponseError
+			if !goerrors.As(err, &responseError) {
+				responseError = &ResponseError{
+					Code:    CodeRequestFailed,
+					Message: err.Error(),
+				}
+			}
+			err = s.conn.SendErrorResponse(message, responseError)
+		} else {
+			err = s.conn.SendResponse(message, result)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handleRequest(request *Message) (any, error) {
+	if request.Method == "initialize" {
+		return s.onInitialize()
+	}
+
+	if !s.initialized {
+		return nil, &ResponseError{
+			Code:    CodeServerNotInitialized,
+			Message: "server is not initialized",
+		}
+	}
+
+	if s.shutdown {
+		return nil, &ResponseError{
+			Code:    CodeInvalidRequest,
+			Message: "server is shut down",
+		}
+	}
+
+	switch request.Method {
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		return handle(request, &params, s.hover)
+
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		return handle(request, &params, s.definition)
+
+	case "textDocument/references":
+		var params ReferenceParams
+		return handle(request, &params, s.references)
+
+	case "textDocument/rename":
+		var params RenameParams
+		return handle(request, &params, s.rename)
+
+	case "textDocument/signatureHelp":
+		var params TextDocumentPositionParams
+		return handle(request, &params, s.signatureHelp)
+
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		return handle(request, &params, s.completion)
+
+	default:
+		return nil, &ResponseError{
+			Code:    CodeMethodNotFound,
+			Message: fmt.Sprintf("unsupported request: %s", request.Method),
+		}
+	}
+}
+
+// handle unmarshals the parameters of the given request and passes them to the given handler
+func handle[P any, R any](request *Message, params *P, handler func(*P) (R, error)) (any, error) {
+	err := json.Unmarshal(request.Params, params)
+	if err != nil {
+		return nil, &ResponseError{
+			Code:    CodeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+	return handler(params)
+}
+
+func (s *Server) handleNotification(notification *Message) error {
+	// Notifications received before initialization are dropped
+	if !s.initialized {
+		return nil
+	}
+
+	switch notification.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		_, err := handle(notification, &params, s.didOpen)
+		return notificationError(err)
+
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		_, err := handle(notification, &params, s.didChange)
+		return notificationError(err)
+
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		_, err := handle(notification, &params, s.didClose)
+		return notificationError(err)
+
+	default:
+		// Other notifications, e.g. `initialized` and `$/cancelRequest`,
+		// require no action
+		return nil
+	}
+}
+
+// notificationError returns the given error if it is a connection error.
+// Notifications have no response, so invalid notifications are ignored
+func notificationError(err error) error {
+	var responseError *ResponseError
+	if goerrors.As(err, &responseError) {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) onInitialize() (any, error) {
+	if s.initialized {
+		return nil, &ResponseError{
+			Code:    CodeInvalidRequest,
+			Message: "server is already initialized",
+		}
+	}
+
+	s.initialized = true
+
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync: TextDocumentSyncOptions{
+				OpenClose: true,
+				Change:    textDocumentSyncKindFull,
+			},
+			HoverProvider:      true,
+			DefinitionProvider: true,
+			ReferencesProvider: true,
+			RenameProvider:     true,
+			CompletionProvider: &CompletionOptions{
+				TriggerCharacters: []string{"."},
+			},
+			SignatureHelpProvider: &SignatureHelpOptions{
+				TriggerCharacters:   []string{"("},
+				RetriggerCharacters: []string{","},
+			},
+		},
+		ServerInfo: ServerInfo{
+			Name:    serverName,
+			Version: cadence.Version,
+		},
+	}, nil
+}
+
+func (s *Server) didOpen(params *DidOpenTextDocumentParams) (any, error) {
+	item := params.TextDocument
+
+	document := newDocument(item.URI, item.Version, item.Text)
+	s.documents[document.location] = document
+
+	return nil, s.documentChanged(document)
+}
+
+func (s *Server) didChange(params *DidChangeTextDocumentParams) (any, error) {
+	document, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	// The server requests full document synchronization,
+	// but also apply incremental changes, in case the client sends them anyway
+	for _, change := range params.ContentChanges {
+		if change.Range != nil {
+			start := document.offset(change.Range.Start)
+			end := document.offset(change.Range.End)
+			document.setText(document.text[:start] + change.Text + document.text[end:])
+		} else {
+			document.setText(change.Text)
+		}
+	}
+	document.version = params.TextDocument.Version
+
+	return nil, s.documentChanged(document)
+}
+
+func (s *Server) didClose(params *DidCloseTextDocumentParams) (any, error) {
+	document, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(s.documents, document.location)
+
+	// Clear the diagnostics of the closed document
+
+	return nil, s.conn.SendNotification(
+		"textDocument/publishDiagnostics",
+		PublishDiagnosticsParams{
+			URI:         document.uri,
+			Diagnostics: []Diagnostic{},
+		},
+	)
+}
+
+// documentChanged checks the given changed document,
+// and all open documents which import it,
+// and publishes their diagnostics
+func (s *Server) documentChanged(changed *document) error {
+	err := s.checkDocument(changed)
+	if err != nil {
+		return err
+	}
+
+	for _, document := range s.documents { //nolint:maprange
+		if document == changed || document.result == nil {
+			continue
+		}
+
+		if _, ok := document.result.imports[changed.location]; !ok {
+			continue
+		}
+
+		err = s.checkDocument(document)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) checkDocument(document *document) error {
+	document.result = s.check(document.location, document.text)
+
+	version := document.version
+
+	return s.conn.SendNotification(
+		"textDocument/publishDiagnostics",
+		PublishDiagnosticsParams{
+			URI:         document.uri,
+			Version:     &version,
+			Diagnostics: document.diagnostics(),
+		},
+	)
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	document, ok := s.documents[uriLocation(uri)]
+	if !ok {
+		return nil, &ResponseError{
+			Code:    CodeInvalidParams,
+			Message: fmt.Sprintf("document is not open: %s", uri),
+		}
+	}
+	return document, nil
+}
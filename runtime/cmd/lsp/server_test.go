@@ -0,0 +1,699 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testClient struct {
+	t      *testing.T
+	writer io.Writer
+	reader *bufio.Reader
+	id     int
+	done   chan error
+	// diagnostics are the most recently published diagnostics, by document URI
+	diagnostics map[string][]Diagnostic
+}
+
+type testMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *ResponseError  `json:"error"`
+}
+
+func newTestClient(t *testing.T) *testClient {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	server := NewServer(serverReader, serverWriter)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- server.Run()
+		_ = serverWriter.Close()
+	}()
+
+	return &testClient{
+		t:           t,
+		writer:      clientWriter,
+		reader:      bufio.NewReader(clientReader),
+		done:        done,
+		diagnostics: map[string][]Diagnostic{},
+	}
+}
+
+func newInitializedTestClient(t *testing.T) *testClient {
+	client := newTestClient(t)
+	client.request("initialize", map[string]any{}, nil)
+	client.notify("initialized", map[string]any{})
+	return client
+}
+
+func (c *testClient) send(message map[string]any) {
+	message["jsonrpc"] = "2.0"
+
+	content, err := json.Marshal(message)
+	require.NoError(c.t, err)
+
+	_, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	require.NoError(c.t, err)
+}
+
+func (c *testClient) read() testMessage {
+	headers, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	require.NoError(c.t, err)
+
+	contentLength, err := strconv.Atoi(headers.Get("Content-Length"))
+	require.NoError(c.t, err)
+
+	content := make([]byte, contentLength)
+	_, err = io.ReadFull(c.reader, content)
+	require.NoError(c.t, err)
+
+	var message testMessage
+	err = json.Unmarshal(content, &message)
+	require.NoError(c.t, err)
+
+	if message.Method == "textDocument/publishDiagnostics" {
+		var params PublishDiagnosticsParams
+		err = json.Unmarshal(message.Params, &params)
+		require.NoError(c.t, err)
+		c.diagnostics[params.URI] = params.Diagnostics
+	}
+
+	return message
+}
+
+// request sends a request, and reads messages until the response is received.
+// The result of a successful response is unmarshalled into the given result
+func (c *testClient) request(method string, params any, result any) *ResponseError {
+	c.id++
+	id := c.id
+
+	c.send(map[string]any{
+		"id":     id,
+		"method": method,
+		"params": params,
+	})
+
+	for {
+		message := c.read()
+		if message.ID == nil || *message.ID != id {
+			continue
+		}
+
+		if message.Error != nil {
+			return message.Error
+		}
+
+		if result != nil {
+			err := json.Unmarshal(message.Result, result)
+			require.NoError(c.t, err)
+		}
+		return nil
+	}
+}
+
+func (c *testClient) notify(method string, params any) {
+	c.send(map[string]any{
+		"method": method,
+		"params": params,
+	})
+}
+
+// open opens a document and reads its diagnostics
+func (c *testClient) open(uri string, text string) []Diagnostic {
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{
+			URI:        uri,
+			LanguageID: "cadence",
+			Version:    1,
+			Text:       text,
+		},
+	})
+	return c.readDiagnostics(uri)
+}
+
+// change changes the full text of a document and reads its diagnostics
+func (c *testClient) change(uri string, version int, text string) []Diagnostic {
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument: VersionedTextDocumentIdentifier{
+			URI:     uri,
+			Version: version,
+		},
+		ContentChanges: []TextDocumentContentChangeEvent{
+			{Text: text},
+		},
+	})
+	return c.readDiagnostics(uri)
+}
+
+func (c *testClient) readDiagnostics(uri string) []Diagnostic {
+	for {
+		message := c.read()
+		if message.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+
+		var params PublishDiagnosticsParams
+		err := json.Unmarshal(message.Params, &params)
+		require.NoError(c.t, err)
+
+		if params.URI == uri {
+			return params.Diagnostics
+		}
+	}
+}
+
+func (c *testClient) shutdown() {
+	responseErr := c.request("shutdown", nil, nil)
+	require.Nil(c.t, responseErr)
+
+	c.notify("exit", nil)
+	require.NoError(c.t, <-c.done)
+}
+
+func positionParams(uri string, line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position: Position{
+			Line:      line,
+			Character: character,
+		},
+	}
+}
+
+func testRange(startLine, startCharacter, endLine, endCharacter int) Range {
+	return Range{
+		Start: Position{Line: startLine, Character: startCharacter},
+		End:   Position{Line: endLine, Character: endCharacter},
+	}
+}
+
+func TestServerLifecycle(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("initialize", func(t *testing.T) {
+
+		t.Parallel()
+
+		client := newTestClient(t)
+
+		// Requests before initialization fail
+
+		responseErr := client.request("textDocument/hover", positionParams("test.cdc", 0, 0), nil)
+		require.NotNil(t, responseErr)
+		require.Equal(t, CodeServerNotInitialized, responseErr.Code)
+
+		var result InitializeResult
+		responseErr = client.request("initialize", map[string]any{}, &result)
+		require.Nil(t, responseErr)
+
+		require.Equal(t, serverName, result.ServerInfo.Name)
+		require.Equal(t, textDocumentSyncKindFull, result.Capabilities.TextDocumentSync.Change)
+		require.True(t, result.Capabilities.HoverProvider)
+		require.True(t, result.Capabilities.DefinitionProvider)
+		require.True(t, result.Capabilities.ReferencesProvider)
+		require.True(t, result.Capabilities.RenameProvider)
+		require.NotNil(t, result.Capabilities.CompletionProvider)
+		require.NotNil(t, result.Capabilities.SignatureHelpProvider)
+
+		responseErr = client.request("unknown", nil, nil)
+		require.NotNil(t, responseErr)
+		require.Equal(t, CodeMethodNotFound, responseErr.Code)
+
+		client.shutdown()
+	})
+
+	t.Run("exit without shutdown", func(t *testing.T) {
+
+		t.Parallel()
+
+		client := newInitializedTestClient(t)
+
+		client.notify("exit", nil)
+		require.ErrorIs(t, <-client.done, ErrExitWithoutShutdown)
+	})
+}
+
+func TestServerDiagnostics(t *testing.T) {
+
+	t.Parallel()
+
+	const uri = "file:///test.cdc"
+
+	client := newInitializedTestClient(t)
+
+	// Parsing error
+
+	diagnostics := client.open(uri, "access(all) fun test() {\n    let x = \n}\n")
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, DiagnosticSeverityError, diagnostics[0].Severity)
+	require.Equal(t, "cadence", diagnostics[0].Source)
+	require.Contains(t, diagnostics[0].Message, "unexpected token")
+
+	// Checking error, with a multi-byte character before it.
+	// Positions are measured in UTF-16 code units
+
+	diagnostics = client.change(uri, 2, "access(all) fun test() {\n    let s = \"🙂\"; let x: Int = y\n}\n")
+	require.Len(t, diagnostics, 1)
+	require.Contains(t, diagnostics[0].Message, "cannot find variable in this scope: `y`")
+	require.Equal(t, testRange(1, 31, 1, 32), diagnostics[0].Range)
+
+	// Fixed
+
+	diagnostics = client.change(uri, 3, "access(all) fun test() {\n    let x = 1\n}\n")
+	require.Empty(t, diagnostics)
+
+	client.shutdown()
+}
+
+const testCode = `
+/// Adds two numbers
+access(all) fun add(_ a: Int, _ b: Int): Int {
+    return a + b
+}
+
+access(all) struct Counter {
+    /// The current count
+    access(all) var count: Int
+
+    init() {
+        self.count = 0
+    }
+
+    access(all) fun increment(by amount: Int) {
+        self.count = add(self.count, amount)
+    }
+}
+
+access(all) fun test() {
+    let counter = Counter()
+    counter.increment(by: 1)
+    let name = "counter"
+    log(name.length)
+}
+`
+
+func TestServerHover(t *testing.T) {
+
+	t.Parallel()
+
+	const uri = "file:///test.cdc"
+
+	client := newInitializedTestClient(t)
+	diagnostics := client.open(uri, testCode)
+	require.Empty(t, diagnostics)
+
+	t.Run("function", func(t *testing.T) {
+		var hover Hover
+		responseErr := client.request("textDocument/hover", positionParams(uri, 15, 21), &hover)
+		require.Nil(t, responseErr)
+		require.Equal(t,
+			Hover{
+				Contents: MarkupContent{
+					Kind:  markupKindMarkdown,
+					Value: "```cadence\nfun add(_ a: Int, _ b: Int): Int\n```\n\nAdds two numbers",
+				},
+				Range: &Range{
+					Start: Position{Line: 15, Character: 21},
+					End:   Position{Line: 15, Character: 24},
+				},
+			},
+			hover,
+		)
+	})
+
+	t.Run("field", func(t *testing.T) {
+		var hover Hover
+		responseErr := client.request("textDocument/hover", positionParams(uri, 15, 31), &hover)
+		require.Nil(t, responseErr)
+		require.Equal(t,
+			"```cadence\ncount: Int\n```\n\nThe current count",
+			hover.Contents.Value,
+		)
+	})
+
+	t.Run("local", func(t *testing.T) {
+		var hover Hover
+		responseErr := client.request("textDocument/hover", positionParams(uri, 21, 5), &hover)
+		require.Nil(t, responseErr)
+		require.Equal(t,
+			"```cadence\nlet counter: Counter\n```",
+			hover.Contents.Value,
+		)
+	})
+
+	t.Run("built-in member", func(t *testing.T) {
+		var hover Hover
+		responseErr := client.request("textDocument/hover", positionParams(uri, 23, 14), &hover)
+		require.Nil(t, responseErr)
+		require.Contains(t, hover.Contents.Value, "```cadence\nlength: Int\n```")
+	})
+
+	t.Run("no occurrence", func(t *testing.T) {
+		var hover *Hover
+		responseErr := client.request("textDocument/hover", positionParams(uri, 0, 0), &hover)
+		require.Nil(t, responseErr)
+		require.Nil(t, hover)
+	})
+}
+
+func TestServerDefinitionAndReferences(t *testing.T) {
+
+	t.Parallel()
+
+	const uri = "file:///test.cdc"
+
+	client := newInitializedTestClient(t)
+	diagnostics := client.open(uri, testCode)
+	require.Empty(t, diagnostics)
+
+	t.Run("definition", func(t *testing.T) {
+		var location Location
+		responseErr := client.request("textDocument/definition", positionParams(uri, 21, 14), &location)
+		require.Nil(t, responseErr)
+		require.Equal(t,
+			Location{
+				URI:   uri,
+				Range: testRange(14, 20, 14, 29),
+			},
+			location,
+		)
+	})
+
+	t.Run("references", func(t *testing.T) {
+		var locations []Location
+		responseErr := client.request(
+			"textDocument/references",
+			ReferenceParams{
+				TextDocumentPositionParams: positionParams(uri, 8, 21),
+				Context: ReferenceContext{
+					IncludeDeclaration: true,
+				},
+			},
+			&locations,
+		)
+		require.Nil(t, responseErr)
+		require.Equal(t,
+			[]Location{
+				{URI: uri, Range: testRange(8, 20, 8, 25)},
+				{URI: uri, Range: testRange(11, 13, 11, 18)},
+				{URI: uri, Range: testRange(15, 13, 15, 18)},
+				{URI: uri, Range: testRange(15, 30, 15, 35)},
+			},
+			locations,
+		)
+	})
+
+	t.Run("references without declaration", func(t *testing.T) {
+		var locations []Location
+		responseErr := client.request(
+			"textDocument/references",
+			ReferenceParams{
+				TextDocumentPositionParams: positionParams(uri, 20, 9),
+			},
+			&locations,
+		)
+		require.Nil(t, responseErr)
+		require.Equal(t,
+			[]Location{
+				{URI: uri, Range: testRange(21, 4, 21, 11)},
+			},
+			locations,
+		)
+	})
+
+	t.Run("built-in", func(t *testing.T) {
+		var location *Location
+		responseErr := client.request("textDocument/definition", positionParams(uri, 23, 5), &location)
+		require.Nil(t, responseErr)
+		require.Nil(t, location)
+	})
+}
+
+func TestServerRename(t *testing.T) {
+
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	libraryPath := filepath.Join(dir, "library.cdc")
+	err := os.WriteFile(
+		libraryPath,
+		[]byte("access(all) fun double(_ x: Int): Int {\n    return x * 2\n}\n"),
+		0644,
+	)
+	require.NoError(t, err)
+
+	libraryURI := pathURI(libraryPath)
+	mainURI := pathURI(filepath.Join(dir, "main.cdc"))
+
+	client := newInitializedTestClient(t)
+
+	// The imported library is read from disk
+
+	diagnostics := client.open(
+		mainURI,
+		"import \"library.cdc\"\n\naccess(all) fun test(): Int {\n    return double(double(1))\n}\n",
+	)
+	require.Empty(t, diagnostics)
+
+	var edit WorkspaceEdit
+	responseErr := client.request(
+		"textDocument/rename",
+		RenameParams{
+			TextDocumentPositionParams: positionParams(mainURI, 3, 12),
+			NewName:                    "twice",
+		},
+		&edit,
+	)
+	require.Nil(t, responseErr)
+	require.Equal(t,
+		WorkspaceEdit{
+			Changes: map[string][]TextEdit{
+				libraryURI: {
+					{Range: testRange(0, 16, 0, 22), NewText: "twice"},
+				},
+				mainURI: {
+					{Range: testRange(3, 11, 3, 17), NewText: "twice"},
+					{Range: testRange(3, 18, 3, 24), NewText: "twice"},
+				},
+			},
+		},
+		edit,
+	)
+
+	t.Run("invalid name", func(t *testing.T) {
+		responseErr := client.request(
+			"textDocument/rename",
+			RenameParams{
+				TextDocumentPositionParams: positionParams(mainURI, 3, 12),
+				NewName:                    "if",
+			},
+			nil,
+		)
+		require.NotNil(t, responseErr)
+		require.Equal(t, "invalid name: if", responseErr.Message)
+	})
+
+	t.Run("built-in", func(t *testing.T) {
+		diagnostics := client.change(mainURI, 2, "access(all) fun test() {\n    log(1)\n}\n")
+		require.Empty(t, diagnostics)
+
+		responseErr := client.request(
+			"textDocument/rename",
+			RenameParams{
+				TextDocumentPositionParams: positionParams(mainURI, 1, 5),
+				NewName:                    "print",
+			},
+			nil,
+		)
+		require.NotNil(t, responseErr)
+		require.Equal(t, "cannot rename built-in declaration", responseErr.Message)
+	})
+}
+
+func TestServerImportedDiagnostics(t *testing.T) {
+
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	libraryURI := pathURI(filepath.Join(dir, "library.cdc"))
+	mainURI := pathURI(filepath.Join(dir, "main.cdc"))
+
+	client := newInitializedTestClient(t)
+
+	diagnostics := client.open(libraryURI, "access(all) let answer = 42\n")
+	require.Empty(t, diagnostics)
+
+	diagnostics = client.open(mainURI, "import \"library.cdc\"\n\naccess(all) let x: Int = answer\n")
+	require.Empty(t, diagnostics)
+
+	// Changing the imported document re-checks the importing document
+
+	client.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument: VersionedTextDocumentIdentifier{
+			URI:     libraryURI,
+			Version: 2,
+		},
+		ContentChanges: []TextDocumentContentChangeEvent{
+			{Text: "access(all) let answer = \"42\"\n"},
+		},
+	})
+
+	diagnostics = client.readDiagnostics(mainURI)
+	require.Len(t, diagnostics, 1)
+	require.Contains(t, diagnostics[0].Message, "mismatched types")
+}
+
+func TestServerSignatureHelp(t *testing.T) {
+
+	t.Parallel()
+
+	const uri = "file:///test.cdc"
+
+	client := newInitializedTestClient(t)
+
+	code := "access(all) fun add(_ a: Int, _ b: Int): Int {\n    return a + b\n}\n\n" +
+		"access(all) fun test() {\n    add(1, )\n}\n"
+	client.open(uri, code)
+
+	var signatureHelp SignatureHelp
+	responseErr := client.request("textDocument/signatureHelp", positionParams(uri, 5, 11), &signatureHelp)
+	require.Nil(t, responseErr)
+	require.Equal(t,
+		SignatureHelp{
+			Signatures: []SignatureInformation{
+				{
+					Label: "fun(_ a: Int, _ b: Int): Int",
+					Parameters: []ParameterInformation{
+						{Label: [2]int{4, 12}},
+						{Label: [2]int{14, 22}},
+					},
+				},
+			},
+			ActiveParameter: 1,
+		},
+		signatureHelp,
+	)
+
+	// Nested invocations
+
+	code = "access(all) fun add(_ a: Int, _ b: Int): Int {\n    return a + b\n}\n\n" +
+		"access(all) fun test() {\n    add(1, add())\n}\n"
+	client.change(uri, 2, code)
+
+	responseErr = client.request("textDocument/signatureHelp", positionParams(uri, 5, 15), &signatureHelp)
+	require.Nil(t, responseErr)
+	require.Equal(t, 0, signatureHelp.ActiveParameter)
+}
+
+func TestServerCompletion(t *testing.T) {
+
+	t.Parallel()
+
+	const uri = "file:///test.cdc"
+
+	client := newInitializedTestClient(t)
+
+	labels := func(list CompletionList) []string {
+		labels := make([]string, len(list.Items))
+		for i, item := range list.Items {
+			labels[i] = item.Label
+		}
+		return labels
+	}
+
+	t.Run("members", func(t *testing.T) {
+
+		code := "access(all) struct Counter {\n" +
+			"    access(all) var count: Int\n" +
+			"    init() { self.count = 0 }\n" +
+			"    access(all) fun increment() {}\n" +
+			"}\n\n" +
+			"access(all) fun test() {\n" +
+			"    let counter = Counter()\n" +
+			"    counter.\n" +
+			"}\n"
+		client.open(uri, code)
+
+		var list CompletionList
+		responseErr := client.request("textDocument/completion", positionParams(uri, 8, 12), &list)
+		require.Nil(t, responseErr)
+
+		require.Subset(t, labels(list), []string{"count", "increment", "getType", "isInstance"})
+
+		for _, item := range list.Items {
+			switch item.Label {
+			case "count":
+				require.Equal(t, CompletionItemKindField, item.Kind)
+				require.Equal(t, "Int", item.Detail)
+			case "increment":
+				require.Equal(t, CompletionItemKindMethod, item.Kind)
+				require.Equal(t, "fun(): Void", item.Detail)
+			}
+		}
+
+		// With a prefix
+
+		client.change(uri, 2, strings.Replace(code, "counter.\n", "counter.inc\n", 1))
+
+		responseErr = client.request("textDocument/completion", positionParams(uri, 8, 15), &list)
+		require.Nil(t, responseErr)
+		require.Contains(t, labels(list), "increment")
+	})
+
+	t.Run("values", func(t *testing.T) {
+
+		code := "access(all) let answer = 42\n\n" +
+			"access(all) fun test(amount: Int) {\n" +
+			"    let local = 1\n" +
+			"    let x = \n" +
+			"}\n"
+		client.change(uri, 3, code)
+
+		var list CompletionList
+		responseErr := client.request("textDocument/completion", positionParams(uri, 4, 12), &list)
+		require.Nil(t, responseErr)
+
+		require.Subset(t,
+			labels(list),
+			[]string{"answer", "amount", "local", "test", "log", "panic", "Int", "String"},
+		)
+		require.NotContains(t, labels(list), completionPlaceholder)
+	})
+}
@@ -23,10 +23,15 @@ import (
 	"os/signal"
 
 	"github.com/onflow/cadence/runtime/cmd/execute"
+	"github.com/onflow/cadence/runtime/cmd/format"
 	"github.com/onflow/cadence/runtime/interpreter"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		os.Exit(format.Run(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	if len(os.Args) > 1 {
 		// TODO: also make the REPL support the interactive debugger
 
@@ -104,8 +104,11 @@ func (a *Address) bytes() []byte {
 }
 
 func (a Address) ShortHexWithPrefix() string {
-	hexString := fmt.Sprintf("%x", [AddressLength]byte(a))
-	return fmt.Sprintf("0x%s", strings.TrimLeft(hexString, "0"))
+	hexString := strings.TrimLeft(fmt.Sprintf("%x", [AddressLength]byte(a)), "0")
+	if hexString == "" {
+		hexString = "0"
+	}
+	return fmt.Sprintf("0x%s", hexString)
 }
 
 func (a Address) HexWithPrefix() string {
@@ -205,6 +205,11 @@ func TestAddress_ShortHexWithPrefix(t *testing.T) {
 		"0x1",
 		Address{0, 0, 0, 0, 0, 0, 0, 0x1}.ShortHexWithPrefix(),
 	)
+
+	assert.Equal(t,
+		"0x0",
+		Address{}.ShortHexWithPrefix(),
+	)
 }
 
 func TestAddress_HexWithPrefix(t *testing.T) {
@@ -35,6 +35,8 @@ type Config struct {
 	ResourceOwnerChangeHandlerEnabled bool
 	// CoverageReport enables and collects coverage reporting metrics
 	CoverageReport *CoverageReport
+	// Profiler enables profiling, and collects the metered computation and memory by call stack
+	Profiler *Profiler
 	// AttachmentsEnabled specifies if attachments are enabled
 	AttachmentsEnabled bool
 	// LegacyContractUpgradeEnabled enabled specifies whether to use the old parser when parsing an old contract
@@ -27,6 +27,11 @@ type Context struct {
 	Location       Location
 	Environment    Environment
 	CoverageReport *CoverageReport
+	// ReadOnly rejects all writes to the storage of accounts, including contract updates,
+	// with an interpreter.ReadOnlyStorageWriteError.
+	// This allows executing untrusted scripts, independent of whether the interface discards writes.
+	// Other effects, e.g. creating accounts, adding keys, or emitting events, are not rejected
+	ReadOnly bool
 }
 
 // CodesAndPrograms collects the source code and AST for each location.
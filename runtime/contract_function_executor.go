@@ -106,6 +106,7 @@ func (executor *interpreterContractFunctionExecutor) preprocess() (err error) {
 	runtimeInterface := context.Interface
 
 	storage := NewStorage(runtimeInterface, runtimeInterface)
+	storage.SetReadOnly(context.ReadOnly)
 	executor.storage = storage
 
 	environment := context.Environment
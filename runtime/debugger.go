@@ -0,0 +1,102 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/errors"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// ParseDebuggerExpression parses an expression which is evaluated by a debugger,
+// e.g. the condition of a breakpoint.
+func ParseDebuggerExpression(code string) (ast.Expression, error) {
+	expression, errs := parser.ParseExpression(nil, []byte(code), parser.Config{})
+	if len(errs) > 0 {
+		return nil, parser.Error{
+			Code:   []byte(code),
+			Errors: errs,
+		}
+	}
+	return expression, nil
+}
+
+// ParseBreakpoint parses the condition and the log message of a breakpoint.
+// Both are optional.
+//
+// Expressions in braces in the log message are interpolated, e.g. `amount: {amount}`.
+func ParseBreakpoint(condition string, hitCount uint, logMessage string) (interpreter.Breakpoint, error) {
+	breakpoint := interpreter.Breakpoint{
+		HitCount: hitCount,
+	}
+
+	if condition != "" {
+		expression, err := ParseDebuggerExpression(condition)
+		if err != nil {
+			return interpreter.Breakpoint{}, err
+		}
+		breakpoint.Condition = expression
+	}
+
+	if logMessage != "" {
+		parts, err := parseLogMessage(logMessage)
+		if err != nil {
+			return interpreter.Breakpoint{}, err
+		}
+		breakpoint.LogMessage = parts
+	}
+
+	return breakpoint, nil
+}
+
+func parseLogMessage(message string) ([]interpreter.LogMessagePart, error) {
+	var parts []interpreter.LogMessagePart
+
+	for len(message) > 0 {
+		start := strings.IndexByte(message, '{')
+		if start < 0 {
+			parts = append(parts, interpreter.LogMessagePart{Text: message})
+			break
+		}
+
+		if start > 0 {
+			parts = append(parts, interpreter.LogMessagePart{Text: message[:start]})
+		}
+
+		end := strings.IndexByte(message[start:], '}')
+		if end < 0 {
+			return nil, errors.NewDefaultUserError("missing closing brace in log message")
+		}
+		end += start
+
+		expression, err := ParseDebuggerExpression(message[start+1 : end])
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, interpreter.LogMessagePart{Expression: expression})
+
+		message = message[end+1:]
+	}
+
+	return parts, nil
+}
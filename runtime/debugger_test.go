@@ -24,10 +24,12 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/onflow/cadence"
 	. "github.com/onflow/cadence/runtime"
 	"github.com/onflow/cadence/runtime/ast"
 	"github.com/onflow/cadence/runtime/common"
 	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
 	. "github.com/onflow/cadence/runtime/tests/runtime_utils"
 )
 
@@ -206,3 +208,387 @@ func TestRuntimeDebuggerBreakpoints(t *testing.T) {
 
 	require.True(t, logged)
 }
+
+func TestRuntimeDebuggerStepping(t *testing.T) {
+
+	t.Parallel()
+
+	nextTransactionLocation := NewTransactionLocationGenerator()
+	location := nextTransactionLocation()
+
+	// Prepare the debugger
+
+	debugger := interpreter.NewDebugger()
+
+	// Add a breakpoint at the invocation of `double`
+	debugger.AddBreakpoint(location, 9)
+
+	// Run the transaction.
+	// It will pause/block at the breakpoint,
+	// so run it in a goroutine
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		config := DefaultTestInterpreterConfig
+		config.Debugger = debugger
+		runtime := NewTestInterpreterRuntimeWithConfig(config)
+
+		address := common.MustBytesToAddress([]byte{0x1})
+
+		runtimeInterface := &TestRuntimeInterface{
+			Storage: NewTestLedger(nil, nil),
+			OnGetSigningAccounts: func() ([]Address, error) {
+				return []Address{address}, nil
+			},
+			OnProgramLog: func(_ string) {},
+		}
+
+		err := runtime.ExecuteTransaction(
+			Script{
+				Source: []byte(`
+                  access(all) fun double(_ n: Int): Int {
+                      let doubled = n * 2
+                      return doubled
+                  }
+
+                  transaction {
+                      prepare(signer: &Account) {
+                          let a = double(1)
+                          let b = double(a)
+                          log(b)
+                      }
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  location,
+			},
+		)
+		require.NoError(t, err)
+	}()
+
+	requireStop := func(stop interpreter.Stop, line int, depth int) {
+		require.Equal(t, line, stop.Statement.StartPosition().Line)
+		require.Len(t, stop.CallStack(), depth)
+	}
+
+	// Wait for the transaction to run into the breakpoint
+	stop := <-debugger.Stops()
+	requireStop(stop, 9, 1)
+
+	// Step into `double`
+
+	stop = debugger.StepInto()
+	requireStop(stop, 3, 2)
+
+	callStack := stop.CallStack()
+
+	// The caller's frame is stopped at the invocation of `double`
+	require.Equal(t, 9, callStack[0].LocationRange.StartPosition().Line)
+	require.Equal(t, 9, callStack[1].Invocation.StartPosition().Line)
+	require.IsType(t, &interpreter.InterpretedFunctionValue{}, callStack[1].Function)
+
+	variable := callStack[1].Activation.Find("n")
+	require.NotNil(t, variable)
+	require.Equal(
+		t,
+		interpreter.NewUnmeteredIntValueFromInt64(1),
+		variable.GetValue(stop.Interpreter),
+	)
+
+	// Step out of `double`, back into the transaction's prepare function
+
+	stop = debugger.StepOut()
+	requireStop(stop, 10, 1)
+
+	// Step over the second invocation of `double`
+
+	stop = debugger.StepOver()
+	requireStop(stop, 11, 1)
+
+	variable = debugger.CurrentActivation(stop.Interpreter).Find("b")
+	require.NotNil(t, variable)
+	require.Equal(
+		t,
+		interpreter.NewUnmeteredIntValueFromInt64(4),
+		variable.GetValue(stop.Interpreter),
+	)
+
+	debugger.Continue()
+
+	// Wait for the transaction to finish execution
+	wg.Wait()
+}
+
+func TestRuntimeDebuggerConditionalBreakpoints(t *testing.T) {
+
+	t.Parallel()
+
+	nextScriptLocation := NewScriptLocationGenerator()
+	location := nextScriptLocation()
+
+	// Prepare the debugger
+
+	debugger := interpreter.NewDebugger()
+
+	// Stop in `withdraw` once the balance is insufficient for the second time
+	breakpoint, err := ParseBreakpoint("self.balance < amount", 2, "")
+	require.NoError(t, err)
+	debugger.AddConditionalBreakpoint(location, 6, breakpoint)
+
+	// Log the loop counter
+	logpoint, err := ParseBreakpoint("", 0, "i = {i}, {\"done\"}")
+	require.NoError(t, err)
+	debugger.AddConditionalBreakpoint(location, 16, logpoint)
+
+	var logMessages []string
+	debugger.SetLogpointHandler(func(message string, locationRange interpreter.LocationRange) {
+		require.Equal(t, location, locationRange.Location)
+		logMessages = append(logMessages, message)
+	})
+
+	// Invalid conditions and log messages are rejected
+
+	_, err = ParseBreakpoint("i ==", 0, "")
+	require.Error(t, err)
+
+	_, err = ParseBreakpoint("", 0, "i = {i")
+	require.Error(t, err)
+
+	// Run the script.
+	// It will pause/block at the breakpoint,
+	// so run it in a goroutine
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		config := DefaultTestInterpreterConfig
+		config.Debugger = debugger
+		runtime := NewTestInterpreterRuntimeWithConfig(config)
+
+		runtimeInterface := &TestRuntimeInterface{
+			Storage: NewTestLedger(nil, nil),
+		}
+
+		_, err := runtime.ExecuteScript(
+			Script{
+				Source: []byte(`
+                  access(all) struct Vault {
+                      access(all) var balance: Int
+                      init(balance: Int) { self.balance = balance }
+                      access(all) fun withdraw(amount: Int) {
+                          self.balance = self.balance - amount
+                      }
+                  }
+
+                  access(all) fun main() {
+                      let vault = Vault(balance: 10)
+                      var i = 0
+                      while i < 5 {
+                          vault.withdraw(amount: 3)
+                          // log the counter
+                          i = i + 1
+                      }
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  location,
+			},
+		)
+		require.NoError(t, err)
+	}()
+
+	// Wait for the script to run into the breakpoint
+	stop := <-debugger.Stops()
+	require.Equal(t, 6, stop.Statement.StartPosition().Line)
+	require.NoError(t, stop.BreakpointError)
+
+	callStack := stop.CallStack()
+	activation := callStack[len(callStack)-1].Activation
+
+	expression, err := ParseDebuggerExpression("self.balance")
+	require.NoError(t, err)
+
+	value, err := debugger.Evaluate(stop.Interpreter, activation, expression)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		interpreter.NewUnmeteredIntValueFromInt64(-2),
+		value,
+	)
+
+	// Evaluation errors are reported
+	expression, err = ParseDebuggerExpression("unknown")
+	require.NoError(t, err)
+
+	_, err = debugger.Evaluate(stop.Interpreter, activation, expression)
+	require.Error(t, err)
+
+	debugger.Continue()
+
+	// Wait for the script to finish execution
+	wg.Wait()
+
+	require.Equal(
+		t,
+		[]string{
+			"i = 0, done",
+			"i = 1, done",
+			"i = 2, done",
+			"i = 3, done",
+			"i = 4, done",
+		},
+		logMessages,
+	)
+}
+
+func TestRuntimeDebuggerConditionalBreakpointsViewOnly(t *testing.T) {
+
+	t.Parallel()
+
+	nextScriptLocation := NewScriptLocationGenerator()
+
+	script := []byte(`
+      access(all) var counter = 0
+
+      access(all) fun increment(): Bool {
+          counter = counter + 1
+          return true
+      }
+
+      access(all) view fun sum(_ n: Int): Int {
+          var sum = 0
+          var i = 0
+          while i < n {
+              sum = sum + i
+              i = i + 1
+          }
+          return sum
+      }
+
+      access(all) fun main(): Int {
+          var i = 0
+          while i < 3 {
+              i = i + 1
+          }
+          return counter
+      }
+    `)
+
+	type result struct {
+		value       cadence.Value
+		computation uint
+		memory      uint64
+	}
+
+	run := func(debugger *interpreter.Debugger) result {
+		location := nextScriptLocation()
+
+		if debugger != nil {
+			// Conditions must not have side effects
+			breakpoint, err := ParseBreakpoint("increment()", 0, "")
+			require.NoError(t, err)
+			debugger.AddConditionalBreakpoint(location, 24, breakpoint)
+
+			// Log messages are not metered
+			logpoint, err := ParseBreakpoint("", 0, "{sum(100)}")
+			require.NoError(t, err)
+			debugger.AddConditionalBreakpoint(location, 22, logpoint)
+		}
+
+		config := DefaultTestInterpreterConfig
+		config.Debugger = debugger
+		runtime := NewTestInterpreterRuntimeWithConfig(config)
+
+		var res result
+
+		runtimeInterface := &TestRuntimeInterface{
+			Storage: NewTestLedger(nil, nil),
+			OnMeterComputation: func(_ common.ComputationKind, intensity uint) error {
+				res.computation += intensity
+				return nil
+			},
+			OnMeterMemory: func(usage common.MemoryUsage) error {
+				res.memory += usage.Amount
+				return nil
+			},
+		}
+
+		value, err := runtime.ExecuteScript(
+			Script{
+				Source: script,
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  location,
+			},
+		)
+		require.NoError(t, err)
+
+		res.value = value
+		return res
+	}
+
+	unmetered := run(nil)
+
+	debugger := interpreter.NewDebugger()
+
+	var logMessages []string
+	debugger.SetLogpointHandler(func(message string, _ interpreter.LocationRange) {
+		logMessages = append(logMessages, message)
+	})
+
+	// Run the script.
+	// It will pause/block at the breakpoint,
+	// so run it in a goroutine
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	var debugged result
+
+	go func() {
+		defer wg.Done()
+		debugged = run(debugger)
+	}()
+
+	// The impure condition is rejected,
+	// so execution stops and reports the error
+
+	stop := <-debugger.Stops()
+	require.Equal(t, 24, stop.Statement.StartPosition().Line)
+
+	var checkerErr *sema.CheckerError
+	require.ErrorAs(t, stop.BreakpointError, &checkerErr)
+
+	errs := checkerErr.ChildErrors()
+	require.Len(t, errs, 1)
+	require.IsType(t, &sema.PurityError{}, errs[0])
+
+	debugger.Continue()
+
+	// Wait for the script to finish execution
+	wg.Wait()
+
+	require.Equal(
+		t,
+		[]string{"4950", "4950", "4950"},
+		logMessages,
+	)
+
+	// The condition had no effect,
+	// and the evaluation of the conditions and log messages was not metered
+
+	require.Equal(t, cadence.NewInt(0), debugged.value)
+	require.Equal(t, unmetered, debugged)
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/onflow/cadence"
 	"github.com/onflow/cadence/runtime/activations"
 
+	"github.com/onflow/atree"
 	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/onflow/cadence/runtime/ast"
@@ -42,6 +43,7 @@ type Environment interface {
 		typeID common.TypeID,
 		handler stdlib.CompositeValueFunctionsHandler,
 	)
+	SetOnStoredValueAccessHandler(handler interpreter.OnStoredValueAccessFunc)
 	DeclareValue(
 		valueDeclaration stdlib.StandardLibraryValue,
 		location common.Location,
@@ -84,6 +86,9 @@ type interpreterEnvironmentReconfigured struct {
 	storage          *Storage
 	coverageReport   *CoverageReport
 	codesAndPrograms CodesAndPrograms
+	// profiledInterpreter is an interpreter of the current execution,
+	// which provides the call stack for the profiler
+	profiledInterpreter *interpreter.Interpreter
 }
 
 type interpreterEnvironment struct {
@@ -236,6 +241,7 @@ func (e *interpreterEnvironment) Configure(
 	e.storage = storage
 	e.InterpreterConfig.Storage = storage
 	e.coverageReport = coverageReport
+	e.profiledInterpreter = nil
 	e.stackDepthLimiter.depth = 0
 }
 
@@ -305,10 +311,28 @@ func (e *interpreterEnvironment) SetCompositeValueFunctionsHandler(
 	e.compositeValueFunctionsHandlers[typeID] = handler
 }
 
+// SetOnStoredValueAccessHandler sets the function which is called
+// when a value stored in an account is read or written, or removes it if nil
+func (e *interpreterEnvironment) SetOnStoredValueAccessHandler(handler interpreter.OnStoredValueAccessFunc) {
+	e.InterpreterConfig.OnStoredValueAccess = handler
+}
+
 func (e *interpreterEnvironment) MeterMemory(usage common.MemoryUsage) error {
+	if profiler := e.config.Profiler; profiler != nil {
+		profiler.MeterMemory(e.profiledCallStack(), usage)
+	}
 	return e.runtimeInterface.MeterMemory(usage)
 }
 
+// profiledCallStack returns the call stack of the current execution.
+// The stack is empty before the first interpreter is created, e.g. while parsing and checking
+func (e *interpreterEnvironment) profiledCallStack() []interpreter.Invocation {
+	if e.profiledInterpreter == nil {
+		return nil
+	}
+	return e.profiledInterpreter.CallStack()
+}
+
 func (e *interpreterEnvironment) ProgramLog(message string, _ interpreter.LocationRange) error {
 	return e.runtimeInterface.ProgramLog(message)
 }
@@ -399,10 +423,18 @@ func (e *interpreterEnvironment) RevokeAccountKey(address common.Address, index
 }
 
 func (e *interpreterEnvironment) UpdateAccountContractCode(location common.AddressLocation, code []byte) error {
+	err := e.storage.checkWrite(atree.Address(location.Address))
+	if err != nil {
+		return err
+	}
 	return e.runtimeInterface.UpdateAccountContractCode(location, code)
 }
 
 func (e *interpreterEnvironment) RemoveAccountContractCode(location common.AddressLocation) error {
+	err := e.storage.checkWrite(atree.Address(location.Address))
+	if err != nil {
+		return err
+	}
 	return e.runtimeInterface.RemoveAccountContractCode(location)
 }
 
@@ -717,11 +749,20 @@ func (e *interpreterEnvironment) newInterpreter(
 		// Even though suboptimal, this ensures that no writes "leak" from one top-level entry call to another
 		// (when interpreter shared state is reused).
 
-		return interpreter.NewInterpreterWithSharedState(
+		inter, err := interpreter.NewInterpreterWithSharedState(
 			program,
 			location,
 			sharedState,
 		)
+		if err != nil {
+			return nil, err
+		}
+
+		if e.config.Profiler != nil {
+			e.profiledInterpreter = inter
+		}
+
+		return inter, nil
 	}
 
 	inter, err := interpreter.NewInterpreter(
@@ -735,6 +776,10 @@ func (e *interpreterEnvironment) newInterpreter(
 
 	e.runtimeInterface.SetInterpreterSharedState(inter.SharedState)
 
+	if e.config.Profiler != nil {
+		e.profiledInterpreter = inter
+	}
+
 	return inter, nil
 }
 
@@ -1069,6 +1114,10 @@ func (e *interpreterEnvironment) newOnInvokedFunctionReturnHandler() func(_ *int
 
 func (e *interpreterEnvironment) newOnMeterComputation() interpreter.OnMeterComputationFunc {
 	return func(compKind common.ComputationKind, intensity uint) {
+		if profiler := e.config.Profiler; profiler != nil {
+			profiler.MeterComputation(e.profiledCallStack(), compKind, intensity)
+		}
+
 		var err error
 		errors.WrapPanic(func() {
 			err = e.runtimeInterface.MeterComputation(compKind, intensity)
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/onflow/cadence"
@@ -32,6 +33,7 @@ import (
 	"github.com/onflow/cadence/runtime/sema"
 	"github.com/onflow/cadence/runtime/stdlib"
 	. "github.com/onflow/cadence/runtime/tests/runtime_utils"
+	. "github.com/onflow/cadence/runtime/tests/utils"
 )
 
 func TestRuntimeError(t *testing.T) {
@@ -222,23 +224,19 @@ func TestRuntimeError(t *testing.T) {
 
 		require.EqualError(t, err,
 			"Execution failed:\n"+
-				"  --> 0100000000000000000000000000000000000000000000000000000000000000:15:12\n"+
-				"   |\n"+
-				"15 | 				destroy createResource()\n"+
-				"   | 				        ^^^^^^^^^^^^^^^^\n"+
-				"\n"+
-				"  --> 0100000000000000000000000000000000000000000000000000000000000000:9:21\n"+
-				"   |\n"+
-				" 9 | 				return <- create Resource(\n"+
-				"10 | 					s: \"argument\"\n"+
-				"11 | 				)\n"+
-				"   | 				^^^^^^^^^^^^^^^^^^^^^^^^^^^\n"+
-				"\n"+
 				"error: panic: 42\n"+
 				" --> 0100000000000000000000000000000000000000000000000000000000000000:4:5\n"+
 				"  |\n"+
 				"4 | 					panic(\"42\")\n"+
-				"  | 					^^^^^^^^^^^\n",
+				"  | 					^^^^^^^^^^^\n"+
+				"\n"+
+				"stack trace:\n"+
+				"Resource(...)\n"+
+				"\t0100000000000000000000000000000000000000000000000000000000000000:4:5\n"+
+				"createResource(...)\n"+
+				"\t0100000000000000000000000000000000000000000000000000000000000000:9:21\n"+
+				"<unknown>\n"+
+				"\t0100000000000000000000000000000000000000000000000000000000000000:15:12\n",
 		)
 	})
 
@@ -378,17 +376,17 @@ func TestRuntimeError(t *testing.T) {
 			t,
 			err,
 			"Execution failed:\n"+
-				" --> 0100000000000000000000000000000000000000000000000000000000000000:5:16\n"+
-				"  |\n"+
-				"5 |                 add()\n"+
-				"  |                 ^^^^^\n"+
-				"\n"+
 				"error: overflow\n"+
 				" --> imported:6:16\n"+
 				"  |\n"+
 				"6 |                 a + b\n"+
 				"  |                 ^^^^^\n"+
-				"",
+				"\n"+
+				"stack trace:\n"+
+				"add(...)\n"+
+				"\timported:6:16\n"+
+				"<unknown>\n"+
+				"\t0100000000000000000000000000000000000000000000000000000000000000:5:16\n",
 		)
 	})
 
@@ -485,6 +483,101 @@ func TestRuntimeError(t *testing.T) {
 	})
 }
 
+func TestRuntimeErrorStackTrace(t *testing.T) {
+
+	t.Parallel()
+
+	runtime := NewTestInterpreterRuntime()
+
+	importedScript := []byte(`
+      access(all) fun withdraw(_ amount: Int) {
+          if amount > 10 {
+              panic("insufficient funds")
+          }
+      }
+
+      access(all) fun transfer(_ amount: Int) {
+          withdraw(amount)
+      }
+    `)
+
+	script := []byte(`
+      import transfer from "imported"
+
+      access(all) fun main() {
+          transfer(42)
+      }
+    `)
+
+	runtimeInterface := &TestRuntimeInterface{
+		OnGetCode: func(location Location) (bytes []byte, err error) {
+			switch location {
+			case common.StringLocation("imported"):
+				return importedScript, nil
+			default:
+				return nil, fmt.Errorf("unknown import location: %s", location)
+			}
+		},
+	}
+
+	location := common.ScriptLocation{0x1}
+
+	_, err := runtime.ExecuteScript(
+		Script{
+			Source: script,
+		},
+		Context{
+			Interface: runtimeInterface,
+			Location:  location,
+		},
+	)
+	RequireError(t, err)
+
+	var interpreterErr interpreter.Error
+	require.ErrorAs(t, err, &interpreterErr)
+
+	type frame struct {
+		function string
+		location common.Location
+		line     int
+	}
+
+	var frames []frame
+	for _, stackTraceFrame := range interpreterErr.StackTraceFrames() {
+		require.IsType(t, interpreter.StackTraceFrame{}, stackTraceFrame)
+		f := stackTraceFrame.(interpreter.StackTraceFrame)
+
+		frames = append(frames, frame{
+			function: f.Function,
+			location: f.Location,
+			line:     f.StartPosition().Line,
+		})
+	}
+
+	importedLocation := common.StringLocation("imported")
+
+	assert.Equal(t,
+		[]frame{
+			{
+				function: "withdraw",
+				location: importedLocation,
+				line:     4,
+			},
+			{
+				function: "transfer",
+				location: importedLocation,
+				line:     9,
+			},
+			{
+				function: "",
+				location: location,
+				line:     5,
+			},
+		},
+		frames,
+	)
+}
+
 func TestRuntimeMultipleInterfaceDefaultImplementationsError(t *testing.T) {
 	t.Parallel()
 
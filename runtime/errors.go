@@ -78,6 +78,24 @@ func (e CallStackLimitExceededError) Error() string {
 	)
 }
 
+// UnsupportedSimulationOperationError is reported when a simulated transaction
+// performs an operation which cannot be simulated, see SimulateTransaction
+
+type UnsupportedSimulationOperationError struct {
+	Operation string
+}
+
+var _ errors.UserError = &UnsupportedSimulationOperationError{}
+
+func (*UnsupportedSimulationOperationError) IsUserError() {}
+
+func (e *UnsupportedSimulationOperationError) Error() string {
+	return fmt.Sprintf(
+		"cannot simulate transaction: unsupported operation: %s",
+		e.Operation,
+	)
+}
+
 // InvalidTransactionCountError
 
 type InvalidTransactionCountError struct {
@@ -97,6 +97,20 @@ type ErrorNote interface {
 	Message() string
 }
 
+// HasStackTrace is an interface for errors that provide a stack trace
+type HasStackTrace interface {
+	// StackTraceFrames returns the frames of the stack trace,
+	// starting with the innermost frame
+	StackTraceFrames() []StackTraceFrame
+}
+
+// StackTraceFrame is a function on the stack of an error.
+// Frames may also provide the current position in the function
+type StackTraceFrame interface {
+	// FunctionName returns the name of the function, or an empty string if it is unknown
+	FunctionName() string
+}
+
 // ParentError is an error that contains one or more child errors.
 type ParentError interface {
 	error
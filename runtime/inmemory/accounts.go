@@ -0,0 +1,216 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+)
+
+type account struct {
+	keys      []runtime.AccountKey
+	contracts map[string][]byte
+	balance   uint64
+	lastID    uint64
+}
+
+func (i *Interface) account(address common.Address) *account {
+	a, ok := i.accounts[address]
+	if !ok {
+		a = &account{
+			contracts: map[string][]byte{},
+		}
+		i.accounts[address] = a
+	}
+	return a
+}
+
+// AccountExists returns true if the account with the given address was created,
+// either by CreateAccount, or by setting its balance, keys, or contracts.
+func (i *Interface) AccountExists(address common.Address) bool {
+	_, ok := i.accounts[address]
+	return ok
+}
+
+// CreateAccount creates a new account.
+// Addresses are assigned sequentially, skipping addresses of existing accounts.
+func (i *Interface) CreateAccount(_ runtime.Address) (runtime.Address, error) {
+	for {
+		if i.lastAddress == math.MaxUint64 {
+			return runtime.Address{}, fmt.Errorf("cannot create account: addresses exhausted")
+		}
+		i.lastAddress++
+
+		var address common.Address
+		binary.BigEndian.PutUint64(address[:], i.lastAddress)
+
+		if i.AccountExists(address) {
+			continue
+		}
+
+		i.account(address)
+		return address, nil
+	}
+}
+
+// SetAccountBalance sets the balance of the account with the given address.
+func (i *Interface) SetAccountBalance(address common.Address, balance uint64) {
+	i.account(address).balance = balance
+}
+
+func (i *Interface) GetAccountBalance(address common.Address) (uint64, error) {
+	a, ok := i.accounts[address]
+	if !ok {
+		return 0, nil
+	}
+	return a.balance, nil
+}
+
+// GetAccountAvailableBalance returns the balance of the account with the given address.
+// No balance is reserved for storage.
+func (i *Interface) GetAccountAvailableBalance(address common.Address) (uint64, error) {
+	return i.GetAccountBalance(address)
+}
+
+func (i *Interface) GetStorageUsed(address runtime.Address) (uint64, error) {
+	return i.ledger.StorageUsed(address[:]), nil
+}
+
+func (i *Interface) GetStorageCapacity(_ runtime.Address) (uint64, error) {
+	if i.config.StorageCapacity == 0 {
+		return math.MaxUint64, nil
+	}
+	return i.config.StorageCapacity, nil
+}
+
+func (i *Interface) GenerateAccountID(address common.Address) (uint64, error) {
+	a := i.account(address)
+	a.lastID++
+	return a.lastID, nil
+}
+
+// Keys
+
+func (i *Interface) AddAccountKey(
+	address runtime.Address,
+	publicKey *runtime.PublicKey,
+	hashAlgo runtime.HashAlgorithm,
+	weight int,
+) (*runtime.AccountKey, error) {
+	a := i.account(address)
+
+	key := runtime.AccountKey{
+		PublicKey: &runtime.PublicKey{
+			PublicKey: append([]byte(nil), publicKey.PublicKey...),
+			SignAlgo:  publicKey.SignAlgo,
+		},
+		KeyIndex: len(a.keys),
+		Weight:   weight,
+		HashAlgo: hashAlgo,
+	}
+	a.keys = append(a.keys, key)
+
+	return copyAccountKey(key), nil
+}
+
+func copyAccountKey(key runtime.AccountKey) *runtime.AccountKey {
+	publicKey := *key.PublicKey
+	publicKey.PublicKey = append([]byte(nil), publicKey.PublicKey...)
+	key.PublicKey = &publicKey
+	return &key
+}
+
+// GetAccountKey returns the key of the account with the given address at the given index,
+// or nil if there is no such key.
+func (i *Interface) GetAccountKey(address runtime.Address, index int) (*runtime.AccountKey, error) {
+	a, ok := i.accounts[address]
+	if !ok || index < 0 || index >= len(a.keys) {
+		return nil, nil
+	}
+	return copyAccountKey(a.keys[index]), nil
+}
+
+// AccountKeysCount returns the number of keys of the account with the given address,
+// including revoked keys.
+func (i *Interface) AccountKeysCount(address runtime.Address) (uint64, error) {
+	a, ok := i.accounts[address]
+	if !ok {
+		return 0, nil
+	}
+	return uint64(len(a.keys)), nil
+}
+
+// RevokeAccountKey revokes the key of the account with the given address at the given index,
+// and returns it, or nil if there is no such key.
+func (i *Interface) RevokeAccountKey(address runtime.Address, index int) (*runtime.AccountKey, error) {
+	a, ok := i.accounts[address]
+	if !ok || index < 0 || index >= len(a.keys) {
+		return nil, nil
+	}
+	a.keys[index].IsRevoked = true
+	return copyAccountKey(a.keys[index]), nil
+}
+
+// Contracts
+
+func (i *Interface) UpdateAccountContractCode(location common.AddressLocation, code []byte) error {
+	i.account(location.Address).contracts[location.Name] = append([]byte(nil), code...)
+	i.invalidatePrograms()
+	return nil
+}
+
+func (i *Interface) GetAccountContractCode(location common.AddressLocation) ([]byte, error) {
+	a, ok := i.accounts[location.Address]
+	if !ok {
+		return nil, nil
+	}
+	return a.contracts[location.Name], nil
+}
+
+func (i *Interface) RemoveAccountContractCode(location common.AddressLocation) error {
+	a, ok := i.accounts[location.Address]
+	if !ok {
+		return nil
+	}
+	delete(a.contracts, location.Name)
+	i.invalidatePrograms()
+	return nil
+}
+
+// GetAccountContractNames returns the names of the contracts of the account with the given address,
+// sorted by name.
+func (i *Interface) GetAccountContractNames(address runtime.Address) ([]string, error) {
+	a, ok := i.accounts[address]
+	if !ok {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(a.contracts))
+	for name := range a.contracts { //nolint:maprange
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
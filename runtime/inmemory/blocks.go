@@ -0,0 +1,80 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	"github.com/onflow/cadence/runtime"
+)
+
+// CommitBlock advances the block clock to the next block.
+func (i *Interface) CommitBlock() {
+	i.height++
+}
+
+func (i *Interface) GetCurrentBlockHeight() (uint64, error) {
+	return i.height, nil
+}
+
+// GetBlockAtHeight returns the block at the given height, if it is not higher than the current height.
+// The block's view is its height, its hash is derived from its height,
+// and its timestamp is the configured start time plus one block interval per block.
+func (i *Interface) GetBlockAtHeight(height uint64) (runtime.Block, bool, error) {
+	if height > i.height {
+		return runtime.Block{}, false, nil
+	}
+
+	var encodedHeight [8]byte
+	binary.BigEndian.PutUint64(encodedHeight[:], height)
+
+	timestamp := i.config.StartTime.Add(time.Duration(height) * i.config.BlockInterval)
+
+	return runtime.Block{
+		Height:    height,
+		View:      height,
+		Hash:      sha256.Sum256(encodedHeight[:]),
+		Timestamp: timestamp.UnixNano(),
+	}, true, nil
+}
+
+// ReadRandom fills the given buffer with deterministic random bytes:
+// SHA-256 is applied to the configured seed and a counter which is incremented for each 32 bytes.
+func (i *Interface) ReadRandom(buffer []byte) error {
+	for len(buffer) > 0 {
+		var encodedCounter [8]byte
+		binary.BigEndian.PutUint64(encodedCounter[:], i.randomCounter)
+		i.randomCounter++
+
+		hasher := sha256.New()
+		hasher.Write(i.config.RandomSeed)
+		hasher.Write(encodedCounter[:])
+
+		n := copy(buffer, hasher.Sum(nil))
+		buffer = buffer[n:]
+	}
+	return nil
+}
+
+func (i *Interface) GenerateUUID() (uint64, error) {
+	i.lastUUID++
+	return i.lastUUID, nil
+}
@@ -0,0 +1,174 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/onflow/cadence/runtime"
+)
+
+// ErrUnsupportedAlgorithm is returned for signature and hash algorithms
+// which are not implemented by the Go standard library,
+// i.e. ECDSA_secp256k1, BLS_BLS12_381, and KMAC128_BLS_BLS12_381.
+var ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+
+// DomainTagLength is the length to which non-empty domain separation tags are padded.
+const DomainTagLength = 32
+
+const ecdsaP256PublicKeyLength = 64
+const ecdsaP256SignatureLength = 64
+
+func newHasher(hashAlgorithm runtime.HashAlgorithm) (hash.Hash, error) {
+	switch hashAlgorithm {
+	case runtime.HashAlgorithmSHA2_256:
+		return sha256.New(), nil
+	case runtime.HashAlgorithmSHA2_384:
+		return sha512.New384(), nil
+	case runtime.HashAlgorithmSHA3_256:
+		return sha3.New256(), nil
+	case runtime.HashAlgorithmSHA3_384:
+		return sha3.New384(), nil
+	case runtime.HashAlgorithmKECCAK_256:
+		return sha3.NewLegacyKeccak256(), nil
+	default:
+		return nil, fmt.Errorf("%w: hash algorithm %s", ErrUnsupportedAlgorithm, hashAlgorithm)
+	}
+}
+
+// HashWithTag hashes the given data with the given hash algorithm.
+// If the tag is not empty, it is padded with zeros to DomainTagLength bytes,
+// and prefixed to the data.
+func HashWithTag(hashAlgorithm runtime.HashAlgorithm, tag string, data []byte) ([]byte, error) {
+	hasher, err := newHasher(hashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag != "" {
+		if len(tag) > DomainTagLength {
+			return nil, fmt.Errorf(
+				"domain tag must not be longer than %d bytes, got %d bytes",
+				DomainTagLength,
+				len(tag),
+			)
+		}
+
+		var paddedTag [DomainTagLength]byte
+		copy(paddedTag[:], tag)
+		hasher.Write(paddedTag[:])
+	}
+
+	hasher.Write(data)
+
+	return hasher.Sum(nil), nil
+}
+
+func decodeECDSAP256PublicKey(publicKey []byte) (*ecdsa.PublicKey, error) {
+	if len(publicKey) != ecdsaP256PublicKeyLength {
+		return nil, fmt.Errorf(
+			"invalid ECDSA_P256 public key: expected %d bytes, got %d bytes",
+			ecdsaP256PublicKeyLength,
+			len(publicKey),
+		)
+	}
+
+	// Validate the point using its uncompressed SEC 1 encoding
+	encoded := append([]byte{0x04}, publicKey...)
+	_, err := ecdh.P256().NewPublicKey(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ECDSA_P256 public key: %w", err)
+	}
+
+	half := ecdsaP256PublicKeyLength / 2
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(publicKey[:half]),
+		Y:     new(big.Int).SetBytes(publicKey[half:]),
+	}, nil
+}
+
+func (i *Interface) ValidatePublicKey(key *runtime.PublicKey) error {
+	switch key.SignAlgo {
+	case runtime.SignatureAlgorithmECDSA_P256:
+		_, err := decodeECDSAP256PublicKey(key.PublicKey)
+		return err
+	default:
+		return fmt.Errorf("%w: signature algorithm %s", ErrUnsupportedAlgorithm, key.SignAlgo)
+	}
+}
+
+func (i *Interface) VerifySignature(
+	signature []byte,
+	tag string,
+	signedData []byte,
+	publicKey []byte,
+	signatureAlgorithm runtime.SignatureAlgorithm,
+	hashAlgorithm runtime.HashAlgorithm,
+) (bool, error) {
+	if signatureAlgorithm != runtime.SignatureAlgorithmECDSA_P256 {
+		return false, fmt.Errorf("%w: signature algorithm %s", ErrUnsupportedAlgorithm, signatureAlgorithm)
+	}
+
+	digest, err := HashWithTag(hashAlgorithm, tag, signedData)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := decodeECDSAP256PublicKey(publicKey)
+	if err != nil {
+		return false, nil
+	}
+
+	if len(signature) != ecdsaP256SignatureLength {
+		return false, nil
+	}
+
+	half := ecdsaP256SignatureLength / 2
+	r := new(big.Int).SetBytes(signature[:half])
+	s := new(big.Int).SetBytes(signature[half:])
+
+	return ecdsa.Verify(key, digest, r, s), nil
+}
+
+func (i *Interface) Hash(data []byte, tag string, hashAlgorithm runtime.HashAlgorithm) ([]byte, error) {
+	return HashWithTag(hashAlgorithm, tag, data)
+}
+
+func (i *Interface) BLSVerifyPOP(_ *runtime.PublicKey, _ []byte) (bool, error) {
+	return false, fmt.Errorf("%w: BLS proof of possession", ErrUnsupportedAlgorithm)
+}
+
+func (i *Interface) BLSAggregateSignatures(_ [][]byte) ([]byte, error) {
+	return nil, fmt.Errorf("%w: BLS signature aggregation", ErrUnsupportedAlgorithm)
+}
+
+func (i *Interface) BLSAggregatePublicKeys(_ []*runtime.PublicKey) (*runtime.PublicKey, error) {
+	return nil, fmt.Errorf("%w: BLS public key aggregation", ErrUnsupportedAlgorithm)
+}
@@ -0,0 +1,310 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package inmemory provides an in-memory implementation of runtime.Interface,
+// which can be used to execute transactions and scripts without a Flow node or emulator,
+// e.g. in tools and tests.
+//
+// It provides accounts with keys and contracts, atree-backed storage, a block clock,
+// deterministic UUIDs and randomness, cryptography based on the Go standard library,
+// and records emitted events and logged messages.
+package inmemory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/onflow/atree"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/onflow/cadence"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+// Config is the configuration of an in-memory interface.
+type Config struct {
+	// StartTime is the timestamp of the block at height 0.
+	// Defaults to the Unix epoch
+	StartTime time.Time
+	// BlockInterval is the duration between the timestamps of consecutive blocks.
+	// Defaults to one second
+	BlockInterval time.Duration
+	// RandomSeed is the seed of the deterministic randomness source
+	RandomSeed []byte
+	// StorageCapacity is the storage capacity of each account, in bytes.
+	// Zero means unlimited
+	StorageCapacity uint64
+}
+
+// Interface is an in-memory implementation of runtime.Interface.
+//
+// All state is kept in memory, and the behavior is deterministic:
+// Executing the same transactions and scripts on a new interface with the same configuration
+// results in the same state, events, and results.
+//
+// An Interface is not safe for concurrent use.
+type Interface struct {
+	config          Config
+	ledger          *Ledger
+	accounts        map[common.Address]*account
+	codes           map[common.Location][]byte
+	programs        map[common.Location]*interpreter.Program
+	signers         []common.Address
+	events          []cadence.Event
+	logs            []string
+	lastAddress     uint64
+	lastUUID        uint64
+	height          uint64
+	randomCounter   uint64
+	computationUsed uint64
+	memoryUsed      uint64
+}
+
+var _ runtime.Interface = &Interface{}
+
+// NewInterface returns a new in-memory interface with the given configuration,
+// without any accounts, at block height 0.
+func NewInterface(config Config) *Interface {
+	if config.StartTime.IsZero() {
+		config.StartTime = time.Unix(0, 0)
+	}
+	if config.BlockInterval == 0 {
+		config.BlockInterval = time.Second
+	}
+
+	return &Interface{
+		config:   config,
+		ledger:   NewLedger(),
+		accounts: map[common.Address]*account{},
+		codes:    map[common.Location][]byte{},
+		programs: map[common.Location]*interpreter.Program{},
+	}
+}
+
+// Ledger returns the ledger which stores the values of all accounts.
+func (i *Interface) Ledger() *Ledger {
+	return i.ledger
+}
+
+// SetSigningAccounts sets the accounts which sign the transactions executed next.
+func (i *Interface) SetSigningAccounts(addresses ...common.Address) {
+	i.signers = append([]common.Address(nil), addresses...)
+}
+
+func (i *Interface) GetSigningAccounts() ([]runtime.Address, error) {
+	return append([]runtime.Address(nil), i.signers...), nil
+}
+
+// SetCode sets the code of the given location, e.g. of a string location,
+// which can then be imported. The code of contracts is stored in accounts instead.
+func (i *Interface) SetCode(location common.Location, code []byte) {
+	i.codes[location] = code
+	i.invalidatePrograms()
+}
+
+// Events returns all events which were emitted, in order.
+// Events of failed transactions are included.
+func (i *Interface) Events() []cadence.Event {
+	return i.events
+}
+
+// Logs returns all messages which were logged, in order.
+// Messages of failed transactions and scripts are included.
+func (i *Interface) Logs() []string {
+	return i.logs
+}
+
+// Programs
+
+// ResolveLocation resolves imports of address locations to the contracts of the account:
+// An import without identifiers imports all contracts of the account.
+func (i *Interface) ResolveLocation(
+	identifiers []runtime.Identifier,
+	location runtime.Location,
+) ([]runtime.ResolvedLocation, error) {
+
+	addressLocation, ok := location.(common.AddressLocation)
+	if !ok {
+		return []runtime.ResolvedLocation{
+			{
+				Location:    location,
+				Identifiers: identifiers,
+			},
+		}, nil
+	}
+
+	if len(identifiers) == 0 {
+		names, err := i.GetAccountContractNames(addressLocation.Address)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			identifiers = append(identifiers, runtime.Identifier{
+				Identifier: name,
+			})
+		}
+	}
+
+	resolvedLocations := make([]runtime.ResolvedLocation, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		resolvedLocations = append(resolvedLocations, runtime.ResolvedLocation{
+			Location: common.AddressLocation{
+				Address: addressLocation.Address,
+				Name:    identifier.Identifier,
+			},
+			Identifiers: []runtime.Identifier{identifier},
+		})
+	}
+
+	return resolvedLocations, nil
+}
+
+// GetCode returns the code of the given location:
+// The contract code for address locations, or the code set with SetCode for other locations.
+func (i *Interface) GetCode(location runtime.Location) ([]byte, error) {
+	if addressLocation, ok := location.(common.AddressLocation); ok {
+		return i.GetAccountContractCode(addressLocation)
+	}
+
+	code, ok := i.codes[location]
+	if !ok {
+		return nil, fmt.Errorf("unknown location: %s", location)
+	}
+	return code, nil
+}
+
+// GetOrLoadProgram caches the programs of contracts, until any code is updated.
+// Other programs, e.g. of transactions and scripts, are not cached,
+// as different transactions and scripts may be executed with the same location.
+func (i *Interface) GetOrLoadProgram(
+	location runtime.Location,
+	load func() (*interpreter.Program, error),
+) (*interpreter.Program, error) {
+
+	if _, ok := location.(common.AddressLocation); !ok {
+		return load()
+	}
+
+	program, ok := i.programs[location]
+	if ok {
+		return program, nil
+	}
+
+	program, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	i.programs[location] = program
+	return program, nil
+}
+
+func (i *Interface) invalidatePrograms() {
+	i.programs = map[common.Location]*interpreter.Program{}
+}
+
+func (i *Interface) SetInterpreterSharedState(_ *interpreter.SharedState) {
+	// NO-OP: the shared state is not reused
+}
+
+func (i *Interface) GetInterpreterSharedState() *interpreter.SharedState {
+	return nil
+}
+
+// Storage
+
+func (i *Interface) GetValue(owner, key []byte) ([]byte, error) {
+	return i.ledger.GetValue(owner, key)
+}
+
+func (i *Interface) SetValue(owner, key, value []byte) error {
+	return i.ledger.SetValue(owner, key, value)
+}
+
+func (i *Interface) ValueExists(owner, key []byte) (bool, error) {
+	return i.ledger.ValueExists(owner, key)
+}
+
+func (i *Interface) AllocateStorageIndex(owner []byte) (atree.StorageIndex, error) {
+	return i.ledger.AllocateStorageIndex(owner)
+}
+
+// Execution
+
+func (i *Interface) ProgramLog(message string) error {
+	i.logs = append(i.logs, message)
+	return nil
+}
+
+func (i *Interface) EmitEvent(event cadence.Event) error {
+	i.events = append(i.events, event)
+	return nil
+}
+
+// DecodeArgument decodes the given JSON-CDC encoded argument.
+func (i *Interface) DecodeArgument(argument []byte, _ cadence.Type) (cadence.Value, error) {
+	return jsoncdc.Decode(nil, argument)
+}
+
+func (i *Interface) ImplementationDebugLog(_ string) error {
+	return nil
+}
+
+func (i *Interface) RecordTrace(_ string, _ runtime.Location, _ time.Duration, _ []attribute.KeyValue) {
+	// NO-OP
+}
+
+func (i *Interface) ResourceOwnerChanged(
+	_ *interpreter.Interpreter,
+	_ *interpreter.CompositeValue,
+	_ common.Address,
+	_ common.Address,
+) {
+	// NO-OP
+}
+
+// Metering
+
+// MeterMemory accumulates the memory usage, which is returned by MemoryUsed.
+// There is no limit.
+func (i *Interface) MeterMemory(usage common.MemoryUsage) error {
+	i.memoryUsed += usage.Amount
+	return nil
+}
+
+// MeterComputation accumulates the computation usage, which is returned by ComputationUsed.
+// There is no limit.
+func (i *Interface) MeterComputation(_ common.ComputationKind, intensity uint) error {
+	i.computationUsed += uint64(intensity)
+	return nil
+}
+
+func (i *Interface) ComputationUsed() (uint64, error) {
+	return i.computationUsed, nil
+}
+
+func (i *Interface) MemoryUsed() (uint64, error) {
+	return i.memoryUsed, nil
+}
+
+func (i *Interface) InteractionUsed() (uint64, error) {
+	return 0, nil
+}
@@ -0,0 +1,415 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/inmemory"
+	"github.com/onflow/cadence/runtime/tests/runtime_utils"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+type testHost struct {
+	t                       *testing.T
+	runtime                 runtime.Runtime
+	inter                   *Interface
+	nextTransactionLocation func() common.TransactionLocation
+}
+
+func newTestHost(t *testing.T, config Config) *testHost {
+	return &testHost{
+		t:                       t,
+		runtime:                 runtime.NewInterpreterRuntime(runtime.Config{}),
+		inter:                   NewInterface(config),
+		nextTransactionLocation: runtime_utils.NewTransactionLocationGenerator(),
+	}
+}
+
+func (h *testHost) executeTransaction(code string, signers ...common.Address) error {
+	h.inter.SetSigningAccounts(signers...)
+	return h.runtime.ExecuteTransaction(
+		runtime.Script{
+			Source: []byte(code),
+		},
+		runtime.Context{
+			Interface: h.inter,
+			Location:  h.nextTransactionLocation(),
+		},
+	)
+}
+
+func (h *testHost) executeScript(code string, arguments ...cadence.Value) cadence.Value {
+	encodedArguments := make([][]byte, 0, len(arguments))
+	for _, argument := range arguments {
+		encodedArguments = append(encodedArguments, jsoncdc.MustEncode(argument))
+	}
+
+	value, err := h.runtime.ExecuteScript(
+		runtime.Script{
+			Source:    []byte(code),
+			Arguments: encodedArguments,
+		},
+		runtime.Context{
+			Interface: h.inter,
+			Location:  common.ScriptLocation{},
+		},
+	)
+	require.NoError(h.t, err)
+	return value
+}
+
+func TestInterfaceTransactions(t *testing.T) {
+
+	t.Parallel()
+
+	host := newTestHost(t, Config{})
+
+	address, err := host.inter.CreateAccount(common.ZeroAddress)
+	require.NoError(t, err)
+	assert.Equal(t, common.MustBytesToAddress([]byte{0x1}), address)
+
+	const contract = `
+      access(all) contract Counter {
+
+          access(all) event Incremented(id: UInt64, count: Int)
+
+          access(all) resource R {
+              access(all) var count: Int
+
+              init() {
+                  self.count = 0
+              }
+
+              access(all) fun increment() {
+                  self.count = self.count + 1
+                  emit Incremented(id: self.uuid, count: self.count)
+              }
+          }
+
+          access(all) fun createR(): @R {
+              return <- create R()
+          }
+      }
+    `
+
+	err = host.executeTransaction(
+		string(utils.DeploymentTransaction("Counter", []byte(contract))),
+		address,
+	)
+	require.NoError(t, err)
+
+	names, err := host.inter.GetAccountContractNames(address)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Counter"}, names)
+
+	err = host.executeTransaction(
+		`
+          import Counter from 0x1
+
+          transaction {
+              prepare(signer: auth(Storage) &Account) {
+                  let r <- Counter.createR()
+                  r.increment()
+                  signer.storage.save(<-r, to: /storage/counter)
+                  log("saved")
+              }
+          }
+        `,
+		address,
+	)
+	require.NoError(t, err)
+
+	err = host.executeTransaction(
+		`
+          import Counter from 0x1
+
+          transaction {
+              prepare(signer: auth(Storage) &Account) {
+                  signer.storage.borrow<&Counter.R>(from: /storage/counter)!.increment()
+              }
+          }
+        `,
+		address,
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{`"saved"`}, host.inter.Logs())
+
+	events := host.inter.Events()
+	require.Len(t, events, 3)
+	assert.Equal(t, "flow.AccountContractAdded", events[0].EventType.ID())
+	assert.Equal(t, "A.0000000000000001.Counter.Incremented", events[2].EventType.ID())
+	assert.Equal(t,
+		[]cadence.Value{cadence.UInt64(1), cadence.NewInt(2)},
+		events[2].Fields,
+	)
+
+	value := host.executeScript(`
+      import Counter from 0x1
+
+      access(all) fun main(address: Address): Int {
+          return getAuthAccount<auth(Storage) &Account>(address)
+              .storage.borrow<&Counter.R>(from: /storage/counter)!.count
+      }
+    `,
+		cadence.Address(address),
+	)
+	assert.Equal(t, cadence.NewInt(2), value)
+
+	used, err := host.inter.GetStorageUsed(address)
+	require.NoError(t, err)
+	assert.NotZero(t, used)
+
+	var registers int
+	err = host.inter.Ledger().ForEachRegister(func(owner, key, value []byte) error {
+		assert.Equal(t, address[:], owner)
+		registers++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, registers)
+
+	// A failed transaction does not write to storage
+
+	err = host.executeTransaction(
+		`
+          transaction {
+              prepare(signer: auth(Storage) &Account) {
+                  signer.storage.save(1, to: /storage/number)
+                  panic("failed")
+              }
+          }
+        `,
+		address,
+	)
+	require.ErrorContains(t, err, "failed")
+
+	exists, err := host.inter.ValueExists(address[:], []byte("number"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestInterfaceBlocksAndRandomness(t *testing.T) {
+
+	t.Parallel()
+
+	config := Config{
+		StartTime:     time.Unix(1_000, 0),
+		BlockInterval: 10 * time.Second,
+		RandomSeed:    []byte("seed"),
+	}
+
+	const script = `
+      access(all) fun main(): [UInt64] {
+          let block = getCurrentBlock()
+          let previous = getBlock(at: block.height - 1)!
+          return [
+              block.height,
+              UInt64(block.timestamp),
+              UInt64(previous.timestamp),
+              revertibleRandom<UInt64>()
+          ]
+      }
+    `
+
+	execute := func() cadence.Value {
+		host := newTestHost(t, config)
+		host.inter.CommitBlock()
+		host.inter.CommitBlock()
+		return host.executeScript(script)
+	}
+
+	value := execute()
+	require.IsType(t, cadence.Array{}, value)
+	values := value.(cadence.Array).Values
+	require.Len(t, values, 4)
+
+	assert.Equal(t, cadence.UInt64(2), values[0])
+	assert.Equal(t, cadence.UInt64(1_020), values[1])
+	assert.Equal(t, cadence.UInt64(1_010), values[2])
+
+	// Randomness is deterministic
+	assert.Equal(t, value, execute())
+
+	host := newTestHost(t, config)
+	_, exists, err := host.inter.GetBlockAtHeight(1)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestInterfaceKeysAndSignatures(t *testing.T) {
+
+	t.Parallel()
+
+	host := newTestHost(t, Config{})
+
+	address, err := host.inter.CreateAccount(common.ZeroAddress)
+	require.NoError(t, err)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	publicKey := make([]byte, 64)
+	privateKey.X.FillBytes(publicKey[:32])
+	privateKey.Y.FillBytes(publicKey[32:])
+
+	err = host.executeTransaction(
+		fmt.Sprintf(
+			`
+              transaction {
+                  prepare(signer: auth(Keys) &Account) {
+                      signer.keys.add(
+                          publicKey: PublicKey(
+                              publicKey: "%s".decodeHex(),
+                              signatureAlgorithm: SignatureAlgorithm.ECDSA_P256
+                          ),
+                          hashAlgorithm: HashAlgorithm.SHA3_256,
+                          weight: 1000.0
+                      )
+                      signer.keys.add(
+                          publicKey: PublicKey(
+                              publicKey: "%[1]s".decodeHex(),
+                              signatureAlgorithm: SignatureAlgorithm.ECDSA_P256
+                          ),
+                          hashAlgorithm: HashAlgorithm.SHA2_256,
+                          weight: 1.0
+                      )
+                      signer.keys.revoke(keyIndex: 1)
+                  }
+              }
+            `,
+			hex.EncodeToString(publicKey),
+		),
+		address,
+	)
+	require.NoError(t, err)
+
+	count, err := host.inter.AccountKeysCount(address)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+
+	key, err := host.inter.GetAccountKey(address, 1)
+	require.NoError(t, err)
+	assert.True(t, key.IsRevoked)
+	assert.Equal(t, runtime.HashAlgorithmSHA2_256, key.HashAlgo)
+
+	key, err = host.inter.GetAccountKey(address, 2)
+	require.NoError(t, err)
+	assert.Nil(t, key)
+
+	// Sign data with the key, with the user domain tag
+
+	const tag = "FLOW-V0.0-user"
+	data := []byte("hello")
+
+	digest, err := HashWithTag(runtime.HashAlgorithmSHA3_256, tag, data)
+	require.NoError(t, err)
+
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest)
+	require.NoError(t, err)
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	const verifyScript = `
+      access(all) fun main(address: Address, signature: String, data: String): Bool {
+          let key = getAccount(address).keys.get(keyIndex: 0)!
+          return key.publicKey.verify(
+              signature: signature.decodeHex(),
+              signedData: data.utf8,
+              domainSeparationTag: "FLOW-V0.0-user",
+              hashAlgorithm: key.hashAlgorithm
+          )
+      }
+    `
+
+	value := host.executeScript(
+		verifyScript,
+		cadence.Address(address),
+		cadence.String(hex.EncodeToString(signature)),
+		cadence.String(data),
+	)
+	assert.Equal(t, cadence.Bool(true), value)
+
+	value = host.executeScript(
+		verifyScript,
+		cadence.Address(address),
+		cadence.String(hex.EncodeToString(signature)),
+		cadence.String("other"),
+	)
+	assert.Equal(t, cadence.Bool(false), value)
+
+	// Algorithms which are not implemented by the standard library are rejected
+
+	err = host.inter.ValidatePublicKey(&runtime.PublicKey{
+		PublicKey: publicKey,
+		SignAlgo:  runtime.SignatureAlgorithmECDSA_secp256k1,
+	})
+	require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+
+	_, err = host.inter.Hash(data, "", runtime.HashAlgorithmKMAC128_BLS_BLS12_381)
+	require.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+}
+
+func TestHashWithTag(t *testing.T) {
+
+	t.Parallel()
+
+	digest, err := HashWithTag(runtime.HashAlgorithmSHA2_256, "", []byte("abc"))
+	require.NoError(t, err)
+	assert.Equal(t,
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		hex.EncodeToString(digest),
+	)
+
+	digest, err = HashWithTag(runtime.HashAlgorithmKECCAK_256, "", []byte(""))
+	require.NoError(t, err)
+	assert.Equal(t,
+		"c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		hex.EncodeToString(digest),
+	)
+
+	// The tag is padded to 32 bytes and prefixed
+
+	tagged, err := HashWithTag(runtime.HashAlgorithmSHA3_256, "tag", []byte("abc"))
+	require.NoError(t, err)
+
+	prefixed := append([]byte("tag"), make([]byte, DomainTagLength-3)...)
+	expected, err := HashWithTag(runtime.HashAlgorithmSHA3_256, "", append(prefixed, "abc"...))
+	require.NoError(t, err)
+	assert.Equal(t, expected, tagged)
+
+	_, err = HashWithTag(runtime.HashAlgorithmSHA3_256, string(make([]byte, DomainTagLength+1)), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,113 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inmemory
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/onflow/atree"
+)
+
+type registerID struct {
+	owner string
+	key   string
+}
+
+// Ledger is an in-memory atree.Ledger.
+// Registers are stored per owner, i.e. per account address.
+type Ledger struct {
+	registers      map[registerID][]byte
+	storageIndices map[string]uint64
+}
+
+var _ atree.Ledger = &Ledger{}
+
+// NewLedger returns a new, empty ledger.
+func NewLedger() *Ledger {
+	return &Ledger{
+		registers:      map[registerID][]byte{},
+		storageIndices: map[string]uint64{},
+	}
+}
+
+func (l *Ledger) GetValue(owner, key []byte) (value []byte, err error) {
+	return l.registers[registerID{string(owner), string(key)}], nil
+}
+
+func (l *Ledger) SetValue(owner, key, value []byte) (err error) {
+	id := registerID{string(owner), string(key)}
+	if len(value) == 0 {
+		delete(l.registers, id)
+		return nil
+	}
+	l.registers[id] = value
+	return nil
+}
+
+func (l *Ledger) ValueExists(owner, key []byte) (exists bool, err error) {
+	return len(l.registers[registerID{string(owner), string(key)}]) > 0, nil
+}
+
+func (l *Ledger) AllocateStorageIndex(owner []byte) (result atree.StorageIndex, err error) {
+	index := l.storageIndices[string(owner)] + 1
+	l.storageIndices[string(owner)] = index
+	binary.BigEndian.PutUint64(result[:], index)
+	return
+}
+
+// ForEachRegister calls the given function for each non-empty register,
+// ordered by owner and key, and stops at the first error.
+func (l *Ledger) ForEachRegister(f func(owner, key, value []byte) error) error {
+	ids := make([]registerID, 0, len(l.registers))
+	for id := range l.registers { //nolint:maprange
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		a := ids[i]
+		b := ids[j]
+		if a.owner != b.owner {
+			return a.owner < b.owner
+		}
+		return a.key < b.key
+	})
+
+	for _, id := range ids {
+		err := f([]byte(id.owner), []byte(id.key), l.registers[id])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StorageUsed returns the number of bytes used by the registers of the given owner,
+// i.e. the sum of the sizes of the keys and values.
+func (l *Ledger) StorageUsed(owner []byte) uint64 {
+	var used uint64
+	for id, value := range l.registers { //nolint:maprange
+		if id.owner != string(owner) {
+			continue
+		}
+		used += uint64(len(id.key) + len(value))
+	}
+	return used
+}
@@ -33,6 +33,8 @@ type Config struct {
 	OnRecordTrace OnRecordTraceFunc
 	// OnResourceOwnerChange is triggered when the owner of a resource changes
 	OnResourceOwnerChange OnResourceOwnerChangeFunc
+	// OnStoredValueAccess is triggered when a value stored in an account is read or written
+	OnStoredValueAccess OnStoredValueAccessFunc
 	// OnMeterComputation is triggered when a computation is about to happen
 	OnMeterComputation OnMeterComputationFunc
 	// InjectedCompositeFieldsHandler is used to initialize new composite values' fields
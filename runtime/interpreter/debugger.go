@@ -19,31 +19,117 @@
 package interpreter
 
 import (
+	"sync"
 	"sync/atomic"
 
 	"github.com/bits-and-blooms/bitset"
 
 	"github.com/onflow/cadence/runtime/ast"
 	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
 )
 
 type Stop struct {
 	Interpreter *Interpreter
 	Statement   ast.Statement
+	// BreakpointError is the error which occurred
+	// when the condition of the breakpoint was evaluated, if any
+	BreakpointError error
+	callStack       []StackFrame
+}
+
+// CallStack returns the call stack at the stop.
+// The first frame is the outermost function invocation,
+// the last frame is the current function, which contains the stop's statement.
+func (s Stop) CallStack() []StackFrame {
+	return s.callStack
+}
+
+// StackFrame is a function invocation on the call stack.
+type StackFrame struct {
+	// Function is the invoked function
+	Function FunctionValue
+	// Invocation is the location of the invocation of the function
+	Invocation LocationRange
+	// LocationRange is the current location in the function
+	LocationRange LocationRange
+	// Interpreter is the interpreter executing the function
+	Interpreter *Interpreter
+	// Activation is the current activation in the function
+	Activation *VariableActivation
+}
+
+// StepMode specifies where execution stops after a step.
+type StepMode uint8
+
+const (
+	StepModeNone StepMode = iota
+	// StepModeInto stops at the next statement,
+	// including statements in invoked functions
+	StepModeInto
+	// StepModeOver stops at the next statement in the current function,
+	// or in a caller, if the current function returns
+	StepModeOver
+	// StepModeOut stops at the next statement in a caller of the current function
+	StepModeOut
+)
+
+// Breakpoint specifies when execution stops at a breakpoint.
+type Breakpoint struct {
+	// Condition is an optional view expression of type Bool.
+	// It is evaluated in the current activation when the breakpoint is reached,
+	// and execution only stops if it evaluates to true.
+	Condition ast.Expression
+	// HitCount is an optional threshold:
+	// Execution only stops once the breakpoint was reached
+	// (and its condition evaluated to true) at least this many times.
+	HitCount uint
+	// LogMessage makes the breakpoint a logpoint, if it is not nil:
+	// Instead of stopping, the message is passed to the logpoint handler.
+	LogMessage []LogMessagePart
+
+	hits uint
+}
+
+// LogMessagePart is a part of the message of a logpoint.
+// It is either literal text, or a view expression which is evaluated and interpolated.
+type LogMessagePart struct {
+	Text       string
+	Expression ast.Expression
+}
+
+// debuggerFrame records the invocation of an interpreted function
+type debuggerFrame struct {
+	function    *InterpretedFunctionValue
+	invocation  Invocation
+	interpreter *Interpreter
+	// callerActivation is the current activation of the caller at the time of the invocation
+	callerActivation *VariableActivation
 }
 
 type Debugger struct {
-	stops          chan Stop
-	continues      chan struct{}
-	breakpoints    map[common.Location]*bitset.BitSet
-	pauseRequested uint32
+	stops       chan Stop
+	continues   chan struct{}
+	breakpoints map[common.Location]*bitset.BitSet
+	// conditionalBreakpoints are the breakpoints which have options
+	conditionalBreakpoints map[common.Location]map[uint]*Breakpoint
+	breakpointsMu          sync.RWMutex
+	pauseRequested         uint32
+	logpointHandler        func(message string, locationRange LocationRange)
+	// frames, stepMode, stepDepth, and evaluating are only accessed by the interpreter,
+	// or while execution is stopped
+	frames     []debuggerFrame
+	stepMode   StepMode
+	stepDepth  int
+	evaluating bool
 }
 
 func NewDebugger() *Debugger {
 	return &Debugger{
-		stops:       make(chan Stop),
-		continues:   make(chan struct{}),
-		breakpoints: map[common.Location]*bitset.BitSet{},
+		stops:                  make(chan Stop),
+		continues:              make(chan struct{}),
+		breakpoints:            map[common.Location]*bitset.BitSet{},
+		conditionalBreakpoints: map[common.Location]map[uint]*Breakpoint{},
 	}
 }
 
@@ -52,6 +138,14 @@ func (d *Debugger) Stops() <-chan Stop {
 }
 
 func (d *Debugger) AddBreakpoint(location common.Location, line uint) {
+	d.breakpointsMu.Lock()
+	defer d.breakpointsMu.Unlock()
+
+	d.addBreakpoint(location, line)
+	delete(d.conditionalBreakpoints[location], line)
+}
+
+func (d *Debugger) addBreakpoint(location common.Location, line uint) {
 	breakpoints, ok := d.breakpoints[location]
 	if !ok {
 		breakpoints = bitset.New(1024)
@@ -60,7 +154,35 @@ func (d *Debugger) AddBreakpoint(location common.Location, line uint) {
 	breakpoints.Set(line)
 }
 
+// AddConditionalBreakpoint adds a breakpoint with the given options.
+func (d *Debugger) AddConditionalBreakpoint(location common.Location, line uint, breakpoint Breakpoint) {
+	breakpoint.hits = 0
+
+	d.breakpointsMu.Lock()
+	defer d.breakpointsMu.Unlock()
+
+	d.addBreakpoint(location, line)
+
+	conditionalBreakpoints, ok := d.conditionalBreakpoints[location]
+	if !ok {
+		conditionalBreakpoints = map[uint]*Breakpoint{}
+		d.conditionalBreakpoints[location] = conditionalBreakpoints
+	}
+	conditionalBreakpoints[line] = &breakpoint
+}
+
+// SetLogpointHandler sets the function which is called with the message of a logpoint,
+// when it is reached.
+func (d *Debugger) SetLogpointHandler(handler func(message string, locationRange LocationRange)) {
+	d.logpointHandler = handler
+}
+
 func (d *Debugger) RemoveBreakpoint(location common.Location, line uint) {
+	d.breakpointsMu.Lock()
+	defer d.breakpointsMu.Unlock()
+
+	delete(d.conditionalBreakpoints[location], line)
+
 	breakpoints, ok := d.breakpoints[location]
 	if !ok {
 		return
@@ -69,36 +191,197 @@ func (d *Debugger) RemoveBreakpoint(location common.Location, line uint) {
 }
 
 func (d *Debugger) ClearBreakpoints() {
+	d.breakpointsMu.Lock()
+	defer d.breakpointsMu.Unlock()
+
 	for location := range d.breakpoints { //nolint:maprange
 		delete(d.breakpoints, location)
 	}
+	for location := range d.conditionalBreakpoints { //nolint:maprange
+		delete(d.conditionalBreakpoints, location)
+	}
 }
 
 func (d *Debugger) ClearBreakpointsForLocation(location common.Location) {
+	d.breakpointsMu.Lock()
+	defer d.breakpointsMu.Unlock()
+
 	delete(d.breakpoints, location)
+	delete(d.conditionalBreakpoints, location)
 }
 
 func (d *Debugger) onStatement(interpreter *Interpreter, statement ast.Statement) {
-	if !atomic.CompareAndSwapUint32(&d.pauseRequested, 1, 0) {
-		breakpoints, ok := d.breakpoints[interpreter.Location]
-		if !ok {
-			return
-		}
+	// Do not stop while evaluating expressions on behalf of the debugger
+	if d.evaluating {
+		return
+	}
 
-		startPosition := statement.StartPosition()
-		if !breakpoints.Test(uint(startPosition.Line)) {
+	depth := len(d.frames)
+
+	var breakpointErr error
+
+	if !atomic.CompareAndSwapUint32(&d.pauseRequested, 1, 0) &&
+		!d.stepCompleted(depth) {
+
+		var stop bool
+		stop, breakpointErr = d.breakpointReached(interpreter, statement)
+		if !stop {
 			return
 		}
 	}
 
+	d.stepMode = StepModeNone
+	d.stepDepth = depth
+
 	d.stops <- Stop{
-		Interpreter: interpreter,
-		Statement:   statement,
+		Interpreter:     interpreter,
+		Statement:       statement,
+		BreakpointError: breakpointErr,
+		callStack:       d.callStack(interpreter, statement),
 	}
 
 	<-d.continues
 }
 
+// breakpointReached returns true if execution should stop at the given statement.
+// If the condition of the breakpoint fails to evaluate, execution stops, and the error is returned.
+func (d *Debugger) breakpointReached(interpreter *Interpreter, statement ast.Statement) (bool, error) {
+	location := interpreter.Location
+	line := uint(statement.StartPosition().Line)
+
+	d.breakpointsMu.RLock()
+	breakpoints, ok := d.breakpoints[location]
+	if !ok || !breakpoints.Test(line) {
+		d.breakpointsMu.RUnlock()
+		return false, nil
+	}
+	breakpoint := d.conditionalBreakpoints[location][line]
+	d.breakpointsMu.RUnlock()
+
+	if breakpoint == nil {
+		return true, nil
+	}
+
+	activation := interpreter.activations.Current()
+
+	if breakpoint.Condition != nil {
+		result, err := d.evaluate(interpreter, activation, breakpoint.Condition, sema.BoolType, true)
+		if err != nil {
+			return true, err
+		}
+
+		if result != TrueValue {
+			return false, nil
+		}
+	}
+
+	breakpoint.hits++
+	if breakpoint.hits < breakpoint.HitCount {
+		return false, nil
+	}
+
+	if breakpoint.LogMessage != nil {
+		if d.logpointHandler != nil {
+			message := d.interpolateLogMessage(interpreter, activation, breakpoint.LogMessage)
+			d.logpointHandler(
+				message,
+				LocationRange{
+					Location:    location,
+					HasPosition: statement,
+				},
+			)
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (d *Debugger) stepCompleted(depth int) bool {
+	switch d.stepMode {
+	case StepModeInto:
+		return true
+	case StepModeOver:
+		return depth <= d.stepDepth
+	case StepModeOut:
+		return depth < d.stepDepth
+	default:
+		return false
+	}
+}
+
+func (d *Debugger) onFunctionInvocation(
+	interpreter *Interpreter,
+	function *InterpretedFunctionValue,
+	invocation Invocation,
+) {
+	var callerActivation *VariableActivation
+	if invocation.Interpreter != nil {
+		callerActivation = invocation.Interpreter.activations.Current()
+	}
+
+	d.frames = append(
+		d.frames,
+		debuggerFrame{
+			function:         function,
+			invocation:       invocation,
+			interpreter:      interpreter,
+			callerActivation: callerActivation,
+		},
+	)
+}
+
+func (d *Debugger) onInvokedFunctionReturn() {
+	lastIndex := len(d.frames) - 1
+	d.frames[lastIndex] = debuggerFrame{}
+	d.frames = d.frames[:lastIndex]
+}
+
+// callStack returns the stack frames for a stop at the given statement.
+// The current location of a caller is the location where it invoked the next frame's function.
+func (d *Debugger) callStack(interpreter *Interpreter, statement ast.Statement) []StackFrame {
+	frameCount := len(d.frames)
+
+	if frameCount == 0 {
+		return []StackFrame{
+			{
+				LocationRange: LocationRange{
+					Location:    interpreter.Location,
+					HasPosition: statement,
+				},
+				Interpreter: interpreter,
+				Activation:  interpreter.activations.Current(),
+			},
+		}
+	}
+
+	stackFrames := make([]StackFrame, frameCount)
+
+	for i, frame := range d.frames {
+		stackFrame := StackFrame{
+			Function:    frame.function,
+			Invocation:  frame.invocation.LocationRange,
+			Interpreter: frame.interpreter,
+		}
+
+		if i == frameCount-1 {
+			stackFrame.LocationRange = LocationRange{
+				Location:    interpreter.Location,
+				HasPosition: statement,
+			}
+			stackFrame.Activation = interpreter.activations.Current()
+		} else {
+			callee := d.frames[i+1]
+			stackFrame.LocationRange = callee.invocation.LocationRange
+			stackFrame.Activation = callee.callerActivation
+		}
+
+		stackFrames[i] = stackFrame
+	}
+
+	return stackFrames
+}
+
 func (d *Debugger) RequestPause() {
 	atomic.StoreUint32(&d.pauseRequested, 1)
 }
@@ -118,6 +401,36 @@ func (d *Debugger) Next() Stop {
 	return <-d.Stops()
 }
 
+// RequestStep requests that execution stops after a step in the given mode.
+// It must only be called while execution is stopped, and does not continue execution.
+func (d *Debugger) RequestStep(mode StepMode) {
+	d.stepMode = mode
+}
+
+func (d *Debugger) step(mode StepMode) Stop {
+	d.RequestStep(mode)
+	d.Continue()
+	return <-d.Stops()
+}
+
+// StepInto continues execution until the next statement,
+// including statements in invoked functions.
+func (d *Debugger) StepInto() Stop {
+	return d.step(StepModeInto)
+}
+
+// StepOver continues execution until the next statement in the current function,
+// i.e. it does not stop in functions invoked by the current statement.
+func (d *Debugger) StepOver() Stop {
+	return d.step(StepModeOver)
+}
+
+// StepOut continues execution until the current function returns,
+// and stops at the next statement in the caller.
+func (d *Debugger) StepOut() Stop {
+	return d.step(StepModeOut)
+}
+
 func (d *Debugger) CurrentActivation(interpreter *Interpreter) *VariableActivation {
 	return interpreter.activations.Current()
 }
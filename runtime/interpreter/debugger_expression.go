@@ -0,0 +1,230 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"strings"
+
+	"github.com/onflow/cadence/runtime/activations"
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// Evaluate checks and evaluates the given expression
+// in the given activation of the given interpreter,
+// e.g. the activation of a frame of the call stack of a stop.
+//
+// Evaluate must only be called while execution is stopped.
+func (d *Debugger) Evaluate(
+	interpreter *Interpreter,
+	activation *VariableActivation,
+	expression ast.Expression,
+) (Value, error) {
+	return d.evaluate(interpreter, activation, expression, nil, false)
+}
+
+// evaluate checks and evaluates the given expression in the given activation.
+//
+// The expression is checked in a new checker, in which the variables referenced by the expression
+// are declared with the types of their current values.
+// If viewOnly is true, the expression must be view, i.e. it must not have side effects.
+// This is required for breakpoint conditions and log messages,
+// which are evaluated implicitly while the program executes.
+//
+// The expression is then evaluated by a new interpreter with the checker's elaboration,
+// which shares the state of the given interpreter.
+// The evaluation is not metered, i.e. it does not use the computation and memory of the program,
+// and does not contribute to its coverage.
+func (d *Debugger) evaluate(
+	interpreter *Interpreter,
+	activation *VariableActivation,
+	expression ast.Expression,
+	expectedType sema.Type,
+	viewOnly bool,
+) (
+	result Value,
+	err error,
+) {
+	// Functions invoked by the expression are executed by the interpreters of their locations,
+	// which share the state of the given interpreter,
+	// so replace its configuration with one that does not meter until the evaluation completes
+
+	sharedState := interpreter.SharedState
+	config := sharedState.Config
+	unmeteredConfig := *config
+	unmeteredConfig.MemoryGauge = nil
+	unmeteredConfig.OnMeterComputation = nil
+	unmeteredConfig.OnStatement = nil
+	unmeteredConfig.OnLoopIteration = nil
+	sharedState.Config = &unmeteredConfig
+
+	defer func() {
+		sharedState.Config = config
+	}()
+
+	valueActivation := sema.NewVariableActivation(sema.BaseValueActivation)
+
+	for _, name := range referencedIdentifiers(expression) {
+		if sema.BaseValueActivation.Find(name) != nil {
+			continue
+		}
+
+		variable := activation.Find(name)
+		if variable == nil {
+			continue
+		}
+
+		value := variable.GetValue(interpreter)
+		if value == nil {
+			continue
+		}
+
+		variableType, err := interpreter.ConvertStaticToSemaType(value.StaticType(interpreter))
+		if err != nil {
+			continue
+		}
+
+		valueActivation.Set(
+			name,
+			&sema.Variable{
+				Identifier:      name,
+				Type:            variableType,
+				DeclarationKind: common.DeclarationKindConstant,
+				Access:          sema.PrimitiveAccess(ast.AccessAll),
+				IsConstant:      true,
+			},
+		)
+	}
+
+	checker, err := sema.NewChecker(
+		nil,
+		interpreter.Location,
+		nil,
+		&sema.Config{
+			BaseValueActivationHandler: func(_ common.Location) *sema.VariableActivation {
+				return valueActivation
+			},
+			AccessCheckMode: sema.AccessCheckModeNone,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	checker.InNewPurityScope(viewOnly, func() {
+		checker.VisitExpression(expression, expectedType)
+	})
+
+	checkerErr := checker.CheckerError()
+	if checkerErr != nil {
+		return nil, checkerErr
+	}
+
+	// NOTE: do not use NewSubInterpreter,
+	// the evaluating interpreter must not replace the interpreter of the location
+
+	evaluator := &Interpreter{
+		Program: &Program{
+			Elaboration: checker.Elaboration,
+		},
+		Location:    interpreter.Location,
+		SharedState: interpreter.SharedState,
+	}
+	evaluator.activations = activations.NewActivations[Variable](evaluator)
+	evaluator.activations.PushNewWithParent(activation)
+
+	// Do not stop in functions invoked by the expression,
+	// and restore the call stack if evaluation fails
+
+	d.evaluating = true
+	callStack := interpreter.SharedState.callStack
+	callStackDepth := len(callStack.Invocations)
+	frameCount := len(d.frames)
+
+	defer func() {
+		d.evaluating = false
+		callStack.Invocations = callStack.Invocations[:callStackDepth]
+		d.frames = d.frames[:frameCount]
+	}()
+
+	defer evaluator.RecoverErrors(func(internalErr error) {
+		err = internalErr
+	})
+
+	return evaluator.evalExpression(expression), nil
+}
+
+// identifierCollector is an ast.Walker which collects the names of all identifier expressions
+type identifierCollector struct {
+	names []string
+}
+
+var _ ast.Walker = &identifierCollector{}
+
+func (c *identifierCollector) Walk(element ast.Element) ast.Walker {
+	if element == nil {
+		return nil
+	}
+
+	if identifierExpression, ok := element.(*ast.IdentifierExpression); ok {
+		c.names = append(c.names, identifierExpression.Identifier.Identifier)
+	}
+
+	return c
+}
+
+func referencedIdentifiers(expression ast.Expression) []string {
+	collector := &identifierCollector{}
+	ast.Walk(collector, expression)
+	return collector.names
+}
+
+// interpolateLogMessage evaluates the expressions of a log message in the given activation.
+// Evaluation errors are reported inline.
+func (d *Debugger) interpolateLogMessage(
+	interpreter *Interpreter,
+	activation *VariableActivation,
+	parts []LogMessagePart,
+) string {
+	var sb strings.Builder
+
+	for _, part := range parts {
+		if part.Expression == nil {
+			sb.WriteString(part.Text)
+			continue
+		}
+
+		value, err := d.evaluate(interpreter, activation, part.Expression, nil, true)
+		if err != nil {
+			sb.WriteString("<error: ")
+			sb.WriteString(err.Error())
+			sb.WriteString(">")
+			continue
+		}
+
+		if stringValue, ok := value.(*StringValue); ok {
+			sb.WriteString(stringValue.Str)
+		} else {
+			sb.WriteString(value.String())
+		}
+	}
+
+	return sb.String()
+}
@@ -65,7 +65,7 @@ func (e Error) Error() string {
 	var sb strings.Builder
 	sb.WriteString("Execution failed:\n")
 	printErr := pretty.NewErrorPrettyPrinter(&sb, false).
-		PrettyPrintError(e.Err, e.Location, map[common.Location][]byte{})
+		PrettyPrintError(e, e.Location, map[common.Location][]byte{})
 	if printErr != nil {
 		panic(printErr)
 	}
@@ -73,6 +73,13 @@ func (e Error) Error() string {
 }
 
 func (e Error) ChildErrors() []error {
+	// Stack traces with multiple frames are written by the pretty printer
+	// as a list of frame locations (see StackTraceFrames),
+	// so only report the invocation as a code excerpt if there is a single frame
+	if len(e.StackTrace) > 1 {
+		return []error{e.Err}
+	}
+
 	errs := make([]error, 0, 1+len(e.StackTrace))
 
 	for _, invocation := range e.StackTrace {
@@ -96,6 +103,80 @@ func (e Error) ImportLocation() common.Location {
 	return e.Location
 }
 
+var _ errors.HasStackTrace = Error{}
+
+// StackTraceFrames returns the frames of the functions on the stack trace,
+// starting with the innermost frame.
+//
+// The location range of the innermost frame is the location of the error,
+// the location range of all other frames is the location of the invocation of the next inner frame
+func (e Error) StackTraceFrames() []errors.StackTraceFrame {
+	count := len(e.StackTrace)
+	if count == 0 {
+		return nil
+	}
+
+	frames := make([]errors.StackTraceFrame, 0, count)
+
+	locationRange := LocationRange{
+		Location: e.Location,
+	}
+	if err, ok := e.Err.(common.HasLocation); ok {
+		if location := err.ImportLocation(); location != nil {
+			locationRange.Location = location
+		}
+	}
+	if err, ok := e.Err.(ast.HasPosition); ok {
+		locationRange.HasPosition = err
+	}
+
+	for i := count - 1; i >= 0; i-- {
+		invocationLocationRange := e.StackTrace[i].LocationRange
+
+		frames = append(
+			frames,
+			StackTraceFrame{
+				Function:      invokedFunctionName(invocationLocationRange.HasPosition),
+				LocationRange: locationRange,
+			},
+		)
+
+		locationRange = invocationLocationRange
+	}
+
+	return frames
+}
+
+// invokedFunctionName returns the name of the function invoked at the given element.
+// Functions do not have names, so the name is the first line of the invoked expression, e.g. `vault.withdraw`
+func invokedFunctionName(element ast.HasPosition) string {
+	invocationExpression, ok := element.(*ast.InvocationExpression)
+	if !ok {
+		return ""
+	}
+
+	name := invocationExpression.InvokedExpression.String()
+	if index := strings.IndexByte(name, '\n'); index >= 0 {
+		name = name[:index]
+	}
+	return name
+}
+
+// StackTraceFrame is a frame of the stack trace of an Error
+type StackTraceFrame struct {
+	// Function is the name of the function, or empty if it is unknown,
+	// e.g. for the entry point of the program
+	Function string
+	// LocationRange is the current location in the function
+	LocationRange
+}
+
+var _ errors.StackTraceFrame = StackTraceFrame{}
+
+func (f StackTraceFrame) FunctionName() string {
+	return f.Function
+}
+
 type StackTraceError struct {
 	LocationRange
 }
@@ -1107,3 +1188,27 @@ func (ResourceLossError) IsUserError() {}
 func (e ResourceLossError) Error() string {
 	return "resource loss: attempting to assign to non-nil resource-typed value"
 }
+
+// ReadOnlyStorageWriteError is reported when a value is written to the storage of an account,
+// or a contract of an account is updated, while the storage is read-only.
+//
+// Writes are detected by the storage, which has no location information,
+// so the location range is the statement which was executed when the write was detected,
+// in the interpreter which performed the write.
+// Writes which are not performed by a statement, e.g. when the value of a contract is constructed,
+// are reported at the invocation which performed them.
+type ReadOnlyStorageWriteError struct {
+	Address common.Address
+	LocationRange
+}
+
+var _ errors.UserError = ReadOnlyStorageWriteError{}
+
+func (ReadOnlyStorageWriteError) IsUserError() {}
+
+func (e ReadOnlyStorageWriteError) Error() string {
+	return fmt.Sprintf(
+		"cannot write to storage of account %s: storage is read-only",
+		e.Address,
+	)
+}
@@ -62,3 +62,59 @@ func TestErrorOutputIncludesLocationRage(t *testing.T) {
 		"Execution failed:\nerror: dereference failed\n --> test:0:0\n",
 	)
 }
+
+func TestErrorOutputIncludesStackTrace(t *testing.T) {
+	t.Parallel()
+
+	importedLocation := common.StringLocation("imported")
+
+	err := Error{
+		Location: utils.TestLocation,
+		Err: DereferenceError{
+			Cause: "the value being referenced has been destroyed or moved",
+			LocationRange: LocationRange{
+				Location: importedLocation,
+				HasPosition: ast.Range{
+					StartPos: ast.Position{Offset: 30, Line: 3, Column: 4},
+					EndPos:   ast.Position{Offset: 35, Line: 3, Column: 9},
+				},
+			},
+		},
+		StackTrace: []Invocation{
+			// invocation of the entry point by the host
+			{},
+			{
+				LocationRange: LocationRange{
+					Location: utils.TestLocation,
+					HasPosition: ast.Range{
+						StartPos: ast.Position{Offset: 10, Line: 2, Column: 8},
+						EndPos:   ast.Position{Offset: 15, Line: 2, Column: 13},
+					},
+				},
+			},
+			{
+				LocationRange: LocationRange{
+					Location: importedLocation,
+					HasPosition: ast.Range{
+						StartPos: ast.Position{Offset: 20, Line: 5, Column: 12},
+						EndPos:   ast.Position{Offset: 25, Line: 5, Column: 17},
+					},
+				},
+			},
+		},
+	}
+
+	message := err.Error()
+
+	frames := err.StackTraceFrames()
+	require.Len(t, frames, 3)
+
+	require.Contains(t, message, "stack trace:")
+	for _, location := range []string{
+		"imported:3:4",
+		"imported:5:12",
+		"test:2:8",
+	} {
+		require.Contains(t, message, "\n\t"+location+"\n")
+	}
+}
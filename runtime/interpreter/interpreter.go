@@ -97,6 +97,14 @@ type OnResourceOwnerChangeFunc func(
 	newOwner common.Address,
 )
 
+// OnStoredValueAccessFunc is a function that is triggered when a value stored in an account is read or written.
+type OnStoredValueAccessFunc func(
+	inter *Interpreter,
+	address common.Address,
+	domain string,
+	key StorageMapKey,
+)
+
 // OnMeterComputationFunc is a function that is called when some computation is about to happen.
 // intensity captures the intensity of the computation and can be set using input sizes
 // complexity of computation given input sizes, or any other factors that could help the upper levels
@@ -512,6 +520,18 @@ func (interpreter *Interpreter) RecoverErrors(onError func(error)) {
 		// Recover all errors, because interpreter can be directly invoked by FVM.
 		err := asCadenceError(r)
 
+		// Writes in read-only mode are detected by the storage, without location information,
+		// so report the current statement
+		if interpreter.statement != nil {
+			err = withReadOnlyStorageWriteLocationRange(
+				err,
+				LocationRange{
+					Location:    interpreter.Location,
+					HasPosition: interpreter.statement,
+				},
+			)
+		}
+
 		// if the error is not yet an interpreter error, wrap it
 		if _, ok := err.(Error); !ok {
 
@@ -534,12 +554,43 @@ func (interpreter *Interpreter) RecoverErrors(onError func(error)) {
 		}
 
 		interpreterErr := err.(Error)
-		interpreterErr.StackTrace = interpreter.CallStack()
+		// Copy the call stack, as it gets reused by subsequent invocations
+		callStack := interpreter.CallStack()
+		interpreterErr.StackTrace = append([]Invocation(nil), callStack...)
 
 		onError(interpreterErr)
 	}
 }
 
+// withReadOnlyStorageWriteLocationRange returns the given error with the given location range,
+// if it is a ReadOnlyStorageWriteError without a location range.
+// Writes in read-only mode are detected by the storage, which has no location information
+func withReadOnlyStorageWriteLocationRange(err error, locationRange LocationRange) error {
+	readOnlyErr, ok := err.(ReadOnlyStorageWriteError)
+	if !ok || readOnlyErr.HasPosition != nil {
+		return err
+	}
+
+	readOnlyErr.LocationRange = locationRange
+	return readOnlyErr
+}
+
+// recoverReadOnlyStorageWriteError adds the given location range to a recovered ReadOnlyStorageWriteError
+// without a location range, and re-panics.
+// It must be deferred
+func recoverReadOnlyStorageWriteError(locationRange LocationRange) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	if err, ok := r.(error); ok {
+		panic(withReadOnlyStorageWriteLocationRange(err, locationRange))
+	}
+
+	panic(r)
+}
+
 func asCadenceError(r any) error {
 	err, isError := r.(error)
 	if !isError {
@@ -2637,6 +2688,8 @@ func (interpreter *Interpreter) ReadStored(
 	domain string,
 	identifier StorageMapKey,
 ) Value {
+	interpreter.reportStoredValueAccess(storageAddress, domain, identifier)
+
 	accountStorage := interpreter.Storage().GetStorageMap(storageAddress, domain, false)
 	if accountStorage == nil {
 		return nil
@@ -2650,10 +2703,23 @@ func (interpreter *Interpreter) WriteStored(
 	key StorageMapKey,
 	value Value,
 ) (existed bool) {
+	interpreter.reportStoredValueAccess(storageAddress, domain, key)
+
 	accountStorage := interpreter.Storage().GetStorageMap(storageAddress, domain, true)
 	return accountStorage.WriteValue(interpreter, key, value)
 }
 
+func (interpreter *Interpreter) reportStoredValueAccess(
+	storageAddress common.Address,
+	domain string,
+	key StorageMapKey,
+) {
+	onStoredValueAccess := interpreter.SharedState.Config.OnStoredValueAccess
+	if onStoredValueAccess != nil {
+		onStoredValueAccess(interpreter, storageAddress, domain, key)
+	}
+}
+
 type fromStringFunctionValue struct {
 	receiverType sema.Type
 	hostFunction *HostFunctionValue
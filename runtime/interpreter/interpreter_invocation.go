@@ -41,6 +41,13 @@ func (interpreter *Interpreter) InvokeFunctionValue(
 		err = internalErr
 	})
 
+	// Writes which are not performed by a statement occur at the invocation,
+	// e.g. when the value of a contract is constructed
+	defer recoverReadOnlyStorageWriteError(LocationRange{
+		Location:    interpreter.Location,
+		HasPosition: invocationPosition,
+	})
+
 	return interpreter.invokeFunctionValue(
 		function,
 		arguments,
@@ -130,6 +137,12 @@ func (interpreter *Interpreter) invokeInterpretedFunction(
 	invocation Invocation,
 ) Value {
 
+	debugger := interpreter.SharedState.Config.Debugger
+	if debugger != nil {
+		debugger.onFunctionInvocation(interpreter, function, invocation)
+		defer debugger.onInvokedFunctionReturn()
+	}
+
 	// Start a new activation record.
 	// Lexical scope: use the function declaration's activation record,
 	// not the current one (which would be dynamic scope)
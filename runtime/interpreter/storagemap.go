@@ -98,6 +98,25 @@ func (s StorageMap) ReadValue(gauge common.MemoryGauge, key StorageMapKey) Value
 	return StoredValue(gauge, storable, s.orderedMap.Storage)
 }
 
+// ReadStorable returns the storable for the given key.
+// Returns nil if the key does not exist.
+func (s StorageMap) ReadStorable(key StorageMapKey) atree.Storable {
+	storable, err := s.orderedMap.Get(
+		key.AtreeValueCompare,
+		key.AtreeValueHashInput,
+		key.AtreeValue(),
+	)
+	if err != nil {
+		var keyNotFoundError *atree.KeyNotFoundError
+		if goerrors.As(err, &keyNotFoundError) {
+			return nil
+		}
+		panic(errors.NewExternalError(err))
+	}
+
+	return storable
+}
+
 // WriteValue sets or removes a value in the storage map.
 // If the given value is nil, the key is removed.
 // If the given value is non-nil, the key is added/updated.
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 	"unicode"
@@ -2755,6 +2756,118 @@ func (v *ArrayValue) GetMember(interpreter *Interpreter, _ LocationRange, name s
 			},
 		)
 
+	case sema.ArrayTypeSortFunctionName:
+		return NewHostFunctionValue(
+			interpreter,
+			sema.ArraySortFunctionType(
+				v.SemaType(interpreter),
+			),
+			func(invocation Invocation) Value {
+				interpreter := invocation.Interpreter
+
+				funcArgument, ok := invocation.Arguments[0].(FunctionValue)
+				if !ok {
+					panic(errors.NewUnreachableError())
+				}
+
+				return v.Sort(
+					interpreter,
+					invocation.LocationRange,
+					funcArgument,
+				)
+			},
+		)
+
+	case sema.ArrayTypeReduceFunctionName:
+		return NewHostFunctionValue(
+			interpreter,
+			sema.ArrayReduceFunctionType(
+				v.SemaType(interpreter).ElementType(false),
+			),
+			func(invocation Invocation) Value {
+				interpreter := invocation.Interpreter
+
+				initial := invocation.Arguments[0]
+
+				funcArgument, ok := invocation.Arguments[1].(FunctionValue)
+				if !ok {
+					panic(errors.NewUnreachableError())
+				}
+
+				typeParameterPair := invocation.TypeParameterTypes.Oldest()
+				if typeParameterPair == nil {
+					panic(errors.NewUnreachableError())
+				}
+
+				return v.Reduce(
+					interpreter,
+					invocation.LocationRange,
+					initial,
+					funcArgument,
+					typeParameterPair.Value,
+				)
+			},
+		)
+
+	case sema.ArrayTypeAnyFunctionName:
+		return NewHostFunctionValue(
+			interpreter,
+			sema.ArrayPredicateFunctionType(
+				v.SemaType(interpreter).ElementType(false),
+			),
+			func(invocation Invocation) Value {
+				interpreter := invocation.Interpreter
+
+				funcArgument, ok := invocation.Arguments[0].(FunctionValue)
+				if !ok {
+					panic(errors.NewUnreachableError())
+				}
+
+				return v.Any(
+					interpreter,
+					invocation.LocationRange,
+					funcArgument,
+				)
+			},
+		)
+
+	case sema.ArrayTypeAllFunctionName:
+		return NewHostFunctionValue(
+			interpreter,
+			sema.ArrayPredicateFunctionType(
+				v.SemaType(interpreter).ElementType(false),
+			),
+			func(invocation Invocation) Value {
+				interpreter := invocation.Interpreter
+
+				funcArgument, ok := invocation.Arguments[0].(FunctionValue)
+				if !ok {
+					panic(errors.NewUnreachableError())
+				}
+
+				return v.All(
+					interpreter,
+					invocation.LocationRange,
+					funcArgument,
+				)
+			},
+		)
+
+	case sema.ArrayTypeLastIndexFunctionName:
+		return NewHostFunctionValue(
+			interpreter,
+			sema.ArrayLastIndexFunctionType(
+				v.SemaType(interpreter).ElementType(false),
+			),
+			func(invocation Invocation) Value {
+				return v.LastIndex(
+					invocation.Interpreter,
+					invocation.LocationRange,
+					invocation.Arguments[0],
+				)
+			},
+		)
+
 	case sema.ArrayTypeToVariableSizedFunctionName:
 		return NewHostFunctionValue(
 			interpreter,
@@ -3446,6 +3559,220 @@ func (v *ArrayValue) Map(
 	)
 }
 
+func (v *ArrayValue) Sort(
+	interpreter *Interpreter,
+	locationRange LocationRange,
+	isLess FunctionValue,
+) Value {
+
+	elementType := v.SemaType(interpreter).ElementType(false)
+	argumentTypes := []sema.Type{elementType, elementType}
+
+	count := v.Count()
+
+	elements := make([]Value, 0, count)
+	v.Iterate(
+		interpreter,
+		func(element Value) (resume bool) {
+			// Meter computation for iterating the array.
+			interpreter.ReportComputation(common.ComputationKindLoop, 1)
+
+			elements = append(elements, element)
+
+			// continue iteration
+			return true
+		},
+		false,
+		locationRange,
+	)
+
+	sort.SliceStable(
+		elements,
+		func(i, j int) bool {
+			// Meter computation for comparing two elements.
+			interpreter.ReportComputation(common.ComputationKindLoop, 1)
+
+			invocation := NewInvocation(
+				interpreter,
+				nil,
+				nil,
+				nil,
+				[]Value{elements[i], elements[j]},
+				argumentTypes,
+				nil,
+				locationRange,
+			)
+
+			less, ok := isLess.invoke(invocation).(BoolValue)
+			if !ok {
+				panic(errors.NewUnreachableError())
+			}
+
+			return bool(less)
+		},
+	)
+
+	index := 0
+
+	return NewArrayValueWithIterator(
+		interpreter,
+		v.Type,
+		common.ZeroAddress,
+		uint64(count),
+		func() Value {
+			if index >= count {
+				return nil
+			}
+
+			value := elements[index]
+			index++
+
+			return value.Transfer(
+				interpreter,
+				locationRange,
+				atree.Address{},
+				false,
+				nil,
+				nil,
+			)
+		},
+	)
+}
+
+func (v *ArrayValue) Reduce(
+	interpreter *Interpreter,
+	locationRange LocationRange,
+	initial Value,
+	reducer FunctionValue,
+	accumulatorType sema.Type,
+) Value {
+
+	argumentTypes := []sema.Type{
+		accumulatorType,
+		v.SemaType(interpreter).ElementType(false),
+	}
+
+	accumulator := initial
+
+	v.Iterate(
+		interpreter,
+		func(element Value) (resume bool) {
+			// Meter computation for iterating the array.
+			interpreter.ReportComputation(common.ComputationKindLoop, 1)
+
+			invocation := NewInvocation(
+				interpreter,
+				nil,
+				nil,
+				nil,
+				[]Value{accumulator, element},
+				argumentTypes,
+				nil,
+				locationRange,
+			)
+
+			accumulator = reducer.invoke(invocation)
+
+			// continue iteration
+			return true
+		},
+		false,
+		locationRange,
+	)
+
+	return accumulator
+}
+
+func (v *ArrayValue) Any(
+	interpreter *Interpreter,
+	locationRange LocationRange,
+	predicate FunctionValue,
+) BoolValue {
+	// Stop at the first element which satisfies the predicate
+	return AsBoolValue(v.findElement(interpreter, locationRange, predicate, true))
+}
+
+func (v *ArrayValue) All(
+	interpreter *Interpreter,
+	locationRange LocationRange,
+	predicate FunctionValue,
+) BoolValue {
+	// Stop at the first element which does not satisfy the predicate
+	return AsBoolValue(!v.findElement(interpreter, locationRange, predicate, false))
+}
+
+// findElement returns true if the predicate returns the given expected result for any element.
+// Iteration stops at the first such element.
+func (v *ArrayValue) findElement(
+	interpreter *Interpreter,
+	locationRange LocationRange,
+	predicate FunctionValue,
+	expected bool,
+) bool {
+
+	argumentTypes := []sema.Type{v.SemaType(interpreter).ElementType(false)}
+
+	var found bool
+
+	v.Iterate(
+		interpreter,
+		func(element Value) (resume bool) {
+			// Meter computation for iterating the array.
+			interpreter.ReportComputation(common.ComputationKindLoop, 1)
+
+			invocation := NewInvocation(
+				interpreter,
+				nil,
+				nil,
+				nil,
+				[]Value{element},
+				argumentTypes,
+				nil,
+				locationRange,
+			)
+
+			result, ok := predicate.invoke(invocation).(BoolValue)
+			if !ok {
+				panic(errors.NewUnreachableError())
+			}
+
+			if bool(result) == expected {
+				found = true
+				// stop iteration
+				return false
+			}
+
+			// continue iteration
+			return true
+		},
+		false,
+		locationRange,
+	)
+
+	return found
+}
+
+func (v *ArrayValue) LastIndex(interpreter *Interpreter, locationRange LocationRange, needleValue Value) OptionalValue {
+
+	needleEquatable, ok := needleValue.(EquatableValue)
+	if !ok {
+		panic(errors.NewUnreachableError())
+	}
+
+	for index := v.Count() - 1; index >= 0; index-- {
+		// Meter computation for iterating the array.
+		interpreter.ReportComputation(common.ComputationKindLoop, 1)
+
+		element := v.Get(interpreter, locationRange, index)
+		if needleEquatable.Equal(interpreter, locationRange, element) {
+			value := NewIntValueFromInt64(interpreter, int64(index))
+			return NewSomeValueNonCopying(interpreter, value)
+		}
+	}
+
+	return NilOptionalValue
+}
+
 func (v *ArrayValue) ForEach(
 	interpreter *Interpreter,
 	_ sema.Type,
@@ -17541,6 +17868,23 @@ func (v *CompositeValue) TypeID() TypeID {
 	return v.typeID
 }
 
+// SetType sets the type of the composite value to the composite type
+// with the given location and qualified identifier, e.g. when the type got renamed.
+// The kind of the composite value is not changed.
+func (v *CompositeValue) SetType(location common.Location, qualifiedIdentifier string) {
+	v.Location = location
+	v.QualifiedIdentifier = qualifiedIdentifier
+	v.typeID = ""
+	v.staticType = nil
+
+	err := v.dictionary.SetType(
+		NewCompositeTypeInfo(nil, location, qualifiedIdentifier, v.Kind),
+	)
+	if err != nil {
+		panic(errors.NewExternalError(err))
+	}
+}
+
 func (v *CompositeValue) ConformsToStaticType(
 	interpreter *Interpreter,
 	locationRange LocationRange,
@@ -18976,6 +19320,222 @@ func (v *DictionaryValue) ForEachKey(
 	interpreter.withMutationPrevention(v.StorageID(), iterate)
 }
 
+// ForEachEntry calls the given function with the key and the value of each entry,
+// until the function returns false
+func (v *DictionaryValue) ForEachEntry(
+	interpreter *Interpreter,
+	locationRange LocationRange,
+	procedure FunctionValue,
+) {
+	dictionaryType := v.SemaType(interpreter)
+	argumentTypes := []sema.Type{dictionaryType.KeyType, dictionaryType.ValueType}
+
+	v.Iterate(
+		interpreter,
+		func(key, value Value) (resume bool) {
+			// Meter computation for iterating the dictionary.
+			interpreter.ReportComputation(common.ComputationKindLoop, 1)
+
+			invocation := NewInvocation(
+				interpreter,
+				nil,
+				nil,
+				nil,
+				[]Value{key, value},
+				argumentTypes,
+				nil,
+				locationRange,
+			)
+
+			shouldContinue, ok := procedure.invoke(invocation).(BoolValue)
+			if !ok {
+				panic(errors.NewUnreachableError())
+			}
+
+			return bool(shouldContinue)
+		},
+		locationRange,
+	)
+}
+
+func (v *DictionaryValue) Filter(
+	interpreter *Interpreter,
+	locationRange LocationRange,
+	procedure FunctionValue,
+) Value {
+
+	dictionaryType := v.SemaType(interpreter)
+	argumentTypes := []sema.Type{dictionaryType.KeyType, dictionaryType.ValueType}
+
+	iterator := v.Iterator()
+
+	// The filtered entries are a subset of the entries of this dictionary,
+	// in the same order, so the dictionary can be constructed from them
+	// with the same seed.
+
+	return newDictionaryValueWithIterator(
+		interpreter,
+		locationRange,
+		v.Type,
+		uint64(v.Count()), // worst case estimation.
+		v.dictionary.Seed(),
+		common.ZeroAddress,
+		func() (Value, Value) {
+
+			for {
+				// Meter computation for iterating the dictionary.
+				interpreter.ReportComputation(common.ComputationKindLoop, 1)
+
+				key, value := iterator.Next(interpreter)
+
+				// End of the dictionary.
+				if key == nil {
+					return nil, nil
+				}
+
+				invocation := NewInvocation(
+					interpreter,
+					nil,
+					nil,
+					nil,
+					[]Value{key, value},
+					argumentTypes,
+					nil,
+					locationRange,
+				)
+
+				shouldInclude, ok := procedure.invoke(invocation).(BoolValue)
+				if !ok {
+					panic(errors.NewUnreachableError())
+				}
+
+				// We found the next entry of the filtered dictionary.
+				if shouldInclude {
+					return transferDictionaryEntry(interpreter, locationRange, key, value)
+				}
+			}
+		},
+	)
+}
+
+func (v *DictionaryValue) MapValues(
+	interpreter *Interpreter,
+	locationRange LocationRange,
+	procedure FunctionValue,
+	transformFunctionType *sema.FunctionType,
+) Value {
+
+	argumentTypes := []sema.Type{v.SemaType(interpreter).ValueType}
+
+	procedureStaticType, ok := ConvertSemaToStaticType(interpreter, transformFunctionType).(FunctionStaticType)
+	if !ok {
+		panic(errors.NewUnreachableError())
+	}
+
+	returnDictionaryStaticType := NewDictionaryStaticType(
+		interpreter,
+		v.Type.KeyType,
+		procedureStaticType.ReturnType(interpreter),
+	)
+
+	iterator := v.Iterator()
+
+	// The keys are unchanged, in the same order,
+	// so the dictionary can be constructed with the same seed.
+
+	return newDictionaryValueWithIterator(
+		interpreter,
+		locationRange,
+		returnDictionaryStaticType,
+		uint64(v.Count()),
+		v.dictionary.Seed(),
+		common.ZeroAddress,
+		func() (Value, Value) {
+
+			// Meter computation for iterating the dictionary.
+			interpreter.ReportComputation(common.ComputationKindLoop, 1)
+
+			key, value := iterator.Next(interpreter)
+
+			// End of the dictionary.
+			if key == nil {
+				return nil, nil
+			}
+
+			invocation := NewInvocation(
+				interpreter,
+				nil,
+				nil,
+				nil,
+				[]Value{value},
+				argumentTypes,
+				nil,
+				locationRange,
+			)
+
+			mappedValue := procedure.invoke(invocation)
+
+			return transferDictionaryEntry(interpreter, locationRange, key, mappedValue)
+		},
+	)
+}
+
+// transferDictionaryEntry transfers the given key and value,
+// so they can be inserted into a new dictionary
+func transferDictionaryEntry(
+	interpreter *Interpreter,
+	locationRange LocationRange,
+	key Value,
+	value Value,
+) (Value, Value) {
+	transferredKey := key.Transfer(
+		interpreter,
+		locationRange,
+		atree.Address{},
+		false,
+		nil,
+		nil,
+	)
+
+	transferredValue := value.Transfer(
+		interpreter,
+		locationRange,
+		atree.Address{},
+		false,
+		nil,
+		nil,
+	)
+
+	return transferredKey, transferredValue
+}
+
+// Merge inserts all entries of the given dictionary into this dictionary
+func (v *DictionaryValue) Merge(
+	interpreter *Interpreter,
+	locationRange LocationRange,
+	other *DictionaryValue,
+) {
+	// The checker rejects merging resource-typed dictionaries:
+	// The values of the other dictionary are copied, not moved
+	if other.IsResourceKinded(interpreter) {
+		panic(errors.NewUnreachableError())
+	}
+
+	other.Iterate(
+		interpreter,
+		func(key, value Value) (resume bool) {
+			// Meter computation for iterating the dictionary.
+			interpreter.ReportComputation(common.ComputationKindLoop, 1)
+
+			v.Insert(interpreter, locationRange, key, value)
+
+			// continue iteration
+			return true
+		},
+		locationRange,
+	)
+}
+
 func (v *DictionaryValue) ContainsKey(
 	interpreter *Interpreter,
 	locationRange LocationRange,
@@ -19281,6 +19841,103 @@ func (v *DictionaryValue) GetMember(
 					funcArgument,
 				)
 
+				return Void
+			},
+		)
+
+	case sema.DictionaryTypeFilterFunctionName:
+		return NewHostFunctionValue(
+			interpreter,
+			sema.DictionaryFilterFunctionType(
+				v.SemaType(interpreter),
+			),
+			func(invocation Invocation) Value {
+				interpreter := invocation.Interpreter
+
+				funcArgument, ok := invocation.Arguments[0].(FunctionValue)
+				if !ok {
+					panic(errors.NewUnreachableError())
+				}
+
+				return v.Filter(
+					interpreter,
+					invocation.LocationRange,
+					funcArgument,
+				)
+			},
+		)
+
+	case sema.DictionaryTypeMapValuesFunctionName:
+		return NewHostFunctionValue(
+			interpreter,
+			sema.DictionaryMapValuesFunctionType(
+				interpreter,
+				v.SemaType(interpreter),
+			),
+			func(invocation Invocation) Value {
+				interpreter := invocation.Interpreter
+
+				funcArgument, ok := invocation.Arguments[0].(FunctionValue)
+				if !ok {
+					panic(errors.NewUnreachableError())
+				}
+
+				transformFunctionType, ok := invocation.ArgumentTypes[0].(*sema.FunctionType)
+				if !ok {
+					panic(errors.NewUnreachableError())
+				}
+
+				return v.MapValues(
+					interpreter,
+					invocation.LocationRange,
+					funcArgument,
+					transformFunctionType,
+				)
+			},
+		)
+
+	case sema.DictionaryTypeForEachFunctionName:
+		return NewHostFunctionValue(
+			interpreter,
+			sema.DictionaryForEachFunctionType(
+				v.SemaType(interpreter),
+			),
+			func(invocation Invocation) Value {
+				interpreter := invocation.Interpreter
+
+				funcArgument, ok := invocation.Arguments[0].(FunctionValue)
+				if !ok {
+					panic(errors.NewUnreachableError())
+				}
+
+				v.ForEachEntry(
+					interpreter,
+					invocation.LocationRange,
+					funcArgument,
+				)
+
+				return Void
+			},
+		)
+
+	case sema.DictionaryTypeMergeFunctionName:
+		return NewHostFunctionValue(
+			interpreter,
+			sema.DictionaryMergeFunctionType(
+				v.SemaType(interpreter),
+			),
+			func(invocation Invocation) Value {
+				otherDictionary, ok := invocation.Arguments[0].(*DictionaryValue)
+				if !ok {
+					panic(errors.NewUnreachableError())
+				}
+
+				v.Merge(
+					invocation.Interpreter,
+					invocation.LocationRange,
+					otherDictionary,
+				)
+
 				return Void
 			},
 		)
@@ -59,6 +59,8 @@ const messageSeparator = ": "
 const excerptArrow = "--> "
 const excerptDots = "... "
 const maxLineLength = 500
+const stackTraceHeader = "stack trace:"
+const stackTraceUnknownFunctionName = "<unknown>"
 
 func FormatErrorMessage(prefix string, message string, useColor bool) string {
 	if prefix == "" && message == "" {
@@ -196,6 +198,8 @@ func (p ErrorPrettyPrinter) PrettyPrintError(
 				}
 			}
 
+			p.writeStackTrace(err, location)
+
 			return nil
 		}
 
@@ -204,6 +208,7 @@ func (p ErrorPrettyPrinter) PrettyPrintError(
 		}
 
 		p.prettyPrintError(err, location, codes[location])
+		p.writeStackTrace(err, location)
 		i++
 		return nil
 	}
@@ -242,6 +247,72 @@ func (p ErrorPrettyPrinter) prettyPrintError(err error, location common.Location
 	p.writeCodeExcerpts(excerpts, location, code)
 }
 
+// writeStackTrace writes the stack trace of the given error, if any, like a Go panic trace:
+// The name of the function of each frame, followed by the frame's current location.
+//
+// Stack traces with only one frame are not written,
+// as the location of the frame is the location of the error
+func (p ErrorPrettyPrinter) writeStackTrace(err error, location common.Location) {
+	stackTraceErr, ok := err.(errors.HasStackTrace)
+	if !ok {
+		return
+	}
+
+	frames := stackTraceErr.StackTraceFrames()
+	if len(frames) < 2 {
+		return
+	}
+
+	header := stackTraceHeader
+	if p.useColor {
+		header = colorizeMeta(header)
+	}
+	p.writeString("\n")
+	p.writeString(header)
+	p.writeString("\n")
+
+	for _, frame := range frames {
+		name := frame.FunctionName()
+		if name == "" {
+			p.writeString(stackTraceUnknownFunctionName)
+		} else {
+			p.writeString(name)
+			p.writeString("(...)")
+		}
+		p.writeString("\n\t")
+
+		frameLocation := location
+		if frame, ok := frame.(common.HasLocation); ok {
+			if importLocation := frame.ImportLocation(); importLocation != nil {
+				frameLocation = importLocation
+			}
+		}
+
+		locationString := "?"
+		if frameLocation != nil {
+			locationString = frameLocation.String()
+		}
+
+		if frame, ok := frame.(ast.HasPosition); ok {
+			startPosition := frame.StartPosition()
+			if startPosition.Line > 0 {
+				locationString = fmt.Sprintf(
+					"%s:%d:%d",
+					locationString,
+					startPosition.Line,
+					startPosition.Column,
+				)
+			}
+		}
+
+		if p.useColor {
+			locationString = colorizeMeta(locationString)
+		}
+		p.writeString(locationString)
+		p.writeString("\n")
+	}
+}
+
 func (p ErrorPrettyPrinter) writeCodeExcerpts(
 	excerpts []excerpt,
 	location common.Location,
@@ -26,6 +26,7 @@ import (
 
 	"github.com/onflow/cadence/runtime/ast"
 	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/errors"
 )
 
 type testError struct {
@@ -112,3 +113,119 @@ func TestPrintTabs(t *testing.T) {
 		sb.String(),
 	)
 }
+
+type testStackTraceFrame struct {
+	function string
+	location common.Location
+	ast.Range
+}
+
+func (f testStackTraceFrame) FunctionName() string {
+	return f.function
+}
+
+func (f testStackTraceFrame) ImportLocation() common.Location {
+	return f.location
+}
+
+type testStackTraceError struct {
+	testError
+	frames []errors.StackTraceFrame
+}
+
+func (e testStackTraceError) StackTraceFrames() []errors.StackTraceFrame {
+	return e.frames
+}
+
+func TestPrintStackTrace(t *testing.T) {
+
+	t.Parallel()
+
+	location := common.StringLocation("test")
+
+	position := func(line, column int) ast.Range {
+		return ast.Range{
+			StartPos: ast.Position{
+				Line:   line,
+				Column: column,
+			},
+			EndPos: ast.Position{
+				Line:   line,
+				Column: column,
+			},
+		}
+	}
+
+	t.Run("multiple frames", func(t *testing.T) {
+
+		t.Parallel()
+
+		var sb strings.Builder
+		printer := NewErrorPrettyPrinter(&sb, false)
+		err := printer.PrettyPrintError(
+			testStackTraceError{
+				testError: testError{
+					Range: position(2, 4),
+				},
+				frames: []errors.StackTraceFrame{
+					testStackTraceFrame{
+						function: "Test.withdraw",
+						location: common.StringLocation("imported"),
+						Range:    position(2, 4),
+					},
+					testStackTraceFrame{
+						function: "transfer",
+						Range:    position(5, 8),
+					},
+					testStackTraceFrame{
+						Range: position(9, 4),
+					},
+				},
+			},
+			location,
+			nil,
+		)
+		require.NoError(t, err)
+		require.Equal(t,
+			"error: test error\n"+
+				" --> test:2:4\n"+
+				"\n"+
+				"stack trace:\n"+
+				"Test.withdraw(...)\n"+
+				"\timported:2:4\n"+
+				"transfer(...)\n"+
+				"\ttest:5:8\n"+
+				"<unknown>\n"+
+				"\ttest:9:4\n",
+			sb.String(),
+		)
+	})
+
+	t.Run("single frame", func(t *testing.T) {
+
+		t.Parallel()
+
+		var sb strings.Builder
+		printer := NewErrorPrettyPrinter(&sb, false)
+		err := printer.PrettyPrintError(
+			testStackTraceError{
+				testError: testError{
+					Range: position(2, 4),
+				},
+				frames: []errors.StackTraceFrame{
+					testStackTraceFrame{
+						Range: position(2, 4),
+					},
+				},
+			},
+			location,
+			nil,
+		)
+		require.NoError(t, err)
+		require.Equal(t,
+			"error: test error\n"+
+				" --> test:2:4\n",
+			sb.String(),
+		)
+	})
+}
@@ -0,0 +1,348 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+// rootProfileFrameName is the name of the frame which computation and memory
+// are attributed to when no function is invoked, e.g. while parsing and checking
+const rootProfileFrameName = "[root]"
+
+// maxProfileFunctionNameLength is the maximum length of a function name in a profile.
+// Longer names, e.g. of invoked function expressions, are truncated
+const maxProfileFunctionNameLength = 80
+
+// ProfileFrame is a frame of a profiled call stack,
+// the invocation of a function.
+type ProfileFrame struct {
+	// Function is the invoked expression, e.g. `Foo.bar`.
+	// It is empty if the function was invoked by the runtime, e.g. a script's `main` function
+	Function string
+	// Location is the location of the invocation
+	Location common.Location
+	// Line is the line of the invocation.
+	// It is 0 if the function was invoked by the runtime
+	Line int
+	// Column is the column of the invocation
+	Column int
+}
+
+// String returns the name of the frame in profiles,
+// e.g. `Foo.bar (S.test.cdc:3:4)`.
+func (f ProfileFrame) String() string {
+	var location string
+	if f.Location != nil {
+		location = f.Location.ID()
+	}
+
+	var name string
+	switch {
+	case f.Line == 0:
+		name = location
+	case f.Function == "":
+		name = fmt.Sprintf("%s:%d:%d", location, f.Line, f.Column)
+	default:
+		name = fmt.Sprintf("%s (%s:%d:%d)", f.Function, location, f.Line, f.Column)
+	}
+
+	// Semicolons separate frames in the folded format
+	return strings.ReplaceAll(name, ";", ",")
+}
+
+// ProfileSample is the computation and memory
+// attributed to a call stack.
+type ProfileSample struct {
+	// Stack is the call stack.
+	// The first frame is the outermost function invocation.
+	// The stack is empty for computation and memory which occurred
+	// outside of function invocations
+	Stack []ProfileFrame
+	// Computation is the metered computation intensity, by kind
+	Computation map[common.ComputationKind]uint64
+	// Memory is the metered memory amount, by kind
+	Memory map[common.MemoryKind]uint64
+}
+
+// TotalComputation returns the total metered computation intensity of all kinds.
+func (s ProfileSample) TotalComputation() uint64 {
+	var total uint64
+	for _, intensity := range s.Computation { //nolint:maprange
+		total += intensity
+	}
+	return total
+}
+
+// TotalMemory returns the total metered memory amount of all kinds.
+func (s ProfileSample) TotalMemory() uint64 {
+	var total uint64
+	for _, amount := range s.Memory { //nolint:maprange
+		total += amount
+	}
+	return total
+}
+
+// Profiler attributes metered computation and memory to call stacks.
+//
+// Each frame of a call stack is the invocation of a function,
+// so the computation and memory of a function include the cost of evaluating its arguments,
+// and the cost of the invocations it performs.
+//
+// Profiles can be written in the pprof format, e.g. to be analyzed with `go tool pprof`,
+// and in the folded stack format, e.g. to be rendered as a flame graph.
+type Profiler struct {
+	mutex sync.Mutex
+	root  *profileNode
+}
+
+// profileNode is a node in the tree of call stacks
+type profileNode struct {
+	frame       ProfileFrame
+	children    map[ProfileFrame]*profileNode
+	computation map[common.ComputationKind]uint64
+	memory      map[common.MemoryKind]uint64
+}
+
+func newProfileNode(frame ProfileFrame) *profileNode {
+	return &profileNode{
+		frame:       frame,
+		children:    map[ProfileFrame]*profileNode{},
+		computation: map[common.ComputationKind]uint64{},
+		memory:      map[common.MemoryKind]uint64{},
+	}
+}
+
+// NewProfiler creates and returns a new profiler.
+// Set it in the runtime configuration to collect profiles.
+func NewProfiler() *Profiler {
+	return &Profiler{
+		root: newProfileNode(ProfileFrame{}),
+	}
+}
+
+// Reset discards all collected samples.
+func (p *Profiler) Reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.root = newProfileNode(ProfileFrame{})
+}
+
+// MeterComputation attributes the given computation to the given call stack.
+func (p *Profiler) MeterComputation(
+	callStack []interpreter.Invocation,
+	kind common.ComputationKind,
+	intensity uint,
+) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.node(callStack).computation[kind] += uint64(intensity)
+}
+
+// MeterMemory attributes the given memory usage to the given call stack.
+func (p *Profiler) MeterMemory(
+	callStack []interpreter.Invocation,
+	usage common.MemoryUsage,
+) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.node(callStack).memory[usage.Kind] += usage.Amount
+}
+
+// node returns the node for the given call stack, creating it if needed
+func (p *Profiler) node(callStack []interpreter.Invocation) *profileNode {
+	node := p.root
+	for _, invocation := range callStack {
+		frame := newProfileFrame(invocation)
+
+		child, ok := node.children[frame]
+		if !ok {
+			child = newProfileNode(frame)
+			node.children[frame] = child
+		}
+		node = child
+	}
+	return node
+}
+
+func newProfileFrame(invocation interpreter.Invocation) ProfileFrame {
+	locationRange := invocation.LocationRange
+
+	// Functions invoked by the runtime have no invocation location
+	if locationRange.HasPosition == nil {
+		var location common.Location
+		if invocation.Interpreter != nil {
+			location = invocation.Interpreter.Location
+		}
+		return ProfileFrame{
+			Location: location,
+		}
+	}
+
+	position := locationRange.StartPosition()
+
+	return ProfileFrame{
+		Function: profileFunctionName(locationRange.HasPosition),
+		Location: locationRange.Location,
+		Line:     position.Line,
+		Column:   position.Column,
+	}
+}
+
+// profileFunctionName returns the name of the function invoked at the given element
+func profileFunctionName(element ast.HasPosition) string {
+	invocationExpression, ok := element.(*ast.InvocationExpression)
+	if !ok {
+		return ""
+	}
+
+	name := invocationExpression.InvokedExpression.String()
+	if index := strings.IndexByte(name, '\n'); index >= 0 {
+		name = name[:index]
+	}
+	if runes := []rune(name); len(runes) > maxProfileFunctionNameLength {
+		name = string(runes[:maxProfileFunctionNameLength]) + "…"
+	}
+	return name
+}
+
+// Samples returns the collected samples,
+// ordered by call stack, callers before callees.
+// Call stacks without any metered computation and memory are omitted.
+func (p *Profiler) Samples() []ProfileSample {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var samples []ProfileSample
+	p.root.collectSamples(nil, &samples)
+	return samples
+}
+
+func (n *profileNode) collectSamples(stack []ProfileFrame, samples *[]ProfileSample) {
+	if len(n.computation) > 0 || len(n.memory) > 0 {
+		sample := ProfileSample{
+			Stack:       append([]ProfileFrame(nil), stack...),
+			Computation: make(map[common.ComputationKind]uint64, len(n.computation)),
+			Memory:      make(map[common.MemoryKind]uint64, len(n.memory)),
+		}
+		for kind, intensity := range n.computation { //nolint:maprange
+			sample.Computation[kind] = intensity
+		}
+		for kind, amount := range n.memory { //nolint:maprange
+			sample.Memory[kind] = amount
+		}
+		*samples = append(*samples, sample)
+	}
+
+	children := make([]*profileNode, 0, len(n.children))
+	for _, child := range n.children { //nolint:maprange
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return compareProfileFrames(children[i].frame, children[j].frame) < 0
+	})
+
+	for _, child := range children {
+		child.collectSamples(append(stack, child.frame), samples)
+	}
+}
+
+func compareProfileFrames(a, b ProfileFrame) int {
+	var aLocation, bLocation string
+	if a.Location != nil {
+		aLocation = a.Location.ID()
+	}
+	if b.Location != nil {
+		bLocation = b.Location.ID()
+	}
+
+	switch {
+	case aLocation != bLocation:
+		return strings.Compare(aLocation, bLocation)
+	case a.Line != b.Line:
+		return a.Line - b.Line
+	case a.Column != b.Column:
+		return a.Column - b.Column
+	default:
+		return strings.Compare(a.Function, b.Function)
+	}
+}
+
+// WriteFoldedComputation writes the total computation of each call stack
+// in the folded stack format, which is e.g. supported by flame graph tools:
+// One line per call stack, the frames separated by semicolons, followed by the value.
+func (p *Profiler) WriteFoldedComputation(w io.Writer) error {
+	return writeFolded(w, p.Samples(), ProfileSample.TotalComputation)
+}
+
+// WriteFoldedMemory writes the total memory of each call stack
+// in the folded stack format, see WriteFoldedComputation.
+func (p *Profiler) WriteFoldedMemory(w io.Writer) error {
+	return writeFolded(w, p.Samples(), ProfileSample.TotalMemory)
+}
+
+func writeFolded(w io.Writer, samples []ProfileSample, value func(ProfileSample) uint64) error {
+	writer := bufio.NewWriter(w)
+
+	for _, sample := range samples {
+		total := value(sample)
+		if total == 0 {
+			continue
+		}
+
+		if len(sample.Stack) == 0 {
+			_, err := writer.WriteString(rootProfileFrameName)
+			if err != nil {
+				return err
+			}
+		}
+
+		for i, frame := range sample.Stack {
+			if i > 0 {
+				err := writer.WriteByte(';')
+				if err != nil {
+					return err
+				}
+			}
+			_, err := writer.WriteString(frame.String())
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := fmt.Fprintf(writer, " %d\n", total)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,321 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// Field numbers of the pprof profile protobuf messages,
+// see https://github.com/google/pprof/blob/main/proto/profile.proto
+const (
+	pprofProfileSampleType        = 1
+	pprofProfileSample            = 2
+	pprofProfileLocation          = 4
+	pprofProfileFunction          = 5
+	pprofProfileStringTable       = 6
+	pprofProfileDefaultSampleType = 14
+
+	pprofValueTypeType = 1
+	pprofValueTypeUnit = 2
+
+	pprofSampleLocationID = 1
+	pprofSampleValue      = 2
+
+	pprofLocationID   = 1
+	pprofLocationLine = 4
+
+	pprofLineFunctionID = 1
+	pprofLineLine       = 2
+
+	pprofFunctionID       = 1
+	pprofFunctionName     = 2
+	pprofFunctionFilename = 4
+)
+
+const (
+	pprofComputationType = "computation"
+	pprofMemoryType      = "memory"
+	pprofUnit            = "count"
+)
+
+// WritePprof writes the collected samples as a gzip-compressed pprof profile.
+//
+// The profile has the sample types `computation` and `memory`,
+// which are the totals of all kinds, and one sample type per metered kind,
+// e.g. `computation_Statement` and `memory_StringValue`.
+// The default sample type is `computation`.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	samples := p.Samples()
+
+	encoder := newPprofEncoder()
+	encoder.encode(samples)
+
+	writer := gzip.NewWriter(w)
+
+	_, err := writer.Write(encoder.buffer.bytes)
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+type pprofFunctionKey struct {
+	name     string
+	filename string
+}
+
+type pprofLocationKey struct {
+	functionID uint64
+	line       int
+}
+
+type pprofEncoder struct {
+	buffer      protobufBuffer
+	strings     []string
+	stringIndex map[string]int64
+	functions   map[pprofFunctionKey]uint64
+	locations   map[pprofLocationKey]uint64
+}
+
+func newPprofEncoder() *pprofEncoder {
+	return &pprofEncoder{
+		// The first string in the string table must be the empty string
+		strings:     []string{""},
+		stringIndex: map[string]int64{"": 0},
+		functions:   map[pprofFunctionKey]uint64{},
+		locations:   map[pprofLocationKey]uint64{},
+	}
+}
+
+func (e *pprofEncoder) encode(samples []ProfileSample) {
+
+	// Determine the metered kinds, in a deterministic order
+
+	computationKindSet := map[common.ComputationKind]struct{}{}
+	memoryKindSet := map[common.MemoryKind]struct{}{}
+
+	for _, sample := range samples {
+		for kind := range sample.Computation { //nolint:maprange
+			computationKindSet[kind] = struct{}{}
+		}
+		for kind := range sample.Memory { //nolint:maprange
+			memoryKindSet[kind] = struct{}{}
+		}
+	}
+
+	computationKinds := make([]common.ComputationKind, 0, len(computationKindSet))
+	for kind := range computationKindSet { //nolint:maprange
+		computationKinds = append(computationKinds, kind)
+	}
+	sort.Slice(computationKinds, func(i, j int) bool {
+		return computationKinds[i] < computationKinds[j]
+	})
+
+	memoryKinds := make([]common.MemoryKind, 0, len(memoryKindSet))
+	for kind := range memoryKindSet { //nolint:maprange
+		memoryKinds = append(memoryKinds, kind)
+	}
+	sort.Slice(memoryKinds, func(i, j int) bool {
+		return memoryKinds[i] < memoryKinds[j]
+	})
+
+	// Sample types
+
+	e.encodeSampleType(pprofComputationType)
+	e.encodeSampleType(pprofMemoryType)
+	for _, kind := range computationKinds {
+		e.encodeSampleType(pprofComputationType + "_" + kind.String())
+	}
+	for _, kind := range memoryKinds {
+		e.encodeSampleType(pprofMemoryType + "_" + kind.String())
+	}
+
+	// Samples
+
+	for _, sample := range samples {
+		values := make([]uint64, 0, 2+len(computationKinds)+len(memoryKinds))
+		values = append(values, sample.TotalComputation(), sample.TotalMemory())
+		for _, kind := range computationKinds {
+			values = append(values, sample.Computation[kind])
+		}
+		for _, kind := range memoryKinds {
+			values = append(values, sample.Memory[kind])
+		}
+
+		// Locations are listed from the innermost frame to the outermost frame
+
+		var locationIDs []uint64
+		if len(sample.Stack) == 0 {
+			locationIDs = []uint64{
+				e.locationID(rootProfileFrameName, "", 0),
+			}
+		} else {
+			locationIDs = make([]uint64, len(sample.Stack))
+			for i, frame := range sample.Stack {
+				var filename string
+				if frame.Location != nil {
+					filename = frame.Location.ID()
+				}
+				locationIDs[len(sample.Stack)-1-i] =
+					e.locationID(frame.String(), filename, frame.Line)
+			}
+		}
+
+		var message protobufBuffer
+		message.packedUint64s(pprofSampleLocationID, locationIDs)
+		message.packedUint64s(pprofSampleValue, values)
+		e.buffer.message(pprofProfileSample, message)
+	}
+
+	e.buffer.int64(pprofProfileDefaultSampleType, e.stringID(pprofComputationType))
+
+	// The string table must be encoded last,
+	// as encoding the other messages adds strings
+
+	for _, s := range e.strings {
+		e.buffer.string(pprofProfileStringTable, s)
+	}
+}
+
+func (e *pprofEncoder) encodeSampleType(name string) {
+	var message protobufBuffer
+	message.int64(pprofValueTypeType, e.stringID(name))
+	message.int64(pprofValueTypeUnit, e.stringID(pprofUnit))
+	e.buffer.message(pprofProfileSampleType, message)
+}
+
+func (e *pprofEncoder) stringID(s string) int64 {
+	id, ok := e.stringIndex[s]
+	if !ok {
+		id = int64(len(e.strings))
+		e.strings = append(e.strings, s)
+		e.stringIndex[s] = id
+	}
+	return id
+}
+
+// locationID returns the ID of the location for the given frame,
+// encoding the location and its function if needed
+func (e *pprofEncoder) locationID(name string, filename string, line int) uint64 {
+	functionKey := pprofFunctionKey{
+		name:     name,
+		filename: filename,
+	}
+
+	functionID, ok := e.functions[functionKey]
+	if !ok {
+		functionID = uint64(len(e.functions) + 1)
+		e.functions[functionKey] = functionID
+
+		var message protobufBuffer
+		message.uint64(pprofFunctionID, functionID)
+		message.int64(pprofFunctionName, e.stringID(name))
+		message.int64(pprofFunctionFilename, e.stringID(filename))
+		e.buffer.message(pprofProfileFunction, message)
+	}
+
+	locationKey := pprofLocationKey{
+		functionID: functionID,
+		line:       line,
+	}
+
+	locationID, ok := e.locations[locationKey]
+	if !ok {
+		locationID = uint64(len(e.locations) + 1)
+		e.locations[locationKey] = locationID
+
+		var lineMessage protobufBuffer
+		lineMessage.uint64(pprofLineFunctionID, functionID)
+		lineMessage.int64(pprofLineLine, int64(line))
+
+		var message protobufBuffer
+		message.uint64(pprofLocationID, locationID)
+		message.message(pprofLocationLine, lineMessage)
+		e.buffer.message(pprofProfileLocation, message)
+	}
+
+	return locationID
+}
+
+// protobufBuffer encodes protobuf messages.
+// Only the wire types needed for pprof profiles are supported
+type protobufBuffer struct {
+	bytes []byte
+}
+
+const (
+	protobufWireTypeVarint          = 0
+	protobufWireTypeLengthDelimited = 2
+)
+
+func (b *protobufBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.bytes = append(b.bytes, byte(x)|0x80)
+		x >>= 7
+	}
+	b.bytes = append(b.bytes, byte(x))
+}
+
+func (b *protobufBuffer) tag(field int, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+// uint64 encodes the given value, if it is not the default value
+func (b *protobufBuffer) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.tag(field, protobufWireTypeVarint)
+	b.varint(x)
+}
+
+// int64 encodes the given value, if it is not the default value
+func (b *protobufBuffer) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *protobufBuffer) packedUint64s(field int, xs []uint64) {
+	if len(xs) == 0 {
+		return
+	}
+
+	var packed protobufBuffer
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	b.message(field, packed)
+}
+
+func (b *protobufBuffer) string(field int, s string) {
+	b.tag(field, protobufWireTypeLengthDelimited)
+	b.varint(uint64(len(s)))
+	b.bytes = append(b.bytes, s...)
+}
+
+func (b *protobufBuffer) message(field int, message protobufBuffer) {
+	b.tag(field, protobufWireTypeLengthDelimited)
+	b.varint(uint64(len(message.bytes)))
+	b.bytes = append(b.bytes, message.bytes...)
+}
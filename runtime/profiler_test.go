@@ -0,0 +1,550 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence"
+	. "github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/tests/runtime_utils"
+)
+
+func executeProfiledScript(t *testing.T, profiler *Profiler) {
+
+	library := []byte(`
+      access(all) fun fib(_ n: Int): Int {
+          if n < 2 {
+              return n
+          }
+          return fib(n - 1) + fib(n - 2)
+      }
+    `)
+
+	script := []byte(`
+      import "Library"
+
+      access(all) fun main(): Int {
+          let a = fib(4)
+          let b = double(a)
+          return b
+      }
+
+      access(all) fun double(_ n: Int): Int {
+          return n * 2
+      }
+    `)
+
+	runtimeInterface := &TestRuntimeInterface{
+		OnGetCode: func(location Location) ([]byte, error) {
+			switch location {
+			case common.StringLocation("Library"):
+				return library, nil
+			default:
+				return nil, fmt.Errorf("unknown import location: %s", location)
+			}
+		},
+	}
+
+	config := DefaultTestInterpreterConfig
+	config.Profiler = profiler
+	runtime := NewTestInterpreterRuntimeWithConfig(config)
+
+	value, err := runtime.ExecuteScript(
+		Script{
+			Source: script,
+		},
+		Context{
+			Interface: runtimeInterface,
+			Location:  common.ScriptLocation{},
+		},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, cadence.NewInt(6), value)
+}
+
+func TestRuntimeProfilerSamples(t *testing.T) {
+
+	t.Parallel()
+
+	profiler := NewProfiler()
+	executeProfiledScript(t, profiler)
+
+	computationByStack := map[string]map[common.ComputationKind]uint64{}
+	var totalMemory uint64
+
+	for _, sample := range profiler.Samples() {
+		frames := make([]string, len(sample.Stack))
+		for i, frame := range sample.Stack {
+			frames[i] = frame.String()
+		}
+		if sample.TotalComputation() > 0 {
+			computationByStack[strings.Join(frames, ";")] = sample.Computation
+		}
+		totalMemory += sample.TotalMemory()
+	}
+
+	// Script locations have long IDs
+	expand := strings.NewReplacer("S.Script", common.ScriptLocation{}.ID()).Replace
+
+	assert.Equal(t,
+		map[string]map[common.ComputationKind]uint64{
+			expand("S.Script"): {
+				common.ComputationKindStatement:          3,
+				common.ComputationKindFunctionInvocation: 2,
+			},
+			expand("S.Script;fib (S.Script:5:18)"): {
+				common.ComputationKindStatement:          2,
+				common.ComputationKindFunctionInvocation: 2,
+			},
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:17)"): {
+				common.ComputationKindStatement:          2,
+				common.ComputationKindFunctionInvocation: 2,
+			},
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:17)"): {
+				common.ComputationKindStatement:          2,
+				common.ComputationKindFunctionInvocation: 2,
+			},
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:17);fib (S.Library:6:17)"): {
+				common.ComputationKindStatement: 2,
+			},
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:17);fib (S.Library:6:30)"): {
+				common.ComputationKindStatement: 2,
+			},
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:30)"): {
+				common.ComputationKindStatement: 2,
+			},
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:30)"): {
+				common.ComputationKindStatement:          2,
+				common.ComputationKindFunctionInvocation: 2,
+			},
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:30);fib (S.Library:6:17)"): {
+				common.ComputationKindStatement: 2,
+			},
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:30);fib (S.Library:6:30)"): {
+				common.ComputationKindStatement: 2,
+			},
+			expand("S.Script;double (S.Script:6:18)"): {
+				common.ComputationKindStatement: 1,
+			},
+		},
+		computationByStack,
+	)
+
+	assert.NotZero(t, totalMemory)
+
+	profiler.Reset()
+	assert.Empty(t, profiler.Samples())
+}
+
+func TestRuntimeProfilerFolded(t *testing.T) {
+
+	t.Parallel()
+
+	profiler := NewProfiler()
+	executeProfiledScript(t, profiler)
+
+	var computation bytes.Buffer
+	err := profiler.WriteFoldedComputation(&computation)
+	require.NoError(t, err)
+
+	// Script locations have long IDs
+	expand := strings.NewReplacer("S.Script", common.ScriptLocation{}.ID()).Replace
+
+	assert.Equal(t,
+		expand(`S.Script 5
+S.Script;fib (S.Script:5:18) 4
+S.Script;fib (S.Script:5:18);fib (S.Library:6:17) 4
+S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:17) 4
+S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:17);fib (S.Library:6:17) 2
+S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:17);fib (S.Library:6:30) 2
+S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:30) 2
+S.Script;fib (S.Script:5:18);fib (S.Library:6:30) 4
+S.Script;fib (S.Script:5:18);fib (S.Library:6:30);fib (S.Library:6:17) 2
+S.Script;fib (S.Script:5:18);fib (S.Library:6:30);fib (S.Library:6:30) 2
+S.Script;double (S.Script:6:18) 1
+`),
+		computation.String(),
+	)
+
+	var memory bytes.Buffer
+	err = profiler.WriteFoldedMemory(&memory)
+	require.NoError(t, err)
+
+	// Parsing and checking happens outside of any function invocation
+	assert.True(t, strings.HasPrefix(memory.String(), "[root] "))
+	assert.Contains(t, memory.String(), expand("\nS.Script;fib (S.Script:5:18) "))
+}
+
+// pprofTestProfile is a pprof profile decoded for tests,
+// with all references to strings, locations, and functions resolved
+type pprofTestProfile struct {
+	SampleTypes       []pprofTestValueType
+	Samples           []pprofTestSample
+	DefaultSampleType string
+}
+
+type pprofTestValueType struct {
+	Type string
+	Unit string
+}
+
+type pprofTestSample struct {
+	// Locations are listed from the innermost frame to the outermost frame
+	Locations []pprofTestLocation
+	Values    []uint64
+}
+
+type pprofTestLocation struct {
+	FunctionName string
+	Filename     string
+	Line         int64
+}
+
+// decodeTestPprof decodes a gzip-compressed pprof profile,
+// see https://github.com/google/pprof/blob/main/proto/profile.proto.
+//
+// Only the messages and fields written by the profiler are decoded,
+// and the profile is checked to only contain valid references
+func decodeTestPprof(t *testing.T, r io.Reader) pprofTestProfile {
+
+	reader, err := gzip.NewReader(r)
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	type valueType struct {
+		typ  uint64
+		unit uint64
+	}
+
+	type sample struct {
+		locationIDs []uint64
+		values      []uint64
+	}
+
+	type line struct {
+		functionID uint64
+		line       uint64
+	}
+
+	type function struct {
+		name     uint64
+		filename uint64
+	}
+
+	var sampleTypes []valueType
+	var samples []sample
+	locationLines := map[uint64][]line{}
+	functions := map[uint64]function{}
+	var stringTable []string
+	var defaultSampleType uint64
+
+	forEachProtobufField(t, data, func(field int, value uint64, message []byte) {
+		switch field {
+		case 1:
+			var sampleType valueType
+			forEachProtobufField(t, message, func(field int, value uint64, _ []byte) {
+				switch field {
+				case 1:
+					sampleType.typ = value
+				case 2:
+					sampleType.unit = value
+				}
+			})
+			sampleTypes = append(sampleTypes, sampleType)
+
+		case 2:
+			var s sample
+			forEachProtobufField(t, message, func(field int, _ uint64, packed []byte) {
+				switch field {
+				case 1:
+					s.locationIDs = decodePackedProtobufVarints(t, packed)
+				case 2:
+					s.values = decodePackedProtobufVarints(t, packed)
+				}
+			})
+			samples = append(samples, s)
+
+		case 4:
+			var id uint64
+			var lines []line
+			forEachProtobufField(t, message, func(field int, value uint64, message []byte) {
+				switch field {
+				case 1:
+					id = value
+				case 4:
+					var l line
+					forEachProtobufField(t, message, func(field int, value uint64, _ []byte) {
+						switch field {
+						case 1:
+							l.functionID = value
+						case 2:
+							l.line = value
+						}
+					})
+					lines = append(lines, l)
+				}
+			})
+			require.NotZero(t, id)
+			require.NotContains(t, locationLines, id)
+			locationLines[id] = lines
+
+		case 5:
+			var id uint64
+			var f function
+			forEachProtobufField(t, message, func(field int, value uint64, _ []byte) {
+				switch field {
+				case 1:
+					id = value
+				case 2:
+					f.name = value
+				case 4:
+					f.filename = value
+				}
+			})
+			require.NotZero(t, id)
+			require.NotContains(t, functions, id)
+			functions[id] = f
+
+		case 6:
+			stringTable = append(stringTable, string(message))
+
+		case 14:
+			defaultSampleType = value
+		}
+	})
+
+	// The first string in the string table must be the empty string
+
+	require.NotEmpty(t, stringTable)
+	require.Equal(t, "", stringTable[0])
+
+	lookupString := func(index uint64) string {
+		require.Less(t, index, uint64(len(stringTable)))
+		return stringTable[index]
+	}
+
+	var profile pprofTestProfile
+
+	for _, sampleType := range sampleTypes {
+		profile.SampleTypes = append(
+			profile.SampleTypes,
+			pprofTestValueType{
+				Type: lookupString(sampleType.typ),
+				Unit: lookupString(sampleType.unit),
+			},
+		)
+	}
+
+	for _, s := range samples {
+		require.Len(t, s.values, len(sampleTypes))
+
+		locations := make([]pprofTestLocation, len(s.locationIDs))
+		for i, locationID := range s.locationIDs {
+			require.Contains(t, locationLines, locationID)
+			lines := locationLines[locationID]
+
+			require.Len(t, lines, 1)
+			l := lines[0]
+
+			require.Contains(t, functions, l.functionID)
+			f := functions[l.functionID]
+
+			locations[i] = pprofTestLocation{
+				FunctionName: lookupString(f.name),
+				Filename:     lookupString(f.filename),
+				Line:         int64(l.line),
+			}
+		}
+
+		profile.Samples = append(
+			profile.Samples,
+			pprofTestSample{
+				Locations: locations,
+				Values:    s.values,
+			},
+		)
+	}
+
+	profile.DefaultSampleType = lookupString(defaultSampleType)
+
+	return profile
+}
+
+// forEachProtobufField calls the given function for each field of the given protobuf message.
+// The value is only set for varint fields, the message is only set for length-delimited fields
+func forEachProtobufField(
+	t *testing.T,
+	data []byte,
+	f func(field int, value uint64, message []byte),
+) {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		require.Positive(t, n)
+		data = data[n:]
+
+		field := int(key >> 3)
+
+		switch wireType := key & 0x7; wireType {
+		case 0:
+			value, n := binary.Uvarint(data)
+			require.Positive(t, n)
+			data = data[n:]
+
+			f(field, value, nil)
+
+		case 2:
+			length, n := binary.Uvarint(data)
+			require.Positive(t, n)
+			data = data[n:]
+
+			require.LessOrEqual(t, length, uint64(len(data)))
+			f(field, 0, data[:length])
+			data = data[length:]
+
+		default:
+			require.FailNow(t, "unsupported wire type", "field %d: %d", field, wireType)
+		}
+	}
+}
+
+func decodePackedProtobufVarints(t *testing.T, data []byte) []uint64 {
+	var values []uint64
+	for len(data) > 0 {
+		value, n := binary.Uvarint(data)
+		require.Positive(t, n)
+		data = data[n:]
+
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestRuntimeProfilerPprof(t *testing.T) {
+
+	t.Parallel()
+
+	profiler := NewProfiler()
+	executeProfiledScript(t, profiler)
+
+	var buffer bytes.Buffer
+	err := profiler.WritePprof(&buffer)
+	require.NoError(t, err)
+
+	parsedProfile := decodeTestPprof(t, &buffer)
+
+	// Sample types
+
+	sampleTypes := make([]string, len(parsedProfile.SampleTypes))
+	for i, sampleType := range parsedProfile.SampleTypes {
+		assert.Equal(t, "count", sampleType.Unit)
+		sampleTypes[i] = sampleType.Type
+	}
+
+	require.GreaterOrEqual(t, len(sampleTypes), 4)
+	assert.Equal(t, []string{"computation", "memory"}, sampleTypes[:2])
+	assert.Contains(t, sampleTypes, "computation_Statement")
+	assert.Contains(t, sampleTypes, "computation_FunctionInvocation")
+	assert.Contains(t, sampleTypes, "memory_Program")
+	assert.Equal(t, "computation", parsedProfile.DefaultSampleType)
+
+	statementIndex := -1
+	for i, sampleType := range sampleTypes {
+		if sampleType == "computation_Statement" {
+			statementIndex = i
+		}
+	}
+
+	// Stacks.
+	// Locations are listed from the innermost frame to the outermost frame
+
+	computationByStack := map[string]uint64{}
+	statementsByStack := map[string]uint64{}
+	lines := map[string]int64{}
+	var rootMemory uint64
+
+	for _, sample := range parsedProfile.Samples {
+		frames := make([]string, len(sample.Locations))
+		for i, location := range sample.Locations {
+			frames[len(frames)-1-i] = location.FunctionName
+
+			if location.FunctionName != "[root]" {
+				assert.NotEmpty(t, location.Filename)
+			}
+			lines[location.FunctionName] = location.Line
+		}
+		stack := strings.Join(frames, ";")
+
+		if stack == "[root]" {
+			rootMemory += sample.Values[1]
+		}
+
+		if sample.Values[0] > 0 {
+			computationByStack[stack] += sample.Values[0]
+		}
+		if sample.Values[statementIndex] > 0 {
+			statementsByStack[stack] += sample.Values[statementIndex]
+		}
+	}
+
+	// Script locations have long IDs
+	expand := strings.NewReplacer("S.Script", common.ScriptLocation{}.ID()).Replace
+
+	assert.Equal(t,
+		map[string]uint64{
+			expand("S.Script"):                                                                                    5,
+			expand("S.Script;fib (S.Script:5:18)"):                                                                4,
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:17)"):                                           4,
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:17)"):                      4,
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:17);fib (S.Library:6:17)"): 2,
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:17);fib (S.Library:6:30)"): 2,
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:17);fib (S.Library:6:30)"):                      2,
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:30)"):                                           4,
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:30);fib (S.Library:6:17)"):                      2,
+			expand("S.Script;fib (S.Script:5:18);fib (S.Library:6:30);fib (S.Library:6:30)"):                      2,
+			expand("S.Script;double (S.Script:6:18)"):                                                             1,
+		},
+		computationByStack,
+	)
+
+	assert.Equal(t, uint64(1), statementsByStack[expand("S.Script;double (S.Script:6:18)")])
+	assert.Equal(t, uint64(3), statementsByStack[expand("S.Script")])
+
+	// Locations have the line of the invocation
+
+	assert.Equal(t, int64(5), lines[expand("fib (S.Script:5:18)")])
+	assert.Equal(t, int64(6), lines[expand("fib (S.Library:6:30)")])
+	assert.Equal(t, int64(6), lines[expand("double (S.Script:6:18)")])
+
+	// Parsing and checking happens outside of any function invocation
+	assert.NotZero(t, rootMemory)
+}
@@ -0,0 +1,563 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package programcache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// BinaryCodec is a Codec which encodes the AST and the elaboration of a program
+// in a deterministic binary format.
+//
+// The AST is encoded generically, field by field.
+// Elements which are referenced multiple times, e.g. by the elaboration,
+// are encoded once and referenced by index afterwards.
+//
+// The types declared by the program are encoded completely.
+// Builtin types are encoded by their type ID,
+// and types declared by other programs by their location and type ID.
+// Other programs are dependencies of the program: They are resolved when decoding.
+//
+// The format depends on the AST and the sema types of the Cadence version which encodes the program,
+// so entries are only valid for the same Cadence version (see Key).
+// Changes of the format itself, e.g. of tags, require incrementing BinaryCodecVersion.
+//
+// Checker-only information is not encoded: Argument checks of builtin function types,
+// and the cache of semantic accesses of the elaboration.
+type BinaryCodec struct{}
+
+var _ Codec = BinaryCodec{}
+
+// BinaryCodecVersion is the version of the encoding of BinaryCodec
+const BinaryCodecVersion uint16 = 1
+
+func (BinaryCodec) Version() uint16 {
+	return BinaryCodecVersion
+}
+
+func (BinaryCodec) EncodeProgram(
+	location common.Location,
+	program *interpreter.Program,
+) (
+	data []byte,
+	imports []common.Location,
+	err error,
+) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+		}
+	}()
+
+	encoder := newProgramEncoder(location)
+	encoder.encodeProgram(program)
+
+	return encoder.buffer.Bytes(), encoder.imports, nil
+}
+
+func (BinaryCodec) DecodeProgram(
+	location common.Location,
+	data []byte,
+	resolver Resolver,
+) (
+	program *interpreter.Program,
+	err error,
+) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+		}
+	}()
+
+	decoder := newProgramDecoder(location, data, resolver)
+	program = decoder.decodeProgram()
+
+	return program, nil
+}
+
+// UnsupportedProgramError is returned when a program cannot be encoded,
+// e.g. because it refers to a builtin type which is not known to the codec.
+type UnsupportedProgramError struct {
+	Reason string
+}
+
+func (e UnsupportedProgramError) Error() string {
+	return fmt.Sprintf("unsupported program: %s", e.Reason)
+}
+
+// codecError wraps errors which are raised while encoding or decoding,
+// and which are returned by the codec
+type codecError struct {
+	err error
+}
+
+func recoveredError(r any) error {
+	switch r := r.(type) {
+	case codecError:
+		return r.err
+	case error:
+		return InvalidEntryError{Reason: r.Error()}
+	default:
+		return InvalidEntryError{Reason: fmt.Sprint(r)}
+	}
+}
+
+func unsupported(format string, args ...any) {
+	panic(codecError{
+		err: UnsupportedProgramError{Reason: fmt.Sprintf(format, args...)},
+	})
+}
+
+func invalid(format string, args ...any) {
+	panic(codecError{
+		err: InvalidEntryError{Reason: fmt.Sprintf(format, args...)},
+	})
+}
+
+// encoder writes the primitive values of the format.
+// All integers are varint-encoded
+type encoder struct {
+	buffer  bytes.Buffer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) writeUint(value uint64) {
+	n := binary.PutUvarint(e.scratch[:], value)
+	e.buffer.Write(e.scratch[:n])
+}
+
+func (e *encoder) writeInt(value int64) {
+	n := binary.PutVarint(e.scratch[:], value)
+	e.buffer.Write(e.scratch[:n])
+}
+
+func (e *encoder) writeBool(value bool) {
+	if value {
+		e.buffer.WriteByte(1)
+	} else {
+		e.buffer.WriteByte(0)
+	}
+}
+
+func (e *encoder) writeBytes(value []byte) {
+	e.writeUint(uint64(len(value)))
+	e.buffer.Write(value)
+}
+
+func (e *encoder) writeString(value string) {
+	e.writeUint(uint64(len(value)))
+	e.buffer.WriteString(value)
+}
+
+func (e *encoder) writeBigInt(value *big.Int) {
+	if value == nil {
+		e.writeUint(0)
+		return
+	}
+	e.writeUint(uint64(value.Sign() + 2))
+	e.writeBytes(value.Bytes())
+}
+
+// Location tags are part of the encoding, see elementTag
+const (
+	locationTagNil         = 0
+	locationTagAddress     = 1
+	locationTagIdentifier  = 2
+	locationTagString      = 3
+	locationTagTransaction = 4
+	locationTagScript      = 5
+	locationTagREPL        = 6
+)
+
+func (e *encoder) writeLocation(location common.Location) {
+	switch location := location.(type) {
+	case nil:
+		e.writeUint(locationTagNil)
+
+	case common.AddressLocation:
+		e.writeUint(locationTagAddress)
+		e.writeBytes(location.Address[:])
+		e.writeString(location.Name)
+
+	case common.IdentifierLocation:
+		e.writeUint(locationTagIdentifier)
+		e.writeString(string(location))
+
+	case common.StringLocation:
+		e.writeUint(locationTagString)
+		e.writeString(string(location))
+
+	case common.TransactionLocation:
+		e.writeUint(locationTagTransaction)
+		e.writeBytes(location[:])
+
+	case common.ScriptLocation:
+		e.writeUint(locationTagScript)
+		e.writeBytes(location[:])
+
+	case common.REPLLocation:
+		e.writeUint(locationTagREPL)
+
+	default:
+		unsupported("location %s (%T)", location, location)
+	}
+}
+
+// decoder reads the primitive values of the format.
+// Reads past the end of the data are invalid
+type decoder struct {
+	data []byte
+}
+
+func (d *decoder) remaining() int {
+	return len(d.data)
+}
+
+func (d *decoder) readUint() uint64 {
+	value, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		invalid("truncated")
+	}
+	d.data = d.data[n:]
+	return value
+}
+
+func (d *decoder) readInt() int64 {
+	value, n := binary.Varint(d.data)
+	if n <= 0 {
+		invalid("truncated")
+	}
+	d.data = d.data[n:]
+	return value
+}
+
+func (d *decoder) readLength() int {
+	length := d.readUint()
+	if length > uint64(len(d.data)) {
+		invalid("truncated")
+	}
+	return int(length)
+}
+
+func (d *decoder) readBool() bool {
+	switch d.readUint() {
+	case 0:
+		return false
+	case 1:
+		return true
+	default:
+		invalid("invalid boolean")
+		return false
+	}
+}
+
+func (d *decoder) readBytes() []byte {
+	length := d.readLength()
+	result := d.data[:length:length]
+	d.data = d.data[length:]
+	return result
+}
+
+func (d *decoder) readString() string {
+	return string(d.readBytes())
+}
+
+func (d *decoder) readBigInt() *big.Int {
+	sign := d.readUint()
+	if sign == 0 {
+		return nil
+	}
+	value := new(big.Int).SetBytes(d.readBytes())
+	switch sign {
+	case 1:
+		value.Neg(value)
+	case 2, 3:
+		break
+	default:
+		invalid("invalid integer sign")
+	}
+	return value
+}
+
+func (d *decoder) readLocation() common.Location {
+	switch tag := d.readUint(); tag {
+	case locationTagNil:
+		return nil
+
+	case locationTagAddress:
+		address, err := common.BytesToAddress(d.readBytes())
+		if err != nil {
+			invalid("invalid address: %s", err)
+		}
+		return common.AddressLocation{
+			Address: address,
+			Name:    d.readString(),
+		}
+
+	case locationTagIdentifier:
+		return common.IdentifierLocation(d.readString())
+
+	case locationTagString:
+		return common.StringLocation(d.readString())
+
+	case locationTagTransaction:
+		var location common.TransactionLocation
+		if copy(location[:], d.readBytes()) != len(location) {
+			invalid("invalid transaction location")
+		}
+		return location
+
+	case locationTagScript:
+		var location common.ScriptLocation
+		if copy(location[:], d.readBytes()) != len(location) {
+			invalid("invalid script location")
+		}
+		return location
+
+	case locationTagREPL:
+		return common.REPLLocation{}
+
+	default:
+		invalid("invalid location tag %d", tag)
+		return nil
+	}
+}
+
+// programEncoder encodes a program.
+//
+// The encoding consists of the AST, the global values and types,
+// the transaction types, and the remaining elaboration entries
+type programEncoder struct {
+	encoder
+	location common.Location
+
+	// elements are the AST elements (pointers) which have been encoded,
+	// in the order in which they were encoded
+	elements       []reflect.Value
+	elementIndices map[any]int
+
+	types         map[sema.Type]int
+	declaredTypes []sema.Type
+
+	typeParameters map[*sema.TypeParameter]int
+	members        map[*sema.Member]int
+
+	// imports are the locations of other programs which the program depends on
+	imports       []common.Location
+	importIndices map[common.Location]struct{}
+}
+
+func newProgramEncoder(location common.Location) *programEncoder {
+	return &programEncoder{
+		location:       location,
+		elementIndices: map[any]int{},
+		types:          map[sema.Type]int{},
+		typeParameters: map[*sema.TypeParameter]int{},
+		members:        map[*sema.Member]int{},
+		importIndices:  map[common.Location]struct{}{},
+	}
+}
+
+func (e *programEncoder) addImport(location common.Location) {
+	if _, ok := e.importIndices[location]; ok {
+		return
+	}
+	e.importIndices[location] = struct{}{}
+	e.imports = append(e.imports, location)
+}
+
+func (e *programEncoder) encodeProgram(program *interpreter.Program) {
+	if program == nil || program.Program == nil || program.Elaboration == nil {
+		unsupported("incomplete program")
+	}
+
+	elaboration := program.Elaboration
+
+	e.encodeElement(program.Program)
+
+	e.encodeVariables(elaboration.ForEachGlobalValue)
+	e.encodeVariables(elaboration.ForEachGlobalType)
+
+	e.writeUint(uint64(len(elaboration.TransactionTypes)))
+	for _, transactionType := range elaboration.TransactionTypes {
+		e.encodeType(transactionType)
+	}
+
+	e.encodeElaboration(elaboration)
+}
+
+// programDecoder decodes a program encoded by a programEncoder
+type programDecoder struct {
+	decoder
+	location common.Location
+	resolver Resolver
+
+	elements       []reflect.Value
+	types          []sema.Type
+	typeParameters []*sema.TypeParameter
+	members        []*sema.Member
+
+	// registrations register the declared types in the elaboration.
+	// They are applied once all types are decoded,
+	// as registration determines the type IDs, which requires the containers of the types
+	registrations []func()
+}
+
+func newProgramDecoder(location common.Location, data []byte, resolver Resolver) *programDecoder {
+	return &programDecoder{
+		decoder: decoder{
+			data: data,
+		},
+		location: location,
+		resolver: resolver,
+	}
+}
+
+func (d *programDecoder) decodeProgram() *interpreter.Program {
+	astProgram, ok := d.decodeElement().(*ast.Program)
+	if !ok || astProgram == nil {
+		invalid("missing program")
+	}
+
+	elaboration := sema.NewElaboration(nil)
+
+	d.decodeVariables(elaboration.SetGlobalValue)
+	d.decodeVariables(elaboration.SetGlobalType)
+
+	transactionTypeCount := d.readLength()
+	for i := 0; i < transactionTypeCount; i++ {
+		transactionType, ok := d.decodeType().(*sema.TransactionType)
+		if !ok {
+			invalid("invalid transaction type")
+		}
+		elaboration.TransactionTypes = append(elaboration.TransactionTypes, transactionType)
+	}
+
+	d.decodeElaboration(elaboration)
+
+	if d.remaining() > 0 {
+		invalid("trailing data")
+	}
+
+	for _, register := range d.registrations {
+		register()
+	}
+
+	return &interpreter.Program{
+		Program:     astProgram,
+		Elaboration: elaboration,
+	}
+}
+
+func (e *programEncoder) encodeVariables(forEach func(func(name string, variable *sema.Variable))) {
+	var names []string
+	var variables []*sema.Variable
+	forEach(func(name string, variable *sema.Variable) {
+		names = append(names, name)
+		variables = append(variables, variable)
+	})
+
+	e.writeUint(uint64(len(names)))
+	for i, name := range names {
+		e.writeString(name)
+		e.encodeVariable(variables[i])
+	}
+}
+
+func (d *programDecoder) decodeVariables(set func(name string, variable *sema.Variable)) {
+	count := d.readLength()
+	for i := 0; i < count; i++ {
+		name := d.readString()
+		set(name, d.decodeVariable())
+	}
+}
+
+func (e *programEncoder) encodeVariable(variable *sema.Variable) {
+	if variable == nil {
+		e.writeBool(false)
+		return
+	}
+	e.writeBool(true)
+
+	e.writeString(variable.Identifier)
+	e.writeString(variable.DocString)
+	e.writeUint(uint64(variable.DeclarationKind))
+	e.writeInt(int64(variable.ActivationDepth))
+	e.writeBool(variable.IsConstant)
+	e.encodeStrings(variable.ArgumentLabels)
+	e.encodeAccess(variable.Access)
+	e.encodeValue(reflect.ValueOf(variable.Pos))
+	e.encodeType(variable.Type)
+}
+
+func (d *programDecoder) decodeVariable() *sema.Variable {
+	if !d.readBool() {
+		return nil
+	}
+
+	variable := &sema.Variable{
+		Identifier:      d.readString(),
+		DocString:       d.readString(),
+		DeclarationKind: common.DeclarationKind(d.readUint()),
+		ActivationDepth: int(d.readInt()),
+		IsConstant:      d.readBool(),
+		ArgumentLabels:  d.decodeStrings(),
+		Access:          d.decodeAccess(),
+	}
+	d.decodeValue(reflect.ValueOf(&variable.Pos).Elem())
+	variable.Type = d.decodeType()
+
+	return variable
+}
+
+func (e *programEncoder) encodeStrings(values []string) {
+	if values == nil {
+		e.writeUint(0)
+		return
+	}
+	e.writeUint(uint64(len(values)) + 1)
+	for _, value := range values {
+		e.writeString(value)
+	}
+}
+
+func (d *programDecoder) decodeStrings() []string {
+	count := d.readUint()
+	if count == 0 {
+		return nil
+	}
+	count--
+	if count > uint64(d.remaining()) {
+		invalid("truncated")
+	}
+	values := make([]string, count)
+	for i := range values {
+		values[i] = d.readString()
+	}
+	return values
+}
@@ -0,0 +1,543 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package programcache
+
+import (
+	"math/big"
+	"reflect"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// elementTag is the tag of the type of an AST element in the encoding.
+//
+// Tags are part of the encoding, so they must be stable:
+// Existing tags must not be changed or reused, and new element types must get new tags.
+// Changing the tag of an element type requires incrementing BinaryCodecVersion
+type elementTag uint64
+
+const (
+	elementTagArgument                      elementTag = 1
+	elementTagArrayExpression               elementTag = 2
+	elementTagAssignmentStatement           elementTag = 3
+	elementTagAttachExpression              elementTag = 4
+	elementTagAttachmentDeclaration         elementTag = 5
+	elementTagBinaryExpression              elementTag = 6
+	elementTagBlock                         elementTag = 7
+	elementTagBoolExpression                elementTag = 8
+	elementTagBreakStatement                elementTag = 9
+	elementTagCastingExpression             elementTag = 10
+	elementTagCompositeDeclaration          elementTag = 11
+	elementTagConditionalExpression         elementTag = 12
+	elementTagConjunctiveEntitlementSet     elementTag = 13
+	elementTagConstantSizedType             elementTag = 14
+	elementTagContinueStatement             elementTag = 15
+	elementTagCreateExpression              elementTag = 16
+	elementTagDestroyExpression             elementTag = 17
+	elementTagDictionaryEntry               elementTag = 18
+	elementTagDictionaryExpression          elementTag = 19
+	elementTagDictionaryType                elementTag = 20
+	elementTagDisjunctiveEntitlementSet     elementTag = 21
+	elementTagEmitCondition                 elementTag = 22
+	elementTagEmitStatement                 elementTag = 23
+	elementTagEntitlementAccess             elementTag = 24
+	elementTagEntitlementDeclaration        elementTag = 25
+	elementTagEntitlementMapRelation        elementTag = 26
+	elementTagEntitlementMappingDeclaration elementTag = 27
+	elementTagEnumCaseDeclaration           elementTag = 28
+	elementTagExpressionStatement           elementTag = 29
+	elementTagFieldDeclaration              elementTag = 30
+	elementTagFixedPointExpression          elementTag = 31
+	elementTagForStatement                  elementTag = 32
+	elementTagForceExpression               elementTag = 33
+	elementTagFunctionBlock                 elementTag = 34
+	elementTagFunctionDeclaration           elementTag = 35
+	elementTagFunctionExpression            elementTag = 36
+	elementTagFunctionType                  elementTag = 37
+	elementTagIdentifier                    elementTag = 38
+	elementTagIdentifierExpression          elementTag = 39
+	elementTagIfStatement                   elementTag = 40
+	elementTagImportDeclaration             elementTag = 41
+	elementTagIndexExpression               elementTag = 42
+	elementTagInstantiationType             elementTag = 43
+	elementTagIntegerExpression             elementTag = 44
+	elementTagInterfaceDeclaration          elementTag = 45
+	elementTagIntersectionType              elementTag = 46
+	elementTagInvocationExpression          elementTag = 47
+	elementTagMappedAccess                  elementTag = 48
+	elementTagMemberExpression              elementTag = 49
+	elementTagMembers                       elementTag = 50
+	elementTagNilExpression                 elementTag = 51
+	elementTagNominalType                   elementTag = 52
+	elementTagOptionalType                  elementTag = 53
+	elementTagParameter                     elementTag = 54
+	elementTagParameterList                 elementTag = 55
+	elementTagPathExpression                elementTag = 56
+	elementTagPosition                      elementTag = 57
+	elementTagPragmaDeclaration             elementTag = 58
+	elementTagProgram                       elementTag = 59
+	elementTagRange                         elementTag = 60
+	elementTagReferenceExpression           elementTag = 61
+	elementTagReferenceType                 elementTag = 62
+	elementTagRemoveStatement               elementTag = 63
+	elementTagReturnStatement               elementTag = 64
+	elementTagSpecialFunctionDeclaration    elementTag = 65
+	elementTagStringExpression              elementTag = 66
+	elementTagSwapStatement                 elementTag = 67
+	elementTagSwitchCase                    elementTag = 68
+	elementTagSwitchStatement               elementTag = 69
+	elementTagTestCondition                 elementTag = 70
+	elementTagTransactionDeclaration        elementTag = 71
+	elementTagTransfer                      elementTag = 72
+	elementTagTypeAnnotation                elementTag = 73
+	elementTagTypeParameter                 elementTag = 74
+	elementTagTypeParameterList             elementTag = 75
+	elementTagUnaryExpression               elementTag = 76
+	elementTagVariableDeclaration           elementTag = 77
+	elementTagVariableSizedType             elementTag = 78
+	elementTagVoidExpression                elementTag = 79
+	elementTagWhileStatement                elementTag = 80
+	elementTagPrimitiveAccess               elementTag = 81
+	elementTagConditions                    elementTag = 82
+)
+
+// elementTypesByTag are the types which may be stored in interface-typed fields of the AST,
+// e.g. the expressions, statements, declarations, types, and accesses, by their tag.
+// Elements may be stored as values of these types, or as pointers to them
+var elementTypesByTag = map[elementTag]reflect.Type{
+	elementTagArgument:                      reflect.TypeOf(ast.Argument{}),
+	elementTagArrayExpression:               reflect.TypeOf(ast.ArrayExpression{}),
+	elementTagAssignmentStatement:           reflect.TypeOf(ast.AssignmentStatement{}),
+	elementTagAttachExpression:              reflect.TypeOf(ast.AttachExpression{}),
+	elementTagAttachmentDeclaration:         reflect.TypeOf(ast.AttachmentDeclaration{}),
+	elementTagBinaryExpression:              reflect.TypeOf(ast.BinaryExpression{}),
+	elementTagBlock:                         reflect.TypeOf(ast.Block{}),
+	elementTagBoolExpression:                reflect.TypeOf(ast.BoolExpression{}),
+	elementTagBreakStatement:                reflect.TypeOf(ast.BreakStatement{}),
+	elementTagCastingExpression:             reflect.TypeOf(ast.CastingExpression{}),
+	elementTagCompositeDeclaration:          reflect.TypeOf(ast.CompositeDeclaration{}),
+	elementTagConditionalExpression:         reflect.TypeOf(ast.ConditionalExpression{}),
+	elementTagConjunctiveEntitlementSet:     reflect.TypeOf(ast.ConjunctiveEntitlementSet{}),
+	elementTagConstantSizedType:             reflect.TypeOf(ast.ConstantSizedType{}),
+	elementTagContinueStatement:             reflect.TypeOf(ast.ContinueStatement{}),
+	elementTagCreateExpression:              reflect.TypeOf(ast.CreateExpression{}),
+	elementTagDestroyExpression:             reflect.TypeOf(ast.DestroyExpression{}),
+	elementTagDictionaryEntry:               reflect.TypeOf(ast.DictionaryEntry{}),
+	elementTagDictionaryExpression:          reflect.TypeOf(ast.DictionaryExpression{}),
+	elementTagDictionaryType:                reflect.TypeOf(ast.DictionaryType{}),
+	elementTagDisjunctiveEntitlementSet:     reflect.TypeOf(ast.DisjunctiveEntitlementSet{}),
+	elementTagEmitCondition:                 reflect.TypeOf(ast.EmitCondition{}),
+	elementTagEmitStatement:                 reflect.TypeOf(ast.EmitStatement{}),
+	elementTagEntitlementAccess:             reflect.TypeOf(ast.EntitlementAccess{}),
+	elementTagEntitlementDeclaration:        reflect.TypeOf(ast.EntitlementDeclaration{}),
+	elementTagEntitlementMapRelation:        reflect.TypeOf(ast.EntitlementMapRelation{}),
+	elementTagEntitlementMappingDeclaration: reflect.TypeOf(ast.EntitlementMappingDeclaration{}),
+	elementTagEnumCaseDeclaration:           reflect.TypeOf(ast.EnumCaseDeclaration{}),
+	elementTagExpressionStatement:           reflect.TypeOf(ast.ExpressionStatement{}),
+	elementTagFieldDeclaration:              reflect.TypeOf(ast.FieldDeclaration{}),
+	elementTagFixedPointExpression:          reflect.TypeOf(ast.FixedPointExpression{}),
+	elementTagForStatement:                  reflect.TypeOf(ast.ForStatement{}),
+	elementTagForceExpression:               reflect.TypeOf(ast.ForceExpression{}),
+	elementTagFunctionBlock:                 reflect.TypeOf(ast.FunctionBlock{}),
+	elementTagFunctionDeclaration:           reflect.TypeOf(ast.FunctionDeclaration{}),
+	elementTagFunctionExpression:            reflect.TypeOf(ast.FunctionExpression{}),
+	elementTagFunctionType:                  reflect.TypeOf(ast.FunctionType{}),
+	elementTagIdentifier:                    reflect.TypeOf(ast.Identifier{}),
+	elementTagIdentifierExpression:          reflect.TypeOf(ast.IdentifierExpression{}),
+	elementTagIfStatement:                   reflect.TypeOf(ast.IfStatement{}),
+	elementTagImportDeclaration:             reflect.TypeOf(ast.ImportDeclaration{}),
+	elementTagIndexExpression:               reflect.TypeOf(ast.IndexExpression{}),
+	elementTagInstantiationType:             reflect.TypeOf(ast.InstantiationType{}),
+	elementTagIntegerExpression:             reflect.TypeOf(ast.IntegerExpression{}),
+	elementTagInterfaceDeclaration:          reflect.TypeOf(ast.InterfaceDeclaration{}),
+	elementTagIntersectionType:              reflect.TypeOf(ast.IntersectionType{}),
+	elementTagInvocationExpression:          reflect.TypeOf(ast.InvocationExpression{}),
+	elementTagMappedAccess:                  reflect.TypeOf(ast.MappedAccess{}),
+	elementTagMemberExpression:              reflect.TypeOf(ast.MemberExpression{}),
+	elementTagMembers:                       reflect.TypeOf(ast.Members{}),
+	elementTagNilExpression:                 reflect.TypeOf(ast.NilExpression{}),
+	elementTagNominalType:                   reflect.TypeOf(ast.NominalType{}),
+	elementTagOptionalType:                  reflect.TypeOf(ast.OptionalType{}),
+	elementTagParameter:                     reflect.TypeOf(ast.Parameter{}),
+	elementTagParameterList:                 reflect.TypeOf(ast.ParameterList{}),
+	elementTagPathExpression:                reflect.TypeOf(ast.PathExpression{}),
+	elementTagPosition:                      reflect.TypeOf(ast.Position{}),
+	elementTagPragmaDeclaration:             reflect.TypeOf(ast.PragmaDeclaration{}),
+	elementTagProgram:                       reflect.TypeOf(ast.Program{}),
+	elementTagRange:                         reflect.TypeOf(ast.Range{}),
+	elementTagReferenceExpression:           reflect.TypeOf(ast.ReferenceExpression{}),
+	elementTagReferenceType:                 reflect.TypeOf(ast.ReferenceType{}),
+	elementTagRemoveStatement:               reflect.TypeOf(ast.RemoveStatement{}),
+	elementTagReturnStatement:               reflect.TypeOf(ast.ReturnStatement{}),
+	elementTagSpecialFunctionDeclaration:    reflect.TypeOf(ast.SpecialFunctionDeclaration{}),
+	elementTagStringExpression:              reflect.TypeOf(ast.StringExpression{}),
+	elementTagSwapStatement:                 reflect.TypeOf(ast.SwapStatement{}),
+	elementTagSwitchCase:                    reflect.TypeOf(ast.SwitchCase{}),
+	elementTagSwitchStatement:               reflect.TypeOf(ast.SwitchStatement{}),
+	elementTagTestCondition:                 reflect.TypeOf(ast.TestCondition{}),
+	elementTagTransactionDeclaration:        reflect.TypeOf(ast.TransactionDeclaration{}),
+	elementTagTransfer:                      reflect.TypeOf(ast.Transfer{}),
+	elementTagTypeAnnotation:                reflect.TypeOf(ast.TypeAnnotation{}),
+	elementTagTypeParameter:                 reflect.TypeOf(ast.TypeParameter{}),
+	elementTagTypeParameterList:             reflect.TypeOf(ast.TypeParameterList{}),
+	elementTagUnaryExpression:               reflect.TypeOf(ast.UnaryExpression{}),
+	elementTagVariableDeclaration:           reflect.TypeOf(ast.VariableDeclaration{}),
+	elementTagVariableSizedType:             reflect.TypeOf(ast.VariableSizedType{}),
+	elementTagVoidExpression:                reflect.TypeOf(ast.VoidExpression{}),
+	elementTagWhileStatement:                reflect.TypeOf(ast.WhileStatement{}),
+	elementTagPrimitiveAccess:               reflect.TypeOf(ast.PrimitiveAccess(0)),
+	elementTagConditions:                    reflect.TypeOf(ast.Conditions{}),
+}
+
+var elementTags = func() map[reflect.Type]elementTag {
+	tags := make(map[reflect.Type]elementTag, len(elementTypesByTag))
+	for tag, elementType := range elementTypesByTag { //nolint:maprange
+		tags[elementType] = tag
+	}
+	return tags
+}()
+
+// encodeElementType encodes the tag of the given element type,
+// and whether the element is a pointer, in the lowest bit.
+// Tags start at 1, so the encoding is never 0, which encodes a nil element
+func encodeElementType(elementType reflect.Type) (uint64, bool) {
+	var pointerBit uint64
+	if elementType.Kind() == reflect.Pointer {
+		elementType = elementType.Elem()
+		pointerBit = 1
+	}
+
+	tag, ok := elementTags[elementType]
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(tag)<<1 | pointerBit, true
+}
+
+// decodeElementType decodes an element type encoded by encodeElementType
+func decodeElementType(encoded uint64) (reflect.Type, bool) {
+	elementType, ok := elementTypesByTag[elementTag(encoded>>1)]
+	if !ok {
+		return nil, false
+	}
+
+	if encoded&1 == 1 {
+		elementType = reflect.PointerTo(elementType)
+	}
+
+	return elementType, true
+}
+
+var (
+	bigIntPointerType  = reflect.TypeOf((*big.Int)(nil))
+	programPointerType = reflect.TypeOf((*ast.Program)(nil))
+	membersPointerType = reflect.TypeOf((*ast.Members)(nil))
+	locationType       = reflect.TypeOf((*common.Location)(nil)).Elem()
+)
+
+// Pointer tags are part of the encoding, see elementTag
+const (
+	pointerTagNil       = 0
+	pointerTagReference = 1
+	pointerTagNew       = 2
+)
+
+// encodeElement encodes the given AST element, e.g. a declaration, statement, or expression.
+// If the element was already encoded, it is encoded as a reference to it
+func (e *programEncoder) encodeElement(element any) {
+	e.encodeValue(reflect.ValueOf(&element).Elem())
+}
+
+func (d *programDecoder) decodeElement() any {
+	var element any
+	d.decodeValue(reflect.ValueOf(&element).Elem())
+	return element
+}
+
+// encodeValue encodes the given value of the AST field by field.
+//
+// Pointers are encoded once, and as a reference afterwards,
+// so elements which are referenced multiple times keep their identity.
+// The dynamic types of interface values are encoded as tags (see elementTag),
+// except for locations, which are encoded like all other locations.
+// Unexported fields are caches, and are not encoded
+func (e *programEncoder) encodeValue(value reflect.Value) {
+	valueType := value.Type()
+
+	switch valueType {
+	case bigIntPointerType:
+		e.writeBigInt(value.Interface().(*big.Int))
+		return
+
+	case locationType:
+		var location common.Location
+		if !value.IsNil() {
+			location = value.Interface().(common.Location)
+		}
+		e.writeLocation(location)
+		return
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		e.writeBool(value.Bool())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(value.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		e.writeUint(value.Uint())
+
+	case reflect.String:
+		e.writeString(value.String())
+
+	case reflect.Slice:
+		if value.IsNil() {
+			e.writeUint(0)
+			return
+		}
+		if valueType.Elem().Kind() == reflect.Uint8 {
+			e.writeUint(1)
+			e.writeBytes(value.Bytes())
+			return
+		}
+		length := value.Len()
+		e.writeUint(uint64(length) + 1)
+		for i := 0; i < length; i++ {
+			e.encodeValue(value.Index(i))
+		}
+
+	case reflect.Array:
+		length := value.Len()
+		for i := 0; i < length; i++ {
+			e.encodeValue(value.Index(i))
+		}
+
+	case reflect.Pointer:
+		e.encodePointer(value)
+
+	case reflect.Interface:
+		if value.IsNil() {
+			e.writeUint(0)
+			return
+		}
+		element := value.Elem()
+		encodedType, ok := encodeElementType(element.Type())
+		if !ok {
+			unsupported("AST element type %s", element.Type())
+		}
+		e.writeUint(encodedType)
+		e.encodeValue(element)
+
+	case reflect.Struct:
+		fieldCount := valueType.NumField()
+		for i := 0; i < fieldCount; i++ {
+			if !valueType.Field(i).IsExported() {
+				continue
+			}
+			e.encodeValue(value.Field(i))
+		}
+
+	default:
+		unsupported("AST value of type %s", valueType)
+	}
+}
+
+func (e *programEncoder) encodePointer(pointer reflect.Value) {
+	if pointer.IsNil() {
+		e.writeUint(pointerTagNil)
+		return
+	}
+
+	key := pointer.Interface()
+	if index, ok := e.elementIndices[key]; ok {
+		e.writeUint(pointerTagReference)
+		e.writeUint(uint64(index))
+		return
+	}
+
+	e.writeUint(pointerTagNew)
+	e.elementIndices[key] = len(e.elements)
+	e.elements = append(e.elements, pointer)
+
+	// Programs and members only have unexported fields,
+	// they are constructed from their declarations
+
+	switch pointerType := pointer.Type(); pointerType {
+	case programPointerType:
+		declarations := pointer.Interface().(*ast.Program).Declarations()
+		e.encodeValue(reflect.ValueOf(declarations))
+
+	case membersPointerType:
+		declarations := pointer.Interface().(*ast.Members).Declarations()
+		e.encodeValue(reflect.ValueOf(declarations))
+
+	default:
+		e.encodeValue(pointer.Elem())
+	}
+}
+
+// decodeValue decodes a value encoded by encodeValue into the given settable value
+func (d *programDecoder) decodeValue(value reflect.Value) {
+	valueType := value.Type()
+
+	switch valueType {
+	case bigIntPointerType:
+		value.Set(reflect.ValueOf(d.readBigInt()))
+		return
+
+	case locationType:
+		location := d.readLocation()
+		if location != nil {
+			value.Set(reflect.ValueOf(location))
+		}
+		return
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		value.SetBool(d.readBool())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		integer := d.readInt()
+		if value.OverflowInt(integer) {
+			invalid("integer overflow")
+		}
+		value.SetInt(integer)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		integer := d.readUint()
+		if value.OverflowUint(integer) {
+			invalid("integer overflow")
+		}
+		value.SetUint(integer)
+
+	case reflect.String:
+		value.SetString(d.readString())
+
+	case reflect.Slice:
+		length := d.readUint()
+		if length == 0 {
+			return
+		}
+		if valueType.Elem().Kind() == reflect.Uint8 {
+			value.SetBytes(append([]byte{}, d.readBytes()...))
+			return
+		}
+		length--
+		if length > uint64(d.remaining()) {
+			invalid("truncated")
+		}
+		slice := reflect.MakeSlice(valueType, int(length), int(length))
+		for i := 0; i < int(length); i++ {
+			d.decodeValue(slice.Index(i))
+		}
+		value.Set(slice)
+
+	case reflect.Array:
+		length := value.Len()
+		for i := 0; i < length; i++ {
+			d.decodeValue(value.Index(i))
+		}
+
+	case reflect.Pointer:
+		d.decodePointer(value)
+
+	case reflect.Interface:
+		encodedType := d.readUint()
+		if encodedType == 0 {
+			return
+		}
+		elementType, ok := decodeElementType(encodedType)
+		if !ok {
+			invalid("invalid AST element tag %d", encodedType>>1)
+		}
+		if !elementType.AssignableTo(valueType) {
+			invalid("invalid AST element type %s for %s", elementType, valueType)
+		}
+		element := reflect.New(elementType).Elem()
+		d.decodeValue(element)
+		value.Set(element)
+
+	case reflect.Struct:
+		fieldCount := valueType.NumField()
+		for i := 0; i < fieldCount; i++ {
+			if !valueType.Field(i).IsExported() {
+				continue
+			}
+			d.decodeValue(value.Field(i))
+		}
+
+	default:
+		invalid("invalid AST value of type %s", valueType)
+	}
+}
+
+func (d *programDecoder) decodePointer(value reflect.Value) {
+	valueType := value.Type()
+
+	switch tag := d.readUint(); tag {
+	case pointerTagNil:
+		return
+
+	case pointerTagReference:
+		index := d.readUint()
+		if index >= uint64(len(d.elements)) {
+			invalid("invalid AST element reference %d", index)
+		}
+		pointer := d.elements[index]
+		if !pointer.IsValid() || pointer.Type() != valueType {
+			invalid("invalid AST element reference %d for %s", index, valueType)
+		}
+		value.Set(pointer)
+
+	case pointerTagNew:
+		index := len(d.elements)
+
+		switch valueType {
+		case programPointerType, membersPointerType:
+			// Reserve the index, the element is constructed from its declarations
+			d.elements = append(d.elements, reflect.Value{})
+
+			var declarations []ast.Declaration
+			d.decodeValue(reflect.ValueOf(&declarations).Elem())
+
+			var pointer reflect.Value
+			if valueType == programPointerType {
+				pointer = reflect.ValueOf(ast.NewProgram(nil, declarations))
+			} else {
+				pointer = reflect.ValueOf(ast.NewMembers(nil, declarations))
+			}
+
+			d.elements[index] = pointer
+			value.Set(pointer)
+
+		default:
+			pointer := reflect.New(valueType.Elem())
+			d.elements = append(d.elements, pointer)
+			d.decodeValue(pointer.Elem())
+			value.Set(pointer)
+		}
+
+	default:
+		invalid("invalid pointer tag %d", tag)
+	}
+}
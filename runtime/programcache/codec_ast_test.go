@@ -0,0 +1,151 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package programcache
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestElementTags pins the tags of all AST element types.
+//
+// Tags are part of the encoding: If this test fails because a tag changed,
+// restore the tag instead of updating the test.
+// New element types are added with new tags, and require incrementing BinaryCodecVersion
+func TestElementTags(t *testing.T) {
+
+	t.Parallel()
+
+	tags := map[elementTag]string{}
+	for tag, elementType := range elementTypesByTag { //nolint:maprange
+		tags[tag] = elementType.String()
+	}
+
+	assert.Equal(t,
+		map[elementTag]string{
+			1:  "ast.Argument",
+			2:  "ast.ArrayExpression",
+			3:  "ast.AssignmentStatement",
+			4:  "ast.AttachExpression",
+			5:  "ast.AttachmentDeclaration",
+			6:  "ast.BinaryExpression",
+			7:  "ast.Block",
+			8:  "ast.BoolExpression",
+			9:  "ast.BreakStatement",
+			10: "ast.CastingExpression",
+			11: "ast.CompositeDeclaration",
+			12: "ast.ConditionalExpression",
+			13: "ast.ConjunctiveEntitlementSet",
+			14: "ast.ConstantSizedType",
+			15: "ast.ContinueStatement",
+			16: "ast.CreateExpression",
+			17: "ast.DestroyExpression",
+			18: "ast.DictionaryEntry",
+			19: "ast.DictionaryExpression",
+			20: "ast.DictionaryType",
+			21: "ast.DisjunctiveEntitlementSet",
+			22: "ast.EmitCondition",
+			23: "ast.EmitStatement",
+			24: "ast.EntitlementAccess",
+			25: "ast.EntitlementDeclaration",
+			26: "ast.EntitlementMapRelation",
+			27: "ast.EntitlementMappingDeclaration",
+			28: "ast.EnumCaseDeclaration",
+			29: "ast.ExpressionStatement",
+			30: "ast.FieldDeclaration",
+			31: "ast.FixedPointExpression",
+			32: "ast.ForStatement",
+			33: "ast.ForceExpression",
+			34: "ast.FunctionBlock",
+			35: "ast.FunctionDeclaration",
+			36: "ast.FunctionExpression",
+			37: "ast.FunctionType",
+			38: "ast.Identifier",
+			39: "ast.IdentifierExpression",
+			40: "ast.IfStatement",
+			41: "ast.ImportDeclaration",
+			42: "ast.IndexExpression",
+			43: "ast.InstantiationType",
+			44: "ast.IntegerExpression",
+			45: "ast.InterfaceDeclaration",
+			46: "ast.IntersectionType",
+			47: "ast.InvocationExpression",
+			48: "ast.MappedAccess",
+			49: "ast.MemberExpression",
+			50: "ast.Members",
+			51: "ast.NilExpression",
+			52: "ast.NominalType",
+			53: "ast.OptionalType",
+			54: "ast.Parameter",
+			55: "ast.ParameterList",
+			56: "ast.PathExpression",
+			57: "ast.Position",
+			58: "ast.PragmaDeclaration",
+			59: "ast.Program",
+			60: "ast.Range",
+			61: "ast.ReferenceExpression",
+			62: "ast.ReferenceType",
+			63: "ast.RemoveStatement",
+			64: "ast.ReturnStatement",
+			65: "ast.SpecialFunctionDeclaration",
+			66: "ast.StringExpression",
+			67: "ast.SwapStatement",
+			68: "ast.SwitchCase",
+			69: "ast.SwitchStatement",
+			70: "ast.TestCondition",
+			71: "ast.TransactionDeclaration",
+			72: "ast.Transfer",
+			73: "ast.TypeAnnotation",
+			74: "ast.TypeParameter",
+			75: "ast.TypeParameterList",
+			76: "ast.UnaryExpression",
+			77: "ast.VariableDeclaration",
+			78: "ast.VariableSizedType",
+			79: "ast.VoidExpression",
+			80: "ast.WhileStatement",
+			81: "ast.PrimitiveAccess",
+			82: "ast.Conditions",
+		},
+		tags,
+	)
+
+	// Each element type has exactly one tag
+	require.Len(t, elementTags, len(elementTypesByTag))
+
+	// Values and pointers of each element type are encoded with the tag of the element type
+
+	for tag, elementType := range elementTypesByTag { //nolint:maprange
+		for _, ty := range []reflect.Type{
+			elementType,
+			reflect.PointerTo(elementType),
+		} {
+			encoded, ok := encodeElementType(ty)
+			require.True(t, ok)
+			assert.NotZero(t, encoded)
+			assert.Equal(t, tag, elementTag(encoded>>1))
+
+			decoded, ok := decodeElementType(encoded)
+			require.True(t, ok)
+			assert.Equal(t, ty, decoded)
+		}
+	}
+}
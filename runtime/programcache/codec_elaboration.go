@@ -0,0 +1,946 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package programcache
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// Elaboration entry tags are part of the encoding, see elementTag
+const (
+	elaborationEntryEnd                                = 0
+	elaborationEntryFunctionDeclarationFunctionType    = 1
+	elaborationEntryVariableDeclarationTypes           = 2
+	elaborationEntryAssignmentStatementTypes           = 3
+	elaborationEntryCompositeDeclarationType           = 4
+	elaborationEntryCompositeNestedDeclarations        = 5
+	elaborationEntryInterfaceDeclarationType           = 6
+	elaborationEntryInterfaceNestedDeclarations        = 7
+	elaborationEntryEntitlementDeclarationType         = 8
+	elaborationEntryEntitlementMapDeclarationType      = 9
+	elaborationEntryConstructorFunctionType            = 10
+	elaborationEntryFunctionExpressionFunctionType     = 11
+	elaborationEntryInvocationExpressionTypes          = 12
+	elaborationEntryCastingExpressionTypes             = 13
+	elaborationEntryStaticCastTypes                    = 14
+	elaborationEntryRuntimeCastTypes                   = 15
+	elaborationEntryStringExpressionType               = 16
+	elaborationEntryReturnStatementTypes               = 17
+	elaborationEntryBinaryExpressionTypes              = 18
+	elaborationEntryNestedResourceMoveExpression       = 19
+	elaborationEntryNumberConversionArgumentTypes      = 20
+	elaborationEntryExpressionTypes                    = 21
+	elaborationEntryArrayExpressionTypes               = 22
+	elaborationEntryDictionaryExpressionTypes          = 23
+	elaborationEntryIntegerExpressionType              = 24
+	elaborationEntryMemberExpressionMemberAccessInfo   = 25
+	elaborationEntryMemberExpressionExpectedType       = 26
+	elaborationEntryFixedPointExpressionType           = 27
+	elaborationEntryTransactionDeclarationType         = 28
+	elaborationEntrySwapStatementTypes                 = 29
+	elaborationEntryDefaultDestroyDeclaration          = 30
+	elaborationEntryPostConditionsRewrite              = 31
+	elaborationEntryEmitStatementEventType             = 32
+	elaborationEntryIdentifierInInvocationType         = 33
+	elaborationEntryImportDeclarationResolvedLocations = 34
+	elaborationEntryReferenceExpressionBorrowType      = 35
+	elaborationEntryIndexExpressionTypes               = 36
+	elaborationEntryAttachmentAccessType               = 37
+	elaborationEntryAttachmentRemoveType               = 38
+	elaborationEntryAttachType                         = 39
+	elaborationEntryForceExpressionType                = 40
+	elaborationEntryForStatementTypes                  = 41
+	elaborationEntryCompositeTypeDeclaration           = 42
+	elaborationEntryCompositeType                      = 43
+	elaborationEntryInterfaceType                      = 44
+	elaborationEntryEntitlementType                    = 45
+	elaborationEntryEntitlementMapType                 = 46
+)
+
+// encodeElaboration encodes the entries of the elaboration.
+//
+// Each entry consists of its kind, the index of the AST element or declared type it belongs to,
+// and its value. The entries are encoded in the order of the elements and types,
+// so the encoding is deterministic. Encoding an entry may encode further elements and types,
+// e.g. the statements of rewritten post-conditions, so the encoding continues until all are covered
+func (e *programEncoder) encodeElaboration(elaboration *sema.Elaboration) {
+	elementIndex := 0
+	typeIndex := 0
+
+	for elementIndex < len(e.elements) || typeIndex < len(e.declaredTypes) {
+		for ; elementIndex < len(e.elements); elementIndex++ {
+			e.encodeElementEntries(elaboration, elementIndex)
+		}
+		for ; typeIndex < len(e.declaredTypes); typeIndex++ {
+			e.encodeTypeEntries(elaboration, e.declaredTypes[typeIndex])
+		}
+	}
+
+	e.writeUint(elaborationEntryEnd)
+}
+
+func (e *programEncoder) writeEntry(kind uint64, index int) {
+	e.writeUint(kind)
+	e.writeUint(uint64(index))
+}
+
+func (e *programEncoder) encodeElementEntries(elaboration *sema.Elaboration, index int) {
+	element := e.elements[index].Interface()
+
+	switch element := element.(type) {
+	case *ast.FunctionDeclaration:
+		if functionType := elaboration.FunctionDeclarationFunctionType(element); functionType != nil {
+			e.writeEntry(elaborationEntryFunctionDeclarationFunctionType, index)
+			e.encodeType(functionType)
+		}
+
+	case *ast.VariableDeclaration:
+		types := elaboration.VariableDeclarationTypes(element)
+		if types != (sema.VariableDeclarationTypes{}) {
+			e.writeEntry(elaborationEntryVariableDeclarationTypes, index)
+			e.encodeType(types.ValueType)
+			e.encodeType(types.SecondValueType)
+			e.encodeType(types.TargetType)
+		}
+
+	case *ast.AssignmentStatement:
+		types := elaboration.AssignmentStatementTypes(element)
+		if types != (sema.AssignmentStatementTypes{}) {
+			e.writeEntry(elaborationEntryAssignmentStatementTypes, index)
+			e.encodeType(types.ValueType)
+			e.encodeType(types.TargetType)
+		}
+
+	case *ast.InterfaceDeclaration:
+		if interfaceType := elaboration.InterfaceDeclarationType(element); interfaceType != nil {
+			e.writeEntry(elaborationEntryInterfaceDeclarationType, index)
+			e.encodeType(interfaceType)
+		}
+		if nestedDeclarations := elaboration.InterfaceNestedDeclarations(element); nestedDeclarations != nil {
+			e.writeEntry(elaborationEntryInterfaceNestedDeclarations, index)
+			e.encodeNestedDeclarations(nestedDeclarations)
+		}
+
+	case *ast.EntitlementDeclaration:
+		if entitlementType := elaboration.EntitlementDeclarationType(element); entitlementType != nil {
+			e.writeEntry(elaborationEntryEntitlementDeclarationType, index)
+			e.encodeType(entitlementType)
+		}
+
+	case *ast.EntitlementMappingDeclaration:
+		if entitlementMapType := elaboration.EntitlementMapDeclarationType(element); entitlementMapType != nil {
+			e.writeEntry(elaborationEntryEntitlementMapDeclarationType, index)
+			e.encodeType(entitlementMapType)
+		}
+
+	case *ast.SpecialFunctionDeclaration:
+		if functionType := elaboration.ConstructorFunctionType(element); functionType != nil {
+			e.writeEntry(elaborationEntryConstructorFunctionType, index)
+			e.encodeType(functionType)
+		}
+
+	case *ast.FunctionExpression:
+		if functionType := elaboration.FunctionExpressionFunctionType(element); functionType != nil {
+			e.writeEntry(elaborationEntryFunctionExpressionFunctionType, index)
+			e.encodeType(functionType)
+		}
+
+	case *ast.InvocationExpression:
+		types := elaboration.InvocationExpressionTypes(element)
+		if types.ReturnType != nil ||
+			types.TypeArguments != nil ||
+			types.ArgumentTypes != nil ||
+			types.TypeParameterTypes != nil {
+
+			e.writeEntry(elaborationEntryInvocationExpressionTypes, index)
+			e.encodeType(types.ReturnType)
+			e.encodeTypeArguments(types.TypeArguments)
+			e.encodeTypes(types.ArgumentTypes)
+			e.encodeTypes(types.TypeParameterTypes)
+		}
+
+	case *ast.CastingExpression:
+		types := elaboration.CastingExpressionTypes(element)
+		if types != (sema.CastingExpressionTypes{}) {
+			e.writeEntry(elaborationEntryCastingExpressionTypes, index)
+			e.encodeType(types.StaticValueType)
+			e.encodeType(types.TargetType)
+		}
+		staticCastTypes := elaboration.StaticCastTypes(element)
+		if staticCastTypes != (sema.CastTypes{}) {
+			e.writeEntry(elaborationEntryStaticCastTypes, index)
+			e.encodeType(staticCastTypes.ExprActualType)
+			e.encodeType(staticCastTypes.TargetType)
+			e.encodeType(staticCastTypes.ExpectedType)
+		}
+		runtimeCastTypes := elaboration.RuntimeCastTypes(element)
+		if runtimeCastTypes != (sema.RuntimeCastTypes{}) {
+			e.writeEntry(elaborationEntryRuntimeCastTypes, index)
+			e.encodeType(runtimeCastTypes.Left)
+			e.encodeType(runtimeCastTypes.Right)
+		}
+
+	case *ast.StringExpression:
+		e.writeEntry(elaborationEntryStringExpressionType, index)
+		e.encodeType(elaboration.StringExpressionType(element))
+
+	case *ast.ReturnStatement:
+		types := elaboration.ReturnStatementTypes(element)
+		if types != (sema.ReturnStatementTypes{}) {
+			e.writeEntry(elaborationEntryReturnStatementTypes, index)
+			e.encodeType(types.ValueType)
+			e.encodeType(types.ReturnType)
+		}
+
+	case *ast.BinaryExpression:
+		types := elaboration.BinaryExpressionTypes(element)
+		if types != (sema.BinaryExpressionTypes{}) {
+			e.writeEntry(elaborationEntryBinaryExpressionTypes, index)
+			e.encodeType(types.ResultType)
+			e.encodeType(types.LeftType)
+			e.encodeType(types.RightType)
+		}
+
+	case *ast.ArrayExpression:
+		types := elaboration.ArrayExpressionTypes(element)
+		if types.ArrayType != nil || types.ArgumentTypes != nil {
+			e.writeEntry(elaborationEntryArrayExpressionTypes, index)
+			e.encodeType(types.ArrayType)
+			e.encodeTypes(types.ArgumentTypes)
+		}
+
+	case *ast.DictionaryExpression:
+		types := elaboration.DictionaryExpressionTypes(element)
+		if types.DictionaryType != nil || types.EntryTypes != nil {
+			e.writeEntry(elaborationEntryDictionaryExpressionTypes, index)
+			if types.DictionaryType == nil {
+				e.encodeType(nil)
+			} else {
+				e.encodeType(types.DictionaryType)
+			}
+			if types.EntryTypes == nil {
+				e.writeUint(0)
+			} else {
+				e.writeUint(uint64(len(types.EntryTypes)) + 1)
+				for _, entryType := range types.EntryTypes {
+					e.encodeType(entryType.KeyType)
+					e.encodeType(entryType.ValueType)
+				}
+			}
+		}
+
+	case *ast.IntegerExpression:
+		e.writeEntry(elaborationEntryIntegerExpressionType, index)
+		e.encodeType(elaboration.IntegerExpressionType(element))
+
+	case *ast.MemberExpression:
+		if accessInfo, ok := elaboration.MemberExpressionMemberAccessInfo(element); ok {
+			e.writeEntry(elaborationEntryMemberExpressionMemberAccessInfo, index)
+			e.encodeType(accessInfo.AccessedType)
+			e.encodeType(accessInfo.ResultingType)
+			e.encodeMember(accessInfo.Member)
+			e.writeBool(accessInfo.IsOptional)
+			e.writeBool(accessInfo.ReturnReference)
+		}
+		if expectedType := elaboration.MemberExpressionExpectedType(element); expectedType != nil {
+			e.writeEntry(elaborationEntryMemberExpressionExpectedType, index)
+			e.encodeType(expectedType)
+		}
+
+	case *ast.FixedPointExpression:
+		e.writeEntry(elaborationEntryFixedPointExpressionType, index)
+		e.encodeType(elaboration.FixedPointExpression(element))
+
+	case *ast.TransactionDeclaration:
+		if transactionType := elaboration.TransactionDeclarationType(element); transactionType != nil {
+			e.writeEntry(elaborationEntryTransactionDeclarationType, index)
+			e.encodeType(transactionType)
+		}
+
+	case *ast.SwapStatement:
+		types := elaboration.SwapStatementTypes(element)
+		if types != (sema.SwapStatementTypes{}) {
+			e.writeEntry(elaborationEntrySwapStatementTypes, index)
+			e.encodeType(types.LeftType)
+			e.encodeType(types.RightType)
+		}
+
+	case *ast.Conditions:
+		rewrite := elaboration.PostConditionsRewrite(element)
+		if rewrite.BeforeStatements != nil || rewrite.RewrittenPostConditions != nil {
+			e.writeEntry(elaborationEntryPostConditionsRewrite, index)
+			e.encodeValue(reflect.ValueOf(rewrite))
+		}
+
+	case *ast.EmitStatement:
+		e.encodeEmitStatementEntries(elaboration, element, index)
+
+	case *ast.EmitCondition:
+		// Emit conditions are checked and interpreted as emit statements
+		e.encodeEmitStatementEntries(elaboration, (*ast.EmitStatement)(element), index)
+
+	case *ast.IdentifierExpression:
+		if invokedType := elaboration.IdentifierInInvocationType(element); invokedType != nil {
+			e.writeEntry(elaborationEntryIdentifierInInvocationType, index)
+			e.encodeType(invokedType)
+		}
+
+	case *ast.ImportDeclaration:
+		resolvedLocations := elaboration.ImportDeclarationsResolvedLocations(element)
+		if resolvedLocations != nil {
+			e.writeEntry(elaborationEntryImportDeclarationResolvedLocations, index)
+			e.writeUint(uint64(len(resolvedLocations)))
+			for _, resolvedLocation := range resolvedLocations {
+				e.addImport(resolvedLocation.Location)
+				e.writeLocation(resolvedLocation.Location)
+				e.encodeValue(reflect.ValueOf(resolvedLocation.Identifiers))
+			}
+		}
+
+	case *ast.ReferenceExpression:
+		if borrowType := elaboration.ReferenceExpressionBorrowType(element); borrowType != nil {
+			e.writeEntry(elaborationEntryReferenceExpressionBorrowType, index)
+			e.encodeType(borrowType)
+		}
+
+	case *ast.IndexExpression:
+		types := elaboration.IndexExpressionTypes(element)
+		if types != (sema.IndexExpressionTypes{}) {
+			e.writeEntry(elaborationEntryIndexExpressionTypes, index)
+			e.encodeType(types.IndexedType)
+			e.encodeType(types.IndexingType)
+			e.encodeType(types.ResultType)
+			e.writeBool(types.ReturnReference)
+		}
+		if attachmentType, ok := elaboration.AttachmentAccessTypes(element); ok {
+			e.writeEntry(elaborationEntryAttachmentAccessType, index)
+			e.encodeType(attachmentType)
+		}
+
+	case *ast.RemoveStatement:
+		if attachmentType := elaboration.AttachmentRemoveTypes(element); attachmentType != nil {
+			e.writeEntry(elaborationEntryAttachmentRemoveType, index)
+			e.encodeType(attachmentType)
+		}
+
+	case *ast.AttachExpression:
+		if attachmentType := elaboration.AttachTypes(element); attachmentType != nil {
+			e.writeEntry(elaborationEntryAttachType, index)
+			e.encodeType(attachmentType)
+		}
+
+	case *ast.ForceExpression:
+		if valueType := elaboration.ForceExpressionType(element); valueType != nil {
+			e.writeEntry(elaborationEntryForceExpressionType, index)
+			e.encodeType(valueType)
+		}
+
+	case *ast.ForStatement:
+		types := elaboration.ForStatementType(element)
+		if types != (sema.ForStatementTypes{}) {
+			e.writeEntry(elaborationEntryForStatementTypes, index)
+			e.encodeType(types.IndexVariableType)
+			e.encodeType(types.ValueVariableType)
+		}
+	}
+
+	if declaration, ok := element.(ast.CompositeLikeDeclaration); ok {
+		if compositeType := elaboration.CompositeDeclarationType(declaration); compositeType != nil {
+			e.writeEntry(elaborationEntryCompositeDeclarationType, index)
+			e.encodeType(compositeType)
+		}
+		if nestedDeclarations := elaboration.CompositeNestedDeclarations(declaration); nestedDeclarations != nil {
+			e.writeEntry(elaborationEntryCompositeNestedDeclarations, index)
+			e.encodeNestedDeclarations(nestedDeclarations)
+		}
+	}
+
+	if declaration, ok := element.(ast.Declaration); ok {
+		if eventDeclaration := elaboration.DefaultDestroyDeclaration(declaration); eventDeclaration != nil {
+			e.writeEntry(elaborationEntryDefaultDestroyDeclaration, index)
+			e.encodeElement(eventDeclaration)
+		}
+	}
+
+	if expression, ok := element.(ast.Expression); ok {
+		if elaboration.IsNestedResourceMoveExpression(expression) {
+			e.writeEntry(elaborationEntryNestedResourceMoveExpression, index)
+		}
+
+		numberConversionArgumentTypes := elaboration.NumberConversionArgumentTypes(expression)
+		if numberConversionArgumentTypes != (sema.NumberConversionArgumentTypes{}) {
+			e.writeEntry(elaborationEntryNumberConversionArgumentTypes, index)
+			e.encodeType(numberConversionArgumentTypes.Type)
+			e.encodeValue(reflect.ValueOf(numberConversionArgumentTypes.Range))
+		}
+
+		types := elaboration.ExpressionTypes(expression)
+		if types != (sema.ExpressionTypes{}) {
+			e.writeEntry(elaborationEntryExpressionTypes, index)
+			e.encodeType(types.ActualType)
+			e.encodeType(types.ExpectedType)
+		}
+	}
+}
+
+func (e *programEncoder) encodeEmitStatementEntries(
+	elaboration *sema.Elaboration,
+	statement *ast.EmitStatement,
+	index int,
+) {
+	if eventType := elaboration.EmitStatementEventType(statement); eventType != nil {
+		e.writeEntry(elaborationEntryEmitStatementEventType, index)
+		e.encodeType(eventType)
+	}
+}
+
+func (e *programEncoder) encodeTypeEntries(elaboration *sema.Elaboration, ty sema.Type) {
+	index := e.types[ty]
+
+	switch ty := ty.(type) {
+	case *sema.CompositeType:
+		if declaration, ok := elaboration.CompositeTypeDeclaration(ty); ok {
+			e.writeEntry(elaborationEntryCompositeTypeDeclaration, index)
+			e.encodeElement(declaration)
+		}
+		if elaboration.CompositeType(ty.ID()) == ty {
+			e.writeEntry(elaborationEntryCompositeType, index)
+		}
+
+	case *sema.InterfaceType:
+		if elaboration.InterfaceType(ty.ID()) == ty {
+			e.writeEntry(elaborationEntryInterfaceType, index)
+		}
+
+	case *sema.EntitlementType:
+		if elaboration.EntitlementType(ty.ID()) == ty {
+			e.writeEntry(elaborationEntryEntitlementType, index)
+		}
+
+	case *sema.EntitlementMapType:
+		if elaboration.EntitlementMapType(ty.ID()) == ty {
+			e.writeEntry(elaborationEntryEntitlementMapType, index)
+		}
+	}
+}
+
+func (e *programEncoder) encodeNestedDeclarations(nestedDeclarations map[string]ast.Declaration) {
+	names := make([]string, 0, len(nestedDeclarations))
+	for name := range nestedDeclarations { //nolint:maprange
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	e.writeUint(uint64(len(names)))
+	for _, name := range names {
+		e.writeString(name)
+		e.encodeElement(nestedDeclarations[name])
+	}
+}
+
+func (e *programEncoder) encodeTypes(types []sema.Type) {
+	if types == nil {
+		e.writeUint(0)
+		return
+	}
+	e.writeUint(uint64(len(types)) + 1)
+	for _, ty := range types {
+		e.encodeType(ty)
+	}
+}
+
+func (e *programEncoder) encodeTypeArguments(typeArguments *sema.TypeParameterTypeOrderedMap) {
+	if typeArguments == nil {
+		e.writeUint(0)
+		return
+	}
+	e.writeUint(uint64(typeArguments.Len()) + 1)
+	typeArguments.Foreach(func(typeParameter *sema.TypeParameter, ty sema.Type) {
+		e.encodeTypeParameter(typeParameter)
+		e.encodeType(ty)
+	})
+}
+
+// decodeElaboration decodes the entries encoded by encodeElaboration into the given elaboration
+func (d *programDecoder) decodeElaboration(elaboration *sema.Elaboration) {
+	for {
+		kind := d.readUint()
+		if kind == elaborationEntryEnd {
+			return
+		}
+
+		index := d.readUint()
+
+		switch kind {
+		case elaborationEntryFunctionDeclarationFunctionType:
+			elaboration.SetFunctionDeclarationFunctionType(
+				decodedElement[*ast.FunctionDeclaration](d, index),
+				decodeTypeAs[*sema.FunctionType](d),
+			)
+
+		case elaborationEntryVariableDeclarationTypes:
+			elaboration.SetVariableDeclarationTypes(
+				decodedElement[*ast.VariableDeclaration](d, index),
+				sema.VariableDeclarationTypes{
+					ValueType:       d.decodeType(),
+					SecondValueType: d.decodeType(),
+					TargetType:      d.decodeType(),
+				},
+			)
+
+		case elaborationEntryAssignmentStatementTypes:
+			elaboration.SetAssignmentStatementTypes(
+				decodedElement[*ast.AssignmentStatement](d, index),
+				sema.AssignmentStatementTypes{
+					ValueType:  d.decodeType(),
+					TargetType: d.decodeType(),
+				},
+			)
+
+		case elaborationEntryCompositeDeclarationType:
+			elaboration.SetCompositeDeclarationType(
+				decodedElement[ast.CompositeLikeDeclaration](d, index),
+				decodeTypeAs[*sema.CompositeType](d),
+			)
+
+		case elaborationEntryCompositeNestedDeclarations:
+			elaboration.SetCompositeNestedDeclarations(
+				decodedElement[ast.CompositeLikeDeclaration](d, index),
+				d.decodeNestedDeclarations(),
+			)
+
+		case elaborationEntryInterfaceDeclarationType:
+			elaboration.SetInterfaceDeclarationWithType(
+				decodedElement[*ast.InterfaceDeclaration](d, index),
+				decodeTypeAs[*sema.InterfaceType](d),
+			)
+
+		case elaborationEntryInterfaceNestedDeclarations:
+			elaboration.SetInterfaceNestedDeclarations(
+				decodedElement[*ast.InterfaceDeclaration](d, index),
+				d.decodeNestedDeclarations(),
+			)
+
+		case elaborationEntryEntitlementDeclarationType:
+			elaboration.SetEntitlementDeclarationWithType(
+				decodedElement[*ast.EntitlementDeclaration](d, index),
+				decodeTypeAs[*sema.EntitlementType](d),
+			)
+
+		case elaborationEntryEntitlementMapDeclarationType:
+			elaboration.SetEntitlementMapDeclarationWithType(
+				decodedElement[*ast.EntitlementMappingDeclaration](d, index),
+				decodeTypeAs[*sema.EntitlementMapType](d),
+			)
+
+		case elaborationEntryConstructorFunctionType:
+			elaboration.SetConstructorFunctionType(
+				decodedElement[*ast.SpecialFunctionDeclaration](d, index),
+				decodeTypeAs[*sema.FunctionType](d),
+			)
+
+		case elaborationEntryFunctionExpressionFunctionType:
+			elaboration.SetFunctionExpressionFunctionType(
+				decodedElement[*ast.FunctionExpression](d, index),
+				decodeTypeAs[*sema.FunctionType](d),
+			)
+
+		case elaborationEntryInvocationExpressionTypes:
+			elaboration.SetInvocationExpressionTypes(
+				decodedElement[*ast.InvocationExpression](d, index),
+				sema.InvocationExpressionTypes{
+					ReturnType:         d.decodeType(),
+					TypeArguments:      d.decodeTypeArguments(),
+					ArgumentTypes:      d.decodeTypes(),
+					TypeParameterTypes: d.decodeTypes(),
+				},
+			)
+
+		case elaborationEntryCastingExpressionTypes:
+			elaboration.SetCastingExpressionTypes(
+				decodedElement[*ast.CastingExpression](d, index),
+				sema.CastingExpressionTypes{
+					StaticValueType: d.decodeType(),
+					TargetType:      d.decodeType(),
+				},
+			)
+
+		case elaborationEntryStaticCastTypes:
+			elaboration.SetStaticCastTypes(
+				decodedElement[*ast.CastingExpression](d, index),
+				sema.CastTypes{
+					ExprActualType: d.decodeType(),
+					TargetType:     d.decodeType(),
+					ExpectedType:   d.decodeType(),
+				},
+			)
+
+		case elaborationEntryRuntimeCastTypes:
+			elaboration.SetRuntimeCastTypes(
+				decodedElement[*ast.CastingExpression](d, index),
+				sema.RuntimeCastTypes{
+					Left:  d.decodeType(),
+					Right: d.decodeType(),
+				},
+			)
+
+		case elaborationEntryStringExpressionType:
+			elaboration.SetStringExpressionType(
+				decodedElement[*ast.StringExpression](d, index),
+				d.decodeType(),
+			)
+
+		case elaborationEntryReturnStatementTypes:
+			elaboration.SetReturnStatementTypes(
+				decodedElement[*ast.ReturnStatement](d, index),
+				sema.ReturnStatementTypes{
+					ValueType:  d.decodeType(),
+					ReturnType: d.decodeType(),
+				},
+			)
+
+		case elaborationEntryBinaryExpressionTypes:
+			elaboration.SetBinaryExpressionTypes(
+				decodedElement[*ast.BinaryExpression](d, index),
+				sema.BinaryExpressionTypes{
+					ResultType: d.decodeType(),
+					LeftType:   d.decodeType(),
+					RightType:  d.decodeType(),
+				},
+			)
+
+		case elaborationEntryNestedResourceMoveExpression:
+			elaboration.SetIsNestedResourceMoveExpression(
+				decodedElement[ast.Expression](d, index),
+			)
+
+		case elaborationEntryNumberConversionArgumentTypes:
+			expression := decodedElement[ast.Expression](d, index)
+			types := sema.NumberConversionArgumentTypes{
+				Type: d.decodeType(),
+			}
+			d.decodeValue(reflect.ValueOf(&types.Range).Elem())
+			elaboration.SetNumberConversionArgumentTypes(expression, types)
+
+		case elaborationEntryExpressionTypes:
+			elaboration.SetExpressionTypes(
+				decodedElement[ast.Expression](d, index),
+				sema.ExpressionTypes{
+					ActualType:   d.decodeType(),
+					ExpectedType: d.decodeType(),
+				},
+			)
+
+		case elaborationEntryArrayExpressionTypes:
+			elaboration.SetArrayExpressionTypes(
+				decodedElement[*ast.ArrayExpression](d, index),
+				sema.ArrayExpressionTypes{
+					ArrayType:     decodeTypeAs[sema.ArrayType](d),
+					ArgumentTypes: d.decodeTypes(),
+				},
+			)
+
+		case elaborationEntryDictionaryExpressionTypes:
+			expression := decodedElement[*ast.DictionaryExpression](d, index)
+			types := sema.DictionaryExpressionTypes{
+				DictionaryType: decodeTypeAs[*sema.DictionaryType](d),
+			}
+			if count := d.readUint(); count > 0 {
+				count--
+				if count > uint64(d.remaining()) {
+					invalid("truncated")
+				}
+				types.EntryTypes = make([]sema.DictionaryEntryType, count)
+				for i := range types.EntryTypes {
+					types.EntryTypes[i] = sema.DictionaryEntryType{
+						KeyType:   d.decodeType(),
+						ValueType: d.decodeType(),
+					}
+				}
+			}
+			elaboration.SetDictionaryExpressionTypes(expression, types)
+
+		case elaborationEntryIntegerExpressionType:
+			elaboration.SetIntegerExpressionType(
+				decodedElement[*ast.IntegerExpression](d, index),
+				d.decodeType(),
+			)
+
+		case elaborationEntryMemberExpressionMemberAccessInfo:
+			elaboration.SetMemberExpressionMemberAccessInfo(
+				decodedElement[*ast.MemberExpression](d, index),
+				sema.MemberAccessInfo{
+					AccessedType:    d.decodeType(),
+					ResultingType:   d.decodeType(),
+					Member:          d.decodeMember(),
+					IsOptional:      d.readBool(),
+					ReturnReference: d.readBool(),
+				},
+			)
+
+		case elaborationEntryMemberExpressionExpectedType:
+			elaboration.SetMemberExpressionExpectedType(
+				decodedElement[*ast.MemberExpression](d, index),
+				d.decodeType(),
+			)
+
+		case elaborationEntryFixedPointExpressionType:
+			elaboration.SetFixedPointExpression(
+				decodedElement[*ast.FixedPointExpression](d, index),
+				d.decodeType(),
+			)
+
+		case elaborationEntryTransactionDeclarationType:
+			elaboration.SetTransactionDeclarationType(
+				decodedElement[*ast.TransactionDeclaration](d, index),
+				decodeTypeAs[*sema.TransactionType](d),
+			)
+
+		case elaborationEntrySwapStatementTypes:
+			elaboration.SetSwapStatementTypes(
+				decodedElement[*ast.SwapStatement](d, index),
+				sema.SwapStatementTypes{
+					LeftType:  d.decodeType(),
+					RightType: d.decodeType(),
+				},
+			)
+
+		case elaborationEntryDefaultDestroyDeclaration:
+			declaration := decodedElement[ast.Declaration](d, index)
+			eventDeclaration, ok := d.decodeElement().(ast.CompositeLikeDeclaration)
+			if !ok {
+				invalid("invalid default destroy event declaration")
+			}
+			elaboration.SetDefaultDestroyDeclaration(declaration, eventDeclaration)
+
+		case elaborationEntryPostConditionsRewrite:
+			conditions := decodedElement[*ast.Conditions](d, index)
+			var rewrite sema.PostConditionsRewrite
+			d.decodeValue(reflect.ValueOf(&rewrite).Elem())
+			elaboration.SetPostConditionsRewrite(conditions, rewrite)
+
+		case elaborationEntryEmitStatementEventType:
+			var statement *ast.EmitStatement
+			switch element := decodedElement[ast.Element](d, index).(type) {
+			case *ast.EmitStatement:
+				statement = element
+			case *ast.EmitCondition:
+				statement = (*ast.EmitStatement)(element)
+			default:
+				invalid("invalid emit statement %T", element)
+			}
+			elaboration.SetEmitStatementEventType(
+				statement,
+				decodeTypeAs[*sema.CompositeType](d),
+			)
+
+		case elaborationEntryIdentifierInInvocationType:
+			elaboration.SetIdentifierInInvocationType(
+				decodedElement[*ast.IdentifierExpression](d, index),
+				d.decodeType(),
+			)
+
+		case elaborationEntryImportDeclarationResolvedLocations:
+			declaration := decodedElement[*ast.ImportDeclaration](d, index)
+			count := d.readLength()
+			resolvedLocations := make([]sema.ResolvedLocation, count)
+			for i := range resolvedLocations {
+				resolvedLocation := &resolvedLocations[i]
+				resolvedLocation.Location = d.readLocation()
+				d.decodeValue(reflect.ValueOf(&resolvedLocation.Identifiers).Elem())
+			}
+			elaboration.SetImportDeclarationsResolvedLocations(declaration, resolvedLocations)
+
+		case elaborationEntryReferenceExpressionBorrowType:
+			elaboration.SetReferenceExpressionBorrowType(
+				decodedElement[*ast.ReferenceExpression](d, index),
+				d.decodeType(),
+			)
+
+		case elaborationEntryIndexExpressionTypes:
+			elaboration.SetIndexExpressionTypes(
+				decodedElement[*ast.IndexExpression](d, index),
+				sema.IndexExpressionTypes{
+					IndexedType:     decodeTypeAs[sema.ValueIndexableType](d),
+					IndexingType:    d.decodeType(),
+					ResultType:      d.decodeType(),
+					ReturnReference: d.readBool(),
+				},
+			)
+
+		case elaborationEntryAttachmentAccessType:
+			elaboration.SetAttachmentAccessTypes(
+				decodedElement[*ast.IndexExpression](d, index),
+				d.decodeType(),
+			)
+
+		case elaborationEntryAttachmentRemoveType:
+			elaboration.SetAttachmentRemoveTypes(
+				decodedElement[*ast.RemoveStatement](d, index),
+				d.decodeType(),
+			)
+
+		case elaborationEntryAttachType:
+			elaboration.SetAttachTypes(
+				decodedElement[*ast.AttachExpression](d, index),
+				decodeTypeAs[*sema.CompositeType](d),
+			)
+
+		case elaborationEntryForceExpressionType:
+			elaboration.SetForceExpressionType(
+				decodedElement[*ast.ForceExpression](d, index),
+				d.decodeType(),
+			)
+
+		case elaborationEntryForStatementTypes:
+			elaboration.SetForStatementType(
+				decodedElement[*ast.ForStatement](d, index),
+				sema.ForStatementTypes{
+					IndexVariableType: d.decodeType(),
+					ValueVariableType: d.decodeType(),
+				},
+			)
+
+		case elaborationEntryCompositeTypeDeclaration:
+			compositeType := decodedType[*sema.CompositeType](d, index)
+			declaration, ok := d.decodeElement().(ast.CompositeLikeDeclaration)
+			if !ok {
+				invalid("invalid composite declaration")
+			}
+			elaboration.SetCompositeTypeDeclaration(compositeType, declaration)
+
+		// The declared types are registered once all types are decoded,
+		// see programDecoder.registrations
+
+		case elaborationEntryCompositeType:
+			compositeType := decodedType[*sema.CompositeType](d, index)
+			d.registrations = append(d.registrations, func() {
+				elaboration.SetCompositeType(compositeType.ID(), compositeType)
+			})
+
+		case elaborationEntryInterfaceType:
+			interfaceType := decodedType[*sema.InterfaceType](d, index)
+			d.registrations = append(d.registrations, func() {
+				elaboration.SetInterfaceType(interfaceType.ID(), interfaceType)
+			})
+
+		case elaborationEntryEntitlementType:
+			entitlementType := decodedType[*sema.EntitlementType](d, index)
+			d.registrations = append(d.registrations, func() {
+				elaboration.SetEntitlementType(entitlementType.ID(), entitlementType)
+			})
+
+		case elaborationEntryEntitlementMapType:
+			entitlementMapType := decodedType[*sema.EntitlementMapType](d, index)
+			d.registrations = append(d.registrations, func() {
+				elaboration.SetEntitlementMapType(entitlementMapType.ID(), entitlementMapType)
+			})
+
+		default:
+			invalid("invalid elaboration entry kind %d", kind)
+		}
+	}
+}
+
+// decodedElement returns the decoded AST element with the given index
+func decodedElement[T any](d *programDecoder, index uint64) T {
+	if index >= uint64(len(d.elements)) || !d.elements[index].IsValid() {
+		invalid("invalid AST element reference %d", index)
+	}
+	element, ok := d.elements[index].Interface().(T)
+	if !ok {
+		invalid("invalid AST element reference %d", index)
+	}
+	return element
+}
+
+// decodedType returns the decoded type with the given index
+func decodedType[T sema.Type](d *programDecoder, index uint64) T {
+	if index >= uint64(len(d.types)) {
+		invalid("invalid type reference %d", index)
+	}
+	ty, ok := d.types[index].(T)
+	if !ok {
+		invalid("invalid type reference %d", index)
+	}
+	return ty
+}
+
+// decodeTypeAs decodes a type, which must be nil or of the given type
+func decodeTypeAs[T sema.Type](d *programDecoder) T {
+	var result T
+	ty := d.decodeType()
+	if ty == nil {
+		return result
+	}
+	result, ok := ty.(T)
+	if !ok {
+		invalid("invalid type %T", ty)
+	}
+	return result
+}
+
+func (d *programDecoder) decodeNestedDeclarations() map[string]ast.Declaration {
+	count := d.readLength()
+	nestedDeclarations := make(map[string]ast.Declaration, count)
+	for i := 0; i < count; i++ {
+		name := d.readString()
+		declaration, ok := d.decodeElement().(ast.Declaration)
+		if !ok {
+			invalid("invalid nested declaration %s", name)
+		}
+		nestedDeclarations[name] = declaration
+	}
+	return nestedDeclarations
+}
+
+func (d *programDecoder) decodeTypes() []sema.Type {
+	count := d.readUint()
+	if count == 0 {
+		return nil
+	}
+	count--
+	if count > uint64(d.remaining()) {
+		invalid("truncated")
+	}
+	types := make([]sema.Type, count)
+	for i := range types {
+		types[i] = d.decodeType()
+	}
+	return types
+}
+
+func (d *programDecoder) decodeTypeArguments() *sema.TypeParameterTypeOrderedMap {
+	count := d.readUint()
+	if count == 0 {
+		return nil
+	}
+	count--
+
+	typeArguments := &sema.TypeParameterTypeOrderedMap{}
+	for i := uint64(0); i < count; i++ {
+		typeParameter := d.decodeTypeParameter()
+		typeArguments.Set(typeParameter, d.decodeType())
+	}
+	return typeArguments
+}
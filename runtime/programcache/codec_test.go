@@ -0,0 +1,523 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package programcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	. "github.com/onflow/cadence/runtime/programcache"
+	"github.com/onflow/cadence/runtime/sema"
+	. "github.com/onflow/cadence/runtime/tests/runtime_utils"
+	. "github.com/onflow/cadence/runtime/tests/utils"
+)
+
+const testFungibleTokenContract = `
+  access(all) contract interface FungibleToken {
+
+      access(all) entitlement Withdraw
+
+      access(all) event Withdrawn(amount: UFix64)
+
+      access(all) resource interface Vault {
+
+          access(all) var balance: UFix64
+
+          access(Withdraw) fun withdraw(amount: UFix64): @{Vault} {
+              pre {
+                  amount <= self.balance: "insufficient balance"
+              }
+              post {
+                  result.balance == amount: "incorrect amount"
+                  self.balance == before(self.balance) - amount
+                  emit Withdrawn(amount: amount)
+              }
+          }
+
+          access(all) fun deposit(from: @{Vault})
+      }
+
+      access(all) fun createEmptyVault(): @{Vault}
+  }
+`
+
+const testTokenContract = `
+  import FungibleToken from 0x1
+
+  access(all) contract Token: FungibleToken {
+
+      access(all) entitlement Inspect
+
+      access(all) entitlement mapping Reveal {
+          FungibleToken.Withdraw -> Inspect
+      }
+
+      access(all) struct interface HasID {
+          access(all) let id: UInt64
+      }
+
+      access(all) struct Info: HasID {
+          access(all) let id: UInt64
+          access(Inspect) let secret: String
+
+          init(id: UInt64, secret: String) {
+              self.id = id
+              self.secret = secret
+          }
+      }
+
+      access(all) enum Kind: UInt8 {
+          access(all) case fungible
+          access(all) case nonFungible
+      }
+
+      access(all) resource Vault: FungibleToken.Vault {
+
+          access(all) event ResourceDestroyed(balance: UFix64 = self.balance)
+
+          access(all) var balance: UFix64
+
+          access(mapping Reveal) let info: Info
+
+          init(balance: UFix64) {
+              self.balance = balance
+              self.info = Info(id: 1, secret: "hidden")
+          }
+
+          access(FungibleToken.Withdraw) fun withdraw(amount: UFix64): @{FungibleToken.Vault} {
+              self.balance = self.balance - amount
+              return <-create Vault(balance: amount)
+          }
+
+          access(all) fun deposit(from: @{FungibleToken.Vault}) {
+              let vault <- from as! @Vault
+              self.balance = self.balance + vault.balance
+              vault.balance = 0.0
+              destroy vault
+          }
+      }
+
+      access(all) attachment Note for Vault {
+          access(all) let text: String
+
+          init(text: String) {
+              self.text = text
+          }
+
+          access(all) fun describe(): String {
+              return self.text.concat(" ").concat(base.balance.toString())
+          }
+      }
+
+      access(all) fun createEmptyVault(): @{FungibleToken.Vault} {
+          return <-create Vault(balance: 0.0)
+      }
+
+      access(all) fun mint(amount: UFix64): @Vault {
+          return <-create Vault(balance: amount)
+      }
+
+      access(all) fun compute(): {String: [Int]} {
+          var numbers: [Int] = []
+          for i, n in [1, 2, 3] {
+              numbers.append(n * i)
+          }
+          var i = 0
+          while i < 3 {
+              i = i + 1
+              if i == 2 {
+                  continue
+              }
+              numbers.insert(at: 0, i)
+          }
+          let kind = Kind.nonFungible
+          switch kind {
+          case Kind.fungible:
+              numbers.append(-1)
+          default:
+              numbers.append(Int(kind.rawValue))
+          }
+          let optional: Int? = numbers.length > 0 ? numbers[0] : nil
+          let sum = numbers.reduce(initial: optional!, fun (acc: Int, n: Int): Int {
+              return acc + n
+          })
+          var a = 1
+          var b = 2
+          a <-> b
+          let range = InclusiveRange(1, 9, step: 2)
+          return {
+              "numbers": numbers,
+              "sum": [sum, a, b, Int(UInt8(7)), range.end],
+              "types": [Type<@Vault>().identifier.length]
+          }
+      }
+
+      init() {
+          let vault <- create Vault(balance: 1.0)
+          self.account.storage.save(<-vault, to: /storage/vault)
+          let capability = self.account.capabilities.storage.issue<&Vault>(/storage/vault)
+          self.account.capabilities.publish(capability, at: /public/vault)
+      }
+  }
+`
+
+const testTokenScript = `
+  import FungibleToken from 0x1
+  import Token from 0x1
+
+  access(all) fun main(): [AnyStruct] {
+      let vault <- Token.mint(amount: 10.0)
+      let ref = &vault as auth(FungibleToken.Withdraw) &Token.Vault
+      let withdrawn <- ref.withdraw(amount: 3.0)
+      let secret = ref.info.secret
+      vault.deposit(from: <-Token.createEmptyVault())
+
+      let noted <- attach Token.Note(text: "balance") to <-vault
+      let description = noted[Token.Note]!.describe()
+      let balance = noted.balance
+      let stored = getAccount(0x1).capabilities.borrow<&Token.Vault>(/public/vault)?.balance
+
+      destroy withdrawn
+      destroy noted
+
+      return [balance, description, secret, stored, Token.compute(), Token.Kind.fungible.rawValue]
+  }
+`
+
+// recordingCodec is a BinaryCodec which records the encoded programs
+type recordingCodec struct {
+	BinaryCodec
+	programs map[common.Location]*interpreter.Program
+}
+
+var _ Codec = &recordingCodec{}
+
+func (c *recordingCodec) EncodeProgram(
+	location common.Location,
+	program *interpreter.Program,
+) (
+	[]byte,
+	[]common.Location,
+	error,
+) {
+	c.programs[location] = program
+	return c.BinaryCodec.EncodeProgram(location, program)
+}
+
+// programsResolver is a Resolver which provides the given programs and codes
+type programsResolver struct {
+	codes    map[common.Location][]byte
+	programs map[common.Location]*interpreter.Program
+}
+
+var _ Resolver = programsResolver{}
+
+func (r programsResolver) GetCode(location common.Location) ([]byte, error) {
+	code, ok := r.codes[location]
+	if !ok {
+		return nil, DependencyUnavailableError{Location: location}
+	}
+	return code, nil
+}
+
+func (r programsResolver) GetProgram(location common.Location) (*interpreter.Program, error) {
+	program, ok := r.programs[location]
+	if !ok {
+		return nil, DependencyUnavailableError{Location: location}
+	}
+	return program, nil
+}
+
+// tokenTestEnvironment deploys the test contracts,
+// and executes the test script with a new interface for each execution.
+// If the codec is nil, programs are not cached
+type tokenTestEnvironment struct {
+	t            *testing.T
+	runtime      runtime.Runtime
+	ledger       TestLedger
+	accountCodes map[common.Location][]byte
+	codec        Codec
+	store        Store
+	events       []cadence.Event
+}
+
+var testAddress = common.MustBytesToAddress([]byte{0x1})
+
+func newTokenTestEnvironment(t *testing.T, codec Codec) *tokenTestEnvironment {
+	env := &tokenTestEnvironment{
+		t:            t,
+		runtime:      runtime.NewInterpreterRuntime(runtime.Config{AttachmentsEnabled: true}),
+		ledger:       NewTestLedger(nil, nil),
+		accountCodes: map[common.Location][]byte{},
+		codec:        codec,
+		store:        NewMemoryStore(),
+	}
+
+	for _, contract := range []struct {
+		name string
+		code string
+	}{
+		{"FungibleToken", testFungibleTokenContract},
+		{"Token", testTokenContract},
+	} {
+		var checks map[common.Location]int
+		err := env.runtime.ExecuteTransaction(
+			runtime.Script{
+				Source: DeploymentTransaction(contract.name, []byte(contract.code)),
+			},
+			runtime.Context{
+				Interface: env.newInterface(&checks),
+				Location:  common.TransactionLocation{},
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	return env
+}
+
+func (env *tokenTestEnvironment) newInterface(checks *map[common.Location]int) runtime.Interface {
+	*checks = map[common.Location]int{}
+
+	runtimeInterface := &TestRuntimeInterface{
+		Storage: env.ledger,
+		OnGetSigningAccounts: func() ([]runtime.Address, error) {
+			return []runtime.Address{testAddress}, nil
+		},
+		OnGetAccountContractCode: func(location common.AddressLocation) ([]byte, error) {
+			return env.accountCodes[location], nil
+		},
+		OnUpdateAccountContractCode: func(location common.AddressLocation, code []byte) error {
+			env.accountCodes[location] = code
+			return nil
+		},
+		OnEmitEvent: func(event cadence.Event) error {
+			env.events = append(env.events, event)
+			return nil
+		},
+		OnResolveLocation: NewSingleIdentifierLocationResolver(env.t),
+		OnProgramChecked: func(location runtime.Location, _ time.Duration) {
+			(*checks)[location]++
+		},
+	}
+
+	if env.codec == nil {
+		return runtimeInterface
+	}
+
+	cache := NewCache(Config{
+		CadenceVersion: cadence.Version,
+		Codec:          env.codec,
+		Store:          env.store,
+		OnError: func(key Key, err error) {
+			env.t.Errorf("unexpected program cache error for %s: %s", key, err)
+		},
+	})
+
+	return NewInterface(runtimeInterface, cache)
+}
+
+func (env *tokenTestEnvironment) executeScript() (cadence.Value, []cadence.Event, map[common.Location]int) {
+	env.events = nil
+
+	var checks map[common.Location]int
+
+	result, err := env.runtime.ExecuteScript(
+		runtime.Script{
+			Source: []byte(testTokenScript),
+		},
+		runtime.Context{
+			Interface: env.newInterface(&checks),
+			Location:  common.ScriptLocation{},
+		},
+	)
+	require.NoError(env.t, err)
+
+	return result, env.events, checks
+}
+
+func TestBinaryCodec(t *testing.T) {
+
+	t.Parallel()
+
+	fungibleTokenLocation := common.NewAddressLocation(nil, testAddress, "FungibleToken")
+	tokenLocation := common.NewAddressLocation(nil, testAddress, "Token")
+
+	t.Run("execution", func(t *testing.T) {
+		t.Parallel()
+
+		// The results of executions without a cache
+
+		uncachedEnv := newTokenTestEnvironment(t, nil)
+
+		result, events, checks := uncachedEnv.executeScript()
+		assert.Equal(t, 1, checks[fungibleTokenLocation])
+		assert.Equal(t, 1, checks[tokenLocation])
+
+		env := newTokenTestEnvironment(t, BinaryCodec{})
+
+		// The first execution stores the programs of the contracts
+
+		firstResult, firstEvents, _ := env.executeScript()
+		assert.Equal(t, result, firstResult)
+		assert.Equal(t, events, firstEvents)
+
+		// Each execution uses a new interface, which simulates a restart.
+		// The programs of the contracts are decoded, instead of parsed and checked
+
+		for i := 0; i < 2; i++ {
+			cachedResult, cachedEvents, cachedChecks := env.executeScript()
+			assert.Equal(t, result, cachedResult)
+			assert.Equal(t, events, cachedEvents)
+			assert.Equal(t, 0, cachedChecks[fungibleTokenLocation])
+			assert.Equal(t, 0, cachedChecks[tokenLocation])
+		}
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		t.Parallel()
+
+		codec := &recordingCodec{
+			programs: map[common.Location]*interpreter.Program{},
+		}
+
+		env := newTokenTestEnvironment(t, codec)
+		env.executeScript()
+
+		require.Contains(t, codec.programs, fungibleTokenLocation)
+		require.Contains(t, codec.programs, tokenLocation)
+
+		resolver := programsResolver{
+			codes:    env.accountCodes,
+			programs: codec.programs,
+		}
+
+		for _, location := range []common.Location{fungibleTokenLocation, tokenLocation} {
+			program := codec.programs[location]
+
+			data, imports, err := BinaryCodec{}.EncodeProgram(location, program)
+			require.NoError(t, err)
+
+			otherData, otherImports, err := BinaryCodec{}.EncodeProgram(location, program)
+			require.NoError(t, err)
+
+			assert.Equal(t, data, otherData)
+			assert.Equal(t, imports, otherImports)
+
+			// Encoding the decoded program results in the same encoding
+
+			decoded, err := BinaryCodec{}.DecodeProgram(location, data, resolver)
+			require.NoError(t, err)
+
+			decodedData, decodedImports, err := BinaryCodec{}.EncodeProgram(location, decoded)
+			require.NoError(t, err)
+
+			assert.Equal(t, data, decodedData)
+			assert.Equal(t, imports, decodedImports)
+		}
+
+		// The token contract depends on the fungible token contract
+
+		_, imports, err := BinaryCodec{}.EncodeProgram(tokenLocation, codec.programs[tokenLocation])
+		require.NoError(t, err)
+		assert.Equal(t, []common.Location{fungibleTokenLocation}, imports)
+	})
+
+	t.Run("changed import", func(t *testing.T) {
+		t.Parallel()
+
+		env := newTokenTestEnvironment(t, BinaryCodec{})
+
+		result, _, _ := env.executeScript()
+
+		// Changing the code of the imported contract, without changing its semantics,
+		// results in a different key, so the program of the importing contract is checked again
+
+		env.accountCodes[fungibleTokenLocation] = append(
+			[]byte("// changed\n"),
+			env.accountCodes[fungibleTokenLocation]...,
+		)
+
+		changedResult, _, checks := env.executeScript()
+		assert.Equal(t, result, changedResult)
+		assert.Equal(t, 1, checks[fungibleTokenLocation])
+		assert.Equal(t, 1, checks[tokenLocation])
+
+		_, _, checks = env.executeScript()
+		assert.Equal(t, 0, checks[fungibleTokenLocation])
+		assert.Equal(t, 0, checks[tokenLocation])
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		t.Parallel()
+
+		codec := &recordingCodec{
+			programs: map[common.Location]*interpreter.Program{},
+		}
+
+		env := newTokenTestEnvironment(t, codec)
+		env.executeScript()
+
+		data, _, err := BinaryCodec{}.EncodeProgram(tokenLocation, codec.programs[tokenLocation])
+		require.NoError(t, err)
+
+		resolver := programsResolver{
+			codes:    env.accountCodes,
+			programs: codec.programs,
+		}
+
+		for _, invalidData := range [][]byte{
+			nil,
+			data[:len(data)/2],
+			append(data[:len(data):len(data)], 0),
+		} {
+			_, err := BinaryCodec{}.DecodeProgram(tokenLocation, invalidData, resolver)
+			require.ErrorAs(t, err, &InvalidEntryError{})
+		}
+
+		// Missing imports are unavailable dependencies
+
+		_, err = BinaryCodec{}.DecodeProgram(tokenLocation, data, programsResolver{})
+		require.ErrorAs(t, err, &DependencyUnavailableError{})
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		t.Parallel()
+
+		location := common.NewAddressLocation(nil, testAddress, "C")
+
+		program := newTestProgram()
+		program.Elaboration.SetGlobalValue("x", &sema.Variable{
+			Identifier: "x",
+			Type: &sema.SimpleType{
+				Name: "Unknown",
+			},
+		})
+
+		_, _, err := BinaryCodec{}.EncodeProgram(location, program)
+		require.ErrorAs(t, err, &UnsupportedProgramError{})
+	})
+}
@@ -0,0 +1,960 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package programcache
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/stdlib"
+)
+
+var builtinTypesOnce sync.Once
+var builtinTypesByID map[sema.TypeID]sema.Type
+
+// builtinTypes returns the builtin types, which are encoded by their type ID.
+//
+// Types which have the same type ID as another builtin type are ambiguous,
+// and cannot be encoded by their type ID
+func builtinTypes() map[sema.TypeID]sema.Type {
+	builtinTypesOnce.Do(func() {
+		types := map[sema.TypeID]sema.Type{}
+		ambiguous := map[sema.TypeID]struct{}{}
+
+		add := func(ty sema.Type) {
+			sema.VisitThisAndNested(ty, func(ty sema.Type) {
+				typeID := ty.ID()
+				if existing, ok := types[typeID]; ok && existing != ty {
+					ambiguous[typeID] = struct{}{}
+					return
+				}
+				types[typeID] = ty
+			})
+		}
+
+		_ = sema.BaseTypeActivation.ForEach(func(_ string, variable *sema.Variable) error {
+			add(variable.Type)
+			return nil
+		})
+
+		for _, ty := range sema.AllNumberTypes {
+			add(ty)
+		}
+
+		for _, ty := range []sema.Type{
+			sema.InvalidType,
+			sema.NeverType,
+			sema.VoidType,
+			sema.AnyType,
+			sema.AnyStructType,
+			sema.AnyResourceType,
+			sema.AnyStructAttachmentType,
+			sema.AnyResourceAttachmentType,
+			sema.HashableStructType,
+			sema.StorableType,
+			sema.PathType,
+			sema.CapabilityPathType,
+			sema.StoragePathType,
+			sema.PublicPathType,
+			sema.PrivatePathType,
+			sema.BlockType,
+			sema.MetaType,
+			sema.CharacterType,
+			sema.StringType,
+			sema.BoolType,
+			sema.TheAddressType,
+			sema.DeployedContractType,
+			sema.StorageCapabilityControllerType,
+			sema.AccountCapabilityControllerType,
+			stdlib.BLSType,
+			stdlib.RLPType,
+		} {
+			add(ty)
+		}
+
+		for _, ty := range sema.NativeCompositeTypes { //nolint:maprange
+			add(ty)
+		}
+		for _, ty := range sema.BuiltinEntitlements { //nolint:maprange
+			add(ty)
+		}
+		for _, ty := range sema.BuiltinEntitlementMappings { //nolint:maprange
+			add(ty)
+		}
+		for _, ty := range stdlib.FlowEventTypes { //nolint:maprange
+			add(ty)
+		}
+
+		for typeID := range ambiguous { //nolint:maprange
+			delete(types, typeID)
+		}
+
+		builtinTypesByID = types
+	})
+
+	return builtinTypesByID
+}
+
+func isBuiltinType(ty sema.Type) bool {
+	return builtinTypes()[ty.ID()] == ty
+}
+
+// Type tags are part of the encoding, see elementTag
+const (
+	typeTagNil                    = 0
+	typeTagReference              = 1
+	typeTagBuiltin                = 2
+	typeTagImportedComposite      = 3
+	typeTagImportedInterface      = 4
+	typeTagImportedEntitlement    = 5
+	typeTagImportedEntitlementMap = 6
+	typeTagOptional               = 7
+	typeTagVariableSized          = 8
+	typeTagConstantSized          = 9
+	typeTagDictionary             = 10
+	typeTagInclusiveRange         = 11
+	typeTagReferenceType          = 12
+	typeTagIntersection           = 13
+	typeTagCapability             = 14
+	typeTagFunction               = 15
+	typeTagGeneric                = 16
+	typeTagTransaction            = 17
+	typeTagComposite              = 18
+	typeTagInterface              = 19
+	typeTagEntitlement            = 20
+	typeTagEntitlementMap         = 21
+)
+
+// encodeType encodes the given type.
+//
+// A type is encoded once, and as a reference afterwards.
+// Types declared by the program are encoded completely,
+// builtin types by their type ID,
+// and types declared by other programs by their location and type ID
+func (e *programEncoder) encodeType(ty sema.Type) {
+	if ty == nil {
+		e.writeUint(typeTagNil)
+		return
+	}
+
+	if index, ok := e.types[ty]; ok {
+		e.writeUint(typeTagReference)
+		e.writeUint(uint64(index))
+		return
+	}
+
+	e.types[ty] = len(e.types)
+
+	switch ty := ty.(type) {
+	case *sema.SimpleType,
+		*sema.NumericType,
+		*sema.FixedPointNumericType,
+		*sema.AddressType:
+
+		if !isBuiltinType(ty) {
+			unsupported("type %s", ty.ID())
+		}
+		e.encodeBuiltinType(ty)
+
+	case *sema.CompositeType:
+		if e.encodeNominalType(ty, ty.Location, typeTagImportedComposite) {
+			return
+		}
+		e.writeUint(typeTagComposite)
+		e.declaredTypes = append(e.declaredTypes, ty)
+
+		e.writeString(ty.Identifier)
+		e.writeUint(uint64(ty.Kind))
+		e.encodeType(ty.GetContainerType())
+		e.encodeNestedTypes(ty.NestedTypes)
+		e.encodeType(ty.EnumRawType)
+		e.encodeType(ty.GetBaseType())
+		e.encodeOptionalType(ty.DefaultDestroyEvent)
+		e.encodeMembers(ty.Members)
+		e.encodeStrings(ty.Fields)
+		e.encodeParameters(ty.ConstructorParameters)
+		e.writeUint(uint64(ty.ConstructorPurity))
+		e.encodeInterfaceTypes(ty.ExplicitInterfaceConformances)
+		e.writeBool(ty.HasComputedMembers)
+		e.writeBool(ty.ImportableBuiltin)
+
+	case *sema.InterfaceType:
+		if e.encodeNominalType(ty, ty.Location, typeTagImportedInterface) {
+			return
+		}
+		e.writeUint(typeTagInterface)
+		e.declaredTypes = append(e.declaredTypes, ty)
+
+		e.writeString(ty.Identifier)
+		e.writeUint(uint64(ty.CompositeKind))
+		e.encodeType(ty.GetContainerType())
+		e.encodeNestedTypes(ty.NestedTypes)
+		e.encodeOptionalType(ty.DefaultDestroyEvent)
+		e.encodeMembers(ty.Members)
+		e.encodeStrings(ty.Fields)
+		e.encodeParameters(ty.InitializerParameters)
+		e.writeUint(uint64(ty.InitializerPurity))
+		e.encodeInterfaceTypes(ty.ExplicitInterfaceConformances)
+
+	case *sema.EntitlementType:
+		if e.encodeNominalType(ty, ty.Location, typeTagImportedEntitlement) {
+			return
+		}
+		e.writeUint(typeTagEntitlement)
+		e.declaredTypes = append(e.declaredTypes, ty)
+
+		e.writeString(ty.Identifier)
+		e.encodeType(ty.GetContainerType())
+
+	case *sema.EntitlementMapType:
+		if e.encodeNominalType(ty, ty.Location, typeTagImportedEntitlementMap) {
+			return
+		}
+		e.writeUint(typeTagEntitlementMap)
+		e.declaredTypes = append(e.declaredTypes, ty)
+
+		e.writeString(ty.Identifier)
+		e.encodeType(ty.GetContainerType())
+		e.writeBool(ty.IncludesIdentity)
+		e.writeUint(uint64(len(ty.Relations)))
+		for _, relation := range ty.Relations {
+			e.encodeType(relation.Input)
+			e.encodeType(relation.Output)
+		}
+
+	case *sema.TransactionType:
+		e.writeUint(typeTagTransaction)
+		e.encodeStrings(ty.Fields)
+		e.encodeParameters(ty.PrepareParameters)
+		e.encodeParameters(ty.Parameters)
+		e.encodeMembers(ty.Members)
+
+	case *sema.OptionalType:
+		e.writeUint(typeTagOptional)
+		e.encodeType(ty.Type)
+
+	case *sema.VariableSizedType:
+		e.writeUint(typeTagVariableSized)
+		e.encodeType(ty.Type)
+
+	case *sema.ConstantSizedType:
+		e.writeUint(typeTagConstantSized)
+		e.writeInt(ty.Size)
+		e.encodeType(ty.Type)
+
+	case *sema.DictionaryType:
+		e.writeUint(typeTagDictionary)
+		e.encodeType(ty.KeyType)
+		e.encodeType(ty.ValueType)
+
+	case *sema.InclusiveRangeType:
+		e.writeUint(typeTagInclusiveRange)
+		e.encodeType(ty.MemberType)
+
+	case *sema.ReferenceType:
+		e.writeUint(typeTagReferenceType)
+		e.encodeAccess(ty.Authorization)
+		e.encodeType(ty.Type)
+
+	case *sema.IntersectionType:
+		e.writeUint(typeTagIntersection)
+		e.encodeInterfaceTypes(ty.Types)
+		e.encodeType(ty.LegacyType)
+
+	case *sema.CapabilityType:
+		e.writeUint(typeTagCapability)
+		e.encodeType(ty.BorrowType)
+
+	case *sema.FunctionType:
+		e.writeUint(typeTagFunction)
+		e.writeUint(uint64(ty.Purity))
+		e.writeBool(ty.IsConstructor)
+		e.writeUint(uint64(len(ty.TypeParameters)))
+		for _, typeParameter := range ty.TypeParameters {
+			e.encodeTypeParameter(typeParameter)
+		}
+		e.encodeParameters(ty.Parameters)
+		e.encodeTypeAnnotation(ty.ReturnTypeAnnotation)
+		if ty.Arity == nil {
+			e.writeBool(false)
+		} else {
+			e.writeBool(true)
+			e.writeInt(int64(ty.Arity.Min))
+			e.writeInt(int64(ty.Arity.Max))
+		}
+		e.encodeMembers(ty.Members)
+
+	case *sema.GenericType:
+		e.writeUint(typeTagGeneric)
+		e.encodeTypeParameter(ty.TypeParameter)
+
+	default:
+		unsupported("type %T", ty)
+	}
+}
+
+func (e *programEncoder) encodeBuiltinType(ty sema.Type) {
+	e.writeUint(typeTagBuiltin)
+	e.writeString(string(ty.ID()))
+}
+
+// encodeNominalType encodes the given nominal type by reference,
+// if it is a builtin type or a type declared by another program.
+// Returns false if the type is declared by the program, and must be encoded completely
+func (e *programEncoder) encodeNominalType(ty sema.Type, location common.Location, importedTag uint64) bool {
+	if isBuiltinType(ty) {
+		e.encodeBuiltinType(ty)
+		return true
+	}
+
+	switch location {
+	case nil:
+		unsupported("type %s", ty.ID())
+
+	case e.location:
+		return false
+	}
+
+	e.addImport(location)
+
+	e.writeUint(importedTag)
+	e.writeLocation(location)
+	e.writeString(string(ty.ID()))
+
+	return true
+}
+
+func (e *programEncoder) encodeOptionalType(ty *sema.CompositeType) {
+	if ty == nil {
+		e.encodeType(nil)
+		return
+	}
+	e.encodeType(ty)
+}
+
+func (e *programEncoder) encodeInterfaceTypes(types []*sema.InterfaceType) {
+	if types == nil {
+		e.writeUint(0)
+		return
+	}
+	e.writeUint(uint64(len(types)) + 1)
+	for _, ty := range types {
+		e.encodeType(ty)
+	}
+}
+
+func (e *programEncoder) encodeNestedTypes(nestedTypes *sema.StringTypeOrderedMap) {
+	if nestedTypes == nil {
+		e.writeUint(0)
+		return
+	}
+	e.writeUint(uint64(nestedTypes.Len()) + 1)
+	nestedTypes.Foreach(func(name string, ty sema.Type) {
+		e.writeString(name)
+		e.encodeType(ty)
+	})
+}
+
+func (d *programDecoder) registerType(ty sema.Type) {
+	d.types = append(d.types, ty)
+}
+
+// decodeType decodes a type encoded by encodeType.
+//
+// New types are registered before their children are decoded,
+// so cyclic references between types can be resolved
+func (d *programDecoder) decodeType() sema.Type {
+	switch tag := d.readUint(); tag {
+	case typeTagNil:
+		return nil
+
+	case typeTagReference:
+		index := d.readUint()
+		if index >= uint64(len(d.types)) {
+			invalid("invalid type reference %d", index)
+		}
+		return d.types[index]
+
+	case typeTagBuiltin:
+		typeID := sema.TypeID(d.readString())
+		ty := builtinTypes()[typeID]
+		if ty == nil {
+			invalid("unknown builtin type %s", typeID)
+		}
+		d.registerType(ty)
+		return ty
+
+	case typeTagImportedComposite,
+		typeTagImportedInterface,
+		typeTagImportedEntitlement,
+		typeTagImportedEntitlementMap:
+
+		ty := d.decodeImportedType(tag)
+		d.registerType(ty)
+		return ty
+
+	case typeTagComposite:
+		ty := &sema.CompositeType{
+			Location: d.location,
+		}
+		d.registerType(ty)
+
+		ty.Identifier = d.readString()
+		ty.Kind = common.CompositeKind(d.readUint())
+		d.decodeContainerType(ty)
+		ty.NestedTypes = d.decodeNestedTypes(ty)
+		ty.EnumRawType = d.decodeType()
+		baseType := d.decodeType()
+		if baseType != nil {
+			ty.SetBaseType(baseType)
+		}
+		ty.DefaultDestroyEvent = d.decodeCompositeType()
+		ty.Members = d.decodeMembers()
+		ty.Fields = d.decodeStrings()
+		ty.ConstructorParameters = d.decodeParameters()
+		ty.ConstructorPurity = sema.FunctionPurity(d.readUint())
+		ty.ExplicitInterfaceConformances = d.decodeInterfaceTypes()
+		ty.HasComputedMembers = d.readBool()
+		ty.ImportableBuiltin = d.readBool()
+
+		return ty
+
+	case typeTagInterface:
+		ty := &sema.InterfaceType{
+			Location: d.location,
+		}
+		d.registerType(ty)
+
+		ty.Identifier = d.readString()
+		ty.CompositeKind = common.CompositeKind(d.readUint())
+		d.decodeContainerType(ty)
+		ty.NestedTypes = d.decodeNestedTypes(ty)
+		ty.DefaultDestroyEvent = d.decodeCompositeType()
+		ty.Members = d.decodeMembers()
+		ty.Fields = d.decodeStrings()
+		ty.InitializerParameters = d.decodeParameters()
+		ty.InitializerPurity = sema.FunctionPurity(d.readUint())
+		ty.ExplicitInterfaceConformances = d.decodeInterfaceTypes()
+
+		return ty
+
+	case typeTagEntitlement:
+		ty := &sema.EntitlementType{
+			Location: d.location,
+		}
+		d.registerType(ty)
+
+		ty.Identifier = d.readString()
+		d.decodeContainerType(ty)
+
+		return ty
+
+	case typeTagEntitlementMap:
+		ty := &sema.EntitlementMapType{
+			Location: d.location,
+		}
+		d.registerType(ty)
+
+		ty.Identifier = d.readString()
+		d.decodeContainerType(ty)
+		ty.IncludesIdentity = d.readBool()
+		relationCount := d.readLength()
+		for i := 0; i < relationCount; i++ {
+			ty.Relations = append(
+				ty.Relations,
+				sema.EntitlementRelation{
+					Input:  d.decodeEntitlementType(),
+					Output: d.decodeEntitlementType(),
+				},
+			)
+		}
+
+		return ty
+
+	case typeTagTransaction:
+		ty := &sema.TransactionType{}
+		d.registerType(ty)
+
+		ty.Fields = d.decodeStrings()
+		ty.PrepareParameters = d.decodeParameters()
+		ty.Parameters = d.decodeParameters()
+		ty.Members = d.decodeMembers()
+
+		return ty
+
+	case typeTagOptional:
+		ty := &sema.OptionalType{}
+		d.registerType(ty)
+		ty.Type = d.decodeType()
+		return ty
+
+	case typeTagVariableSized:
+		ty := &sema.VariableSizedType{}
+		d.registerType(ty)
+		ty.Type = d.decodeType()
+		return ty
+
+	case typeTagConstantSized:
+		ty := &sema.ConstantSizedType{}
+		d.registerType(ty)
+		ty.Size = d.readInt()
+		ty.Type = d.decodeType()
+		return ty
+
+	case typeTagDictionary:
+		ty := &sema.DictionaryType{}
+		d.registerType(ty)
+		ty.KeyType = d.decodeType()
+		ty.ValueType = d.decodeType()
+		return ty
+
+	case typeTagInclusiveRange:
+		ty := &sema.InclusiveRangeType{}
+		d.registerType(ty)
+		ty.MemberType = d.decodeType()
+		return ty
+
+	case typeTagReferenceType:
+		ty := &sema.ReferenceType{}
+		d.registerType(ty)
+		ty.Authorization = d.decodeAccess()
+		ty.Type = d.decodeType()
+		return ty
+
+	case typeTagIntersection:
+		ty := &sema.IntersectionType{}
+		d.registerType(ty)
+		ty.Types = d.decodeInterfaceTypes()
+		ty.LegacyType = d.decodeType()
+		return ty
+
+	case typeTagCapability:
+		ty := &sema.CapabilityType{}
+		d.registerType(ty)
+		ty.BorrowType = d.decodeType()
+		return ty
+
+	case typeTagFunction:
+		ty := &sema.FunctionType{}
+		d.registerType(ty)
+
+		ty.Purity = sema.FunctionPurity(d.readUint())
+		ty.IsConstructor = d.readBool()
+		typeParameterCount := d.readLength()
+		for i := 0; i < typeParameterCount; i++ {
+			ty.TypeParameters = append(ty.TypeParameters, d.decodeTypeParameter())
+		}
+		ty.Parameters = d.decodeParameters()
+		ty.ReturnTypeAnnotation = d.decodeTypeAnnotation()
+		if d.readBool() {
+			ty.Arity = &sema.Arity{
+				Min: int(d.readInt()),
+				Max: int(d.readInt()),
+			}
+		}
+		ty.Members = d.decodeMembers()
+
+		return ty
+
+	case typeTagGeneric:
+		ty := &sema.GenericType{}
+		d.registerType(ty)
+		ty.TypeParameter = d.decodeTypeParameter()
+		return ty
+
+	default:
+		invalid("invalid type tag %d", tag)
+		return nil
+	}
+}
+
+// decodeImportedType resolves a type declared by another program
+func (d *programDecoder) decodeImportedType(tag uint64) sema.Type {
+	location := d.readLocation()
+	typeID := sema.TypeID(d.readString())
+
+	if location == nil || location == d.location {
+		invalid("invalid location of imported type %s", typeID)
+	}
+
+	program, err := d.resolver.GetProgram(location)
+	if err != nil {
+		panic(codecError{err: err})
+	}
+	if program == nil || program.Elaboration == nil {
+		invalid("missing program for imported type %s", typeID)
+	}
+
+	elaboration := program.Elaboration
+
+	var ty sema.Type
+	switch tag {
+	case typeTagImportedComposite:
+		if compositeType := elaboration.CompositeType(typeID); compositeType != nil {
+			ty = compositeType
+		}
+	case typeTagImportedInterface:
+		if interfaceType := elaboration.InterfaceType(typeID); interfaceType != nil {
+			ty = interfaceType
+		}
+	case typeTagImportedEntitlement:
+		if entitlementType := elaboration.EntitlementType(typeID); entitlementType != nil {
+			ty = entitlementType
+		}
+	case typeTagImportedEntitlementMap:
+		if entitlementMapType := elaboration.EntitlementMapType(typeID); entitlementMapType != nil {
+			ty = entitlementMapType
+		}
+	}
+
+	if ty == nil {
+		invalid("missing imported type %s", typeID)
+	}
+
+	return ty
+}
+
+func (d *programDecoder) decodeContainerType(ty sema.ContainedType) {
+	containerType := d.decodeType()
+	if containerType != nil {
+		ty.SetContainerType(containerType)
+	}
+}
+
+func (d *programDecoder) decodeNestedTypes(containerType sema.Type) *sema.StringTypeOrderedMap {
+	count := d.readUint()
+	if count == 0 {
+		return nil
+	}
+	count--
+
+	nestedTypes := &sema.StringTypeOrderedMap{}
+	for i := uint64(0); i < count; i++ {
+		name := d.readString()
+		nestedType, ok := d.decodeType().(sema.ContainedType)
+		if !ok {
+			invalid("invalid nested type %s", name)
+		}
+		nestedTypes.Set(name, nestedType)
+		nestedType.SetContainerType(containerType)
+	}
+
+	return nestedTypes
+}
+
+func (d *programDecoder) decodeCompositeType() *sema.CompositeType {
+	ty := d.decodeType()
+	if ty == nil {
+		return nil
+	}
+	compositeType, ok := ty.(*sema.CompositeType)
+	if !ok {
+		invalid("invalid composite type %T", ty)
+	}
+	return compositeType
+}
+
+func (d *programDecoder) decodeEntitlementType() *sema.EntitlementType {
+	entitlementType, ok := d.decodeType().(*sema.EntitlementType)
+	if !ok {
+		invalid("invalid entitlement type")
+	}
+	return entitlementType
+}
+
+func (d *programDecoder) decodeInterfaceTypes() []*sema.InterfaceType {
+	count := d.readUint()
+	if count == 0 {
+		return nil
+	}
+	count--
+	if count > uint64(d.remaining()) {
+		invalid("truncated")
+	}
+
+	types := make([]*sema.InterfaceType, count)
+	for i := range types {
+		interfaceType, ok := d.decodeType().(*sema.InterfaceType)
+		if !ok {
+			invalid("invalid interface type")
+		}
+		types[i] = interfaceType
+	}
+	return types
+}
+
+func (e *programEncoder) encodeTypeAnnotation(typeAnnotation sema.TypeAnnotation) {
+	e.writeBool(typeAnnotation.IsResource)
+	e.encodeType(typeAnnotation.Type)
+}
+
+func (d *programDecoder) decodeTypeAnnotation() sema.TypeAnnotation {
+	return sema.TypeAnnotation{
+		IsResource: d.readBool(),
+		Type:       d.decodeType(),
+	}
+}
+
+func (e *programEncoder) encodeParameters(parameters []sema.Parameter) {
+	if parameters == nil {
+		e.writeUint(0)
+		return
+	}
+	e.writeUint(uint64(len(parameters)) + 1)
+	for _, parameter := range parameters {
+		e.writeString(parameter.Label)
+		e.writeString(parameter.Identifier)
+		e.encodeTypeAnnotation(parameter.TypeAnnotation)
+		e.encodeType(parameter.DefaultArgument)
+	}
+}
+
+func (d *programDecoder) decodeParameters() []sema.Parameter {
+	count := d.readUint()
+	if count == 0 {
+		return nil
+	}
+	count--
+	if count > uint64(d.remaining()) {
+		invalid("truncated")
+	}
+
+	parameters := make([]sema.Parameter, count)
+	for i := range parameters {
+		parameters[i] = sema.Parameter{
+			Label:           d.readString(),
+			Identifier:      d.readString(),
+			TypeAnnotation:  d.decodeTypeAnnotation(),
+			DefaultArgument: d.decodeType(),
+		}
+	}
+	return parameters
+}
+
+// encodeTypeParameter encodes the given type parameter once, and as a reference afterwards,
+// so generic types keep referring to the type parameters of their function types
+func (e *programEncoder) encodeTypeParameter(typeParameter *sema.TypeParameter) {
+	if typeParameter == nil {
+		e.writeUint(pointerTagNil)
+		return
+	}
+
+	if index, ok := e.typeParameters[typeParameter]; ok {
+		e.writeUint(pointerTagReference)
+		e.writeUint(uint64(index))
+		return
+	}
+
+	e.writeUint(pointerTagNew)
+	e.typeParameters[typeParameter] = len(e.typeParameters)
+
+	e.writeString(typeParameter.Name)
+	e.writeBool(typeParameter.Optional)
+	e.encodeType(typeParameter.TypeBound)
+}
+
+func (d *programDecoder) decodeTypeParameter() *sema.TypeParameter {
+	switch tag := d.readUint(); tag {
+	case pointerTagNil:
+		return nil
+
+	case pointerTagReference:
+		index := d.readUint()
+		if index >= uint64(len(d.typeParameters)) {
+			invalid("invalid type parameter reference %d", index)
+		}
+		return d.typeParameters[index]
+
+	case pointerTagNew:
+		typeParameter := &sema.TypeParameter{}
+		d.typeParameters = append(d.typeParameters, typeParameter)
+
+		typeParameter.Name = d.readString()
+		typeParameter.Optional = d.readBool()
+		typeParameter.TypeBound = d.decodeType()
+
+		return typeParameter
+
+	default:
+		invalid("invalid type parameter tag %d", tag)
+		return nil
+	}
+}
+
+func (e *programEncoder) encodeMembers(members *sema.StringMemberOrderedMap) {
+	if members == nil {
+		e.writeUint(0)
+		return
+	}
+	e.writeUint(uint64(members.Len()) + 1)
+	members.Foreach(func(name string, member *sema.Member) {
+		e.writeString(name)
+		e.encodeMember(member)
+	})
+}
+
+func (d *programDecoder) decodeMembers() *sema.StringMemberOrderedMap {
+	count := d.readUint()
+	if count == 0 {
+		return nil
+	}
+	count--
+
+	members := &sema.StringMemberOrderedMap{}
+	for i := uint64(0); i < count; i++ {
+		name := d.readString()
+		members.Set(name, d.decodeMember())
+	}
+	return members
+}
+
+// encodeMember encodes the given member once, and as a reference afterwards,
+// so member access information keeps referring to the members of the types
+func (e *programEncoder) encodeMember(member *sema.Member) {
+	if member == nil {
+		e.writeUint(pointerTagNil)
+		return
+	}
+
+	if index, ok := e.members[member]; ok {
+		e.writeUint(pointerTagReference)
+		e.writeUint(uint64(index))
+		return
+	}
+
+	e.writeUint(pointerTagNew)
+	e.members[member] = len(e.members)
+
+	e.encodeValue(reflect.ValueOf(member.Identifier))
+	e.writeString(member.DocString)
+	e.encodeStrings(member.ArgumentLabels)
+	e.writeUint(uint64(member.DeclarationKind))
+	e.writeUint(uint64(member.VariableKind))
+	e.writeBool(member.Predeclared)
+	e.writeBool(member.HasImplementation)
+	e.writeBool(member.HasConditions)
+	e.writeBool(member.IgnoreInSerialization)
+	e.encodeAccess(member.Access)
+	e.encodeType(member.ContainerType)
+	e.encodeTypeAnnotation(member.TypeAnnotation)
+}
+
+func (d *programDecoder) decodeMember() *sema.Member {
+	switch tag := d.readUint(); tag {
+	case pointerTagNil:
+		return nil
+
+	case pointerTagReference:
+		index := d.readUint()
+		if index >= uint64(len(d.members)) {
+			invalid("invalid member reference %d", index)
+		}
+		return d.members[index]
+
+	case pointerTagNew:
+		member := &sema.Member{}
+		d.members = append(d.members, member)
+
+		d.decodeValue(reflect.ValueOf(&member.Identifier).Elem())
+		member.DocString = d.readString()
+		member.ArgumentLabels = d.decodeStrings()
+		member.DeclarationKind = common.DeclarationKind(d.readUint())
+		member.VariableKind = ast.VariableKind(d.readUint())
+		member.Predeclared = d.readBool()
+		member.HasImplementation = d.readBool()
+		member.HasConditions = d.readBool()
+		member.IgnoreInSerialization = d.readBool()
+		member.Access = d.decodeAccess()
+		member.ContainerType = d.decodeType()
+		member.TypeAnnotation = d.decodeTypeAnnotation()
+
+		return member
+
+	default:
+		invalid("invalid member tag %d", tag)
+		return nil
+	}
+}
+
+// Access tags are part of the encoding, see elementTag
+const (
+	accessTagNil            = 0
+	accessTagPrimitive      = 1
+	accessTagEntitlementSet = 2
+	accessTagEntitlementMap = 3
+)
+
+func (e *programEncoder) encodeAccess(access sema.Access) {
+	switch access := access.(type) {
+	case nil:
+		e.writeUint(accessTagNil)
+
+	case sema.PrimitiveAccess:
+		e.writeUint(accessTagPrimitive)
+		e.writeUint(uint64(access))
+
+	case sema.EntitlementSetAccess:
+		e.writeUint(accessTagEntitlementSet)
+		e.writeUint(uint64(access.SetKind))
+		e.writeUint(uint64(access.Entitlements.Len()))
+		access.Entitlements.Foreach(func(entitlementType *sema.EntitlementType, _ struct{}) {
+			e.encodeType(entitlementType)
+		})
+
+	case *sema.EntitlementMapAccess:
+		e.writeUint(accessTagEntitlementMap)
+		e.encodeType(access.Type)
+
+	default:
+		unsupported("access %T", access)
+	}
+}
+
+func (d *programDecoder) decodeAccess() sema.Access {
+	switch tag := d.readUint(); tag {
+	case accessTagNil:
+		return nil
+
+	case accessTagPrimitive:
+		return sema.PrimitiveAccess(d.readUint())
+
+	case accessTagEntitlementSet:
+		setKind := sema.EntitlementSetKind(d.readUint())
+		count := d.readLength()
+		entitlements := make([]*sema.EntitlementType, count)
+		for i := range entitlements {
+			entitlements[i] = d.decodeEntitlementType()
+		}
+		return sema.NewEntitlementSetAccess(entitlements, setKind)
+
+	case accessTagEntitlementMap:
+		entitlementMapType, ok := d.decodeType().(*sema.EntitlementMapType)
+		if !ok {
+			invalid("invalid entitlement map type")
+		}
+		return sema.NewEntitlementMapAccess(entitlementMapType)
+
+	default:
+		invalid("invalid access tag %d", tag)
+		return nil
+	}
+}
@@ -0,0 +1,256 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package programcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// entryMagic is the prefix of every encoded entry
+var entryMagic = []byte("CDCPRG")
+
+// EntryFormatVersion is the version of the entry encoding.
+// It must be incremented when the encoding of entries changes,
+// so entries written by older versions are discarded instead of misinterpreted.
+const EntryFormatVersion uint16 = 3
+
+const checksumLength = sha256.Size
+
+// InvalidEntryError is returned when an encoded entry cannot be decoded,
+// or when it does not belong to the expected key.
+type InvalidEntryError struct {
+	Reason string
+}
+
+func (e InvalidEntryError) Error() string {
+	return fmt.Sprintf("invalid program cache entry: %s", e.Reason)
+}
+
+// EncodeEntry encodes the given codec payload of a program as an entry for the given key.
+//
+// The encoding is deterministic:
+//
+//	magic "CDCPRG"
+//	format version (uint16, big endian)
+//	codec version (uint16, big endian)
+//	Cadence version length (uint16, big endian) and bytes
+//	code hash (32 bytes)
+//	imports hash (32 bytes)
+//	payload length (uint64, big endian) and bytes
+//	SHA-256 checksum of all preceding bytes
+func EncodeEntry(key Key, payload []byte) ([]byte, error) {
+	if len(key.CadenceVersion) > 0xFFFF {
+		return nil, fmt.Errorf("Cadence version too long: %d bytes", len(key.CadenceVersion))
+	}
+
+	var buffer bytes.Buffer
+	buffer.Grow(
+		len(entryMagic) + 2 + 2 + 2 + len(key.CadenceVersion) +
+			len(key.CodeHash) + len(key.ImportsHash) + 8 + len(payload) + checksumLength,
+	)
+
+	buffer.Write(entryMagic)
+
+	var scratch [8]byte
+
+	binary.BigEndian.PutUint16(scratch[:2], EntryFormatVersion)
+	buffer.Write(scratch[:2])
+
+	binary.BigEndian.PutUint16(scratch[:2], key.CodecVersion)
+	buffer.Write(scratch[:2])
+
+	binary.BigEndian.PutUint16(scratch[:2], uint16(len(key.CadenceVersion)))
+	buffer.Write(scratch[:2])
+	buffer.WriteString(key.CadenceVersion)
+
+	buffer.Write(key.CodeHash[:])
+	buffer.Write(key.ImportsHash[:])
+
+	binary.BigEndian.PutUint64(scratch[:], uint64(len(payload)))
+	buffer.Write(scratch[:])
+	buffer.Write(payload)
+
+	checksum := sha256.Sum256(buffer.Bytes())
+	buffer.Write(checksum[:])
+
+	return buffer.Bytes(), nil
+}
+
+// DecodeEntry decodes the given entry, checks that it is intact and was written for the given key,
+// and returns its codec payload.
+func DecodeEntry(key Key, data []byte) ([]byte, error) {
+	if len(data) < checksumLength {
+		return nil, InvalidEntryError{Reason: "truncated"}
+	}
+
+	content := data[:len(data)-checksumLength]
+	checksum := sha256.Sum256(content)
+	if !bytes.Equal(checksum[:], data[len(content):]) {
+		return nil, InvalidEntryError{Reason: "checksum mismatch"}
+	}
+
+	reader := entryReader{data: content}
+
+	magic := reader.read(len(entryMagic))
+	if !bytes.Equal(magic, entryMagic) {
+		return nil, InvalidEntryError{Reason: "missing magic"}
+	}
+
+	formatVersion := reader.readUint16()
+	if reader.err == nil && formatVersion != EntryFormatVersion {
+		return nil, InvalidEntryError{
+			Reason: fmt.Sprintf("unsupported format version %d", formatVersion),
+		}
+	}
+
+	codecVersion := reader.readUint16()
+	cadenceVersion := string(reader.read(int(reader.readUint16())))
+	codeHash := reader.read(len(key.CodeHash))
+	importsHash := reader.read(len(key.ImportsHash))
+
+	payloadLength := reader.readUint64()
+	if reader.err == nil && payloadLength != uint64(reader.remaining()) {
+		return nil, InvalidEntryError{Reason: "payload length mismatch"}
+	}
+	payload := reader.read(int(payloadLength))
+
+	if reader.err != nil {
+		return nil, reader.err
+	}
+
+	if cadenceVersion != key.CadenceVersion {
+		return nil, InvalidEntryError{
+			Reason: fmt.Sprintf(
+				"Cadence version mismatch: expected %s, got %s",
+				key.CadenceVersion,
+				cadenceVersion,
+			),
+		}
+	}
+
+	if codecVersion != key.CodecVersion {
+		return nil, InvalidEntryError{
+			Reason: fmt.Sprintf(
+				"codec version mismatch: expected %d, got %d",
+				key.CodecVersion,
+				codecVersion,
+			),
+		}
+	}
+
+	if !bytes.Equal(codeHash, key.CodeHash[:]) {
+		return nil, InvalidEntryError{Reason: "code hash mismatch"}
+	}
+
+	if !bytes.Equal(importsHash, key.ImportsHash[:]) {
+		return nil, InvalidEntryError{Reason: "imports hash mismatch"}
+	}
+
+	return payload, nil
+}
+
+// encodeImportLocations encodes the payload of an index entry,
+// i.e. the locations of the imports of a program
+func encodeImportLocations(locations []common.Location) (data []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+		}
+	}()
+
+	var encoder encoder
+	encoder.writeUint(uint64(len(locations)))
+	for _, location := range locations {
+		encoder.writeLocation(location)
+	}
+
+	return encoder.buffer.Bytes(), nil
+}
+
+// decodeImportLocations decodes the payload of an index entry
+func decodeImportLocations(data []byte) (locations []common.Location, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+		}
+	}()
+
+	decoder := decoder{
+		data: data,
+	}
+	count := decoder.readLength()
+	locations = make([]common.Location, count)
+	for i := range locations {
+		location := decoder.readLocation()
+		if location == nil {
+			invalid("missing import location")
+		}
+		locations[i] = location
+	}
+	if decoder.remaining() > 0 {
+		invalid("trailing data")
+	}
+
+	return locations, nil
+}
+
+// entryReader reads the fields of an entry.
+// Once a read fails, all further reads return nothing, and err is set
+type entryReader struct {
+	data []byte
+	err  error
+}
+
+func (r *entryReader) remaining() int {
+	return len(r.data)
+}
+
+func (r *entryReader) read(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = InvalidEntryError{Reason: "truncated"}
+		return nil
+	}
+	result := r.data[:n]
+	r.data = r.data[n:]
+	return result
+}
+
+func (r *entryReader) readUint16() uint16 {
+	data := r.read(2)
+	if data == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(data)
+}
+
+func (r *entryReader) readUint64() uint64 {
+	data := r.read(8)
+	if data == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
@@ -0,0 +1,203 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package programcache
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+// Interface is a runtime.Interface which forwards all calls to another runtime.Interface,
+// but loads the programs of contracts through a Cache.
+//
+// The wrapped interface still determines which program is used during an execution,
+// as required by runtime.Interface.GetOrLoadProgram:
+// The cache only replaces parsing and checking when the wrapped interface loads a program.
+//
+// Programs of transactions and scripts are not cached,
+// as different transactions and scripts may be executed with the same location.
+// Only contracts may be imports of cached programs.
+type Interface struct {
+	runtime.Interface
+	cache *Cache
+
+	// programs are the programs of contracts which were loaded or decoded,
+	// so imports of decoded programs and the programs loaded during the execution are the same
+	programsMutex sync.Mutex
+	programs      map[common.AddressLocation]cachedProgram
+}
+
+type cachedProgram struct {
+	codeHash [sha256.Size]byte
+	program  *interpreter.Program
+}
+
+var _ runtime.Interface = &Interface{}
+var _ runtime.Metrics = &Interface{}
+
+// NewInterface returns a new interface which forwards all calls to the given interface,
+// and loads the programs of contracts through the given cache.
+func NewInterface(inner runtime.Interface, cache *Cache) *Interface {
+	return &Interface{
+		Interface: inner,
+		cache:     cache,
+		programs:  map[common.AddressLocation]cachedProgram{},
+	}
+}
+
+func (i *Interface) GetOrLoadProgram(
+	location runtime.Location,
+	load func() (*interpreter.Program, error),
+) (*interpreter.Program, error) {
+
+	addressLocation, ok := location.(common.AddressLocation)
+	if !ok {
+		return i.Interface.GetOrLoadProgram(location, load)
+	}
+
+	return i.Interface.GetOrLoadProgram(
+		location,
+		func() (*interpreter.Program, error) {
+			code, err := i.Interface.GetAccountContractCode(addressLocation)
+			if err != nil || code == nil {
+				return load()
+			}
+
+			codeHash := sha256.Sum256(code)
+
+			if program := i.program(addressLocation, codeHash); program != nil {
+				return program, nil
+			}
+
+			program, err := i.cache.GetOrLoadProgram(location, code, interfaceResolver{i}, load)
+			if err != nil {
+				return nil, err
+			}
+
+			i.setProgram(addressLocation, codeHash, program)
+
+			return program, nil
+		},
+	)
+}
+
+// program returns the program of the given contract, if it was loaded or decoded for the given code
+func (i *Interface) program(location common.AddressLocation, codeHash [sha256.Size]byte) *interpreter.Program {
+	i.programsMutex.Lock()
+	defer i.programsMutex.Unlock()
+
+	cached, ok := i.programs[location]
+	if !ok || cached.codeHash != codeHash {
+		return nil
+	}
+	return cached.program
+}
+
+func (i *Interface) setProgram(
+	location common.AddressLocation,
+	codeHash [sha256.Size]byte,
+	program *interpreter.Program,
+) {
+	i.programsMutex.Lock()
+	defer i.programsMutex.Unlock()
+
+	i.programs[location] = cachedProgram{
+		codeHash: codeHash,
+		program:  program,
+	}
+}
+
+// interfaceResolver resolves the imports of cached programs.
+//
+// It only provides programs which are already known or cached, and never loads a program:
+// Loading requires parsing and checking through the runtime environment, which decides which program is used.
+// If an import is not available, the importing program is loaded instead
+type interfaceResolver struct {
+	*Interface
+}
+
+var _ Resolver = interfaceResolver{}
+
+func (r interfaceResolver) GetCode(location common.Location) ([]byte, error) {
+	addressLocation, ok := location.(common.AddressLocation)
+	if !ok {
+		return nil, DependencyUnavailableError{Location: location}
+	}
+
+	code, err := r.Interface.Interface.GetAccountContractCode(addressLocation)
+	if err != nil {
+		return nil, err
+	}
+	if code == nil {
+		return nil, DependencyUnavailableError{Location: location}
+	}
+
+	return code, nil
+}
+
+func (r interfaceResolver) GetProgram(location common.Location) (*interpreter.Program, error) {
+	code, err := r.GetCode(location)
+	if err != nil {
+		return nil, err
+	}
+
+	addressLocation := location.(common.AddressLocation)
+	codeHash := sha256.Sum256(code)
+
+	if program := r.program(addressLocation, codeHash); program != nil {
+		return program, nil
+	}
+
+	program, err := r.cache.GetProgram(location, code, r)
+	if err != nil {
+		return nil, err
+	}
+	if program == nil {
+		return nil, DependencyUnavailableError{Location: location}
+	}
+
+	r.setProgram(addressLocation, codeHash, program)
+
+	return program, nil
+}
+
+// Metrics
+
+func (i *Interface) ProgramParsed(location runtime.Location, duration time.Duration) {
+	if metrics, ok := i.Interface.(runtime.Metrics); ok {
+		metrics.ProgramParsed(location, duration)
+	}
+}
+
+func (i *Interface) ProgramChecked(location runtime.Location, duration time.Duration) {
+	if metrics, ok := i.Interface.(runtime.Metrics); ok {
+		metrics.ProgramChecked(location, duration)
+	}
+}
+
+func (i *Interface) ProgramInterpreted(location runtime.Location, duration time.Duration) {
+	if metrics, ok := i.Interface.(runtime.Metrics); ok {
+		metrics.ProgramInterpreted(location, duration)
+	}
+}
@@ -0,0 +1,415 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package programcache provides a persistent cache of checked programs,
+// so programs of hot contracts do not have to be parsed and checked again,
+// e.g. after a node restart.
+//
+// Entries are keyed by the hash of the program's code, by the hashes of the code of its imports,
+// by the Cadence version, and by the version of the codec, so an entry is never used for different code,
+// for code which imports different code, by a different version of Cadence,
+// which might parse or check the code differently, or by a codec which encodes programs differently.
+// The imports of a program are only known once it is checked,
+// so the locations of the imports are stored in an index entry,
+// keyed by the location and the code of the program, the Cadence version, and the codec version.
+// Entries have a versioned, checksummed, deterministic encoding (see EncodeEntry).
+// The program itself is encoded by a Codec, e.g. BinaryCodec.
+//
+// Invalid entries, and entries which fail to decode, are discarded,
+// and the program is parsed and checked again.
+package programcache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+// Key is the key of a cached program.
+type Key struct {
+	CodeHash       [sha256.Size]byte
+	ImportsHash    [sha256.Size]byte
+	CadenceVersion string
+	// CodecVersion is the version of the codec which encodes the program, see Codec.Version
+	CodecVersion uint16
+}
+
+// Import is a program which another program depends on, e.g. an imported contract.
+type Import struct {
+	Location common.Location
+	Code     []byte
+}
+
+// NewKey returns the key of the program for the given code and imports,
+// checked by the given Cadence version, and encoded by the given codec version.
+//
+// The imports hash covers the locations and the code hashes of the imports,
+// independent of their order.
+func NewKey(code []byte, cadenceVersion string, codecVersion uint16, imports []Import) Key {
+	sortedImports := make([]Import, len(imports))
+	copy(sortedImports, imports)
+	sort.Slice(sortedImports, func(i, j int) bool {
+		return sortedImports[i].Location.ID() < sortedImports[j].Location.ID()
+	})
+
+	hasher := sha256.New()
+	for _, imported := range sortedImports {
+		writeLocationID(hasher, imported.Location)
+
+		codeHash := sha256.Sum256(imported.Code)
+		hasher.Write(codeHash[:])
+	}
+
+	key := Key{
+		CodeHash:       sha256.Sum256(code),
+		CadenceVersion: cadenceVersion,
+		CodecVersion:   codecVersion,
+	}
+	hasher.Sum(key.ImportsHash[:0])
+
+	return key
+}
+
+// indexKeyPrefix is the prefix of the hashed location of an index key,
+// so the key does not collide with the key of a program entry
+var indexKeyPrefix = []byte("index")
+
+// newIndexKey returns the key of the index entry for the program at the given location with the given code,
+// checked by the given Cadence version, and encoded by the given codec version.
+// The index entry stores the locations of the imports of the program.
+//
+// Programs at different locations may have the same code, but resolve different imports,
+// so the imports hash of the key is the hash of the location
+func newIndexKey(location common.Location, code []byte, cadenceVersion string, codecVersion uint16) Key {
+	hasher := sha256.New()
+	hasher.Write(indexKeyPrefix)
+	writeLocationID(hasher, location)
+
+	key := Key{
+		CodeHash:       sha256.Sum256(code),
+		CadenceVersion: cadenceVersion,
+		CodecVersion:   codecVersion,
+	}
+	hasher.Sum(key.ImportsHash[:0])
+
+	return key
+}
+
+// writeLocationID writes the length-prefixed ID of the given location to the given hash
+func writeLocationID(hasher hash.Hash, location common.Location) {
+	locationID := location.ID()
+
+	var lengthBytes [8]byte
+	binary.BigEndian.PutUint64(lengthBytes[:], uint64(len(locationID)))
+	hasher.Write(lengthBytes[:])
+	hasher.Write([]byte(locationID))
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf(
+		"%s-%s@%s/%d",
+		hex.EncodeToString(k.CodeHash[:]),
+		hex.EncodeToString(k.ImportsHash[:]),
+		k.CadenceVersion,
+		k.CodecVersion,
+	)
+}
+
+// Codec encodes and decodes checked programs, i.e. their AST and elaboration.
+//
+// Encoding must be deterministic, and decoding must return a program
+// of which the elaboration annotates the AST of the program.
+//
+// Encoding returns the locations of the programs which the encoded program depends on,
+// e.g. the programs which declare the imported types used by the program.
+// Decoding obtains these programs from the resolver.
+type Codec interface {
+	// Version returns the version of the encoding.
+	// It must be changed when the encoding changes,
+	// so entries encoded by other versions are not decoded
+	Version() uint16
+	EncodeProgram(location common.Location, program *interpreter.Program) (data []byte, imports []common.Location, err error)
+	DecodeProgram(location common.Location, data []byte, resolver Resolver) (*interpreter.Program, error)
+}
+
+// Resolver provides the code and the programs of the imports of a program.
+type Resolver interface {
+	// GetCode returns the code of the program at the given location
+	GetCode(location common.Location) ([]byte, error)
+	// GetProgram returns the checked program at the given location
+	GetProgram(location common.Location) (*interpreter.Program, error)
+}
+
+// DependencyUnavailableError is returned by a Resolver
+// when the code or the program of an import is not available.
+// The program is then loaded instead, but the error is not reported.
+type DependencyUnavailableError struct {
+	Location common.Location
+}
+
+func (e DependencyUnavailableError) Error() string {
+	return fmt.Sprintf("dependency %s is unavailable", e.Location)
+}
+
+func isDependencyUnavailable(err error) bool {
+	var dependencyUnavailableError DependencyUnavailableError
+	return errors.As(err, &dependencyUnavailableError)
+}
+
+// Store stores encoded entries.
+type Store interface {
+	// Get returns the entry for the given key, if any
+	Get(key Key) (data []byte, ok bool, err error)
+	// Set stores the entry for the given key
+	Set(key Key, data []byte) error
+	// Remove removes the entry for the given key, if any
+	Remove(key Key) error
+}
+
+// Config is the configuration of a program cache.
+type Config struct {
+	// CadenceVersion is the version of Cadence which checks the programs,
+	// e.g. cadence.Version
+	CadenceVersion string
+	Codec          Codec
+	Store          Store
+	// OnError is called with errors which occur when reading, decoding, encoding, or writing entries.
+	// These errors are not fatal, the program is loaded instead. Optional
+	OnError func(key Key, err error)
+}
+
+// Cache is a persistent cache of checked programs.
+type Cache struct {
+	config Config
+}
+
+// NewCache returns a new program cache with the given configuration.
+func NewCache(config Config) *Cache {
+	return &Cache{
+		config: config,
+	}
+}
+
+// GetProgram returns the program for the given location and code,
+// if the store has a valid entry for the code and the current code of its imports.
+// It returns nil if there is no such entry.
+func (c *Cache) GetProgram(
+	location common.Location,
+	code []byte,
+	resolver Resolver,
+) (*interpreter.Program, error) {
+	program, _, err := c.get(location, code, resolver)
+	if err != nil {
+		if isDependencyUnavailable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return program, nil
+}
+
+// GetOrLoadProgram returns the program for the given location and code.
+//
+// If the store has a valid entry for the code and the current code of its imports,
+// the program is decoded from it, and load is not called, i.e. the code is not parsed and checked.
+// Otherwise, the program is loaded by calling load, and stored if loading succeeded.
+func (c *Cache) GetOrLoadProgram(
+	location common.Location,
+	code []byte,
+	resolver Resolver,
+	load func() (*interpreter.Program, error),
+) (*interpreter.Program, error) {
+
+	indexKey := c.newIndexKey(location, code)
+
+	program, key, err := c.get(location, code, resolver)
+	if err != nil {
+		if !isDependencyUnavailable(err) {
+			c.reportError(indexKey, err)
+
+			// Fall back to loading the program, and replace the invalid entries
+
+			err = c.config.Store.Remove(indexKey)
+			if err != nil {
+				c.reportError(indexKey, err)
+			}
+
+			if key != (Key{}) {
+				err = c.config.Store.Remove(key)
+				if err != nil {
+					c.reportError(key, err)
+				}
+			}
+		}
+	} else if program != nil {
+		return program, nil
+	}
+
+	program, err = load()
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.set(location, code, resolver, program)
+	if err != nil && !isDependencyUnavailable(err) {
+		c.reportError(indexKey, err)
+	}
+
+	return program, nil
+}
+
+// get returns the program for the given code, if the store has an entry for it.
+// It also returns the key of the program entry, if it could be determined
+func (c *Cache) get(
+	location common.Location,
+	code []byte,
+	resolver Resolver,
+) (
+	program *interpreter.Program,
+	key Key,
+	err error,
+) {
+	indexKey := c.newIndexKey(location, code)
+
+	data, ok, err := c.config.Store.Get(indexKey)
+	if err != nil || !ok {
+		return nil, key, err
+	}
+
+	payload, err := DecodeEntry(indexKey, data)
+	if err != nil {
+		return nil, key, err
+	}
+
+	locations, err := decodeImportLocations(payload)
+	if err != nil {
+		return nil, key, err
+	}
+
+	imports, err := resolveImports(resolver, locations)
+	if err != nil {
+		return nil, key, err
+	}
+
+	key = c.newKey(code, imports)
+
+	data, ok, err = c.config.Store.Get(key)
+	if err != nil || !ok {
+		return nil, key, err
+	}
+
+	payload, err = DecodeEntry(key, data)
+	if err != nil {
+		return nil, key, err
+	}
+
+	program, err = c.config.Codec.DecodeProgram(location, payload, resolver)
+	if err != nil {
+		return nil, key, fmt.Errorf("failed to decode program: %w", err)
+	}
+	if program == nil || program.Program == nil || program.Elaboration == nil {
+		return nil, key, InvalidEntryError{Reason: "incomplete program"}
+	}
+
+	return program, key, nil
+}
+
+// set stores the entry of the given program, and the index entry with the locations of its imports.
+// The program entry is stored first, so an index entry never refers to a missing program entry
+// because of a failed write
+func (c *Cache) set(
+	location common.Location,
+	code []byte,
+	resolver Resolver,
+	program *interpreter.Program,
+) error {
+	payload, locations, err := c.config.Codec.EncodeProgram(location, program)
+	if err != nil {
+		return fmt.Errorf("failed to encode program: %w", err)
+	}
+
+	imports, err := resolveImports(resolver, locations)
+	if err != nil {
+		return err
+	}
+
+	key := c.newKey(code, imports)
+
+	data, err := EncodeEntry(key, payload)
+	if err != nil {
+		return err
+	}
+
+	err = c.config.Store.Set(key, data)
+	if err != nil {
+		return err
+	}
+
+	indexKey := c.newIndexKey(location, code)
+
+	indexPayload, err := encodeImportLocations(locations)
+	if err != nil {
+		return err
+	}
+
+	indexData, err := EncodeEntry(indexKey, indexPayload)
+	if err != nil {
+		return err
+	}
+
+	return c.config.Store.Set(indexKey, indexData)
+}
+
+// resolveImports returns the imports at the given locations, i.e. their current code
+func resolveImports(resolver Resolver, locations []common.Location) ([]Import, error) {
+	imports := make([]Import, 0, len(locations))
+	for _, location := range locations {
+		code, err := resolver.GetCode(location)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, Import{
+			Location: location,
+			Code:     code,
+		})
+	}
+	return imports, nil
+}
+
+func (c *Cache) newKey(code []byte, imports []Import) Key {
+	return NewKey(code, c.config.CadenceVersion, c.config.Codec.Version(), imports)
+}
+
+func (c *Cache) newIndexKey(location common.Location, code []byte) Key {
+	return newIndexKey(location, code, c.config.CadenceVersion, c.config.Codec.Version())
+}
+
+func (c *Cache) reportError(key Key, err error) {
+	onError := c.config.OnError
+	if onError == nil {
+		return
+	}
+	onError(key, err)
+}
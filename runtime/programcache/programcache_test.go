@@ -0,0 +1,684 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package programcache_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	. "github.com/onflow/cadence/runtime/programcache"
+	"github.com/onflow/cadence/runtime/sema"
+	. "github.com/onflow/cadence/runtime/tests/runtime_utils"
+	. "github.com/onflow/cadence/runtime/tests/utils"
+)
+
+// testCodec is a Codec which keeps programs in memory,
+// and encodes them as a reference to the kept program
+type testCodec struct {
+	programs map[string]*interpreter.Program
+	imports  []common.Location
+	decodes  int
+}
+
+var _ Codec = &testCodec{}
+
+const testCodecVersion uint16 = 1
+
+func newTestCodec() *testCodec {
+	return &testCodec{
+		programs: map[string]*interpreter.Program{},
+	}
+}
+
+func (c *testCodec) Version() uint16 {
+	return testCodecVersion
+}
+
+func (c *testCodec) EncodeProgram(
+	location common.Location,
+	program *interpreter.Program,
+) (
+	[]byte,
+	[]common.Location,
+	error,
+) {
+	reference := fmt.Sprintf("%s#%d", location.ID(), len(c.programs))
+	c.programs[reference] = program
+	return []byte(reference), c.imports, nil
+}
+
+func (c *testCodec) DecodeProgram(_ common.Location, data []byte, _ Resolver) (*interpreter.Program, error) {
+	program, ok := c.programs[string(data)]
+	if !ok {
+		return nil, fmt.Errorf("unknown program: %s", data)
+	}
+	c.decodes++
+	return program, nil
+}
+
+// testResolver is a Resolver which provides the code of imports from a map
+type testResolver struct {
+	codes map[common.Location][]byte
+}
+
+var _ Resolver = testResolver{}
+
+func (r testResolver) GetCode(location common.Location) ([]byte, error) {
+	code, ok := r.codes[location]
+	if !ok {
+		return nil, DependencyUnavailableError{Location: location}
+	}
+	return code, nil
+}
+
+func (r testResolver) GetProgram(location common.Location) (*interpreter.Program, error) {
+	return nil, DependencyUnavailableError{Location: location}
+}
+
+func newTestProgram() *interpreter.Program {
+	return &interpreter.Program{
+		Program:     &ast.Program{},
+		Elaboration: sema.NewElaboration(nil),
+	}
+}
+
+func TestEntry(t *testing.T) {
+
+	t.Parallel()
+
+	key := NewKey([]byte("access(all) contract C {}"), "v1.0.0", testCodecVersion, nil)
+	payload := []byte("payload")
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := EncodeEntry(key, payload)
+		require.NoError(t, err)
+
+		decoded, err := DecodeEntry(key, data)
+		require.NoError(t, err)
+		assert.Equal(t, payload, decoded)
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		t.Parallel()
+
+		data1, err := EncodeEntry(key, payload)
+		require.NoError(t, err)
+
+		data2, err := EncodeEntry(key, payload)
+		require.NoError(t, err)
+
+		assert.Equal(t, data1, data2)
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := EncodeEntry(key, payload)
+		require.NoError(t, err)
+
+		data[len(data)/2] ^= 0xFF
+
+		_, err = DecodeEntry(key, data)
+		require.ErrorAs(t, err, &InvalidEntryError{})
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := DecodeEntry(key, []byte("CDC"))
+		require.ErrorAs(t, err, &InvalidEntryError{})
+	})
+
+	t.Run("different Cadence version", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := EncodeEntry(key, payload)
+		require.NoError(t, err)
+
+		otherKey := key
+		otherKey.CadenceVersion = "v1.0.1"
+
+		_, err = DecodeEntry(otherKey, data)
+		require.ErrorAs(t, err, &InvalidEntryError{})
+	})
+
+	t.Run("different codec version", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := EncodeEntry(key, payload)
+		require.NoError(t, err)
+
+		otherKey := key
+		otherKey.CodecVersion = key.CodecVersion + 1
+
+		_, err = DecodeEntry(otherKey, data)
+		require.ErrorAs(t, err, &InvalidEntryError{})
+	})
+
+	t.Run("different code", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := EncodeEntry(key, payload)
+		require.NoError(t, err)
+
+		otherKey := NewKey([]byte("access(all) contract D {}"), key.CadenceVersion, testCodecVersion, nil)
+
+		_, err = DecodeEntry(otherKey, data)
+		require.ErrorAs(t, err, &InvalidEntryError{})
+	})
+
+	t.Run("different imports", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := EncodeEntry(key, payload)
+		require.NoError(t, err)
+
+		otherKey := NewKey(
+			[]byte("access(all) contract C {}"),
+			key.CadenceVersion,
+			key.CodecVersion,
+			[]Import{
+				{
+					Location: common.IdentifierLocation("D"),
+					Code:     []byte("access(all) contract D {}"),
+				},
+			},
+		)
+
+		_, err = DecodeEntry(otherKey, data)
+		require.ErrorAs(t, err, &InvalidEntryError{})
+	})
+}
+
+func TestNewKey(t *testing.T) {
+
+	t.Parallel()
+
+	code := []byte("access(all) contract C {}")
+
+	importD := Import{
+		Location: common.IdentifierLocation("D"),
+		Code:     []byte("access(all) contract D {}"),
+	}
+	importE := Import{
+		Location: common.IdentifierLocation("E"),
+		Code:     []byte("access(all) contract E {}"),
+	}
+
+	key := NewKey(code, "v1.0.0", testCodecVersion, []Import{importD, importE})
+
+	// The order of the imports is irrelevant
+	assert.Equal(t, key, NewKey(code, "v1.0.0", testCodecVersion, []Import{importE, importD}))
+
+	// The code of the imports is relevant
+	changedImportE := importE
+	changedImportE.Code = []byte("access(all) contract E { access(all) let x: Int; init() { self.x = 1 } }")
+	assert.NotEqual(t, key, NewKey(code, "v1.0.0", testCodecVersion, []Import{importD, changedImportE}))
+
+	// The locations of the imports are relevant
+	movedImportE := importE
+	movedImportE.Location = common.IdentifierLocation("F")
+	assert.NotEqual(t, key, NewKey(code, "v1.0.0", testCodecVersion, []Import{importD, movedImportE}))
+
+	// The code hash only depends on the code
+	assert.Equal(t, key.CodeHash, NewKey(code, "v1.0.0", testCodecVersion, nil).CodeHash)
+}
+
+func TestCache(t *testing.T) {
+
+	t.Parallel()
+
+	location := common.NewAddressLocation(nil, common.MustBytesToAddress([]byte{0x1}), "C")
+	code := []byte("access(all) contract C {}")
+	resolver := testResolver{}
+
+	// storeEntries stores the entries of a program for the code
+	storeEntries := func(t *testing.T, store Store) {
+		cache := NewCache(Config{
+			CadenceVersion: "v1.0.0",
+			Codec:          newTestCodec(),
+			Store:          store,
+		})
+
+		_, err := cache.GetOrLoadProgram(location, code, resolver, func() (*interpreter.Program, error) {
+			return newTestProgram(), nil
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("load once", func(t *testing.T) {
+		t.Parallel()
+
+		codec := newTestCodec()
+		store := NewMemoryStore()
+		program := newTestProgram()
+
+		loads := 0
+		load := func() (*interpreter.Program, error) {
+			loads++
+			return program, nil
+		}
+
+		// A new cache with the same store simulates a restart
+		for i := 0; i < 2; i++ {
+			cache := NewCache(Config{
+				CadenceVersion: "v1.0.0",
+				Codec:          codec,
+				Store:          store,
+			})
+
+			result, err := cache.GetOrLoadProgram(location, code, resolver, load)
+			require.NoError(t, err)
+			assert.Same(t, program, result)
+		}
+
+		assert.Equal(t, 1, loads)
+		assert.Equal(t, 1, codec.decodes)
+	})
+
+	t.Run("different Cadence version", func(t *testing.T) {
+		t.Parallel()
+
+		codec := newTestCodec()
+		store := NewMemoryStore()
+
+		loads := 0
+		load := func() (*interpreter.Program, error) {
+			loads++
+			return newTestProgram(), nil
+		}
+
+		for _, version := range []string{"v1.0.0", "v1.0.1"} {
+			cache := NewCache(Config{
+				CadenceVersion: version,
+				Codec:          codec,
+				Store:          store,
+			})
+
+			_, err := cache.GetOrLoadProgram(location, code, resolver, load)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, 2, loads)
+		assert.Equal(t, 0, codec.decodes)
+	})
+
+	t.Run("same code at different locations", func(t *testing.T) {
+		t.Parallel()
+
+		codec := newTestCodec()
+		cache := NewCache(Config{
+			CadenceVersion: "v1.0.0",
+			Codec:          codec,
+			Store:          NewMemoryStore(),
+		})
+
+		importLocation := common.IdentifierLocation("D")
+		resolver := testResolver{
+			codes: map[common.Location][]byte{
+				importLocation: []byte("access(all) contract D {}"),
+			},
+		}
+
+		otherLocation := common.NewAddressLocation(nil, common.MustBytesToAddress([]byte{0x2}), "C")
+
+		// The programs at the two locations have the same code, but different imports
+
+		program := newTestProgram()
+		codec.imports = []common.Location{importLocation}
+		_, err := cache.GetOrLoadProgram(location, code, resolver, func() (*interpreter.Program, error) {
+			return program, nil
+		})
+		require.NoError(t, err)
+
+		otherProgram := newTestProgram()
+		codec.imports = nil
+		_, err = cache.GetOrLoadProgram(otherLocation, code, resolver, func() (*interpreter.Program, error) {
+			return otherProgram, nil
+		})
+		require.NoError(t, err)
+
+		result, err := cache.GetProgram(location, code, resolver)
+		require.NoError(t, err)
+		assert.Same(t, program, result)
+
+		result, err = cache.GetProgram(otherLocation, code, resolver)
+		require.NoError(t, err)
+		assert.Same(t, otherProgram, result)
+	})
+
+	t.Run("load error", func(t *testing.T) {
+		t.Parallel()
+
+		store := NewMemoryStore()
+		cache := NewCache(Config{
+			CadenceVersion: "v1.0.0",
+			Codec:          newTestCodec(),
+			Store:          store,
+		})
+
+		loadErr := errors.New("load failed")
+
+		_, err := cache.GetOrLoadProgram(location, code, resolver, func() (*interpreter.Program, error) {
+			return nil, loadErr
+		})
+		require.ErrorIs(t, err, loadErr)
+
+		_, ok, err := store.Get(NewKey(code, "v1.0.0", testCodecVersion, nil))
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("fall back on invalid entry", func(t *testing.T) {
+		t.Parallel()
+
+		store := NewMemoryStore()
+		storeEntries(t, store)
+
+		key := NewKey(code, "v1.0.0", testCodecVersion, nil)
+
+		err := store.Set(key, []byte("invalid"))
+		require.NoError(t, err)
+
+		var reportedErrors []error
+
+		codec := newTestCodec()
+		cache := NewCache(Config{
+			CadenceVersion: "v1.0.0",
+			Codec:          codec,
+			Store:          store,
+			OnError: func(_ Key, err error) {
+				reportedErrors = append(reportedErrors, err)
+			},
+		})
+
+		program := newTestProgram()
+		loads := 0
+
+		result, err := cache.GetOrLoadProgram(location, code, resolver, func() (*interpreter.Program, error) {
+			loads++
+			return program, nil
+		})
+		require.NoError(t, err)
+		assert.Same(t, program, result)
+		assert.Equal(t, 1, loads)
+
+		require.Len(t, reportedErrors, 1)
+		require.ErrorAs(t, reportedErrors[0], &InvalidEntryError{})
+
+		// The invalid entry got replaced
+
+		data, ok, err := store.Get(key)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		_, err = DecodeEntry(key, data)
+		require.NoError(t, err)
+	})
+
+	t.Run("fall back on decoding error", func(t *testing.T) {
+		t.Parallel()
+
+		store := NewMemoryStore()
+		storeEntries(t, store)
+
+		key := NewKey(code, "v1.0.0", testCodecVersion, nil)
+
+		data, err := EncodeEntry(key, []byte("unknown"))
+		require.NoError(t, err)
+
+		err = store.Set(key, data)
+		require.NoError(t, err)
+
+		var reportedErrors []error
+
+		cache := NewCache(Config{
+			CadenceVersion: "v1.0.0",
+			Codec:          newTestCodec(),
+			Store:          store,
+			OnError: func(_ Key, err error) {
+				reportedErrors = append(reportedErrors, err)
+			},
+		})
+
+		loads := 0
+
+		_, err = cache.GetOrLoadProgram(location, code, resolver, func() (*interpreter.Program, error) {
+			loads++
+			return newTestProgram(), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, loads)
+		require.Len(t, reportedErrors, 1)
+	})
+
+	t.Run("different import code", func(t *testing.T) {
+		t.Parallel()
+
+		importLocation := common.NewAddressLocation(nil, common.MustBytesToAddress([]byte{0x1}), "D")
+
+		codec := newTestCodec()
+		codec.imports = []common.Location{importLocation}
+
+		store := NewMemoryStore()
+
+		var reportedErrors []error
+
+		cache := NewCache(Config{
+			CadenceVersion: "v1.0.0",
+			Codec:          codec,
+			Store:          store,
+			OnError: func(_ Key, err error) {
+				reportedErrors = append(reportedErrors, err)
+			},
+		})
+
+		loads := 0
+		load := func() (*interpreter.Program, error) {
+			loads++
+			return newTestProgram(), nil
+		}
+
+		resolver := testResolver{
+			codes: map[common.Location][]byte{
+				importLocation: []byte("access(all) contract D {}"),
+			},
+		}
+
+		for i := 0; i < 2; i++ {
+			_, err := cache.GetOrLoadProgram(location, code, resolver, load)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, 1, loads)
+		assert.Equal(t, 1, codec.decodes)
+
+		// Changing the code of the import invalidates the entry
+
+		resolver.codes[importLocation] = []byte("access(all) contract D { access(all) fun d() {} }")
+
+		for i := 0; i < 2; i++ {
+			_, err := cache.GetOrLoadProgram(location, code, resolver, load)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, 2, loads)
+		assert.Equal(t, 2, codec.decodes)
+
+		// Unavailable imports are not errors, the program is loaded
+
+		delete(resolver.codes, importLocation)
+
+		program, err := cache.GetProgram(location, code, resolver)
+		require.NoError(t, err)
+		assert.Nil(t, program)
+
+		_, err = cache.GetOrLoadProgram(location, code, resolver, load)
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, loads)
+		assert.Empty(t, reportedErrors)
+	})
+}
+
+func TestDirectoryStore(t *testing.T) {
+
+	t.Parallel()
+
+	store, err := NewDirectoryStore(t.TempDir())
+	require.NoError(t, err)
+
+	key := NewKey([]byte("access(all) contract C {}"), "v1.0.0-preview.1+build/1", testCodecVersion, nil)
+
+	_, ok, err := store.Get(key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	err = store.Set(key, []byte("entry"))
+	require.NoError(t, err)
+
+	data, ok, err := store.Get(key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []byte("entry"), data)
+
+	err = store.Remove(key)
+	require.NoError(t, err)
+
+	_, ok, err = store.Get(key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Removing a missing entry is not an error
+	err = store.Remove(key)
+	require.NoError(t, err)
+}
+
+func TestInterface(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.MustBytesToAddress([]byte{0x1})
+	contractLocation := common.NewAddressLocation(nil, address, "C")
+
+	contract := []byte(`
+      access(all) contract C {
+          access(all) fun answer(): Int {
+              return 42
+          }
+      }
+    `)
+
+	script := []byte(`
+      import C from 0x1
+
+      access(all) fun main(): Int {
+          return C.answer()
+      }
+    `)
+
+	codec := newTestCodec()
+	store := NewMemoryStore()
+
+	ledger := NewTestLedger(nil, nil)
+	accountCodes := map[runtime.Location][]byte{}
+
+	rt := runtime.NewInterpreterRuntime(runtime.Config{})
+
+	// Each execution uses a new interface, i.e. a new program cache of the embedder,
+	// which simulates a restart
+
+	newInterface := func(checks *int) runtime.Interface {
+		runtimeInterface := &TestRuntimeInterface{
+			Storage: ledger,
+			OnGetSigningAccounts: func() ([]runtime.Address, error) {
+				return []runtime.Address{address}, nil
+			},
+			OnGetAccountContractCode: func(location common.AddressLocation) ([]byte, error) {
+				return accountCodes[location], nil
+			},
+			OnUpdateAccountContractCode: func(location common.AddressLocation, code []byte) error {
+				accountCodes[location] = code
+				return nil
+			},
+			OnEmitEvent: func(event cadence.Event) error {
+				return nil
+			},
+			OnResolveLocation: NewSingleIdentifierLocationResolver(t),
+			OnProgramChecked: func(location runtime.Location, _ time.Duration) {
+				if location == contractLocation {
+					*checks++
+				}
+			},
+		}
+
+		cache := NewCache(Config{
+			CadenceVersion: cadence.Version,
+			Codec:          codec,
+			Store:          store,
+		})
+
+		return NewInterface(runtimeInterface, cache)
+	}
+
+	var deploymentChecks int
+
+	err := rt.ExecuteTransaction(
+		runtime.Script{
+			Source: DeploymentTransaction("C", contract),
+		},
+		runtime.Context{
+			Interface: newInterface(&deploymentChecks),
+			Location:  common.TransactionLocation{},
+		},
+	)
+	require.NoError(t, err)
+
+	execute := func() (checks int) {
+		result, err := rt.ExecuteScript(
+			runtime.Script{
+				Source: script,
+			},
+			runtime.Context{
+				Interface: newInterface(&checks),
+				Location:  common.ScriptLocation{},
+			},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, cadence.NewInt(42), result)
+
+		return checks
+	}
+
+	assert.Equal(t, 1, execute())
+	assert.Equal(t, 0, execute())
+	assert.Equal(t, 1, codec.decodes)
+}
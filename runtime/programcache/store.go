@@ -0,0 +1,147 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package programcache
+
+import (
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// MemoryStore is a Store which keeps entries in memory.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mutex   sync.RWMutex
+	entries map[Key][]byte
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore returns a new, empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: map[Key][]byte{},
+	}
+}
+
+func (s *MemoryStore) Get(key Key) ([]byte, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	data, ok := s.entries[key]
+	return data, ok, nil
+}
+
+func (s *MemoryStore) Set(key Key, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemoryStore) Remove(key Key) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// DirectoryStore is a Store which keeps each entry in a file in a directory.
+// Files are replaced atomically, so concurrent readers never observe partially written entries.
+type DirectoryStore struct {
+	directory string
+}
+
+var _ Store = DirectoryStore{}
+
+// NewDirectoryStore returns a new store which keeps entries in the given directory.
+// The directory is created if it does not exist.
+func NewDirectoryStore(directory string) (DirectoryStore, error) {
+	err := os.MkdirAll(directory, 0o755)
+	if err != nil {
+		return DirectoryStore{}, err
+	}
+	return DirectoryStore{
+		directory: directory,
+	}, nil
+}
+
+// path returns the path of the file of the entry for the given key.
+// The Cadence version is hex-encoded, as it may contain characters which are invalid in file names
+func (s DirectoryStore) path(key Key) string {
+	name := hex.EncodeToString(key.CodeHash[:]) +
+		"-" +
+		hex.EncodeToString(key.ImportsHash[:]) +
+		"-" +
+		hex.EncodeToString([]byte(key.CadenceVersion)) +
+		"-" +
+		strconv.FormatUint(uint64(key.CodecVersion), 10) +
+		".cdcprg"
+	return filepath.Join(s.directory, name)
+}
+
+func (s DirectoryStore) Get(key Key) ([]byte, bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+func (s DirectoryStore) Set(key Key, data []byte) (err error) {
+	file, err := os.CreateTemp(s.directory, ".cdcprg-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	_, err = file.Write(data)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), s.path(key))
+}
+
+func (s DirectoryStore) Remove(key Key) error {
+	err := os.Remove(s.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,801 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package recording records the calls of the runtime to a runtime.Interface,
+// and replays them to deterministically re-execute transactions and scripts,
+// e.g. to reproduce failures offline.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/onflow/atree"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/onflow/cadence"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+// ExecutionKind is the kind of a recorded execution.
+type ExecutionKind string
+
+const (
+	ExecutionKindTransaction ExecutionKind = "transaction"
+	ExecutionKindScript      ExecutionKind = "script"
+)
+
+// Recording is the recording of the execution of a transaction or script:
+// The script, all calls of the runtime to the interface, and the result of the execution.
+//
+// A recording is written by RecordTransaction and RecordScript,
+// read by Read, and replayed by Recording.Replay.
+type Recording struct {
+	Kind     ExecutionKind
+	Location runtime.Location
+	Script   runtime.Script
+	Calls    []Call
+	// Completed is true if the end of the execution was recorded
+	Completed bool
+	// Error is the error message of the execution, if it failed
+	Error string
+	// Result is the JSON-CDC encoded result of the script, if it succeeded
+	Result []byte
+}
+
+// Call is a recorded call of the runtime to the runtime.Interface.
+// The arguments and results are encoded as JSON arrays.
+type Call struct {
+	Method    string          `json:"method"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Results   json.RawMessage `json:"results,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	// Count is the number of calls of the metering method, including this call.
+	// Calls of metering methods are very frequent, so they are only recorded if they fail
+	Count int `json:"count,omitempty"`
+}
+
+func (c Call) String() string {
+	var builder strings.Builder
+	builder.WriteString(c.Method)
+	builder.WriteByte('(')
+	arguments := string(c.Arguments)
+	arguments = strings.TrimPrefix(arguments, "[")
+	arguments = strings.TrimSuffix(arguments, "]")
+	builder.WriteString(arguments)
+	builder.WriteByte(')')
+	return builder.String()
+}
+
+// recordingEntry is a line of a recording file.
+// The first line begins the execution, the last line ends it,
+// and all lines in between are calls
+type recordingEntry struct {
+	Begin *recordingBegin `json:"begin,omitempty"`
+	Call  *Call           `json:"call,omitempty"`
+	End   *recordingEnd   `json:"end,omitempty"`
+}
+
+type recordingBegin struct {
+	Kind      ExecutionKind `json:"kind"`
+	Location  string        `json:"location"`
+	Source    string        `json:"source"`
+	Arguments [][]byte      `json:"arguments"`
+}
+
+type recordingEnd struct {
+	Error  string          `json:"error,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// recordedIdentifier is the recorded form of an ast.Identifier,
+// which cannot be decoded from its JSON encoding
+type recordedIdentifier struct {
+	Identifier string
+	Pos        ast.Position
+}
+
+type recordedResolvedLocation struct {
+	Location    string
+	Identifiers []recordedIdentifier
+}
+
+// recordedLocationID returns the ID of the given location, which is decoded by decodeRecordedLocation
+func recordedLocationID(location runtime.Location) string {
+	if location == nil {
+		return ""
+	}
+	return location.ID()
+}
+
+func decodeRecordedLocation(id string) (runtime.Location, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	// String locations may contain dots, so they cannot be decoded from a type ID
+	stringLocationPrefix := common.StringLocationPrefix + "."
+	if strings.HasPrefix(id, stringLocationPrefix) {
+		return common.StringLocation(strings.TrimPrefix(id, stringLocationPrefix)), nil
+	}
+
+	location, _, err := common.DecodeTypeID(nil, id)
+	if err != nil {
+		return nil, err
+	}
+	if location == nil {
+		return nil, fmt.Errorf("invalid location: %s", id)
+	}
+	return location, nil
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Recorder is a runtime.Interface which forwards all calls to another runtime.Interface,
+// and records them, and their results, to a writer.
+// The recording can be replayed by a Replayer.
+//
+// Programs and the interpreter shared state are cached by the recorder itself,
+// instead of by the wrapped interface, so all code which is needed to replay the execution is recorded.
+type Recorder struct {
+	inner             runtime.Interface
+	w                 *bufio.Writer
+	encoder           *json.Encoder
+	err               error
+	programs          map[runtime.Location]*interpreter.Program
+	sharedState       *interpreter.SharedState
+	meterMemoryCount  int
+	meterComputeCount int
+}
+
+var _ runtime.Interface = &Recorder{}
+var _ runtime.Metrics = &Recorder{}
+
+// NewRecorder returns a new Recorder,
+// which forwards all calls to the given interface and records them to the given writer.
+func NewRecorder(inner runtime.Interface, w io.Writer) *Recorder {
+	bufferedWriter := bufio.NewWriter(w)
+	return &Recorder{
+		inner:    inner,
+		w:        bufferedWriter,
+		encoder:  json.NewEncoder(bufferedWriter),
+		programs: map[runtime.Location]*interpreter.Program{},
+	}
+}
+
+// Err returns the first error which occurred when writing the recording, if any.
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+func (r *Recorder) writeEntry(entry recordingEntry) {
+	if r.err != nil {
+		return
+	}
+	r.err = r.encoder.Encode(entry)
+}
+
+// Begin records the beginning of the execution of the given transaction or script.
+func (r *Recorder) Begin(kind ExecutionKind, script runtime.Script, location runtime.Location) {
+	r.writeEntry(recordingEntry{
+		Begin: &recordingBegin{
+			Kind:      kind,
+			Location:  recordedLocationID(location),
+			Source:    string(script.Source),
+			Arguments: script.Arguments,
+		},
+	})
+}
+
+// End records the end of the execution, i.e. the error or the result of the script,
+// and flushes the recording.
+func (r *Recorder) End(executionErr error, result cadence.Value) error {
+	end := &recordingEnd{
+		Error: errorMessage(executionErr),
+	}
+
+	if executionErr == nil && result != nil {
+		encoded, err := jsoncdc.Encode(result)
+		if err != nil {
+			return err
+		}
+		end.Result = encoded
+	}
+
+	r.writeEntry(recordingEntry{
+		End: end,
+	})
+
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+
+	return r.err
+}
+
+// record records a call of the given method, with the given arguments, results, and error
+func (r *Recorder) record(method string, arguments []any, results []any, err error) {
+	if r.err != nil {
+		return
+	}
+
+	call := &Call{
+		Method: method,
+		Error:  errorMessage(err),
+	}
+
+	if len(arguments) > 0 {
+		call.Arguments, r.err = json.Marshal(arguments)
+		if r.err != nil {
+			return
+		}
+	}
+
+	if err == nil && len(results) > 0 {
+		call.Results, r.err = json.Marshal(results)
+		if r.err != nil {
+			return
+		}
+	}
+
+	r.writeEntry(recordingEntry{
+		Call: call,
+	})
+}
+
+// RecordTransaction executes the given transaction with the given runtime and context,
+// and records the execution to the given writer.
+//
+// It returns the error of the execution, or the error of writing the recording.
+func RecordTransaction(
+	rt runtime.Runtime,
+	script runtime.Script,
+	context runtime.Context,
+	w io.Writer,
+) error {
+	recorder := NewRecorder(context.Interface, w)
+	recorder.Begin(ExecutionKindTransaction, script, context.Location)
+
+	context.Interface = recorder
+	executionErr := rt.ExecuteTransaction(script, context)
+
+	recordErr := recorder.End(executionErr, nil)
+	if executionErr != nil {
+		return executionErr
+	}
+	return recordErr
+}
+
+// RecordScript executes the given script with the given runtime and context,
+// and records the execution to the given writer.
+//
+// It returns the result and error of the execution, or the error of writing the recording.
+func RecordScript(
+	rt runtime.Runtime,
+	script runtime.Script,
+	context runtime.Context,
+	w io.Writer,
+) (cadence.Value, error) {
+	recorder := NewRecorder(context.Interface, w)
+	recorder.Begin(ExecutionKindScript, script, context.Location)
+
+	context.Interface = recorder
+	result, executionErr := rt.ExecuteScript(script, context)
+
+	recordErr := recorder.End(executionErr, result)
+	if executionErr != nil {
+		return nil, executionErr
+	}
+	if recordErr != nil {
+		return nil, recordErr
+	}
+	return result, nil
+}
+
+// Read reads a recording written by RecordTransaction, RecordScript, or a Recorder.
+// The recording of an execution which did not complete, e.g. because the process crashed, can be read,
+// but is not marked as completed.
+func Read(r io.Reader) (*Recording, error) {
+	decoder := json.NewDecoder(r)
+
+	recording := &Recording{}
+
+	for line := 1; ; line++ {
+		var entry recordingEntry
+		err := decoder.Decode(&entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid recording entry %d: %w", line, err)
+		}
+
+		switch {
+		case entry.Begin != nil:
+			if line != 1 {
+				return nil, fmt.Errorf("invalid recording entry %d: unexpected beginning of execution", line)
+			}
+
+			begin := entry.Begin
+			recording.Kind = begin.Kind
+			recording.Script = runtime.Script{
+				Source:    []byte(begin.Source),
+				Arguments: begin.Arguments,
+			}
+			recording.Location, err = decodeRecordedLocation(begin.Location)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recording entry %d: %w", line, err)
+			}
+
+		case line == 1:
+			return nil, fmt.Errorf("invalid recording entry %d: missing beginning of execution", line)
+
+		case recording.Completed:
+			return nil, fmt.Errorf("invalid recording entry %d: unexpected entry after end of execution", line)
+
+		case entry.Call != nil:
+			recording.Calls = append(recording.Calls, *entry.Call)
+
+		case entry.End != nil:
+			recording.Completed = true
+			recording.Error = entry.End.Error
+			recording.Result = entry.End.Result
+
+		default:
+			return nil, fmt.Errorf("invalid recording entry %d: empty entry", line)
+		}
+	}
+
+	return recording, nil
+}
+
+// Metering
+
+func (r *Recorder) MeterMemory(usage common.MemoryUsage) error {
+	r.meterMemoryCount++
+	err := r.inner.MeterMemory(usage)
+	if err != nil {
+		r.recordMeteringError("MeterMemory", r.meterMemoryCount, []any{usage}, err)
+	}
+	return err
+}
+
+func (r *Recorder) MeterComputation(operationType common.ComputationKind, intensity uint) error {
+	r.meterComputeCount++
+	err := r.inner.MeterComputation(operationType, intensity)
+	if err != nil {
+		r.recordMeteringError("MeterComputation", r.meterComputeCount, []any{operationType, intensity}, err)
+	}
+	return err
+}
+
+func (r *Recorder) recordMeteringError(method string, count int, arguments []any, err error) {
+	if r.err != nil {
+		return
+	}
+
+	encodedArguments, encodingErr := json.Marshal(arguments)
+	if encodingErr != nil {
+		r.err = encodingErr
+		return
+	}
+
+	r.writeEntry(recordingEntry{
+		Call: &Call{
+			Method:    method,
+			Arguments: encodedArguments,
+			Error:     err.Error(),
+			Count:     count,
+		},
+	})
+}
+
+func (r *Recorder) ComputationUsed() (uint64, error) {
+	used, err := r.inner.ComputationUsed()
+	r.record("ComputationUsed", nil, []any{used}, err)
+	return used, err
+}
+
+func (r *Recorder) MemoryUsed() (uint64, error) {
+	used, err := r.inner.MemoryUsed()
+	r.record("MemoryUsed", nil, []any{used}, err)
+	return used, err
+}
+
+func (r *Recorder) InteractionUsed() (uint64, error) {
+	used, err := r.inner.InteractionUsed()
+	r.record("InteractionUsed", nil, []any{used}, err)
+	return used, err
+}
+
+// Programs
+
+func (r *Recorder) ResolveLocation(identifiers []runtime.Identifier, location runtime.Location) ([]runtime.ResolvedLocation, error) {
+	resolvedLocations, err := r.inner.ResolveLocation(identifiers, location)
+
+	var recordedResolvedLocations []recordedResolvedLocation
+	for _, resolvedLocation := range resolvedLocations {
+		var recordedIdentifiers []recordedIdentifier
+		for _, identifier := range resolvedLocation.Identifiers {
+			recordedIdentifiers = append(recordedIdentifiers, recordedIdentifier(identifier))
+		}
+		recordedResolvedLocations = append(
+			recordedResolvedLocations,
+			recordedResolvedLocation{
+				Location:    recordedLocationID(resolvedLocation.Location),
+				Identifiers: recordedIdentifiers,
+			},
+		)
+	}
+
+	r.record(
+		"ResolveLocation",
+		[]any{recordedIdentifiers(identifiers), recordedLocationID(location)},
+		[]any{recordedResolvedLocations},
+		err,
+	)
+	return resolvedLocations, err
+}
+
+func recordedIdentifiers(identifiers []runtime.Identifier) []string {
+	names := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		names = append(names, identifier.Identifier)
+	}
+	return names
+}
+
+func (r *Recorder) GetCode(location runtime.Location) ([]byte, error) {
+	code, err := r.inner.GetCode(location)
+	r.record("GetCode", []any{recordedLocationID(location)}, []any{string(code)}, err)
+	return code, err
+}
+
+func (r *Recorder) GetOrLoadProgram(
+	location runtime.Location,
+	load func() (*interpreter.Program, error),
+) (*interpreter.Program, error) {
+	return getOrLoadProgram(r.programs, location, load)
+}
+
+// getOrLoadProgram returns the program for the given location from the given cache,
+// or loads and caches it
+func getOrLoadProgram(
+	programs map[runtime.Location]*interpreter.Program,
+	location runtime.Location,
+	load func() (*interpreter.Program, error),
+) (*interpreter.Program, error) {
+	program, ok := programs[location]
+	if ok {
+		return program, nil
+	}
+
+	program, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	programs[location] = program
+	return program, nil
+}
+
+func (r *Recorder) SetInterpreterSharedState(state *interpreter.SharedState) {
+	r.sharedState = state
+}
+
+func (r *Recorder) GetInterpreterSharedState() *interpreter.SharedState {
+	return r.sharedState
+}
+
+// Storage
+
+func (r *Recorder) GetValue(owner, key []byte) ([]byte, error) {
+	value, err := r.inner.GetValue(owner, key)
+	r.record("GetValue", []any{owner, key}, []any{value}, err)
+	return value, err
+}
+
+func (r *Recorder) SetValue(owner, key, value []byte) error {
+	err := r.inner.SetValue(owner, key, value)
+	r.record("SetValue", []any{owner, key, value}, nil, err)
+	return err
+}
+
+func (r *Recorder) ValueExists(owner, key []byte) (bool, error) {
+	exists, err := r.inner.ValueExists(owner, key)
+	r.record("ValueExists", []any{owner, key}, []any{exists}, err)
+	return exists, err
+}
+
+func (r *Recorder) AllocateStorageIndex(owner []byte) (atree.StorageIndex, error) {
+	index, err := r.inner.AllocateStorageIndex(owner)
+	r.record("AllocateStorageIndex", []any{owner}, []any{index}, err)
+	return index, err
+}
+
+// Accounts
+
+func (r *Recorder) CreateAccount(payer runtime.Address) (runtime.Address, error) {
+	address, err := r.inner.CreateAccount(payer)
+	r.record("CreateAccount", []any{payer}, []any{address}, err)
+	return address, err
+}
+
+func (r *Recorder) AddAccountKey(
+	address runtime.Address,
+	publicKey *runtime.PublicKey,
+	hashAlgo runtime.HashAlgorithm,
+	weight int,
+) (*runtime.AccountKey, error) {
+	key, err := r.inner.AddAccountKey(address, publicKey, hashAlgo, weight)
+	r.record("AddAccountKey", []any{address, publicKey, hashAlgo, weight}, []any{key}, err)
+	return key, err
+}
+
+func (r *Recorder) GetAccountKey(address runtime.Address, index int) (*runtime.AccountKey, error) {
+	key, err := r.inner.GetAccountKey(address, index)
+	r.record("GetAccountKey", []any{address, index}, []any{key}, err)
+	return key, err
+}
+
+func (r *Recorder) AccountKeysCount(address runtime.Address) (uint64, error) {
+	count, err := r.inner.AccountKeysCount(address)
+	r.record("AccountKeysCount", []any{address}, []any{count}, err)
+	return count, err
+}
+
+func (r *Recorder) RevokeAccountKey(address runtime.Address, index int) (*runtime.AccountKey, error) {
+	key, err := r.inner.RevokeAccountKey(address, index)
+	r.record("RevokeAccountKey", []any{address, index}, []any{key}, err)
+	return key, err
+}
+
+func (r *Recorder) UpdateAccountContractCode(location common.AddressLocation, code []byte) error {
+	err := r.inner.UpdateAccountContractCode(location, code)
+	r.record("UpdateAccountContractCode", []any{recordedLocationID(location), string(code)}, nil, err)
+	return err
+}
+
+func (r *Recorder) GetAccountContractCode(location common.AddressLocation) ([]byte, error) {
+	code, err := r.inner.GetAccountContractCode(location)
+	r.record("GetAccountContractCode", []any{recordedLocationID(location)}, []any{code}, err)
+	return code, err
+}
+
+func (r *Recorder) RemoveAccountContractCode(location common.AddressLocation) error {
+	err := r.inner.RemoveAccountContractCode(location)
+	r.record("RemoveAccountContractCode", []any{recordedLocationID(location)}, nil, err)
+	return err
+}
+
+func (r *Recorder) GetSigningAccounts() ([]runtime.Address, error) {
+	addresses, err := r.inner.GetSigningAccounts()
+	r.record("GetSigningAccounts", nil, []any{addresses}, err)
+	return addresses, err
+}
+
+func (r *Recorder) GetAccountBalance(address common.Address) (uint64, error) {
+	balance, err := r.inner.GetAccountBalance(address)
+	r.record("GetAccountBalance", []any{address}, []any{balance}, err)
+	return balance, err
+}
+
+func (r *Recorder) GetAccountAvailableBalance(address common.Address) (uint64, error) {
+	balance, err := r.inner.GetAccountAvailableBalance(address)
+	r.record("GetAccountAvailableBalance", []any{address}, []any{balance}, err)
+	return balance, err
+}
+
+func (r *Recorder) GetStorageUsed(address runtime.Address) (uint64, error) {
+	used, err := r.inner.GetStorageUsed(address)
+	r.record("GetStorageUsed", []any{address}, []any{used}, err)
+	return used, err
+}
+
+func (r *Recorder) GetStorageCapacity(address runtime.Address) (uint64, error) {
+	capacity, err := r.inner.GetStorageCapacity(address)
+	r.record("GetStorageCapacity", []any{address}, []any{capacity}, err)
+	return capacity, err
+}
+
+func (r *Recorder) GetAccountContractNames(address runtime.Address) ([]string, error) {
+	names, err := r.inner.GetAccountContractNames(address)
+	r.record("GetAccountContractNames", []any{address}, []any{names}, err)
+	return names, err
+}
+
+func (r *Recorder) GenerateAccountID(address common.Address) (uint64, error) {
+	id, err := r.inner.GenerateAccountID(address)
+	r.record("GenerateAccountID", []any{address}, []any{id}, err)
+	return id, err
+}
+
+func (r *Recorder) ResourceOwnerChanged(
+	interpreter *interpreter.Interpreter,
+	resource *interpreter.CompositeValue,
+	oldOwner common.Address,
+	newOwner common.Address,
+) {
+	r.inner.ResourceOwnerChanged(interpreter, resource, oldOwner, newOwner)
+	r.record("ResourceOwnerChanged", []any{resource.TypeID(), oldOwner, newOwner}, nil, nil)
+}
+
+// Execution
+
+func (r *Recorder) ProgramLog(message string) error {
+	err := r.inner.ProgramLog(message)
+	r.record("ProgramLog", []any{message}, nil, err)
+	return err
+}
+
+func (r *Recorder) EmitEvent(event cadence.Event) error {
+	err := r.inner.EmitEvent(event)
+
+	encodedEvent, encodingErr := jsoncdc.Encode(event)
+	if encodingErr != nil {
+		if r.err == nil {
+			r.err = encodingErr
+		}
+		return err
+	}
+
+	r.record("EmitEvent", []any{json.RawMessage(encodedEvent)}, nil, err)
+	return err
+}
+
+func (r *Recorder) GenerateUUID() (uint64, error) {
+	uuid, err := r.inner.GenerateUUID()
+	r.record("GenerateUUID", nil, []any{uuid}, err)
+	return uuid, err
+}
+
+func (r *Recorder) DecodeArgument(argument []byte, argumentType cadence.Type) (cadence.Value, error) {
+	value, err := r.inner.DecodeArgument(argument, argumentType)
+
+	var results []any
+	if err == nil {
+		encodedValue, encodingErr := jsoncdc.Encode(value)
+		if encodingErr != nil {
+			if r.err == nil {
+				r.err = encodingErr
+			}
+			return value, err
+		}
+		results = []any{json.RawMessage(encodedValue)}
+	}
+
+	r.record("DecodeArgument", []any{argument, argumentType.ID()}, results, err)
+	return value, err
+}
+
+func (r *Recorder) GetCurrentBlockHeight() (uint64, error) {
+	height, err := r.inner.GetCurrentBlockHeight()
+	r.record("GetCurrentBlockHeight", nil, []any{height}, err)
+	return height, err
+}
+
+func (r *Recorder) GetBlockAtHeight(height uint64) (runtime.Block, bool, error) {
+	block, exists, err := r.inner.GetBlockAtHeight(height)
+	r.record("GetBlockAtHeight", []any{height}, []any{block, exists}, err)
+	return block, exists, err
+}
+
+func (r *Recorder) ReadRandom(buffer []byte) error {
+	err := r.inner.ReadRandom(buffer)
+	r.record("ReadRandom", []any{len(buffer)}, []any{buffer}, err)
+	return err
+}
+
+func (r *Recorder) ImplementationDebugLog(message string) error {
+	err := r.inner.ImplementationDebugLog(message)
+	r.record("ImplementationDebugLog", []any{message}, nil, err)
+	return err
+}
+
+func (r *Recorder) RecordTrace(
+	operation string,
+	location runtime.Location,
+	duration time.Duration,
+	attrs []attribute.KeyValue,
+) {
+	// Traces depend on the duration of operations, so they are not recorded
+	r.inner.RecordTrace(operation, location, duration, attrs)
+}
+
+// Crypto
+
+func (r *Recorder) VerifySignature(
+	signature []byte,
+	tag string,
+	signedData []byte,
+	publicKey []byte,
+	signatureAlgorithm runtime.SignatureAlgorithm,
+	hashAlgorithm runtime.HashAlgorithm,
+) (bool, error) {
+	valid, err := r.inner.VerifySignature(signature, tag, signedData, publicKey, signatureAlgorithm, hashAlgorithm)
+	r.record(
+		"VerifySignature",
+		[]any{signature, tag, signedData, publicKey, signatureAlgorithm, hashAlgorithm},
+		[]any{valid},
+		err,
+	)
+	return valid, err
+}
+
+func (r *Recorder) Hash(data []byte, tag string, hashAlgorithm runtime.HashAlgorithm) ([]byte, error) {
+	hash, err := r.inner.Hash(data, tag, hashAlgorithm)
+	r.record("Hash", []any{data, tag, hashAlgorithm}, []any{hash}, err)
+	return hash, err
+}
+
+func (r *Recorder) ValidatePublicKey(key *runtime.PublicKey) error {
+	err := r.inner.ValidatePublicKey(key)
+	r.record("ValidatePublicKey", []any{key}, nil, err)
+	return err
+}
+
+func (r *Recorder) BLSVerifyPOP(publicKey *runtime.PublicKey, signature []byte) (bool, error) {
+	valid, err := r.inner.BLSVerifyPOP(publicKey, signature)
+	r.record("BLSVerifyPOP", []any{publicKey, signature}, []any{valid}, err)
+	return valid, err
+}
+
+func (r *Recorder) BLSAggregateSignatures(signatures [][]byte) ([]byte, error) {
+	signature, err := r.inner.BLSAggregateSignatures(signatures)
+	r.record("BLSAggregateSignatures", []any{signatures}, []any{signature}, err)
+	return signature, err
+}
+
+func (r *Recorder) BLSAggregatePublicKeys(publicKeys []*runtime.PublicKey) (*runtime.PublicKey, error) {
+	publicKey, err := r.inner.BLSAggregatePublicKeys(publicKeys)
+	r.record("BLSAggregatePublicKeys", []any{publicKeys}, []any{publicKey}, err)
+	return publicKey, err
+}
+
+// Metrics
+
+func (r *Recorder) ProgramParsed(location runtime.Location, duration time.Duration) {
+	if metrics, ok := r.inner.(runtime.Metrics); ok {
+		metrics.ProgramParsed(location, duration)
+	}
+}
+
+func (r *Recorder) ProgramChecked(location runtime.Location, duration time.Duration) {
+	if metrics, ok := r.inner.(runtime.Metrics); ok {
+		metrics.ProgramChecked(location, duration)
+	}
+}
+
+func (r *Recorder) ProgramInterpreted(location runtime.Location, duration time.Duration) {
+	if metrics, ok := r.inner.(runtime.Metrics); ok {
+		metrics.ProgramInterpreted(location, duration)
+	}
+}
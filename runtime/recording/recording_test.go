@@ -0,0 +1,388 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package recording_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+	. "github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/recording"
+	. "github.com/onflow/cadence/runtime/tests/runtime_utils"
+	. "github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func newRecordingTestInterface(t *testing.T) *TestRuntimeInterface {
+	address := common.MustBytesToAddress([]byte{0x1})
+
+	accountCodes := map[Location][]byte{}
+
+	return &TestRuntimeInterface{
+		Storage: NewTestLedger(nil, nil),
+		OnGetSigningAccounts: func() ([]Address, error) {
+			return []Address{address}, nil
+		},
+		OnResolveLocation: NewSingleIdentifierLocationResolver(t),
+		OnGetCode: func(location Location) ([]byte, error) {
+			return accountCodes[location], nil
+		},
+		OnGetAccountContractCode: func(location common.AddressLocation) ([]byte, error) {
+			return accountCodes[location], nil
+		},
+		OnUpdateAccountContractCode: func(location common.AddressLocation, code []byte) error {
+			accountCodes[location] = code
+			return nil
+		},
+		OnEmitEvent: func(event cadence.Event) error {
+			return nil
+		},
+		OnProgramLog: func(message string) {},
+		OnReadRandom: func(buffer []byte) error {
+			for i := range buffer {
+				buffer[i] = byte(i + 1)
+			}
+			return nil
+		},
+	}
+}
+
+func recordTestTransaction(t *testing.T) *Recording {
+
+	contract := []byte(`
+      access(all) contract Test {
+
+          access(all) event Saved(value: UInt64)
+
+          access(all) fun save(_ value: UInt64, account: auth(Storage) &Account) {
+              account.storage.save(value, to: /storage/value)
+              emit Saved(value: value)
+          }
+      }
+    `)
+
+	tx := []byte(`
+      import Test from 0x1
+
+      transaction {
+          prepare(signer: auth(Storage) &Account) {
+              let value = revertibleRandom<UInt64>()
+              Test.save(value, account: signer)
+              log(value)
+          }
+      }
+    `)
+
+	runtimeInterface := newRecordingTestInterface(t)
+	runtime := NewInterpreterRuntime(Config{})
+	nextTransactionLocation := NewTransactionLocationGenerator()
+
+	err := runtime.ExecuteTransaction(
+		Script{
+			Source: DeploymentTransaction("Test", contract),
+		},
+		Context{
+			Interface: runtimeInterface,
+			Location:  nextTransactionLocation(),
+		},
+	)
+	require.NoError(t, err)
+
+	var events []cadence.Event
+	runtimeInterface.OnEmitEvent = func(event cadence.Event) error {
+		events = append(events, event)
+		return nil
+	}
+
+	var output bytes.Buffer
+	err = RecordTransaction(
+		runtime,
+		Script{
+			Source: tx,
+		},
+		Context{
+			Interface: runtimeInterface,
+			Location:  nextTransactionLocation(),
+		},
+		&output,
+	)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	recording, err := Read(&output)
+	require.NoError(t, err)
+
+	return recording
+}
+
+func findRecordedCall(t *testing.T, recording *Recording, method string) int {
+	for i, call := range recording.Calls {
+		if call.Method == method {
+			return i
+		}
+	}
+	require.FailNow(t, "missing recorded call", method)
+	return -1
+}
+
+func TestRuntimeRecordReplayTransaction(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("replay", func(t *testing.T) {
+		t.Parallel()
+
+		recording := recordTestTransaction(t)
+
+		assert.Equal(t, ExecutionKindTransaction, recording.Kind)
+		assert.True(t, recording.Completed)
+		assert.Empty(t, recording.Error)
+
+		var methods []string
+		for _, call := range recording.Calls {
+			methods = append(methods, call.Method)
+		}
+		assert.Contains(t, methods, "GetAccountContractCode")
+		assert.Contains(t, methods, "ReadRandom")
+		assert.Contains(t, methods, "SetValue")
+		assert.Contains(t, methods, "EmitEvent")
+		assert.Contains(t, methods, "ProgramLog")
+
+		// Replaying does not require the original interface
+		err := recording.Replay(NewInterpreterRuntime(Config{}), Context{})
+		require.NoError(t, err)
+	})
+
+	t.Run("different random value", func(t *testing.T) {
+		t.Parallel()
+
+		recording := recordTestTransaction(t)
+
+		index := findRecordedCall(t, recording, "ReadRandom")
+		recording.Calls[index].Results = json.RawMessage(`["AAAAAAAAAAA="]`)
+
+		err := recording.Replay(NewInterpreterRuntime(Config{}), Context{})
+		var divergenceErr DivergenceError
+		require.ErrorAs(t, err, &divergenceErr)
+
+		// The random value is emitted before storage is written on commit,
+		// so the event diverges first
+		assert.Greater(t, divergenceErr.Index, index)
+		assert.Contains(t, divergenceErr.Expected, "EmitEvent")
+		assert.Contains(t, divergenceErr.Actual, "EmitEvent")
+	})
+
+	t.Run("different event", func(t *testing.T) {
+		t.Parallel()
+
+		recording := recordTestTransaction(t)
+
+		index := findRecordedCall(t, recording, "EmitEvent")
+
+		event := cadence.NewEvent([]cadence.Value{cadence.UInt64(0)}).
+			WithType(&cadence.EventType{
+				Location:            common.AddressLocation{Address: common.MustBytesToAddress([]byte{0x1}), Name: "Test"},
+				QualifiedIdentifier: "Test.Saved",
+				Fields: []cadence.Field{
+					{Identifier: "value", Type: cadence.UInt64Type},
+				},
+			})
+		encodedEvent, err := jsoncdc.Encode(event)
+		require.NoError(t, err)
+		arguments, err := json.Marshal([]json.RawMessage{encodedEvent})
+		require.NoError(t, err)
+		recording.Calls[index].Arguments = arguments
+
+		err = recording.Replay(NewInterpreterRuntime(Config{}), Context{})
+		var divergenceErr DivergenceError
+		require.ErrorAs(t, err, &divergenceErr)
+
+		assert.Equal(t, index, divergenceErr.Index)
+		assert.Contains(t, divergenceErr.Expected, "EmitEvent")
+		assert.Contains(t, divergenceErr.Actual, "EmitEvent")
+	})
+
+	t.Run("different storage write", func(t *testing.T) {
+		t.Parallel()
+
+		recording := recordTestTransaction(t)
+
+		index := findRecordedCall(t, recording, "SetValue")
+
+		var arguments [][]byte
+		err := json.Unmarshal(recording.Calls[index].Arguments, &arguments)
+		require.NoError(t, err)
+		arguments[2] = []byte{0x1}
+		recording.Calls[index].Arguments, err = json.Marshal(arguments)
+		require.NoError(t, err)
+
+		err = recording.Replay(NewInterpreterRuntime(Config{}), Context{})
+		var divergenceErr DivergenceError
+		require.ErrorAs(t, err, &divergenceErr)
+
+		assert.Equal(t, index, divergenceErr.Index)
+		assert.Contains(t, divergenceErr.Expected, "SetValue")
+		assert.Contains(t, divergenceErr.Actual, "SetValue")
+	})
+
+	t.Run("missing call", func(t *testing.T) {
+		t.Parallel()
+
+		recording := recordTestTransaction(t)
+		recording.Calls = append(recording.Calls, Call{
+			Method:    "ProgramLog",
+			Arguments: json.RawMessage(`["extra"]`),
+		})
+
+		err := recording.Replay(NewInterpreterRuntime(Config{}), Context{})
+		require.Equal(t,
+			DivergenceError{
+				Index:    len(recording.Calls) - 1,
+				Expected: `ProgramLog("extra")`,
+				Actual:   "end of execution",
+			},
+			err,
+		)
+	})
+
+	t.Run("recorded error", func(t *testing.T) {
+		t.Parallel()
+
+		recording := recordTestTransaction(t)
+
+		index := findRecordedCall(t, recording, "SetValue")
+		recording.Calls[index].Error = "storage unavailable"
+
+		err := recording.Replay(NewInterpreterRuntime(Config{}), Context{})
+		var divergenceErr DivergenceError
+		require.ErrorAs(t, err, &divergenceErr)
+
+		// The replayed execution fails like the recorded call,
+		// but the recorded execution succeeded
+		assert.Equal(t, "success", divergenceErr.Expected)
+		assert.Contains(t, divergenceErr.Actual, "storage unavailable")
+	})
+}
+
+func TestRuntimeRecordReplayScript(t *testing.T) {
+
+	t.Parallel()
+
+	script := []byte(`
+      access(all) fun main(height: UInt64): UInt64 {
+          let block = getBlock(at: height)!
+          return block.height + getCurrentBlock().height
+      }
+    `)
+
+	runtimeInterface := &TestRuntimeInterface{
+		OnDecodeArgument: func(b []byte, t cadence.Type) (cadence.Value, error) {
+			return jsoncdc.Decode(nil, b)
+		},
+	}
+
+	var output bytes.Buffer
+	result, err := RecordScript(
+		NewInterpreterRuntime(Config{}),
+		Script{
+			Source: script,
+			Arguments: [][]byte{
+				jsoncdc.MustEncode(cadence.UInt64(5)),
+			},
+		},
+		Context{
+			Interface: runtimeInterface,
+			Location:  common.ScriptLocation{},
+		},
+		&output,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, cadence.UInt64(6), result)
+
+	recording, err := Read(bytes.NewReader(output.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, ExecutionKindScript, recording.Kind)
+	assert.Equal(t, common.ScriptLocation{}, recording.Location)
+
+	err = recording.Replay(NewInterpreterRuntime(Config{}), Context{})
+	require.NoError(t, err)
+
+	// A different block at the given height results in a different result
+
+	index := findRecordedCall(t, recording, "GetBlockAtHeight")
+	var results []json.RawMessage
+	err = json.Unmarshal(recording.Calls[index].Results, &results)
+	require.NoError(t, err)
+
+	block := Block{
+		Height: 6,
+	}
+	encodedBlock, err := json.Marshal(block)
+	require.NoError(t, err)
+	results[0] = encodedBlock
+	recording.Calls[index].Results, err = json.Marshal(results)
+	require.NoError(t, err)
+
+	err = recording.Replay(NewInterpreterRuntime(Config{}), Context{})
+	require.Equal(t,
+		DivergenceError{
+			Index:    len(recording.Calls),
+			Expected: `result {"value":"6","type":"UInt64"}`,
+			Actual:   `result {"value":"7","type":"UInt64"}`,
+		},
+		err,
+	)
+}
+
+func TestRuntimeReadRecording(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("incomplete", func(t *testing.T) {
+		t.Parallel()
+
+		recording, err := Read(bytes.NewReader([]byte(
+			`{"begin":{"kind":"script","location":"S.test","source":"","arguments":null}}` + "\n" +
+				`{"call":{"method":"GenerateUUID","results":[1]}}` + "\n",
+		)))
+		require.NoError(t, err)
+
+		assert.False(t, recording.Completed)
+		assert.Equal(t, common.StringLocation("test"), recording.Location)
+		require.Len(t, recording.Calls, 1)
+
+		err = recording.Replay(NewInterpreterRuntime(Config{}), Context{})
+		require.EqualError(t, err, "cannot replay incomplete recording")
+	})
+
+	t.Run("missing beginning", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Read(bytes.NewReader([]byte(
+			`{"call":{"method":"GenerateUUID","results":[1]}}` + "\n",
+		)))
+		require.EqualError(t, err, "invalid recording entry 1: missing beginning of execution")
+	})
+}
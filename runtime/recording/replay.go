@@ -0,0 +1,597 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/onflow/atree"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/onflow/cadence"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+// DivergenceError is returned when a replayed execution diverges from the recording,
+// for example because a different value is written to storage, or a different event is emitted.
+type DivergenceError struct {
+	// Index is the index of the recorded call at which the execution diverged
+	Index int
+	// Expected is the recorded call or outcome
+	Expected string
+	// Actual is the call or outcome of the replayed execution
+	Actual string
+}
+
+func (e DivergenceError) Error() string {
+	return fmt.Sprintf(
+		"replay diverged from recording at call %d: expected %s, got %s",
+		e.Index,
+		e.Expected,
+		e.Actual,
+	)
+}
+
+const endOfRecording = "end of recording"
+const endOfExecution = "end of execution"
+
+// Replayer is a runtime.Interface which replays recorded calls:
+// Each call must match the next recorded call, and returns the recorded results.
+//
+// If a call does not match, the replayed execution diverged from the recording,
+// and the call and all further calls return a DivergenceError.
+type Replayer struct {
+	calls             []Call
+	index             int
+	divergence        *DivergenceError
+	programs          map[runtime.Location]*interpreter.Program
+	sharedState       *interpreter.SharedState
+	meterMemoryCount  int
+	meterComputeCount int
+}
+
+var _ runtime.Interface = &Replayer{}
+
+// NewReplayer returns a new Replayer, which replays the given calls.
+func NewReplayer(calls []Call) *Replayer {
+	return &Replayer{
+		calls:    calls,
+		programs: map[runtime.Location]*interpreter.Program{},
+	}
+}
+
+// Err returns the divergence of the replayed execution from the recording, if any.
+func (r *Replayer) Err() error {
+	if r.divergence == nil {
+		return nil
+	}
+	return *r.divergence
+}
+
+// Done returns an error if not all recorded calls were replayed.
+func (r *Replayer) Done() error {
+	err := r.Err()
+	if err != nil {
+		return err
+	}
+
+	if r.index < len(r.calls) {
+		return r.diverge(r.calls[r.index].String(), endOfExecution)
+	}
+
+	return nil
+}
+
+func (r *Replayer) diverge(expected, actual string) error {
+	if r.divergence == nil {
+		r.divergence = &DivergenceError{
+			Index:    r.index,
+			Expected: expected,
+			Actual:   actual,
+		}
+	}
+	return *r.divergence
+}
+
+func recordedArguments(arguments []any) json.RawMessage {
+	if len(arguments) == 0 {
+		return nil
+	}
+
+	encoded, err := json.Marshal(arguments)
+	if err != nil {
+		panic(err)
+	}
+	return encoded
+}
+
+func recordedArgumentsEqual(a, b json.RawMessage) bool {
+	var compactA, compactB bytes.Buffer
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
+
+// replay replays a call of the given method with the given arguments:
+// The call must match the next recorded call.
+// The recorded results are decoded into the given result pointers,
+// and the recorded error is returned
+func (r *Replayer) replay(method string, arguments []any, results ...any) error {
+	if r.divergence != nil {
+		return *r.divergence
+	}
+
+	actual := Call{
+		Method:    method,
+		Arguments: recordedArguments(arguments),
+	}
+
+	if r.index >= len(r.calls) {
+		return r.diverge(endOfRecording, actual.String())
+	}
+
+	expected := r.calls[r.index]
+	if expected.Method != method ||
+		expected.Count != 0 ||
+		!recordedArgumentsEqual(expected.Arguments, actual.Arguments) {
+
+		return r.diverge(expected.String(), actual.String())
+	}
+
+	r.index++
+
+	if expected.Error != "" {
+		return errors.New(expected.Error)
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+
+	var recordedResults []json.RawMessage
+	err := json.Unmarshal(expected.Results, &recordedResults)
+	if err != nil {
+		return fmt.Errorf("invalid recorded results of %s: %w", method, err)
+	}
+	if len(recordedResults) != len(results) {
+		return fmt.Errorf(
+			"invalid recorded results of %s: expected %d results, got %d",
+			method,
+			len(results),
+			len(recordedResults),
+		)
+	}
+
+	for i, result := range results {
+		err = json.Unmarshal(recordedResults[i], result)
+		if err != nil {
+			return fmt.Errorf("invalid recorded results of %s: %w", method, err)
+		}
+	}
+
+	return nil
+}
+
+// replayMetering replays the count-th call of the given metering method.
+// Only failed metering calls are recorded, so the call only has to match
+// if it is the next recorded call
+func (r *Replayer) replayMetering(method string, count int, arguments []any) error {
+	if r.divergence != nil {
+		return *r.divergence
+	}
+
+	if r.index >= len(r.calls) {
+		return nil
+	}
+
+	expected := r.calls[r.index]
+	if expected.Method != method || expected.Count != count {
+		return nil
+	}
+
+	actual := Call{
+		Method:    method,
+		Arguments: recordedArguments(arguments),
+	}
+	if !recordedArgumentsEqual(expected.Arguments, actual.Arguments) {
+		return r.diverge(expected.String(), actual.String())
+	}
+
+	r.index++
+
+	return errors.New(expected.Error)
+}
+
+// Replay re-executes the recorded transaction or script with the given runtime and context,
+// replaying the recorded calls instead of calling the interface of the context.
+// The interface and location of the context are set by Replay.
+//
+// Replay returns a DivergenceError if the replayed execution diverges from the recording,
+// i.e. if a call does not match the recorded call, not all recorded calls are replayed,
+// or the error or result of the execution differs.
+func (r *Recording) Replay(rt runtime.Runtime, context runtime.Context) error {
+	if !r.Completed {
+		return errors.New("cannot replay incomplete recording")
+	}
+
+	replayer := NewReplayer(r.Calls)
+
+	context.Interface = replayer
+	context.Location = r.Location
+
+	var result cadence.Value
+	var executionErr error
+
+	switch r.Kind {
+	case ExecutionKindTransaction:
+		executionErr = rt.ExecuteTransaction(r.Script, context)
+	case ExecutionKindScript:
+		result, executionErr = rt.ExecuteScript(r.Script, context)
+	default:
+		return fmt.Errorf("cannot replay recording of unsupported kind: %s", r.Kind)
+	}
+
+	err := replayer.Err()
+	if err != nil {
+		return err
+	}
+
+	actualError := errorMessage(executionErr)
+	if actualError != r.Error {
+		return replayer.diverge(
+			outcomeDescription(r.Error),
+			outcomeDescription(actualError),
+		)
+	}
+
+	if executionErr == nil && result != nil {
+		encodedResult, err := jsoncdc.Encode(result)
+		if err != nil {
+			return err
+		}
+		if !recordedArgumentsEqual(r.Result, encodedResult) {
+			return replayer.diverge(
+				fmt.Sprintf("result %s", bytes.TrimSpace(r.Result)),
+				fmt.Sprintf("result %s", bytes.TrimSpace(encodedResult)),
+			)
+		}
+	}
+
+	return replayer.Done()
+}
+
+func outcomeDescription(errorMessage string) string {
+	if errorMessage == "" {
+		return "success"
+	}
+	return fmt.Sprintf("error %q", errorMessage)
+}
+
+// Metering
+
+func (r *Replayer) MeterMemory(usage common.MemoryUsage) error {
+	r.meterMemoryCount++
+	return r.replayMetering("MeterMemory", r.meterMemoryCount, []any{usage})
+}
+
+func (r *Replayer) MeterComputation(operationType common.ComputationKind, intensity uint) error {
+	r.meterComputeCount++
+	return r.replayMetering("MeterComputation", r.meterComputeCount, []any{operationType, intensity})
+}
+
+func (r *Replayer) ComputationUsed() (used uint64, err error) {
+	err = r.replay("ComputationUsed", nil, &used)
+	return
+}
+
+func (r *Replayer) MemoryUsed() (used uint64, err error) {
+	err = r.replay("MemoryUsed", nil, &used)
+	return
+}
+
+func (r *Replayer) InteractionUsed() (used uint64, err error) {
+	err = r.replay("InteractionUsed", nil, &used)
+	return
+}
+
+// Programs
+
+func (r *Replayer) ResolveLocation(identifiers []runtime.Identifier, location runtime.Location) ([]runtime.ResolvedLocation, error) {
+	var recordedResolvedLocations []recordedResolvedLocation
+	err := r.replay(
+		"ResolveLocation",
+		[]any{recordedIdentifiers(identifiers), recordedLocationID(location)},
+		&recordedResolvedLocations,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedLocations := make([]runtime.ResolvedLocation, 0, len(recordedResolvedLocations))
+	for _, recordedResolvedLocation := range recordedResolvedLocations {
+		resolvedLocation, err := decodeRecordedLocation(recordedResolvedLocation.Location)
+		if err != nil {
+			return nil, err
+		}
+
+		var resolvedIdentifiers []ast.Identifier
+		for _, identifier := range recordedResolvedLocation.Identifiers {
+			resolvedIdentifiers = append(resolvedIdentifiers, ast.Identifier(identifier))
+		}
+
+		resolvedLocations = append(
+			resolvedLocations,
+			runtime.ResolvedLocation{
+				Location:    resolvedLocation,
+				Identifiers: resolvedIdentifiers,
+			},
+		)
+	}
+
+	return resolvedLocations, nil
+}
+
+func (r *Replayer) GetCode(location runtime.Location) ([]byte, error) {
+	var code string
+	err := r.replay("GetCode", []any{recordedLocationID(location)}, &code)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(code), nil
+}
+
+func (r *Replayer) GetOrLoadProgram(
+	location runtime.Location,
+	load func() (*interpreter.Program, error),
+) (*interpreter.Program, error) {
+	return getOrLoadProgram(r.programs, location, load)
+}
+
+func (r *Replayer) SetInterpreterSharedState(state *interpreter.SharedState) {
+	r.sharedState = state
+}
+
+func (r *Replayer) GetInterpreterSharedState() *interpreter.SharedState {
+	return r.sharedState
+}
+
+// Storage
+
+func (r *Replayer) GetValue(owner, key []byte) (value []byte, err error) {
+	err = r.replay("GetValue", []any{owner, key}, &value)
+	return
+}
+
+func (r *Replayer) SetValue(owner, key, value []byte) error {
+	return r.replay("SetValue", []any{owner, key, value})
+}
+
+func (r *Replayer) ValueExists(owner, key []byte) (exists bool, err error) {
+	err = r.replay("ValueExists", []any{owner, key}, &exists)
+	return
+}
+
+func (r *Replayer) AllocateStorageIndex(owner []byte) (index atree.StorageIndex, err error) {
+	err = r.replay("AllocateStorageIndex", []any{owner}, &index)
+	return
+}
+
+// Accounts
+
+func (r *Replayer) CreateAccount(payer runtime.Address) (address runtime.Address, err error) {
+	err = r.replay("CreateAccount", []any{payer}, &address)
+	return
+}
+
+func (r *Replayer) AddAccountKey(
+	address runtime.Address,
+	publicKey *runtime.PublicKey,
+	hashAlgo runtime.HashAlgorithm,
+	weight int,
+) (key *runtime.AccountKey, err error) {
+	err = r.replay("AddAccountKey", []any{address, publicKey, hashAlgo, weight}, &key)
+	return
+}
+
+func (r *Replayer) GetAccountKey(address runtime.Address, index int) (key *runtime.AccountKey, err error) {
+	err = r.replay("GetAccountKey", []any{address, index}, &key)
+	return
+}
+
+func (r *Replayer) AccountKeysCount(address runtime.Address) (count uint64, err error) {
+	err = r.replay("AccountKeysCount", []any{address}, &count)
+	return
+}
+
+func (r *Replayer) RevokeAccountKey(address runtime.Address, index int) (key *runtime.AccountKey, err error) {
+	err = r.replay("RevokeAccountKey", []any{address, index}, &key)
+	return
+}
+
+func (r *Replayer) UpdateAccountContractCode(location common.AddressLocation, code []byte) error {
+	return r.replay("UpdateAccountContractCode", []any{recordedLocationID(location), string(code)})
+}
+
+func (r *Replayer) GetAccountContractCode(location common.AddressLocation) (code []byte, err error) {
+	err = r.replay("GetAccountContractCode", []any{recordedLocationID(location)}, &code)
+	return
+}
+
+func (r *Replayer) RemoveAccountContractCode(location common.AddressLocation) error {
+	return r.replay("RemoveAccountContractCode", []any{recordedLocationID(location)})
+}
+
+func (r *Replayer) GetSigningAccounts() (addresses []runtime.Address, err error) {
+	err = r.replay("GetSigningAccounts", nil, &addresses)
+	return
+}
+
+func (r *Replayer) GetAccountBalance(address common.Address) (balance uint64, err error) {
+	err = r.replay("GetAccountBalance", []any{address}, &balance)
+	return
+}
+
+func (r *Replayer) GetAccountAvailableBalance(address common.Address) (balance uint64, err error) {
+	err = r.replay("GetAccountAvailableBalance", []any{address}, &balance)
+	return
+}
+
+func (r *Replayer) GetStorageUsed(address runtime.Address) (used uint64, err error) {
+	err = r.replay("GetStorageUsed", []any{address}, &used)
+	return
+}
+
+func (r *Replayer) GetStorageCapacity(address runtime.Address) (capacity uint64, err error) {
+	err = r.replay("GetStorageCapacity", []any{address}, &capacity)
+	return
+}
+
+func (r *Replayer) GetAccountContractNames(address runtime.Address) (names []string, err error) {
+	err = r.replay("GetAccountContractNames", []any{address}, &names)
+	return
+}
+
+func (r *Replayer) GenerateAccountID(address common.Address) (id uint64, err error) {
+	err = r.replay("GenerateAccountID", []any{address}, &id)
+	return
+}
+
+func (r *Replayer) ResourceOwnerChanged(
+	_ *interpreter.Interpreter,
+	resource *interpreter.CompositeValue,
+	oldOwner common.Address,
+	newOwner common.Address,
+) {
+	// The function cannot fail, so a divergence is reported by Err
+	_ = r.replay("ResourceOwnerChanged", []any{resource.TypeID(), oldOwner, newOwner})
+}
+
+// Execution
+
+func (r *Replayer) ProgramLog(message string) error {
+	return r.replay("ProgramLog", []any{message})
+}
+
+func (r *Replayer) EmitEvent(event cadence.Event) error {
+	encodedEvent, err := jsoncdc.Encode(event)
+	if err != nil {
+		return err
+	}
+	return r.replay("EmitEvent", []any{json.RawMessage(encodedEvent)})
+}
+
+func (r *Replayer) GenerateUUID() (uuid uint64, err error) {
+	err = r.replay("GenerateUUID", nil, &uuid)
+	return
+}
+
+func (r *Replayer) DecodeArgument(argument []byte, argumentType cadence.Type) (cadence.Value, error) {
+	var encodedValue json.RawMessage
+	err := r.replay("DecodeArgument", []any{argument, argumentType.ID()}, &encodedValue)
+	if err != nil {
+		return nil, err
+	}
+	return jsoncdc.Decode(nil, encodedValue)
+}
+
+func (r *Replayer) GetCurrentBlockHeight() (height uint64, err error) {
+	err = r.replay("GetCurrentBlockHeight", nil, &height)
+	return
+}
+
+func (r *Replayer) GetBlockAtHeight(height uint64) (block runtime.Block, exists bool, err error) {
+	err = r.replay("GetBlockAtHeight", []any{height}, &block, &exists)
+	return
+}
+
+func (r *Replayer) ReadRandom(buffer []byte) error {
+	var random []byte
+	err := r.replay("ReadRandom", []any{len(buffer)}, &random)
+	if err != nil {
+		return err
+	}
+	if len(random) != len(buffer) {
+		return fmt.Errorf(
+			"invalid recorded results of ReadRandom: expected %d bytes, got %d",
+			len(buffer),
+			len(random),
+		)
+	}
+	copy(buffer, random)
+	return nil
+}
+
+func (r *Replayer) ImplementationDebugLog(message string) error {
+	return r.replay("ImplementationDebugLog", []any{message})
+}
+
+func (r *Replayer) RecordTrace(_ string, _ runtime.Location, _ time.Duration, _ []attribute.KeyValue) {
+	// Traces are not recorded
+}
+
+// Crypto
+
+func (r *Replayer) VerifySignature(
+	signature []byte,
+	tag string,
+	signedData []byte,
+	publicKey []byte,
+	signatureAlgorithm runtime.SignatureAlgorithm,
+	hashAlgorithm runtime.HashAlgorithm,
+) (valid bool, err error) {
+	err = r.replay(
+		"VerifySignature",
+		[]any{signature, tag, signedData, publicKey, signatureAlgorithm, hashAlgorithm},
+		&valid,
+	)
+	return
+}
+
+func (r *Replayer) Hash(data []byte, tag string, hashAlgorithm runtime.HashAlgorithm) (hash []byte, err error) {
+	err = r.replay("Hash", []any{data, tag, hashAlgorithm}, &hash)
+	return
+}
+
+func (r *Replayer) ValidatePublicKey(key *runtime.PublicKey) error {
+	return r.replay("ValidatePublicKey", []any{key})
+}
+
+func (r *Replayer) BLSVerifyPOP(publicKey *runtime.PublicKey, signature []byte) (valid bool, err error) {
+	err = r.replay("BLSVerifyPOP", []any{publicKey, signature}, &valid)
+	return
+}
+
+func (r *Replayer) BLSAggregateSignatures(signatures [][]byte) (signature []byte, err error) {
+	err = r.replay("BLSAggregateSignatures", []any{signatures}, &signature)
+	return
+}
+
+func (r *Replayer) BLSAggregatePublicKeys(publicKeys []*runtime.PublicKey) (publicKey *runtime.PublicKey, err error) {
+	err = r.replay("BLSAggregatePublicKeys", []any{publicKeys}, &publicKey)
+	return
+}
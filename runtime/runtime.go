@@ -115,6 +115,20 @@ type Runtime interface {
 	// or if the execution fails.
 	ExecuteTransaction(Script, Context) error
 
+	// SimulateTransaction executes the given transaction without committing its effects.
+	//
+	// Writes to storage, updates of contract code, and emitted events are not passed to the interface.
+	// Instead, the result contains the changed values stored in the accounts, the updated contracts,
+	// the emitted events, and the computation used.
+	// Only the values which were read or written by the transaction are compared.
+	//
+	// Transactions which create accounts or add or revoke account keys cannot be simulated,
+	// and fail with an UnsupportedSimulationOperationError.
+	//
+	// This function returns an error if the program has errors (e.g syntax errors, type errors),
+	// or if the execution fails.
+	SimulateTransaction(Script, Context) (*TransactionSimulation, error)
+
 	// NewContractFunctionExecutor returns an executor which invokes a contract
 	// function with the given arguments.
 	NewContractFunctionExecutor(
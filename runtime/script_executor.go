@@ -108,6 +108,7 @@ func (executor *interpreterScriptExecutor) preprocess() (err error) {
 	runtimeInterface := context.Interface
 
 	storage := NewStorage(runtimeInterface, runtimeInterface)
+	storage.SetReadOnly(context.ReadOnly)
 	executor.storage = storage
 
 	environment := context.Environment
@@ -65,3 +65,12 @@ func (f *FunctionInvocations) Find(pos Position) *FunctionInvocation {
 	}
 	return &invocation
 }
+
+func (f *FunctionInvocations) FindAll(pos Position) []FunctionInvocation {
+	entries := f.tree.SearchAll(pos)
+	invocations := make([]FunctionInvocation, len(entries))
+	for i, entry := range entries {
+		invocations[i] = entry.Value
+	}
+	return invocations
+}
@@ -2195,6 +2195,46 @@ const arrayTypeMapFunctionDocString = `
 Returns a new array whose elements are produced by applying the mapper function on each element of the original array.
 `
 
+const ArrayTypeSortFunctionName = "sort"
+
+const arrayTypeSortFunctionDocString = `
+Returns a new array with the elements of the original array in sorted order.
+The given function must return true if the first element should be ordered before the second element.
+The sort is stable, i.e. equal elements retain their relative order.
+Available if the array element type is not resource-kinded.
+`
+
+const ArrayTypeReduceFunctionName = "reduce"
+
+const arrayTypeReduceFunctionDocString = `
+Combines the elements of the array into a single value.
+The given function is called with the initial value and the first element,
+then with the result of the previous call and the next element, and so on.
+Returns the result of the last call, or the initial value if the array is empty.
+Available if the array element type is not resource-kinded.
+`
+
+const ArrayTypeAnyFunctionName = "any"
+
+const arrayTypeAnyFunctionDocString = `
+Returns true if the given predicate function returns true for at least one element of the array.
+Available if the array element type is not resource-kinded.
+`
+
+const ArrayTypeAllFunctionName = "all"
+
+const arrayTypeAllFunctionDocString = `
+Returns true if the given predicate function returns true for all elements of the array.
+Available if the array element type is not resource-kinded.
+`
+
+const ArrayTypeLastIndexFunctionName = "lastIndex"
+
+const arrayTypeLastIndexFunctionDocString = `
+Returns the index of the last element matching the given object in the array, nil if no match.
+Available if the array element type is not resource-kinded and equatable.
+`
+
 func getArrayMembers(arrayType ArrayType) map[string]MemberResolver {
 
 	members := map[string]MemberResolver{
@@ -2388,6 +2428,170 @@ func getArrayMembers(arrayType ArrayType) map[string]MemberResolver {
 				)
 			},
 		},
+		ArrayTypeSortFunctionName: {
+			Kind: common.DeclarationKindFunction,
+			Resolve: func(
+				memoryGauge common.MemoryGauge,
+				identifier string,
+				targetRange ast.HasPosition,
+				report func(error),
+			) *Member {
+
+				elementType := arrayType.ElementType(false)
+
+				// It is impossible for a resource to be present in two arrays.
+
+				if elementType.IsResourceType() {
+					report(
+						&InvalidResourceArrayMemberError{
+							Name:            identifier,
+							DeclarationKind: common.DeclarationKindFunction,
+							Range:           ast.NewRangeFromPositioned(memoryGauge, targetRange),
+						},
+					)
+				}
+
+				return NewPublicFunctionMember(
+					memoryGauge,
+					arrayType,
+					identifier,
+					ArraySortFunctionType(arrayType),
+					arrayTypeSortFunctionDocString,
+				)
+			},
+		},
+		ArrayTypeReduceFunctionName: {
+			Kind: common.DeclarationKindFunction,
+			Resolve: func(
+				memoryGauge common.MemoryGauge,
+				identifier string,
+				targetRange ast.HasPosition,
+				report func(error),
+			) *Member {
+
+				elementType := arrayType.ElementType(false)
+
+				if elementType.IsResourceType() {
+					report(
+						&InvalidResourceArrayMemberError{
+							Name:            identifier,
+							DeclarationKind: common.DeclarationKindFunction,
+							Range:           ast.NewRangeFromPositioned(memoryGauge, targetRange),
+						},
+					)
+				}
+
+				return NewPublicFunctionMember(
+					memoryGauge,
+					arrayType,
+					identifier,
+					ArrayReduceFunctionType(elementType),
+					arrayTypeReduceFunctionDocString,
+				)
+			},
+		},
+		ArrayTypeAnyFunctionName: {
+			Kind: common.DeclarationKindFunction,
+			Resolve: func(
+				memoryGauge common.MemoryGauge,
+				identifier string,
+				targetRange ast.HasPosition,
+				report func(error),
+			) *Member {
+
+				elementType := arrayType.ElementType(false)
+
+				if elementType.IsResourceType() {
+					report(
+						&InvalidResourceArrayMemberError{
+							Name:            identifier,
+							DeclarationKind: common.DeclarationKindFunction,
+							Range:           ast.NewRangeFromPositioned(memoryGauge, targetRange),
+						},
+					)
+				}
+
+				return NewPublicFunctionMember(
+					memoryGauge,
+					arrayType,
+					identifier,
+					ArrayPredicateFunctionType(elementType),
+					arrayTypeAnyFunctionDocString,
+				)
+			},
+		},
+		ArrayTypeAllFunctionName: {
+			Kind: common.DeclarationKindFunction,
+			Resolve: func(
+				memoryGauge common.MemoryGauge,
+				identifier string,
+				targetRange ast.HasPosition,
+				report func(error),
+			) *Member {
+
+				elementType := arrayType.ElementType(false)
+
+				if elementType.IsResourceType() {
+					report(
+						&InvalidResourceArrayMemberError{
+							Name:            identifier,
+							DeclarationKind: common.DeclarationKindFunction,
+							Range:           ast.NewRangeFromPositioned(memoryGauge, targetRange),
+						},
+					)
+				}
+
+				return NewPublicFunctionMember(
+					memoryGauge,
+					arrayType,
+					identifier,
+					ArrayPredicateFunctionType(elementType),
+					arrayTypeAllFunctionDocString,
+				)
+			},
+		},
+		ArrayTypeLastIndexFunctionName: {
+			Kind: common.DeclarationKindFunction,
+			Resolve: func(
+				memoryGauge common.MemoryGauge,
+				identifier string,
+				targetRange ast.HasPosition,
+				report func(error),
+			) *Member {
+
+				elementType := arrayType.ElementType(false)
+
+				// It is impossible for an array of resources to have a `lastIndex` function:
+				// if the resource is passed as an argument, it cannot be inside the array
+
+				if elementType.IsResourceType() {
+					report(
+						&InvalidResourceArrayMemberError{
+							Name:            identifier,
+							DeclarationKind: common.DeclarationKindFunction,
+							Range:           ast.NewRangeFromPositioned(memoryGauge, targetRange),
+						},
+					)
+				}
+
+				if !elementType.IsEquatable() {
+					report(
+						&NotEquatableTypeError{
+							Type:  elementType,
+							Range: ast.NewRangeFromPositioned(memoryGauge, targetRange),
+						},
+					)
+				}
+
+				return NewPublicFunctionMember(
+					memoryGauge,
+					arrayType,
+					identifier,
+					ArrayLastIndexFunctionType(elementType),
+					arrayTypeLastIndexFunctionDocString,
+				)
+			},
+		},
 	}
 
 	// TODO: maybe still return members but report a helpful error?
@@ -2954,6 +3158,135 @@ func ArrayMapFunctionType(memoryGauge common.MemoryGauge, arrayType ArrayType) *
 	}
 }
 
+func ArraySortFunctionType(arrayType ArrayType) *FunctionType {
+	// For [T] or [T; N]
+	// fun sort(by isLess: view fun(T, T): Bool): [T]
+	//               or
+	// fun sort(by isLess: view fun(T, T): Bool): [T; N]
+
+	elementTypeAnnotation := NewTypeAnnotation(arrayType.ElementType(false))
+
+	// isLessFuncType: (elementType, elementType) -> Bool
+	isLessFuncType := &FunctionType{
+		Parameters: []Parameter{
+			{
+				Identifier:     "a",
+				TypeAnnotation: elementTypeAnnotation,
+			},
+			{
+				Identifier:     "b",
+				TypeAnnotation: elementTypeAnnotation,
+			},
+		},
+		ReturnTypeAnnotation: BoolTypeAnnotation,
+		Purity:               FunctionPurityView,
+	}
+
+	return &FunctionType{
+		Parameters: []Parameter{
+			{
+				Label:          "by",
+				Identifier:     "isLess",
+				TypeAnnotation: NewTypeAnnotation(isLessFuncType),
+			},
+		},
+		ReturnTypeAnnotation: NewTypeAnnotation(arrayType),
+		Purity:               FunctionPurityView,
+	}
+}
+
+func ArrayReduceFunctionType(elementType Type) *FunctionType {
+	// fun reduce(initial: U, _ f: fun(U, T): U): U
+
+	typeParameter := &TypeParameter{
+		Name: "U",
+	}
+
+	typeUAnnotation := NewTypeAnnotation(
+		&GenericType{
+			TypeParameter: typeParameter,
+		},
+	)
+
+	// reducerFuncType: (U, elementType) -> U
+	reducerFuncType := &FunctionType{
+		Parameters: []Parameter{
+			{
+				Identifier:     "accumulator",
+				TypeAnnotation: typeUAnnotation,
+			},
+			{
+				Identifier:     "element",
+				TypeAnnotation: NewTypeAnnotation(elementType),
+			},
+		},
+		ReturnTypeAnnotation: typeUAnnotation,
+	}
+
+	return &FunctionType{
+		TypeParameters: []*TypeParameter{
+			typeParameter,
+		},
+		Parameters: []Parameter{
+			{
+				Identifier:     "initial",
+				TypeAnnotation: typeUAnnotation,
+			},
+			{
+				Label:          ArgumentLabelNotRequired,
+				Identifier:     "f",
+				TypeAnnotation: NewTypeAnnotation(reducerFuncType),
+			},
+		},
+		ReturnTypeAnnotation: typeUAnnotation,
+	}
+}
+
+// ArrayPredicateFunctionType is the type of the `any` and `all` functions
+func ArrayPredicateFunctionType(elementType Type) *FunctionType {
+	// fun any(_ predicate: view fun(T): Bool): Bool
+	// fun all(_ predicate: view fun(T): Bool): Bool
+
+	// predicateFuncType: elementType -> Bool
+	predicateFuncType := &FunctionType{
+		Parameters: []Parameter{
+			{
+				Identifier:     "element",
+				TypeAnnotation: NewTypeAnnotation(elementType),
+			},
+		},
+		ReturnTypeAnnotation: BoolTypeAnnotation,
+		Purity:               FunctionPurityView,
+	}
+
+	return &FunctionType{
+		Parameters: []Parameter{
+			{
+				Label:          ArgumentLabelNotRequired,
+				Identifier:     "predicate",
+				TypeAnnotation: NewTypeAnnotation(predicateFuncType),
+			},
+		},
+		ReturnTypeAnnotation: BoolTypeAnnotation,
+		Purity:               FunctionPurityView,
+	}
+}
+
+func ArrayLastIndexFunctionType(elementType Type) *FunctionType {
+	return NewSimpleFunctionType(
+		FunctionPurityView,
+		[]Parameter{
+			{
+				Identifier:     "of",
+				TypeAnnotation: NewTypeAnnotation(elementType),
+			},
+		},
+		NewTypeAnnotation(
+			&OptionalType{Type: IntType},
+		),
+	)
+}
+
 // VariableSizedType is a variable sized array type
 type VariableSizedType struct {
 	Type                Type
@@ -4899,6 +5232,13 @@ func (t *CompositeType) GetBaseType() Type {
 	return t.baseType
 }
 
+// SetBaseType sets the base type of an attachment type.
+// The checker sets the base type when it declares the attachment,
+// this is only needed when reconstructing a checked attachment type, e.g. when decoding a cached program
+func (t *CompositeType) SetBaseType(baseType Type) {
+	t.baseType = baseType
+}
+
 func (t *CompositeType) GetLocation() common.Location {
 	return t.Location
 }
@@ -6203,6 +6543,42 @@ Removes the value for the given key from the dictionary.
 Returns the value as an optional if the dictionary contained the key, or nil if the dictionary did not contain the key
 `
 
+const DictionaryTypeFilterFunctionName = "filter"
+
+const dictionaryTypeFilterFunctionDocString = `
+Returns a new dictionary which contains the entries of the dictionary for which the given function returns true.
+The function is called with the key and the value of each entry.
+Available if the key type and the value type are not resource-kinded.
+`
+
+const DictionaryTypeMapValuesFunctionName = "mapValues"
+
+const dictionaryTypeMapValuesFunctionDocString = `
+Returns a new dictionary with the same keys as the dictionary,
+whose values are produced by applying the given function on each value of the dictionary.
+Available if the key type and the value type are not resource-kinded.
+`
+
+const DictionaryTypeForEachFunctionName = "forEach"
+
+const dictionaryTypeForEachFunctionDocString = `
+Iterate over each entry in this dictionary, exiting early if the passed function returns false.
+The function is called with the key and the value of each entry.
+This method is more performant than iterating over .keys and looking up each value,
+since no intermediate storage is allocated.
+
+The order of iteration is undefined.
+Available if the key type and the value type are not resource-kinded.
+`
+
+const DictionaryTypeMergeFunctionName = "merge"
+
+const dictionaryTypeMergeFunctionDocString = `
+Inserts all entries of the given dictionary into the dictionary.
+If the dictionary already contains a key, its value is replaced with the value of the given dictionary.
+Available if the key type and the value type are not resource-kinded.
+`
+
 func (t *DictionaryType) Map(gauge common.MemoryGauge, typeParamMap map[*TypeParameter]*TypeParameter, f func(Type) Type) Type {
 	return f(NewDictionaryType(
 		gauge,
@@ -6378,6 +6754,115 @@ func (t *DictionaryType) initializeMemberResolvers() {
 						)
 					},
 				},
+				DictionaryTypeFilterFunctionName: {
+					Kind: common.DeclarationKindFunction,
+					Resolve: func(
+						memoryGauge common.MemoryGauge,
+						identifier string,
+						targetRange ast.HasPosition,
+						report func(error),
+					) *Member {
+						if t.KeyType.IsResourceType() || t.ValueType.IsResourceType() {
+							report(
+								&InvalidResourceDictionaryMemberError{
+									Name:            identifier,
+									DeclarationKind: common.DeclarationKindFunction,
+									Range:           ast.NewRangeFromPositioned(memoryGauge, targetRange),
+								},
+							)
+						}
+
+						return NewPublicFunctionMember(
+							memoryGauge,
+							t,
+							identifier,
+							DictionaryFilterFunctionType(t),
+							dictionaryTypeFilterFunctionDocString,
+						)
+					},
+				},
+				DictionaryTypeMapValuesFunctionName: {
+					Kind: common.DeclarationKindFunction,
+					Resolve: func(
+						memoryGauge common.MemoryGauge,
+						identifier string,
+						targetRange ast.HasPosition,
+						report func(error),
+					) *Member {
+						if t.KeyType.IsResourceType() || t.ValueType.IsResourceType() {
+							report(
+								&InvalidResourceDictionaryMemberError{
+									Name:            identifier,
+									DeclarationKind: common.DeclarationKindFunction,
+									Range:           ast.NewRangeFromPositioned(memoryGauge, targetRange),
+								},
+							)
+						}
+
+						return NewPublicFunctionMember(
+							memoryGauge,
+							t,
+							identifier,
+							DictionaryMapValuesFunctionType(memoryGauge, t),
+							dictionaryTypeMapValuesFunctionDocString,
+						)
+					},
+				},
+				DictionaryTypeForEachFunctionName: {
+					Kind: common.DeclarationKindFunction,
+					Resolve: func(
+						memoryGauge common.MemoryGauge,
+						identifier string,
+						targetRange ast.HasPosition,
+						report func(error),
+					) *Member {
+						if t.KeyType.IsResourceType() || t.ValueType.IsResourceType() {
+							report(
+								&InvalidResourceDictionaryMemberError{
+									Name:            identifier,
+									DeclarationKind: common.DeclarationKindFunction,
+									Range:           ast.NewRangeFromPositioned(memoryGauge, targetRange),
+								},
+							)
+						}
+
+						return NewPublicFunctionMember(
+							memoryGauge,
+							t,
+							identifier,
+							DictionaryForEachFunctionType(t),
+							dictionaryTypeForEachFunctionDocString,
+						)
+					},
+				},
+				DictionaryTypeMergeFunctionName: {
+					Kind: common.DeclarationKindFunction,
+					Resolve: func(
+						memoryGauge common.MemoryGauge,
+						identifier string,
+						targetRange ast.HasPosition,
+						report func(error),
+					) *Member {
+						if t.KeyType.IsResourceType() || t.ValueType.IsResourceType() {
+							report(
+								&InvalidResourceDictionaryMemberError{
+									Name:            identifier,
+									DeclarationKind: common.DeclarationKindFunction,
+									Range:           ast.NewRangeFromPositioned(memoryGauge, targetRange),
+								},
+							)
+						}
+
+						return NewFunctionMember(
+							memoryGauge,
+							t,
+							insertMutateEntitledAccess,
+							identifier,
+							DictionaryMergeFunctionType(t),
+							dictionaryTypeMergeFunctionDocString,
+						)
+					},
+				},
 			},
 		)
 	})
@@ -6465,6 +6950,124 @@ func DictionaryForEachKeyFunctionType(t *DictionaryType) *FunctionType {
 	)
 }
 
+func DictionaryFilterFunctionType(t *DictionaryType) *FunctionType {
+	// fun filter(_ f: view fun(K, V): Bool): {K: V}
+
+	// funcType: (K, V) -> Bool
+	funcType := &FunctionType{
+		Parameters: []Parameter{
+			{
+				Identifier:     "key",
+				TypeAnnotation: NewTypeAnnotation(t.KeyType),
+			},
+			{
+				Identifier:     "value",
+				TypeAnnotation: NewTypeAnnotation(t.ValueType),
+			},
+		},
+		ReturnTypeAnnotation: BoolTypeAnnotation,
+		Purity:               FunctionPurityView,
+	}
+
+	return &FunctionType{
+		Parameters: []Parameter{
+			{
+				Label:          ArgumentLabelNotRequired,
+				Identifier:     "f",
+				TypeAnnotation: NewTypeAnnotation(funcType),
+			},
+		},
+		ReturnTypeAnnotation: NewTypeAnnotation(t),
+		Purity:               FunctionPurityView,
+	}
+}
+
+func DictionaryMapValuesFunctionType(memoryGauge common.MemoryGauge, t *DictionaryType) *FunctionType {
+	// fun mapValues(_ transform: fun(V): U): {K: U}
+
+	typeParameter := &TypeParameter{
+		Name: "U",
+	}
+
+	typeU := &GenericType{
+		TypeParameter: typeParameter,
+	}
+
+	// transformFuncType: V -> U
+	transformFuncType := &FunctionType{
+		Parameters: []Parameter{
+			{
+				Identifier:     "value",
+				TypeAnnotation: NewTypeAnnotation(t.ValueType),
+			},
+		},
+		ReturnTypeAnnotation: NewTypeAnnotation(typeU),
+	}
+
+	return &FunctionType{
+		TypeParameters: []*TypeParameter{
+			typeParameter,
+		},
+		Parameters: []Parameter{
+			{
+				Label:          ArgumentLabelNotRequired,
+				Identifier:     "transform",
+				TypeAnnotation: NewTypeAnnotation(transformFuncType),
+			},
+		},
+		ReturnTypeAnnotation: NewTypeAnnotation(
+			NewDictionaryType(memoryGauge, t.KeyType, typeU),
+		),
+	}
+}
+
+func DictionaryForEachFunctionType(t *DictionaryType) *FunctionType {
+	const functionPurity = FunctionPurityImpure
+
+	// fun(K, V): Bool
+	funcType := NewSimpleFunctionType(
+		functionPurity,
+		[]Parameter{
+			{
+				Identifier:     "key",
+				TypeAnnotation: NewTypeAnnotation(t.KeyType),
+			},
+			{
+				Identifier:     "value",
+				TypeAnnotation: NewTypeAnnotation(t.ValueType),
+			},
+		},
+		BoolTypeAnnotation,
+	)
+
+	// fun forEach(_ function: fun(K, V): Bool): Void
+	return NewSimpleFunctionType(
+		functionPurity,
+		[]Parameter{
+			{
+				Label:          ArgumentLabelNotRequired,
+				Identifier:     "function",
+				TypeAnnotation: NewTypeAnnotation(funcType),
+			},
+		},
+		VoidTypeAnnotation,
+	)
+}
+
+func DictionaryMergeFunctionType(t *DictionaryType) *FunctionType {
+	return NewSimpleFunctionType(
+		FunctionPurityImpure,
+		[]Parameter{
+			{
+				Label:          ArgumentLabelNotRequired,
+				Identifier:     "other",
+				TypeAnnotation: NewTypeAnnotation(t),
+			},
+		},
+		VoidTypeAnnotation,
+	)
+}
+
 func (*DictionaryType) isValueIndexableType() bool {
 	return true
 }
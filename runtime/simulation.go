@@ -0,0 +1,604 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"bytes"
+	"math"
+	"sort"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/encoding/ccf"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/errors"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/stdlib"
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=StorageChangeKind -trimprefix=StorageChangeKind
+
+// StorageChangeKind is the kind of change of a value in storage.
+type StorageChangeKind uint8
+
+const (
+	StorageChangeKindUnknown StorageChangeKind = iota
+	StorageChangeKindAdded
+	StorageChangeKindRemoved
+	StorageChangeKindChanged
+)
+
+// StorageChange is a change of the value stored in a domain of an account under a key,
+// e.g. the value stored at a path.
+type StorageChange struct {
+	// Domain is the storage domain, e.g. "storage" or "public"
+	Domain string
+	// Key is the key in the storage domain, e.g. the identifier of a path
+	Key  string
+	Kind StorageChangeKind
+	// OldValue is the value before the transaction, nil if the value was added
+	OldValue cadence.Value
+	// NewValue is the value after the transaction, nil if the value was removed
+	NewValue cadence.Value
+}
+
+// AccountStorageDiff is the difference of the storage of an account
+// before and after a simulated transaction.
+type AccountStorageDiff struct {
+	Address common.Address
+	// Changes are the changes of values, sorted by domain and key
+	Changes []StorageChange
+	// BytesDelta is the difference of the sizes of the registers of the account,
+	// i.e. of their keys and values
+	BytesDelta int64
+}
+
+// TransactionSimulation is the result of a simulated transaction.
+type TransactionSimulation struct {
+	// Storage are the differences of the storage of all accounts which were written,
+	// sorted by address
+	Storage []AccountStorageDiff
+	// UpdatedContracts are the locations of contracts of which the code was updated or removed,
+	// sorted by address and name
+	UpdatedContracts []common.AddressLocation
+	// Events are the events which were emitted, in order
+	Events []cadence.Event
+	// ComputationUsed is the sum of all computation intensities that were metered
+	ComputationUsed uint64
+}
+
+// simulatedStorageDomains are the storage domains which are compared when simulating a transaction.
+// Only domains which contain values that can be exported are included,
+// so e.g. contract values and capability controllers are not compared.
+// Updates of contract code are reported in TransactionSimulation.UpdatedContracts instead
+var simulatedStorageDomains = []string{
+	common.PathDomainStorage.Identifier(),
+	common.PathDomainPrivate.Identifier(),
+	common.PathDomainPublic.Identifier(),
+	stdlib.InboxStorageDomain,
+}
+
+type simulatedStorageKey struct {
+	address common.Address
+	domain  string
+	key     string
+}
+
+// simulationInterface is a runtime interface which buffers all writes
+// of the wrapped interface's storage and contract code, and records emitted events.
+//
+// UUIDs and account IDs are generated without changing the state of the wrapped interface.
+// Operations which cannot be buffered, like creating accounts and adding or revoking account keys,
+// are rejected with an UnsupportedSimulationOperationError.
+type simulationInterface struct {
+	Interface
+	ledger          *BufferedLedger
+	accountIDs      map[common.Address]uint64
+	uuid            uint64
+	accessedKeys    map[simulatedStorageKey]struct{}
+	contractCodes   map[common.AddressLocation][]byte
+	events          []cadence.Event
+	computationUsed uint64
+}
+
+var _ Interface = &simulationInterface{}
+
+func newSimulationInterface(inner Interface) *simulationInterface {
+	return &simulationInterface{
+		Interface:     inner,
+		ledger:        NewBufferedLedger(inner),
+		accountIDs:    map[common.Address]uint64{},
+		uuid:          math.MaxUint64,
+		accessedKeys:  map[simulatedStorageKey]struct{}{},
+		contractCodes: map[common.AddressLocation][]byte{},
+	}
+}
+
+func (i *simulationInterface) GetValue(owner, key []byte) ([]byte, error) {
+	return i.ledger.GetValue(owner, key)
+}
+
+func (i *simulationInterface) SetValue(owner, key, value []byte) error {
+	return i.ledger.SetValue(owner, key, value)
+}
+
+func (i *simulationInterface) ValueExists(owner, key []byte) (bool, error) {
+	return i.ledger.ValueExists(owner, key)
+}
+
+// AllocateStorageIndex allocates storage indices in the buffered ledger,
+// so the allocation state of the wrapped interface is not changed.
+func (i *simulationInterface) AllocateStorageIndex(owner []byte) (atree.StorageIndex, error) {
+	return i.ledger.AllocateStorageIndex(owner)
+}
+
+// GenerateUUID generates UUIDs from the top of the UUID space,
+// so they do not collide with UUIDs generated by the wrapped interface,
+// and the UUID state of the wrapped interface is not changed.
+func (i *simulationInterface) GenerateUUID() (uint64, error) {
+	if i.uuid == 0 {
+		return 0, errors.NewUnexpectedError("UUIDs exhausted")
+	}
+	uuid := i.uuid
+	i.uuid--
+	return uuid, nil
+}
+
+// GenerateAccountID generates account IDs from the top of the ID space,
+// so they do not collide with IDs generated by the wrapped interface,
+// and the account ID state of the wrapped interface is not changed.
+func (i *simulationInterface) GenerateAccountID(address common.Address) (uint64, error) {
+	id, ok := i.accountIDs[address]
+	if !ok {
+		id = math.MaxUint64
+	}
+	if id == 0 {
+		return 0, errors.NewUnexpectedError("account IDs exhausted")
+	}
+	i.accountIDs[address] = id - 1
+	return id, nil
+}
+
+func (i *simulationInterface) CreateAccount(_ Address) (Address, error) {
+	return Address{}, &UnsupportedSimulationOperationError{
+		Operation: "create account",
+	}
+}
+
+func (i *simulationInterface) AddAccountKey(
+	_ Address,
+	_ *PublicKey,
+	_ HashAlgorithm,
+	_ int,
+) (*AccountKey, error) {
+	return nil, &UnsupportedSimulationOperationError{
+		Operation: "add account key",
+	}
+}
+
+func (i *simulationInterface) RevokeAccountKey(_ Address, _ int) (*AccountKey, error) {
+	return nil, &UnsupportedSimulationOperationError{
+		Operation: "revoke account key",
+	}
+}
+
+// recordStoredValueAccess records the keys of the compared storage domains which were read or written,
+// as only the values stored under these keys may have changed.
+// It is the OnStoredValueAccess handler of the environment during the simulation
+func (i *simulationInterface) recordStoredValueAccess(
+	_ *interpreter.Interpreter,
+	address common.Address,
+	domain string,
+	key interpreter.StorageMapKey,
+) {
+	stringKey, ok := key.(interpreter.StringStorageMapKey)
+	if !ok || simulatedStorageDomainIndex(domain) < 0 {
+		return
+	}
+
+	i.accessedKeys[simulatedStorageKey{
+		address: address,
+		domain:  domain,
+		key:     string(stringKey),
+	}] = struct{}{}
+}
+
+// simulatedStorageDomainIndex returns the index of the given domain in simulatedStorageDomains,
+// or -1 if the domain is not compared
+func simulatedStorageDomainIndex(domain string) int {
+	for index, simulatedDomain := range simulatedStorageDomains {
+		if domain == simulatedDomain {
+			return index
+		}
+	}
+	return -1
+}
+
+// accessedStorageKeys returns the keys of the given account which were read or written,
+// sorted by domain, in the order of simulatedStorageDomains, and key
+func (i *simulationInterface) accessedStorageKeys(address common.Address) []simulatedStorageKey {
+	var keys []simulatedStorageKey
+	for key := range i.accessedKeys { //nolint:maprange
+		if key.address == address {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a := keys[i]
+		b := keys[j]
+		if a.domain != b.domain {
+			return simulatedStorageDomainIndex(a.domain) < simulatedStorageDomainIndex(b.domain)
+		}
+		return a.key < b.key
+	})
+
+	return keys
+}
+
+func (i *simulationInterface) UpdateAccountContractCode(location common.AddressLocation, code []byte) error {
+	i.contractCodes[location] = append([]byte{}, code...)
+	return nil
+}
+
+func (i *simulationInterface) RemoveAccountContractCode(location common.AddressLocation) error {
+	i.contractCodes[location] = nil
+	return nil
+}
+
+func (i *simulationInterface) GetAccountContractCode(location common.AddressLocation) ([]byte, error) {
+	code, ok := i.contractCodes[location]
+	if ok {
+		return code, nil
+	}
+	return i.Interface.GetAccountContractCode(location)
+}
+
+func (i *simulationInterface) GetAccountContractNames(address Address) ([]string, error) {
+	names, err := i.Interface.GetAccountContractNames(address)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := false
+	for location, code := range i.contractCodes { //nolint:maprange
+		if location.Address != address {
+			continue
+		}
+		updated = true
+
+		index := -1
+		for j, name := range names {
+			if name == location.Name {
+				index = j
+				break
+			}
+		}
+
+		switch {
+		case code == nil && index >= 0:
+			names = append(names[:index:index], names[index+1:]...)
+		case code != nil && index < 0:
+			names = append(names, location.Name)
+		}
+	}
+
+	if updated {
+		sort.Strings(names)
+	}
+
+	return names, nil
+}
+
+// GetOrLoadProgram does not cache the programs of contracts of which the code was updated,
+// so the wrapped interface does not observe the updated code
+func (i *simulationInterface) GetOrLoadProgram(
+	location Location,
+	load func() (*interpreter.Program, error),
+) (*interpreter.Program, error) {
+	if addressLocation, ok := location.(common.AddressLocation); ok {
+		if _, ok := i.contractCodes[addressLocation]; ok {
+			return load()
+		}
+	}
+	return i.Interface.GetOrLoadProgram(location, load)
+}
+
+func (i *simulationInterface) EmitEvent(event cadence.Event) error {
+	i.events = append(i.events, event)
+	return nil
+}
+
+func (i *simulationInterface) MeterComputation(operationType common.ComputationKind, intensity uint) error {
+	i.computationUsed += uint64(intensity)
+	return i.Interface.MeterComputation(operationType, intensity)
+}
+
+// writtenAddresses returns the addresses of all accounts of which registers were written, sorted
+func (i *simulationInterface) writtenAddresses() ([]common.Address, error) {
+	var addresses []common.Address
+
+	// Registers are ordered by owner,
+	// so the addresses are sorted, and only the last address needs to be compared
+	err := i.ledger.ForEachBufferedRegister(func(owner, _, _ []byte) error {
+		address, err := common.BytesToAddress(owner)
+		if err != nil {
+			return nil
+		}
+		if len(addresses) > 0 && addresses[len(addresses)-1] == address {
+			return nil
+		}
+		addresses = append(addresses, address)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+// bytesDelta returns the difference of the sizes of the registers of the given account
+func (i *simulationInterface) bytesDelta(address common.Address) (int64, error) {
+	var delta int64
+
+	err := i.ledger.ForEachBufferedRegister(func(owner, key, value []byte) error {
+		if !bytes.Equal(owner, address[:]) {
+			return nil
+		}
+
+		oldValue, err := i.Interface.GetValue(owner, key)
+		if err != nil {
+			return err
+		}
+
+		delta += registerSize(key, value) - registerSize(key, oldValue)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return delta, nil
+}
+
+func registerSize(key []byte, value []byte) int64 {
+	if len(value) == 0 {
+		return 0
+	}
+	return int64(len(key) + len(value))
+}
+
+// updatedContracts returns the locations of all contracts of which the code was updated or removed, sorted
+func (i *simulationInterface) updatedContracts() []common.AddressLocation {
+	var locations []common.AddressLocation
+	for location := range i.contractCodes { //nolint:maprange
+		locations = append(locations, location)
+	}
+
+	sort.Slice(locations, func(i, j int) bool {
+		a := locations[i]
+		b := locations[j]
+		if c := bytes.Compare(a.Address[:], b.Address[:]); c != 0 {
+			return c < 0
+		}
+		return a.Name < b.Name
+	})
+
+	return locations
+}
+
+func (r *interpreterRuntime) SimulateTransaction(
+	script Script,
+	context Context,
+) (
+	result *TransactionSimulation,
+	err error,
+) {
+	location := context.Location
+	if _, ok := location.(common.TransactionLocation); !ok {
+		return nil, errors.NewUnexpectedError("invalid non-transaction location: %s", location)
+	}
+
+	var codesAndPrograms CodesAndPrograms
+
+	defer r.Recover(
+		func(internalErr Error) {
+			err = internalErr
+		},
+		location,
+		codesAndPrograms,
+	)
+
+	simulation := newSimulationInterface(context.Interface)
+
+	environment := context.Environment
+	if environment == nil {
+		environment = NewBaseInterpreterEnvironment(r.defaultConfig)
+	}
+
+	// Record the keys of the values which are read or written by the transaction,
+	// and reset the handler afterwards, as the environment may be reused
+	environment.SetOnStoredValueAccessHandler(simulation.recordStoredValueAccess)
+	defer environment.SetOnStoredValueAccessHandler(nil)
+
+	innerContext := context
+	context.Interface = simulation
+	context.Environment = environment
+
+	_, err = r.NewTransactionExecutor(script, context).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	_, oldInter, err := r.Storage(innerContext)
+	if err != nil {
+		return nil, err
+	}
+
+	_, newInter, err := r.Storage(context)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := simulation.writtenAddresses()
+	if err != nil {
+		return nil, newError(interpreter.WrappedExternalError(err), location, codesAndPrograms)
+	}
+
+	var diffs []AccountStorageDiff
+
+	for _, address := range addresses {
+
+		changes, err := diffAccountStorage(
+			simulation.accessedStorageKeys(address),
+			oldInter,
+			newInter,
+		)
+		if err != nil {
+			return nil, newError(err, location, codesAndPrograms)
+		}
+
+		bytesDelta, err := simulation.bytesDelta(address)
+		if err != nil {
+			return nil, newError(interpreter.WrappedExternalError(err), location, codesAndPrograms)
+		}
+
+		if len(changes) == 0 && bytesDelta == 0 {
+			continue
+		}
+
+		diffs = append(diffs, AccountStorageDiff{
+			Address:    address,
+			Changes:    changes,
+			BytesDelta: bytesDelta,
+		})
+	}
+
+	return &TransactionSimulation{
+		Storage:          diffs,
+		UpdatedContracts: simulation.updatedContracts(),
+		Events:           simulation.events,
+		ComputationUsed:  simulation.computationUsed,
+	}, nil
+}
+
+// diffAccountStorage compares the values stored under the given keys before and after the transaction.
+//
+// Values are compared by their deterministic CCF encodings,
+// as exported values may e.g. contain types, which are not comparable structurally
+func diffAccountStorage(
+	keys []simulatedStorageKey,
+	oldInter *interpreter.Interpreter,
+	newInter *interpreter.Interpreter,
+) (
+	changes []StorageChange,
+	err error,
+) {
+	for _, key := range keys {
+		change := StorageChange{
+			Domain: key.domain,
+			Key:    key.key,
+		}
+
+		var oldEncoded, newEncoded []byte
+
+		change.OldValue, oldEncoded, err = exportStoredValue(oldInter, key)
+		if err != nil {
+			return nil, err
+		}
+
+		change.NewValue, newEncoded, err = exportStoredValue(newInter, key)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case change.OldValue == nil && change.NewValue == nil:
+			continue
+		case change.OldValue == nil:
+			change.Kind = StorageChangeKindAdded
+		case change.NewValue == nil:
+			change.Kind = StorageChangeKindRemoved
+		case !bytes.Equal(oldEncoded, newEncoded):
+			change.Kind = StorageChangeKindChanged
+		default:
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// storedValueEncMode is the CCF encoding mode used to compare stored values.
+// Composite fields, intersection types, and entitlements are sorted,
+// so the encoding of equal values is identical
+var storedValueEncMode = func() ccf.EncMode {
+	encMode, err := ccf.EncOptions{
+		SortCompositeFields:   ccf.SortBytewiseLexical,
+		SortIntersectionTypes: ccf.SortBytewiseLexical,
+		SortEntitlementTypes:  ccf.SortBytewiseLexical,
+	}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return encMode
+}()
+
+// exportStoredValue returns the exported value stored under the given key and its CCF encoding,
+// or nil if there is none
+func exportStoredValue(
+	inter *interpreter.Interpreter,
+	key simulatedStorageKey,
+) (
+	cadence.Value,
+	[]byte,
+	error,
+) {
+	value := readStoredValue(inter, key)
+	if value == nil {
+		return nil, nil, nil
+	}
+
+	exported, err := ExportValue(value, inter, interpreter.EmptyLocationRange)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encoded, err := storedValueEncMode.Encode(exported)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return exported, encoded, nil
+}
+
+// readStoredValue returns the value stored under the given key, or nil if there is none.
+// The value is read directly from the storage map, so the access is not recorded
+func readStoredValue(inter *interpreter.Interpreter, key simulatedStorageKey) interpreter.Value {
+	storageMap := inter.Storage().GetStorageMap(key.address, key.domain, false)
+	if storageMap == nil {
+		return nil
+	}
+
+	return storageMap.ReadValue(inter, interpreter.StringStorageMapKey(key.key))
+}
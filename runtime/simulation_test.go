@@ -0,0 +1,481 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence"
+	. "github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/tests/runtime_utils"
+	. "github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestRuntimeSimulateTransaction(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.MustBytesToAddress([]byte{0x1})
+
+	contract := []byte(`
+      access(all) contract Test {
+
+          access(all) event Saved(value: Int)
+
+          access(all) resource R {}
+
+          access(all) fun createR(): @R {
+              return <-create R()
+          }
+
+          access(all) fun save(account: auth(Storage) &Account, value: Int) {
+              account.storage.save(value, to: /storage/added)
+              emit Saved(value: value)
+          }
+      }
+    `)
+
+	newRuntimeInterface := func() (*TestRuntimeInterface, *int, *[]cadence.Event) {
+		var writes int
+		var events []cadence.Event
+		accountCodes := map[Location][]byte{}
+
+		return &TestRuntimeInterface{
+			Storage: NewTestLedger(nil, func(_, _, _ []byte) {
+				writes++
+			}),
+			OnGetSigningAccounts: func() ([]Address, error) {
+				return []Address{address}, nil
+			},
+			OnResolveLocation: NewSingleIdentifierLocationResolver(t),
+			OnUpdateAccountContractCode: func(location common.AddressLocation, code []byte) error {
+				accountCodes[location] = code
+				return nil
+			},
+			OnGetAccountContractCode: func(location common.AddressLocation) ([]byte, error) {
+				return accountCodes[location], nil
+			},
+			OnEmitEvent: func(event cadence.Event) error {
+				events = append(events, event)
+				return nil
+			},
+			OnMeterComputation: func(_ common.ComputationKind, _ uint) error {
+				return nil
+			},
+		}, &writes, &events
+	}
+
+	setup := func(
+		t *testing.T,
+		runtime Runtime,
+		runtimeInterface Interface,
+		nextTransactionLocation func() common.TransactionLocation,
+	) {
+		err := runtime.ExecuteTransaction(
+			Script{
+				Source: DeploymentTransaction("Test", contract),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  nextTransactionLocation(),
+			},
+		)
+		require.NoError(t, err)
+
+		err = runtime.ExecuteTransaction(
+			Script{
+				Source: []byte(`
+                  transaction {
+                      prepare(signer: auth(Storage) &Account) {
+                          signer.storage.save([1, 2, 3], to: /storage/numbers)
+                          signer.storage.save("old", to: /storage/removed)
+                          signer.storage.save(true, to: /storage/unchanged)
+                      }
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  nextTransactionLocation(),
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	t.Run("diff", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, writes, events := newRuntimeInterface()
+		nextTransactionLocation := NewTransactionLocationGenerator()
+		setup(t, runtime, runtimeInterface, nextTransactionLocation)
+		*writes = 0
+		*events = nil
+
+		simulation, err := runtime.SimulateTransaction(
+			Script{
+				Source: []byte(`
+                  import Test from 0x1
+
+                  transaction {
+                      prepare(signer: auth(Storage) &Account) {
+                          let numbers = signer.storage.load<[Int]>(from: /storage/numbers)!
+                          numbers.append(4)
+                          signer.storage.save(numbers, to: /storage/numbers)
+
+                          signer.storage.load<String>(from: /storage/removed)
+
+                          Test.save(account: signer, value: 42)
+                      }
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  nextTransactionLocation(),
+			},
+		)
+		require.NoError(t, err)
+
+		// The effects are not committed
+
+		assert.Zero(t, *writes)
+		assert.Empty(t, *events)
+
+		require.Len(t, simulation.Storage, 1)
+
+		diff := simulation.Storage[0]
+		assert.Equal(t, address, diff.Address)
+		assert.NotZero(t, diff.BytesDelta)
+
+		numbersType := cadence.NewVariableSizedArrayType(cadence.IntType)
+
+		assert.Equal(t,
+			[]StorageChange{
+				{
+					Domain:   "storage",
+					Key:      "added",
+					Kind:     StorageChangeKindAdded,
+					NewValue: cadence.NewInt(42),
+				},
+				{
+					Domain: "storage",
+					Key:    "numbers",
+					Kind:   StorageChangeKindChanged,
+					OldValue: cadence.NewArray([]cadence.Value{
+						cadence.NewInt(1),
+						cadence.NewInt(2),
+						cadence.NewInt(3),
+					}).WithType(numbersType),
+					NewValue: cadence.NewArray([]cadence.Value{
+						cadence.NewInt(1),
+						cadence.NewInt(2),
+						cadence.NewInt(3),
+						cadence.NewInt(4),
+					}).WithType(numbersType),
+				},
+				{
+					Domain:   "storage",
+					Key:      "removed",
+					Kind:     StorageChangeKindRemoved,
+					OldValue: cadence.String("old"),
+				},
+			},
+			diff.Changes,
+		)
+
+		require.Len(t, simulation.Events, 1)
+		assert.Equal(t,
+			"A.0000000000000001.Test.Saved",
+			simulation.Events[0].EventType.ID(),
+		)
+		assert.Equal(t,
+			[]cadence.Value{cadence.NewInt(42)},
+			simulation.Events[0].Fields,
+		)
+
+		assert.Empty(t, simulation.UpdatedContracts)
+		assert.NotZero(t, simulation.ComputationUsed)
+
+		// The storage is unchanged
+
+		value, err := runtime.ExecuteScript(
+			Script{
+				Source: []byte(`
+                  access(all) fun main(): [Int] {
+                      return getAuthAccount<auth(Storage) &Account>(0x1)
+                          .storage.copy<[Int]>(from: /storage/numbers)!
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  common.ScriptLocation{},
+			},
+		)
+		require.NoError(t, err)
+		assert.Equal(t,
+			cadence.NewArray([]cadence.Value{
+				cadence.NewInt(1),
+				cadence.NewInt(2),
+				cadence.NewInt(3),
+			}).WithType(numbersType),
+			value,
+		)
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, _, _ := newRuntimeInterface()
+		nextTransactionLocation := NewTransactionLocationGenerator()
+		setup(t, runtime, runtimeInterface, nextTransactionLocation)
+
+		simulation, err := runtime.SimulateTransaction(
+			Script{
+				Source: []byte(`
+                  transaction {
+                      prepare(signer: auth(Storage) &Account) {
+                          let value = signer.storage.load<Bool>(from: /storage/unchanged)!
+                          signer.storage.save(value, to: /storage/unchanged)
+
+                          let numbers = signer.storage.load<[Int]>(from: /storage/numbers)!
+                          signer.storage.save(numbers, to: /storage/numbers)
+                      }
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  nextTransactionLocation(),
+			},
+		)
+		require.NoError(t, err)
+
+		for _, diff := range simulation.Storage {
+			assert.Empty(t, diff.Changes)
+		}
+		assert.Empty(t, simulation.Events)
+	})
+
+	t.Run("mutation through reference, UUIDs, and account IDs", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, writes, _ := newRuntimeInterface()
+		nextTransactionLocation := NewTransactionLocationGenerator()
+		setup(t, runtime, runtimeInterface, nextTransactionLocation)
+		*writes = 0
+
+		runtimeInterface.OnGenerateUUID = func() (uint64, error) {
+			require.FailNow(t, "unexpected UUID generation")
+			return 0, nil
+		}
+		runtimeInterface.OnGenerateAccountID = func(_ common.Address) (uint64, error) {
+			require.FailNow(t, "unexpected account ID generation")
+			return 0, nil
+		}
+
+		simulation, err := runtime.Runtime.SimulateTransaction(
+			Script{
+				Source: []byte(`
+                  import Test from 0x1
+
+                  transaction {
+                      prepare(signer: auth(Storage, Capabilities) &Account) {
+                          signer.storage.borrow<auth(Mutate) &[Int]>(from: /storage/numbers)!
+                              .append(4)
+
+                          signer.storage.save(<-Test.createR(), to: /storage/r)
+
+                          let cap = signer.capabilities.storage.issue<&[Int]>(/storage/numbers)
+                          signer.capabilities.publish(cap, at: /public/numbers)
+                      }
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  nextTransactionLocation(),
+			},
+		)
+		require.NoError(t, err)
+
+		assert.Zero(t, *writes)
+
+		require.Len(t, simulation.Storage, 1)
+
+		type changeKey struct {
+			domain string
+			key    string
+			kind   StorageChangeKind
+		}
+
+		var changes []changeKey
+		for _, change := range simulation.Storage[0].Changes {
+			changes = append(changes, changeKey{
+				domain: change.Domain,
+				key:    change.Key,
+				kind:   change.Kind,
+			})
+		}
+
+		assert.Equal(t,
+			[]changeKey{
+				{domain: "storage", key: "numbers", kind: StorageChangeKindChanged},
+				{domain: "storage", key: "r", kind: StorageChangeKindAdded},
+				{domain: "public", key: "numbers", kind: StorageChangeKindAdded},
+			},
+			changes,
+		)
+	})
+
+	t.Run("unsupported operations", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, _, _ := newRuntimeInterface()
+		nextTransactionLocation := NewTransactionLocationGenerator()
+		setup(t, runtime, runtimeInterface, nextTransactionLocation)
+
+		var createdAccounts, revokedKeys int
+		runtimeInterface.OnCreateAccount = func(_ Address) (Address, error) {
+			createdAccounts++
+			return common.MustBytesToAddress([]byte{0x2}), nil
+		}
+		runtimeInterface.OnRemoveAccountKey = func(_ Address, _ int) (*AccountKey, error) {
+			revokedKeys++
+			return nil, nil
+		}
+
+		for _, code := range []string{
+			`
+              transaction {
+                  prepare(signer: auth(Storage) &Account) {
+                      Account(payer: signer)
+                  }
+              }
+            `,
+			`
+              transaction {
+                  prepare(signer: auth(RevokeKey) &Account) {
+                      signer.keys.revoke(keyIndex: 0)
+                  }
+              }
+            `,
+		} {
+			_, err := runtime.SimulateTransaction(
+				Script{
+					Source: []byte(code),
+				},
+				Context{
+					Interface: runtimeInterface,
+					Location:  nextTransactionLocation(),
+				},
+			)
+			RequireError(t, err)
+
+			var unsupportedErr *UnsupportedSimulationOperationError
+			require.ErrorAs(t, err, &unsupportedErr)
+		}
+
+		assert.Zero(t, createdAccounts)
+		assert.Zero(t, revokedKeys)
+	})
+
+	t.Run("contract update", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, writes, _ := newRuntimeInterface()
+		nextTransactionLocation := NewTransactionLocationGenerator()
+		setup(t, runtime, runtimeInterface, nextTransactionLocation)
+		*writes = 0
+
+		simulation, err := runtime.SimulateTransaction(
+			Script{
+				Source: DeploymentTransaction("Other", []byte(`
+                  access(all) contract Other {}
+                `)),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  nextTransactionLocation(),
+			},
+		)
+		require.NoError(t, err)
+
+		assert.Zero(t, *writes)
+
+		assert.Equal(t,
+			[]common.AddressLocation{
+				{
+					Address: address,
+					Name:    "Other",
+				},
+			},
+			simulation.UpdatedContracts,
+		)
+
+		code, err := runtimeInterface.GetAccountContractCode(common.AddressLocation{
+			Address: address,
+			Name:    "Other",
+		})
+		require.NoError(t, err)
+		assert.Nil(t, code)
+
+		require.Len(t, simulation.Storage, 1)
+		assert.Empty(t, simulation.Storage[0].Changes)
+		assert.Positive(t, simulation.Storage[0].BytesDelta)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, writes, _ := newRuntimeInterface()
+		nextTransactionLocation := NewTransactionLocationGenerator()
+		setup(t, runtime, runtimeInterface, nextTransactionLocation)
+		*writes = 0
+
+		_, err := runtime.SimulateTransaction(
+			Script{
+				Source: []byte(`
+                  transaction {
+                      prepare(signer: auth(Storage) &Account) {
+                          signer.storage.save(1, to: /storage/numbers)
+                      }
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  nextTransactionLocation(),
+			},
+		)
+		RequireError(t, err)
+
+		assert.Zero(t, *writes)
+	})
+}
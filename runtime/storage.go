@@ -41,6 +41,7 @@ type Storage struct {
 	contractUpdates *orderedmap.OrderedMap[interpreter.StorageKey, *interpreter.CompositeValue]
 	Ledger          atree.Ledger
 	memoryGauge     common.MemoryGauge
+	readOnly        bool
 }
 
 var _ atree.SlabStorage = &Storage{}
@@ -81,6 +82,52 @@ func NewStorage(ledger atree.Ledger, memoryGauge common.MemoryGauge) *Storage {
 	}
 }
 
+// SetReadOnly configures if the storage is read-only.
+//
+// A read-only storage rejects all writes to the storage of accounts
+// with an interpreter.ReadOnlyStorageWriteError:
+// Storing and removing slabs of accounts, creating new slabs in accounts,
+// creating new storage maps, and recording contract updates.
+// Temporary slabs, which are not stored in an account, can still be written.
+func (s *Storage) SetReadOnly(readOnly bool) {
+	s.readOnly = readOnly
+}
+
+// checkWrite returns an interpreter.ReadOnlyStorageWriteError
+// if the storage is read-only and the given address is the address of an account
+func (s *Storage) checkWrite(address atree.Address) error {
+	if !s.readOnly || address == atree.AddressUndefined {
+		return nil
+	}
+	return interpreter.ReadOnlyStorageWriteError{
+		Address: common.Address(address),
+	}
+}
+
+// mustCheckWrite is like checkWrite, but panics with the error.
+// Errors returned to atree would be wrapped, so the error is not reported as a user error
+func (s *Storage) mustCheckWrite(address atree.Address) {
+	err := s.checkWrite(address)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (s *Storage) Store(id atree.StorageID, slab atree.Slab) error {
+	s.mustCheckWrite(id.Address)
+	return s.PersistentSlabStorage.Store(id, slab)
+}
+
+func (s *Storage) Remove(id atree.StorageID) error {
+	s.mustCheckWrite(id.Address)
+	return s.PersistentSlabStorage.Remove(id)
+}
+
+func (s *Storage) GenerateStorageID(address atree.Address) (atree.StorageID, error) {
+	s.mustCheckWrite(address)
+	return s.PersistentSlabStorage.GenerateStorageID(address)
+}
+
 const storageIndexLength = 8
 
 func (s *Storage) GetStorageMap(
@@ -147,6 +194,8 @@ func (s *Storage) loadExistingStorageMap(address atree.Address, storageIndex atr
 }
 
 func (s *Storage) StoreNewStorageMap(address atree.Address, domain string) *interpreter.StorageMap {
+	s.mustCheckWrite(address)
+
 	storageMap := interpreter.NewStorageMap(s.memoryGauge, s, address)
 
 	storageIndex := storageMap.StorageID().Index
@@ -165,6 +214,8 @@ func (s *Storage) recordContractUpdate(
 	location common.AddressLocation,
 	contractValue *interpreter.CompositeValue,
 ) {
+	s.mustCheckWrite(atree.Address(location.Address))
+
 	key := interpreter.NewStorageKey(s.memoryGauge, location.Address, location.Name)
 
 	// NOTE: do NOT delete the map entry,
@@ -5947,3 +5947,273 @@ func TestRuntimeStorageReferenceAccess(t *testing.T) {
 		require.ErrorAs(t, err, &interpreter.DereferenceError{})
 	})
 }
+
+func TestRuntimeReadOnlyStorage(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.MustBytesToAddress([]byte{0x1})
+
+	newRuntimeInterface := func() (*TestRuntimeInterface, *int) {
+		var writes int
+		accountCodes := map[Location][]byte{}
+
+		return &TestRuntimeInterface{
+			Storage: NewTestLedger(nil, func(_, _, _ []byte) {
+				writes++
+			}),
+			OnGetSigningAccounts: func() ([]Address, error) {
+				return []Address{address}, nil
+			},
+			OnResolveLocation: NewSingleIdentifierLocationResolver(t),
+			OnUpdateAccountContractCode: func(location common.AddressLocation, code []byte) error {
+				accountCodes[location] = code
+				return nil
+			},
+			OnGetAccountContractCode: func(location common.AddressLocation) ([]byte, error) {
+				return accountCodes[location], nil
+			},
+			OnEmitEvent: func(_ cadence.Event) error {
+				return nil
+			},
+		}, &writes
+	}
+
+	setup := func(t *testing.T, runtime Runtime, runtimeInterface Interface) {
+		err := runtime.ExecuteTransaction(
+			Script{
+				Source: []byte(`
+                  transaction {
+                      prepare(signer: auth(Storage) &Account) {
+                          signer.storage.save([1, 2, 3], to: /storage/numbers)
+                      }
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  common.TransactionLocation{},
+			},
+		)
+		require.NoError(t, err)
+	}
+
+	requireReadOnlyStorageWriteError := func(t *testing.T, err error, location common.Location, line int) {
+		RequireError(t, err)
+
+		var writeErr interpreter.ReadOnlyStorageWriteError
+		require.ErrorAs(t, err, &writeErr)
+		assert.Equal(t, address, writeErr.Address)
+		assert.Equal(t, location, writeErr.Location)
+		require.NotNil(t, writeErr.HasPosition)
+		assert.Equal(t, line, writeErr.StartPosition().Line)
+	}
+
+	t.Run("read", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, writes := newRuntimeInterface()
+		setup(t, runtime, runtimeInterface)
+		*writes = 0
+
+		result, err := runtime.ExecuteScript(
+			Script{
+				Source: []byte(`
+                  access(all) fun main(): [Int] {
+                      let numbers = getAuthAccount<auth(Storage) &Account>(0x1)
+                          .storage.copy<[Int]>(from: /storage/numbers)!
+                      // temporary values are not written to storage
+                      let more = numbers.concat([4])
+                      return more
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  common.ScriptLocation{},
+				ReadOnly:  true,
+			},
+		)
+		require.NoError(t, err)
+		assert.Equal(t,
+			cadence.NewArray([]cadence.Value{
+				cadence.NewInt(1),
+				cadence.NewInt(2),
+				cadence.NewInt(3),
+				cadence.NewInt(4),
+			}).WithType(cadence.NewVariableSizedArrayType(cadence.IntType)),
+			result,
+		)
+		assert.Zero(t, *writes)
+	})
+
+	t.Run("save", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, writes := newRuntimeInterface()
+		setup(t, runtime, runtimeInterface)
+		*writes = 0
+
+		_, err := runtime.ExecuteScript(
+			Script{
+				Source: []byte(`
+                  access(all) fun main() {
+                      let account = getAuthAccount<auth(Storage) &Account>(0x1)
+                      account.storage.save(42, to: /storage/answer)
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  common.ScriptLocation{},
+				ReadOnly:  true,
+			},
+		)
+		requireReadOnlyStorageWriteError(t, err, common.ScriptLocation{}, 4)
+		assert.Zero(t, *writes)
+	})
+
+	t.Run("mutate reference", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, writes := newRuntimeInterface()
+		setup(t, runtime, runtimeInterface)
+		*writes = 0
+
+		_, err := runtime.ExecuteScript(
+			Script{
+				Source: []byte(`
+                  access(all) fun main() {
+                      let account = getAuthAccount<auth(Storage) &Account>(0x1)
+                      let numbers = account.storage.borrow<auth(Mutate) &[Int]>(from: /storage/numbers)!
+                      numbers.append(4)
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  common.ScriptLocation{},
+				ReadOnly:  true,
+			},
+		)
+		requireReadOnlyStorageWriteError(t, err, common.ScriptLocation{}, 5)
+		assert.Zero(t, *writes)
+	})
+
+	t.Run("add contract", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, writes := newRuntimeInterface()
+		setup(t, runtime, runtimeInterface)
+		*writes = 0
+
+		_, err := runtime.ExecuteScript(
+			Script{
+				Source: []byte(`
+                  access(all) fun main() {
+                      let account = getAuthAccount<auth(Contracts) &Account>(0x1)
+                      account.contracts.add(name: "C", code: "access(all) contract C {}".utf8)
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  common.ScriptLocation{},
+				ReadOnly:  true,
+			},
+		)
+		// The contract value is written when the contract is initialized,
+		// outside any statement of the contract, so the write is reported at the contract declaration
+		requireReadOnlyStorageWriteError(
+			t,
+			err,
+			common.NewAddressLocation(nil, address, "C"),
+			1,
+		)
+		assert.Zero(t, *writes)
+	})
+
+	t.Run("imported contract function", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, writes := newRuntimeInterface()
+		setup(t, runtime, runtimeInterface)
+
+		contract := []byte(`
+          access(all) contract C {
+
+              // Writes the answer
+              access(all) fun save() {
+                  self.account.storage.save(42, to: /storage/answer)
+              }
+          }
+        `)
+
+		err := runtime.ExecuteTransaction(
+			Script{
+				Source: DeploymentTransaction("C", contract),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  common.TransactionLocation{0x1},
+			},
+		)
+		require.NoError(t, err)
+		*writes = 0
+
+		_, err = runtime.ExecuteScript(
+			Script{
+				Source: []byte(`
+                  import C from 0x1
+
+                  access(all) fun main() {
+                      C.save()
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  common.ScriptLocation{},
+				ReadOnly:  true,
+			},
+		)
+		// The write is reported in the imported contract, not at the invocation in the script
+		requireReadOnlyStorageWriteError(
+			t,
+			err,
+			common.NewAddressLocation(nil, address, "C"),
+			6,
+		)
+		assert.Zero(t, *writes)
+	})
+
+	t.Run("not read-only", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := NewTestInterpreterRuntime()
+		runtimeInterface, writes := newRuntimeInterface()
+		setup(t, runtime, runtimeInterface)
+		*writes = 0
+
+		_, err := runtime.ExecuteScript(
+			Script{
+				Source: []byte(`
+                  access(all) fun main() {
+                      let account = getAuthAccount<auth(Storage) &Account>(0x1)
+                      account.storage.save(42, to: /storage/answer)
+                  }
+                `),
+			},
+			Context{
+				Interface: runtimeInterface,
+				Location:  common.ScriptLocation{},
+			},
+		)
+		require.NoError(t, err)
+	})
+}
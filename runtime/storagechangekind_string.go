@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=StorageChangeKind -trimprefix=StorageChangeKind"; DO NOT EDIT.
+
+package runtime
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[StorageChangeKindUnknown-0]
+	_ = x[StorageChangeKindAdded-1]
+	_ = x[StorageChangeKindRemoved-2]
+	_ = x[StorageChangeKindChanged-3]
+}
+
+const _StorageChangeKind_name = "UnknownAddedRemovedChanged"
+
+var _StorageChangeKind_index = [...]uint8{0, 7, 12, 19, 26}
+
+func (i StorageChangeKind) String() string {
+	if i >= StorageChangeKind(len(_StorageChangeKind_index)-1) {
+		return "StorageChangeKind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _StorageChangeKind_name[_StorageChangeKind_index[i]:_StorageChangeKind_index[i+1]]
+}
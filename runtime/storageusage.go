@@ -0,0 +1,254 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/errors"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/stdlib"
+)
+
+// StorageDomains are the domains of the storage maps of an account
+var StorageDomains = func() []string {
+	domains := make([]string, 0, len(common.AllPathDomains)+6)
+	for _, domain := range common.AllPathDomains {
+		domains = append(domains, domain.Identifier())
+	}
+	return append(
+		domains,
+		stdlib.InboxStorageDomain,
+		StorageDomainContract,
+		stdlib.CapabilityControllerStorageDomain,
+		stdlib.PathCapabilityStorageDomain,
+		stdlib.AccountCapabilityStorageDomain,
+		stdlib.CapabilityControllerTagStorageDomain,
+	)
+}()
+
+// AccountStorageUsage is the storage used by the storage maps of an account.
+type AccountStorageUsage struct {
+	Address     common.Address
+	StorageMaps []StorageMapUsage
+}
+
+// Bytes returns the total size of all slabs of the account's storage maps and their values.
+func (u AccountStorageUsage) Bytes() uint64 {
+	var bytes uint64
+	for _, storageMap := range u.StorageMaps {
+		bytes += storageMap.TotalBytes()
+	}
+	return bytes
+}
+
+// StorageMapUsage is the storage used by a storage map of an account.
+type StorageMapUsage struct {
+	Domain string
+	// Slabs and Bytes are the number and size of the slabs of the storage map itself,
+	// which contain the keys, and the values which are stored inline
+	Slabs int
+	Bytes uint64
+	// Paths are the storage used by the values stored in the storage map
+	Paths []PathStorageUsage
+}
+
+// TotalBytes returns the total size of the slabs of the storage map and its values.
+func (u StorageMapUsage) TotalBytes() uint64 {
+	bytes := u.Bytes
+	for _, path := range u.Paths {
+		bytes += path.Bytes
+	}
+	return bytes
+}
+
+// PathStorageUsage is the storage used by a value stored in a storage map.
+type PathStorageUsage struct {
+	Key        interpreter.StorageMapKey
+	StaticType interpreter.StaticType
+	// InlineBytes is the size of the value in the slabs of the storage map.
+	// If the value is stored in separate slabs, this is the size of the reference to them
+	InlineBytes uint32
+	// Slabs and Bytes are the number and size of the slabs reachable from the value,
+	// i.e. excluding the slabs of the storage map
+	Slabs int
+	Bytes uint64
+}
+
+// StorageUsage returns the storage used by the storage maps of the given account,
+// and by the values stored in them.
+//
+// Only storage maps of the domains in StorageDomains are considered.
+// Only the root of each stored value is decoded, to determine its static type.
+// Nested values are not loaded, only the slabs reachable from the value are retrieved.
+func (s *Storage) StorageUsage(
+	inter *interpreter.Interpreter,
+	address common.Address,
+) (
+	usage AccountStorageUsage,
+	err error,
+) {
+	// User errors and external errors (e.g. errors of the ledger) are returned,
+	// all other panics are internal errors and are propagated
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		switch r := r.(type) {
+		case errors.UserError:
+			err = r
+		case errors.ExternalError:
+			err = r
+		default:
+			panic(r)
+		}
+	}()
+
+	usage.Address = address
+
+	for _, domain := range StorageDomains {
+		storageMap := s.GetStorageMap(address, domain, false)
+		if storageMap == nil {
+			continue
+		}
+
+		storageMapUsage, err := s.storageMapUsage(inter, domain, storageMap)
+		if err != nil {
+			return AccountStorageUsage{}, err
+		}
+
+		usage.StorageMaps = append(usage.StorageMaps, storageMapUsage)
+	}
+
+	return usage, nil
+}
+
+func (s *Storage) storageMapUsage(
+	inter *interpreter.Interpreter,
+	domain string,
+	storageMap *interpreter.StorageMap,
+) (
+	StorageMapUsage,
+	error,
+) {
+	usage := StorageMapUsage{
+		Domain: domain,
+	}
+
+	// Determine the size of all slabs reachable from the storage map,
+	// and subtract the size of the slabs reachable from the values
+
+	totalSlabs, totalBytes, err := s.reachableSlabs(
+		atree.StorageIDStorable(storageMap.StorageID()),
+	)
+	if err != nil {
+		return StorageMapUsage{}, err
+	}
+
+	var valueSlabs int
+	var valueBytes uint64
+
+	iterator := storageMap.Iterator(nil)
+
+	for {
+		key := iterator.NextKey()
+		if key == nil {
+			break
+		}
+
+		var storageMapKey interpreter.StorageMapKey
+		switch key := key.(type) {
+		case interpreter.StringAtreeValue:
+			storageMapKey = interpreter.StringStorageMapKey(key)
+		case interpreter.Uint64AtreeValue:
+			storageMapKey = interpreter.Uint64StorageMapKey(key)
+		default:
+			return StorageMapUsage{}, errors.NewUnexpectedError(
+				"unsupported storage map key in domain %s: %T",
+				domain,
+				key,
+			)
+		}
+
+		storable := storageMap.ReadStorable(storageMapKey)
+
+		slabs, bytes, err := s.reachableSlabs(storable)
+		if err != nil {
+			return StorageMapUsage{}, err
+		}
+
+		value := interpreter.StoredValue(nil, storable, s)
+
+		usage.Paths = append(
+			usage.Paths,
+			PathStorageUsage{
+				Key:         storageMapKey,
+				StaticType:  value.StaticType(inter),
+				InlineBytes: storable.ByteSize(),
+				Slabs:       slabs,
+				Bytes:       bytes,
+			},
+		)
+
+		valueSlabs += slabs
+		valueBytes += bytes
+	}
+
+	usage.Slabs = totalSlabs - valueSlabs
+	usage.Bytes = totalBytes - valueBytes
+
+	return usage, nil
+}
+
+// reachableSlabs returns the number and size of the slabs reachable from the given storable
+func (s *Storage) reachableSlabs(storable atree.Storable) (slabs int, bytes uint64, err error) {
+
+	storables := []atree.Storable{storable}
+
+	for len(storables) > 0 {
+		storable := storables[len(storables)-1]
+		storables = storables[:len(storables)-1]
+
+		storageIDStorable, ok := storable.(atree.StorageIDStorable)
+		if !ok {
+			storables = append(storables, storable.ChildStorables()...)
+			continue
+		}
+
+		storageID := atree.StorageID(storageIDStorable)
+
+		slab, found, err := s.Retrieve(storageID)
+		if err != nil {
+			return 0, 0, interpreter.WrappedExternalError(err)
+		}
+		if !found {
+			return 0, 0, errors.NewUnexpectedError("missing slab %s", storageID)
+		}
+
+		slabs++
+		bytes += uint64(slab.ByteSize())
+
+		storables = append(storables, slab.ChildStorables()...)
+	}
+
+	return slabs, bytes, nil
+}
@@ -0,0 +1,232 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence"
+	. "github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	cadenceErrors "github.com/onflow/cadence/runtime/errors"
+	"github.com/onflow/cadence/runtime/interpreter"
+	. "github.com/onflow/cadence/runtime/tests/runtime_utils"
+)
+
+func TestRuntimeStorageUsage(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.MustBytesToAddress([]byte{0x1})
+
+	ledger := NewTestLedger(nil, nil)
+
+	runtime := NewTestInterpreterRuntime()
+
+	runtimeInterface := &TestRuntimeInterface{
+		Storage: ledger,
+		OnGetSigningAccounts: func() ([]Address, error) {
+			return []Address{address}, nil
+		},
+		OnEmitEvent: func(_ cadence.Event) error {
+			return nil
+		},
+	}
+
+	err := runtime.ExecuteTransaction(
+		Script{
+			Source: []byte(`
+              transaction {
+                  prepare(signer: auth(Storage, Capabilities) &Account) {
+                      signer.storage.save(42, to: /storage/number)
+
+                      let strings: [String] = []
+                      var i = 0
+                      while i < 1000 {
+                          strings.append("string ".concat(i.toString()))
+                          i = i + 1
+                      }
+                      signer.storage.save(strings, to: /storage/strings)
+
+                      signer.storage.save({"a": [1, 2, 3]}, to: /storage/nested)
+
+                      let cap = signer.capabilities.storage.issue<&[String]>(/storage/strings)
+                      signer.capabilities.publish(cap, at: /public/strings)
+                  }
+              }
+            `),
+		},
+		Context{
+			Interface: runtimeInterface,
+			Location:  common.TransactionLocation{},
+		},
+	)
+	require.NoError(t, err)
+
+	storage := NewStorage(ledger, nil)
+
+	inter, err := interpreter.NewInterpreter(
+		nil,
+		nil,
+		&interpreter.Config{
+			Storage: storage,
+		},
+	)
+	require.NoError(t, err)
+
+	usage, err := storage.StorageUsage(inter, address)
+	require.NoError(t, err)
+
+	assert.Equal(t, address, usage.Address)
+
+	storageMaps := map[string]StorageMapUsage{}
+	for _, storageMap := range usage.StorageMaps {
+		storageMaps[storageMap.Domain] = storageMap
+	}
+
+	require.Contains(t, storageMaps, common.PathDomainStorage.Identifier())
+	require.Contains(t, storageMaps, common.PathDomainPublic.Identifier())
+
+	storageDomain := storageMaps[common.PathDomainStorage.Identifier()]
+	assert.Equal(t, 1, storageDomain.Slabs)
+	assert.NotZero(t, storageDomain.Bytes)
+
+	paths := map[interpreter.StorageMapKey]PathStorageUsage{}
+	for _, path := range storageDomain.Paths {
+		paths[path.Key] = path
+	}
+	require.Len(t, paths, 3)
+
+	// The number is stored inline
+
+	number := paths[interpreter.StringStorageMapKey("number")]
+	assert.Equal(t, interpreter.PrimitiveStaticTypeInt, number.StaticType)
+	assert.NotZero(t, number.InlineBytes)
+	assert.Zero(t, number.Slabs)
+	assert.Zero(t, number.Bytes)
+
+	// The large array is stored in multiple slabs
+
+	strings := paths[interpreter.StringStorageMapKey("strings")]
+	assert.Equal(t,
+		interpreter.NewVariableSizedStaticType(nil, interpreter.PrimitiveStaticTypeString),
+		strings.StaticType,
+	)
+	assert.Greater(t, strings.Slabs, 1)
+	assert.Greater(t, strings.Bytes, uint64(1000*len("string 000")))
+
+	// The slabs of nested values are attributed to the stored value
+
+	nested := paths[interpreter.StringStorageMapKey("nested")]
+	assert.Equal(t,
+		interpreter.NewDictionaryStaticType(
+			nil,
+			interpreter.PrimitiveStaticTypeString,
+			interpreter.NewVariableSizedStaticType(nil, interpreter.PrimitiveStaticTypeInt),
+		),
+		nested.StaticType,
+	)
+	assert.Equal(t, 2, nested.Slabs)
+
+	// The total is the sum of all storage maps and values
+
+	var total uint64
+	for _, storageMap := range usage.StorageMaps {
+		total += storageMap.Bytes
+		for _, path := range storageMap.Paths {
+			total += path.Bytes
+		}
+	}
+	assert.Equal(t, total, usage.Bytes())
+
+	// Accounts without storage have no storage maps
+
+	emptyUsage, err := storage.StorageUsage(inter, common.MustBytesToAddress([]byte{0x2}))
+	require.NoError(t, err)
+	assert.Empty(t, emptyUsage.StorageMaps)
+	assert.Zero(t, emptyUsage.Bytes())
+}
+
+func TestRuntimeStorageUsageErrors(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.MustBytesToAddress([]byte{0x1})
+
+	newInterpreter := func(t *testing.T, storage *Storage) *interpreter.Interpreter {
+		inter, err := interpreter.NewInterpreter(
+			nil,
+			nil,
+			&interpreter.Config{
+				Storage: storage,
+			},
+		)
+		require.NoError(t, err)
+		return inter
+	}
+
+	t.Run("external error", func(t *testing.T) {
+
+		t.Parallel()
+
+		ledgerErr := errors.New("ledger failure")
+
+		ledger := TestLedger{
+			OnGetValue: func(_, _ []byte) ([]byte, error) {
+				return nil, ledgerErr
+			},
+		}
+
+		storage := NewStorage(ledger, nil)
+
+		_, err := storage.StorageUsage(newInterpreter(t, storage), address)
+		require.Error(t, err)
+
+		var externalErr cadenceErrors.ExternalError
+		require.ErrorAs(t, err, &externalErr)
+		assert.ErrorIs(t, err, ledgerErr)
+	})
+
+	t.Run("internal error", func(t *testing.T) {
+
+		t.Parallel()
+
+		// An invalid storage index for a storage map is an internal error,
+		// which must not be converted to an error
+
+		ledger := TestLedger{
+			OnGetValue: func(_, _ []byte) ([]byte, error) {
+				return []byte{0x1}, nil
+			},
+		}
+
+		storage := NewStorage(ledger, nil)
+
+		defer func() {
+			r := recover()
+			require.IsType(t, cadenceErrors.UnexpectedError{}, r)
+		}()
+
+		_, _ = storage.StorageUsage(newInterpreter(t, storage), address)
+	})
+}
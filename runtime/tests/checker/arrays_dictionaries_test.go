@@ -320,6 +320,153 @@ func TestCheckDictionaryValues(t *testing.T) {
 	)
 }
 
+func TestCheckDictionaryFilter(t *testing.T) {
+
+	t.Parallel()
+
+	checker, err := ParseAndCheck(t, `
+        let filtered = {"abc": 1, "def": 2}.filter(view fun (_ key: String, _ value: Int): Bool {
+            return value > 1
+        })
+    `)
+
+	require.NoError(t, err)
+
+	filteredType := RequireGlobalValue(t, checker.Elaboration, "filtered")
+
+	assert.Equal(t,
+		&sema.DictionaryType{
+			KeyType:   sema.StringType,
+			ValueType: sema.IntType,
+		},
+		filteredType,
+	)
+}
+
+func TestCheckDictionaryFilterInvalidArgs(t *testing.T) {
+
+	t.Parallel()
+
+	// The filter function must be a view function
+	_, err := ParseAndCheck(t, `
+        let filtered = {"abc": 1, "def": 2}.filter(fun (_ key: String, _ value: Int): Bool {
+            return value > 1
+        })
+    `)
+
+	errs := RequireCheckerErrors(t, err, 1)
+
+	assert.IsType(t, &sema.TypeMismatchError{}, errs[0])
+}
+
+func TestCheckDictionaryMapValues(t *testing.T) {
+
+	t.Parallel()
+
+	checker, err := ParseAndCheck(t, `
+        let mapped = {"abc": 1, "def": 2}.mapValues(fun (_ value: Int): String {
+            return value.toString()
+        })
+    `)
+
+	require.NoError(t, err)
+
+	mappedType := RequireGlobalValue(t, checker.Elaboration, "mapped")
+
+	assert.Equal(t,
+		&sema.DictionaryType{
+			KeyType:   sema.StringType,
+			ValueType: sema.StringType,
+		},
+		mappedType,
+	)
+}
+
+func TestCheckDictionaryMapValuesInvalidArgs(t *testing.T) {
+
+	t.Parallel()
+
+	_, err := ParseAndCheck(t, `
+        let mapped = {"abc": 1, "def": 2}.mapValues(fun (_ value: String): String {
+            return value
+        })
+    `)
+
+	errs := RequireCheckerErrors(t, err, 1)
+
+	assert.IsType(t, &sema.TypeMismatchError{}, errs[0])
+}
+
+func TestCheckDictionaryForEach(t *testing.T) {
+
+	t.Parallel()
+
+	_, err := ParseAndCheck(t, `
+        fun test(): Int {
+            var sum = 0
+            {"abc": 1, "def": 2}.forEach(fun (_ key: String, _ value: Int): Bool {
+                sum = sum + value
+                return true
+            })
+            return sum
+        }
+    `)
+
+	require.NoError(t, err)
+}
+
+func TestCheckDictionaryMerge(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := ParseAndCheck(t, `
+            fun test() {
+                let dict = {"abc": 1}
+                dict.merge({"def": 2})
+            }
+        `)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid argument", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := ParseAndCheck(t, `
+            fun test() {
+                let dict = {"abc": 1}
+                dict.merge({"def": "2"})
+            }
+        `)
+
+		errs := RequireCheckerErrors(t, err, 1)
+
+		assert.IsType(t, &sema.TypeMismatchError{}, errs[0])
+	})
+
+	t.Run("unauthorized reference", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := ParseAndCheck(t, `
+            fun test() {
+                let dict = {"abc": 1}
+                let ref = &dict as &{String: Int}
+                ref.merge({"def": 2})
+            }
+        `)
+
+		errs := RequireCheckerErrors(t, err, 1)
+
+		assert.IsType(t, &sema.InvalidAccessError{}, errs[0])
+	})
+}
+
 func TestCheckDictionaryEqual(t *testing.T) {
 	t.Parallel()
 
@@ -1327,6 +1474,288 @@ func TestCheckResourceArrayMapInvalid(t *testing.T) {
 	assert.IsType(t, &sema.InvalidResourceArrayMemberError{}, errs[0])
 }
 
+func TestCheckArraySort(t *testing.T) {
+
+	t.Parallel()
+
+	_, err := ParseAndCheck(t, `
+		fun test() {
+			let x = [3, 1, 2]
+			let isLess =
+				view fun (_ a: Int, _ b: Int): Bool {
+					return a < b
+				}
+
+			let y: [Int] = x.sort(by: isLess)
+		}
+
+		fun testFixedSize() {
+			let x: [Int; 3] = [3, 1, 2]
+			let y: [Int; 3] = x.sort(by: view fun (_ a: Int, _ b: Int): Bool {
+				return a > b
+			})
+		}
+	`)
+
+	require.NoError(t, err)
+}
+
+func TestCheckArraySortInvalidArgs(t *testing.T) {
+
+	t.Parallel()
+
+	testInvalidArgs := func(code string, expectedErrors []sema.SemanticError) {
+		_, err := ParseAndCheck(t, code)
+
+		errs := RequireCheckerErrors(t, err, len(expectedErrors))
+
+		for i, e := range expectedErrors {
+			assert.IsType(t, e, errs[i])
+		}
+	}
+
+	testInvalidArgs(`
+		fun test() {
+			let x = [1, 2, 3]
+			let y = x.sort(by: view fun (_ a: Int): Bool {
+				return a > 0
+			})
+		}
+	`,
+		[]sema.SemanticError{
+			&sema.TypeMismatchError{},
+		},
+	)
+
+	// The comparison function must be a view function
+	testInvalidArgs(`
+		fun test() {
+			let x = [1, 2, 3]
+			let isLess =
+				fun (_ a: Int, _ b: Int): Bool {
+					return a < b
+				}
+			let y = x.sort(by: isLess)
+		}
+	`,
+		[]sema.SemanticError{
+			&sema.TypeMismatchError{},
+		},
+	)
+}
+
+func TestCheckArrayReduce(t *testing.T) {
+
+	t.Parallel()
+
+	_, err := ParseAndCheck(t, `
+		fun test() {
+			let x = [1, 2, 3]
+
+			let sum: Int = x.reduce(initial: 0, fun (_ sum: Int, _ element: Int): Int {
+				return sum + element
+			})
+
+			let joined: String = x.reduce(initial: "", fun (_ s: String, _ element: Int): String {
+				return s.concat(element.toString())
+			})
+		}
+
+		fun testFixedSize() {
+			let x: [Int; 3] = [1, 2, 3]
+			let max: Int = x.reduce(initial: 0, fun (_ max: Int, _ element: Int): Int {
+				return element > max ? element : max
+			})
+		}
+	`)
+
+	require.NoError(t, err)
+}
+
+func TestCheckArrayReduceInvalidArgs(t *testing.T) {
+
+	t.Parallel()
+
+	_, err := ParseAndCheck(t, `
+		fun test() {
+			let x = [1, 2, 3]
+			let y = x.reduce(initial: "", fun (_ sum: Int, _ element: Int): Int {
+				return sum + element
+			})
+		}
+	`)
+
+	errs := RequireCheckerErrors(t, err, 1)
+
+	assert.IsType(t, &sema.TypeMismatchError{}, errs[0])
+}
+
+func TestCheckArrayAnyAll(t *testing.T) {
+
+	t.Parallel()
+
+	for _, name := range []string{"any", "all"} {
+
+		name := name
+
+		t.Run(name, func(t *testing.T) {
+
+			t.Parallel()
+
+			_, err := ParseAndCheck(t,
+				fmt.Sprintf(
+					`
+                      fun test() {
+                          let x = [1, 2, 3]
+                          let isEven =
+                              view fun (_ x: Int): Bool {
+                                  return x %% 2 == 0
+                              }
+
+                          let y: Bool = x.%[1]s(isEven)
+                      }
+
+                      fun testFixedSize() {
+                          let x: [Int; 3] = [1, 2, 3]
+                          let y: Bool = x.%[1]s(view fun (_ x: Int): Bool {
+                              return x > 0
+                          })
+                      }
+                    `,
+					name,
+				),
+			)
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckArrayLastIndex(t *testing.T) {
+
+	t.Parallel()
+
+	_, err := ParseAndCheck(t, `
+		fun test(): Int? {
+			let x = [1, 2, 3, 2]
+			return x.lastIndex(of: 2)
+		}
+	`)
+
+	require.NoError(t, err)
+}
+
+func TestCheckArrayLastIndexNotEquatable(t *testing.T) {
+
+	t.Parallel()
+
+	_, err := ParseAndCheck(t, `
+		struct S {}
+
+		fun test(): Int? {
+			let x = [S()]
+			return x.lastIndex(of: S())
+		}
+	`)
+
+	errs := RequireCheckerErrors(t, err, 1)
+
+	assert.IsType(t, &sema.NotEquatableTypeError{}, errs[0])
+}
+
+func TestCheckResourceArraySortReduceSearchInvalid(t *testing.T) {
+
+	t.Parallel()
+
+	test := func(name string, code string, expectedErrors []sema.SemanticError) {
+		t.Run(name, func(t *testing.T) {
+
+			t.Parallel()
+
+			_, err := ParseAndCheck(t,
+				fmt.Sprintf(
+					`
+                      resource X {}
+
+                      fun test() {
+                          let xs <- [<-create X()]
+                          %s
+                          destroy xs
+                      }
+                    `,
+					code,
+				),
+			)
+
+			errs := RequireCheckerErrors(t, err, len(expectedErrors))
+
+			for i, e := range expectedErrors {
+				assert.IsType(t, e, errs[i])
+			}
+		})
+	}
+
+	test(
+		"sort",
+		`
+          let ys <- xs.sort(by: view fun (_ a: @X, _ b: @X): Bool {
+              destroy a
+              destroy b
+              return true
+          })
+          destroy ys
+        `,
+		[]sema.SemanticError{
+			&sema.InvalidResourceArrayMemberError{},
+			&sema.PurityError{},
+			&sema.PurityError{},
+		},
+	)
+
+	test(
+		"reduce",
+		`
+          let y = xs.reduce(initial: 0, fun (_ count: Int, _ x: @X): Int {
+              destroy x
+              return count + 1
+          })
+        `,
+		[]sema.SemanticError{
+			&sema.InvalidResourceArrayMemberError{},
+		},
+	)
+
+	for _, name := range []string{"any", "all"} {
+		test(
+			name,
+			fmt.Sprintf(
+				`
+                  let y = xs.%s(view fun (_ x: @X): Bool {
+                      destroy x
+                      return true
+                  })
+                `,
+				name,
+			),
+			[]sema.SemanticError{
+				&sema.InvalidResourceArrayMemberError{},
+				&sema.PurityError{},
+			},
+		)
+	}
+
+	test(
+		"lastIndex",
+		`
+          let y = xs.lastIndex(of: <-create X())
+        `,
+		[]sema.SemanticError{
+			&sema.InvalidResourceArrayMemberError{},
+			&sema.NotEquatableTypeError{},
+		},
+	)
+}
+
 func TestCheckArrayContains(t *testing.T) {
 
 	t.Parallel()
@@ -3911,6 +3911,90 @@ func TestCheckInvalidResourceDictionaryKeysForeach(t *testing.T) {
 	assert.IsType(t, &sema.InvalidResourceDictionaryMemberError{}, errs[1])
 }
 
+func TestCheckInvalidResourceDictionaryHigherOrderFunctions(t *testing.T) {
+
+	t.Parallel()
+
+	test := func(name string, code string, expectedErrors []sema.SemanticError) {
+		t.Run(name, func(t *testing.T) {
+
+			t.Parallel()
+
+			_, err := ParseAndCheck(t,
+				fmt.Sprintf(
+					`
+                      resource X {}
+
+                      fun test() {
+                          let xs <- {"x1": <-create X()}
+                          %s
+                          destroy xs
+                      }
+                    `,
+					code,
+				),
+			)
+
+			errs := RequireCheckerErrors(t, err, len(expectedErrors))
+
+			for i, e := range expectedErrors {
+				assert.IsType(t, e, errs[i])
+			}
+		})
+	}
+
+	test(
+		"filter",
+		`
+          let ys <- xs.filter(view fun (_ key: String, _ x: @X): Bool {
+              destroy x
+              return true
+          })
+          destroy ys
+        `,
+		[]sema.SemanticError{
+			&sema.InvalidResourceDictionaryMemberError{},
+			&sema.PurityError{},
+		},
+	)
+
+	test(
+		"mapValues",
+		`
+          let ys = xs.mapValues(fun (_ x: @X): Bool {
+              destroy x
+              return true
+          })
+        `,
+		[]sema.SemanticError{
+			&sema.InvalidResourceDictionaryMemberError{},
+		},
+	)
+
+	test(
+		"forEach",
+		`
+          xs.forEach(fun (_ key: String, _ x: @X): Bool {
+              destroy x
+              return true
+          })
+        `,
+		[]sema.SemanticError{
+			&sema.InvalidResourceDictionaryMemberError{},
+		},
+	)
+
+	test(
+		"merge",
+		`
+          xs.merge(<-{"x2": <-create X()})
+        `,
+		[]sema.SemanticError{
+			&sema.InvalidResourceDictionaryMemberError{},
+		},
+	)
+}
+
 func TestCheckInvalidResourceLossAfterMoveThroughDictionaryIndexing(t *testing.T) {
 
 	t.Parallel()
@@ -35,6 +35,7 @@ import (
 
 	"github.com/onflow/cadence/runtime/ast"
 	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/errors"
 	"github.com/onflow/cadence/runtime/interpreter"
 	"github.com/onflow/cadence/runtime/parser"
 	"github.com/onflow/cadence/runtime/pretty"
@@ -4364,21 +4365,19 @@ func TestInterpretImportError(t *testing.T) {
 		)
 	require.NoError(t, printErr)
 	assert.Equal(t,
-		" --> test:5:17\n"+
-			"  |\n"+
-			"5 |           return answer()\n"+
-			"  |                  ^^^^^^^^\n"+
-			"\n"+
-			" --> imported2:5:17\n"+
-			"  |\n"+
-			"5 |           return realAnswer()\n"+
-			"  |                  ^^^^^^^^^^^^\n"+
-			"\n"+
-			"error: panic: ?!\n"+
+		"error: panic: ?!\n"+
 			" --> imported1:3:17\n"+
 			"  |\n"+
 			"3 |           return panic(\"?!\")\n"+
-			"  |                  ^^^^^^^^^^^\n",
+			"  |                  ^^^^^^^^^^^\n"+
+			"\n"+
+			"stack trace:\n"+
+			"realAnswer(...)\n"+
+			"\timported1:3:17\n"+
+			"answer(...)\n"+
+			"\timported2:5:17\n"+
+			"<unknown>\n"+
+			"\ttest:5:17\n",
 		sb.String(),
 	)
 	RequireError(t, err)
@@ -6428,6 +6427,203 @@ func TestInterpretDictionaryValues(t *testing.T) {
 	)
 }
 
+func TestInterpretDictionaryFilter(t *testing.T) {
+
+	t.Parallel()
+
+	inter := parseCheckAndInterpret(t, `
+      let dict = {"a": 1, "b": 2, "c": 3, "d": 4}
+
+      fun test(): Bool {
+          let filtered: {String: Int} = dict.filter(view fun (_ key: String, _ value: Int): Bool {
+              return value % 2 == 0 || key == "a"
+          })
+          return filtered == {"a": 1, "b": 2, "d": 4}
+      }
+
+      fun testNone(): Int {
+          return dict.filter(view fun (_ key: String, _ value: Int): Bool {
+              return false
+          }).length
+      }
+
+      fun testOriginal(): Bool {
+          dict.filter(view fun (_ key: String, _ value: Int): Bool {
+              return false
+          })
+          // Original dictionary remains unchanged
+          return dict == {"a": 1, "b": 2, "c": 3, "d": 4}
+      }
+    `)
+
+	value, err := inter.Invoke("test")
+	require.NoError(t, err)
+	AssertValuesEqual(t, inter, interpreter.TrueValue, value)
+
+	value, err = inter.Invoke("testNone")
+	require.NoError(t, err)
+	AssertValuesEqual(t, inter, interpreter.NewUnmeteredIntValueFromInt64(0), value)
+
+	value, err = inter.Invoke("testOriginal")
+	require.NoError(t, err)
+	AssertValuesEqual(t, inter, interpreter.TrueValue, value)
+}
+
+func TestInterpretDictionaryMapValues(t *testing.T) {
+
+	t.Parallel()
+
+	inter := parseCheckAndInterpret(t, `
+      fun test(): {String: String} {
+          let dict = {"a": 1, "b": 2}
+          return dict.mapValues(fun (_ value: Int): String {
+              return value.toString()
+          })
+      }
+    `)
+
+	value, err := inter.Invoke("test")
+	require.NoError(t, err)
+
+	require.IsType(t, &interpreter.DictionaryValue{}, value)
+	dictionary := value.(*interpreter.DictionaryValue)
+
+	require.Equal(t,
+		interpreter.NewDictionaryStaticType(
+			nil,
+			interpreter.PrimitiveStaticTypeString,
+			interpreter.PrimitiveStaticTypeString,
+		),
+		dictionary.Type,
+	)
+
+	for key, expected := range map[string]string{"a": "1", "b": "2"} {
+		actual, ok := dictionary.Get(
+			inter,
+			interpreter.EmptyLocationRange,
+			interpreter.NewUnmeteredStringValue(key),
+		)
+		require.True(t, ok)
+
+		AssertValuesEqual(t, inter, interpreter.NewUnmeteredStringValue(expected), actual)
+	}
+}
+
+func TestInterpretDictionaryForEach(t *testing.T) {
+
+	t.Parallel()
+
+	inter := parseCheckAndInterpret(t, `
+      fun test(): Int {
+          let dict = {1: 10, 2: 20, 3: 30}
+          var sum = 0
+          dict.forEach(fun (_ key: Int, _ value: Int): Bool {
+              sum = sum + key + value
+              return true
+          })
+          return sum
+      }
+
+      fun testExitEarly(): Int {
+          let dict = {1: 10, 2: 20, 3: 30}
+          var count = 0
+          dict.forEach(fun (_ key: Int, _ value: Int): Bool {
+              count = count + 1
+              return false
+          })
+          return count
+      }
+
+      fun testMutation() {
+          let dict = {1: 10, 2: 20, 3: 30}
+          dict.forEach(fun (_ key: Int, _ value: Int): Bool {
+              dict[key] = value + 1
+              return true
+          })
+      }
+    `)
+
+	value, err := inter.Invoke("test")
+	require.NoError(t, err)
+	AssertValuesEqual(t, inter, interpreter.NewUnmeteredIntValueFromInt64(66), value)
+
+	value, err = inter.Invoke("testExitEarly")
+	require.NoError(t, err)
+	AssertValuesEqual(t, inter, interpreter.NewUnmeteredIntValueFromInt64(1), value)
+
+	_, err = inter.Invoke("testMutation")
+	RequireError(t, err)
+	require.ErrorAs(t, err, &interpreter.ContainerMutatedDuringIterationError{})
+}
+
+func TestInterpretDictionaryMerge(t *testing.T) {
+
+	t.Parallel()
+
+	inter := parseCheckAndInterpret(t, `
+      fun test(): Bool {
+          let dict = {"a": 1, "b": 2}
+          dict.merge({"b": 3, "c": 4})
+          return dict == {"a": 1, "b": 3, "c": 4}
+      }
+
+      fun testSelf(): Bool {
+          let dict = {"a": 1, "b": 2}
+          dict.merge(dict)
+          return dict == {"a": 1, "b": 2}
+      }
+    `)
+
+	value, err := inter.Invoke("test")
+	require.NoError(t, err)
+	AssertValuesEqual(t, inter, interpreter.TrueValue, value)
+
+	value, err = inter.Invoke("testSelf")
+	require.NoError(t, err)
+	AssertValuesEqual(t, inter, interpreter.TrueValue, value)
+
+	t.Run("resource-typed dictionaries", func(t *testing.T) {
+
+		t.Parallel()
+
+		// The checker rejects merging resource-typed dictionaries,
+		// so merge them directly, without checking
+
+		inter := parseCheckAndInterpret(t, `
+          resource R {}
+
+          fun test(): @{String: R} {
+              return <-{"a": <-create R()}
+          }
+        `)
+
+		first, err := inter.Invoke("test")
+		require.NoError(t, err)
+		require.IsType(t, &interpreter.DictionaryValue{}, first)
+
+		second, err := inter.Invoke("test")
+		require.NoError(t, err)
+		require.IsType(t, &interpreter.DictionaryValue{}, second)
+
+		// The resource-typed dictionary is rejected before any change
+
+		func() {
+			defer func() {
+				r := recover()
+				require.IsType(t, errors.UnexpectedError{}, r)
+			}()
+
+			first.(*interpreter.DictionaryValue).Merge(
+				inter,
+				interpreter.EmptyLocationRange,
+				second.(*interpreter.DictionaryValue),
+			)
+		}()
+
+		assert.Equal(t, 1, first.(*interpreter.DictionaryValue).Count())
+	})
+}
+
 func TestInterpretDictionaryKeyTypes(t *testing.T) {
 
 	t.Parallel()
@@ -11518,6 +11714,310 @@ func TestInterpretArrayToConstantSized(t *testing.T) {
 	})
 }
 
+func TestInterpretArraySort(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("variable sized", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := parseCheckAndInterpret(t, `
+          let xs = [3, 1, 4, 1, 5, 9, 2, 6]
+
+          fun sorted(): [Int] {
+              return xs.sort(by: view fun (_ a: Int, _ b: Int): Bool {
+                  return a < b
+              })
+          }
+
+          fun original(): [Int] {
+              return xs
+          }
+        `)
+
+		value, err := inter.Invoke("sorted")
+		require.NoError(t, err)
+
+		require.IsType(t, &interpreter.ArrayValue{}, value)
+		sortedArray := value.(*interpreter.ArrayValue)
+
+		require.Equal(t,
+			interpreter.NewVariableSizedStaticType(nil, interpreter.PrimitiveStaticTypeInt),
+			sortedArray.Type,
+		)
+
+		AssertValueSlicesEqual(
+			t,
+			inter,
+			[]interpreter.Value{
+				interpreter.NewUnmeteredIntValueFromInt64(1),
+				interpreter.NewUnmeteredIntValueFromInt64(1),
+				interpreter.NewUnmeteredIntValueFromInt64(2),
+				interpreter.NewUnmeteredIntValueFromInt64(3),
+				interpreter.NewUnmeteredIntValueFromInt64(4),
+				interpreter.NewUnmeteredIntValueFromInt64(5),
+				interpreter.NewUnmeteredIntValueFromInt64(6),
+				interpreter.NewUnmeteredIntValueFromInt64(9),
+			},
+			ArrayElements(inter, sortedArray),
+		)
+
+		// Original array remains unchanged
+
+		value, err = inter.Invoke("original")
+		require.NoError(t, err)
+
+		require.IsType(t, &interpreter.ArrayValue{}, value)
+
+		AssertValueSlicesEqual(
+			t,
+			inter,
+			[]interpreter.Value{
+				interpreter.NewUnmeteredIntValueFromInt64(3),
+				interpreter.NewUnmeteredIntValueFromInt64(1),
+				interpreter.NewUnmeteredIntValueFromInt64(4),
+				interpreter.NewUnmeteredIntValueFromInt64(1),
+				interpreter.NewUnmeteredIntValueFromInt64(5),
+				interpreter.NewUnmeteredIntValueFromInt64(9),
+				interpreter.NewUnmeteredIntValueFromInt64(2),
+				interpreter.NewUnmeteredIntValueFromInt64(6),
+			},
+			ArrayElements(inter, value.(*interpreter.ArrayValue)),
+		)
+	})
+
+	t.Run("constant sized, stable", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := parseCheckAndInterpret(t, `
+          fun test(): [String; 4] {
+              let xs: [String; 4] = ["bb", "a", "cc", "d"]
+              return xs.sort(by: view fun (_ a: String, _ b: String): Bool {
+                  return a.length < b.length
+              })
+          }
+        `)
+
+		value, err := inter.Invoke("test")
+		require.NoError(t, err)
+
+		require.IsType(t, &interpreter.ArrayValue{}, value)
+		sortedArray := value.(*interpreter.ArrayValue)
+
+		require.Equal(t,
+			interpreter.NewConstantSizedStaticType(nil, interpreter.PrimitiveStaticTypeString, 4),
+			sortedArray.Type,
+		)
+
+		AssertValueSlicesEqual(
+			t,
+			inter,
+			[]interpreter.Value{
+				interpreter.NewUnmeteredStringValue("a"),
+				interpreter.NewUnmeteredStringValue("d"),
+				interpreter.NewUnmeteredStringValue("bb"),
+				interpreter.NewUnmeteredStringValue("cc"),
+			},
+			ArrayElements(inter, sortedArray),
+		)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := parseCheckAndInterpret(t, `
+          fun test(): Int {
+              let xs: [Int] = []
+              return xs.sort(by: view fun (_ a: Int, _ b: Int): Bool {
+                  return a < b
+              }).length
+          }
+        `)
+
+		value, err := inter.Invoke("test")
+		require.NoError(t, err)
+
+		AssertValuesEqual(
+			t,
+			inter,
+			interpreter.NewUnmeteredIntValueFromInt64(0),
+			value,
+		)
+	})
+}
+
+func TestInterpretArrayReduce(t *testing.T) {
+
+	t.Parallel()
+
+	inter := parseCheckAndInterpret(t, `
+      let xs = [1, 2, 3, 4]
+
+      fun sum(): Int {
+          return xs.reduce(initial: 0, fun (_ sum: Int, _ x: Int): Int {
+              return sum + x
+          })
+      }
+
+      fun join(): String {
+          return xs.reduce(initial: "", fun (_ s: String, _ x: Int): String {
+              return s.concat(x.toString())
+          })
+      }
+
+      fun empty(): Int {
+          let ys: [Int] = []
+          return ys.reduce(initial: 42, fun (_ sum: Int, _ x: Int): Int {
+              return sum + x
+          })
+      }
+    `)
+
+	value, err := inter.Invoke("sum")
+	require.NoError(t, err)
+
+	AssertValuesEqual(
+		t,
+		inter,
+		interpreter.NewUnmeteredIntValueFromInt64(10),
+		value,
+	)
+
+	value, err = inter.Invoke("join")
+	require.NoError(t, err)
+
+	AssertValuesEqual(
+		t,
+		inter,
+		interpreter.NewUnmeteredStringValue("1234"),
+		value,
+	)
+
+	value, err = inter.Invoke("empty")
+	require.NoError(t, err)
+
+	AssertValuesEqual(
+		t,
+		inter,
+		interpreter.NewUnmeteredIntValueFromInt64(42),
+		value,
+	)
+}
+
+func TestInterpretArrayReduceMutation(t *testing.T) {
+
+	t.Parallel()
+
+	inter := parseCheckAndInterpret(t, `
+      fun test(): Int {
+          let xs = [1, 2, 3]
+          return xs.reduce(initial: 0, fun (_ sum: Int, _ x: Int): Int {
+              xs.append(x)
+              return sum + x
+          })
+      }
+    `)
+
+	_, err := inter.Invoke("test")
+	RequireError(t, err)
+
+	require.ErrorAs(t, err, &interpreter.ContainerMutatedDuringIterationError{})
+}
+
+func TestInterpretArrayAnyAll(t *testing.T) {
+
+	t.Parallel()
+
+	inter := parseCheckAndInterpret(t, `
+      let xs = [1, 2, 3, 4]
+      let empty: [Int] = []
+
+      let isEven = view fun (_ x: Int): Bool {
+          return x % 2 == 0
+      }
+
+      let isPositive = view fun (_ x: Int): Bool {
+          return x > 0
+      }
+
+      let isNegative = view fun (_ x: Int): Bool {
+          return x < 0
+      }
+
+      fun test(): [Bool] {
+          return [
+              xs.any(isEven),
+              xs.any(isNegative),
+              xs.all(isPositive),
+              xs.all(isEven),
+              empty.any(isPositive),
+              empty.all(isNegative)
+          ]
+      }
+    `)
+
+	value, err := inter.Invoke("test")
+	require.NoError(t, err)
+
+	require.IsType(t, &interpreter.ArrayValue{}, value)
+
+	AssertValueSlicesEqual(
+		t,
+		inter,
+		[]interpreter.Value{
+			interpreter.TrueValue,
+			interpreter.FalseValue,
+			interpreter.TrueValue,
+			interpreter.FalseValue,
+			interpreter.FalseValue,
+			interpreter.TrueValue,
+		},
+		ArrayElements(inter, value.(*interpreter.ArrayValue)),
+	)
+}
+
+func TestInterpretArrayLastIndex(t *testing.T) {
+
+	t.Parallel()
+
+	inter := parseCheckAndInterpret(t, `
+      let xs = [1, 2, 3, 2, 1]
+
+      fun test(): Int? {
+          return xs.lastIndex(of: 2)
+      }
+
+      fun testDoesNotExist(): Int? {
+          return xs.lastIndex(of: 5)
+      }
+    `)
+
+	value, err := inter.Invoke("test")
+	require.NoError(t, err)
+
+	AssertValuesEqual(
+		t,
+		inter,
+		interpreter.NewUnmeteredSomeValueNonCopying(
+			interpreter.NewUnmeteredIntValueFromInt64(3),
+		),
+		value,
+	)
+
+	value, err = inter.Invoke("testDoesNotExist")
+	require.NoError(t, err)
+
+	AssertValuesEqual(
+		t,
+		inter,
+		interpreter.Nil,
+		value,
+	)
+}
+
 func TestInterpretCastingBoxing(t *testing.T) {
 
 	t.Parallel()
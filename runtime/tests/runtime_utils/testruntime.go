@@ -56,6 +56,15 @@ func (r TestInterpreterRuntime) ExecuteTransaction(script runtime.Script, contex
 	return r.Runtime.ExecuteTransaction(script, context)
 }
 
+func (r TestInterpreterRuntime) SimulateTransaction(
+	script runtime.Script,
+	context runtime.Context,
+) (*runtime.TransactionSimulation, error) {
+	i := context.Interface.(*TestRuntimeInterface)
+	i.onTransactionExecutionStart()
+	return r.Runtime.SimulateTransaction(script, context)
+}
+
 func (r TestInterpreterRuntime) ExecuteScript(script runtime.Script, context runtime.Context) (cadence.Value, error) {
 	i := context.Interface.(*TestRuntimeInterface)
 	i.onScriptExecutionStart()
@@ -107,6 +107,7 @@ func (executor *interpreterTransactionExecutor) preprocess() (err error) {
 	runtimeInterface := context.Interface
 
 	storage := NewStorage(runtimeInterface, runtimeInterface)
+	storage.SetReadOnly(context.ReadOnly)
 	executor.storage = storage
 
 	environment := context.Environment
@@ -34,4 +34,7 @@ const (
 
 	// NeedExtendedElaboration provides an extended elaboration.
 	NeedExtendedElaboration
+
+	// NeedSuggestions provides suggestions for checker errors, e.g. suggested fixes.
+	NeedSuggestions
 )
@@ -0,0 +1,143 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes
+
+import (
+	goerrors "errors"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+const DeprecatedAccessModifierCode = "deprecated-access-modifier"
+
+// The messages of the syntax errors the parser reports for the removed access modifiers
+const (
+	pubErrorMessage    = "`pub` is no longer a valid access keyword"
+	privErrorMessage   = "`priv` is no longer a valid access keyword"
+	pubSetErrorMessage = "`pub(set)` is no longer a valid access keyword"
+)
+
+// DeprecatedAccessModifierAnalyzer reports uses of the removed access modifiers
+// `pub`, `priv`, and `pub(set)`, and suggests replacements.
+//
+// The parser rejects these access modifiers,
+// so the analyzer requires the program to be loaded with a parser error handler
+// which allows the analysis of programs with parser errors, see analysis.Config.HandleParserError.
+var DeprecatedAccessModifierAnalyzer = &analysis.Analyzer{
+	Description: "Detects the removed access modifiers pub, priv, and pub(set)",
+	Run: func(pass *analysis.Pass) interface{} {
+		var parserError parser.Error
+		if !goerrors.As(pass.Program.LoadError, &parserError) {
+			return nil
+		}
+
+		for _, err := range parserError.Errors {
+			diagnostic, ok := deprecatedAccessModifierDiagnostic(err, pass.Program.Code)
+			if !ok {
+				continue
+			}
+			diagnostic.Location = pass.Program.Location
+			pass.Report(diagnostic)
+		}
+
+		return nil
+	},
+}
+
+// IsDeprecatedAccessModifierError returns true if the given parser error
+// is reported by DeprecatedAccessModifierAnalyzer.
+func IsDeprecatedAccessModifierError(err error) bool {
+	_, ok := deprecatedAccessModifierDiagnostic(err, nil)
+	return ok
+}
+
+func deprecatedAccessModifierDiagnostic(err error, code []byte) (analysis.Diagnostic, bool) {
+	switch err := err.(type) {
+	case *parser.SyntaxErrorWithSuggestedReplacement:
+		var keyword string
+		switch err.Message {
+		case pubErrorMessage:
+			keyword = "pub"
+		case privErrorMessage:
+			keyword = "priv"
+		default:
+			return analysis.Diagnostic{}, false
+		}
+
+		return newDeprecatedAccessModifierDiagnostic(keyword, err.SuggestedFix, err.Range), true
+
+	case *parser.SyntaxError:
+		if err.Message != pubSetErrorMessage {
+			return analysis.Diagnostic{}, false
+		}
+
+		return newDeprecatedAccessModifierDiagnostic(
+			"pub(set)",
+			"access(all)",
+			pubSetRange(err.Pos, code),
+		), true
+	}
+
+	return analysis.Diagnostic{}, false
+}
+
+func newDeprecatedAccessModifierDiagnostic(
+	keyword string,
+	replacement string,
+	r ast.Range,
+) analysis.Diagnostic {
+	return analysis.Diagnostic{
+		Range:    r,
+		Category: DeprecatedCategory,
+		Code:     DeprecatedAccessModifierCode,
+		Message:  "`" + keyword + "` is no longer a valid access modifier",
+		SuggestedFixes: []analysis.SuggestedFix{
+			{
+				Message: "replace with `" + replacement + "`",
+				TextEdits: []analysis.TextEdit{
+					{
+						Replacement: replacement,
+						Range:       r,
+					},
+				},
+			},
+		},
+	}
+}
+
+// pubSetRange returns the range of the `pub(set)` access modifier starting at the given position.
+// The modifier may contain whitespace, so the range ends at the closing parenthesis.
+func pubSetRange(startPos ast.Position, code []byte) ast.Range {
+	endPos := startPos
+
+	if startPos.Offset < len(code) {
+		closingOffset := strings.IndexByte(string(code[startPos.Offset:]), ')')
+		if closingOffset >= 0 {
+			endPos = startPos.Shifted(nil, closingOffset)
+		}
+	}
+
+	return ast.Range{
+		StartPos: startPos,
+		EndPos:   endPos,
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/tools/analysis"
+	"github.com/onflow/cadence/tools/analysis/passes"
+)
+
+func TestDeprecatedAccessModifierAnalyzer(t *testing.T) {
+
+	t.Parallel()
+
+	program, diagnostics := runAnalyzers(t,
+		`
+          pub contract Test {
+              pub(set) var x: Int
+              priv let y: Int
+
+              init() {
+                  self.x = 1
+                  self.y = 2
+              }
+          }
+        `,
+		passes.DeprecatedAccessModifierAnalyzer,
+	)
+
+	diagnostic := func(keyword string, replacement string, r ast.Range) analysis.Diagnostic {
+		return analysis.Diagnostic{
+			Location: testLocation,
+			Range:    r,
+			Category: passes.DeprecatedCategory,
+			Code:     passes.DeprecatedAccessModifierCode,
+			Message:  "`" + keyword + "` is no longer a valid access modifier",
+			SuggestedFixes: []analysis.SuggestedFix{
+				{
+					Message: "replace with `" + replacement + "`",
+					TextEdits: []analysis.TextEdit{
+						{
+							Replacement: replacement,
+							Range:       r,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	require.Equal(
+		t,
+		[]analysis.Diagnostic{
+			diagnostic(
+				"pub",
+				"access(all)",
+				ast.Range{
+					StartPos: ast.Position{Offset: 11, Line: 2, Column: 10},
+					EndPos:   ast.Position{Offset: 13, Line: 2, Column: 12},
+				},
+			),
+			diagnostic(
+				"pub(set)",
+				"access(all)",
+				ast.Range{
+					StartPos: ast.Position{Offset: 45, Line: 3, Column: 14},
+					EndPos:   ast.Position{Offset: 52, Line: 3, Column: 21},
+				},
+			),
+			diagnostic(
+				"priv",
+				"access(self)",
+				ast.Range{
+					StartPos: ast.Position{Offset: 79, Line: 4, Column: 14},
+					EndPos:   ast.Position{Offset: 82, Line: 4, Column: 17},
+				},
+			),
+		},
+		diagnostics,
+	)
+
+	require.Error(t, program.LoadError)
+}
@@ -0,0 +1,134 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes
+
+import (
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+// LocalVariable is a variable declared in a function block
+type LocalVariable struct {
+	Declaration *ast.VariableDeclaration
+	// Type is the type of the declared variable, if known
+	Type sema.Type
+	// Used is true if the variable is referred to after its declaration
+	Used bool
+}
+
+// LocalVariablesAnalyzer collects all local variables of a program,
+// and whether they are used.
+//
+// The result is a []LocalVariable.
+// It is nil if the program was not loaded with position information.
+var LocalVariablesAnalyzer = &analysis.Analyzer{
+	Description: "Collects local variables and their uses",
+	Requires: []*analysis.Analyzer{
+		analysis.InspectorAnalyzer,
+	},
+	Run: func(pass *analysis.Pass) interface{} {
+		checker := pass.Program.Checker
+		if checker == nil || checker.PositionInfo == nil {
+			return nil
+		}
+
+		inspector := pass.ResultOf[analysis.InspectorAnalyzer].(*ast.Inspector)
+
+		// Index the origins of all variables by the position of their declaration
+
+		origins := map[ast.Position]*sema.Origin{}
+		for _, origin := range checker.PositionInfo.VariableOrigins {
+			if origin.StartPos == nil {
+				continue
+			}
+			switch origin.DeclarationKind {
+			case common.DeclarationKindConstant,
+				common.DeclarationKindVariable:
+
+				origins[*origin.StartPos] = origin
+			}
+		}
+
+		var localVariables []LocalVariable
+
+		inspector.WithStack(
+			[]ast.Element{
+				(*ast.VariableDeclaration)(nil),
+			},
+			func(element ast.Element, push bool, stack []ast.Element) bool {
+				if !push {
+					return true
+				}
+
+				declaration := element.(*ast.VariableDeclaration)
+
+				if !inFunctionBlock(stack) {
+					return true
+				}
+
+				// Variables with a name starting with an underscore are explicitly unused
+				if strings.HasPrefix(declaration.Identifier.Identifier, "_") {
+					return true
+				}
+
+				origin, ok := origins[declaration.Identifier.Pos]
+				if !ok {
+					return true
+				}
+
+				types := checker.Elaboration.VariableDeclarationTypes(declaration)
+
+				localVariables = append(
+					localVariables,
+					LocalVariable{
+						Declaration: declaration,
+						Type:        types.TargetType,
+						// The first occurrence is the declaration itself
+						Used: len(origin.Occurrences) > 1,
+					},
+				)
+
+				return true
+			},
+		)
+
+		return localVariables
+	},
+}
+
+func inFunctionBlock(stack []ast.Element) bool {
+	for _, element := range stack {
+		if _, ok := element.(*ast.FunctionBlock); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isReferenceType(ty sema.Type) bool {
+	if optionalType, ok := ty.(*sema.OptionalType); ok {
+		return isReferenceType(optionalType.Type)
+	}
+	_, ok := ty.(*sema.ReferenceType)
+	return ok
+}
@@ -0,0 +1,102 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes
+
+import (
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+const PanicInViewFunctionCode = "panic-in-view-function"
+
+const panicFunctionName = "panic"
+
+// PanicInViewFunctionAnalyzer reports invocations of `panic` in view functions.
+// View functions are expected to only query state,
+// and callers do not expect them to abort the program.
+var PanicInViewFunctionAnalyzer = &analysis.Analyzer{
+	Description: "Detects invocations of panic in view functions",
+	Requires: []*analysis.Analyzer{
+		analysis.InspectorAnalyzer,
+	},
+	Run: func(pass *analysis.Pass) interface{} {
+		checker := pass.Program.Checker
+
+		inspector := pass.ResultOf[analysis.InspectorAnalyzer].(*ast.Inspector)
+
+		inspector.WithStack(
+			[]ast.Element{
+				(*ast.InvocationExpression)(nil),
+			},
+			func(element ast.Element, push bool, stack []ast.Element) bool {
+				if !push {
+					return true
+				}
+
+				invocation := element.(*ast.InvocationExpression)
+
+				identifierExpression, ok := invocation.InvokedExpression.(*ast.IdentifierExpression)
+				if !ok || identifierExpression.Identifier.Identifier != panicFunctionName {
+					return true
+				}
+
+				// Ensure the invoked function is the built-in function, and not a shadowing declaration
+				if checker != nil {
+					returnType := checker.Elaboration.InvocationExpressionTypes(invocation).ReturnType
+					if returnType != sema.NeverType {
+						return true
+					}
+				}
+
+				if enclosingFunctionPurity(stack) != ast.FunctionPurityView {
+					return true
+				}
+
+				pass.Report(
+					analysis.Diagnostic{
+						Location:         pass.Program.Location,
+						Range:            ast.NewRangeFromPositioned(nil, invocation),
+						Category:         LintCategory,
+						Code:             PanicInViewFunctionCode,
+						Message:          "`panic` is invoked in a view function",
+						SecondaryMessage: "consider using a pre-condition, or returning an optional",
+					},
+				)
+
+				return true
+			},
+		)
+
+		return nil
+	},
+}
+
+// enclosingFunctionPurity returns the purity of the innermost function in the given stack
+func enclosingFunctionPurity(stack []ast.Element) ast.FunctionPurity {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch element := stack[i].(type) {
+		case *ast.FunctionDeclaration:
+			return element.Purity
+		case *ast.FunctionExpression:
+			return element.Purity
+		}
+	}
+	return ast.FunctionPurityUnspecified
+}
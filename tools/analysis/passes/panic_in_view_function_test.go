@@ -0,0 +1,88 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/tools/analysis"
+	"github.com/onflow/cadence/tools/analysis/passes"
+)
+
+func TestPanicInViewFunctionAnalyzer(t *testing.T) {
+
+	t.Parallel()
+
+	diagnostics := testAnalyzers(t,
+		`
+          access(all) view fun get(_ values: [Int], _ index: Int): Int {
+              if index >= values.length {
+                  panic("out of bounds")
+              }
+              return values[index]
+          }
+
+          access(all) fun set(_ values: [Int], _ index: Int) {
+              if index >= values.length {
+                  panic("out of bounds")
+              }
+          }
+
+          access(all) fun nested(): Int {
+              let f = view fun (): Int {
+                  panic("nested")
+              }
+              return f()
+          }
+        `,
+		passes.PanicInViewFunctionAnalyzer,
+	)
+
+	require.Equal(
+		t,
+		[]analysis.Diagnostic{
+			{
+				Location: testLocation,
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 134, Line: 4, Column: 18},
+					EndPos:   ast.Position{Offset: 155, Line: 4, Column: 39},
+				},
+				Category:         passes.LintCategory,
+				Code:             passes.PanicInViewFunctionCode,
+				Message:          "`panic` is invoked in a view function",
+				SecondaryMessage: "consider using a pre-condition, or returning an optional",
+			},
+			{
+				Location: testLocation,
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 497, Line: 17, Column: 18},
+					EndPos:   ast.Position{Offset: 511, Line: 17, Column: 32},
+				},
+				Category:         passes.LintCategory,
+				Code:             passes.PanicInViewFunctionCode,
+				Message:          "`panic` is invoked in a view function",
+				SecondaryMessage: "consider using a pre-condition, or returning an optional",
+			},
+		},
+		diagnostics,
+	)
+}
@@ -0,0 +1,66 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package passes provides a set of analyzers for Cadence programs,
+// built on the analysis package.
+//
+// Most analyzers require type information, and some require position information
+// and extended elaboration. Programs should be loaded with the load mode LoadMode.
+package passes
+
+import (
+	"sort"
+
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+const (
+	// LintCategory is the category of diagnostics about likely mistakes or dead code
+	LintCategory = "lint"
+	// SecurityCategory is the category of diagnostics about potential vulnerabilities
+	SecurityCategory = "security"
+	// DeprecatedCategory is the category of diagnostics about deprecated language features
+	DeprecatedCategory = "deprecated"
+)
+
+// LoadMode is the load mode programs must be loaded with,
+// so that all analyzers of this package can run
+const LoadMode = analysis.NeedTypes |
+	analysis.NeedPositionInfo |
+	analysis.NeedExtendedElaboration
+
+// Analyzers are all analyzers of this package, by name
+var Analyzers = map[string]*analysis.Analyzer{
+	UnusedVariableCode:           UnusedVariableAnalyzer,
+	UnusedResourceCode:           UnusedResourceAnalyzer,
+	UnusedReferenceCode:          UnusedReferenceAnalyzer,
+	PublicAuthCapabilityCode:     PublicAuthCapabilityAnalyzer,
+	PanicInViewFunctionCode:      PanicInViewFunctionAnalyzer,
+	RedundantForceUnwrapCode:     RedundantForceUnwrapAnalyzer,
+	DeprecatedAccessModifierCode: DeprecatedAccessModifierAnalyzer,
+}
+
+// AnalyzerNames returns the names of all analyzers of this package, sorted
+func AnalyzerNames() []string {
+	names := make([]string, 0, len(Analyzers))
+	for name := range Analyzers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,111 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes_test
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/tools/analysis"
+	"github.com/onflow/cadence/tools/analysis/passes"
+)
+
+var testLocation = common.StringLocation("test")
+
+// testAnalyzers runs the given analyzers on the given code,
+// which must be valid, and returns the reported diagnostics
+func testAnalyzers(t *testing.T, code string, analyzers ...*analysis.Analyzer) []analysis.Diagnostic {
+	program, diagnostics := runAnalyzers(t, code, analyzers...)
+	require.NoError(t, program.LoadError)
+	return diagnostics
+}
+
+// runAnalyzers runs the given analyzers on the given code,
+// which may be invalid, and returns the program and the reported diagnostics
+func runAnalyzers(
+	t *testing.T,
+	code string,
+	analyzers ...*analysis.Analyzer,
+) (
+	*analysis.Program,
+	[]analysis.Diagnostic,
+) {
+
+	config := analysis.NewSimpleConfig(
+		passes.LoadMode,
+		map[common.Location][]byte{
+			testLocation: []byte(code),
+		},
+		nil,
+		nil,
+	)
+	config.HandleParserError = func(_ analysis.ParsingCheckingError, _ *ast.Program) error {
+		return nil
+	}
+	config.HandleCheckerError = func(_ analysis.ParsingCheckingError, _ *sema.Checker) error {
+		return nil
+	}
+
+	programs, err := analysis.Load(config, testLocation)
+	require.NoError(t, err)
+
+	var lock sync.Mutex
+	var diagnostics []analysis.Diagnostic
+
+	program := programs[testLocation]
+
+	program.Run(
+		analyzers,
+		func(diagnostic analysis.Diagnostic) {
+			lock.Lock()
+			defer lock.Unlock()
+			diagnostics = append(diagnostics, diagnostic)
+		},
+	)
+
+	sort.Slice(diagnostics, func(i, j int) bool {
+		return diagnostics[i].StartPos.Compare(diagnostics[j].StartPos) < 0
+	})
+
+	return program, diagnostics
+}
+
+func TestAnalyzerNames(t *testing.T) {
+
+	t.Parallel()
+
+	require.Equal(t,
+		[]string{
+			"deprecated-access-modifier",
+			"panic-in-view-function",
+			"public-auth-capability",
+			"redundant-force-unwrap",
+			"unused-reference",
+			"unused-resource",
+			"unused-variable",
+		},
+		passes.AnalyzerNames(),
+	)
+}
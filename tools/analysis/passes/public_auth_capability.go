@@ -0,0 +1,106 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes
+
+import (
+	"fmt"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+const PublicAuthCapabilityCode = "public-auth-capability"
+
+// PublicAuthCapabilityAnalyzer reports capabilities for authorized references
+// which are published, i.e. made available to everyone at a public path.
+var PublicAuthCapabilityAnalyzer = &analysis.Analyzer{
+	Description: "Detects capabilities for authorized references which are published at public paths",
+	Requires: []*analysis.Analyzer{
+		analysis.InspectorAnalyzer,
+	},
+	Run: func(pass *analysis.Pass) interface{} {
+		checker := pass.Program.Checker
+		if checker == nil {
+			return nil
+		}
+
+		elaboration := checker.Elaboration
+
+		inspector := pass.ResultOf[analysis.InspectorAnalyzer].(*ast.Inspector)
+
+		inspector.Preorder(
+			[]ast.Element{
+				(*ast.InvocationExpression)(nil),
+			},
+			func(element ast.Element) {
+				invocation := element.(*ast.InvocationExpression)
+
+				memberExpression, ok := invocation.InvokedExpression.(*ast.MemberExpression)
+				if !ok ||
+					memberExpression.Identifier.Identifier != sema.Account_CapabilitiesTypePublishFunctionName {
+
+					return
+				}
+
+				memberInfo, ok := elaboration.MemberExpressionMemberAccessInfo(memberExpression)
+				if !ok || memberInfo.Member == nil ||
+					memberInfo.Member.ContainerType != sema.Account_CapabilitiesType {
+
+					return
+				}
+
+				argumentTypes := elaboration.InvocationExpressionTypes(invocation).ArgumentTypes
+				if len(argumentTypes) < 1 || len(invocation.Arguments) < 2 {
+					return
+				}
+
+				capabilityType, ok := argumentTypes[0].(*sema.CapabilityType)
+				if !ok {
+					return
+				}
+
+				referenceType, ok := capabilityType.BorrowType.(*sema.ReferenceType)
+				if !ok || referenceType.Authorization == sema.UnauthorizedAccess {
+					return
+				}
+
+				pathArgument := invocation.Arguments[1].Expression
+
+				pass.Report(
+					analysis.Diagnostic{
+						Location: pass.Program.Location,
+						Range:    ast.NewRangeFromPositioned(nil, invocation),
+						Category: SecurityCategory,
+						Code:     PublicAuthCapabilityCode,
+						Message: fmt.Sprintf(
+							"capability for authorized reference `%s` is published at public path `%s`",
+							referenceType.QualifiedString(),
+							pathArgument,
+						),
+						SecondaryMessage: "anyone can borrow the capability and access the entitled members; " +
+							"publish a capability for an unauthorized reference instead",
+					},
+				)
+			},
+		)
+
+		return nil
+	},
+}
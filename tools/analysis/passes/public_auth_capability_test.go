@@ -0,0 +1,71 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/tools/analysis"
+	"github.com/onflow/cadence/tools/analysis/passes"
+)
+
+func TestPublicAuthCapabilityAnalyzer(t *testing.T) {
+
+	t.Parallel()
+
+	diagnostics := testAnalyzers(t,
+		`
+          access(all) entitlement Withdraw
+
+          access(all) resource Vault {}
+
+          access(all) fun test(account: auth(Storage, Capabilities) &Account) {
+              let authCap = account.capabilities.storage.issue<auth(Withdraw) &Vault>(/storage/vault)
+              account.capabilities.publish(authCap, at: /public/vault)
+
+              let cap = account.capabilities.storage.issue<&Vault>(/storage/vault)
+              account.capabilities.publish(cap, at: /public/vaultReadOnly)
+          }
+        `,
+		passes.PublicAuthCapabilityAnalyzer,
+	)
+
+	require.Equal(
+		t,
+		[]analysis.Diagnostic{
+			{
+				Location: testLocation,
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 282, Line: 8, Column: 14},
+					EndPos:   ast.Position{Offset: 337, Line: 8, Column: 69},
+				},
+				Category: passes.SecurityCategory,
+				Code:     passes.PublicAuthCapabilityCode,
+				Message: "capability for authorized reference `auth(Withdraw) &Vault` " +
+					"is published at public path `/public/vault`",
+				SecondaryMessage: "anyone can borrow the capability and access the entitled members; " +
+					"publish a capability for an unauthorized reference instead",
+			},
+		},
+		diagnostics,
+	)
+}
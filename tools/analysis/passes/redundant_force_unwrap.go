@@ -0,0 +1,94 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes
+
+import (
+	"fmt"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+const RedundantForceUnwrapCode = "redundant-force-unwrap"
+
+// RedundantForceUnwrapAnalyzer reports force-unwraps of values which are not optional.
+//
+// The analyzer requires the program to be loaded with extended elaboration.
+var RedundantForceUnwrapAnalyzer = &analysis.Analyzer{
+	Description: "Detects force-unwraps of values which are not optional",
+	Requires: []*analysis.Analyzer{
+		analysis.InspectorAnalyzer,
+	},
+	Run: func(pass *analysis.Pass) interface{} {
+		checker := pass.Program.Checker
+		if checker == nil {
+			return nil
+		}
+
+		inspector := pass.ResultOf[analysis.InspectorAnalyzer].(*ast.Inspector)
+
+		inspector.Preorder(
+			[]ast.Element{
+				(*ast.ForceExpression)(nil),
+			},
+			func(element ast.Element) {
+				forceExpression := element.(*ast.ForceExpression)
+
+				valueType := checker.Elaboration.ForceExpressionType(forceExpression)
+				if valueType == nil || valueType.IsInvalidType() {
+					return
+				}
+
+				if _, ok := valueType.(*sema.OptionalType); ok {
+					return
+				}
+
+				pass.Report(
+					analysis.Diagnostic{
+						Location: pass.Program.Location,
+						Range:    ast.NewRangeFromPositioned(nil, forceExpression),
+						Category: LintCategory,
+						Code:     RedundantForceUnwrapCode,
+						Message: fmt.Sprintf(
+							"force-unwrap of value of non-optional type `%s` is redundant",
+							valueType.QualifiedString(),
+						),
+						SuggestedFixes: []analysis.SuggestedFix{
+							{
+								Message: "remove force-unwrap",
+								TextEdits: []analysis.TextEdit{
+									{
+										Replacement: "",
+										Range: ast.Range{
+											StartPos: forceExpression.EndPos,
+											EndPos:   forceExpression.EndPos,
+										},
+									},
+								},
+							},
+						},
+					},
+				)
+			},
+		)
+
+		return nil
+	},
+}
@@ -0,0 +1,76 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/tools/analysis"
+	"github.com/onflow/cadence/tools/analysis/passes"
+)
+
+func TestRedundantForceUnwrapAnalyzer(t *testing.T) {
+
+	t.Parallel()
+
+	diagnostics := testAnalyzers(t,
+		`
+          access(all) fun test(opt: Int?, value: Int): Int {
+              return opt! + value!
+          }
+        `,
+		passes.RedundantForceUnwrapAnalyzer,
+	)
+
+	forceRange := ast.Range{
+		StartPos: ast.Position{Offset: 95, Line: 3, Column: 33},
+		EndPos:   ast.Position{Offset: 95, Line: 3, Column: 33},
+	}
+
+	require.Equal(
+		t,
+		[]analysis.Diagnostic{
+			{
+				Location: testLocation,
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 90, Line: 3, Column: 28},
+					EndPos:   forceRange.EndPos,
+				},
+				Category: passes.LintCategory,
+				Code:     passes.RedundantForceUnwrapCode,
+				Message:  "force-unwrap of value of non-optional type `Int` is redundant",
+				SuggestedFixes: []analysis.SuggestedFix{
+					{
+						Message: "remove force-unwrap",
+						TextEdits: []analysis.TextEdit{
+							{
+								Replacement: "",
+								Range:       forceRange,
+							},
+						},
+					},
+				},
+			},
+		},
+		diagnostics,
+	)
+}
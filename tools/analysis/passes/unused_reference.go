@@ -0,0 +1,101 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes
+
+import (
+	"fmt"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+const UnusedReferenceCode = "unused-reference"
+
+// UnusedReferenceAnalyzer reports references which are taken, but never used:
+// local variables of reference type which are never used,
+// and reference expressions which are evaluated as a statement.
+var UnusedReferenceAnalyzer = &analysis.Analyzer{
+	Description: "Detects references which are taken, but never used",
+	Requires: []*analysis.Analyzer{
+		analysis.InspectorAnalyzer,
+		LocalVariablesAnalyzer,
+	},
+	Run: func(pass *analysis.Pass) interface{} {
+		location := pass.Program.Location
+
+		localVariables, _ := pass.ResultOf[LocalVariablesAnalyzer].([]LocalVariable)
+
+		for _, localVariable := range localVariables {
+			if localVariable.Used || !isReferenceType(localVariable.Type) {
+				continue
+			}
+
+			identifier := localVariable.Declaration.Identifier
+
+			pass.Report(
+				analysis.Diagnostic{
+					Location: location,
+					Range:    ast.NewRangeFromPositioned(nil, identifier),
+					Category: LintCategory,
+					Code:     UnusedReferenceCode,
+					Message: fmt.Sprintf(
+						"reference `%s` is taken, but never used",
+						identifier.Identifier,
+					),
+				},
+			)
+		}
+
+		inspector := pass.ResultOf[analysis.InspectorAnalyzer].(*ast.Inspector)
+
+		inspector.Preorder(
+			[]ast.Element{
+				(*ast.ExpressionStatement)(nil),
+			},
+			func(element ast.Element) {
+				statement := element.(*ast.ExpressionStatement)
+
+				// The reference expression may be statically cast, e.g. `&x as &T`
+
+				expression := statement.Expression
+				if castingExpression, ok := expression.(*ast.CastingExpression); ok &&
+					castingExpression.Operation == ast.OperationCast {
+
+					expression = castingExpression.Expression
+				}
+
+				if _, ok := expression.(*ast.ReferenceExpression); !ok {
+					return
+				}
+
+				pass.Report(
+					analysis.Diagnostic{
+						Location: location,
+						Range:    ast.NewRangeFromPositioned(nil, statement.Expression),
+						Category: LintCategory,
+						Code:     UnusedReferenceCode,
+						Message:  "reference is taken, but never used",
+					},
+				)
+			},
+		)
+
+		return nil
+	},
+}
@@ -0,0 +1,87 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/tools/analysis"
+	"github.com/onflow/cadence/tools/analysis/passes"
+)
+
+func TestUnusedReferenceAnalyzer(t *testing.T) {
+
+	t.Parallel()
+
+	diagnostics := testAnalyzers(t,
+		`
+          access(all) fun test(): Int {
+              let numbers = [1, 2, 3]
+              let ref = &numbers as &[Int];
+              &numbers as &[Int]
+              let usedRef = &numbers as &[Int]
+              let unusedNumber = 1
+              return usedRef.length
+          }
+        `,
+		passes.UnusedReferenceAnalyzer,
+		passes.UnusedVariableAnalyzer,
+	)
+
+	require.Equal(
+		t,
+		[]analysis.Diagnostic{
+			{
+				Location: testLocation,
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 97, Line: 4, Column: 18},
+					EndPos:   ast.Position{Offset: 99, Line: 4, Column: 20},
+				},
+				Category: passes.LintCategory,
+				Code:     passes.UnusedReferenceCode,
+				Message:  "reference `ref` is taken, but never used",
+			},
+			{
+				Location: testLocation,
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 137, Line: 5, Column: 14},
+					EndPos:   ast.Position{Offset: 154, Line: 5, Column: 31},
+				},
+				Category: passes.LintCategory,
+				Code:     passes.UnusedReferenceCode,
+				Message:  "reference is taken, but never used",
+			},
+			{
+				Location: testLocation,
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 221, Line: 7, Column: 18},
+					EndPos:   ast.Position{Offset: 232, Line: 7, Column: 29},
+				},
+				Category:         passes.LintCategory,
+				Code:             passes.UnusedVariableCode,
+				Message:          "variable `unusedNumber` is declared, but never used",
+				SecondaryMessage: "remove the declaration, or prefix the name with an underscore",
+			},
+		},
+		diagnostics,
+	)
+}
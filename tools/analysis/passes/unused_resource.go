@@ -0,0 +1,101 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes
+
+import (
+	"fmt"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+const UnusedResourceCode = "unused-resource"
+
+// UnusedResourceAnalyzer reports resource types which are declared, but never created.
+//
+// Resources can only be created in the location they are declared in,
+// so a resource which is not created in its program can never exist.
+var UnusedResourceAnalyzer = &analysis.Analyzer{
+	Description: "Detects resource types which are declared, but never created",
+	Requires: []*analysis.Analyzer{
+		analysis.InspectorAnalyzer,
+	},
+	Run: func(pass *analysis.Pass) interface{} {
+		checker := pass.Program.Checker
+		if checker == nil {
+			return nil
+		}
+
+		elaboration := checker.Elaboration
+
+		inspector := pass.ResultOf[analysis.InspectorAnalyzer].(*ast.Inspector)
+
+		var declarations []*ast.CompositeDeclaration
+		created := map[*sema.CompositeType]struct{}{}
+
+		inspector.Preorder(
+			[]ast.Element{
+				(*ast.CompositeDeclaration)(nil),
+				(*ast.CreateExpression)(nil),
+			},
+			func(element ast.Element) {
+				switch element := element.(type) {
+				case *ast.CompositeDeclaration:
+					if element.Kind() == common.CompositeKindResource {
+						declarations = append(declarations, element)
+					}
+
+				case *ast.CreateExpression:
+					invocationTypes := elaboration.InvocationExpressionTypes(element.InvocationExpression)
+					if compositeType, ok := invocationTypes.ReturnType.(*sema.CompositeType); ok {
+						created[compositeType] = struct{}{}
+					}
+				}
+			},
+		)
+
+		for _, declaration := range declarations {
+			compositeType := elaboration.CompositeDeclarationType(declaration)
+			if compositeType == nil {
+				continue
+			}
+
+			if _, ok := created[compositeType]; ok {
+				continue
+			}
+
+			pass.Report(
+				analysis.Diagnostic{
+					Location: pass.Program.Location,
+					Range:    ast.NewRangeFromPositioned(nil, declaration.Identifier),
+					Category: LintCategory,
+					Code:     UnusedResourceCode,
+					Message: fmt.Sprintf(
+						"resource `%s` is declared, but never created",
+						compositeType.QualifiedString(),
+					),
+				},
+			)
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,71 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/tools/analysis"
+	"github.com/onflow/cadence/tools/analysis/passes"
+)
+
+func TestUnusedResourceAnalyzer(t *testing.T) {
+
+	t.Parallel()
+
+	diagnostics := testAnalyzers(t,
+		`
+          access(all) contract Test {
+
+              access(all) resource Created {}
+
+              access(all) resource Unused {}
+
+              access(all) resource interface I {}
+
+              access(all) struct S {}
+
+              access(all) fun createCreated(): @Created {
+                  return <-create Created()
+              }
+          }
+        `,
+		passes.UnusedResourceAnalyzer,
+	)
+
+	require.Equal(
+		t,
+		[]analysis.Diagnostic{
+			{
+				Location: testLocation,
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 122, Line: 6, Column: 35},
+					EndPos:   ast.Position{Offset: 127, Line: 6, Column: 40},
+				},
+				Category: passes.LintCategory,
+				Code:     passes.UnusedResourceCode,
+				Message:  "resource `Test.Unused` is declared, but never created",
+			},
+		},
+		diagnostics,
+	)
+}
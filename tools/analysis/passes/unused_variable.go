@@ -0,0 +1,64 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes
+
+import (
+	"fmt"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+const UnusedVariableCode = "unused-variable"
+
+// UnusedVariableAnalyzer reports local variables which are declared, but never used.
+// Unused variables of reference type are reported by UnusedReferenceAnalyzer.
+var UnusedVariableAnalyzer = &analysis.Analyzer{
+	Description: "Detects local variables which are never used",
+	Requires: []*analysis.Analyzer{
+		LocalVariablesAnalyzer,
+	},
+	Run: func(pass *analysis.Pass) interface{} {
+		localVariables, _ := pass.ResultOf[LocalVariablesAnalyzer].([]LocalVariable)
+
+		for _, localVariable := range localVariables {
+			if localVariable.Used || isReferenceType(localVariable.Type) {
+				continue
+			}
+
+			identifier := localVariable.Declaration.Identifier
+
+			pass.Report(
+				analysis.Diagnostic{
+					Location: pass.Program.Location,
+					Range:    ast.NewRangeFromPositioned(nil, identifier),
+					Category: LintCategory,
+					Code:     UnusedVariableCode,
+					Message: fmt.Sprintf(
+						"variable `%s` is declared, but never used",
+						identifier.Identifier,
+					),
+					SecondaryMessage: "remove the declaration, or prefix the name with an underscore",
+				},
+			)
+		}
+
+		return nil
+	},
+}
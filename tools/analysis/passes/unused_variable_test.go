@@ -0,0 +1,84 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package passes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/tools/analysis"
+	"github.com/onflow/cadence/tools/analysis/passes"
+)
+
+func TestUnusedVariableAnalyzer(t *testing.T) {
+
+	t.Parallel()
+
+	diagnostics := testAnalyzers(t,
+		`
+          access(all) let global = 1
+
+          access(all) fun test(opt: Int?): Int {
+              let unused = 1
+              var assigned = 2
+              assigned = 3
+              let used = 4
+              let _ignored = 5
+              if let value = opt {
+                  return used
+              }
+              let captured = 6
+              let f = fun (): Int { return captured }
+              return f()
+          }
+        `,
+		passes.UnusedVariableAnalyzer,
+	)
+
+	require.Equal(
+		t,
+		[]analysis.Diagnostic{
+			{
+				Location: testLocation,
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 106, Line: 5, Column: 18},
+					EndPos:   ast.Position{Offset: 111, Line: 5, Column: 23},
+				},
+				Category:         passes.LintCategory,
+				Code:             passes.UnusedVariableCode,
+				Message:          "variable `unused` is declared, but never used",
+				SecondaryMessage: "remove the declaration, or prefix the name with an underscore",
+			},
+			{
+				Location: testLocation,
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 254, Line: 10, Column: 21},
+					EndPos:   ast.Position{Offset: 258, Line: 10, Column: 25},
+				},
+				Category:         passes.LintCategory,
+				Code:             passes.UnusedVariableCode,
+				Message:          "variable `value` is declared, but never used",
+				SecondaryMessage: "remove the declaration, or prefix the name with an underscore",
+			},
+		},
+		diagnostics,
+	)
+}
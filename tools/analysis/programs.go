@@ -143,6 +143,7 @@ func (programs Programs) check(
 			),
 			PositionInfoEnabled:        config.Mode&NeedPositionInfo != 0,
 			ExtendedElaborationEnabled: config.Mode&NeedExtendedElaboration != 0,
+			SuggestionsEnabled:         config.Mode&NeedSuggestions != 0,
 			ImportHandler: func(
 				checker *sema.Checker,
 				importedLocation common.Location,
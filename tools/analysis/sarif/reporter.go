@@ -0,0 +1,352 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"reflect"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/errors"
+	"github.com/onflow/cadence/tools/analysis"
+)
+
+// DiagnosticCategoryProperty is the name of the result property
+// which contains the category of a reported analysis diagnostic
+const DiagnosticCategoryProperty = "category"
+
+// Reporter reports errors and analysis diagnostics as results of a single SARIF run
+type Reporter struct {
+	run         *Run
+	ruleIndices map[string]int
+}
+
+// NewReporter returns a new reporter for a run of the tool with the given name
+func NewReporter(toolName string, informationURI string) *Reporter {
+	return &Reporter{
+		run: &Run{
+			Tool: Tool{
+				Driver: ToolComponent{
+					Name:           toolName,
+					InformationURI: informationURI,
+				},
+			},
+			ColumnKind: ColumnKindUnicodeCodePoints,
+			Results:    []*Result{},
+		},
+		ruleIndices: map[string]int{},
+	}
+}
+
+// Log returns the SARIF log of all reported results
+func (r *Reporter) Log() *Log {
+	return &Log{
+		Schema:  Schema,
+		Version: Version,
+		Runs:    []*Run{r.run},
+	}
+}
+
+// Write writes the SARIF log of all reported results as JSON
+func (r *Reporter) Write(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.Log())
+}
+
+// ReportError reports the given error, e.g. a parser or checker error, which occurred in the given location.
+//
+// Like the pretty printer, the reporter reports each child error of parent errors separately,
+// and the location of errors is updated for errors which occurred in imported programs.
+// The given codes are used to suggest fixes.
+func (r *Reporter) ReportError(
+	err error,
+	location common.Location,
+	codes map[common.Location][]byte,
+) {
+	if err, ok := err.(common.HasLocation); ok {
+		importLocation := err.ImportLocation()
+		if importLocation != nil {
+			location = importLocation
+		}
+	}
+
+	if err, ok := err.(errors.ParentError); ok {
+		for _, childErr := range err.ChildErrors() {
+			r.ReportError(childErr, location, codes)
+		}
+		return
+	}
+
+	r.reportError(err, location, codes[location])
+}
+
+func (r *Reporter) reportError(err error, location common.Location, code []byte) {
+	ruleID := errorRuleID(err)
+
+	result := &Result{
+		RuleID:    ruleID,
+		RuleIndex: r.ruleIndex(ruleID, ""),
+		Level:     LevelError,
+		Message: Message{
+			Text: err.Error(),
+		},
+	}
+
+	var secondaryMessage string
+	if secondaryError, ok := err.(errors.SecondaryError); ok {
+		secondaryMessage = secondaryError.SecondaryError()
+	}
+
+	if positioned, ok := err.(ast.HasPosition); ok {
+		result.Locations = []*Location{
+			newLocation(
+				location,
+				positioned.StartPosition(),
+				positioned.EndPosition(nil),
+				secondaryMessage,
+			),
+		}
+	}
+
+	if errorNotes, ok := err.(errors.ErrorNotes); ok {
+		for _, errorNote := range errorNotes.ErrorNotes() {
+			positioned, ok := errorNote.(ast.HasPosition)
+			if !ok {
+				continue
+			}
+
+			relatedLocation := newLocation(
+				location,
+				positioned.StartPosition(),
+				positioned.EndPosition(nil),
+				errorNote.Message(),
+			)
+			id := len(result.RelatedLocations)
+			relatedLocation.ID = &id
+
+			result.RelatedLocations = append(result.RelatedLocations, relatedLocation)
+		}
+	}
+
+	if hasSuggestedFixes, ok := err.(errors.HasSuggestedFixes[ast.TextEdit]); ok {
+		result.Fixes = newFixes(location, hasSuggestedFixes.SuggestFixes(string(code)))
+	}
+
+	r.run.Results = append(r.run.Results, result)
+}
+
+// ReportDiagnostic reports the given analysis diagnostic.
+// The rule ID of the result is the code of the diagnostic, or if it has none, its category.
+func (r *Reporter) ReportDiagnostic(diagnostic analysis.Diagnostic) {
+	ruleID := diagnostic.Code
+	if ruleID == "" {
+		ruleID = diagnostic.Category
+	}
+
+	result := &Result{
+		RuleID:    ruleID,
+		RuleIndex: r.ruleIndex(ruleID, diagnostic.URL),
+		Level:     LevelWarning,
+		Message: Message{
+			Text: diagnostic.Message,
+		},
+		Locations: []*Location{
+			newLocation(
+				diagnostic.Location,
+				diagnostic.StartPos,
+				diagnostic.EndPos,
+				diagnostic.SecondaryMessage,
+			),
+		},
+		Fixes: newFixes(diagnostic.Location, diagnostic.SuggestedFixes),
+	}
+
+	if diagnostic.Category != "" {
+		result.Properties = map[string]any{
+			DiagnosticCategoryProperty: diagnostic.Category,
+		}
+	}
+
+	r.run.Results = append(r.run.Results, result)
+}
+
+// ruleIndex returns the index of the rule with the given ID,
+// and adds the rule to the tool if it was not reported before
+func (r *Reporter) ruleIndex(ruleID string, helpURI string) int {
+	index, ok := r.ruleIndices[ruleID]
+	if ok {
+		return index
+	}
+
+	driver := &r.run.Tool.Driver
+
+	index = len(driver.Rules)
+	driver.Rules = append(
+		driver.Rules,
+		&ReportingDescriptor{
+			ID:      ruleID,
+			HelpURI: helpURI,
+		},
+	)
+	r.ruleIndices[ruleID] = index
+
+	return index
+}
+
+// errorRuleID returns the rule ID for the given error, the name of its type, e.g. `TypeMismatchError`
+func errorRuleID(err error) string {
+	errorType := reflect.TypeOf(err)
+	for errorType.Kind() == reflect.Pointer {
+		errorType = errorType.Elem()
+	}
+	return errorType.Name()
+}
+
+// ArtifactURI returns the URI of the artifact for the given location.
+// The URI of a string location is its path, or a file URI if the path is absolute.
+// The URI of other locations is their ID.
+func ArtifactURI(location common.Location) string {
+	if location == nil {
+		return ""
+	}
+	if stringLocation, ok := location.(common.StringLocation); ok {
+		path := string(stringLocation)
+		if filepath.IsAbs(path) {
+			return (&url.URL{
+				Scheme: "file",
+				Path:   filepath.ToSlash(path),
+			}).String()
+		}
+		return filepath.ToSlash(path)
+	}
+	return location.ID()
+}
+
+// NewRegion returns the region for the given range.
+// Cadence positions have 0-based columns and inclusive ends.
+func NewRegion(startPos, endPos ast.Position) Region {
+	return Region{
+		StartLine:   startPos.Line,
+		StartColumn: startPos.Column + 1,
+		EndLine:     endPos.Line,
+		EndColumn:   endPos.Column + 2,
+	}
+}
+
+func newInsertionRegion(pos ast.Position) Region {
+	return Region{
+		StartLine:   pos.Line,
+		StartColumn: pos.Column + 1,
+		EndLine:     pos.Line,
+		EndColumn:   pos.Column + 1,
+	}
+}
+
+func newLocation(
+	location common.Location,
+	startPos ast.Position,
+	endPos ast.Position,
+	message string,
+) *Location {
+	result := &Location{
+		PhysicalLocation: &PhysicalLocation{
+			ArtifactLocation: ArtifactLocation{
+				URI: ArtifactURI(location),
+			},
+		},
+	}
+
+	// Positions of errors without position information are invalid
+	if startPos.Line > 0 {
+		region := NewRegion(startPos, endPos)
+		result.PhysicalLocation.Region = &region
+	}
+
+	if message != "" {
+		result.Message = &Message{
+			Text: message,
+		}
+	}
+
+	return result
+}
+
+func newFixes(location common.Location, suggestedFixes []errors.SuggestedFix[ast.TextEdit]) []*Fix {
+	if len(suggestedFixes) == 0 {
+		return nil
+	}
+
+	fixes := make([]*Fix, 0, len(suggestedFixes))
+
+	for _, suggestedFix := range suggestedFixes {
+		replacements := make([]*Replacement, 0, len(suggestedFix.TextEdits))
+
+		for _, textEdit := range suggestedFix.TextEdits {
+			var replacement *Replacement
+
+			// An insertion is inserted at the start position,
+			// a replacement replaces the whole range
+			if textEdit.Insertion != "" {
+				replacement = &Replacement{
+					DeletedRegion: newInsertionRegion(textEdit.StartPos),
+					InsertedContent: &ArtifactContent{
+						Text: textEdit.Insertion,
+					},
+				}
+			} else {
+				replacement = &Replacement{
+					DeletedRegion: NewRegion(textEdit.StartPos, textEdit.EndPos),
+				}
+				if textEdit.Replacement != "" {
+					replacement.InsertedContent = &ArtifactContent{
+						Text: textEdit.Replacement,
+					}
+				}
+			}
+
+			replacements = append(replacements, replacement)
+		}
+
+		fix := &Fix{
+			ArtifactChanges: []*ArtifactChange{
+				{
+					ArtifactLocation: ArtifactLocation{
+						URI: ArtifactURI(location),
+					},
+					Replacements: replacements,
+				},
+			},
+		}
+
+		if suggestedFix.Message != "" {
+			fix.Description = &Message{
+				Text: suggestedFix.Message,
+			}
+		}
+
+		fixes = append(fixes, fix)
+	}
+
+	return fixes
+}
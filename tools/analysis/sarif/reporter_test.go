@@ -0,0 +1,307 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sarif_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/tests/checker"
+	"github.com/onflow/cadence/tools/analysis"
+	"github.com/onflow/cadence/tools/analysis/sarif"
+)
+
+func TestReportCheckerErrors(t *testing.T) {
+
+	t.Parallel()
+
+	location := common.StringLocation("contracts/test.cdc")
+
+	code := `
+      access(all) fun test(opt: String?) {
+          let x = 1
+          let x = 2
+          add(1, b: 2)
+          opt.length
+      }
+
+      access(all) fun add(a: Int, b: Int): Int {
+          return a + b
+      }
+    `
+
+	_, err := checker.ParseAndCheckWithOptions(t,
+		code,
+		checker.ParseAndCheckOptions{
+			Location: location,
+			Config: &sema.Config{
+				SuggestionsEnabled: true,
+			},
+		},
+	)
+	errs := checker.RequireCheckerErrors(t, err, 3)
+	require.IsType(t, &sema.RedeclarationError{}, errs[0])
+	require.IsType(t, &sema.MissingArgumentLabelError{}, errs[1])
+	require.IsType(t, &sema.NotDeclaredMemberError{}, errs[2])
+
+	reporter := sarif.NewReporter("check", "")
+	reporter.ReportError(
+		err,
+		location,
+		map[common.Location][]byte{
+			location: []byte(code),
+		},
+	)
+
+	var builder strings.Builder
+	err = reporter.Write(&builder)
+	require.NoError(t, err)
+
+	require.JSONEq(t,
+		// language=json
+		`
+          {
+            "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+            "version": "2.1.0",
+            "runs": [
+              {
+                "tool": {
+                  "driver": {
+                    "name": "check",
+                    "rules": [
+                      {"id": "RedeclarationError"},
+                      {"id": "MissingArgumentLabelError"},
+                      {"id": "NotDeclaredMemberError"}
+                    ]
+                  }
+                },
+                "columnKind": "unicodeCodePoints",
+                "results": [
+                  {
+                    "ruleId": "RedeclarationError",
+                    "ruleIndex": 0,
+                    "level": "error",
+                    "message": {"text": "cannot redeclare constant: `+"`x`"+` is already declared"},
+                    "locations": [
+                      {
+                        "physicalLocation": {
+                          "artifactLocation": {"uri": "contracts/test.cdc"},
+                          "region": {"startLine": 4, "startColumn": 15, "endLine": 4, "endColumn": 16}
+                        }
+                      }
+                    ],
+                    "relatedLocations": [
+                      {
+                        "id": 0,
+                        "physicalLocation": {
+                          "artifactLocation": {"uri": "contracts/test.cdc"},
+                          "region": {"startLine": 3, "startColumn": 15, "endLine": 3, "endColumn": 16}
+                        },
+                        "message": {"text": "previously declared here"}
+                      }
+                    ]
+                  },
+                  {
+                    "ruleId": "MissingArgumentLabelError",
+                    "ruleIndex": 1,
+                    "level": "error",
+                    "message": {"text": "missing argument label: `+"`a`"+`"},
+                    "locations": [
+                      {
+                        "physicalLocation": {
+                          "artifactLocation": {"uri": "contracts/test.cdc"},
+                          "region": {"startLine": 5, "startColumn": 15, "endLine": 5, "endColumn": 16}
+                        }
+                      }
+                    ],
+                    "fixes": [
+                      {
+                        "description": {"text": "insert argument label"},
+                        "artifactChanges": [
+                          {
+                            "artifactLocation": {"uri": "contracts/test.cdc"},
+                            "replacements": [
+                              {
+                                "deletedRegion": {"startLine": 5, "startColumn": 15, "endLine": 5, "endColumn": 15},
+                                "insertedContent": {"text": "a: "}
+                              }
+                            ]
+                          }
+                        ]
+                      }
+                    ]
+                  },
+                  {
+                    "ruleId": "NotDeclaredMemberError",
+                    "ruleIndex": 2,
+                    "level": "error",
+                    "message": {"text": "value of type `+"`String?`"+` has no member `+"`length`"+`"},
+                    "locations": [
+                      {
+                        "physicalLocation": {
+                          "artifactLocation": {"uri": "contracts/test.cdc"},
+                          "region": {"startLine": 6, "startColumn": 15, "endLine": 6, "endColumn": 21}
+                        },
+                        "message": {"text": "type is optional, consider optional-chaining: ?.length"}
+                      }
+                    ],
+                    "fixes": [
+                      {
+                        "description": {"text": "use optional chaining"},
+                        "artifactChanges": [
+                          {
+                            "artifactLocation": {"uri": "contracts/test.cdc"},
+                            "replacements": [
+                              {
+                                "deletedRegion": {"startLine": 6, "startColumn": 14, "endLine": 6, "endColumn": 14},
+                                "insertedContent": {"text": "?"}
+                              }
+                            ]
+                          }
+                        ]
+                      }
+                    ]
+                  }
+                ]
+              }
+            ]
+          }
+        `,
+		builder.String(),
+	)
+}
+
+func TestReportDiagnostics(t *testing.T) {
+
+	t.Parallel()
+
+	location := common.AddressLocation{
+		Address: common.MustBytesToAddress([]byte{0x1}),
+		Name:    "Test",
+	}
+
+	reporter := sarif.NewReporter("cadence-lint", "https://cadence-lang.org")
+
+	for i := 0; i < 2; i++ {
+		reporter.ReportDiagnostic(
+			analysis.Diagnostic{
+				Location:         location,
+				Category:         "lint",
+				Code:             "redundant-force-unwrap",
+				URL:              "https://cadence-lang.org/docs",
+				Message:          "force-unwrap is redundant",
+				SecondaryMessage: "remove it",
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 10, Line: 2 + i, Column: 4},
+					EndPos:   ast.Position{Offset: 14, Line: 2 + i, Column: 8},
+				},
+				SuggestedFixes: []analysis.SuggestedFix{
+					{
+						Message: "remove force-unwrap",
+						TextEdits: []analysis.TextEdit{
+							{
+								Range: ast.Range{
+									StartPos: ast.Position{Offset: 14, Line: 2 + i, Column: 8},
+									EndPos:   ast.Position{Offset: 14, Line: 2 + i, Column: 8},
+								},
+							},
+						},
+					},
+				},
+			},
+		)
+	}
+
+	log := reporter.Log()
+	require.Len(t, log.Runs, 1)
+
+	run := log.Runs[0]
+	require.Equal(t,
+		[]*sarif.ReportingDescriptor{
+			{
+				ID:      "redundant-force-unwrap",
+				HelpURI: "https://cadence-lang.org/docs",
+			},
+		},
+		run.Tool.Driver.Rules,
+	)
+
+	require.Len(t, run.Results, 2)
+	require.Equal(t,
+		&sarif.Result{
+			RuleID:    "redundant-force-unwrap",
+			RuleIndex: 0,
+			Level:     sarif.LevelWarning,
+			Message: sarif.Message{
+				Text: "force-unwrap is redundant",
+			},
+			Locations: []*sarif.Location{
+				{
+					PhysicalLocation: &sarif.PhysicalLocation{
+						ArtifactLocation: sarif.ArtifactLocation{
+							URI: "A.0000000000000001.Test",
+						},
+						Region: &sarif.Region{
+							StartLine:   3,
+							StartColumn: 5,
+							EndLine:     3,
+							EndColumn:   10,
+						},
+					},
+					Message: &sarif.Message{
+						Text: "remove it",
+					},
+				},
+			},
+			Fixes: []*sarif.Fix{
+				{
+					Description: &sarif.Message{
+						Text: "remove force-unwrap",
+					},
+					ArtifactChanges: []*sarif.ArtifactChange{
+						{
+							ArtifactLocation: sarif.ArtifactLocation{
+								URI: "A.0000000000000001.Test",
+							},
+							Replacements: []*sarif.Replacement{
+								{
+									DeletedRegion: sarif.Region{
+										StartLine:   3,
+										StartColumn: 9,
+										EndLine:     3,
+										EndColumn:   10,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			Properties: map[string]any{
+				sarif.DiagnosticCategoryProperty: "lint",
+			},
+		},
+		run.Results[1],
+	)
+}
@@ -0,0 +1,125 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sarif emits parser errors, checker errors, and analysis diagnostics
+// in the Static Analysis Results Interchange Format (SARIF) 2.1.0.
+//
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+package sarif
+
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Levels of results
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// ColumnKindUnicodeCodePoints is the column kind of Cadence positions
+const ColumnKindUnicodeCodePoints = "unicodeCodePoints"
+
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []*Run `json:"runs"`
+}
+
+type Run struct {
+	Tool       Tool      `json:"tool"`
+	ColumnKind string    `json:"columnKind,omitempty"`
+	Results    []*Result `json:"results"`
+}
+
+type Tool struct {
+	Driver ToolComponent `json:"driver"`
+}
+
+type ToolComponent struct {
+	Name           string                 `json:"name"`
+	InformationURI string                 `json:"informationUri,omitempty"`
+	Rules          []*ReportingDescriptor `json:"rules,omitempty"`
+}
+
+type ReportingDescriptor struct {
+	ID               string   `json:"id"`
+	ShortDescription *Message `json:"shortDescription,omitempty"`
+	HelpURI          string   `json:"helpUri,omitempty"`
+}
+
+type Result struct {
+	RuleID           string         `json:"ruleId"`
+	RuleIndex        int            `json:"ruleIndex"`
+	Level            string         `json:"level"`
+	Message          Message        `json:"message"`
+	Locations        []*Location    `json:"locations,omitempty"`
+	RelatedLocations []*Location    `json:"relatedLocations,omitempty"`
+	Fixes            []*Fix         `json:"fixes,omitempty"`
+	Properties       map[string]any `json:"properties,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	ID               *int              `json:"id,omitempty"`
+	PhysicalLocation *PhysicalLocation `json:"physicalLocation,omitempty"`
+	Message          *Message          `json:"message,omitempty"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Region is a region of an artifact.
+// Lines and columns are 1-based, and the end column is exclusive.
+// If the start and end are equal, the region is an insertion point.
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type Fix struct {
+	Description     *Message          `json:"description,omitempty"`
+	ArtifactChanges []*ArtifactChange `json:"artifactChanges"`
+}
+
+type ArtifactChange struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Replacements     []*Replacement   `json:"replacements"`
+}
+
+type Replacement struct {
+	DeletedRegion   Region           `json:"deletedRegion"`
+	InsertedContent *ArtifactContent `json:"insertedContent,omitempty"`
+}
+
+type ArtifactContent struct {
+	Text string `json:"text"`
+}
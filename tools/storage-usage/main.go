@@ -0,0 +1,322 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/onflow/flow-go/cmd/util/ledger/util"
+	"github.com/onflow/flow-go/fvm/environment"
+	"github.com/onflow/flow-go/fvm/storage/state"
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+func main() {
+
+	payloadsFlag := flag.String("payloads", "", "payloads file")
+	addressesFlag := flag.String("addresses", "", "comma-separated addresses of accounts (default: all accounts)")
+	formatFlag := flag.String("format", "text", "output format: text or json")
+	topFlag := flag.Int("top", 20, "number of largest paths in text output")
+	flag.Parse()
+
+	consoleWriter := zerolog.ConsoleWriter{
+		Out:        os.Stderr,
+		TimeFormat: time.DateTime,
+	}
+	log := zerolog.New(consoleWriter).With().Timestamp().Logger()
+
+	payloadsPath := *payloadsFlag
+	if payloadsPath == "" {
+		log.Fatal().Msg("missing payloads")
+	}
+
+	format := *formatFlag
+	if format != "text" && format != "json" {
+		log.Fatal().Msgf("invalid format: %s", format)
+	}
+
+	_, payloads, err := util.ReadPayloadFile(log, payloadsPath)
+	if err != nil {
+		log.Fatal().Err(err)
+	}
+
+	log.Info().Msgf("read %d payloads", len(payloads))
+
+	log.Info().Msg("building payload snapshot ...")
+
+	payloadSnapshot, err := util.NewPayloadSnapshot(payloads)
+	if err != nil {
+		log.Fatal().Err(err)
+	}
+
+	var addresses []common.Address
+	if *addressesFlag != "" {
+		for _, hexAddress := range strings.Split(*addressesFlag, ",") {
+			address, err := common.HexToAddress(strings.TrimSpace(hexAddress))
+			if err != nil {
+				log.Fatal().Err(err).Msgf("invalid address: %s", hexAddress)
+			}
+			addresses = append(addresses, address)
+		}
+	} else {
+		addresses = payloadAddresses(payloadSnapshot)
+	}
+
+	log.Info().Msg("creating storage ...")
+
+	transactionState := state.NewTransactionState(payloadSnapshot, state.DefaultParameters())
+	accounts := environment.NewAccounts(transactionState)
+
+	accountsAtreeLedger := util.NewAccountsAtreeLedger(accounts)
+	runtimeStorage := runtime.NewStorage(accountsAtreeLedger, util.NopMemoryGauge{})
+
+	// Determining the static types of stored values does not require programs
+	inter, err := interpreter.NewInterpreter(
+		nil,
+		nil,
+		&interpreter.Config{
+			Storage: runtimeStorage,
+		},
+	)
+	if err != nil {
+		log.Fatal().Err(err)
+	}
+
+	log.Info().Msgf("computing storage usage of %d accounts ...", len(addresses))
+
+	var usages []runtime.AccountStorageUsage
+
+	for _, address := range addresses {
+		usage, err := runtimeStorage.StorageUsage(inter, address)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to compute storage usage of account %s", address)
+			continue
+		}
+		usages = append(usages, usage)
+	}
+
+	switch format {
+	case "json":
+		err = writeJSON(os.Stdout, usages)
+	case "text":
+		err = writeText(os.Stdout, usages, *topFlag)
+	}
+	if err != nil {
+		log.Fatal().Err(err)
+	}
+}
+
+func payloadAddresses(payloadSnapshot *util.PayloadSnapshot) []common.Address {
+	addressSet := map[common.Address]struct{}{}
+	for registerID := range payloadSnapshot.Payloads {
+		owner := registerID.Owner
+		if len(owner) > 0 {
+			addressSet[common.Address([]byte(owner))] = struct{}{}
+		}
+	}
+
+	addresses := make([]common.Address, 0, len(addressSet))
+	for address := range addressSet {
+		addresses = append(addresses, address)
+	}
+
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].Hex() < addresses[j].Hex()
+	})
+
+	return addresses
+}
+
+type jsonAccountUsage struct {
+	Address     string           `json:"address"`
+	Bytes       uint64           `json:"bytes"`
+	StorageMaps []jsonStorageMap `json:"storageMaps"`
+}
+
+type jsonStorageMap struct {
+	Domain string     `json:"domain"`
+	Slabs  int        `json:"slabs"`
+	Bytes  uint64     `json:"bytes"`
+	Paths  []jsonPath `json:"paths"`
+}
+
+type jsonPath struct {
+	Key         string `json:"key"`
+	Type        string `json:"type"`
+	InlineBytes uint32 `json:"inlineBytes"`
+	Slabs       int    `json:"slabs"`
+	Bytes       uint64 `json:"bytes"`
+}
+
+// writeJSON writes the storage usage of each account as a line of JSON
+func writeJSON(w io.Writer, usages []runtime.AccountStorageUsage) error {
+	encoder := json.NewEncoder(w)
+
+	for _, usage := range usages {
+		account := jsonAccountUsage{
+			Address: usage.Address.HexWithPrefix(),
+			Bytes:   usage.Bytes(),
+		}
+
+		for _, storageMap := range usage.StorageMaps {
+			jsonStorageMap := jsonStorageMap{
+				Domain: storageMap.Domain,
+				Slabs:  storageMap.Slabs,
+				Bytes:  storageMap.Bytes,
+			}
+
+			for _, path := range storageMap.Paths {
+				jsonStorageMap.Paths = append(
+					jsonStorageMap.Paths,
+					jsonPath{
+						Key:         fmt.Sprint(path.Key),
+						Type:        string(path.StaticType.ID()),
+						InlineBytes: path.InlineBytes,
+						Slabs:       path.Slabs,
+						Bytes:       path.Bytes,
+					},
+				)
+			}
+
+			account.StorageMaps = append(account.StorageMaps, jsonStorageMap)
+		}
+
+		err := encoder.Encode(account)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type textPath struct {
+	address common.Address
+	domain  string
+	key     string
+	typeID  string
+	slabs   int
+	bytes   uint64
+}
+
+type typeUsage struct {
+	typeID string
+	paths  int
+	slabs  int
+	bytes  uint64
+}
+
+// writeText writes the storage usage aggregated by static type,
+// and the paths which use the most storage
+func writeText(w io.Writer, usages []runtime.AccountStorageUsage, top int) error {
+	var paths []textPath
+	types := map[string]*typeUsage{}
+
+	var storageMapSlabs int
+	var storageMapBytes uint64
+
+	for _, usage := range usages {
+		for _, storageMap := range usage.StorageMaps {
+			storageMapSlabs += storageMap.Slabs
+			storageMapBytes += storageMap.Bytes
+
+			for _, path := range storageMap.Paths {
+				typeID := string(path.StaticType.ID())
+
+				paths = append(paths, textPath{
+					address: usage.Address,
+					domain:  storageMap.Domain,
+					key:     fmt.Sprint(path.Key),
+					typeID:  typeID,
+					slabs:   path.Slabs,
+					bytes:   path.Bytes,
+				})
+
+				t, ok := types[typeID]
+				if !ok {
+					t = &typeUsage{typeID: typeID}
+					types[typeID] = t
+				}
+				t.paths++
+				t.slabs += path.Slabs
+				t.bytes += path.Bytes
+			}
+		}
+	}
+
+	sortedTypes := make([]*typeUsage, 0, len(types))
+	for _, t := range types {
+		sortedTypes = append(sortedTypes, t)
+	}
+	sort.Slice(sortedTypes, func(i, j int) bool {
+		a, b := sortedTypes[i], sortedTypes[j]
+		if a.bytes != b.bytes {
+			return a.bytes > b.bytes
+		}
+		return a.typeID < b.typeID
+	})
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		return paths[i].bytes > paths[j].bytes
+	})
+	if top >= 0 && len(paths) > top {
+		paths = paths[:top]
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	_, _ = fmt.Fprintf(tw, "Storage maps\n\n")
+	_, _ = fmt.Fprintf(tw, "SLABS\tBYTES\n")
+	_, _ = fmt.Fprintf(tw, "%d\t%d\n", storageMapSlabs, storageMapBytes)
+
+	_, _ = fmt.Fprintf(tw, "\nStored values by static type\n\n")
+	_, _ = fmt.Fprintf(tw, "TYPE\tPATHS\tSLABS\tBYTES\n")
+	for _, t := range sortedTypes {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", t.typeID, t.paths, t.slabs, t.bytes)
+	}
+
+	_, _ = fmt.Fprintf(tw, "\nLargest paths\n\n")
+	_, _ = fmt.Fprintf(tw, "ADDRESS\tDOMAIN\tKEY\tTYPE\tSLABS\tBYTES\n")
+	for _, path := range paths {
+		_, _ = fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t%d\t%d\n",
+			path.address.HexWithPrefix(),
+			path.domain,
+			path.key,
+			path.typeID,
+			path.slabs,
+			path.bytes,
+		)
+	}
+
+	return tw.Flush()
+}
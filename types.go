@@ -461,7 +461,8 @@ func GetFieldsMappedByName(v HasFields) map[string]Value {
 	return fieldsMap
 }
 
-// DecodeFields decodes a HasFields into a struct
+// DecodeFields decodes a HasFields into a struct.
+// Only top-level fields are decoded, see Unmarshal for a recursive conversion.
 func DecodeFields(hasFields HasFields, s interface{}) error {
 	v := reflect.ValueOf(s)
 	if !v.IsValid() || v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {